@@ -5,4 +5,5 @@ type LocalAlbum struct {
 	Title               string  // either the directory base name, or metadata
 	Description         string  // As found in the metadata
 	Latitude, Longitude float64 // As found in the metadata
+	Order               string  // Order of the assets in the album: asc or desc, empty for the server's default
 }
@@ -0,0 +1,101 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// extAliases gives the extension used by SniffExtension for the other extensions of the same format
+var extAliases = map[string]string{
+	".jpeg": ".jpg",
+	".jpe":  ".jpg",
+	".tiff": ".tif",
+	".heif": ".heic",
+	".hif":  ".heic",
+	".m2ts": ".mts",
+}
+
+// distinctImages are the image formats recognized by their content, the other images are raw files
+var distinctImages = map[string]bool{
+	".jpg": true, ".png": true, ".gif": true, ".heic": true, ".avif": true, ".webp": true, ".jxl": true, ".psd": true,
+}
+
+// isoVideos are the video formats based on the ISO base media file format
+var isoVideos = map[string]bool{
+	".mp4": true, ".mov": true, ".3gp": true, ".m4v": true, ".insv": true, ".mp": true,
+}
+
+// sameFormat tells if the extension matches the format recognized in the file's content
+func (la *LocalAssetBrowser) sameFormat(ext, sniffed string) bool {
+	ext = strings.ToLower(ext)
+	if a, ok := extAliases[ext]; ok {
+		ext = a
+	}
+	switch {
+	case ext == sniffed:
+		return true
+	case sniffed == ".tif" || sniffed == ".cr2":
+		// most raw formats are based on TIFF
+		return la.sm.TypeFromExt(ext) == immich.TypeImage && !distinctImages[ext]
+	case isoVideos[sniffed]:
+		return isoVideos[ext]
+	}
+	return false
+}
+
+// checkContent compares the file's content with its extension, and gives the media type of the file.
+// When the type is detected by the content, the extension matching the content is kept for the file.
+func (la *LocalAssetBrowser) checkContent(ctx context.Context, fsys fs.FS, name string, mediaType string) string {
+	ext := path.Ext(name)
+	sniffed, err := sniffFile(fsys, name)
+	if err != nil || sniffed == "" || !la.sm.IsMedia(sniffed) || la.sameFormat(ext, sniffed) {
+		return mediaType
+	}
+	la.log.Record(ctx, fileevent.DiscoveredMismatch, nil, name, "extension", ext, "content", sniffed)
+	if la.typeDetection != "CONTENT" {
+		return mediaType
+	}
+	if la.contentExts[fsys] == nil {
+		la.contentExts[fsys] = map[string]string{}
+	}
+	la.contentExts[fsys][name] = sniffed
+	return la.sm.TypeFromExt(sniffed)
+}
+
+// typeOf gives the media type of the file, after its content when it has been detected
+func (la *LocalAssetBrowser) typeOf(fsys fs.FS, name string) string {
+	return la.sm.TypeFromExt(la.extOf(fsys, name))
+}
+
+// contentTitle gives the title of the file with the extension matching its content
+func (la *LocalAssetBrowser) contentTitle(name string, ext string) string {
+	base := filepath.Base(name)
+	if e := path.Ext(base); la.sm.TypeFromExt(e) != immich.TypeUnknown {
+		base = strings.TrimSuffix(base, e)
+	}
+	return base + ext
+}
+
+// sniffFile reads the first bytes of the file to recognize its format
+func sniffFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b := make([]byte, metadata.SniffSize)
+	n, err := io.ReadFull(f, b)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return metadata.SniffExtension(b[:n]), nil
+}
@@ -0,0 +1,92 @@
+package files
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestTypeDetection(t *testing.T) {
+	const (
+		jpeg = "\xff\xd8\xff\xe0\x00\x10JFIF"
+		heic = "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic"
+		tiff = "II*\x00\x08\x00\x00\x00"
+		mp4  = "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2"
+	)
+	fsys := newInMemFS()
+	for name, content := range map[string]string{
+		"IMG_0001.jpg":  heic, // wrong extension
+		"IMG_0002":      jpeg, // no extension
+		"IMG_0003.dng":  tiff, // raw file based on TIFF
+		"IMG_0004.mov":  mp4,  // ISO base media file
+		"IMG_0005.JPEG": jpeg,
+		"notes.txt":     "some notes",
+	} {
+		err := fsys.WriteFile(name, []byte(content), 0o666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		detection  string
+		mismatches int64
+		titles     map[string]string
+	}{
+		{
+			detection: "EXTENSION",
+			titles: map[string]string{
+				"IMG_0001.jpg": "IMG_0001.jpg", "IMG_0003.dng": "IMG_0003.dng", "IMG_0004.mov": "IMG_0004.mov", "IMG_0005.JPEG": "IMG_0005.JPEG",
+			},
+		},
+		{
+			detection:  "CHECK",
+			mismatches: 1,
+			titles: map[string]string{
+				"IMG_0001.jpg": "IMG_0001.jpg", "IMG_0003.dng": "IMG_0003.dng", "IMG_0004.mov": "IMG_0004.mov", "IMG_0005.JPEG": "IMG_0005.JPEG",
+			},
+		},
+		{
+			detection:  "CONTENT",
+			mismatches: 2,
+			titles: map[string]string{
+				"IMG_0001.jpg": "IMG_0001.heic", "IMG_0002": "IMG_0002.jpg", "IMG_0003.dng": "IMG_0003.dng", "IMG_0004.mov": "IMG_0004.mov", "IMG_0005.JPEG": "IMG_0005.JPEG",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.detection, func(t *testing.T) {
+			ctx := context.Background()
+			jnl := fileevent.NewRecorder(nil, false)
+			b, err := NewLocalFiles(ctx, jnl, fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.SetTypeDetection(tt.detection)
+			err = b.Prepare(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			titles := map[string]string{}
+			for a := range b.Browse(ctx) {
+				titles[a.FileName] = a.Title
+				if a.FileName == "IMG_0001.jpg" && tt.detection == "CONTENT" && a.Ext() != ".heic" {
+					t.Errorf("unexpected extension %q", a.Ext())
+				}
+				a.Close()
+			}
+			if len(titles) != len(tt.titles) {
+				t.Errorf("got %v, want %v", titles, tt.titles)
+			}
+			for f, title := range tt.titles {
+				if titles[f] != title {
+					t.Errorf("title of %s = %q, want %q", f, titles[f], title)
+				}
+			}
+			if got := jnl.GetCounts()[fileevent.DiscoveredMismatch]; got != tt.mismatches {
+				t.Errorf("mismatches = %d, want %d", got, tt.mismatches)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package files
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// The sources of the date of take
+const (
+	DateFromEXIF       = "exif"       // the metadata of the file
+	DateFromXMP        = "xmp"        // the XMP sidecar file
+	DateFromJSON       = "json"       // the other sidecar files, like exiftool's JSON files
+	DateFromFilename   = "filename"   // the name of the file or of its folders
+	DateFromFilesystem = "filesystem" // the modification time of the file
+)
+
+// DefaultDateSources are the sources of the date of take used when none are given
+var DefaultDateSources = []string{DateFromJSON, DateFromFilename, DateFromEXIF}
+
+// SetDateSources sets the sources of the date of take, by order of priority.
+// The first source giving a date wins, the -when-no-date option applies when none gives a date.
+func (la *LocalAssetBrowser) SetDateSources(sources []string) *LocalAssetBrowser {
+	la.dateSources = sources
+	return la
+}
+
+// sidecarDate is the date given by the sidecar files
+type sidecarDate struct {
+	date  time.Time
+	local bool
+}
+
+// dateAsset sets the date of take of the asset from the first source giving a date
+func (la *LocalAssetBrowser) dateAsset(ctx context.Context, a *browser.LocalAssetFile, xmp string, scDate sidecarDate) error {
+	sources := la.dateSources
+	if len(sources) == 0 {
+		sources = DefaultDateSources
+	}
+	a.Metadata.DateTaken, a.Metadata.LocalTime = time.Time{}, false
+	for _, src := range sources {
+		var d time.Time
+		local := false
+		switch src {
+		case DateFromEXIF:
+			err := la.ReadMetadataFromFile(a)
+			if err != nil {
+				return err
+			}
+			d, local = a.Metadata.DateTaken, a.Metadata.LocalTime
+		case DateFromXMP:
+			if xmp == "" {
+				continue
+			}
+			var err error
+			d, local, err = readXMPDate(a.FSys, xmp)
+			if err != nil {
+				la.log.Record(ctx, fileevent.Error, nil, xmp, "error", err.Error())
+				continue
+			}
+		case DateFromJSON:
+			d, local = scDate.date, scDate.local
+		case DateFromFilename:
+			fullPath := a.FileName
+			if fsys, ok := a.FSys.(fshelper.NameFS); ok {
+				fullPath = filepath.Join(fsys.Name(), a.FileName)
+			}
+			d, local = metadata.TakeTimeFromPath(fullPath), true
+		case DateFromFilesystem:
+			i, err := fs.Stat(a.FSys, a.FileName)
+			if err != nil {
+				return err
+			}
+			d = i.ModTime()
+		}
+		if !d.Before(toOldDate) {
+			a.Metadata.DateTaken, a.Metadata.LocalTime = d, local
+			la.log.Record(ctx, fileevent.INFO, nil, a.FileName, "date", d.Format(time.RFC3339), "source", src)
+			return nil
+		}
+	}
+
+	a.Metadata.DateTaken, a.Metadata.LocalTime = time.Time{}, false
+	switch la.whenNoDate {
+	case "FILE":
+		i, err := fs.Stat(a.FSys, a.FileName)
+		if err != nil {
+			return err
+		}
+		a.Metadata.DateTaken = i.ModTime()
+		la.log.Record(ctx, fileevent.INFO, nil, a.FileName, "date", a.Metadata.DateTaken.Format(time.RFC3339), "source", DateFromFilesystem)
+	case "NOW":
+		a.Metadata.DateTaken = time.Now()
+		la.log.Record(ctx, fileevent.INFO, nil, a.FileName, "date", a.Metadata.DateTaken.Format(time.RFC3339), "source", "now")
+	}
+	return nil
+}
+
+// readXMPDate reads the date of take of a XMP sidecar file
+func readXMPDate(fsys fs.FS, name string) (time.Time, bool, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer f.Close()
+	return metadata.ReadXMPDateTaken(f)
+}
@@ -0,0 +1,65 @@
+package files
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestDateSources(t *testing.T) {
+	fsys := newInMemFS().addFile("photos/PXL_20220101_101010.jpg")
+	for name, content := range map[string]string{
+		"photos/PXL_20220101_101010.jpg.xmp": `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" exif:DateTimeOriginal="2021-05-05T05:05:05Z"/></rdf:RDF></x:xmpmeta>`,
+		"photos/PXL_20220101_101010.json":    `{"DateTimeOriginal": "2020:02:02 02:02:02Z"}`,
+	} {
+		if err := fsys.WriteFile(name, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+	i, err := fs.Stat(fsys, "photos/PXL_20220101_101010.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sources []string
+		want    time.Time
+	}{
+		{sources: nil, want: time.Date(2020, 2, 2, 2, 2, 2, 0, time.UTC)},
+		{sources: []string{DateFromFilename, DateFromJSON}, want: time.Date(2022, 1, 1, 10, 10, 10, 0, time.Local)},
+		{sources: []string{DateFromXMP, DateFromJSON}, want: time.Date(2021, 5, 5, 5, 5, 5, 0, time.UTC)},
+		// the files in memory have no modification time, the next source is used
+		{sources: []string{DateFromEXIF, DateFromFilesystem, DateFromJSON}, want: time.Date(2020, 2, 2, 2, 2, 2, 0, time.UTC)},
+		{sources: []string{DateFromEXIF}, want: i.ModTime()}, // -when-no-date=FILE
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		b, err := NewLocalFiles(ctx, fileevent.NewRecorder(nil, false), fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.SetDateSources(tt.sources)
+		b.SetOtherSidecars(true)
+		err = b.Prepare(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for a := range b.Browse(ctx) {
+			n++
+			if !a.Metadata.DateTaken.Equal(tt.want) {
+				t.Errorf("sources %v: date %s, want %s", tt.sources, a.Metadata.DateTaken, tt.want)
+			}
+			a.Close()
+		}
+		if n != 1 {
+			t.Errorf("sources %v: %d assets, want 1", tt.sources, n)
+		}
+	}
+}
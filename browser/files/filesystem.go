@@ -0,0 +1,53 @@
+package files
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+// snapshotFolders are the folders of the file system snapshots, never browsed
+var snapshotFolders = map[string]bool{
+	".snapshot":          true, // NetApp, NFS servers
+	".snapshots":         true, // snapper, btrfs
+	".zfs":               true, // ZFS, TrueNAS
+	"#snapshot":          true, // Synology
+	"@recently-snapshot": true, // QNAP
+}
+
+// SetOneFileSystem makes the walk stay on the file system of the source, the mount points are skipped
+func (la *LocalAssetBrowser) SetOneFileSystem(b bool) *LocalAssetBrowser {
+	la.oneFileSystem = b
+	return la
+}
+
+// skipFolder tells if the folder must not be browsed: a snapshot folder, or a folder on another file system
+func (la *LocalAssetBrowser) skipFolder(ctx context.Context, fsys fs.FS, name string) bool {
+	if snapshotFolders[strings.ToLower(path.Base(name))] {
+		la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "snapshot folder")
+		return true
+	}
+	if !la.oneFileSystem {
+		return false
+	}
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return false
+	}
+	dev, ok := deviceID(fi)
+	if !ok {
+		return false
+	}
+	if name == "." {
+		la.devices[fsys] = dev
+		return false
+	}
+	if root, ok := la.devices[fsys]; ok && root != dev {
+		la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "folder on another file system")
+		return true
+	}
+	return false
+}
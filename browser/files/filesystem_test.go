@@ -0,0 +1,44 @@
+package files
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestSnapshotFolders(t *testing.T) {
+	fsys := newInMemFS().
+		addFile("photos/a.jpg").
+		addFile("photos/.snapshot/hourly.0/photos/a.jpg").
+		addFile("#snapshot/daily/a.jpg").
+		addFile("@Recently-Snapshot/GMT+01_2024-01-01/a.jpg")
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+	ctx := context.Background()
+	jnl := fileevent.NewRecorder(nil, false)
+	b, err := NewLocalFiles(ctx, jnl, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the snapshots are skipped, even when the hidden folders are browsed
+	b.SetIncludeHidden(true)
+	err = b.Prepare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for a := range b.Browse(ctx) {
+		got = append(got, a.FileName)
+	}
+	sort.Strings(got)
+	if want := []string{"photos/a.jpg"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if n := jnl.GetCounts()[fileevent.DiscoveredDiscarded]; n != 3 {
+		t.Errorf("expected 3 snapshot folders discarded, got %d", n)
+	}
+}
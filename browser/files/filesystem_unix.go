@@ -0,0 +1,17 @@
+//go:build !windows
+
+package files
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// deviceID gives the identifier of the file system of the file
+func deviceID(fi fs.FileInfo) (uint64, bool) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		// the type of Dev depends on the system
+		return uint64(st.Dev), true
+	}
+	return 0, false
+}
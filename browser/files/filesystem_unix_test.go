@@ -0,0 +1,84 @@
+//go:build !windows
+
+package files
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+// mountedFS gives the folders under mnt a different file system
+type mountedFS struct {
+	*inMemFS
+	mnt string
+}
+
+type devInfo struct {
+	fs.FileInfo
+	dev uint64
+}
+
+func (i devInfo) Sys() any {
+	st := &syscall.Stat_t{}
+	// the type of Dev depends on the system
+	v := reflect.ValueOf(&st.Dev).Elem()
+	if v.CanInt() {
+		v.SetInt(int64(i.dev))
+	} else {
+		v.SetUint(i.dev)
+	}
+	return st
+}
+
+func (m mountedFS) Stat(name string) (fs.FileInfo, error) {
+	fi, err := fs.Stat(m.inMemFS, name)
+	if err != nil {
+		return nil, err
+	}
+	dev := uint64(1)
+	if name == m.mnt || strings.HasPrefix(name, m.mnt+"/") {
+		dev = 2
+	}
+	return devInfo{FileInfo: fi, dev: dev}, nil
+}
+
+func TestOneFileSystem(t *testing.T) {
+	for _, one := range []bool{false, true} {
+		mem := newInMemFS().
+			addFile("photos/a.jpg").
+			addFile("photos/nfs/b.jpg")
+		if mem.err != nil {
+			t.Fatal(mem.err)
+		}
+		fsys := mountedFS{inMemFS: mem, mnt: "photos/nfs"}
+		ctx := context.Background()
+		b, err := NewLocalFiles(ctx, fileevent.NewRecorder(nil, false), fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.SetOneFileSystem(one)
+		err = b.Prepare(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for a := range b.Browse(ctx) {
+			got = append(got, a.FileName)
+		}
+		sort.Strings(got)
+		want := []string{"photos/a.jpg", "photos/nfs/b.jpg"}
+		if one {
+			want = want[:1]
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("one file system %v: expected %v, got %v", one, want, got)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package files
+
+import "io/fs"
+
+// deviceID gives the identifier of the file system of the file. The file information of Windows doesn't give the volume,
+// the mount points are reached through junctions, not followed without -follow-symlinks.
+func deviceID(fi fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
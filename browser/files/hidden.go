@@ -0,0 +1,19 @@
+package files
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SetIncludeHidden makes the walk browse the hidden files and folders, skipped by default
+func (la *LocalAssetBrowser) SetIncludeHidden(b bool) *LocalAssetBrowser {
+	la.includeHidden = b
+	return la
+}
+
+// isHidden tells if the file or the folder is hidden: its name begins with a dot,
+// or it has the hidden attribute on Windows
+func isHidden(name string, d fs.DirEntry) bool {
+	return strings.HasPrefix(path.Base(name), ".") || hiddenAttribute(d)
+}
@@ -0,0 +1,63 @@
+package files
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestHiddenFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		includeHidden bool
+		want          []string
+		hidden        int64
+	}{
+		{
+			name:   "skipped",
+			want:   []string{"photos/c.jpg"},
+			hidden: 2,
+		},
+		{
+			name:          "included",
+			includeHidden: true,
+			want:          []string{".stfolder/a.jpg", "photos/.b.jpg", "photos/c.jpg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := newInMemFS().
+				addFile(".stfolder/a.jpg").
+				addFile("photos/.b.jpg").
+				addFile("photos/c.jpg")
+			if fsys.err != nil {
+				t.Fatal(fsys.err)
+			}
+			ctx := context.Background()
+			jnl := fileevent.NewRecorder(nil, false)
+			b, err := NewLocalFiles(ctx, jnl, fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.SetIncludeHidden(tt.includeHidden)
+			err = b.Prepare(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for a := range b.Browse(ctx) {
+				got = append(got, a.FileName)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if n := jnl.GetCounts()[fileevent.DiscoveredHidden]; n != tt.hidden {
+				t.Errorf("expected %d hidden files, got %d", tt.hidden, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package files
+
+import "io/fs"
+
+// hiddenAttribute tells if the file has the hidden attribute, only the names tell it out of Windows
+func hiddenAttribute(d fs.DirEntry) bool {
+	return false
+}
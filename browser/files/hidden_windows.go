@@ -0,0 +1,20 @@
+//go:build windows
+
+package files
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// hiddenAttribute tells if the file has the hidden attribute
+func hiddenAttribute(d fs.DirEntry) bool {
+	fi, err := d.Info()
+	if err != nil {
+		return false
+	}
+	if a, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return a.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+	}
+	return false
+}
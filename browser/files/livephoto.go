@@ -0,0 +1,103 @@
+package files
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// SetLinkByContentID enables the pairing of the photos and videos of Live Photos
+// by their content identifier, when their names don't match
+func (la *LocalAssetBrowser) SetLinkByContentID(b bool) *LocalAssetBrowser {
+	la.linkByContentID = b
+	return la
+}
+
+// livePhotoCandidate tells if the file can be a part of a Live Photo
+func livePhotoCandidate(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".heic", ".heif", ".jpg", ".jpeg", ".mov", ".mp4":
+		return true
+	}
+	return false
+}
+
+// pairByContentID links the images and the videos left alone after the name matching
+// when they share the same Apple content identifier, wherever they are in the file system
+func (la *LocalAssetBrowser) pairByContentID(ctx context.Context, fsys fs.FS) error {
+	var images, videos []string
+	dirs := gen.MapKeys(la.catalogs[fsys])
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		links := la.linkFiles(fsys, dir)
+		files := gen.MapKeys(links)
+		sort.Strings(files)
+		for _, f := range files {
+			l := links[f]
+			switch {
+			case l.image != "" && l.video == "" && livePhotoCandidate(la.extOf(fsys, l.image)):
+				images = append(images, l.image)
+			case l.image == "" && l.video != "" && livePhotoCandidate(la.extOf(fsys, l.video)):
+				videos = append(videos, l.video)
+			}
+		}
+	}
+	if len(images) == 0 || len(videos) == 0 {
+		return nil
+	}
+
+	videoIDs := map[string]string{}
+	for _, v := range videos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		id := la.contentID(fsys, v)
+		if _, exists := videoIDs[id]; id != "" && !exists {
+			videoIDs[id] = v
+		}
+	}
+	if len(videoIDs) == 0 {
+		return nil
+	}
+	la.livePhotos[fsys] = map[string]string{}
+	la.pairedVideos[fsys] = map[string]bool{}
+	for _, i := range images {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		id := la.contentID(fsys, i)
+		if v, ok := videoIDs[id]; ok && id != "" {
+			la.livePhotos[fsys][i] = v
+			la.pairedVideos[fsys][v] = true
+			delete(videoIDs, id)
+		}
+	}
+	return nil
+}
+
+// contentID reads the content identifier of the file, or "" when it hasn't any
+func (la *LocalAssetBrowser) contentID(fsys fs.FS, name string) string {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	md, err := metadata.GetFromReader(f, la.extOf(fsys, name))
+	if err != nil {
+		return ""
+	}
+	return md.ContentID
+}
+
+// extOf gives the extension of the file, the one matching its content when detected
+func (la *LocalAssetBrowser) extOf(fsys fs.FS, name string) string {
+	if ext, ok := la.contentExts[fsys][name]; ok {
+		return ext
+	}
+	return path.Ext(name)
+}
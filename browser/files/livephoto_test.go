@@ -0,0 +1,149 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func be(v ...any) []byte {
+	b := &bytes.Buffer{}
+	for _, x := range v {
+		_ = binary.Write(b, binary.BigEndian, x)
+	}
+	return b.Bytes()
+}
+
+func box(typ string, content ...[]byte) []byte {
+	l := 8
+	for _, c := range content {
+		l += len(c)
+	}
+	return append(append(be(uint32(l)), typ...), bytes.Join(content, nil)...)
+}
+
+// livePhotoJPEG builds a JPEG file with the content identifier in the Apple maker notes
+func livePhotoJPEG(id string) []byte {
+	note := &bytes.Buffer{}
+	note.WriteString("Apple iOS\x00")
+	note.Write(be(uint16(1)))
+	note.WriteString("MM")
+	note.Write(be(uint16(1)))
+	note.Write(be(uint16(0x0011), uint16(2), uint32(len(id)+1), uint32(32)))
+	note.Write(be(uint32(0)))
+	note.WriteString(id + "\x00")
+
+	const date = "2023:10:06 08:30:00\x00"
+	tiff := &bytes.Buffer{}
+	tiff.WriteString("MM\x00*")
+	tiff.Write(be(uint32(8)))
+	// IFD0 at 8
+	tiff.Write(be(uint16(1), uint16(0x8769), uint16(4), uint32(1), uint32(26), uint32(0)))
+	// Exif IFD at 26, values at 56
+	tiff.Write(be(uint16(2)))
+	tiff.Write(be(uint16(0x9003), uint16(2), uint32(len(date)), uint32(56)))
+	tiff.Write(be(uint16(0x927c), uint16(7), uint32(note.Len()), uint32(56+len(date))))
+	tiff.Write(be(uint32(0)))
+	tiff.WriteString(date)
+	tiff.Write(note.Bytes())
+
+	b := []byte{0xff, 0xd8, 0xff, 0xe1}
+	b = append(b, be(uint16(tiff.Len()+8))...)
+	b = append(b, "Exif\x00\x00"...)
+	b = append(b, tiff.Bytes()...)
+	return append(b, 0xff, 0xd9)
+}
+
+// livePhotoMOV builds a QuickTime file with the content identifier in the Apple metadata
+func livePhotoMOV(id string) []byte {
+	keys := box("keys", be(uint32(0), uint32(1)), box("mdta", []byte("com.apple.quicktime.content.identifier")))
+	ilst := box("ilst", box(string(be(uint32(1))), box("data", be(uint32(1), uint32(0)), []byte(id))))
+	mvhd := box("mvhd", be(uint32(0), uint32(3779591400), uint32(3779591400)), make([]byte, 88))
+	return append(box("ftyp", []byte("qt  \x00\x00\x00\x00qt  ")),
+		box("moov", mvhd, box("meta", box("hdlr", make([]byte, 25)), keys, ilst))...)
+}
+
+func TestLinkByContentID(t *testing.T) {
+	const (
+		id1 = "9A4E1B52-6A7E-4A29-B1C4-2E1E0B7D3F10"
+		id2 = "0D54C6A4-70C2-4E1B-8C5A-6B7C1F3E2A11"
+	)
+	fsys := newInMemFS()
+	for name, content := range map[string][]byte{
+		"photos/renamed.jpg":  livePhotoJPEG(id1),
+		"videos/clip.mov":     livePhotoMOV(id1),
+		"videos/other.mov":    livePhotoMOV(id2),
+		"photos/IMG_0002.jpg": livePhotoJPEG(id2),
+		"photos/IMG_0002.mov": livePhotoMOV(id2),
+	} {
+		err := fsys.MkdirAll("photos", 0o777)
+		if err == nil {
+			err = fsys.MkdirAll("videos", 0o777)
+		}
+		if err == nil {
+			err = fsys.WriteFile(name, content, 0o666)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		enable bool
+		want   map[string]string // asset -> live photo video
+	}{
+		{
+			name: "by name",
+			want: map[string]string{
+				"photos/renamed.jpg":  "",
+				"photos/IMG_0002.jpg": "photos/IMG_0002.mov",
+				"videos/clip.mov":     "",
+				"videos/other.mov":    "",
+			},
+		},
+		{
+			name:   "by content identifier",
+			enable: true,
+			want: map[string]string{
+				"photos/renamed.jpg":  "videos/clip.mov",
+				"photos/IMG_0002.jpg": "photos/IMG_0002.mov",
+				"videos/other.mov":    "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			b, err := NewLocalFiles(ctx, fileevent.NewRecorder(nil, false), fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.SetLinkByContentID(tt.enable)
+			err = b.Prepare(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for a := range b.Browse(ctx) {
+				got[a.FileName] = ""
+				if a.LivePhoto != nil {
+					got[a.FileName] = a.LivePhoto.FileName
+					a.LivePhoto.Close()
+				}
+				a.Close()
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			for f, v := range tt.want {
+				if g, ok := got[f]; !ok || g != v {
+					t.Errorf("live photo of %s = %q, want %q", f, g, v)
+				}
+			}
+		})
+	}
+}
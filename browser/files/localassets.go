@@ -11,37 +11,71 @@ import (
 
 	"github.com/simulot/immich-go/browser"
 	"github.com/simulot/immich-go/helpers/fileevent"
-	"github.com/simulot/immich-go/helpers/fshelper"
 	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/membudget"
+	"github.com/simulot/immich-go/helpers/metacache"
 	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/helpers/scandb"
 	"github.com/simulot/immich-go/immich"
 	"github.com/simulot/immich-go/immich/metadata"
 )
 
 type fileLinks struct {
-	image   string
-	video   string
-	sidecar string
+	image    string
+	video    string
+	sidecar  string
+	sidecars []string // sidecars of other formats than XMP, read for their metadata
 }
 
+// DefaultWorkers is the default number of assets prepared concurrently
+const DefaultWorkers = 4
+
 type LocalAssetBrowser struct {
-	fsyss       []fs.FS
-	albums      map[string]string
-	catalogs    map[fs.FS]map[string][]string
-	log         *fileevent.Recorder
-	sm          immich.SupportedMedia
-	bannedFiles namematcher.List // list of file pattern to be exclude
-	whenNoDate  string
+	fsyss           []fs.FS
+	albums          map[string]string
+	catalogs        map[fs.FS]map[string][]string
+	log             *fileevent.Recorder
+	sm              immich.SupportedMedia
+	bannedFiles     namematcher.List     // list of file pattern to be exclude
+	includePaths    namematcher.PathList // when set, only files matching one of those path patterns are kept
+	excludePaths    namematcher.PathList // files matching one of those path patterns are excluded
+	whenNoDate      string
+	dateSources     []string                    // sources of the date of take, by order of priority
+	workers         int                         // number of assets prepared concurrently
+	typeDetection   string                      // EXTENSION, CHECK or CONTENT
+	contentExts     map[fs.FS]map[string]string // extension of the files detected by their content
+	linkByContentID bool                        // pair the Live Photos by their content identifier
+	otherSidecars   bool                        // read the .txt, .json and .picasa.ini sidecars
+	livePhotos      map[fs.FS]map[string]string // video of the images paired by their content identifier
+	pairedVideos    map[fs.FS]map[string]bool   // videos paired by their content identifier
+	metaCache       *metacache.Cache            // metadata read during the previous runs
+	followSymlinks  bool                        // enter the folders given by links
+	includeHidden   bool                        // browse the hidden files and folders
+	oneFileSystem   bool                        // stay on the file system of the source
+	devices         map[fs.FS]uint64            // file system of the sources
+	visited         map[fs.FS][]fs.FileInfo     // folders browsed when following the links
+	memory          *membudget.Budget           // memory allowed to the assets prepared in advance
+	scanDB          *scandb.DB                  // files uploaded by the previous runs
+
+	picasa map[fs.FS]map[string]map[string]metadata.Metadata // metadata of the .picasa.ini files by folder, by lower case file name
 }
 
 func NewLocalFiles(ctx context.Context, l *fileevent.Recorder, fsyss ...fs.FS) (*LocalAssetBrowser, error) {
 	return &LocalAssetBrowser{
-		fsyss:      fsyss,
-		albums:     map[string]string{},
-		catalogs:   map[fs.FS]map[string][]string{},
-		log:        l,
-		whenNoDate: "FILE",
-		sm:         immich.DefaultSupportedMedia,
+		fsyss:         fsyss,
+		albums:        map[string]string{},
+		catalogs:      map[fs.FS]map[string][]string{},
+		log:           l,
+		whenNoDate:    "FILE",
+		sm:            immich.DefaultSupportedMedia,
+		workers:       DefaultWorkers,
+		typeDetection: "EXTENSION",
+		contentExts:   map[fs.FS]map[string]string{},
+		livePhotos:    map[fs.FS]map[string]string{},
+		pairedVideos:  map[fs.FS]map[string]bool{},
+		picasa:        map[fs.FS]map[string]map[string]metadata.Metadata{},
+		visited:       map[fs.FS][]fs.FileInfo{},
+		devices:       map[fs.FS]uint64{},
 	}, nil
 }
 
@@ -55,17 +89,79 @@ func (la *LocalAssetBrowser) SetBannedFiles(banned namematcher.List) *LocalAsset
 	return la
 }
 
+func (la *LocalAssetBrowser) SetPathFilters(include, exclude namematcher.PathList) *LocalAssetBrowser {
+	la.includePaths = include
+	la.excludePaths = exclude
+	return la
+}
+
+// SetWorkers sets the number of assets prepared concurrently during the browsing
+func (la *LocalAssetBrowser) SetWorkers(n int) *LocalAssetBrowser {
+	la.workers = n
+	return la
+}
+
 func (la *LocalAssetBrowser) SetWhenNoDate(opt string) *LocalAssetBrowser {
 	la.whenNoDate = opt
 	return la
 }
 
+// SetTypeDetection sets how the type of the files is determined:
+//   - EXTENSION: by the file's extension
+//   - CHECK: by the file's extension, mismatches with the file's content are reported
+//   - CONTENT: by the file's content when it is recognized, files with a missing or wrong extension are uploaded with the right one
+func (la *LocalAssetBrowser) SetTypeDetection(opt string) *LocalAssetBrowser {
+	la.typeDetection = opt
+	return la
+}
+
+// SetOtherSidecars enables the reading of the sidecars of other formats than XMP, like the .txt captions,
+// the .json files exported by exiftool and the .picasa.ini files. The names of those files are too common to be read by default.
+func (la *LocalAssetBrowser) SetOtherSidecars(b bool) *LocalAssetBrowser {
+	la.otherSidecars = b
+	return la
+}
+
+// sidecarReader gives the reader of the sidecar format of the extension, when enabled.
+// The ignored formats, like the thumbnails, are always reported.
+func (la *LocalAssetBrowser) sidecarReader(ext string) (metadata.SidecarReader, bool) {
+	sr, ok := metadata.GetSidecarReader(ext)
+	if ok && sr != nil && !la.otherSidecars {
+		return nil, false
+	}
+	return sr, ok
+}
+
+// SetMetadataCache sets the cache of the metadata read from the files
+func (la *LocalAssetBrowser) SetMetadataCache(c *metacache.Cache) *LocalAssetBrowser {
+	la.metaCache = c
+	return la
+}
+
+// SetScanDB sets the files uploaded by the previous runs, they are skipped when unchanged
+func (la *LocalAssetBrowser) SetScanDB(db *scandb.DB) *LocalAssetBrowser {
+	la.scanDB = db
+	return la
+}
+
+// SetMemoryBudget slows down the preparation of the assets in advance while the memory exceeds the budget
+func (la *LocalAssetBrowser) SetMemoryBudget(b *membudget.Budget) *LocalAssetBrowser {
+	la.memory = b
+	return la
+}
+
 func (la *LocalAssetBrowser) Prepare(ctx context.Context) error {
 	for _, fsys := range la.fsyss {
 		err := la.passOneFsWalk(ctx, fsys)
 		if err != nil {
 			return err
 		}
+		if la.linkByContentID {
+			err = la.pairByContentID(ctx, fsys)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -73,196 +169,471 @@ func (la *LocalAssetBrowser) Prepare(ctx context.Context) error {
 
 func (la *LocalAssetBrowser) passOneFsWalk(ctx context.Context, fsys fs.FS) error {
 	la.catalogs[fsys] = map[string][]string{}
-	err := fs.WalkDir(fsys, ".",
-		func(name string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
+	var walk fs.WalkDirFunc
+	walk = func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 
+		if la.otherSidecars && !d.IsDir() && metadata.IsPicasaIni(path.Base(name)) {
+			la.readPicasaIni(ctx, fsys, name)
+			return nil
+		}
+		if name != "." && !la.includeHidden && isHidden(name, d) {
+			la.log.Record(ctx, fileevent.DiscoveredHidden, nil, name, "reason", "hidden file or folder, use -include-hidden")
 			if d.IsDir() {
-				la.catalogs[fsys][name] = []string{}
-				return nil
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if isLink(d) {
+			followed, err := la.followLink(ctx, fsys, name, walk)
+			if followed || err != nil {
+				return err
+			}
+		}
+		if d.IsDir() {
+			if la.skipFolder(ctx, fsys, name) || !la.enterFolder(ctx, fsys, name) {
+				return fs.SkipDir
+			}
+			la.catalogs[fsys][name] = []string{}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			// If the context has been cancelled, return immediately
+			return ctx.Err()
+		default:
+			dir, base := filepath.Split(name)
+			dir = strings.TrimSuffix(dir, "/")
+			if dir == "" {
+				dir = "."
+			}
+			ext := filepath.Ext(base)
+			mediaType := la.sm.TypeFromExt(ext)
+			switch {
+			case mediaType == immich.TypeSidecar:
+			case la.typeDetection == "CONTENT",
+				la.typeDetection == "CHECK" && mediaType != immich.TypeUnknown:
+				mediaType = la.checkContent(ctx, fsys, name, mediaType)
 			}
-			select {
-			case <-ctx.Done():
-				// If the context has been cancelled, return immediately
-				return ctx.Err()
-			default:
-				dir, base := filepath.Split(name)
-				dir = strings.TrimSuffix(dir, "/")
-				if dir == "" {
-					dir = "."
-				}
-				ext := filepath.Ext(base)
-				mediaType := la.sm.TypeFromExt(ext)
 
-				if mediaType == immich.TypeUnknown {
-					la.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unsupported file type")
-					return nil
+			if mediaType == immich.TypeUnknown {
+				if sr, ok := la.sidecarReader(ext); ok {
+					if sr == nil {
+						la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "ignored sidecar file")
+						return nil
+					}
+					mediaType = immich.TypeSidecar
 				}
+			}
+			if mediaType == immich.TypeUnknown {
+				la.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unsupported file type")
+				return nil
+			}
 
-				cat := la.catalogs[fsys][dir]
+			cat := la.catalogs[fsys][dir]
 
-				switch mediaType {
-				case immich.TypeImage:
-					la.log.Record(ctx, fileevent.DiscoveredImage, nil, name)
-				case immich.TypeVideo:
-					la.log.Record(ctx, fileevent.DiscoveredVideo, nil, name)
-				case immich.TypeSidecar:
-					la.log.Record(ctx, fileevent.DiscoveredSidecar, nil, name)
-				}
+			switch mediaType {
+			case immich.TypeImage:
+				la.log.Record(ctx, fileevent.DiscoveredImage, nil, name)
+			case immich.TypeVideo:
+				la.log.Record(ctx, fileevent.DiscoveredVideo, nil, name)
+			case immich.TypeSidecar:
+				la.log.Record(ctx, fileevent.DiscoveredSidecar, nil, name)
+			}
 
-				if la.bannedFiles.Match(name) {
-					la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "banned file")
-					return nil
-				}
-				la.catalogs[fsys][dir] = append(cat, name)
+			if la.bannedFiles.Match(name) {
+				la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "banned file")
+				return nil
 			}
-			return nil
-		})
-	return err
+			if !la.includePaths.Include(name) {
+				la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "path not included")
+				return nil
+			}
+			if la.excludePaths.Match(name) {
+				la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "path excluded")
+				return nil
+			}
+			la.catalogs[fsys][dir] = append(cat, name)
+		}
+		return nil
+	}
+	return fs.WalkDir(fsys, ".", walk)
+}
+
+// assetJob is the unit of work of the browsing pipeline
+type assetJob struct {
+	fsys   fs.FS
+	linked fileLinks
+	a      *browser.LocalAssetFile // the prepared asset
+	err    error                   // error encountered during the preparation
+	file   string                  // the file in error
+	done   chan struct{}           // closed when the job is completed
 }
 
+// Browse sends the assets found during the Prepare phase.
+//
+// Assets are prepared (stat, metadata extraction) by a pool of workers running ahead
+// of the upload. The number of assets being prepared is bounded to cap the memory
+// and the number of opened files. When a memory budget is set, the workers don't get new
+// assets while the memory exceeds it and prepared assets are waiting for the upload.
+// Assets are sent in the same order as the sequential browsing.
 func (la *LocalAssetBrowser) Browse(ctx context.Context) chan *browser.LocalAssetFile {
 	fileChan := make(chan *browser.LocalAssetFile)
-	// Browse all given FS to collect the list of files
+	workers := max(la.workers, 1)
+
 	go func(ctx context.Context) {
 		defer close(fileChan)
-		var err error
-
-		errFn := func(name string, err error) {
-			if err != nil {
-				la.log.Record(ctx, fileevent.Error, nil, name, "error", err.Error())
-			}
-		}
-		for _, fsys := range la.fsyss {
-			dirs := gen.MapKeys(la.catalogs[fsys])
-			sort.Strings(dirs)
-			for _, dir := range dirs {
-				links := map[string]fileLinks{}
-				files := la.catalogs[fsys][dir]
-
-				if len(files) == 0 {
-					continue
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		jobs := make(chan *assetJob, workers)
+		ordered := make(chan *assetJob, 2*workers)
+
+		// Workers prepare the assets
+		for i := 0; i < workers; i++ {
+			go func() {
+				for j := range jobs {
+					la.prepareAsset(ctx, j)
+					close(j.done)
 				}
+			}()
+		}
 
-				// Scan images first
-				for _, file := range files {
-					ext := path.Ext(file)
-					if la.sm.TypeFromExt(ext) == immich.TypeImage {
-						linked := links[file]
-						linked.image = file
-						links[file] = linked
-					}
-				}
-
-			next:
-				for _, file := range files {
-					ext := path.Ext(file)
-					t := la.sm.TypeFromExt(ext)
-					if t == immich.TypeImage {
-						continue next
-					}
-
-					base := strings.TrimSuffix(file, ext)
-					switch t {
-					case immich.TypeSidecar:
-						if image, ok := links[base]; ok {
-							// file.ext.XMP -> file.ext
-							image.sidecar = file
-							links[base] = image
-							continue next
+		// Producer sends the files in the browsing order
+		go func() {
+			defer close(ordered)
+			defer close(jobs)
+			for _, fsys := range la.fsyss {
+				dirs := gen.MapKeys(la.catalogs[fsys])
+				sort.Strings(dirs)
+				for _, dir := range dirs {
+					links := la.linkFiles(fsys, dir)
+					files := gen.MapKeys(links)
+					sort.Strings(files)
+					for _, file := range files {
+						j := &assetJob{
+							fsys:   fsys,
+							linked: links[file],
+							done:   make(chan struct{}),
 						}
-						for f := range links {
-							if strings.TrimSuffix(f, path.Ext(f)) == base {
-								if image, ok := links[f]; ok {
-									// base.XMP -> base.ext
-									image.sidecar = file
-									links[f] = image
-									continue next
-								}
-							}
-						}
-					case immich.TypeVideo:
-						if image, ok := links[base]; ok {
-							// file.MP.ext -> file.ext
-							image.sidecar = file
-							links[base] = image
-							continue next
-						}
-						for f := range links {
-							if strings.TrimSuffix(f, path.Ext(f)) == base {
-								if image, ok := links[f]; ok {
-									// base.MP4 -> base.ext
-									image.video = file
-									links[f] = image
-									continue next
-								}
-							}
-							if strings.TrimSuffix(f, path.Ext(f)) == file {
-								if image, ok := links[f]; ok {
-									// base.MP4 -> base.ext
-									image.video = file
-									links[f] = image
-									continue next
-								}
-							}
-						}
-						// Unlinked video
-						links[file] = fileLinks{video: file}
-					}
-				}
-
-				files = gen.MapKeys(links)
-				sort.Strings(files)
-				for _, file := range files {
-					var a *browser.LocalAssetFile
-					linked := links[file]
-
-					if linked.image != "" {
-						a, err = la.assetFromFile(fsys, linked.image)
-						if err != nil {
-							errFn(linked.image, err)
+						if la.memory.Wait(ctx, func() bool { return len(ordered) > 0 }) != nil {
 							return
 						}
-						if linked.video != "" {
-							a.LivePhoto, err = la.assetFromFile(fsys, linked.video)
-							if err != nil {
-								errFn(linked.video, err)
-								return
-							}
-						}
-					} else if linked.video != "" {
-						a, err = la.assetFromFile(fsys, linked.video)
-						if err != nil {
-							errFn(linked.video, err)
+						select {
+						case <-ctx.Done():
 							return
+						case ordered <- j:
 						}
-					}
-
-					if a != nil && linked.sidecar != "" {
-						a.SideCar = metadata.SideCarFile{
-							FSys:     fsys,
-							FileName: linked.sidecar,
+						select {
+						case <-ctx.Done():
+							j.err = ctx.Err()
+							close(j.done)
+							return
+						case jobs <- j:
 						}
-						la.log.Record(ctx, fileevent.AnalysisAssociatedMetadata, nil, linked.sidecar, "main", a.FileName)
 					}
-					select {
-					case <-ctx.Done():
-						return
-					default:
-						if a != nil {
-							fileChan <- a
-						}
+				}
+			}
+		}()
+
+		// Release the assets prepared in advance when leaving early
+		defer func() {
+			cancel()
+			for j := range ordered {
+				<-j.done
+				if j.a != nil {
+					j.a.Close()
+					if j.a.LivePhoto != nil {
+						j.a.LivePhoto.Close()
 					}
 				}
 			}
+		}()
+
+		for j := range ordered {
+			<-j.done
+			if j.err != nil {
+				if ctx.Err() == nil {
+					la.log.Record(ctx, fileevent.Error, nil, j.file, "error", j.err.Error())
+				}
+				return
+			}
+			if j.a == nil {
+				continue
+			}
+			a := j.a
+			j.a = nil
+			if j.linked.sidecar != "" {
+				la.log.Record(ctx, fileevent.AnalysisAssociatedMetadata, nil, j.linked.sidecar, "main", a.FileName)
+			}
+			for _, sc := range j.linked.sidecars {
+				la.log.Record(ctx, fileevent.AnalysisAssociatedMetadata, nil, sc, "main", a.FileName)
+			}
+			select {
+			case <-ctx.Done():
+				a.Close()
+				return
+			case fileChan <- a:
+			}
 		}
 	}(ctx)
 
 	return fileChan
 }
 
+// linkFiles associates images, videos and sidecars of the directory
+func (la *LocalAssetBrowser) linkFiles(fsys fs.FS, dir string) map[string]fileLinks {
+	links := map[string]fileLinks{}
+	files := la.catalogs[fsys][dir]
+
+	if len(files) == 0 {
+		return links
+	}
+
+	// Scan images first
+	for _, file := range files {
+		if la.typeOf(fsys, file) == immich.TypeImage {
+			linked := links[file]
+			linked.image = file
+			links[file] = linked
+		}
+	}
+
+	// Then videos, linked to images for motion pictures
+nextVideo:
+	for _, file := range files {
+		if la.typeOf(fsys, file) != immich.TypeVideo {
+			continue
+		}
+		ext := path.Ext(file)
+		base := strings.TrimSuffix(file, ext)
+		if image, ok := links[base]; ok {
+			// file.ext.MP4 -> file.ext
+			image.video = file
+			links[base] = image
+			continue nextVideo
+		}
+		for f := range links {
+			if strings.TrimSuffix(f, path.Ext(f)) == base {
+				if image, ok := links[f]; ok {
+					// base.MP4 -> base.ext
+					image.video = file
+					links[f] = image
+					continue nextVideo
+				}
+			}
+			if strings.TrimSuffix(f, path.Ext(f)) == file {
+				if image, ok := links[f]; ok {
+					// base.MP4 -> base.ext
+					image.video = file
+					links[f] = image
+					continue nextVideo
+				}
+			}
+		}
+		if la.pairedVideos[fsys][file] {
+			// linked to its image by the content identifier
+			continue nextVideo
+		}
+		// Unlinked video
+		links[file] = fileLinks{video: file}
+	}
+
+	// Live Photos paired by their content identifier
+	for file, image := range links {
+		if video, ok := la.livePhotos[fsys][file]; ok && image.video == "" {
+			image.video = video
+			links[file] = image
+		}
+	}
+
+	// Sidecars last, they can belong to images or videos
+nextSidecar:
+	for _, file := range files {
+		ext := path.Ext(file)
+		isXMP := la.typeOf(fsys, file) == immich.TypeSidecar
+		if !isXMP {
+			if sr, ok := la.sidecarReader(ext); !ok || sr == nil {
+				continue
+			}
+		}
+		attach := func(asset fileLinks) fileLinks {
+			if isXMP {
+				asset.sidecar = file
+			} else {
+				asset.sidecars = append(asset.sidecars, file)
+			}
+			return asset
+		}
+		base := strings.TrimSuffix(file, ext)
+		if asset, ok := links[base]; ok {
+			// file.ext.XMP -> file.ext
+			links[base] = attach(asset)
+			continue nextSidecar
+		}
+		for f := range links {
+			if strings.TrimSuffix(f, path.Ext(f)) == base {
+				if asset, ok := links[f]; ok {
+					// base.XMP -> base.ext
+					links[f] = attach(asset)
+					continue nextSidecar
+				}
+			}
+		}
+	}
+	return links
+}
+
+// prepareAsset builds the asset of the job
+func (la *LocalAssetBrowser) prepareAsset(ctx context.Context, j *assetJob) {
+	var err error
+	if ctx.Err() != nil {
+		j.err = ctx.Err()
+		return
+	}
+	linked := j.linked
+	if linked.image != "" {
+		j.a, err = la.assetFromFile(j.fsys, linked.image)
+		if err != nil {
+			j.err, j.file = err, linked.image
+			return
+		}
+		if linked.video != "" {
+			j.a.LivePhoto, err = la.assetFromFile(j.fsys, linked.video)
+			if err != nil {
+				j.a.Close()
+				j.a = nil
+				j.err, j.file = err, linked.video
+				return
+			}
+		}
+	} else if linked.video != "" {
+		j.a, err = la.assetFromFile(j.fsys, linked.video)
+		if err != nil {
+			j.err, j.file = err, linked.video
+			return
+		}
+	}
+
+	if j.a == nil {
+		return
+	}
+	if la.skipUnchanged(ctx, j.a) {
+		j.a.Close()
+		j.a = nil
+		return
+	}
+	if linked.sidecar != "" {
+		j.a.SideCar = metadata.SideCarFile{
+			FSys:     j.fsys,
+			FileName: linked.sidecar,
+		}
+	}
+	var scDate sidecarDate
+	for _, sc := range linked.sidecars {
+		md, err := readSidecar(j.fsys, sc)
+		if err != nil {
+			la.log.Record(ctx, fileevent.Error, nil, sc, "error", err.Error())
+			continue
+		}
+		// the date of the sidecar competes with the other sources of the date
+		if !md.DateTaken.IsZero() {
+			scDate = sidecarDate{date: md.DateTaken, local: md.LocalTime}
+			md.DateTaken, md.LocalTime = time.Time{}, false
+		}
+		j.a.Metadata.Merge(md)
+	}
+	if md, ok := la.picasa[j.fsys][path.Dir(j.a.FileName)][strings.ToLower(path.Base(j.a.FileName))]; ok {
+		j.a.Metadata.Merge(md)
+	}
+	err = la.dateAsset(ctx, j.a, linked.sidecar, scDate)
+	if err != nil {
+		j.err, j.file = err, j.a.FileName
+		j.a.Close()
+		j.a = nil
+		return
+	}
+	if j.a.LivePhoto != nil {
+		err = la.dateAsset(ctx, j.a.LivePhoto, "", sidecarDate{})
+		if err != nil {
+			j.a.Close()
+			j.a = nil
+			j.err, j.file = err, linked.video
+			return
+		}
+	}
+}
+
+// skipUnchanged tells if the asset, and its live photo video, have been uploaded by a previous run and haven't changed since.
+// The files are skipped before reading their metadata and their checksum.
+func (la *LocalAssetBrowser) skipUnchanged(ctx context.Context, a *browser.LocalAssetFile) bool {
+	if la.scanDB == nil {
+		return false
+	}
+	files := []*browser.LocalAssetFile{a}
+	if a.LivePhoto != nil {
+		files = append(files, a.LivePhoto)
+	}
+	ids := []string{}
+	for _, f := range files {
+		i, err := fs.Stat(f.FSys, f.FileName)
+		if err != nil {
+			return false
+		}
+		id, ok := la.scanDB.Uploaded(metacache.Key(f.FSys, f.FileName), i.Size(), i.ModTime())
+		if !ok {
+			return false
+		}
+		ids = append(ids, id)
+	}
+	for n, f := range files {
+		la.log.Record(ctx, fileevent.UploadUnchanged, f, f.FileName, "assetID", ids[n])
+	}
+	return true
+}
+
+// readPicasaIni reads the metadata given by the .picasa.ini file to the files of its folder
+func (la *LocalAssetBrowser) readPicasaIni(ctx context.Context, fsys fs.FS, name string) {
+	la.log.Record(ctx, fileevent.DiscoveredSidecar, nil, name)
+	f, err := fsys.Open(name)
+	if err != nil {
+		la.log.Record(ctx, fileevent.Error, nil, name, "error", err.Error())
+		return
+	}
+	defer f.Close()
+	files, err := metadata.ReadPicasaIni(f)
+	if err != nil {
+		la.log.Record(ctx, fileevent.Error, nil, name, "error", err.Error())
+		return
+	}
+	if la.picasa[fsys] == nil {
+		la.picasa[fsys] = map[string]map[string]metadata.Metadata{}
+	}
+	dir := path.Dir(name)
+	if la.picasa[fsys][dir] == nil {
+		la.picasa[fsys][dir] = map[string]metadata.Metadata{}
+	}
+	for file, md := range files {
+		la.picasa[fsys][dir][strings.ToLower(file)] = md
+	}
+}
+
+// readSidecar reads the metadata of a sidecar file of a registered format
+func readSidecar(fsys fs.FS, name string) (metadata.Metadata, error) {
+	sr, ok := metadata.GetSidecarReader(path.Ext(name))
+	if !ok || sr == nil {
+		return metadata.Metadata{}, nil
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return metadata.Metadata{}, err
+	}
+	defer f.Close()
+	return sr.ReadSidecar(f)
+}
+
 var toOldDate = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
 
 func (la *LocalAssetBrowser) assetFromFile(fsys fs.FS, name string) (*browser.LocalAssetFile, error) {
@@ -271,38 +642,33 @@ func (la *LocalAssetBrowser) assetFromFile(fsys fs.FS, name string) (*browser.Lo
 		Title:    filepath.Base(name),
 		FSys:     fsys,
 	}
-
-	fullPath := name
-	if fsys, ok := fsys.(fshelper.NameFS); ok {
-		fullPath = filepath.Join(fsys.Name(), name)
+	if ext, ok := la.contentExts[fsys][name]; ok {
+		a.ContentExt = ext
+		a.Title = la.contentTitle(name, ext)
 	}
 
-	a.Metadata.DateTaken = metadata.TakeTimeFromPath(fullPath)
-
 	i, err := fs.Stat(fsys, name)
 	if err != nil {
 		return nil, err
 	}
 	a.FileSize = int(i.Size())
-	if a.Metadata.DateTaken.IsZero() {
-		err = la.ReadMetadataFromFile(a)
-		if err != nil {
-			return nil, err
-		}
-		if a.Metadata.DateTaken.Before(toOldDate) {
-			switch la.whenNoDate {
-			case "FILE":
-				a.Metadata.DateTaken = i.ModTime()
-			case "NOW":
-				a.Metadata.DateTaken = time.Now()
-			}
-		}
-	}
 	return a, nil
 }
 
 func (la *LocalAssetBrowser) ReadMetadataFromFile(a *browser.LocalAssetFile) error {
-	ext := strings.ToLower(path.Ext(a.FileName))
+	ext := strings.ToLower(a.Ext())
+
+	key := metacache.Key(a.FSys, a.FileName)
+	i, err := fs.Stat(a.FSys, a.FileName)
+	if err != nil {
+		return err
+	}
+	if m, found, ok := la.metaCache.Get(key, i.Size(), i.ModTime()); ok {
+		if found {
+			a.Metadata.DateTaken, a.Metadata.LocalTime = m.DateTaken, m.LocalTime
+		}
+		return nil
+	}
 
 	// Open the file
 	r, err := a.PartialSourceReader()
@@ -310,8 +676,10 @@ func (la *LocalAssetBrowser) ReadMetadataFromFile(a *browser.LocalAssetFile) err
 		return err
 	}
 	m, err := metadata.GetFromReader(r, ext)
+	_ = a.ClosePartialSourceReader()
+	la.metaCache.Put(key, i.Size(), i.ModTime(), m, err == nil)
 	if err == nil {
-		a.Metadata.DateTaken = m.DateTaken
+		a.Metadata.DateTaken, a.Metadata.LocalTime = m.DateTaken, m.LocalTime
 	}
 	return nil
 }
@@ -3,14 +3,20 @@ package files
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"path"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
 
 	"github.com/kr/pretty"
 	"github.com/psanford/memfs"
+	"github.com/simulot/immich-go/browser"
 	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/membudget"
 	"github.com/simulot/immich-go/helpers/namematcher"
 	"github.com/simulot/immich-go/immich"
 )
@@ -99,6 +105,19 @@ func TestLocalAssets(t *testing.T) {
 				"video_01.mp4":   {video: "video_01.mp4", sidecar: "video_01.mp4.XMP"},
 			},
 		},
+		{
+			name: "video sidecar",
+			fsys: newInMemFS().
+				addFile("video_02.XMP").
+				addFile("video_02.mp4").
+				addFile("root_04.jpg").
+				addFile("root_04.jpg.mp4").
+				addFile("root_04.jpg.XMP"),
+			expected: map[string]fileLinks{
+				"video_02.mp4": {video: "video_02.mp4", sidecar: "video_02.XMP"},
+				"root_04.jpg":  {image: "root_04.jpg", video: "root_04.jpg.mp4", sidecar: "root_04.jpg.XMP"},
+			},
+		},
 	}
 
 	for _, c := range tc {
@@ -148,3 +167,126 @@ func TestLocalAssets(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalAssetsWorkers(t *testing.T) {
+	ctx := context.Background()
+	fsys := newInMemFS()
+	for i := 0; i < 50; i++ {
+		fsys.addFile(fmt.Sprintf("photos/%02d/photo_%03d.jpg", i%7, i))
+	}
+
+	browse := func(workers int, memory *membudget.Budget) []string {
+		b, err := NewLocalFiles(ctx, fileevent.NewRecorder(nil, false), fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.SetWorkers(workers)
+		b.SetMemoryBudget(memory)
+		err = b.Prepare(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := []string{}
+		for a := range b.Browse(ctx) {
+			files = append(files, a.FileName)
+			a.Close()
+		}
+		return files
+	}
+
+	sequential := browse(1, nil)
+	if len(sequential) != 50 {
+		t.Fatalf("expected 50 assets, got %d", len(sequential))
+	}
+	parallel := browse(8, nil)
+	if !reflect.DeepEqual(sequential, parallel) {
+		t.Errorf("the browsing order depends on the number of workers")
+		pretty.Ldiff(t, sequential, parallel)
+	}
+
+	// a budget always exceeded slows down the workers without blocking the browsing
+	overBudget := browse(8, membudget.New(1))
+	if !reflect.DeepEqual(sequential, overBudget) {
+		t.Errorf("the browsing depends on the memory budget")
+		pretty.Ldiff(t, sequential, overBudget)
+	}
+}
+
+func TestLocalAssetsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fsys := newInMemFS()
+	for i := 0; i < 50; i++ {
+		fsys.addFile(fmt.Sprintf("photos/photo_%03d.jpg", i))
+	}
+	b, err := NewLocalFiles(ctx, fileevent.NewRecorder(nil, false), fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Prepare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := b.Browse(ctx)
+	a := <-c
+	a.Close()
+	cancel()
+	// the channel must be closed
+	for a := range c {
+		a.Close()
+	}
+}
+
+// openCountFS counts the files left open
+type openCountFS struct {
+	fs.FS
+	open *atomic.Int32
+}
+
+type openCountFile struct {
+	fs.File
+	open *atomic.Int32
+}
+
+func (fsys openCountFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fsys.open.Add(1)
+	return openCountFile{File: f, open: fsys.open}, nil
+}
+
+func (f openCountFile) Close() error {
+	f.open.Add(-1)
+	return f.File.Close()
+}
+
+// TestReadMetadataClosesFile checks that the file isn't kept open once the metadata are read
+func TestReadMetadataClosesFile(t *testing.T) {
+	fsys := openCountFS{
+		FS:   fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte("not really a jpeg")}},
+		open: &atomic.Int32{},
+	}
+	la := &LocalAssetBrowser{}
+	a := &browser.LocalAssetFile{FSys: fsys, FileName: "photo.jpg"}
+	err := la.ReadMetadataFromFile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := fsys.open.Load(); n != 0 {
+		t.Errorf("%d file(s) left open after reading the metadata", n)
+	}
+
+	f, err := a.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+	if n := fsys.open.Load(); n != 0 {
+		t.Errorf("%d file(s) left open after closing the asset", n)
+	}
+}
@@ -0,0 +1,104 @@
+package files
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestOtherSidecars(t *testing.T) {
+	fsys := newInMemFS().
+		addFile("photos/IMG_0001.jpg").
+		addFile("photos/IMG_0002.jpg").
+		addFile("photos/IMG_0002.THM").
+		addFile("photos/IMG_0003.mp4")
+	for name, content := range map[string]string{
+		"photos/IMG_0001.jpg.txt": "A caption\n",
+		"photos/IMG_0001.json":    `{"DateTimeOriginal": "2023:10:06 08:30:00Z", "GPSLatitude": 48.8577, "GPSLongitude": 2.295}`,
+		"photos/IMG_0003.txt":     "A video caption",
+		"photos/orphan.txt":       "no asset",
+		"photos/.picasa.ini":      "[img_0002.JPG]\nstar=yes\ncaption=From Picasa\nkeywords=beach,summer\n",
+	} {
+		if err := fsys.WriteFile(name, []byte(content), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+
+	ctx := context.Background()
+	jnl := fileevent.NewRecorder(nil, false)
+	b, err := NewLocalFiles(ctx, jnl, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetOtherSidecars(true)
+	err = b.Prepare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for a := range b.Browse(ctx) {
+		got[a.FileName] = a.Metadata.Description
+		if a.FileName == "photos/IMG_0001.jpg" {
+			if !a.Metadata.DateTaken.Equal(time.Date(2023, 10, 6, 8, 30, 0, 0, time.UTC)) || a.Metadata.Latitude != 48.8577 {
+				t.Errorf("unexpected metadata %+v", a.Metadata)
+			}
+		}
+		a.Close()
+	}
+	want := map[string]string{
+		"photos/IMG_0001.jpg": "A caption",
+		"photos/IMG_0002.jpg": "From Picasa",
+		"photos/IMG_0003.mp4": "A video caption",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for f, d := range want {
+		if got[f] != d {
+			t.Errorf("description of %s = %q, want %q", f, got[f], d)
+		}
+	}
+	counts := jnl.GetCounts()
+	if counts[fileevent.DiscoveredDiscarded] != 1 || counts[fileevent.AnalysisAssociatedMetadata] != 3 {
+		t.Errorf("unexpected counts: discarded %d, associated %d", counts[fileevent.DiscoveredDiscarded], counts[fileevent.AnalysisAssociatedMetadata])
+	}
+}
+
+func TestOtherSidecarsDisabled(t *testing.T) {
+	fsys := newInMemFS().
+		addFile("photos/README.jpg").
+		addFile("photos/IMG_0002.jpg").
+		addFile("photos/IMG_0002.THM")
+	if err := fsys.WriteFile("photos/README.txt", []byte("Photos of the trip"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if fsys.err != nil {
+		t.Fatal(fsys.err)
+	}
+
+	ctx := context.Background()
+	jnl := fileevent.NewRecorder(nil, false)
+	b, err := NewLocalFiles(ctx, jnl, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Prepare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for a := range b.Browse(ctx) {
+		if a.Metadata.Description != "" {
+			t.Errorf("the text file has been read as the caption of %s", a.FileName)
+		}
+		a.Close()
+	}
+	counts := jnl.GetCounts()
+	if counts[fileevent.DiscoveredUnsupported] != 1 || counts[fileevent.DiscoveredDiscarded] != 1 {
+		t.Errorf("unexpected counts: unsupported %d, discarded %d", counts[fileevent.DiscoveredUnsupported], counts[fileevent.DiscoveredDiscarded])
+	}
+}
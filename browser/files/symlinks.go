@@ -0,0 +1,54 @@
+package files
+
+import (
+	"context"
+	"io/fs"
+	"os"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+// SetFollowSymlinks makes the walk enter the folders given by symbolic links and Windows junctions.
+// A folder already browsed is skipped, so the loops made by the links are broken.
+func (la *LocalAssetBrowser) SetFollowSymlinks(b bool) *LocalAssetBrowser {
+	la.followSymlinks = b
+	return la
+}
+
+// isLink tells if the entry is a symbolic link, or a junction: the recent versions of Go report them as irregular files
+func isLink(d fs.DirEntry) bool {
+	return d.Type()&(fs.ModeSymlink|fs.ModeIrregular) != 0
+}
+
+// followLink walks the folder given by the link. It tells false when the link doesn't give a folder,
+// then the link is handled as a file.
+func (la *LocalAssetBrowser) followLink(ctx context.Context, fsys fs.FS, name string, walk fs.WalkDirFunc) (bool, error) {
+	fi, err := fs.Stat(fsys, name)
+	if err != nil || !fi.IsDir() {
+		return false, nil
+	}
+	if !la.followSymlinks {
+		la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "link to a folder, not followed without -follow-symlinks")
+		return true, nil
+	}
+	return true, fs.WalkDir(fsys, name, walk)
+}
+
+// enterFolder tells if the folder must be browsed: a folder reached again through a link is skipped
+func (la *LocalAssetBrowser) enterFolder(ctx context.Context, fsys fs.FS, name string) bool {
+	if !la.followSymlinks {
+		return true
+	}
+	fi, err := fs.Stat(fsys, name)
+	if err != nil {
+		return true
+	}
+	for _, v := range la.visited[fsys] {
+		if os.SameFile(v, fi) {
+			la.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "folder already browsed through a link")
+			return false
+		}
+	}
+	la.visited[fsys] = append(la.visited[fsys], fi)
+	return true
+}
@@ -0,0 +1,91 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+func TestFollowSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the symbolic links need the developer mode on Windows")
+	}
+	dir := t.TempDir()
+	for _, d := range []string{"photos/2023", "archive/scans"} {
+		err := os.MkdirAll(filepath.Join(dir, d), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"photos/2023/a.jpg", "archive/scans/b.jpg"} {
+		err := os.WriteFile(filepath.Join(dir, f), []byte(f), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"photos/scans": "../archive/scans", // folder outside of the source
+		"photos/loop":  ".",                // loop to the folder itself
+		"photos/b.jpg": "../archive/scans/b.jpg",
+	}
+	for link, target := range links {
+		err := os.Symlink(target, filepath.Join(dir, link))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		follow    bool
+		want      []string
+		discarded int64
+	}{
+		{
+			follow:    false,
+			want:      []string{"2023/a.jpg", "b.jpg"},
+			discarded: 2,
+		},
+		{
+			follow:    true,
+			want:      []string{"2023/a.jpg", "b.jpg", "scans/b.jpg"},
+			discarded: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(map[bool]string{false: "not followed", true: "followed"}[tt.follow], func(t *testing.T) {
+			ctx := context.Background()
+			fsys, err := fshelper.NewGlobWalkFS(filepath.Join(dir, "photos"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			jnl := fileevent.NewRecorder(nil, false)
+			b, err := NewLocalFiles(ctx, jnl, fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.SetFollowSymlinks(tt.follow)
+			err = b.Prepare(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for a := range b.Browse(ctx) {
+				got = append(got, a.FileName)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if n := jnl.GetCounts()[fileevent.DiscoveredDiscarded]; n != tt.discarded {
+				t.Errorf("expected %d discarded, got %d", tt.discarded, n)
+			}
+		})
+	}
+}
@@ -7,12 +7,13 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"unicode/utf8"
 
 	"github.com/simulot/immich-go/browser"
 	"github.com/simulot/immich-go/helpers/fileevent"
-	"github.com/simulot/immich-go/helpers/fshelper"
 	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/membudget"
 	"github.com/simulot/immich-go/helpers/namematcher"
 	"github.com/simulot/immich-go/immich"
 	"github.com/simulot/immich-go/immich/metadata"
@@ -25,8 +26,12 @@ type Takeout struct {
 	log      *fileevent.Recorder
 	sm       immich.SupportedMedia
 
-	banned            namematcher.List // Banned files
+	banned            namematcher.List     // Banned files
+	includePaths      namematcher.PathList // when set, only files matching one of those path patterns are kept
+	excludePaths      namematcher.PathList // files matching one of those path patterns are excluded
 	acceptMissingJSON bool
+	workers           int               // number of folders walked concurrently
+	memory            *membudget.Budget // memory allowed to the folders walked in advance
 }
 
 // directoryCatalog captures all files in a given directory
@@ -51,6 +56,7 @@ func NewTakeout(ctx context.Context, l *fileevent.Recorder, sm immich.SupportedM
 		albums:   map[string]browser.LocalAlbum{},
 		log:      l,
 		sm:       sm,
+		workers:  DefaultWorkers,
 	}
 
 	return &to, nil
@@ -61,120 +67,169 @@ func (to *Takeout) SetBannedFiles(banned namematcher.List) *Takeout {
 	return to
 }
 
+func (to *Takeout) SetPathFilters(include, exclude namematcher.PathList) *Takeout {
+	to.includePaths = include
+	to.excludePaths = exclude
+	return to
+}
+
 func (to *Takeout) SetAcceptMissingJSON(flag bool) *Takeout {
 	to.acceptMissingJSON = flag
 	return to
 }
 
+// SetWorkers sets the number of folders walked concurrently when preparing the catalog
+func (to *Takeout) SetWorkers(n int) *Takeout {
+	to.workers = n
+	return to
+}
+
+// SetMemoryBudget holds the walk of the next folders while the memory exceeds the budget
+func (to *Takeout) SetMemoryBudget(b *membudget.Budget) *Takeout {
+	to.memory = b
+	return to
+}
+
 // Prepare scans all files in all walker to build the file catalog of the archive
 // metadata files content is read and kept
 
 func (to *Takeout) Prepare(ctx context.Context) error {
-	for _, w := range to.fsyss {
-		err := to.passOneFsWalk(ctx, w)
-		if err != nil {
-			return err
-		}
+	err := to.passOneFsWalk(ctx)
+	if err != nil {
+		return err
 	}
-	err := to.solvePuzzle(ctx)
+	err = to.solvePuzzle(ctx)
 	return err
 }
 
-func (to *Takeout) passOneFsWalk(ctx context.Context, w fs.FS) error {
-	err := fs.WalkDir(w, ".", func(name string, d fs.DirEntry, err error) error {
+// passOneFsWalk builds the catalog of the takeout parts.
+// The folders are walked concurrently, and their files are added to the catalog in the order of the parts and of the folders.
+func (to *Takeout) passOneFsWalk(ctx context.Context) error {
+	units := []*walkUnit{}
+	for _, w := range to.fsyss {
+		u, err := splitWalk(w)
 		if err != nil {
 			return err
 		}
+		units = append(units, u...)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var merged atomic.Int64
+	to.walkUnits(ctx, units, &merged)
 
+	for _, u := range units {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		default:
-
-			if d.IsDir() {
-				return nil
-			}
-
-			dir, base := path.Split(name)
-			dir = strings.TrimSuffix(dir, "/")
-			ext := strings.ToLower(path.Ext(base))
-
-			dirCatalog, ok := to.catalogs[dir]
-			if !ok {
-				dirCatalog.jsons = map[string]*GoogleMetaData{}
-				dirCatalog.unMatchedFiles = map[string]*assetFile{}
-				dirCatalog.matchedFiles = map[string]*assetFile{}
-			}
-			if _, ok := dirCatalog.unMatchedFiles[base]; ok {
-				to.log.Record(ctx, fileevent.AnalysisLocalDuplicate, nil, name)
-				return nil
-			}
-
-			finfo, err := d.Info()
+		case <-u.done:
+		}
+		for _, f := range u.files {
+			err := to.catalogFile(ctx, u.fsys, f)
 			if err != nil {
-				to.log.Record(ctx, fileevent.Error, nil, name, "error", err.Error())
 				return err
 			}
-			switch ext {
-			case ".json":
-				md, err := fshelper.ReadJSON[GoogleMetaData](w, name)
-				if err == nil {
-					switch {
-					case md.isAsset():
-						md.foundInPaths = append(md.foundInPaths, dir)
-						dirCatalog.jsons[base] = md
-						to.log.Record(ctx, fileevent.DiscoveredSidecar, nil, name, "type", "asset metadata", "title", md.Title)
-					case md.isAlbum():
-						a := to.albums[dir]
-						a.Title = md.Title
-						a.Path = filepath.Base(dir)
-						if e := md.Enrichments; e != nil {
-							a.Description = e.Text
-							a.Latitude = e.Latitude
-							a.Longitude = e.Longitude
-						}
-						to.albums[dir] = a
-						to.log.Record(ctx, fileevent.DiscoveredSidecar, nil, name, "type", "album metadata", "title", md.Title)
-					default:
-						to.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unknown JSONfile")
-						return nil
-					}
-				} else {
-					to.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unknown JSONfile")
-					return nil
-				}
-			default:
-				t := to.sm.TypeFromExt(ext)
-				switch t {
-				case immich.TypeUnknown:
-					to.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unsupported file type")
-					return nil
-				case immich.TypeVideo:
-					to.log.Record(ctx, fileevent.DiscoveredVideo, nil, name)
-					if strings.Contains(name, "Failed Videos") {
-						to.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "can't upload failed videos")
-						return nil
-					}
-				case immich.TypeImage:
-					to.log.Record(ctx, fileevent.DiscoveredImage, nil, name)
-				}
+		}
+		if u.err != nil {
+			return u.err
+		}
+		u.files = nil
+		merged.Add(1)
+	}
+	return nil
+}
 
-				if to.banned.Match(name) {
-					to.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "banned file")
-					return nil
-				}
+// catalogFile adds a file found by the walkers to the catalog of its folder
+func (to *Takeout) catalogFile(ctx context.Context, w fs.FS, f walkedFile) error {
+	name := f.name
+	dir, base := path.Split(name)
+	dir = strings.TrimSuffix(dir, "/")
+	ext := strings.ToLower(path.Ext(base))
+
+	dirCatalog, ok := to.catalogs[dir]
+	if !ok {
+		dirCatalog.jsons = map[string]*GoogleMetaData{}
+		dirCatalog.unMatchedFiles = map[string]*assetFile{}
+		dirCatalog.matchedFiles = map[string]*assetFile{}
+	}
+	if _, ok := dirCatalog.unMatchedFiles[base]; ok {
+		to.log.Record(ctx, fileevent.AnalysisLocalDuplicate, nil, name)
+		return nil
+	}
 
-				dirCatalog.unMatchedFiles[base] = &assetFile{
-					fsys:   w,
-					base:   base,
-					length: int(finfo.Size()),
+	if f.err != nil {
+		to.log.Record(ctx, fileevent.Error, nil, name, "error", f.err.Error())
+		return f.err
+	}
+	switch ext {
+	case ".json":
+		md := f.md
+		if md != nil {
+			switch {
+			case md.isAsset():
+				md.foundInPaths = append(md.foundInPaths, dir)
+				dirCatalog.jsons[base] = md
+				to.log.Record(ctx, fileevent.DiscoveredSidecar, nil, name, "type", "asset metadata", "title", md.Title)
+			case md.isAlbum():
+				a := to.albums[dir]
+				a.Title = md.Title
+				a.Path = filepath.Base(dir)
+				a.Description = md.Description
+				if e := md.Enrichments; e != nil {
+					if a.Description == "" {
+						a.Description = e.Text
+					}
+					a.Latitude = e.Latitude
+					a.Longitude = e.Longitude
 				}
+				to.albums[dir] = a
+				to.log.Record(ctx, fileevent.DiscoveredSidecar, nil, name, "type", "album metadata", "title", md.Title)
+			default:
+				to.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unknown JSONfile")
+				return nil
 			}
-			to.catalogs[dir] = dirCatalog
+		} else {
+			to.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unknown JSONfile")
 			return nil
 		}
-	})
-	return err
+	default:
+		t := to.sm.TypeFromExt(ext)
+		switch t {
+		case immich.TypeUnknown:
+			to.log.Record(ctx, fileevent.DiscoveredUnsupported, nil, name, "reason", "unsupported file type")
+			return nil
+		case immich.TypeVideo:
+			to.log.Record(ctx, fileevent.DiscoveredVideo, nil, name)
+			if strings.Contains(name, "Failed Videos") {
+				to.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "can't upload failed videos")
+				return nil
+			}
+		case immich.TypeImage:
+			to.log.Record(ctx, fileevent.DiscoveredImage, nil, name)
+		}
+
+		if to.banned.Match(name) {
+			to.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "banned file")
+			return nil
+		}
+		if !to.includePaths.Include(name) {
+			to.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "path not included")
+			return nil
+		}
+		if to.excludePaths.Match(name) {
+			to.log.Record(ctx, fileevent.DiscoveredDiscarded, nil, name, "reason", "path excluded")
+			return nil
+		}
+
+		dirCatalog.unMatchedFiles[base] = &assetFile{
+			fsys:   w,
+			base:   base,
+			length: int(f.size),
+		}
+	}
+	to.catalogs[dir] = dirCatalog
+	return nil
 }
 
 // solvePuzzle prepares metadata with information collected during pass one for each accepted files
@@ -489,10 +544,16 @@ func (to *Takeout) passTwo(ctx context.Context, dir string, assetChan chan *brow
 		image *assetFile
 	}{}
 
+	// The files are sorted to send the assets in the same order at each run
+	files := gen.MapKeys(catalog.matchedFiles)
+	sort.Strings(files)
+
 	// Scan pictures
-	for _, f := range gen.MapKeys(catalog.matchedFiles) {
+	images := []string{}
+	for _, f := range files {
 		ext := path.Ext(f)
 		if to.sm.TypeFromExt(ext) == immich.TypeImage {
+			images = append(images, f)
 			linked := linkedFiles[f]
 			linked.image = catalog.matchedFiles[f]
 			linkedFiles[f] = linked
@@ -501,11 +562,12 @@ func (to *Takeout) passTwo(ctx context.Context, dir string, assetChan chan *brow
 
 	// Scan videos
 nextVideo:
-	for _, f := range gen.MapKeys(catalog.matchedFiles) {
+	for _, f := range files {
 		fExt := path.Ext(f)
 		if to.sm.TypeFromExt(fExt) == immich.TypeVideo {
 			name := strings.TrimSuffix(f, fExt)
-			for i, linked := range linkedFiles {
+			for _, i := range images {
+				linked := linkedFiles[i]
 				if linked.image == nil {
 					continue
 				}
@@ -534,7 +596,9 @@ nextVideo:
 		}
 	}
 
-	for _, base := range gen.MapKeys(linkedFiles) {
+	bases := gen.MapKeys(linkedFiles)
+	sort.Strings(bases)
+	for _, base := range bases {
 		var a *browser.LocalAssetFile
 		var err error
 
@@ -0,0 +1,76 @@
+package gp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/fakefs"
+)
+
+func TestGeneratedTakeout(t *testing.T) {
+	opt := fakefs.DefaultTakeoutOptions()
+	opt.PartSize = 200_000_000
+	checkGeneratedTakeout(t, opt)
+}
+
+func FuzzGeneratedTakeout(f *testing.F) {
+	f.Add(int64(1), 0.1, 0.1)
+	f.Add(int64(2), 0.5, 0.0)
+	f.Fuzz(func(t *testing.T, seed int64, longNames float64, duplicates float64) {
+		opt := fakefs.DefaultTakeoutOptions()
+		opt.Seed, opt.Photos, opt.LongNameRatio, opt.DuplicateRatio = seed, 200, longNames, duplicates
+		opt.PartSize = 50_000_000
+		checkGeneratedTakeout(t, opt)
+	})
+}
+
+// checkGeneratedTakeout checks that each file of the generated takeout is found with its title
+func checkGeneratedTakeout(t *testing.T, opt fakefs.TakeoutOptions) {
+	fsyss, assets := fakefs.GenerateTakeout(opt)
+
+	// the expected title of each file, the files without JSON are found only when they are numbered duplicates
+	want := map[string]string{}
+	hasJSON := map[string]bool{}
+	for _, a := range assets {
+		for i, f := range a.Files {
+			want[f] = a.Title
+			hasJSON[f] = a.JSONs[i] != ""
+		}
+	}
+
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	to, err := NewTakeout(ctx, fileevent.NewRecorder(log, false), immich.DefaultSupportedMedia, fsyss...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = to.Prepare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for a := range to.Browse(ctx) {
+		got[a.FileName] = a.Title
+		if a.LivePhoto != nil {
+			got[a.LivePhoto.FileName] = a.LivePhoto.Title
+		}
+	}
+	for f, title := range want {
+		g, ok := got[f]
+		switch {
+		case !ok && hasJSON[f]:
+			t.Errorf("%s not found", f)
+		case ok && g != title:
+			t.Errorf("%s: expected title %q, got %q", f, title, g)
+		}
+	}
+	for f := range got {
+		if _, ok := want[f]; !ok {
+			t.Errorf("%s unexpected", f)
+		}
+	}
+}
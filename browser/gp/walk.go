@@ -0,0 +1,136 @@
+package gp
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// DefaultWorkers is the default number of folders walked concurrently
+const DefaultWorkers = 4
+
+// walkUnit is a part of a takeout walked by a worker: the files of a folder, and the ones of its sub-folders when recursive
+type walkUnit struct {
+	fsys      fs.FS
+	dir       string
+	recursive bool
+	entries   []fs.DirEntry // entries of a non recursive folder, already read
+
+	files []walkedFile  // files found, in the walking order
+	err   error         // error that stopped the walk
+	done  chan struct{} // closed when the walk is completed
+}
+
+// walkedFile is a file found by a worker, with the information read from the file system
+type walkedFile struct {
+	name string
+	size int64
+	md   *GoogleMetaData // content of the JSON files, nil when it can't be read
+	err  error           // error when getting the file's information
+}
+
+// splitWalk splits the walk of a takeout part into independent units.
+// The folders having a single sub-folder, like Takeout/Google Photos, are entered
+// to walk concurrently the folders of the years and the albums.
+func splitWalk(fsys fs.FS) ([]*walkUnit, error) {
+	units := []*walkUnit{}
+	dir := "."
+	for {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, &walkUnit{fsys: fsys, dir: dir, entries: entries})
+		subDirs := []string{}
+		for _, e := range entries {
+			if e.IsDir() {
+				subDirs = append(subDirs, path.Join(dir, e.Name()))
+			}
+		}
+		if len(subDirs) != 1 {
+			for _, d := range subDirs {
+				units = append(units, &walkUnit{fsys: fsys, dir: d, recursive: true})
+			}
+			return units, nil
+		}
+		dir = subDirs[0]
+	}
+}
+
+// walkUnits walks the units with a pool of workers. The done channel of each unit is closed when its walk is completed.
+// merged counts the units added to the catalog: the next units wait while the memory exceeds the budget
+// and walked units are waiting to be merged.
+func (to *Takeout) walkUnits(ctx context.Context, units []*walkUnit, merged *atomic.Int64) {
+	for _, u := range units {
+		u.done = make(chan struct{})
+	}
+	jobs := make(chan *walkUnit)
+	for i := 0; i < max(to.workers, 1); i++ {
+		go func() {
+			for u := range jobs {
+				u.walk(ctx)
+				close(u.done)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i, u := range units {
+			if to.memory.Wait(ctx, func() bool { return merged.Load() < int64(i) }) != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- u:
+			}
+		}
+	}()
+}
+
+// walk collects the files of the unit, and reads the JSON files
+func (u *walkUnit) walk(ctx context.Context) {
+	add := func(name string, d fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f := walkedFile{name: name}
+		info, err := d.Info()
+		if err != nil {
+			f.err = err
+		} else {
+			f.size = info.Size()
+			if strings.ToLower(path.Ext(name)) == ".json" {
+				f.md, _ = fshelper.ReadJSON[GoogleMetaData](u.fsys, name)
+			}
+		}
+		u.files = append(u.files, f)
+		return nil
+	}
+
+	if !u.recursive {
+		for _, d := range u.entries {
+			if d.IsDir() {
+				continue
+			}
+			u.err = add(path.Join(u.dir, d.Name()), d)
+			if u.err != nil {
+				return
+			}
+		}
+		return
+	}
+	u.err = fs.WalkDir(u.fsys, u.dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return add(name, d)
+	})
+}
@@ -0,0 +1,88 @@
+package gp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/membudget"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/fakefs"
+)
+
+func TestSplitWalk(t *testing.T) {
+	fsyss, err := fakefs.ScanStringList("2006-01-02 15:04", `Archive: takeout-001.zip
+   145804  2024-05-25 22:15   Takeout/archive_browser.html
+   145804  2024-05-25 22:15   Takeout/Google Photos/Photos from 2020/IMG_1.jpg
+   145804  2024-05-25 22:15   Takeout/Google Photos/Photos from 2021/IMG_2.jpg
+   145804  2024-05-25 22:15   Takeout/Google Photos/Holidays/IMG_2.jpg
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	units, err := splitWalk(fsyss[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, u := range units {
+		if u.recursive {
+			got = append(got, u.dir+"/**")
+		} else {
+			got = append(got, u.dir)
+		}
+	}
+	want := []string{
+		".",
+		"Takeout",
+		"Takeout/Google Photos",
+		"Takeout/Google Photos/Holidays/**",
+		"Takeout/Google Photos/Photos from 2020/**",
+		"Takeout/Google Photos/Photos from 2021/**",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitWalk() = %v, want %v", got, want)
+	}
+}
+
+// TestConcurrentWalk checks that the assets are the same, in the same order, whatever the number of workers
+func TestConcurrentWalk(t *testing.T) {
+	opt := fakefs.DefaultTakeoutOptions()
+	opt.Photos = 500
+	opt.PartSize = 50_000_000
+	fsyss, _ := fakefs.GenerateTakeout(opt)
+
+	browse := func(workers int, memory *membudget.Budget) []string {
+		ctx := context.Background()
+		to, err := NewTakeout(ctx, fileevent.NewRecorder(nil, false), immich.DefaultSupportedMedia, fsyss...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		to.SetWorkers(workers)
+		to.SetMemoryBudget(memory)
+		err = to.Prepare(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for a := range to.Browse(ctx) {
+			names = append(names, a.FileName+" "+a.Title)
+		}
+		return names
+	}
+
+	want := browse(1, nil)
+	if len(want) == 0 {
+		t.Fatal("no asset found")
+	}
+	for _, workers := range []int{2, 8} {
+		if got := browse(workers, nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers: the assets differ from the sequential walk", workers)
+		}
+	}
+	// a budget always exceeded holds the walkers without blocking the catalog
+	if got := browse(8, membudget.New(1)); !reflect.DeepEqual(got, want) {
+		t.Errorf("the assets depend on the memory budget")
+	}
+}
@@ -1,11 +1,14 @@
 package browser
 
 import (
+	"crypto/sha1"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
-	"os"
+	"path"
 	"time"
 
 	"github.com/simulot/immich-go/helpers/fshelper"
@@ -28,12 +31,15 @@ import (
 
 type LocalAssetFile struct {
 	// Common fields
-	FileName string               // The asset's path in the fsys
-	Title    string               // Google Photos may a have title longer than the filename
-	Albums   []LocalAlbum         // The asset's album, if any
-	Err      error                // keep errors encountered
-	SideCar  metadata.SideCarFile // sidecar file if found
-	Metadata metadata.Metadata    // Metadata fields
+	FileName   string               // The asset's path in the fsys
+	Title      string               // Google Photos may a have title longer than the filename
+	Albums     []LocalAlbum         // The asset's album, if any
+	Err        error                // keep errors encountered
+	SideCar    metadata.SideCarFile // sidecar file if found
+	Metadata   metadata.Metadata    // Metadata fields
+	ContentExt string               // Extension matching the file's content, when it differs from the file name's
+	DateFixed  bool                 // The date of capture has been corrected, the asset's own sidecar has the original one
+	Shifted    bool                 // The -time-shift options have been applied, once for all the servers
 
 	// Google Photos flags
 	Trashed     bool // The asset is trashed
@@ -45,14 +51,14 @@ type LocalAssetFile struct {
 	LivePhoto   *LocalAssetFile // Local asset of the movie part
 	LivePhotoID string          // ID of the movie part, just uploaded
 
-	FSys     fs.FS // Asset's file system
-	FileSize int   // File size in bytes
+	FSys     fs.FS  // Asset's file system
+	FileSize int    // File size in bytes
+	Checksum string // SHA1 of the file, base64 encoded, when computed
 
-	// buffer management
-	sourceFile fs.File   // the opened source file
-	tempFile   *os.File  // buffer that keep partial reads available for the full file reading
-	teeReader  io.Reader // write each read from it into the tempWriter
-	reader     io.Reader // the reader that combines the partial read and original file for full file reading
+	// file management
+	sourceFile  fs.File   // the file opened for the full reading
+	partialFile fs.File   // the file opened to read the beginning of the asset
+	hasher      hash.Hash // computes the checksum during the full reading, when it isn't known
 }
 
 func (l LocalAssetFile) DebugObject() any {
@@ -60,6 +66,14 @@ func (l LocalAssetFile) DebugObject() any {
 	return l
 }
 
+// Ext gives the extension of the file, the one matching its content when known
+func (l *LocalAssetFile) Ext() string {
+	if l.ContentExt != "" {
+		return l.ContentExt
+	}
+	return path.Ext(l.FileName)
+}
+
 func (l *LocalAssetFile) AddAlbum(album LocalAlbum) {
 	for _, al := range l.Albums {
 		if al == album {
@@ -81,36 +95,56 @@ func (l *LocalAssetFile) DeviceAssetID() string {
 	return fmt.Sprintf("%s-%d", l.Title, l.FileSize)
 }
 
-// PartialSourceReader open a reader on the current asset.
-// each byte read from it is saved into a temporary file.
-//
-// It returns a TeeReader that writes each read byte from the source into the temporary file.
-// The temporary file is discarded when the LocalAssetFile is closed
+// ComputeChecksum reads the whole file to compute its SHA1 checksum
+func (l *LocalAssetFile) ComputeChecksum() error {
+	if l.Checksum != "" {
+		return nil
+	}
+	f, err := l.FSys.Open(l.FileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha1.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	l.Checksum = base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return nil
+}
 
+// PartialSourceReader opens a reader on the beginning of the asset, to read its metadata.
+//
+// Nothing is kept on the disk: the file is opened again by Open for the full reading,
+// so the assets of a zip archive are streamed without being extracted.
+// Call ClosePartialSourceReader once the metadata are read, so the file isn't kept open until the upload.
 func (l *LocalAssetFile) PartialSourceReader() (reader io.Reader, err error) {
-	if l.sourceFile == nil {
-		l.sourceFile, err = l.FSys.Open(l.FileName)
-		if err != nil {
-			return nil, err
-		}
+	if l.partialFile != nil {
+		_ = l.partialFile.Close()
+		l.partialFile = nil
 	}
-	if l.tempFile == nil {
-		l.tempFile, err = os.CreateTemp("", "immich-go_*.tmp")
-		if err != nil {
-			return nil, err
-		}
-		if l.teeReader == nil {
-			l.teeReader = io.TeeReader(l.sourceFile, l.tempFile)
-		}
-	}
-	_, err = l.tempFile.Seek(0, 0)
+	l.partialFile, err = l.FSys.Open(l.FileName)
 	if err != nil {
 		return nil, err
 	}
-	return io.MultiReader(l.tempFile, l.teeReader), nil
+	return l.partialFile, nil
+}
+
+// ClosePartialSourceReader closes the reader opened by PartialSourceReader
+func (l *LocalAssetFile) ClosePartialSourceReader() error {
+	if l.partialFile == nil {
+		return nil
+	}
+	err := l.partialFile.Close()
+	l.partialFile = nil
+	return err
 }
 
-// Open return fs.File that reads previously read bytes followed by the actual file content.
+// Open return fs.File that reads the file content from its beginning.
+//
+// When the checksum isn't known, it is computed while the file is read: it is set once the whole file has been read,
+// so the upload gives the checksum without reading the file a second time.
 func (l *LocalAssetFile) Open() (fs.File, error) {
 	var err error
 	if l.sourceFile == nil {
@@ -118,37 +152,36 @@ func (l *LocalAssetFile) Open() (fs.File, error) {
 		if err != nil {
 			return nil, err
 		}
-	}
-	if l.tempFile != nil {
-		_, err = l.tempFile.Seek(0, 0)
-		if err != nil {
-			return nil, err
+		l.hasher = nil
+		if l.Checksum == "" {
+			l.hasher = sha1.New()
 		}
-		l.reader = io.MultiReader(l.tempFile, l.sourceFile)
-	} else {
-		l.reader = l.sourceFile
 	}
 	return l, nil
 }
 
 // Read
 func (l *LocalAssetFile) Read(b []byte) (int, error) {
-	return l.reader.Read(b)
+	n, err := l.sourceFile.Read(b)
+	if l.hasher != nil {
+		l.hasher.Write(b[:n])
+		if err == io.EOF {
+			l.Checksum = base64.StdEncoding.EncodeToString(l.hasher.Sum(nil))
+			l.hasher = nil
+		}
+	}
+	return n, err
 }
 
-// Close close the temporary file  and close the source
+// Close closes the files opened for the asset
 func (l *LocalAssetFile) Close() error {
 	var err error
 	if l.sourceFile != nil {
 		err = errors.Join(err, l.sourceFile.Close())
 		l.sourceFile = nil
+		l.hasher = nil
 	}
-	if l.tempFile != nil {
-		f := l.tempFile.Name()
-		err = errors.Join(err, l.tempFile.Close())
-		err = errors.Join(err, os.Remove(f))
-		l.tempFile = nil
-	}
+	err = errors.Join(err, l.ClosePartialSourceReader())
 	return err
 }
 
@@ -0,0 +1,96 @@
+package browser
+
+import (
+	"io"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// TestPartialSourceReader checks that the file is read from its beginning after a partial read, without temporary file
+func TestPartialSourceReader(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	content := "the header of the file, followed by its content"
+	la := &LocalAssetFile{
+		FSys:     fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte(content)}},
+		FileName: "photo.jpg",
+		FileSize: len(content),
+	}
+
+	for i := 0; i < 2; i++ {
+		r, err := la.PartialSourceReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 10)
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content[:10] {
+			t.Errorf("partial read: got %q, want %q", b, content[:10])
+		}
+	}
+
+	f, err := la.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content {
+		t.Errorf("full read: got %q, want %q", b, content)
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("unexpected temporary file %s", entries[0].Name())
+	}
+	err = la.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestChecksumWhileReading checks that the checksum is computed when the whole file is read
+func TestChecksumWhileReading(t *testing.T) {
+	content := "the content of the photo"
+	fsys := fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte(content)}}
+	want := &LocalAssetFile{FSys: fsys, FileName: "photo.jpg"}
+	err := want.ComputeChecksum()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	la := &LocalAssetFile{FSys: fsys, FileName: "photo.jpg", FileSize: len(content)}
+	f, err := la.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadFull(f, make([]byte, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if la.Checksum != "" {
+		t.Error("the checksum is given before the end of the file")
+	}
+	la.Close()
+
+	f, err = la.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	la.Close()
+	if la.Checksum != want.Checksum {
+		t.Errorf("checksum: got %q, want %q", la.Checksum, want.Checksum)
+	}
+}
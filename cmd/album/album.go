@@ -17,11 +17,36 @@ func AlbumCommand(ctx context.Context, common *cmd.SharedFlags, args []string) e
 		cmd := args[0]
 		args = args[1:]
 
-		if cmd == "delete" {
+		switch cmd {
+		case "delete":
 			return deleteAlbum(ctx, common, args)
+		case "export":
+			return exportAlbum(ctx, common, args)
+		case "merge":
+			return mergeAlbums(ctx, common, args)
+		case "rename":
+			return renameAlbums(ctx, common, args)
+		case "prune-empty":
+			return pruneEmptyAlbums(ctx, common, args)
 		}
 	}
-	return fmt.Errorf("tool album need a command: delete")
+	return fmt.Errorf("tool album need a command: delete|export|merge|rename|prune-empty")
+}
+
+// parseArgs parses the flags placed before and after the names of the albums, and gives the names
+func parseArgs(cmd *flag.FlagSet, args []string) ([]string, error) {
+	var names []string
+	for {
+		err := cmd.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.NArg() == 0 {
+			return names, nil
+		}
+		names = append(names, cmd.Arg(0))
+		args = cmd.Args()[1:]
+	}
 }
 
 type DeleteAlbumCmd struct {
@@ -34,7 +59,7 @@ func deleteAlbum(ctx context.Context, common *cmd.SharedFlags, args []string) er
 	app := &DeleteAlbumCmd{
 		SharedFlags: common,
 	}
-	cmd := flag.NewFlagSet("album delete", flag.ExitOnError)
+	cmd := flag.NewFlagSet("album delete", flag.ContinueOnError)
 	app.SharedFlags.SetFlags(cmd)
 
 	cmd.BoolFunc("yes", "When true, assume Yes to all actions", func(s string) error {
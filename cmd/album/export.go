@@ -0,0 +1,201 @@
+package album
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+type ExportAlbumCmd struct {
+	*cmd.SharedFlags
+	Output string // Name of the zip file
+
+	dl    *download.DownloadCmd // selection of the assets and sidecars
+	names map[string]bool       // names of the entries already in the zip
+}
+
+// exportAlbum writes the assets of the albums and their sidecars into a zip file, one folder per album
+func exportAlbum(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app := &ExportAlbumCmd{
+		SharedFlags: common,
+		dl:          download.NewDownloader(common),
+		names:       map[string]bool{},
+	}
+	cmd := flag.NewFlagSet("album export", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.StringVar(&app.Output, "o", "", "Name of the zip file")
+	cmd.Var(&app.dl.DateRange, "date", "Export only the assets having a capture date in that range.")
+	cmd.StringVar(&app.dl.Sidecar, "sidecar", download.SidecarXMP, "Write the metadata of the assets into a XMP or JSON sidecar file, or NONE. (default: XMP)")
+	names, err := parseArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return errors.New("the album export command needs the names of the albums")
+	}
+	if app.Output == "" {
+		return errors.New("the album export command needs the name of the zip file: -o FILE")
+	}
+	app.dl.Albums = names
+	err = app.dl.Validate("")
+	if err != nil {
+		return err
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *ExportAlbumCmd) run(ctx context.Context) error {
+	albums, err := app.Immich.GetAllAlbums(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the albums list: %w", err)
+	}
+	for _, want := range app.dl.Albums {
+		found := false
+		for _, al := range albums {
+			if al.AlbumName == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("album %q not found", want)
+		}
+	}
+	err = app.dl.ReadAlbums(ctx)
+	if err != nil {
+		return err
+	}
+
+	var assets []*immich.Asset
+	err = app.dl.GetAssets(ctx, func(a *immich.Asset) error {
+		assets = append(assets, a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(app.Output)
+	if err != nil {
+		return err
+	}
+	z := zip.NewWriter(f)
+	count := 0
+	for _, a := range assets {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			break
+		}
+		err = app.exportAsset(ctx, z, a)
+		if err != nil {
+			err = fmt.Errorf("can't export %s: %w", a.OriginalFileName, err)
+			break
+		}
+		count++
+	}
+	err = errors.Join(err, z.Close(), f.Close())
+	if err != nil {
+		_ = os.Remove(app.Output)
+		return err
+	}
+	fmt.Printf("%d asset(s) exported into %s\n", count, app.Output)
+	return nil
+}
+
+// exportAsset writes the asset, its Live Photo video and its sidecar in the folder of each of the exported albums it belongs to
+func (app *ExportAlbumCmd) exportAsset(ctx context.Context, z *zip.Writer, a *immich.Asset) error {
+	date := download.AssetDate(a)
+	sidecarExt, sidecar, err := app.dl.SidecarFile(a)
+	if err != nil {
+		return err
+	}
+
+	for _, album := range app.dl.Albums {
+		if !slices.Contains(app.dl.AssetAlbums(a.ID), album) {
+			continue
+		}
+		name := app.entryName(path.Join(download.CleanName(album, "Untitled"), download.CleanName(a.OriginalFileName, a.ID)))
+		app.Log.Info("export", "file", name, "id", a.ID)
+		err = app.writeEntry(ctx, z, a.ID, name, date)
+		if err != nil {
+			return err
+		}
+		base := strings.TrimSuffix(name, path.Ext(name))
+		if a.LivePhotoVideoID != "" {
+			err = app.writeEntry(ctx, z, a.LivePhotoVideoID, base, date)
+			if err != nil {
+				return err
+			}
+		}
+		if sidecarExt != "" {
+			w, err := z.CreateHeader(&zip.FileHeader{Name: name + sidecarExt, Method: zip.Deflate, Modified: date})
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(sidecar)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeEntry copies the content of the asset into the zip. When name has no extension, the entry gets the extension matching its content.
+func (app *ExportAlbumCmd) writeEntry(ctx context.Context, z *zip.Writer, id string, name string, date time.Time) error {
+	r, err := app.Immich.DownloadAsset(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	br := bufio.NewReader(r)
+	if path.Ext(name) == "" {
+		b, _ := br.Peek(metadata.SniffSize)
+		ext := strings.ToUpper(metadata.SniffExtension(b))
+		if ext == "" {
+			ext = ".MOV"
+		}
+		name = app.entryName(name + ext)
+	}
+	// photos and videos are already compressed
+	w, err := z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: date})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, br)
+	return err
+}
+
+// entryName gives a name not yet used in the zip, suffixed by a number when needed
+func (app *ExportAlbumCmd) entryName(name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 0; ; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		if !app.names[candidate] {
+			app.names[candidate] = true
+			return candidate
+		}
+	}
+}
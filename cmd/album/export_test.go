@@ -0,0 +1,105 @@
+package album
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+type icExport struct {
+	fakeimmich.MockedCLient
+	assets  []*immich.Asset
+	content map[string]string
+	albums  map[string][]string // album -> asset IDs
+}
+
+func (c *icExport) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icExport) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	return c.GetAllAssetsWithFilter(ctx, fn)
+}
+
+func (c *icExport) DownloadAsset(ctx context.Context, id string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(c.content[id])), nil
+}
+
+func (c *icExport) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	var albums []immich.AlbumSimplified
+	for name := range c.albums {
+		albums = append(albums, immich.AlbumSimplified{ID: name, AlbumName: name})
+	}
+	return albums, nil
+}
+
+func (c *icExport) GetAlbumInfo(ctx context.Context, id string, withoutAssets bool) (immich.AlbumContent, error) {
+	content := immich.AlbumContent{ID: id, AlbumName: id}
+	for _, a := range c.albums[id] {
+		content.Assets = append(content.Assets, immich.AssetSimplified{ID: a})
+	}
+	return content, nil
+}
+
+func TestExportAlbum(t *testing.T) {
+	ic := &icExport{
+		content: map[string]string{"a": "aaa", "b": "bbb", "c": "ccc"},
+		albums:  map[string][]string{"Summer 2023": {"a", "b"}, "Other": {"c"}},
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		a := &immich.Asset{ID: id, OriginalFileName: "IMG.jpg", Type: "IMAGE"}
+		a.ExifInfo.DateTimeOriginal.Time = time.Date(2023, 7, 1, 10, 0, 0, 0, time.UTC)
+		ic.assets = append(ic.assets, a)
+	}
+
+	common := &cmd.SharedFlags{
+		Immich: ic,
+		Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Jnl:    fileevent.NewRecorder(nil, false),
+	}
+	app := &ExportAlbumCmd{
+		SharedFlags: common,
+		Output:      filepath.Join(t.TempDir(), "summer.zip"),
+		dl:          download.NewDownloader(common),
+		names:       map[string]bool{},
+	}
+	app.dl.Albums = []string{"Summer 2023"}
+	if err := app.dl.Validate(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.OpenReader(app.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	var names []string
+	for _, f := range z.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	want := []string{"Summer 2023/IMG (1).jpg", "Summer 2023/IMG (1).jpg.xmp", "Summer 2023/IMG.jpg", "Summer 2023/IMG.jpg.xmp"}
+	if strings.Join(names, "|") != strings.Join(want, "|") {
+		t.Errorf("zip content %v, want %v", names, want)
+	}
+}
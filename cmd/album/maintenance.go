@@ -0,0 +1,265 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/ui"
+)
+
+// MaintenanceCmd merges, renames and prunes the albums left by the migrations
+type MaintenanceCmd struct {
+	*cmd.SharedFlags
+	AssumeYes bool
+	DryRun    bool   // Display the actions but don't change anything
+	Into      string // merge: name of the album receiving the assets
+	Regex     bool   // rename: the name is a regular expression, and the new name may refer to its groups
+
+	albums []immich.AlbumSimplified // server's albums, sorted by name
+}
+
+func newMaintenanceCmd(ctx context.Context, common *cmd.SharedFlags, name string, args []string) (*MaintenanceCmd, []string, error) {
+	app := &MaintenanceCmd{
+		SharedFlags: common,
+	}
+	cmd := flag.NewFlagSet("album "+name, flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.BoolFunc("yes", "When true, assume Yes to all actions", myflag.BoolFlagFn(&app.AssumeYes, false))
+	cmd.BoolFunc("dry-run", "Display the actions but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	switch name {
+	case "merge":
+		cmd.StringVar(&app.Into, "into", "", "Name of the album receiving the assets of the merged albums, created when missing")
+	case "rename":
+		cmd.BoolFunc("regex", "The name is a regular expression, and the new name can refer to its groups with $1, $2...", myflag.BoolFlagFn(&app.Regex, false))
+	}
+	args, err := parseArgs(cmd, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	app.albums, err = app.Immich.GetAllAlbums(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't get the albums list: %w", err)
+	}
+	sort.Slice(app.albums, func(i, j int) bool {
+		return app.albums[i].AlbumName < app.albums[j].AlbumName
+	})
+	return app, args, nil
+}
+
+// confirm asks the user, unless -yes is given
+func (app *MaintenanceCmd) confirm(ctx context.Context) (bool, error) {
+	if app.AssumeYes {
+		return true, nil
+	}
+	r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
+	return r == "y", err
+}
+
+// albumByName gives the album having that name
+func (app *MaintenanceCmd) albumByName(name string) (immich.AlbumSimplified, bool) {
+	for _, al := range app.albums {
+		if al.AlbumName == name {
+			return al, true
+		}
+	}
+	return immich.AlbumSimplified{}, false
+}
+
+// mergeAlbums moves the assets of the albums matching the patterns into the -into album, and deletes the merged albums
+func mergeAlbums(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, patterns, err := newMaintenanceCmd(ctx, common, "merge", args)
+	if err != nil {
+		return err
+	}
+	return app.merge(ctx, patterns)
+}
+
+func (app *MaintenanceCmd) merge(ctx context.Context, patterns []string) error {
+	if len(patterns) == 0 {
+		return errors.New("the album merge command needs the names of the albums to merge")
+	}
+	if app.Into == "" {
+		return errors.New("the album merge command needs the name of the album receiving the assets: -into NAME")
+	}
+	var sources []immich.AlbumSimplified
+	for _, al := range app.albums {
+		if al.AlbumName == app.Into {
+			continue
+		}
+		for _, p := range patterns {
+			if ok, err := path.Match(p, al.AlbumName); err != nil {
+				return fmt.Errorf("album pattern %q can't be parsed: %w", p, err)
+			} else if ok {
+				sources = append(sources, al)
+				break
+			}
+		}
+	}
+	if len(sources) == 0 {
+		fmt.Println("No album to merge")
+		return nil
+	}
+
+	fmt.Printf("Merge into the album '%s':\n", app.Into)
+	for _, al := range sources {
+		fmt.Printf("  %s\n", al.AlbumName)
+	}
+	if app.DryRun {
+		return nil
+	}
+	if ok, err := app.confirm(ctx); err != nil || !ok {
+		return err
+	}
+
+	var ids []string
+	seen := map[string]bool{}
+	for _, al := range sources {
+		content, err := app.Immich.GetAlbumInfo(ctx, al.ID, false)
+		if err != nil {
+			return fmt.Errorf("can't get the assets of the album '%s': %w", al.AlbumName, err)
+		}
+		for _, a := range content.Assets {
+			if !seen[a.ID] {
+				seen[a.ID] = true
+				ids = append(ids, a.ID)
+			}
+		}
+	}
+
+	if target, ok := app.albumByName(app.Into); ok {
+		_, err := app.Immich.AddAssetToAlbum(ctx, target.ID, ids)
+		if err != nil {
+			return fmt.Errorf("can't add the assets to the album '%s': %w", app.Into, err)
+		}
+	} else {
+		_, err := app.Immich.CreateAlbum(ctx, app.Into, "", ids)
+		if err != nil {
+			return fmt.Errorf("can't create the album '%s': %w", app.Into, err)
+		}
+	}
+	for _, al := range sources {
+		err := app.Immich.DeleteAlbum(ctx, al.ID)
+		if err != nil {
+			return fmt.Errorf("can't delete the album '%s': %w", al.AlbumName, err)
+		}
+	}
+	fmt.Printf("%d album(s) merged into '%s', %d asset(s)\n", len(sources), app.Into, len(ids))
+	return nil
+}
+
+// renameAlbums renames the album, or the albums matching the regular expression with -regex
+func renameAlbums(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, args, err := newMaintenanceCmd(ctx, common, "rename", args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return errors.New("the album rename command needs the name of the album and its new name")
+	}
+	return app.rename(ctx, args[0], args[1])
+}
+
+func (app *MaintenanceCmd) rename(ctx context.Context, name string, newName string) error {
+	var re *regexp.Regexp
+	if app.Regex {
+		var err error
+		re, err = regexp.Compile(name)
+		if err != nil {
+			return fmt.Errorf("album pattern %q can't be parsed: %w", name, err)
+		}
+	}
+
+	renamed := 0
+	for _, al := range app.albums {
+		target := newName
+		if re != nil {
+			if !re.MatchString(al.AlbumName) {
+				continue
+			}
+			target = re.ReplaceAllString(al.AlbumName, newName)
+		} else if al.AlbumName != name {
+			continue
+		}
+		if target == al.AlbumName {
+			continue
+		}
+		if _, exists := app.albumByName(target); exists {
+			fmt.Printf("The album '%s' can't be renamed '%s': the album exists, merge them with the album merge command\n", al.AlbumName, target)
+			continue
+		}
+		fmt.Printf("Rename the album '%s' into '%s'\n", al.AlbumName, target)
+		if app.DryRun {
+			continue
+		}
+		ok, err := app.confirm(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		_, err = app.Immich.UpdateAlbum(ctx, al.ID, immich.AlbumUpdate{AlbumName: target})
+		if err != nil {
+			return fmt.Errorf("can't rename the album '%s': %w", al.AlbumName, err)
+		}
+		renamed++
+	}
+	fmt.Printf("%d album(s) renamed\n", renamed)
+	return nil
+}
+
+// pruneEmptyAlbums deletes the albums without assets
+func pruneEmptyAlbums(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, _, err := newMaintenanceCmd(ctx, common, "prune-empty", args)
+	if err != nil {
+		return err
+	}
+	return app.pruneEmpty(ctx)
+}
+
+func (app *MaintenanceCmd) pruneEmpty(ctx context.Context) error {
+	deleted := 0
+	for _, al := range app.albums {
+		if al.AssetCount > 0 {
+			continue
+		}
+		// double check, the count isn't given by all the servers
+		content, err := app.Immich.GetAlbumInfo(ctx, al.ID, false)
+		if err != nil {
+			return fmt.Errorf("can't get the assets of the album '%s': %w", al.AlbumName, err)
+		}
+		if len(content.Assets) > 0 {
+			continue
+		}
+		fmt.Printf("Delete the empty album '%s'\n", al.AlbumName)
+		if app.DryRun {
+			continue
+		}
+		ok, err := app.confirm(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		err = app.Immich.DeleteAlbum(ctx, al.ID)
+		if err != nil {
+			return fmt.Errorf("can't delete the album '%s': %w", al.AlbumName, err)
+		}
+		deleted++
+	}
+	fmt.Printf("%d empty album(s) deleted\n", deleted)
+	return nil
+}
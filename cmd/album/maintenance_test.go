@@ -0,0 +1,159 @@
+package album
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icAlbums keeps the albums of the server in memory
+type icAlbums struct {
+	fakeimmich.MockedCLient
+	albums map[string][]string // album name -> asset IDs
+}
+
+func (c *icAlbums) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	var albums []immich.AlbumSimplified
+	for name, ids := range c.albums {
+		albums = append(albums, immich.AlbumSimplified{ID: name, AlbumName: name, AssetCount: len(ids)})
+	}
+	return albums, nil
+}
+
+func (c *icAlbums) GetAlbumInfo(ctx context.Context, id string, withoutAssets bool) (immich.AlbumContent, error) {
+	content := immich.AlbumContent{ID: id, AlbumName: id}
+	for _, a := range c.albums[id] {
+		content.Assets = append(content.Assets, immich.AssetSimplified{ID: a})
+	}
+	return content, nil
+}
+
+func (c *icAlbums) AddAssetToAlbum(ctx context.Context, id string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	for _, a := range ids {
+		if !slices.Contains(c.albums[id], a) {
+			c.albums[id] = append(c.albums[id], a)
+		}
+	}
+	return nil, nil
+}
+
+func (c *icAlbums) CreateAlbum(ctx context.Context, name string, description string, ids []string) (immich.AlbumSimplified, error) {
+	c.albums[name] = ids
+	return immich.AlbumSimplified{ID: name, AlbumName: name}, nil
+}
+
+func (c *icAlbums) UpdateAlbum(ctx context.Context, id string, update immich.AlbumUpdate) (immich.AlbumSimplified, error) {
+	c.albums[update.AlbumName] = c.albums[id]
+	delete(c.albums, id)
+	return immich.AlbumSimplified{ID: update.AlbumName, AlbumName: update.AlbumName}, nil
+}
+
+func (c *icAlbums) DeleteAlbum(ctx context.Context, id string) error {
+	delete(c.albums, id)
+	return nil
+}
+
+func TestAlbumMaintenance(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, app *MaintenanceCmd) error
+		want map[string][]string
+	}{
+		{
+			name: "merge into existing",
+			run: func(ctx context.Context, app *MaintenanceCmd) error {
+				app.Into = "Summer 2023"
+				return app.merge(ctx, []string{"Summer 2023*"})
+			},
+			want: map[string][]string{"Summer 2023": {"1", "2", "3"}, "2023-08-01": {}, "Winter": {"4"}},
+		},
+		{
+			name: "merge into new",
+			run: func(ctx context.Context, app *MaintenanceCmd) error {
+				app.Into = "2023"
+				return app.merge(ctx, []string{"Summer 2023*", "Winter"})
+			},
+			want: map[string][]string{"2023": {"1", "2", "3", "4"}, "2023-08-01": {}},
+		},
+		{
+			name: "merge dry run",
+			run: func(ctx context.Context, app *MaintenanceCmd) error {
+				app.Into = "Summer 2023"
+				app.DryRun = true
+				return app.merge(ctx, []string{"Summer*"})
+			},
+			want: map[string][]string{"Summer 2023": {"1"}, "Summer 2023 (1)": {"2", "3"}, "2023-08-01": {}, "Winter": {"4"}},
+		},
+		{
+			name: "rename",
+			run: func(ctx context.Context, app *MaintenanceCmd) error {
+				return app.rename(ctx, "Winter", "Winter 2023")
+			},
+			want: map[string][]string{"Summer 2023": {"1"}, "Summer 2023 (1)": {"2", "3"}, "2023-08-01": {}, "Winter 2023": {"4"}},
+		},
+		{
+			name: "rename regex",
+			run: func(ctx context.Context, app *MaintenanceCmd) error {
+				app.Regex = true
+				return app.rename(ctx, `^(\d{4})-(\d{2})-(\d{2})$`, "$3/$2/$1")
+			},
+			want: map[string][]string{"Summer 2023": {"1"}, "Summer 2023 (1)": {"2", "3"}, "01/08/2023": {}, "Winter": {"4"}},
+		},
+		{
+			name: "rename existing",
+			run: func(ctx context.Context, app *MaintenanceCmd) error {
+				return app.rename(ctx, "Summer 2023 (1)", "Summer 2023")
+			},
+			want: map[string][]string{"Summer 2023": {"1"}, "Summer 2023 (1)": {"2", "3"}, "2023-08-01": {}, "Winter": {"4"}},
+		},
+		{
+			name: "prune empty",
+			run: func(ctx context.Context, app *MaintenanceCmd) error {
+				return app.pruneEmpty(ctx)
+			},
+			want: map[string][]string{"Summer 2023": {"1"}, "Summer 2023 (1)": {"2", "3"}, "Winter": {"4"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icAlbums{albums: map[string][]string{
+				"Summer 2023":     {"1"},
+				"Summer 2023 (1)": {"2", "3"},
+				"2023-08-01":      {},
+				"Winter":          {"4"},
+			}}
+			app := &MaintenanceCmd{SharedFlags: &cmd.SharedFlags{Immich: ic}, AssumeYes: true}
+			app.albums, _ = ic.GetAllAlbums(context.Background())
+			sort.Slice(app.albums, func(i, j int) bool { return app.albums[i].AlbumName < app.albums[j].AlbumName })
+			if err := tt.run(context.Background(), app); err != nil {
+				t.Fatal(err)
+			}
+			for _, l := range ic.albums {
+				sort.Strings(l)
+			}
+			if !reflect.DeepEqual(ic.albums, tt.want) {
+				t.Errorf("expected albums %v, got %v", tt.want, ic.albums)
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	cmd := flag.NewFlagSet("album merge", flag.ContinueOnError)
+	into := cmd.String("into", "", "")
+	yes := cmd.Bool("yes", false, "")
+	names, err := parseArgs(cmd, []string{"Summer 2023*", "-into", "Summer 2023", "Winter", "-yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"Summer 2023*", "Winter"}) || *into != "Summer 2023" || !*yes {
+		t.Errorf("unexpected names %v, into %q, yes %v", names, *into, *yes)
+	}
+}
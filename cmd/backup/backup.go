@@ -0,0 +1,285 @@
+/*
+Back up the server into a local folder, run after run: only the assets added or modified since the previous run are
+fetched from the server and downloaded.
+*/
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+)
+
+// DefaultStateFile is the name of the state file in the backup folder
+const DefaultStateFile = ".immich-go-backup.json"
+
+// updatedMargin widens the search of the modified assets, for the clock differences between the server and the client.
+// The assets fetched again are recognized as unchanged.
+const updatedMargin = time.Hour
+
+type BackupCmd struct {
+	*cmd.SharedFlags
+	StateFile string // File recording the backed up assets (default: DIR/.immich-go-backup.json)
+	Prune     bool   // Remove the files of the assets deleted or trashed on the server
+	FullScan  bool   // Check all the server's assets, not only the ones modified since the previous run
+
+	dl   *download.DownloadCmd // downloader of the server's assets
+	root string                // backup folder
+
+	state                                          backupState
+	downloaded, updated, unchanged, pruned, failed int
+}
+
+// backupState is the content of the state file
+type backupState struct {
+	LastRun time.Time              `json:"lastRun"`
+	Assets  map[string]backupEntry `json:"assets"` // by asset ID
+}
+
+// backupEntry records the files of an asset in the backup folder
+type backupEntry struct {
+	Checksum  string    `json:"checksum"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Files     []string  `json:"files"` // relative to the backup folder, with slashes
+}
+
+func NewBackupCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*BackupCmd, error) {
+	cmd := flag.NewFlagSet("backup", flag.ContinueOnError)
+	app := BackupCmd{
+		SharedFlags: common,
+		dl:          download.NewDownloader(common),
+	}
+	app.SharedFlags.SetFlags(cmd)
+	app.dl.SetFlags(cmd)
+	cmd.StringVar(&app.StateFile, "state-file", "", "File recording the backed up assets. (default: DIR/"+DefaultStateFile+")")
+	cmd.BoolFunc("prune", "Remove the files of the assets deleted or trashed on the server", myflag.BoolFlagFn(&app.Prune, false))
+	cmd.BoolFunc("full-scan", "Check all the server's assets, and download again the files missing in the backup folder", myflag.BoolFlagFn(&app.FullScan, false))
+
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.NArg() != 1 {
+		return nil, errors.New("the backup command needs the backup folder")
+	}
+	err = app.dl.Validate(cmd.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	app.root = cmd.Arg(0)
+	if app.StateFile == "" {
+		app.StateFile = filepath.Join(app.root, DefaultStateFile)
+	}
+
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+func BackupCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewBackupCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *BackupCmd) run(ctx context.Context) error {
+	err := app.readState()
+	if err != nil {
+		return err
+	}
+	startedAt := time.Now()
+
+	fmt.Println("Get server's albums...")
+	err = app.dl.ReadAlbums(ctx)
+	if err != nil {
+		return err
+	}
+	app.dl.UpdatedAfter = time.Time{}
+	if !app.FullScan && !app.state.LastRun.IsZero() {
+		app.dl.UpdatedAfter = app.state.LastRun.Add(-updatedMargin)
+		fmt.Printf("Get server's assets modified since %s...\n", app.state.LastRun.Format(time.DateTime))
+	} else {
+		fmt.Println("Get server's assets...")
+	}
+	var assets []*immich.Asset
+	err = app.dl.GetAssets(ctx, func(a *immich.Asset) error {
+		assets = append(assets, a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range assets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err = app.backupAsset(ctx, a)
+		if err != nil {
+			app.failed++
+			app.Log.Error("can't back up the asset", "file", a.OriginalFileName, "id", a.ID, "error", err.Error())
+		}
+	}
+
+	if app.Prune {
+		// the assets out of the selection are still on the server
+		onServer := map[string]bool{}
+		err = app.Immich.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
+			if !a.IsTrashed {
+				onServer[a.ID] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for id, s := range app.state.Assets {
+			if onServer[id] {
+				continue
+			}
+			app.Log.Info("prune", "id", id, "files", s.Files)
+			err = app.removeFiles(s.Files)
+			if err != nil {
+				app.failed++
+				app.Log.Error("can't remove the files of the asset", "id", id, "error", err.Error())
+				continue
+			}
+			delete(app.state.Assets, id)
+			app.pruned++
+		}
+	}
+
+	fmt.Printf("%d asset(s) downloaded, %d updated, %d unchanged, %d pruned, %d error(s)\n", app.downloaded, app.updated, app.unchanged, app.pruned, app.failed)
+	if app.dl.DryRun {
+		return nil
+	}
+	if app.failed == 0 {
+		// the assets in error are fetched again by the next run
+		app.state.LastRun = startedAt
+	}
+	return app.writeState()
+}
+
+// backupAsset downloads the asset when it is new or modified since the previous run
+func (app *BackupCmd) backupAsset(ctx context.Context, a *immich.Asset) error {
+	s, known := app.state.Assets[a.ID]
+	if known && s.Checksum == a.Checksum && !a.UpdatedAt.After(s.UpdatedAt) && app.filesExist(s.Files) {
+		app.unchanged++
+		return nil
+	}
+	if known && s.Checksum != a.Checksum {
+		// the asset has been replaced on the server, the new version may get another name
+		app.Log.Info("replaced", "id", a.ID, "files", s.Files)
+		err := app.removeFiles(s.Files)
+		if err != nil {
+			return err
+		}
+		s.Files = nil
+	}
+
+	r, err := app.dl.DownloadAsset(ctx, a)
+	if err != nil {
+		return err
+	}
+	files := s.Files
+	for _, f := range r.Files {
+		rel, err := filepath.Rel(app.root, f)
+		if err != nil {
+			return err
+		}
+		files = appendOnce(files, filepath.ToSlash(rel))
+	}
+	if app.state.Assets == nil {
+		app.state.Assets = map[string]backupEntry{}
+	}
+	app.state.Assets[a.ID] = backupEntry{Checksum: a.Checksum, UpdatedAt: a.UpdatedAt.Time, Files: files}
+	if known {
+		app.updated++
+	} else {
+		app.downloaded++
+	}
+	return nil
+}
+
+// filesExist tells if all the files are still in the backup folder
+func (app *BackupCmd) filesExist(files []string) bool {
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(app.root, filepath.FromSlash(f))); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// removeFiles removes the files from the backup folder, the missing files are ignored
+func (app *BackupCmd) removeFiles(files []string) error {
+	if app.dl.DryRun {
+		return nil
+	}
+	var errs error
+	for _, f := range files {
+		err := os.Remove(filepath.Join(app.root, filepath.FromSlash(f)))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
+
+func (app *BackupCmd) readState() error {
+	app.state = backupState{Assets: map[string]backupEntry{}}
+	b, err := os.ReadFile(app.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, &app.state)
+	if err != nil {
+		return fmt.Errorf("can't read the state file %q: %w", app.StateFile, err)
+	}
+	return nil
+}
+
+// writeState writes the state file through a temporary file, so an interrupted write doesn't lose the previous state
+func (app *BackupCmd) writeState() error {
+	b, err := json.MarshalIndent(app.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(app.StateFile), 0o755)
+	if err != nil {
+		return err
+	}
+	tmp := app.StateFile + ".tmp"
+	err = os.WriteFile(tmp, b, 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, app.StateFile)
+}
+
+func appendOnce(l []string, s string) []string {
+	for _, e := range l {
+		if e == s {
+			return l
+		}
+	}
+	return append(l, s)
+}
@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icBackup serves the assets and counts the downloads
+type icBackup struct {
+	fakeimmich.MockedCLient
+	assets    []*immich.Asset
+	content   map[string]string
+	downloads int
+	query     immich.SearchQuery // last search
+}
+
+func (c *icBackup) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icBackup) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	c.query = q
+	return c.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
+		if !q.UpdatedAfter.IsZero() && !a.UpdatedAt.After(q.UpdatedAfter) {
+			return nil
+		}
+		return fn(a)
+	})
+}
+
+func (c *icBackup) DownloadAsset(ctx context.Context, id string) (io.ReadCloser, error) {
+	c.downloads++
+	return io.NopCloser(strings.NewReader(c.content[id])), nil
+}
+
+func (c *icBackup) setAsset(id, name, content string, updated time.Time) {
+	h := sha1.Sum([]byte(content))
+	a := &immich.Asset{ID: id, OriginalFileName: name, Type: "IMAGE", Checksum: base64.StdEncoding.EncodeToString(h[:])}
+	a.ExifInfo.FileSizeInByte = len(content)
+	a.ExifInfo.DateTimeOriginal.Time = time.Date(2023, 6, 1, 10, 0, 0, 0, time.Local)
+	a.UpdatedAt.Time = updated
+	for i := range c.assets {
+		if c.assets[i].ID == id {
+			c.assets[i] = a
+			c.content[id] = content
+			return
+		}
+	}
+	c.assets = append(c.assets, a)
+	c.content[id] = content
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Now().Add(-48 * time.Hour)
+	ic := &icBackup{content: map[string]string{}}
+	ic.setAsset("a", "a.jpg", "aaa", t0)
+	ic.setAsset("b", "b.jpg", "bbb", t0)
+
+	backup := func(prune bool, fullScan bool) *BackupCmd {
+		t.Helper()
+		common := &cmd.SharedFlags{
+			Immich: ic,
+			Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Jnl:    fileevent.NewRecorder(nil, false),
+		}
+		app := &BackupCmd{
+			SharedFlags: common,
+			Prune:       prune,
+			FullScan:    fullScan,
+			StateFile:   filepath.Join(dir, DefaultStateFile),
+			dl:          download.NewDownloader(common),
+			root:        dir,
+		}
+		app.dl.Sidecar = download.SidecarNone
+		if err := app.dl.Validate(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if app.failed != 0 {
+			t.Fatalf("%d error(s)", app.failed)
+		}
+		return app
+	}
+	folder := filepath.Join(dir, "2023", "2023-06")
+
+	app := backup(false, false)
+	if app.downloaded != 2 || ic.downloads != 2 || !ic.query.UpdatedAfter.IsZero() {
+		t.Errorf("first run: downloaded %d, downloads %d, want 2, updated after %s", app.downloaded, ic.downloads, ic.query.UpdatedAfter)
+	}
+
+	// nothing changed on the server: only the modified assets are fetched
+	app = backup(false, false)
+	if app.unchanged != 0 || ic.downloads != 2 || ic.query.UpdatedAfter.IsZero() {
+		t.Errorf("second run: unchanged %d, downloads %d, want 2, updated after %s", app.unchanged, ic.downloads, ic.query.UpdatedAfter)
+	}
+
+	// a is replaced by an edited version, b is deleted, c is new
+	now := time.Now()
+	ic.setAsset("a", "a.jpg", "edited aaa", now)
+	ic.assets = ic.assets[:1]
+	ic.setAsset("c", "c.jpg", "ccc", now)
+	app = backup(false, false)
+	if app.downloaded != 1 || app.updated != 1 || app.pruned != 0 {
+		t.Errorf("third run: downloaded %d, updated %d, pruned %d", app.downloaded, app.updated, app.pruned)
+	}
+	if b, _ := os.ReadFile(filepath.Join(folder, "a.jpg")); string(b) != "edited aaa" {
+		t.Errorf("a.jpg contains %q, want the edited version", string(b))
+	}
+	if _, err := os.Stat(filepath.Join(folder, "b.jpg")); err != nil {
+		t.Errorf("b.jpg is removed without -prune")
+	}
+
+	app = backup(true, true)
+	if app.pruned != 1 || app.unchanged != 2 || !ic.query.UpdatedAfter.IsZero() {
+		t.Errorf("fourth run: pruned %d, unchanged %d, updated after %s", app.pruned, app.unchanged, ic.query.UpdatedAfter)
+	}
+	if _, err := os.Stat(filepath.Join(folder, "b.jpg")); err == nil {
+		t.Errorf("b.jpg isn't pruned")
+	}
+	if len(app.state.Assets) != 2 {
+		t.Errorf("the state has %d assets, want 2", len(app.state.Assets))
+	}
+
+	// a file removed from the backup folder is downloaded again by a full scan only
+	if err := os.Remove(filepath.Join(folder, "c.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range ic.assets {
+		// the modifications are older than the search margin
+		a.UpdatedAt.Time = t0
+	}
+	app = backup(false, false)
+	if app.updated != 0 {
+		t.Errorf("fifth run: updated %d, want 0", app.updated)
+	}
+	app = backup(false, true)
+	if app.updated != 1 || app.unchanged != 1 {
+		t.Errorf("sixth run: updated %d, unchanged %d", app.updated, app.unchanged)
+	}
+}
@@ -0,0 +1,323 @@
+/*
+Measure the upload performances of the server with dummy assets, deleted after the measure.
+*/
+package bench
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+	"github.com/simulot/immich-go/internal/fakefs"
+	"github.com/simulot/immich-go/ui"
+)
+
+// processingPoll is the delay between two readings of the server's jobs
+const processingPoll = time.Second
+
+type BenchCmd struct {
+	*cmd.SharedFlags
+	Assets            int           // number of assets uploaded for each concurrency level
+	Size              int           // size of the assets in KB
+	Concurrency       []int         // concurrency levels to measure
+	ProcessingTimeout time.Duration // maximum wait for the server's processing, 0 to skip it
+
+	stdout         io.Writer
+	processingPoll time.Duration
+}
+
+// result is the measure of one concurrency level
+type result struct {
+	Concurrency int
+	Uploaded    int
+	Errors      int
+	Bytes       int64
+	Elapsed     time.Duration   // from the first upload to the last response
+	Latencies   []time.Duration // duration of each upload, sorted
+	Lag         time.Duration   // delay for the server to process the uploads, after the last response
+	LagKnown    bool            // the jobs of the server are readable
+	LagTimeout  bool            // the processing wasn't finished at the timeout
+}
+
+func BenchCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewBenchCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func NewBenchCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*BenchCmd, error) {
+	app := &BenchCmd{
+		SharedFlags:    common,
+		stdout:         os.Stdout,
+		processingPoll: processingPoll,
+		Concurrency:    []int{1, 2, 4, 8},
+	}
+	cmd := flag.NewFlagSet("bench", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.IntVar(&app.Assets, "assets", 20, "Number of dummy assets uploaded for each concurrency level")
+	cmd.IntVar(&app.Size, "size", 1024, "Size of the dummy assets in KB")
+	cmd.Func("concurrency", "Comma separated list of the concurrency levels to measure (default: 1,2,4,8)", func(s string) error {
+		l, err := parseLevels(s)
+		if err != nil {
+			return err
+		}
+		app.Concurrency = l
+		return nil
+	})
+	cmd.Func("processing-timeout", "Maximum wait for the server to process the uploaded assets, 0 to skip the measure (default: 5m)",
+		myflag.DurationFlagFn(&app.ProcessingTimeout, 5*time.Minute))
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if app.Assets <= 0 {
+		return nil, errors.New("the option -assets must be positive")
+	}
+	if app.Size <= 0 {
+		return nil, errors.New("the option -size must be positive")
+	}
+	if app.ProcessingTimeout < 0 {
+		return nil, errors.New("the option -processing-timeout must be positive")
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// parseLevels reads a comma separated list of concurrency levels
+func parseLevels(s string) ([]int, error) {
+	l := []int{}
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid concurrency level %q", v)
+		}
+		l = append(l, n)
+	}
+	return l, nil
+}
+
+func (app *BenchCmd) run(ctx context.Context) error {
+	fmt.Fprintf(app.stdout, "Uploading %d dummy assets of %s for each concurrency level, they are deleted after the measure\n",
+		app.Assets, ui.FormatBytes(app.Size*1024))
+	results := []result{}
+	for _, c := range app.Concurrency {
+		r, err := app.measure(ctx, c)
+		if err != nil {
+			return err
+		}
+		results = append(results, r)
+	}
+	app.print(results)
+	return nil
+}
+
+// measure uploads the dummy assets with the given concurrency, waits for their processing, and deletes them
+func (app *BenchCmd) measure(ctx context.Context, concurrency int) (result, error) {
+	r := result{Concurrency: concurrency}
+	assets, err := app.dummyAssets(concurrency)
+	if err != nil {
+		return r, err
+	}
+
+	var lock sync.Mutex
+	ids := []string{}
+	var firstErr error
+
+	work := make(chan *browser.LocalAssetFile)
+	wg := sync.WaitGroup{}
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for la := range work {
+				t := time.Now()
+				ar, err := app.Immich.AssetUpload(ctx, la)
+				d := time.Since(t)
+				_ = la.Close()
+
+				lock.Lock()
+				if err != nil {
+					r.Errors++
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					r.Uploaded++
+					r.Bytes += int64(la.FileSize)
+					r.Latencies = append(r.Latencies, d)
+					// never delete an asset that was already on the server
+					if ar.Status != immich.UploadDuplicate {
+						ids = append(ids, ar.ID)
+					}
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+sending:
+	for _, la := range assets {
+		select {
+		case <-ctx.Done():
+			break sending
+		case work <- la:
+		}
+	}
+	close(work)
+	wg.Wait()
+	r.Elapsed = time.Since(start)
+	sort.Slice(r.Latencies, func(i, j int) bool { return r.Latencies[i] < r.Latencies[j] })
+
+	if ctx.Err() == nil && len(ids) > 0 && app.ProcessingTimeout > 0 {
+		r.Lag, r.LagKnown, r.LagTimeout = app.processingLag(ctx)
+	}
+
+	// the dummy assets are removed even when the benchmark is interrupted
+	if len(ids) > 0 {
+		err = app.Immich.DeleteAssets(context.WithoutCancel(ctx), ids, true)
+		if err != nil {
+			app.Log.Error(fmt.Sprintf("can't delete the %d dummy assets: %s", len(ids), err))
+		}
+	}
+	if ctx.Err() != nil {
+		return r, ctx.Err()
+	}
+	fmt.Fprintf(app.stdout, "%d concurrent upload(s): %d asset(s) in %s\n", concurrency, r.Uploaded, r.Elapsed.Round(time.Millisecond))
+	if firstErr != nil {
+		msg := fmt.Sprintf("%d upload(s) failed with %d concurrent upload(s), the first error: %s", r.Errors, concurrency, firstErr)
+		app.Log.Error(msg)
+		fmt.Fprintln(app.stdout, msg)
+	}
+	return r, nil
+}
+
+// dummyAssets gives JPEG files with random content, so the server sees them as new assets
+func (app *BenchCmd) dummyAssets(concurrency int) ([]*browser.LocalAssetFile, error) {
+	const dateFormat = "2006-01-02 15:04"
+	size := app.Size * 1024
+	date := time.Now().Truncate(time.Minute)
+	sb := strings.Builder{}
+	for i := 0; i < app.Assets; i++ {
+		fmt.Fprintf(&sb, "%9d  %s   bench/immich-go-bench-c%d-%04d.jpg\n", size, date.Add(-time.Duration(i)*time.Minute).Format(dateFormat), concurrency, i+1)
+	}
+	fsyss, err := fakefs.ScanStringList(dateFormat, sb.String(), fakefs.WithJPEGContent())
+	if err != nil {
+		return nil, err
+	}
+	assets := []*browser.LocalAssetFile{}
+	for i := 0; i < app.Assets; i++ {
+		name := fmt.Sprintf("bench/immich-go-bench-c%d-%04d.jpg", concurrency, i+1)
+		assets = append(assets, &browser.LocalAssetFile{
+			FSys:     fsyss[0],
+			FileName: name,
+			Title:    path.Base(name),
+			FileSize: size,
+			Metadata: metadata.Metadata{DateTaken: date.Add(-time.Duration(i) * time.Minute)},
+		})
+	}
+	return assets, nil
+}
+
+// processingLag waits until the job queues of the server are empty
+func (app *BenchCmd) processingLag(ctx context.Context) (lag time.Duration, known bool, timeout bool) {
+	start := time.Now()
+	idle := 0
+	for {
+		jobs, err := app.Immich.GetJobs(ctx)
+		if err != nil {
+			// only the administrators read the jobs
+			app.Log.Warn("can't read the jobs of the server, the processing lag isn't measured: " + err.Error())
+			return 0, false, false
+		}
+		pending := 0
+		for _, j := range jobs {
+			pending += j.JobCounts.Active + j.JobCounts.Waiting + j.JobCounts.Delayed
+		}
+		if pending == 0 {
+			idle++
+		} else {
+			idle = 0
+		}
+		// the queues may be empty for a moment between two jobs of an asset
+		if idle > 1 {
+			return time.Since(start), true, false
+		}
+		if time.Since(start) > app.ProcessingTimeout {
+			return time.Since(start), true, true
+		}
+		select {
+		case <-ctx.Done():
+			return time.Since(start), true, false
+		case <-time.After(app.processingPoll):
+		}
+	}
+}
+
+// percentile gives the latency under which the given percentage of the uploads are done
+func (r result) percentile(p int) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := (len(r.Latencies)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return r.Latencies[i]
+}
+
+// throughput gives the bytes per second sent to the server
+func (r result) throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Elapsed.Seconds()
+}
+
+func (app *BenchCmd) print(results []result) {
+	fmt.Fprintln(app.stdout)
+	w := tabwriter.NewWriter(app.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Concurrency\tUploaded\tErrors\tAssets/s\tThroughput\tLatency p50\tLatency p95\tLatency max\tProcessing lag")
+	best := -1
+	for i, r := range results {
+		lag := "n/a"
+		switch {
+		case r.LagTimeout:
+			lag = "> " + r.Lag.Round(time.Second).String()
+		case r.LagKnown:
+			lag = r.Lag.Round(100 * time.Millisecond).String()
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%.1f\t%s/s\t%s\t%s\t%s\t%s\n",
+			r.Concurrency, r.Uploaded, r.Errors,
+			float64(r.Uploaded)/r.Elapsed.Seconds(),
+			ui.FormatBytes(int(r.throughput())),
+			r.percentile(50).Round(time.Millisecond), r.percentile(95).Round(time.Millisecond), r.percentile(100).Round(time.Millisecond),
+			lag)
+		if r.Errors == 0 && (best < 0 || r.throughput() > results[best].throughput()) {
+			best = i
+		}
+	}
+	w.Flush()
+	if best >= 0 {
+		fmt.Fprintf(app.stdout, "\nBest throughput with %d concurrent upload(s): %s/s\n", results[best].Concurrency, ui.FormatBytes(int(results[best].throughput())))
+	}
+}
@@ -0,0 +1,95 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/fakeserver"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []int
+		wantErr bool
+	}{
+		{s: "1,2,4", want: []int{1, 2, 4}},
+		{s: " 8 ", want: []int{8}},
+		{s: "1,,2", wantErr: true},
+		{s: "0", wantErr: true},
+		{s: "two", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := parseLevels(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLevels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLevels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	r := result{}
+	for i := 1; i <= 20; i++ {
+		r.Latencies = append(r.Latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[int]time.Duration{50: 10 * time.Millisecond, 95: 19 * time.Millisecond, 100: 20 * time.Millisecond} {
+		if got := r.percentile(p); got != want {
+			t.Errorf("percentile(%d) = %s, want %s", p, got, want)
+		}
+	}
+}
+
+func TestBench(t *testing.T) {
+	s := fakeserver.New("KEY")
+	server := httptest.NewServer(s)
+	defer server.Close()
+	ic, err := immich.NewImmichClient(server.URL, "KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ic.ValidateConnection(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app := &BenchCmd{
+		SharedFlags:       &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))},
+		Assets:            10,
+		Size:              20,
+		Concurrency:       []int{1, 3},
+		ProcessingTimeout: time.Second,
+		stdout:            out,
+		processingPoll:    time.Millisecond,
+	}
+	err = app.run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all the assets are received, and deleted after the measure
+	if s.Uploads() != 20 {
+		t.Errorf("expected 20 uploads, got %d", s.Uploads())
+	}
+	for _, a := range s.Assets() {
+		t.Errorf("the asset %s isn't deleted", a.FileName)
+	}
+	for _, want := range []string{"Concurrency", "\n1  ", "\n3  ", "Best throughput with"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%q not found in the output:\n%s", want, out.String())
+		}
+	}
+}
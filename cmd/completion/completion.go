@@ -0,0 +1,255 @@
+/*
+Generate the completion scripts of the shells, and compute the candidates of the word being typed.
+
+The scripts call the hidden command __complete with the words of the command line,
+the last one being the word being typed. The candidates are printed one per line.
+*/
+package completion
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+)
+
+// Command is the entry point of a command
+type Command func(ctx context.Context, common *cmd.SharedFlags, args []string) error
+
+// actions of the commands having actions
+var actions = map[string][]string{
+	"album":   {"delete", "export", "merge", "prune-empty", "rename"},
+	"library": {"add", "list", "scan"},
+	"people":  {"assign", "merge", "rename"},
+	"tag":     {"add", "list", "remove"},
+	"tool":    {"album"},
+}
+
+// albumActions are the actions of the album command taking album names
+var albumActions = map[string]bool{"delete": true, "export": true, "merge": true, "rename": true}
+
+// albumFlags are the flags taking an album name
+var albumFlags = map[string]bool{"album": true, "from-album": true}
+
+// albumsTimeout is the time given to the server to list the albums
+const albumsTimeout = 5 * time.Second
+
+// Completer computes the candidates of the word being typed
+type Completer struct {
+	commands  map[string]Command
+	mainFlags *flag.FlagSet
+	albums    func(ctx context.Context) ([]string, error) // names of the server's albums
+}
+
+// NewCompleter gives a completer for the commands. The albums are read from the server configured by common.
+func NewCompleter(commands map[string]Command, mainFlags *flag.FlagSet, common *cmd.SharedFlags) *Completer {
+	return &Completer{
+		commands:  commands,
+		mainFlags: mainFlags,
+		albums: func(ctx context.Context) ([]string, error) {
+			return serverAlbums(ctx, common)
+		},
+	}
+}
+
+// CompletionCommand prints the completion script of the shell
+func CompletionCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("the completion command needs the shell: %s", strings.Join(shells(), "|"))
+	}
+	script, ok := scripts[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown shell %q, the shells are: %s", args[0], strings.Join(shells(), "|"))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// CompleteCommand prints the candidates of the last word
+func (c *Completer) CompleteCommand(ctx context.Context, common *cmd.SharedFlags, words []string) error {
+	for _, candidate := range c.Complete(ctx, words) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// Complete gives the candidates of the last word of the list, sorted.
+// No candidate means the shell completes the file names.
+func (c *Completer) Complete(ctx context.Context, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	words = words[:len(words)-1]
+
+	// the options placed before the command
+	i := 0
+	for i < len(words) && strings.HasPrefix(words[i], "-") {
+		if takesValue(c.mainFlags, words[i]) {
+			i++
+		}
+		i++
+	}
+	if i == len(words) {
+		if strings.HasPrefix(cur, "-") {
+			return filter(flagNames(c.mainFlags), cur)
+		}
+		names := []string{"completion"}
+		for n := range c.commands {
+			names = append(names, n)
+		}
+		return filter(names, cur)
+	}
+	command := words[i]
+	args := words[i+1:]
+	if _, ok := c.commands[command]; !ok {
+		return nil
+	}
+
+	// the actions of the command, ex: tool album delete
+	var path []string
+	key := command
+	for len(actions[key]) > 0 {
+		j := 0
+		for j < len(args) && strings.HasPrefix(args[j], "-") {
+			j++
+		}
+		if j == len(args) {
+			if strings.HasPrefix(cur, "-") {
+				break
+			}
+			return filter(actions[key], cur)
+		}
+		path = append(path, args[j])
+		key = args[j]
+		args = args[j+1:]
+	}
+
+	// the value of an album option
+	if name, value, ok := strings.Cut(strings.TrimLeft(cur, "-"), "="); ok && strings.HasPrefix(cur, "-") {
+		if !albumFlags[name] {
+			return nil
+		}
+		return prefixed(filter(c.albumNames(ctx), value), cur[:len(cur)-len(value)])
+	}
+	if len(args) > 0 && albumFlags[strings.TrimLeft(args[len(args)-1], "-")] && strings.HasPrefix(args[len(args)-1], "-") {
+		return filter(c.albumNames(ctx), cur)
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		return filter(c.commandFlags(ctx, command, path), cur)
+	}
+
+	// the albums are the arguments of the album actions
+	parent := command
+	if len(path) > 1 {
+		parent = path[len(path)-2]
+	}
+	if parent == "album" && albumActions[key] {
+		return filter(c.albumNames(ctx), cur)
+	}
+	return nil
+}
+
+// commandFlags gives the flags of the command, by running it with the -h option: the flags are parsed before any action
+func (c *Completer) commandFlags(ctx context.Context, command string, path []string) (names []string) {
+	var last *flag.FlagSet
+	common := &cmd.SharedFlags{
+		Log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		OnFlagSet: func(fs *flag.FlagSet) {
+			fs.SetOutput(io.Discard)
+			last = fs
+		},
+	}
+	common.InitSharedFlags()
+	defer func() {
+		// a command unable to handle the -h option gives no flag
+		_ = recover()
+		if last != nil {
+			names = flagNames(last)
+		}
+	}()
+	_ = c.commands[command](ctx, common, append(append([]string{}, path...), "-h"))
+	return names
+}
+
+func (c *Completer) albumNames(ctx context.Context) []string {
+	if c.albums == nil {
+		return nil
+	}
+	names, err := c.albums(ctx)
+	if err != nil {
+		return nil
+	}
+	return names
+}
+
+// serverAlbums reads the names of the albums of the configured server, without writing any log file
+func serverAlbums(ctx context.Context, common *cmd.SharedFlags) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, albumsTimeout)
+	defer cancel()
+	common.LogFile = ""
+	common.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	err := common.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	albums, err := common.Immich.GetAllAlbums(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(albums))
+	for _, a := range albums {
+		names = append(names, a.AlbumName)
+	}
+	return names, nil
+}
+
+// takesValue tells if the option is followed by its value
+func takesValue(fs *flag.FlagSet, option string) bool {
+	name := strings.TrimLeft(option, "-")
+	if strings.Contains(name, "=") {
+		return false
+	}
+	f := fs.Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+func flagNames(fs *flag.FlagSet) []string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return names
+}
+
+// filter gives the sorted candidates beginning with the prefix, without duplicates
+func filter(candidates []string, prefix string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			result = append(result, c)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func prefixed(candidates []string, prefix string) []string {
+	for i := range candidates {
+		candidates[i] = prefix + candidates[i]
+	}
+	return candidates
+}
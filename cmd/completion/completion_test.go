@@ -0,0 +1,90 @@
+package completion
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+)
+
+// fakeCommand defines the flags of the command like the real ones
+func fakeCommand(names ...string) Command {
+	return func(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+		fs := flag.NewFlagSet("fake", flag.ContinueOnError)
+		common.SetFlags(fs)
+		for _, n := range names {
+			fs.String(n, "", "")
+		}
+		for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			args = args[1:]
+		}
+		return fs.Parse(args)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	main := flag.NewFlagSet("main", flag.ContinueOnError)
+	main.String("profile", "", "")
+	main.String("config", "", "")
+
+	c := &Completer{
+		commands: map[string]Command{
+			"upload":   fakeCommand("create-album-folder", "album"),
+			"download": fakeCommand("album"),
+			"album":    fakeCommand("yes"),
+			"tool":     fakeCommand("yes"),
+		},
+		mainFlags: main,
+		albums: func(ctx context.Context) ([]string, error) {
+			return []string{"Summer 2023", "Sunset", "Winter"}, nil
+		},
+	}
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{""}, []string{"album", "completion", "download", "tool", "upload"}},
+		{[]string{"up"}, []string{"upload"}},
+		{[]string{"-pro"}, []string{"-profile"}},
+		{[]string{"-profile", "home", ""}, []string{"album", "completion", "download", "tool", "upload"}},
+		{[]string{"-profile", "home", "do"}, []string{"download"}},
+		{[]string{"upload", "-create"}, []string{"-create-album-folder"}},
+		{[]string{"upload", "-quie"}, []string{"-quiet"}},
+		{[]string{"upload", "photos/"}, nil},
+		{[]string{"album", "re"}, []string{"rename"}},
+		{[]string{"tool", ""}, []string{"album"}},
+		{[]string{"tool", "album", "m"}, []string{"merge"}},
+		{[]string{"album", "merge", "-y"}, []string{"-yes"}},
+		{[]string{"album", "merge", "Su"}, []string{"Summer 2023", "Sunset"}},
+		{[]string{"tool", "album", "delete", "W"}, []string{"Winter"}},
+		{[]string{"album", "prune-empty", "W"}, nil},
+		{[]string{"download", "-album=Su"}, []string{"-album=Summer 2023", "-album=Sunset"}},
+		{[]string{"download", "-album", "W"}, []string{"Winter"}},
+		{[]string{"download", "-date=2023"}, nil},
+		{[]string{"unknown", "-"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.words, " "), func(t *testing.T) {
+			got := c.Complete(context.Background(), tt.words)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompletionCommand(t *testing.T) {
+	for _, shell := range shells() {
+		if !strings.Contains(scripts[shell], "__complete") {
+			t.Errorf("the %s script should call the __complete command", shell)
+		}
+	}
+	err := CompletionCommand(context.Background(), nil, []string{"tcsh"})
+	if err == nil {
+		t.Error("an unknown shell should give an error")
+	}
+}
@@ -0,0 +1,84 @@
+package completion
+
+import "sort"
+
+const bashScript = `# bash completion of immich-go
+# source <(immich-go completion bash)
+_immich_go_completion() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    [[ "$line" == *" " ]] && words+=("")
+    local cur="${words[${#words[@]}-1]}"
+    local prefix=""
+    # bash splits the words at the = sign: the candidates replace the part after it
+    if [[ "$cur" == -*=* && "$COMP_WORDBREAKS" == *=* ]]; then
+        prefix="${cur%%=*}="
+    fi
+    local IFS=$'\n'
+    local -a candidates
+    candidates=($("${words[0]}" __complete "${words[@]:1}" 2>/dev/null))
+    COMPREPLY=()
+    local c
+    for c in "${candidates[@]}"; do
+        COMPREPLY+=("${c#"$prefix"}")
+    done
+    if [[ ${#COMPREPLY[@]} -eq 0 ]]; then
+        compopt -o default 2>/dev/null
+    fi
+}
+complete -F _immich_go_completion immich-go
+`
+
+const zshScript = `#compdef immich-go
+# zsh completion of immich-go
+# source <(immich-go completion zsh)
+_immich_go() {
+    local -a candidates
+    candidates=("${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -Q -- "${candidates[@]}"
+    else
+        _files
+    fi
+}
+compdef _immich_go immich-go
+`
+
+const fishScript = `# fish completion of immich-go
+# immich-go completion fish | source
+function __immich_go_complete
+    set -l words (commandline -opc) (commandline -ct)
+    $words[1] __complete $words[2..-1] 2>/dev/null
+end
+complete -c immich-go -a '(__immich_go_complete)'
+`
+
+const powershellScript = `# PowerShell completion of immich-go
+# immich-go completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName immich-go -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '""' }
+    & $commandAst.CommandElements[0].ToString() __complete @words 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`
+
+var scripts = map[string]string{
+	"bash":       bashScript,
+	"zsh":        zshScript,
+	"fish":       fishScript,
+	"powershell": powershellScript,
+}
+
+func shells() []string {
+	names := make([]string, 0, len(scripts))
+	for n := range scripts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,497 @@
+/*
+Download the assets of the server into a local folder.
+*/
+package download
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// Values of the -sidecar option
+const (
+	SidecarXMP  = "XMP"
+	SidecarJSON = "JSON"
+	SidecarNone = "NONE"
+)
+
+// DefaultFolderTemplate is the default value of the -folder-template option
+const DefaultFolderTemplate = "{{.Year}}/{{.Year}}-{{.Month}}"
+
+type DownloadCmd struct {
+	*cmd.SharedFlags
+	DateRange      immich.DateRange // Set capture date range
+	Albums         []string         // Download only the assets of those albums
+	People         []string         // Download only the assets showing those persons
+	FavoritesOnly  bool             // Download only the favorite assets
+	ArchivedOnly   bool             // Download only the archived assets
+	Type           string           // Download only the IMAGE or the VIDEO assets
+	SelectTypes    []string         // Download only the files with those extensions
+	ExcludeTypes   []string         // Don't download the files with those extensions
+	Layout         string           // Organize the files with the TEMPLATE or like a Google Photos TAKEOUT
+	FolderTemplate string           // Template of the folder of the assets
+	Sidecar        string           // Write the metadata into a XMP or JSON sidecar, or NONE
+	DryRun         bool             // Display actions but don't change anything
+	UpdatedAfter   time.Time        // Get only the assets added or modified after this time, set by the backup command
+
+	root              string             // destination folder
+	folder            *template.Template // parsed FolderTemplate
+	albumsByAsset     map[string][]string
+	albumDescriptions map[string]string // album name -> description
+	albumWritten      map[string]bool   // folders of the albums whose metadata.json is written
+	downloaded        int
+	skipped           int
+	failed            int
+}
+
+// NewDownloader gives a downloader of the server's assets, used by the download, sync and backup commands
+func NewDownloader(common *cmd.SharedFlags) *DownloadCmd {
+	return &DownloadCmd{
+		SharedFlags:       common,
+		Layout:            LayoutTemplate,
+		FolderTemplate:    DefaultFolderTemplate,
+		Sidecar:           SidecarXMP,
+		albumsByAsset:     map[string][]string{},
+		albumDescriptions: map[string]string{},
+		albumWritten:      map[string]bool{},
+	}
+}
+
+// SetFlags adds the options of the download to the flag set
+func (app *DownloadCmd) SetFlags(cmd *flag.FlagSet) {
+	cmd.Var(&app.DateRange, "date", "Process only the assets having a capture date in that range.")
+	cmd.Func("album", "Download only the assets of the album, can be repeated.", func(s string) error {
+		app.Albums = append(app.Albums, s)
+		return nil
+	})
+	cmd.Func("person", "Download only the assets showing the person, can be repeated.", func(s string) error {
+		app.People = append(app.People, s)
+		return nil
+	})
+	cmd.BoolFunc("favorite", "Download only the favorite assets", myflag.BoolFlagFn(&app.FavoritesOnly, false))
+	cmd.BoolFunc("archived", "Download only the archived assets", myflag.BoolFlagFn(&app.ArchivedOnly, false))
+	cmd.StringVar(&app.Type, "type", "", "Download only the IMAGE or the VIDEO assets.")
+	cmd.Func("select-types", "list of selected extensions separated by a comma", func(s string) error {
+		app.SelectTypes = append(app.SelectTypes, extensions(s)...)
+		return nil
+	})
+	cmd.Func("exclude-types", "list of excluded extensions separated by a comma", func(s string) error {
+		app.ExcludeTypes = append(app.ExcludeTypes, extensions(s)...)
+		return nil
+	})
+	cmd.StringVar(&app.Layout, "layout", LayoutTemplate, "Organize the downloaded files with the -folder-template TEMPLATE, or like a Google Photos TAKEOUT with its JSON files. (default: TEMPLATE)")
+	cmd.StringVar(&app.FolderTemplate, "folder-template", DefaultFolderTemplate, "Template of the folder of the downloaded assets within the destination folder. (default: "+DefaultFolderTemplate+")")
+	cmd.StringVar(&app.Sidecar, "sidecar", SidecarXMP, "Write the metadata of the downloaded assets into a XMP or JSON sidecar file, or NONE. (default: XMP)")
+	cmd.BoolFunc("dry-run", "display actions but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+}
+
+// Validate checks the options, and sets the destination folder
+func (app *DownloadCmd) Validate(root string) error {
+	app.root = root
+	app.Layout = strings.ToUpper(app.Layout)
+	switch app.Layout {
+	case LayoutTemplate, LayoutTakeout:
+	default:
+		return fmt.Errorf("the -layout accepts TEMPLATE or TAKEOUT")
+	}
+	app.Type = strings.ToUpper(app.Type)
+	switch app.Type {
+	case "", "IMAGE", "VIDEO":
+	default:
+		return fmt.Errorf("the -type accepts IMAGE or VIDEO")
+	}
+	app.Sidecar = strings.ToUpper(app.Sidecar)
+	switch app.Sidecar {
+	case SidecarXMP, SidecarJSON, SidecarNone:
+	default:
+		return fmt.Errorf("the -sidecar accepts XMP, JSON or NONE")
+	}
+	var err error
+	app.folder, err = parseFolderTemplate(app.FolderTemplate)
+	return err
+}
+
+func NewDownloadCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*DownloadCmd, error) {
+	cmd := flag.NewFlagSet("download", flag.ContinueOnError)
+	app := NewDownloader(common)
+	app.SharedFlags.SetFlags(cmd)
+	app.SetFlags(cmd)
+
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.NArg() != 1 {
+		return nil, errors.New("the download command needs the destination folder")
+	}
+	err = app.Validate(cmd.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func DownloadCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewDownloadCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *DownloadCmd) run(ctx context.Context) error {
+	fmt.Println("Get server's albums...")
+	err := app.ReadAlbums(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Get server's assets...")
+	var assets []*immich.Asset
+	err = app.GetAssets(ctx, func(a *immich.Asset) error {
+		assets = append(assets, a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range assets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r, err := app.DownloadAsset(ctx, a)
+		switch {
+		case err != nil:
+			app.failed++
+			app.Log.Error("can't download the asset", "file", a.OriginalFileName, "id", a.ID, "error", err.Error())
+		case r.Downloaded:
+			app.downloaded++
+		default:
+			app.skipped++
+		}
+	}
+	fmt.Printf("%d asset(s) downloaded, %d already present, %d error(s)\n", app.downloaded, app.skipped, app.failed)
+	return nil
+}
+
+// ReadAlbums reads the albums of the assets
+func (app *DownloadCmd) ReadAlbums(ctx context.Context) error {
+	albums, err := app.Immich.GetAllAlbums(ctx)
+	if err != nil {
+		return err
+	}
+	for _, album := range albums {
+		app.albumDescriptions[album.AlbumName] = album.Description
+		content, err := app.Immich.GetAlbumInfo(ctx, album.ID, false)
+		if err != nil {
+			return err
+		}
+		for _, a := range content.Assets {
+			app.albumsByAsset[a.ID] = append(app.albumsByAsset[a.ID], album.AlbumName)
+		}
+	}
+	for _, l := range app.albumsByAsset {
+		sort.Strings(l)
+	}
+	return nil
+}
+
+// SelectAlbums selects the assets of the albums whose name matches the patterns, and reads the albums of the assets
+func (app *DownloadCmd) SelectAlbums(ctx context.Context, patterns namematcher.List) error {
+	albums, err := app.Immich.GetAllAlbums(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the albums list: %w", err)
+	}
+	for _, al := range albums {
+		if patterns.Match(al.AlbumName) {
+			app.Albums = append(app.Albums, al.AlbumName)
+		}
+	}
+	if len(app.Albums) == 0 {
+		return fmt.Errorf("no album matches %s", patterns.String())
+	}
+	return app.ReadAlbums(ctx)
+}
+
+// GetAssets calls fn for each server's asset selected by the options. The server's search does most of the selection,
+// so only the matching assets are fetched. ReadAlbums must be called before.
+func (app *DownloadCmd) GetAssets(ctx context.Context, fn func(*immich.Asset) error) error {
+	q := immich.SearchQuery{
+		FavoritesOnly: app.FavoritesOnly,
+		ArchivedOnly:  app.ArchivedOnly,
+		Type:          app.Type,
+		UpdatedAfter:  app.UpdatedAfter,
+	}
+	if app.DateRange.IsSet() {
+		q.TakenAfter, q.TakenBefore = app.DateRange.After, app.DateRange.Before
+	}
+	if len(app.People) > 0 {
+		people, err := app.Immich.GetAllPeople(ctx)
+		if err != nil {
+			return err
+		}
+		for _, name := range app.People {
+			id := ""
+			for _, p := range people {
+				if strings.EqualFold(p.Name, name) {
+					id = p.ID
+					break
+				}
+			}
+			if id == "" {
+				return fmt.Errorf("person %q not found", name)
+			}
+			q.PersonIDs = append(q.PersonIDs, id)
+		}
+	}
+	return app.Immich.SearchAssets(ctx, q, func(a *immich.Asset) error {
+		if !app.IsSelected(a) {
+			return nil
+		}
+		return fn(a)
+	})
+}
+
+// IsSelected tells if the asset is selected by the options, except the persons only known by the server
+func (app *DownloadCmd) IsSelected(a *immich.Asset) bool {
+	if a.IsTrashed {
+		return false
+	}
+	if app.DateRange.IsSet() && !app.DateRange.InRange(a.ExifInfo.DateTimeOriginal.Time) {
+		return false
+	}
+	if (app.FavoritesOnly && !a.IsFavorite) || (app.ArchivedOnly && !a.IsArchived) {
+		return false
+	}
+	if app.Type != "" && a.Type != app.Type {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(a.OriginalFileName))
+	if len(app.SelectTypes) > 0 && !slices.Contains(app.SelectTypes, ext) {
+		return false
+	}
+	if slices.Contains(app.ExcludeTypes, ext) {
+		return false
+	}
+	if len(app.Albums) > 0 {
+		for _, album := range app.albumsByAsset[a.ID] {
+			for _, want := range app.Albums {
+				if album == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// AssetAlbums gives the names of the albums of the asset
+func (app *DownloadCmd) AssetAlbums(id string) []string {
+	return app.albumsByAsset[id]
+}
+
+// Result tells what DownloadAsset has done
+type Result struct {
+	Files      []string // files of the asset in the destination folder: the asset, its Live Photo video, its sidecars
+	Downloaded bool     // false when the asset was already there
+}
+
+// DownloadAsset writes the asset, its Live Photo video and its sidecar into the destination folder.
+func (app *DownloadCmd) DownloadAsset(ctx context.Context, a *immich.Asset) (Result, error) {
+	if app.Layout == LayoutTakeout {
+		return app.downloadTakeout(ctx, a)
+	}
+	var r Result
+	dir, err := app.assetFolder(a)
+	if err != nil {
+		return r, err
+	}
+	dir = filepath.Join(app.root, filepath.FromSlash(dir))
+	name, present, err := targetName(dir, CleanName(a.OriginalFileName, a.ID), int64(a.ExifInfo.FileSizeInByte), a.Checksum)
+	if err != nil {
+		return r, err
+	}
+	file := filepath.Join(dir, name)
+	base, ext := strings.TrimSuffix(name, path.Ext(name)), path.Ext(name)
+	r.Files = append(r.Files, file)
+	if present {
+		app.Log.Debug("already downloaded", "file", file)
+	} else {
+		app.Log.Info("download", "file", file, "id", a.ID)
+		if !app.DryRun {
+			_, err = app.downloadFile(ctx, a.ID, dir, base, ext, AssetDate(a))
+			if err != nil {
+				return r, err
+			}
+		}
+		r.Downloaded = true
+	}
+
+	if a.LivePhotoVideoID != "" && !present && !app.DryRun {
+		// the video gets the name of the photo, with the extension matching its content
+		video, err := app.downloadFile(ctx, a.LivePhotoVideoID, dir, base, "", AssetDate(a))
+		if err != nil {
+			return r, err
+		}
+		r.Files = append(r.Files, filepath.Join(dir, video))
+	}
+
+	if app.Sidecar != SidecarNone && !app.DryRun {
+		sidecar, err := app.writeSidecar(a, file)
+		if err != nil {
+			return r, err
+		}
+		if sidecar != "" {
+			r.Files = append(r.Files, sidecar)
+		}
+	}
+	return r, nil
+}
+
+// downloadFile writes the content of the asset into the folder, through a temporary file.
+// When ext is empty, the file gets the extension matching its content.
+func (app *DownloadCmd) downloadFile(ctx context.Context, id string, dir string, base string, ext string, date time.Time) (string, error) {
+	r, err := app.Immich.DownloadAsset(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	br := bufio.NewReader(r)
+
+	if ext == "" {
+		b, _ := br.Peek(metadata.SniffSize)
+		ext = strings.ToUpper(metadata.SniffExtension(b))
+		if ext == "" {
+			ext = ".MOV"
+		}
+	}
+	name := base + ext
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".*")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(tmp, br)
+	err = errors.Join(err, tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if !date.IsZero() {
+		_ = os.Chtimes(filepath.Join(dir, name), date, date)
+	}
+	return name, nil
+}
+
+// targetName gives the name of the file in the folder: the asset's name, suffixed by a number
+// when another file has the same name. present is true when the file is already there.
+//
+// The files are compared by size, or by checksum when the server doesn't give the size.
+func targetName(dir string, name string, size int64, checksum string) (string, bool, error) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 0; ; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		file := filepath.Join(dir, candidate)
+		i, err := os.Stat(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			return candidate, false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if !i.Mode().IsRegular() {
+			continue
+		}
+		if size == 0 && checksum != "" {
+			sum, err := fileChecksum(file)
+			if err != nil {
+				return "", false, err
+			}
+			if sum == checksum {
+				return candidate, true, nil
+			}
+			continue
+		}
+		if i.Size() == size {
+			return candidate, true, nil
+		}
+	}
+}
+
+// fileChecksum gives the base64 encoded SHA1 of the file, like the server's checksums
+func fileChecksum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// AssetDate gives the date of capture of the asset
+func AssetDate(a *immich.Asset) time.Time {
+	if !a.ExifInfo.DateTimeOriginal.IsZero() {
+		return a.ExifInfo.DateTimeOriginal.Time
+	}
+	return a.FileCreatedAt.Time
+}
+
+// extensions splits the comma separated list of extensions, in lower case with their dot
+func extensions(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		l = append(l, e)
+	}
+	return l
+}
@@ -0,0 +1,276 @@
+package download
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icDownload serves the assets and their content
+type icDownload struct {
+	fakeimmich.MockedCLient
+	assets  []*immich.Asset
+	content map[string]string
+	albums  map[string][]string // album name -> asset IDs
+	people  []immich.Person
+	query   immich.SearchQuery // last search
+}
+
+func (c *icDownload) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icDownload) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	c.query = q
+	return c.GetAllAssetsWithFilter(ctx, fn)
+}
+
+func (c *icDownload) GetAllPeople(ctx context.Context) ([]immich.Person, error) {
+	return c.people, nil
+}
+
+func (c *icDownload) DownloadAsset(ctx context.Context, id string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(c.content[id])), nil
+}
+
+func (c *icDownload) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	var l []immich.AlbumSimplified
+	for name := range c.albums {
+		l = append(l, immich.AlbumSimplified{ID: name, AlbumName: name})
+	}
+	return l, nil
+}
+
+func (c *icDownload) GetAlbumInfo(ctx context.Context, id string, withoutAssets bool) (immich.AlbumContent, error) {
+	album := immich.AlbumContent{ID: id, AlbumName: id}
+	for _, a := range c.albums[id] {
+		album.Assets = append(album.Assets, immich.AssetSimplified{ID: a})
+	}
+	return album, nil
+}
+
+func newAsset(id, name string, size int, date time.Time) *immich.Asset {
+	a := &immich.Asset{ID: id, OriginalFileName: name, Type: "IMAGE"}
+	a.ExifInfo.FileSizeInByte = size
+	a.ExifInfo.DateTimeOriginal.Time = date
+	return a
+}
+
+func TestDownload(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.Local)
+	live := newAsset("3", "IMG_0003.HEIC", 4, date)
+	live.LivePhotoVideoID = "3v"
+	trashed := newAsset("4", "IMG_0004.jpg", 5, date)
+	trashed.IsTrashed = true
+	ic := &icDownload{
+		assets: []*immich.Asset{
+			newAsset("1", "IMG_0001.jpg", 5, date),
+			newAsset("2", "IMG_0001.jpg", 6, date.AddDate(0, 0, 1)),
+			live,
+			trashed,
+		},
+		content: map[string]string{
+			"1":  "photo",
+			"2":  "photo2",
+			"3":  "heic",
+			"3v": "\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00qt  video",
+			"4":  "trash",
+		},
+		albums: map[string][]string{"Holidays": {"1", "3"}},
+	}
+	ic.assets[0].ExifInfo.Description = "A caption"
+
+	dir := t.TempDir()
+	run := func() *DownloadCmd {
+		app := NewDownloader(&cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))})
+		app.Sidecar = SidecarJSON
+		app.FolderTemplate = "{{.Year}}/{{.Album}}"
+		err := app.Validate(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = app.run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return app
+	}
+
+	app := run()
+	if app.downloaded != 3 || app.skipped != 0 || app.failed != 0 {
+		t.Errorf("first run: downloaded %d, skipped %d, failed %d", app.downloaded, app.skipped, app.failed)
+	}
+	for name, content := range map[string]string{
+		"2023/Holidays/IMG_0001.jpg":  "photo",
+		"2023/IMG_0001.jpg":           "photo2",
+		"2023/Holidays/IMG_0003.HEIC": "heic",
+		"2023/Holidays/IMG_0003.MOV":  ic.content["3v"],
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("missing file %s: %v", name, err)
+			continue
+		}
+		if string(b) != content {
+			t.Errorf("file %s: %q, want %q", name, b, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2023", "Holidays", "IMG_0004.jpg")); err == nil {
+		t.Errorf("the trashed asset is downloaded")
+	}
+
+	// the sidecar is read back by the upload command
+	f, err := os.Open(filepath.Join(dir, "2023", "Holidays", "IMG_0001.jpg.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, _ := metadata.GetSidecarReader(".json")
+	md, err := sr.ReadSidecar(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Description != "A caption" || !md.DateTaken.Equal(date) || len(md.Tags) != 1 || md.Tags[0] != "Holidays" {
+		t.Errorf("unexpected sidecar metadata: %+v", md)
+	}
+
+	app = run()
+	if app.downloaded != 0 || app.skipped != 3 {
+		t.Errorf("second run: downloaded %d, skipped %d", app.downloaded, app.skipped)
+	}
+}
+
+func TestGetAssets(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	video := newAsset("2", "VID_0002.mp4", 5, date)
+	video.Type = "VIDEO"
+	favorite := newAsset("3", "IMG_0003.HEIC", 5, date)
+	favorite.IsFavorite = true
+	ic := &icDownload{
+		assets: []*immich.Asset{newAsset("1", "IMG_0001.jpg", 5, date), video, favorite},
+		people: []immich.Person{{ID: "p1", Name: "Alice"}},
+	}
+
+	tests := []struct {
+		name   string
+		set    func(app *DownloadCmd)
+		want   string
+		person []string
+	}{
+		{name: "all", set: func(app *DownloadCmd) {}, want: "1,2,3"},
+		{name: "favorites", set: func(app *DownloadCmd) { app.FavoritesOnly = true }, want: "3"},
+		{name: "videos", set: func(app *DownloadCmd) { app.Type = "video" }, want: "2"},
+		{name: "select-types", set: func(app *DownloadCmd) { app.SelectTypes = extensions("JPG, heic") }, want: "1,3"},
+		{name: "exclude-types", set: func(app *DownloadCmd) { app.ExcludeTypes = extensions("mp4") }, want: "1,3"},
+		{name: "person", set: func(app *DownloadCmd) { app.People = []string{"alice"} }, want: "1,2,3", person: []string{"p1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := NewDownloader(&cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))})
+			tt.set(app)
+			if err := app.Validate(t.TempDir()); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			err := app.GetAssets(context.Background(), func(a *immich.Asset) error {
+				ids = append(ids, a.ID)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Errorf("got assets %s, want %s", got, tt.want)
+			}
+			if strings.Join(ic.query.PersonIDs, ",") != strings.Join(tt.person, ",") {
+				t.Errorf("query persons %v, want %v", ic.query.PersonIDs, tt.person)
+			}
+		})
+	}
+
+	app := NewDownloader(&cmd.SharedFlags{Immich: ic})
+	app.People = []string{"Bob"}
+	if err := app.GetAssets(context.Background(), func(*immich.Asset) error { return nil }); err == nil {
+		t.Errorf("expecting an error for an unknown person")
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "2023/2023-06", want: "2023/2023-06"},
+		{path: "2023//", want: "2023"},
+		{path: "Trip: Paris?/../x", want: "Trip_ Paris_/x"},
+		{path: " . /a\\b", want: "a/b"},
+	}
+	for _, tt := range tests {
+		if got := cleanPath(tt.path); got != tt.want {
+			t.Errorf("cleanPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTargetName(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.jpg": "aaa", "b.jpg": "bbb", "b (1).jpg": "bbbb", "not-a-dir": "x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "d.jpg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sumA, err := fileChecksum(filepath.Join(dir, "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		file     string
+		size     int64
+		checksum string
+		want     string
+		present  bool
+		err      bool
+	}{
+		{name: "new file", file: "c.jpg", size: 3, want: "c.jpg"},
+		{name: "same size", file: "a.jpg", size: 3, want: "a.jpg", present: true},
+		{name: "other size", file: "a.jpg", size: 5, want: "a (1).jpg"},
+		{name: "second name", file: "b.jpg", size: 4, want: "b (1).jpg", present: true},
+		{name: "unknown size, same checksum", file: "a.jpg", checksum: sumA, want: "a.jpg", present: true},
+		{name: "unknown size, other checksum", file: "a.jpg", checksum: "other", want: "a (1).jpg"},
+		{name: "unknown size and checksum", file: "a.jpg", want: "a (1).jpg"},
+		{name: "folder with the name", file: "d.jpg", size: 3, want: "d (1).jpg"},
+		{name: "stat error", dir: "not-a-dir", file: "a.jpg", size: 3, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, present, err := targetName(filepath.Join(dir, tt.dir), tt.file, tt.size, tt.checksum)
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want || present != tt.present {
+				t.Errorf("targetName() = %q, %v, want %q, %v", got, present, tt.want, tt.present)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package download
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// jsonSidecar is the content of the JSON sidecar, with the field names of exiftool -json,
+// so the upload command can read it back
+type jsonSidecar struct {
+	FileName         string   `json:"FileName"`
+	DateTimeOriginal string   `json:"DateTimeOriginal,omitempty"`
+	Description      string   `json:"Description,omitempty"`
+	GPSLatitude      float64  `json:"GPSLatitude,omitempty"`
+	GPSLongitude     float64  `json:"GPSLongitude,omitempty"`
+	Make             string   `json:"Make,omitempty"`
+	Model            string   `json:"Model,omitempty"`
+	Keywords         []string `json:"Keywords,omitempty"`
+	Favorite         bool     `json:"Favorite,omitempty"`
+	Archived         bool     `json:"Archived,omitempty"`
+	ImmichID         string   `json:"ImmichID"`
+}
+
+// writeSidecar writes the metadata of the asset next to the downloaded file, and gives the name of the sidecar.
+func (app *DownloadCmd) writeSidecar(a *immich.Asset, file string) (string, error) {
+	ext, b, err := app.SidecarFile(a)
+	if err != nil || ext == "" {
+		return "", err
+	}
+	return file + ext, os.WriteFile(file+ext, b, 0o644)
+}
+
+// SidecarFile gives the extension and the content of the sidecar of the asset, in the format of the -sidecar option.
+// The albums of the asset are written as keywords. The extension is empty when there is no sidecar to write.
+func (app *DownloadCmd) SidecarFile(a *immich.Asset) (string, []byte, error) {
+	switch app.Sidecar {
+	case SidecarXMP:
+		md := metadata.Metadata{
+			Description: a.ExifInfo.Description,
+			DateTaken:   AssetDate(a),
+			Latitude:    a.ExifInfo.Latitude,
+			Longitude:   a.ExifInfo.Longitude,
+			Tags:        app.albumsByAsset[a.ID],
+		}
+		if !md.IsSet() {
+			return "", nil, nil
+		}
+		return ".xmp", []byte(md.String()), nil
+	case SidecarJSON:
+		js := jsonSidecar{
+			FileName:     a.OriginalFileName,
+			Description:  a.ExifInfo.Description,
+			GPSLatitude:  a.ExifInfo.Latitude,
+			GPSLongitude: a.ExifInfo.Longitude,
+			Make:         a.ExifInfo.Make,
+			Model:        a.ExifInfo.Model,
+			Keywords:     app.albumsByAsset[a.ID],
+			Favorite:     a.IsFavorite,
+			Archived:     a.IsArchived,
+			ImmichID:     a.ID,
+		}
+		if d := AssetDate(a); !d.IsZero() {
+			js.DateTimeOriginal = d.Format("2006:01:02 15:04:05Z07:00")
+		}
+		b, err := json.MarshalIndent(js, "", "  ")
+		if err != nil {
+			return "", nil, err
+		}
+		return ".json", b, nil
+	}
+	return "", nil, nil
+}
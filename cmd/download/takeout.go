@@ -0,0 +1,191 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// Values of the -layout option
+const (
+	LayoutTemplate = "TEMPLATE" // folders given by the -folder-template option
+	LayoutTakeout  = "TAKEOUT"  // folders and JSON files of a Google Photos takeout
+)
+
+// takeoutRoot is the folder of the photos in a Google Photos takeout
+const takeoutRoot = "Takeout/Google Photos"
+
+// takeoutTime is a date in the Google Photos JSON files
+type takeoutTime struct {
+	Timestamp string `json:"timestamp"`
+	Formatted string `json:"formatted"`
+}
+
+func newTakeoutTime(t time.Time) takeoutTime {
+	return takeoutTime{
+		Timestamp: strconv.FormatInt(t.Unix(), 10),
+		Formatted: t.UTC().Format("Jan 2, 2006, 3:04:05 PM UTC"),
+	}
+}
+
+type takeoutGeoData struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+}
+
+// takeoutJSON is the JSON file of an asset in a Google Photos takeout
+type takeoutJSON struct {
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	CreationTime   takeoutTime    `json:"creationTime"`
+	PhotoTakenTime takeoutTime    `json:"photoTakenTime"`
+	GeoData        takeoutGeoData `json:"geoData"`
+	GeoDataExif    takeoutGeoData `json:"geoDataExif"`
+	Favorited      bool           `json:"favorited,omitempty"`
+	Archived       bool           `json:"archived,omitempty"`
+}
+
+// takeoutAlbumJSON is the metadata.json file of an album in a Google Photos takeout
+type takeoutAlbumJSON struct {
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Access      string      `json:"access"`
+	Date        takeoutTime `json:"date"`
+}
+
+// downloadTakeout writes the asset in the folder "Photos from YYYY" and in the folders of its albums,
+// each copy with its JSON file, like in a Google Photos takeout
+func (app *DownloadCmd) downloadTakeout(ctx context.Context, a *immich.Asset) (Result, error) {
+	var r Result
+	date := AssetDate(a)
+	dirs := []string{fmt.Sprintf("Photos from %04d", date.Year())}
+	for _, album := range app.albumsByAsset[a.ID] {
+		dirs = append(dirs, CleanName(album, "Untitled"))
+	}
+
+	for i, dir := range dirs {
+		dir = filepath.Join(app.root, filepath.FromSlash(takeoutRoot), dir)
+		if i > 0 {
+			err := app.writeTakeoutAlbum(dir, app.albumsByAsset[a.ID][i-1])
+			if err != nil {
+				return r, err
+			}
+		}
+
+		name, n, present := takeoutName(dir, CleanName(a.OriginalFileName, a.ID), int64(a.ExifInfo.FileSizeInByte))
+		file := filepath.Join(dir, name)
+		ext := path.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		r.Files = append(r.Files, file)
+		if present {
+			app.Log.Debug("already downloaded", "file", file)
+		} else {
+			app.Log.Info("download", "file", file, "id", a.ID)
+			if !app.DryRun {
+				_, err := app.downloadFile(ctx, a.ID, dir, base, ext, date)
+				if err != nil {
+					return r, err
+				}
+			}
+			r.Downloaded = true
+		}
+		if !app.DryRun {
+			js := filepath.Join(dir, takeoutJSONName(CleanName(a.OriginalFileName, a.ID), n))
+			err := writeTakeoutJSON(a, js, a.OriginalFileName)
+			if err != nil {
+				return r, err
+			}
+			r.Files = append(r.Files, js)
+		}
+
+		if a.LivePhotoVideoID != "" && !present && !app.DryRun {
+			video, err := app.downloadFile(ctx, a.LivePhotoVideoID, dir, base, "", date)
+			if err != nil {
+				return r, err
+			}
+			err = writeTakeoutJSON(a, filepath.Join(dir, video+".json"), video)
+			if err != nil {
+				return r, err
+			}
+			r.Files = append(r.Files, filepath.Join(dir, video), filepath.Join(dir, video+".json"))
+		}
+	}
+	return r, nil
+}
+
+// takeoutName gives the name of the file in the folder, numbered like Google does when a file of another size has the same name:
+// IMG_1234(1).jpg. present is true when the file is already there.
+func takeoutName(dir string, name string, size int64) (string, int, bool) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 0; ; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%s(%d)%s", base, n, ext)
+		}
+		i, err := os.Stat(filepath.Join(dir, candidate))
+		if err != nil {
+			return candidate, n, false
+		}
+		if size == 0 || i.Size() == size {
+			return candidate, n, true
+		}
+	}
+}
+
+// takeoutJSONName gives the name of the JSON file of the nth file with that name: IMG_1234.jpg.json, IMG_1234.jpg(1).json
+func takeoutJSONName(name string, n int) string {
+	if n == 0 {
+		return name + ".json"
+	}
+	return fmt.Sprintf("%s(%d).json", name, n)
+}
+
+func writeTakeoutJSON(a *immich.Asset, file string, title string) error {
+	js := takeoutJSON{
+		Title:          title,
+		Description:    a.ExifInfo.Description,
+		CreationTime:   newTakeoutTime(a.FileCreatedAt.Time),
+		PhotoTakenTime: newTakeoutTime(AssetDate(a)),
+		GeoData:        takeoutGeoData{Latitude: a.ExifInfo.Latitude, Longitude: a.ExifInfo.Longitude},
+		GeoDataExif:    takeoutGeoData{Latitude: a.ExifInfo.Latitude, Longitude: a.ExifInfo.Longitude},
+		Favorited:      a.IsFavorite,
+		Archived:       a.IsArchived,
+	}
+	b, err := json.MarshalIndent(js, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0o644)
+}
+
+// writeTakeoutAlbum writes the metadata.json file of the album's folder
+func (app *DownloadCmd) writeTakeoutAlbum(dir string, album string) error {
+	if app.DryRun || app.albumWritten[dir] {
+		return nil
+	}
+	app.albumWritten[dir] = true
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(takeoutAlbumJSON{
+		Title:       album,
+		Description: app.albumDescriptions[album],
+		Access:      "protected",
+		Date:        newTakeoutTime(time.Now()),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "metadata.json"), b, 0o644)
+}
@@ -0,0 +1,111 @@
+package download
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser/gp"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich"
+)
+
+// TestTakeoutLayout checks that the export is read back by the Google Photos takeout browser
+func TestTakeoutLayout(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.Local)
+	ic := &icDownload{
+		assets: []*immich.Asset{
+			newAsset("1", "IMG_0001.jpg", 5, date),
+			newAsset("2", "IMG_0001.jpg", 6, date.AddDate(0, 1, 0)),
+			newAsset("3", "IMG_0003.jpg", 7, date),
+		},
+		content: map[string]string{
+			"1": "photo",
+			"2": "photo2",
+			"3": "photo33",
+		},
+		albums: map[string][]string{"Holidays": {"1"}},
+	}
+	ic.assets[0].ExifInfo.Description = "A caption"
+	ic.assets[2].IsFavorite = true
+
+	dir := t.TempDir()
+	app := NewDownloader(&cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	app.Layout = LayoutTakeout
+	err := app.Validate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsyss, err := fshelper.ParsePath([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	to, err := gp.NewTakeout(ctx, fileevent.NewRecorder(nil, false), immich.DefaultSupportedMedia, fsyss...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = to.Prepare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		title  string
+		date   time.Time
+		albums []string
+	}
+	got := map[string]result{}
+	for a := range to.Browse(ctx) {
+		r := got[a.FileName]
+		r.title, r.date = a.Title, a.Metadata.DateTaken
+		for _, al := range a.Albums {
+			r.albums = append(r.albums, al.Title)
+		}
+		got[a.FileName] = r
+		if a.Title == "IMG_0003.jpg" && !a.Favorite {
+			t.Errorf("the favorite flag is lost")
+		}
+		if a.FileName == "Takeout/Google Photos/Holidays/IMG_0001.jpg" && a.Metadata.Description != "A caption" {
+			t.Errorf("the description is lost: %q", a.Metadata.Description)
+		}
+		a.Close()
+	}
+	want := map[string]result{
+		"Takeout/Google Photos/Photos from 2023/IMG_0001.jpg":    {title: "IMG_0001.jpg", date: date},
+		"Takeout/Google Photos/Photos from 2023/IMG_0001(1).jpg": {title: "IMG_0001.jpg", date: date.AddDate(0, 1, 0)},
+		"Takeout/Google Photos/Photos from 2023/IMG_0003.jpg":    {title: "IMG_0003.jpg", date: date},
+		"Takeout/Google Photos/Holidays/IMG_0001.jpg":            {title: "IMG_0001.jpg", date: date, albums: []string{"Holidays"}},
+	}
+	names := func(m map[string]result) []string {
+		var l []string
+		for k := range m {
+			l = append(l, k)
+		}
+		sort.Strings(l)
+		return l
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", names(got), names(want))
+	}
+	for f, w := range want {
+		g, ok := got[f]
+		if !ok {
+			t.Errorf("missing %s", f)
+			continue
+		}
+		if g.title != w.title || !g.date.Equal(w.date) || len(g.albums) != len(w.albums) {
+			t.Errorf("%s: got %+v, want %+v", f, g, w)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package download
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// FolderTemplateData gives the values available in the -folder-template option
+type FolderTemplateData struct {
+	Year, Month, Day string    // Date of capture, empty when unknown
+	Date             time.Time // Date of capture
+	Album            string    // First album of the asset by name, empty when the asset isn't in an album
+	Albums           []string  // Albums of the asset
+	FileName         string    // Asset's original file name
+	Type             string    // IMAGE or VIDEO
+}
+
+func parseFolderTemplate(s string) (*template.Template, error) {
+	t, err := template.New("folder").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -folder-template: %w", err)
+	}
+	// check the template against the available values
+	err = t.Execute(&strings.Builder{}, FolderTemplateData{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid -folder-template: %w", err)
+	}
+	return t, nil
+}
+
+// assetFolder gives the folder of the asset within the destination folder
+func (app *DownloadCmd) assetFolder(a *immich.Asset) (string, error) {
+	d := FolderTemplateData{
+		Date:     AssetDate(a),
+		Albums:   app.albumsByAsset[a.ID],
+		FileName: a.OriginalFileName,
+		Type:     a.Type,
+	}
+	if len(d.Albums) > 0 {
+		d.Album = d.Albums[0]
+	}
+	if !d.Date.IsZero() {
+		d.Year = d.Date.Format("2006")
+		d.Month = d.Date.Format("01")
+		d.Day = d.Date.Format("02")
+	}
+	sb := strings.Builder{}
+	err := app.folder.Execute(&sb, d)
+	if err != nil {
+		return "", err
+	}
+	return cleanPath(sb.String()), nil
+}
+
+// cleanPath removes the empty components of the path and the characters refused by the file systems
+func cleanPath(p string) string {
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(p, "\\", "/"), "/") {
+		part = CleanName(part, "")
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return path.Join(parts...)
+}
+
+// CleanName removes the characters refused by the file systems from the name, def is used when the name is empty
+func CleanName(name string, def string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return def
+	}
+	return name
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/simulot/immich-go/cmd"
 	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/keeprules"
 	"github.com/simulot/immich-go/helpers/myflag"
 	"github.com/simulot/immich-go/immich"
 	"github.com/simulot/immich-go/ui"
@@ -25,6 +26,12 @@ type DuplicateCmd struct {
 	DateRange       immich.DateRange // Set capture date range
 	IgnoreTZErrors  bool             // Enable TZ error tolerance
 	IgnoreExtension bool             // Ignore file extensions when checking for duplicates
+	Similar         bool             // Look for visually identical images instead of copies with the same name
+	SimilarDistance int              // Maximum distance between the perceptual hashes of similar images
+	KeepBest        bool             // With Similar, propose to keep the image with the best resolution
+	DryRun          bool             // Display the actions but don't change anything
+	Report          string           // Write the groups into this CSV or JSON file
+	KeepRules       keeprules.Rules  // Rules choosing the copy to keep
 
 	assetsByID          map[string]*immich.Asset
 	assetsByBaseAndDate map[duplicateKey][]*immich.Asset
@@ -37,7 +44,7 @@ type duplicateKey struct {
 }
 
 func NewDuplicateCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*DuplicateCmd, error) {
-	cmd := flag.NewFlagSet("duplicate", flag.ExitOnError)
+	cmd := flag.NewFlagSet("duplicate", flag.ContinueOnError)
 	validRange := immich.DateRange{}
 	_ = validRange.Set("1850-01-04,2030-01-01")
 	app := DuplicateCmd{
@@ -53,10 +60,30 @@ func NewDuplicateCmd(ctx context.Context, common *cmd.SharedFlags, args []string
 	cmd.BoolFunc("yes", "When true, assume Yes to all actions", myflag.BoolFlagFn(&app.AssumeYes, false))
 	cmd.Var(&app.DateRange, "date", "Process only documents having a capture date in that range.")
 	cmd.BoolFunc("ignore-extension", "When true, ignores extensions when checking for duplicates (default: FALSE)", myflag.BoolFlagFn(&app.IgnoreExtension, false))
+	cmd.BoolFunc("similar", "When true, looks for visually identical images taken at the same time, whatever their names, encodings or resolutions (default: FALSE)", myflag.BoolFlagFn(&app.Similar, false))
+	cmd.IntVar(&app.SimilarDistance, "similar-distance", DefaultSimilarDistance, "Maximum number of different bits between the perceptual hashes of similar images, from 0 to 64")
+	cmd.BoolFunc("keep-best", "With -similar, proposes to keep the image having the best resolution, and to delete the others (default: FALSE)", myflag.BoolFlagFn(&app.KeepBest, false))
+	cmd.Func("keep-rules", "Ordered list of rules choosing the copy to keep: raw, size, resolution, earlier, albums, name:PATTERN", func(s string) error {
+		var err error
+		app.KeepRules, err = keeprules.Parse(s)
+		return err
+	})
+	cmd.BoolFunc("dry-run", "Display the actions but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	cmd.StringVar(&app.Report, "report", "", "Write the duplicate groups, the kept assets and the reasons into a CSV or JSON file")
 	err := cmd.Parse(args)
 	if err != nil {
 		return nil, err
 	}
+	if app.Report != "" {
+		switch strings.ToLower(path.Ext(app.Report)) {
+		case ".csv", ".json":
+		default:
+			return nil, fmt.Errorf("the -report accepts a .csv or a .json file")
+		}
+	}
+	if app.SimilarDistance < 0 || app.SimilarDistance > 64 {
+		return nil, fmt.Errorf("the -similar-distance accepts a value from 0 to 64")
+	}
 	err = app.SharedFlags.Start(ctx)
 	if err != nil {
 		return nil, err
@@ -69,10 +96,13 @@ func DuplicateCommand(ctx context.Context, common *cmd.SharedFlags, args []strin
 	if err != nil {
 		return err
 	}
+	return app.run(ctx)
+}
 
+func (app *DuplicateCmd) run(ctx context.Context) error {
 	dupCount := 0
 	fmt.Println("Get server's assets...")
-	err = app.Immich.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
+	err := app.Immich.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
 		if a.IsTrashed {
 			return nil
 		}
@@ -80,12 +110,8 @@ func DuplicateCommand(ctx context.Context, common *cmd.SharedFlags, args []strin
 			return nil
 		}
 		app.assetsByID[a.ID] = a
-		d := a.ExifInfo.DateTimeOriginal.Time.Round(time.Minute)
-		if app.IgnoreTZErrors {
-			d = time.Date(d.Year(), d.Month(), d.Day(), 0, d.Minute(), d.Second(), 0, time.UTC)
-		}
 		k := duplicateKey{
-			Date: d,
+			Date: app.roundedDate(a),
 			Name: strings.ToUpper(a.OriginalFileName + path.Ext(a.OriginalPath)),
 			Type: a.Type,
 		}
@@ -104,8 +130,58 @@ func DuplicateCommand(ctx context.Context, common *cmd.SharedFlags, args []strin
 		return err
 	}
 	fmt.Printf("%d received\n", len(app.assetsByID))
-	fmt.Printf("%d duplicate(s) determined.\n", dupCount)
 
+	var groups []duplicateGroup
+	if app.Similar {
+		groups, err = app.similarGroups(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d group(s) of similar images determined.\n", len(groups))
+	} else {
+		fmt.Printf("%d duplicate(s) determined.\n", dupCount)
+		groups = app.sameNameGroups()
+	}
+
+	for i := range groups {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		g := &groups[i]
+		if g.Similar {
+			fmt.Printf("There are %d similar images, taken on %s\n", len(g.Assets), g.Assets[0].ExifInfo.DateTimeOriginal.Format(time.RFC3339))
+		} else {
+			fmt.Printf("There are %d copies of the asset %s, taken on %s\n", len(g.Assets), g.Name, g.Assets[0].ExifInfo.DateTimeOriginal.Format(time.RFC3339))
+		}
+		if g.Similar && !app.KeepBest {
+			for _, a := range g.Assets {
+				fmt.Printf("  similar %s %dx%d, %s, %s\n", a.OriginalFileName, a.ExifInfo.ExifImageWidth, a.ExifInfo.ExifImageHeight, ui.FormatBytes(a.ExifInfo.FileSizeInByte), a.OriginalPath)
+			}
+			continue
+		}
+		g.Resolve = true
+		if app.KeepRules.IsSet() {
+			app.applyKeepRules(ctx, g)
+		}
+		err = app.resolveGroup(ctx, g)
+		if err != nil {
+			return err
+		}
+	}
+
+	if app.Report != "" {
+		err = writeReport(app.Report, groups)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Check the report file:", app.Report)
+	}
+	return nil
+}
+
+// sameNameGroups gives the groups of assets having the same name and date of capture, sorted by date.
+// Each group is sorted by file size: the biggest file is the last one.
+func (app *DuplicateCmd) sameNameGroups() []duplicateGroup {
 	keys := gen.MapFilterKeys(app.assetsByBaseAndDate, func(i []*immich.Asset) bool {
 		return len(i) > 1
 	})
@@ -122,51 +198,106 @@ func DuplicateCommand(ctx context.Context, common *cmd.SharedFlags, args []strin
 		return c == -1
 	})
 
+	groups := make([]duplicateGroup, 0, len(keys))
 	for _, k := range keys {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			l := app.assetsByBaseAndDate[k]
-			fmt.Printf("There are %d copies of the asset %s, taken on %s\n", len(l), k.Name, l[0].ExifInfo.DateTimeOriginal.Format(time.RFC3339))
-			albums := []immich.AlbumSimplified{}
-			assetsToDelete := []string{}
-			sort.Slice(l, func(i, j int) bool { return l[i].ExifInfo.FileSizeInByte < l[j].ExifInfo.FileSizeInByte })
-			for p, a := range l {
-				if p < len(l)-1 {
-					fmt.Printf("  delete %s %dx%d, %s, %s\n", a.OriginalFileName, a.ExifInfo.ExifImageWidth, a.ExifInfo.ExifImageHeight, ui.FormatBytes(a.ExifInfo.FileSizeInByte), a.OriginalPath)
-					assetsToDelete = append(assetsToDelete, a.ID)
-					r, err := app.Immich.GetAssetAlbums(ctx, a.ID)
-					if err != nil {
-						fmt.Printf("Can't get asset's albums: %s\n", err.Error())
-					} else {
-						albums = append(albums, r...)
-					}
+		l := app.assetsByBaseAndDate[k]
+		sort.Slice(l, func(i, j int) bool { return l[i].ExifInfo.FileSizeInByte < l[j].ExifInfo.FileSizeInByte })
+		groups = append(groups, duplicateGroup{Name: k.Name, Assets: l, Reason: "biggest file"})
+	}
+	return groups
+}
+
+// roundedDate gives the date of capture of the asset, rounded to the minute
+func (app *DuplicateCmd) roundedDate(a *immich.Asset) time.Time {
+	d := a.ExifInfo.DateTimeOriginal.Time.Round(time.Minute)
+	if app.IgnoreTZErrors {
+		d = time.Date(d.Year(), d.Month(), d.Day(), 0, d.Minute(), d.Second(), 0, time.UTC)
+	}
+	return d
+}
+
+// applyKeepRules moves the copy chosen by the -keep-rules at the end of the group
+func (app *DuplicateCmd) applyKeepRules(ctx context.Context, g *duplicateGroup) {
+	candidates := make([]keeprules.Candidate, len(g.Assets))
+	for i, a := range g.Assets {
+		candidates[i] = assetCandidate(a)
+		if app.KeepRules.NeedAlbums() {
+			albums, err := app.Immich.GetAssetAlbums(ctx, a.ID)
+			if err == nil {
+				candidates[i].Albums = len(albums)
+			}
+		}
+	}
+	last := len(g.Assets) - 1
+	best, reason := app.KeepRules.Best(candidates, last)
+	if reason != "" {
+		g.Reason = "rule " + reason
+	}
+	g.Assets[best], g.Assets[last] = g.Assets[last], g.Assets[best]
+}
+
+// assetCandidate describes the asset for the keep rules
+func assetCandidate(a *immich.Asset) keeprules.Candidate {
+	name := a.OriginalFileName
+	if path.Ext(name) == "" {
+		name += path.Ext(a.OriginalPath)
+	}
+	return keeprules.Candidate{
+		Name:       name,
+		Size:       int64(a.ExifInfo.FileSizeInByte),
+		Width:      a.ExifInfo.ExifImageWidth,
+		Height:     a.ExifInfo.ExifImageHeight,
+		UploadedAt: a.CreatedAt.Time,
+		Albums:     -1,
+	}
+}
+
+// resolveGroup proposes to delete the copies of the group, except the last one. The kept copy is added to the albums of the deleted ones.
+// With -dry-run, the actions are only displayed.
+func (app *DuplicateCmd) resolveGroup(ctx context.Context, g *duplicateGroup) error {
+	l := g.Assets
+	albums := []immich.AlbumSimplified{}
+	assetsToDelete := []string{}
+	for p, a := range l {
+		if p < len(l)-1 {
+			fmt.Printf("  delete %s %dx%d, %s, %s\n", a.OriginalFileName, a.ExifInfo.ExifImageWidth, a.ExifInfo.ExifImageHeight, ui.FormatBytes(a.ExifInfo.FileSizeInByte), a.OriginalPath)
+			assetsToDelete = append(assetsToDelete, a.ID)
+			if app.DryRun {
+				continue
+			}
+			r, err := app.Immich.GetAssetAlbums(ctx, a.ID)
+			if err != nil {
+				fmt.Printf("Can't get asset's albums: %s\n", err.Error())
+			} else {
+				albums = append(albums, r...)
+			}
+		} else {
+			fmt.Printf("  keep   %s %dx%d, %s, %s (%s)\n", a.OriginalFileName, a.ExifInfo.ExifImageWidth, a.ExifInfo.ExifImageHeight, ui.FormatBytes(a.ExifInfo.FileSizeInByte), a.OriginalPath, g.Reason)
+			if app.DryRun {
+				return nil
+			}
+			yes := app.AssumeYes
+			if !app.AssumeYes {
+				r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
+				if err != nil {
+					return err
+				}
+				if r == "y" {
+					yes = true
+				}
+			}
+			if yes {
+				err := app.TrashAssets(ctx, "duplicate", assetsToDelete)
+				if err != nil {
+					fmt.Printf("Can't delete asset: %s\n", err.Error())
 				} else {
-					fmt.Printf("  keep   %s %dx%d, %s, %s\n", a.OriginalFileName, a.ExifInfo.ExifImageWidth, a.ExifInfo.ExifImageHeight, ui.FormatBytes(a.ExifInfo.FileSizeInByte), a.OriginalPath)
-					yes := app.AssumeYes
-					if !app.AssumeYes {
-						r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
-						if err != nil {
-							return err
-						}
-						if r == "y" {
-							yes = true
-						}
-					}
-					if yes {
-						err = app.Immich.DeleteAssets(ctx, assetsToDelete, false)
+					g.Deleted = true
+					fmt.Println("  Asset moved to the trash")
+					for _, al := range albums {
+						fmt.Printf("  Update the album %s with the best copy\n", al.AlbumName)
+						_, err = app.Immich.AddAssetToAlbum(ctx, al.ID, []string{a.ID})
 						if err != nil {
 							fmt.Printf("Can't delete asset: %s\n", err.Error())
-						} else {
-							fmt.Println("  Asset removed")
-							for _, al := range albums {
-								fmt.Printf("  Update the album %s with the best copy\n", al.AlbumName)
-								_, err = app.Immich.AddAssetToAlbum(ctx, al.ID, []string{a.ID})
-								if err != nil {
-									fmt.Printf("Can't delete asset: %s\n", err.Error())
-								}
-							}
 						}
 					}
 				}
@@ -0,0 +1,77 @@
+package duplicate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/keeprules"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icAlbums gives the albums of the assets
+type icAlbums struct {
+	icDuplicates
+	albums map[string]int
+}
+
+func (c *icAlbums) GetAssetAlbums(ctx context.Context, id string) ([]immich.AlbumSimplified, error) {
+	return make([]immich.AlbumSimplified, c.albums[id]), nil
+}
+
+func TestKeepRules(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rules  string
+		keep   string
+		reason string
+	}{
+		{rules: "size", keep: "big", reason: "rule size"},
+		{rules: "earlier,size", keep: "small", reason: "rule earlier"},
+		{rules: "albums", keep: "small", reason: "rule albums"},
+		{rules: "resolution", keep: "big", reason: "biggest file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rules, func(t *testing.T) {
+			ic := &icAlbums{albums: map[string]int{"small": 2, "big": 1}}
+			for i, a := range []struct {
+				id   string
+				size int
+			}{{"small", 100}, {"big", 200}} {
+				asset := &immich.Asset{ID: a.id, OriginalFileName: "IMG_0001.jpg", Type: "IMAGE"}
+				asset.ExifInfo.DateTimeOriginal.Time = date
+				asset.ExifInfo.FileSizeInByte = a.size
+				asset.CreatedAt.Time = date.AddDate(0, 0, i)
+				ic.assets = append(ic.assets, asset)
+			}
+			app := newDuplicateCmd(ic)
+			app.DryRun = true
+			app.Report = filepath.Join(t.TempDir(), "report.json")
+			var err error
+			app.KeepRules, err = keeprules.Parse(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = app.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(app.Report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var report []reportGroup
+			if err = json.Unmarshal(b, &report); err != nil {
+				t.Fatal(err)
+			}
+			if len(report) != 1 || len(report[0].Assets) != 2 {
+				t.Fatalf("unexpected report: %+v", report)
+			}
+			if kept := report[0].Assets[1]; kept.ID != tt.keep || kept.Action != "keep" || report[0].Reason != tt.reason {
+				t.Errorf("kept %s for %q, expected %s for %q", kept.ID, report[0].Reason, tt.keep, tt.reason)
+			}
+		})
+	}
+}
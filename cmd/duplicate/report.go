@@ -0,0 +1,114 @@
+package duplicate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// duplicateGroup is a group of copies of the same image
+type duplicateGroup struct {
+	Name    string          // name of the copies, empty for similar images
+	Similar bool            // the images are visually identical, they don't have the same name
+	Assets  []*immich.Asset // the kept asset is the last one
+	Reason  string          // why the last asset is kept
+	Resolve bool            // the deletion of the other assets is proposed
+	Deleted bool            // the other assets are deleted
+}
+
+// action gives what happens to the nth asset of the group
+func (g duplicateGroup) action(n int) string {
+	switch {
+	case !g.Resolve:
+		return "report"
+	case n == len(g.Assets)-1:
+		return "keep"
+	default:
+		return "delete"
+	}
+}
+
+type reportAsset struct {
+	ID       string    `json:"id"`
+	FileName string    `json:"fileName"`
+	Path     string    `json:"path"`
+	Date     time.Time `json:"date"`
+	Width    int       `json:"width"`
+	Height   int       `json:"height"`
+	Size     int       `json:"size"`
+	Action   string    `json:"action"` // keep, delete or report
+}
+
+type reportGroup struct {
+	Group   int           `json:"group"`
+	Kind    string        `json:"kind"` // same-name or similar
+	Reason  string        `json:"reason,omitempty"`
+	Deleted bool          `json:"deleted"`
+	Assets  []reportAsset `json:"assets"`
+}
+
+func newReport(groups []duplicateGroup) []reportGroup {
+	report := make([]reportGroup, 0, len(groups))
+	for i, g := range groups {
+		rg := reportGroup{Group: i + 1, Kind: "same-name", Deleted: g.Deleted}
+		if g.Similar {
+			rg.Kind = "similar"
+		}
+		if g.Resolve {
+			rg.Reason = g.Reason
+		}
+		for n, a := range g.Assets {
+			rg.Assets = append(rg.Assets, reportAsset{
+				ID:       a.ID,
+				FileName: a.OriginalFileName,
+				Path:     a.OriginalPath,
+				Date:     a.ExifInfo.DateTimeOriginal.Time,
+				Width:    a.ExifInfo.ExifImageWidth,
+				Height:   a.ExifInfo.ExifImageHeight,
+				Size:     a.ExifInfo.FileSizeInByte,
+				Action:   g.action(n),
+			})
+		}
+		report = append(report, rg)
+	}
+	return report
+}
+
+// writeReport writes the groups into a JSON file, or a CSV file with a line per asset
+func writeReport(name string, groups []duplicateGroup) error {
+	report := newReport(groups)
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.ToLower(path.Ext(name)) == ".json" {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"group", "kind", "action", "reason", "id", "file name", "path", "date", "width", "height", "size"})
+	for _, g := range report {
+		for _, a := range g.Assets {
+			reason := ""
+			if a.Action == "keep" {
+				reason = g.Reason
+			}
+			_ = w.Write([]string{
+				strconv.Itoa(g.Group), g.Kind, a.Action, reason, a.ID, a.FileName, a.Path,
+				a.Date.Format(time.RFC3339), strconv.Itoa(a.Width), strconv.Itoa(a.Height), strconv.Itoa(a.Size),
+			})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
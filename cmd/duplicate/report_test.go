@@ -0,0 +1,100 @@
+package duplicate
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icDuplicates serves the assets and records the deletions
+type icDuplicates struct {
+	fakeimmich.MockedCLient
+	assets  []*immich.Asset
+	deleted []string
+}
+
+func (c *icDuplicates) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icDuplicates) DeleteAssets(ctx context.Context, ids []string, force bool) error {
+	c.deleted = append(c.deleted, ids...)
+	return nil
+}
+
+func newDuplicateCmd(ic immich.ImmichInterface) *DuplicateCmd {
+	return &DuplicateCmd{
+		SharedFlags:         &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))},
+		assetsByID:          map[string]*immich.Asset{},
+		assetsByBaseAndDate: map[duplicateKey][]*immich.Asset{},
+	}
+}
+
+func TestDryRunReport(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	ic := &icDuplicates{}
+	for _, a := range []struct {
+		id, name string
+		size     int
+	}{{"small", "IMG_0001.jpg", 100}, {"big", "IMG_0001.jpg", 200}, {"alone", "IMG_0002.jpg", 100}} {
+		asset := &immich.Asset{ID: a.id, OriginalFileName: a.name, Type: "IMAGE"}
+		asset.ExifInfo.DateTimeOriginal.Time = date
+		asset.ExifInfo.FileSizeInByte = a.size
+		ic.assets = append(ic.assets, asset)
+	}
+
+	for _, ext := range []string{".json", ".csv"} {
+		t.Run(ext, func(t *testing.T) {
+			app := newDuplicateCmd(ic)
+			app.DryRun = true
+			app.Report = filepath.Join(t.TempDir(), "report"+ext)
+			if err := app.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if len(ic.deleted) > 0 {
+				t.Errorf("assets deleted with -dry-run: %v", ic.deleted)
+			}
+			f, err := os.Open(app.Report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if ext == ".json" {
+				var report []reportGroup
+				if err = json.NewDecoder(f).Decode(&report); err != nil {
+					t.Fatal(err)
+				}
+				if len(report) != 1 || len(report[0].Assets) != 2 || report[0].Reason != "biggest file" {
+					t.Fatalf("unexpected report: %+v", report)
+				}
+				if a := report[0].Assets; a[0].ID != "small" || a[0].Action != "delete" || a[1].ID != "big" || a[1].Action != "keep" {
+					t.Errorf("unexpected actions: %+v", a)
+				}
+				return
+			}
+			lines, err := csv.NewReader(f).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lines) != 3 || lines[2][2] != "keep" || lines[2][3] != "biggest file" || lines[2][4] != "big" {
+				t.Errorf("unexpected report: %v", lines)
+			}
+		})
+	}
+}
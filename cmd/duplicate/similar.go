@@ -0,0 +1,130 @@
+package duplicate
+
+import (
+	"context"
+	"image/jpeg"
+	"sort"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/imagehash"
+	"github.com/simulot/immich-go/immich"
+)
+
+// DefaultSimilarDistance is the default value of the -similar-distance option
+const DefaultSimilarDistance = 4
+
+// similarGroups gives the groups of visually identical images. Only the images taken at the same minute are compared,
+// by the perceptual hashes of their previews. Each group is sorted by resolution, then by file size: the best image is the last one.
+func (app *DuplicateCmd) similarGroups(ctx context.Context) ([]duplicateGroup, error) {
+	byDate := map[time.Time][]*immich.Asset{}
+	for _, a := range app.assetsByID {
+		if a.Type != "IMAGE" || a.ExifInfo.DateTimeOriginal.IsZero() {
+			continue
+		}
+		d := app.roundedDate(a)
+		byDate[d] = append(byDate[d], a)
+	}
+	dates := make([]time.Time, 0, len(byDate))
+	for d, l := range byDate {
+		if len(l) > 1 {
+			dates = append(dates, d)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	var groups [][]*immich.Asset
+	for _, d := range dates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		l := byDate[d]
+		sort.Slice(l, func(i, j int) bool { return l[i].ID < l[j].ID })
+
+		var assets []*immich.Asset
+		var hashes []imagehash.Hash
+		for _, a := range l {
+			h, err := app.previewHash(ctx, a)
+			if err != nil {
+				app.Log.Error("can't get the preview of the asset", "file", a.OriginalFileName, "id", a.ID, "error", err.Error())
+				continue
+			}
+			assets = append(assets, a)
+			hashes = append(hashes, h)
+		}
+		groups = append(groups, groupByHash(assets, hashes, app.SimilarDistance)...)
+	}
+	result := make([]duplicateGroup, 0, len(groups))
+	for _, g := range groups {
+		sort.SliceStable(g, func(i, j int) bool {
+			ri, rj := resolution(g[i]), resolution(g[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return g[i].ExifInfo.FileSizeInByte < g[j].ExifInfo.FileSizeInByte
+		})
+		reason := "biggest file"
+		if resolution(g[len(g)-1]) > resolution(g[len(g)-2]) {
+			reason = "best resolution"
+		}
+		result = append(result, duplicateGroup{Similar: true, Assets: g, Reason: reason})
+	}
+	return result, nil
+}
+
+func resolution(a *immich.Asset) int {
+	return a.ExifInfo.ExifImageWidth * a.ExifInfo.ExifImageHeight
+}
+
+// groupByHash groups the assets whose hashes are within the distance, directly or through other assets of the group.
+// The groups of a single asset are dropped.
+func groupByHash(assets []*immich.Asset, hashes []imagehash.Hash, distance int) [][]*immich.Asset {
+	parent := make([]int, len(assets))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for i := range assets {
+		for j := i + 1; j < len(assets); j++ {
+			if imagehash.Distance(hashes[i], hashes[j]) <= distance {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+
+	byRoot := map[int][]*immich.Asset{}
+	var roots []int
+	for i, a := range assets {
+		r := root(i)
+		if _, ok := byRoot[r]; !ok {
+			roots = append(roots, r)
+		}
+		byRoot[r] = append(byRoot[r], a)
+	}
+	var groups [][]*immich.Asset
+	for _, r := range roots {
+		if len(byRoot[r]) > 1 {
+			groups = append(groups, byRoot[r])
+		}
+	}
+	return groups
+}
+
+func (app *DuplicateCmd) previewHash(ctx context.Context, a *immich.Asset) (imagehash.Hash, error) {
+	r, err := app.Immich.GetAssetPreview(ctx, a.ID)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	img, err := jpeg.Decode(r)
+	if err != nil {
+		return 0, err
+	}
+	return imagehash.DHash(img), nil
+}
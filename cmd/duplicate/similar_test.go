@@ -0,0 +1,82 @@
+package duplicate
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icPreviews serves the previews of the assets
+type icPreviews struct {
+	fakeimmich.MockedCLient
+	previews map[string][]byte
+}
+
+func (c *icPreviews) GetAssetPreview(ctx context.Context, id string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(c.previews[id])), nil
+}
+
+// picture encodes a picture of the given size, quality gives the JPEG compression
+func picture(t *testing.T, w, h int, offset int, quality int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*5*256/w + y*2*256/h + offset) % 256)
+			img.Set(x, y, color.RGBA{v, 255 - v, v / 3, 255})
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestSimilarGroups(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	ic := &icPreviews{previews: map[string][]byte{
+		"original":   picture(t, 400, 300, 0, 95),
+		"google":     picture(t, 200, 150, 0, 40),
+		"other":      picture(t, 400, 300, 128, 95),
+		"later":      picture(t, 400, 300, 0, 95),
+		"screenshot": picture(t, 400, 300, 0, 95),
+	}}
+	app := &DuplicateCmd{
+		SharedFlags:     &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))},
+		SimilarDistance: DefaultSimilarDistance,
+		assetsByID:      map[string]*immich.Asset{},
+	}
+	add := func(id string, w, h int, d time.Time) {
+		a := &immich.Asset{ID: id, OriginalFileName: id + ".jpg", Type: "IMAGE"}
+		a.ExifInfo.DateTimeOriginal.Time = d
+		a.ExifInfo.ExifImageWidth, a.ExifInfo.ExifImageHeight = w, h
+		app.assetsByID[id] = a
+	}
+	add("original", 4000, 3000, date)
+	add("google", 2048, 1536, date.Add(10*time.Second))
+	add("other", 4000, 3000, date)
+	add("later", 4000, 3000, date.Add(time.Hour))
+	add("screenshot", 4000, 3000, time.Time{})
+
+	groups, err := app.similarGroups(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || len(groups[0].Assets) != 2 {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+	l := groups[0].Assets
+	if l[0].ID != "google" || l[1].ID != "original" || groups[0].Reason != "best resolution" {
+		t.Errorf("the best image must be the last one, got %s, %s, %s", l[0].ID, l[1].ID, groups[0].Reason)
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+)
+
+// Exit codes of the program, so scripts can branch on the result of a command
+const (
+	ExitOK               = 0   // all good
+	ExitFatal            = 1   // the command has failed
+	ExitFileErrors       = 2   // the command has completed, but some files are in error
+	ExitServerDuplicates = 3   // the command has completed, but some files were not uploaded because the server already has them
+	ExitInterrupted      = 130 // the command was interrupted by Ctrl+C
+)
+
+// ExitError is an error that gives the exit code of the program
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return ""
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode gives the exit code matching the error returned by a command
+func ExitCode(err error) int {
+	var e *ExitError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &e):
+		return e.Code
+	}
+	return ExitFatal
+}
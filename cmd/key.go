@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// readKey reads the API key from the key file, or from the standard input when the key is -,
+// so the key isn't visible on the command line. The key file takes precedence over the -key option.
+func (app *SharedFlags) readKey(stdin io.Reader) error {
+	switch {
+	case app.KeyFile != "":
+		b, err := os.ReadFile(app.KeyFile)
+		if err != nil {
+			return fmt.Errorf("can't read the key file: %w", err)
+		}
+		app.Key = strings.TrimSpace(string(b))
+		if app.Key == "" {
+			return fmt.Errorf("the key file %s is empty", app.KeyFile)
+		}
+		app.KeyFile = ""
+	case app.Key == "-":
+		var line string
+		if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			fmt.Print("API key: ")
+			b, err := term.ReadPassword(int(f.Fd()))
+			fmt.Println()
+			if err != nil {
+				return err
+			}
+			line = string(b)
+		} else {
+			var err error
+			line, err = bufio.NewReader(stdin).ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return fmt.Errorf("can't read the key from the standard input: %w", err)
+			}
+		}
+		app.Key = strings.TrimSpace(line)
+		if app.Key == "" {
+			return errors.New("no key given on the standard input")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "immich_key")
+	err := os.WriteFile(secret, []byte("FILE_KEY\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	err = os.WriteFile(empty, []byte("\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		keyFile string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "key", key: "KEY", want: "KEY"},
+		{name: "key file", keyFile: secret, want: "FILE_KEY"},
+		{name: "key file over the key", key: "PROFILE_KEY", keyFile: secret, want: "FILE_KEY"},
+		{name: "missing key file", keyFile: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "empty key file", keyFile: empty, wantErr: true},
+		{name: "standard input", key: "-", stdin: "STDIN_KEY\nnext line", want: "STDIN_KEY"},
+		{name: "standard input without end of line", key: "-", stdin: "STDIN_KEY", want: "STDIN_KEY"},
+		{name: "empty standard input", key: "-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &SharedFlags{Key: tt.key, KeyFile: tt.keyFile}
+			err := app.readKey(strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && app.Key != tt.want {
+				t.Errorf("expected key %q, got %q", tt.want, app.Key)
+			}
+		})
+	}
+}
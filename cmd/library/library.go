@@ -0,0 +1,239 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+)
+
+// LibraryCmd registers the external libraries of the server and triggers their scan
+type LibraryCmd struct {
+	*cmd.SharedFlags
+	User    string   // add: owner of the library, email, name or ID of the user
+	Name    string   // add: name of the library
+	Exclude []string // add: patterns of the files ignored by the server
+	Scan    bool     // add: scan the library once created
+	DryRun  bool
+}
+
+// LibraryCommand dispatches the add, scan and list commands
+func LibraryCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "add", "scan", "list":
+			app, names, err := newLibraryCmd(ctx, common, args[0], args[1:])
+			if err != nil {
+				return err
+			}
+			switch args[0] {
+			case "add":
+				return app.add(ctx, names)
+			case "scan":
+				return app.scan(ctx, names)
+			default:
+				return app.list(ctx)
+			}
+		}
+	}
+	return fmt.Errorf("library needs a command: add|scan|list")
+}
+
+func newLibraryCmd(ctx context.Context, common *cmd.SharedFlags, action string, args []string) (*LibraryCmd, []string, error) {
+	app := &LibraryCmd{
+		SharedFlags: common,
+	}
+	cmd := flag.NewFlagSet("library "+action, flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	if action == "add" {
+		cmd.StringVar(&app.User, "user", "", "Owner of the library: email, name or ID of the user (default: the owner of the key)")
+		cmd.StringVar(&app.Name, "name", "", "Name of the library (default: the name of the first folder)")
+		cmd.Func("exclude", "Pattern of the files ignored by the server, ex: **/@eaDir/**, can be repeated", func(s string) error {
+			app.Exclude = append(app.Exclude, s)
+			return nil
+		})
+		cmd.BoolFunc("scan", "Scan the library once created (default: FALSE)", myflag.BoolFlagFn(&app.Scan, false))
+	}
+	if action != "list" {
+		cmd.BoolFunc("dry-run", "Display the actions but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	}
+	names, err := parseArgs(cmd, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return app, names, nil
+}
+
+// parseArgs parses the flags placed before, between or after the arguments, and gives the arguments
+func parseArgs(cmd *flag.FlagSet, args []string) ([]string, error) {
+	var names []string
+	for {
+		err := cmd.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.NArg() == 0 {
+			return names, nil
+		}
+		names = append(names, cmd.Arg(0))
+		args = cmd.Args()[1:]
+	}
+}
+
+// add registers the folders as an external library. The folders are paths seen by the server, not by immich-go.
+func (app *LibraryCmd) add(ctx context.Context, folders []string) error {
+	if len(folders) == 0 {
+		return errors.New("the library add command needs the folders of the library, as seen by the server")
+	}
+	for _, f := range folders {
+		if !path.IsAbs(f) {
+			return fmt.Errorf("the folder %q must be an absolute path on the server", f)
+		}
+	}
+	owner, err := app.owner(ctx)
+	if err != nil {
+		return err
+	}
+	name := app.Name
+	if name == "" {
+		name = path.Base(folders[0])
+	}
+	fmt.Printf("Add the library '%s' of %s: %s\n", name, ownerName(owner), strings.Join(folders, ", "))
+	if app.DryRun {
+		return nil
+	}
+	l, err := app.Immich.CreateLibrary(ctx, immich.LibraryCreate{
+		OwnerID:           owner.ID,
+		Name:              name,
+		ImportPaths:       folders,
+		ExclusionPatterns: app.Exclude,
+	})
+	if err != nil {
+		return fmt.Errorf("can't create the library: %w", err)
+	}
+	fmt.Printf("Library '%s' created, ID %s\n", l.Name, l.ID)
+	if !app.Scan {
+		return nil
+	}
+	err = app.Immich.ScanLibrary(ctx, l.ID)
+	if err != nil {
+		return fmt.Errorf("can't scan the library: %w", err)
+	}
+	fmt.Printf("Scan of the library '%s' started\n", l.Name)
+	return nil
+}
+
+// owner gives the user named by the -user option, or the owner of the key
+func (app *LibraryCmd) owner(ctx context.Context) (immich.User, error) {
+	if app.User == "" {
+		u, err := app.Immich.ValidateConnection(ctx)
+		if err != nil {
+			return u, fmt.Errorf("can't get the owner of the key: %w", err)
+		}
+		return u, nil
+	}
+	users, err := app.Immich.GetAllUsers(ctx)
+	if err != nil {
+		return immich.User{}, fmt.Errorf("can't get the users, only the administrators can add a library for another user: %w", err)
+	}
+	for _, u := range users {
+		if u.ID == app.User || strings.EqualFold(u.Email, app.User) || strings.EqualFold(u.Name, app.User) {
+			return u, nil
+		}
+	}
+	return immich.User{}, fmt.Errorf("user %q not found", app.User)
+}
+
+func ownerName(u immich.User) string {
+	if u.Name != "" {
+		return u.Name
+	}
+	return u.Email
+}
+
+// scan triggers the scan of the libraries having the names, all the libraries when no name is given
+func (app *LibraryCmd) scan(ctx context.Context, names []string) error {
+	libraries, err := app.Immich.GetAllLibraries(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the libraries: %w", err)
+	}
+	var selected []immich.Library
+	for _, l := range libraries {
+		if len(names) == 0 || matchLibrary(l, names) {
+			selected = append(selected, l)
+		}
+	}
+	for _, n := range names {
+		if !hasLibrary(selected, n) {
+			return fmt.Errorf("library %q not found", n)
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Println("No library to scan")
+		return nil
+	}
+	for _, l := range selected {
+		fmt.Printf("Scan the library '%s'\n", l.Name)
+		if app.DryRun {
+			continue
+		}
+		err = app.Immich.ScanLibrary(ctx, l.ID)
+		if err != nil {
+			return fmt.Errorf("can't scan the library '%s': %w", l.Name, err)
+		}
+	}
+	return nil
+}
+
+// matchLibrary tells if the library is named by one of the names, or by its ID
+func matchLibrary(l immich.Library, names []string) bool {
+	for _, n := range names {
+		if l.ID == n || strings.EqualFold(l.Name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLibrary(libraries []immich.Library, name string) bool {
+	for _, l := range libraries {
+		if matchLibrary(l, []string{name}) {
+			return true
+		}
+	}
+	return false
+}
+
+// list prints the libraries of the server
+func (app *LibraryCmd) list(ctx context.Context) error {
+	libraries, err := app.Immich.GetAllLibraries(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the libraries: %w", err)
+	}
+	if len(libraries) == 0 {
+		fmt.Println("No library")
+		return nil
+	}
+	for _, l := range libraries {
+		refreshed := "never scanned"
+		if l.RefreshedAt != nil {
+			refreshed = "scanned on " + l.RefreshedAt.Local().Format(time.DateTime)
+		}
+		fmt.Printf("%s: %d asset(s), %s\n", l.Name, l.AssetCount, refreshed)
+		for _, p := range l.ImportPaths {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	return nil
+}
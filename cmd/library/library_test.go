@@ -0,0 +1,132 @@
+package library
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icLibraries serves the users and the libraries, and records the changes
+type icLibraries struct {
+	fakeimmich.MockedCLient
+	libraries []immich.Library
+	created   []immich.LibraryCreate
+	scanned   []string
+}
+
+func (c *icLibraries) ValidateConnection(ctx context.Context) (immich.User, error) {
+	return immich.User{ID: "u1", Email: "admin@example.com", Name: "Admin"}, nil
+}
+
+func (c *icLibraries) GetAllUsers(ctx context.Context) ([]immich.User, error) {
+	return []immich.User{
+		{ID: "u1", Email: "admin@example.com", Name: "Admin"},
+		{ID: "u2", Email: "grandma@example.com", Name: "Grandma"},
+	}, nil
+}
+
+func (c *icLibraries) GetAllLibraries(ctx context.Context) ([]immich.Library, error) {
+	return c.libraries, nil
+}
+
+func (c *icLibraries) CreateLibrary(ctx context.Context, l immich.LibraryCreate) (immich.Library, error) {
+	c.created = append(c.created, l)
+	return immich.Library{ID: "new", Name: l.Name, OwnerID: l.OwnerID, ImportPaths: l.ImportPaths}, nil
+}
+
+func (c *icLibraries) ScanLibrary(ctx context.Context, id string) error {
+	c.scanned = append(c.scanned, id)
+	return nil
+}
+
+func newTestCmd() (*LibraryCmd, *icLibraries) {
+	ic := &icLibraries{
+		libraries: []immich.Library{
+			{ID: "l1", Name: "Photos", ImportPaths: []string{"/mnt/photos"}},
+			{ID: "l2", Name: "Scans", ImportPaths: []string{"/mnt/scans"}},
+		},
+	}
+	app := &LibraryCmd{SharedFlags: &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}}
+	return app, ic
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		lib     string
+		scan    bool
+		dryRun  bool
+		folders []string
+		want    []immich.LibraryCreate
+		scanned []string
+		wantErr bool
+	}{
+		{
+			name:    "owner of the key",
+			folders: []string{"/mnt/nas/family"},
+			want:    []immich.LibraryCreate{{OwnerID: "u1", Name: "family", ImportPaths: []string{"/mnt/nas/family"}}},
+		},
+		{
+			name:    "other user and scan",
+			user:    "GRANDMA@example.com",
+			lib:     "Grandma's photos",
+			scan:    true,
+			folders: []string{"/mnt/nas/grandma", "/mnt/usb/grandma"},
+			want:    []immich.LibraryCreate{{OwnerID: "u2", Name: "Grandma's photos", ImportPaths: []string{"/mnt/nas/grandma", "/mnt/usb/grandma"}}},
+			scanned: []string{"new"},
+		},
+		{name: "unknown user", user: "nobody", folders: []string{"/mnt/nas"}, wantErr: true},
+		{name: "relative folder", folders: []string{"nas"}, wantErr: true},
+		{name: "no folder", wantErr: true},
+		{name: "dry run", dryRun: true, scan: true, folders: []string{"/mnt/nas"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, ic := newTestCmd()
+			app.User, app.Name, app.Scan, app.DryRun = tt.user, tt.lib, tt.scan, tt.dryRun
+			err := app.add(context.Background(), tt.folders)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ic.created, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, ic.created)
+			}
+			if !reflect.DeepEqual(ic.scanned, tt.scanned) {
+				t.Errorf("expected the scans %v, got %v", tt.scanned, ic.scanned)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", want: []string{"l1", "l2"}},
+		{name: "by name", args: []string{"scans"}, want: []string{"l2"}},
+		{name: "by id", args: []string{"l1"}, want: []string{"l1"}},
+		{name: "unknown", args: []string{"Photos", "Videos"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, ic := newTestCmd()
+			err := app.scan(context.Background(), tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ic.scanned, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ic.scanned)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Open a session on the server with its OAuth login, for the servers where the API keys are restricted.
+*/
+package login
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/helpers/myflag"
+)
+
+type LoginCmd struct {
+	*cmd.SharedFlags
+	RedirectURI string // address receiving the redirection of the identity provider
+	NoBrowser   bool   // print the address of the login page without opening the browser
+}
+
+func LoginCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app := &LoginCmd{
+		SharedFlags: common,
+		RedirectURI: cmd.DefaultRedirectURI,
+	}
+	cmd := flag.NewFlagSet("login", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.StringVar(&app.RedirectURI, "redirect-uri", app.RedirectURI, "Address receiving the redirection of the identity provider, allowed in the provider's OAuth client settings")
+	cmd.BoolFunc("no-browser", "Print the address of the login page without opening the browser", myflag.BoolFlagFn(&app.NoBrowser, false))
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *LoginCmd) run(ctx context.Context) error {
+	conf, err := configuration.ConfigRead(app.ConfigurationFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if app.Server == "" && app.API == "" {
+		app.Server, app.API = conf.ServerURL, conf.APIURL
+	}
+	if app.Server == "" && app.API == "" {
+		return errors.New("missing -server, Immich server address (http://<your-ip>:2283 or https://<your-domain>)")
+	}
+	app.Server = strings.TrimSuffix(app.Server, "/")
+	// the session is used instead of the key
+	app.Key = ""
+
+	open := cmd.OpenBrowser
+	if app.NoBrowser {
+		open = nil
+	}
+	login, err := app.OAuthLogin(ctx, app.RedirectURI, open)
+	if err != nil {
+		return err
+	}
+
+	// the next commands use the server of the configuration file with the session instead of a key
+	conf = configuration.Configuration{ServerURL: app.Server, APIURL: app.API}
+	err = configuration.MakeDirForFile(app.ConfigurationFile)
+	if err != nil {
+		return err
+	}
+	err = conf.Write(app.ConfigurationFile)
+	if err != nil {
+		return fmt.Errorf("can't write into the configuration file: %w", err)
+	}
+	fmt.Printf("Logged in as %s, the session is stored into %s\n", login.UserEmail, app.SessionsFile)
+	return nil
+}
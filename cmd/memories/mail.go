@@ -0,0 +1,178 @@
+package memories
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// mailer sends the memories by email, with the previews of the assets attached
+type mailer struct {
+	To       []string // Recipients
+	From     string   // Sender, the SMTP user by default
+	Server   string   // SMTP server, host:port
+	User     string   // SMTP user, no authentication when empty
+	Password string   // SMTP password
+	Max      int      // Maximum number of attached previews
+
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error // smtp.SendMail by default
+}
+
+func newMailer() mailer {
+	return mailer{Max: 20, send: smtp.SendMail}
+}
+
+// SetFlags adds the options of the email to the flag set
+func (m *mailer) SetFlags(cmd *flag.FlagSet) {
+	cmd.Func("mail-to", "Send the memories to this address, can be repeated", func(s string) error {
+		for _, a := range strings.Split(s, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				m.To = append(m.To, a)
+			}
+		}
+		return nil
+	})
+	cmd.StringVar(&m.From, "mail-from", "", "Sender of the email (default: the -smtp-user)")
+	cmd.StringVar(&m.Server, "smtp-server", "", "SMTP server sending the email, host:port")
+	cmd.StringVar(&m.User, "smtp-user", "", "User of the SMTP server, no authentication when empty")
+	cmd.StringVar(&m.Password, "smtp-password", "", "Password of the SMTP user")
+	cmd.IntVar(&m.Max, "mail-max", m.Max, "Maximum number of previews attached to the email (default: 20)")
+}
+
+// Validate checks the options of the email
+func (m *mailer) Validate() error {
+	if len(m.To) == 0 {
+		return nil
+	}
+	if m.Server == "" {
+		return errors.New("the option -mail-to needs the -smtp-server")
+	}
+	if _, _, err := net.SplitHostPort(m.Server); err != nil {
+		return fmt.Errorf("the -smtp-server must be given as host:port: %w", err)
+	}
+	if m.From == "" {
+		m.From = m.User
+	}
+	if m.From == "" {
+		return errors.New("the option -mail-to needs the -mail-from")
+	}
+	if m.Max < 0 {
+		return errors.New("the option -mail-max must be positive")
+	}
+	return nil
+}
+
+// attachment is a file attached to the email
+type attachment struct {
+	name string
+	data []byte
+}
+
+// sendMemories sends the previews of the memories' assets, the most recent years first
+func (app *MemoriesCmd) sendMemories(ctx context.Context, memories []immich.Memory) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "On this day, %s:\n\n", app.Day.Format("January 2"))
+	var files []attachment
+	total := 0
+	for _, m := range memories {
+		fmt.Fprintf(&text, "%d, %s: %d asset(s)\n", m.Data.Year, yearsAgo(app.Day, m.Data.Year), len(m.Assets))
+		for _, a := range m.Assets {
+			total++
+			if server := app.ServerName(); server != "" {
+				fmt.Fprintf(&text, "  %s/photos/%s\n", strings.TrimSuffix(server, "/"), a.ID)
+			}
+			if len(files) >= app.Mail.Max {
+				continue
+			}
+			b, err := app.preview(ctx, a.ID)
+			if err != nil {
+				app.Log.Error("can't get the preview of the asset", "file", a.OriginalFileName, "id", a.ID, "error", err.Error())
+				continue
+			}
+			files = append(files, attachment{name: strings.TrimSuffix(a.OriginalFileName, path.Ext(a.OriginalFileName)) + ".jpg", data: b})
+		}
+	}
+	if total > len(files) {
+		fmt.Fprintf(&text, "\n%d of the %d assets are attached.\n", len(files), total)
+	}
+	subject := fmt.Sprintf("On this day: %d memories", total)
+	msg, err := buildMessage(app.Mail.From, app.Mail.To, subject, text.String(), files)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if app.Mail.User != "" {
+		host, _, _ := net.SplitHostPort(app.Mail.Server)
+		auth = smtp.PlainAuth("", app.Mail.User, app.Mail.Password, host)
+	}
+	return app.Mail.send(app.Mail.Server, auth, app.Mail.From, app.Mail.To, msg)
+}
+
+func (app *MemoriesCmd) preview(ctx context.Context, id string) ([]byte, error) {
+	r, err := app.Immich.GetAssetPreview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// buildMessage gives the email with the text and the attached JPEG files
+func buildMessage(from string, to []string, subject string, text string, files []attachment) ([]byte, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", w.Boundary())
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "8bit")
+	p, err := w.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(p, strings.ReplaceAll(text, "\n", "\r\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", "image/jpeg")
+		h.Set("Content-Transfer-Encoding", "base64")
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.name}))
+		p, err := w.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		s := base64.StdEncoding.EncodeToString(f.data)
+		for len(s) > 76 {
+			_, _ = io.WriteString(p, s[:76]+"\r\n")
+			s = s[76:]
+		}
+		_, err = io.WriteString(p, s+"\r\n")
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = w.Close()
+	return b.Bytes(), err
+}
@@ -0,0 +1,208 @@
+package memories
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+)
+
+// MemoriesCmd exports the "on this day" memories of the server: the assets taken the same day of the previous years
+type MemoriesCmd struct {
+	*cmd.SharedFlags
+	Day    time.Time // Day of the memories, today by default
+	Clean  bool      // Remove from the folder the files that aren't memories of the day
+	DryRun bool      // Display the actions but don't change anything
+	Mail   mailer    // Send the memories by email
+
+	root string                // destination folder, empty when the memories are only sent by email
+	dl   *download.DownloadCmd // writes the assets into the folder
+}
+
+func NewMemoriesCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*MemoriesCmd, error) {
+	app := &MemoriesCmd{
+		SharedFlags: common,
+		Day:         time.Now(),
+		Mail:        newMailer(),
+	}
+	cmd := flag.NewFlagSet("memories", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.Func("date", "Day of the memories, YYYY-MM-DD (default: today)", func(s string) error {
+		d, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+		if err != nil {
+			return fmt.Errorf("the -date must be given as YYYY-MM-DD: %w", err)
+		}
+		app.Day = d
+		return nil
+	})
+	cmd.BoolFunc("clean", "Remove from the folder the files that aren't memories of the day, for the folders of the digital photo frames (default: FALSE)", myflag.BoolFlagFn(&app.Clean, false))
+	cmd.BoolFunc("dry-run", "Display the actions but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	app.Mail.SetFlags(cmd)
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	switch cmd.NArg() {
+	case 0:
+		if len(app.Mail.To) == 0 {
+			return nil, errors.New("the memories command needs the destination folder, or the -mail-to option")
+		}
+	case 1:
+		app.root = cmd.Arg(0)
+	default:
+		return nil, errors.New("the memories command needs one destination folder")
+	}
+	if app.Clean && app.root == "" {
+		return nil, errors.New("the option -clean needs the destination folder")
+	}
+	err = app.Mail.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if app.root != "" {
+		app.dl = download.NewDownloader(common)
+		app.dl.FolderTemplate = "{{.Year}}"
+		app.dl.Sidecar = download.SidecarNone
+		app.dl.DryRun = app.DryRun
+		err = app.dl.Validate(app.root)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func MemoriesCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewMemoriesCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *MemoriesCmd) run(ctx context.Context) error {
+	memories, err := app.Immich.GetOnThisDayMemories(ctx, app.Day)
+	if err != nil {
+		return fmt.Errorf("can't get the memories: %w", err)
+	}
+	count := 0
+	for _, m := range memories {
+		fmt.Printf("%d, %s: %d asset(s)\n", m.Data.Year, yearsAgo(app.Day, m.Data.Year), len(m.Assets))
+		count += len(m.Assets)
+	}
+	if count == 0 {
+		fmt.Printf("No memory on %s\n", app.Day.Format("January 2"))
+	}
+
+	if app.root != "" {
+		err = app.download(ctx, memories)
+		if err != nil {
+			return err
+		}
+	}
+	if len(app.Mail.To) > 0 && count > 0 {
+		if app.DryRun {
+			fmt.Printf("Send the memories to %s, dry run mode\n", strings.Join(app.Mail.To, ", "))
+			return nil
+		}
+		err = app.sendMemories(ctx, memories)
+		if err != nil {
+			return fmt.Errorf("can't send the memories: %w", err)
+		}
+		fmt.Printf("Memories sent to %s\n", strings.Join(app.Mail.To, ", "))
+	}
+	return nil
+}
+
+// download writes the assets of the memories into the folder, one folder by year
+func (app *MemoriesCmd) download(ctx context.Context, memories []immich.Memory) error {
+	keep := map[string]bool{}
+	downloaded, failed := 0, 0
+	for _, m := range memories {
+		for _, a := range m.Assets {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r, err := app.dl.DownloadAsset(ctx, a)
+			for _, f := range r.Files {
+				keep[f] = true
+			}
+			switch {
+			case err != nil:
+				failed++
+				app.Log.Error("can't download the asset", "file", a.OriginalFileName, "id", a.ID, "error", err.Error())
+			case r.Downloaded:
+				downloaded++
+			}
+		}
+	}
+	fmt.Printf("%d asset(s) downloaded, %d error(s)\n", downloaded, failed)
+	if !app.Clean || failed > 0 {
+		// the files of a failed download are kept, so the frame shows something
+		return nil
+	}
+	removed, err := cleanFolder(app.root, keep, app.DryRun)
+	if removed > 0 {
+		fmt.Printf("%d file(s) of the previous memories removed\n", removed)
+	}
+	return err
+}
+
+// cleanFolder removes the files of the folder not kept, and the empty folders
+func cleanFolder(root string, keep map[string]bool, dryRun bool) (int, error) {
+	removed := 0
+	var dirs []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == root {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if p != root {
+				dirs = append(dirs, p)
+			}
+			return nil
+		}
+		if keep[p] {
+			return nil
+		}
+		removed++
+		if dryRun {
+			return nil
+		}
+		return os.Remove(p)
+	})
+	if err != nil || dryRun {
+		return removed, err
+	}
+	// the deepest folders first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i]) // fails when the folder isn't empty
+	}
+	return removed, nil
+}
+
+// yearsAgo gives the age of the memory
+func yearsAgo(day time.Time, year int) string {
+	n := day.Year() - year
+	if n == 1 {
+		return "1 year ago"
+	}
+	return fmt.Sprintf("%d years ago", n)
+}
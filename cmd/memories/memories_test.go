@@ -0,0 +1,192 @@
+package memories
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icMemories serves the memories of the day, and the content of the assets
+type icMemories struct {
+	fakeimmich.MockedCLient
+	memories []immich.Memory
+}
+
+func (c *icMemories) GetOnThisDayMemories(ctx context.Context, day time.Time) ([]immich.Memory, error) {
+	return c.memories, nil
+}
+
+func (c *icMemories) DownloadAsset(ctx context.Context, id string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("content of " + id)), nil
+}
+
+func (c *icMemories) GetAssetPreview(ctx context.Context, id string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("preview of " + id)), nil
+}
+
+func newMemory(year int, ids ...string) immich.Memory {
+	m := immich.Memory{Type: immich.MemoryTypeOnThisDay}
+	m.Data.Year = year
+	for _, id := range ids {
+		a := &immich.Asset{ID: id, OriginalFileName: "IMG_" + id + ".jpg", Type: "IMAGE"}
+		a.ExifInfo.DateTimeOriginal.Time = time.Date(year, 6, 14, 10, 0, 0, 0, time.UTC)
+		a.ExifInfo.FileSizeInByte = len("content of " + id)
+		m.Assets = append(m.Assets, a)
+	}
+	return m
+}
+
+func newTestCmd(t *testing.T, root string, memories ...immich.Memory) *MemoriesCmd {
+	common := &cmd.SharedFlags{Immich: &icMemories{memories: memories}, Server: "http://photos:2283", Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	app := &MemoriesCmd{SharedFlags: common, Day: time.Date(2024, 6, 14, 0, 0, 0, 0, time.Local), Mail: newMailer(), root: root}
+	if root != "" {
+		app.dl = download.NewDownloader(common)
+		app.dl.FolderTemplate = "{{.Year}}"
+		app.dl.Sidecar = download.SidecarNone
+		if err := app.dl.Validate(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return app
+}
+
+// listFiles gives the files of the folder, relative to it
+func listFiles(t *testing.T, root string) []string {
+	var l []string
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			r, _ := filepath.Rel(root, p)
+			l = append(l, filepath.ToSlash(r))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(l)
+	return l
+}
+
+func TestDownloadMemories(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	app := newTestCmd(t, root, newMemory(2023, "1", "2"), newMemory(2019, "3"))
+	if err := app.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2019/IMG_3.jpg", "2023/IMG_1.jpg", "2023/IMG_2.jpg"}
+	if got := listFiles(t, root); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the files %v, got %v", want, got)
+	}
+
+	// the next day, the folder of the photo frame gets the new memories only
+	app = newTestCmd(t, root, newMemory(2020, "4"), newMemory(2019, "3"))
+	app.Clean = true
+	if err := app.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"2019/IMG_3.jpg", "2020/IMG_4.jpg"}
+	if got := listFiles(t, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the files %v, got %v", want, got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "2023")); !os.IsNotExist(err) {
+		t.Errorf("the empty folder 2023 is kept")
+	}
+}
+
+func TestMailMemories(t *testing.T) {
+	app := newTestCmd(t, "", newMemory(2023, "1", "2"), newMemory(2019, "3"))
+	app.Mail.To = []string{"grandma@example.com"}
+	app.Mail.From = "frame@example.com"
+	app.Mail.Server = "smtp.example.com:587"
+	app.Mail.Max = 2
+	var sent []byte
+	app.Mail.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if addr != "smtp.example.com:587" || from != "frame@example.com" || !reflect.DeepEqual(to, []string{"grandma@example.com"}) {
+			t.Errorf("unexpected envelope %s %s %v", addr, from, to)
+		}
+		sent = msg
+		return nil
+	}
+	if err := app.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(sent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := msg.Header.Get("Subject"); s != "On this day: 3 memories" {
+		t.Errorf("unexpected subject %q", s)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	var text string
+	var files []string
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(p)
+		if p.FileName() == "" {
+			text = string(b)
+		} else {
+			files = append(files, p.FileName())
+		}
+	}
+	if !reflect.DeepEqual(files, []string{"IMG_1.jpg", "IMG_2.jpg"}) {
+		t.Errorf("unexpected attachments %v", files)
+	}
+	for _, s := range []string{"2023, 1 year ago: 2 asset(s)", "2019, 5 years ago: 1 asset(s)", "http://photos:2283/photos/3", "2 of the 3 assets are attached."} {
+		if !strings.Contains(text, s) {
+			t.Errorf("the text doesn't contain %q:\n%s", s, text)
+		}
+	}
+}
+
+func TestMemoriesOptions(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{args: nil, err: "the memories command needs the destination folder, or the -mail-to option"},
+		{args: []string{"-clean", "-mail-to=a@example.com", "-smtp-server=smtp:25", "-mail-from=b@example.com"}, err: "the option -clean needs the destination folder"},
+		{args: []string{"-mail-to=a@example.com"}, err: "the option -mail-to needs the -smtp-server"},
+		{args: []string{"-mail-to=a@example.com", "-smtp-server=smtp:25"}, err: "the option -mail-to needs the -mail-from"},
+		{args: []string{"-date=14/06/2024", "folder"}, err: "the -date must be given as YYYY-MM-DD"},
+	}
+	for _, tt := range tests {
+		_, err := NewMemoriesCmd(context.Background(), &cmd.SharedFlags{Immich: &icMemories{}, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}, tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%v: expected the error %q, got %v", tt.args, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package metadata
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/helpers/tzone"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+	"github.com/simulot/immich-go/ui"
+)
+
+// FixMetadataCmd changes the date of capture, the time zone and the GPS location of the server's assets
+type FixMetadataCmd struct {
+	*cmd.SharedFlags
+	Albums       namematcher.List // Select the assets of the albums matching these patterns
+	Names        namematcher.List // Select the assets whose name matches these patterns
+	Make         string           // Select the assets taken by the camera of this maker
+	Model        string           // Select the assets taken by the camera of this model
+	Shift        time.Duration    // Shift the date of capture
+	TimeZone     *time.Location   // Time zone of the capture, the instant of the capture is kept
+	DateFromName bool             // Set the missing dates of capture from the file names
+	Latitude     *float64         // Set the GPS location
+	Longitude    *float64
+	Journal      string // Write the previous values into this file
+	Undo         string // Restore the values of this journal
+	AssumeYes    bool
+	DryRun       bool
+
+	dl *download.DownloadCmd // selection of the assets
+}
+
+// journalEntry gives the values of an asset before the change
+type journalEntry struct {
+	ID               string   `json:"id"`
+	FileName         string   `json:"fileName"`
+	DateTimeOriginal string   `json:"dateTimeOriginal,omitempty"`
+	Latitude         *float64 `json:"latitude,omitempty"`
+	Longitude        *float64 `json:"longitude,omitempty"`
+}
+
+func NewFixMetadataCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*FixMetadataCmd, error) {
+	app := &FixMetadataCmd{
+		SharedFlags: common,
+		Journal:     "fix-metadata-" + time.Now().Format("20060102-150405") + ".jsonl",
+		dl:          download.NewDownloader(common),
+	}
+	cmd := flag.NewFlagSet("fix-metadata", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.Var(&app.dl.DateRange, "date", "Select the assets having a capture date in that range.")
+	cmd.Var(&app.Albums, "album", "Select the assets of the albums whose name matches the pattern, can be repeated.")
+	cmd.Func("person", "Select the assets showing the person, can be repeated.", func(s string) error {
+		app.dl.People = append(app.dl.People, s)
+		return nil
+	})
+	cmd.Var(&app.Names, "name", "Select the assets whose file name matches the pattern, can be repeated.")
+	cmd.StringVar(&app.Make, "make", "", "Select the assets taken by a camera of this maker")
+	cmd.StringVar(&app.Model, "model", "", "Select the assets taken by a camera of this model")
+	cmd.DurationVar(&app.Shift, "shift", 0, "Shift the date of capture by this duration, ex: +7h, -30m")
+	cmd.Func("timezone", "Set the time zone of the capture, ex: +07:00 or Asia/Bangkok. The instant of the capture is kept", func(s string) error {
+		var err error
+		app.TimeZone, err = parseTimeZone(s)
+		return err
+	})
+	cmd.BoolFunc("date-from-name", "Set the missing dates of capture from the file names (default: FALSE)", myflag.BoolFlagFn(&app.DateFromName, false))
+	cmd.Func("gps", "Set the GPS location: LATITUDE,LONGITUDE", func(s string) error {
+		lat, lon, ok := strings.Cut(s, ",")
+		if !ok {
+			return errors.New("the -gps needs LATITUDE,LONGITUDE")
+		}
+		la, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		if err != nil || la < -90 || la > 90 {
+			return fmt.Errorf("invalid latitude %q", lat)
+		}
+		lo, err := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		if err != nil || lo < -180 || lo > 180 {
+			return fmt.Errorf("invalid longitude %q", lon)
+		}
+		app.Latitude, app.Longitude = &la, &lo
+		return nil
+	})
+	cmd.StringVar(&app.Journal, "journal", app.Journal, "Write the previous values of the changed assets into this file, to undo the changes")
+	cmd.StringVar(&app.Undo, "undo", "", "Restore the values written into this journal")
+	cmd.BoolFunc("yes", "When true, assume Yes to all actions", myflag.BoolFlagFn(&app.AssumeYes, false))
+	cmd.BoolFunc("dry-run", "Display the changes but don't touch the server's assets", myflag.BoolFlagFn(&app.DryRun, false))
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if app.Undo == "" {
+		if app.Shift == 0 && app.TimeZone == nil && !app.DateFromName && app.Latitude == nil {
+			return nil, errors.New("nothing to fix: use -shift, -timezone, -date-from-name or -gps")
+		}
+		if !app.Albums.IsSet() && !app.Names.IsSet() && !app.dl.DateRange.IsSet() && len(app.dl.People) == 0 && app.Make == "" && app.Model == "" {
+			return nil, errors.New("select the assets with -album, -date, -person, -name, -make or -model")
+		}
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func FixMetadataCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewFixMetadataCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	if app.Undo != "" {
+		return app.undo(ctx)
+	}
+	return app.run(ctx)
+}
+
+// parseTimeZone reads an offset like +07:00, or a location name like Asia/Bangkok
+func parseTimeZone(s string) (*time.Location, error) {
+	if t, err := time.Parse("-07:00", s); err == nil {
+		_, offset := t.Zone()
+		return time.FixedZone("UTC"+s, offset), nil
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", s)
+	}
+	return loc, nil
+}
+
+func (app *FixMetadataCmd) run(ctx context.Context) error {
+	if app.Albums.IsSet() {
+		err := app.dl.SelectAlbums(ctx, app.Albums)
+		if err != nil {
+			return err
+		}
+	}
+	type change struct {
+		a      *immich.Asset
+		before journalEntry
+		update immich.AssetMetadataUpdate
+	}
+	var changes []change
+	err := app.dl.GetAssets(ctx, func(a *immich.Asset) error {
+		if app.Names.IsSet() && !app.Names.Match(a.OriginalFileName) {
+			return nil
+		}
+		if app.Make != "" && !strings.EqualFold(app.Make, a.ExifInfo.Make) {
+			return nil
+		}
+		if app.Model != "" && !strings.EqualFold(app.Model, a.ExifInfo.Model) {
+			return nil
+		}
+		update, ok := app.fix(a)
+		if ok {
+			changes = append(changes, change{a: a, before: previousValues(a), update: update})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		fmt.Printf("%s:", c.a.OriginalFileName)
+		if c.update.DateTimeOriginal != "" {
+			fmt.Printf(" date %s -> %s", orNone(c.before.DateTimeOriginal), c.update.DateTimeOriginal)
+		}
+		if c.update.Latitude != nil {
+			fmt.Printf(" GPS %s -> %f,%f", formatGPS(c.before.Latitude, c.before.Longitude), *c.update.Latitude, *c.update.Longitude)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d asset(s) to fix\n", len(changes))
+	if len(changes) == 0 || app.DryRun {
+		return nil
+	}
+	if !app.AssumeYes {
+		r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
+		if err != nil {
+			return err
+		}
+		if r != "y" {
+			return nil
+		}
+	}
+
+	f, err := os.Create(app.Journal)
+	if err != nil {
+		return fmt.Errorf("can't write the journal: %w", err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	fixed := 0
+	for _, c := range changes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// the journal is written before the change, so an interrupted run can be undone
+		err = enc.Encode(c.before)
+		if err != nil {
+			return fmt.Errorf("can't write the journal: %w", err)
+		}
+		err = app.Immich.UpdateAssetMetadata(ctx, c.a.ID, c.update)
+		if err != nil {
+			app.Log.Error("can't update the asset", "file", c.a.OriginalFileName, "id", c.a.ID, "error", err.Error())
+			continue
+		}
+		fixed++
+	}
+	fmt.Printf("%d asset(s) fixed, undo the changes with -undo %s\n", fixed, app.Journal)
+	return nil
+}
+
+// fix gives the changes of the asset, false when nothing changes
+func (app *FixMetadataCmd) fix(a *immich.Asset) (immich.AssetMetadataUpdate, bool) {
+	var update immich.AssetMetadataUpdate
+	changed := false
+	date := a.ExifInfo.DateTimeOriginal.Time
+	loc := assetZone(a)
+	if app.DateFromName && date.IsZero() {
+		if d := metadata.TakeTimeFromName(path.Base(a.OriginalFileName)); !d.IsZero() {
+			// the name gives the wall clock of the capture
+			if app.TimeZone != nil {
+				loc = app.TimeZone
+			} else if local, err := tzone.Local(); err == nil {
+				loc = local
+			}
+			date = time.Date(d.Year(), d.Month(), d.Day(), d.Hour(), d.Minute(), d.Second(), 0, loc)
+			changed = true
+		}
+	}
+	if !date.IsZero() {
+		if app.Shift != 0 {
+			date = date.Add(app.Shift)
+			changed = true
+		}
+		if app.TimeZone != nil {
+			loc = app.TimeZone
+			changed = true
+		}
+	}
+	if changed {
+		update.DateTimeOriginal = date.In(loc).Format(time.RFC3339)
+	}
+	if app.Latitude != nil {
+		update.Latitude, update.Longitude = app.Latitude, app.Longitude
+		changed = true
+	}
+	return update, changed
+}
+
+// assetZone gives the time zone of the capture of the asset, the local time zone when unknown
+func assetZone(a *immich.Asset) *time.Location {
+	tz := a.ExifInfo.TimeZone
+	if offset, ok := strings.CutPrefix(tz, "UTC"); ok {
+		if offset == "" {
+			return time.UTC
+		}
+		if h, err := strconv.Atoi(offset); err == nil {
+			return time.FixedZone(tz, h*3600)
+		}
+		if loc, err := parseTimeZone(offset); err == nil {
+			return loc
+		}
+	}
+	if tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	return a.ExifInfo.DateTimeOriginal.Location()
+}
+
+// previousValues gives the values of the asset to restore them
+func previousValues(a *immich.Asset) journalEntry {
+	e := journalEntry{ID: a.ID, FileName: a.OriginalFileName}
+	if !a.ExifInfo.DateTimeOriginal.IsZero() {
+		e.DateTimeOriginal = a.ExifInfo.DateTimeOriginal.In(assetZone(a)).Format(time.RFC3339)
+	}
+	if a.ExifInfo.Latitude != 0 || a.ExifInfo.Longitude != 0 {
+		lat, lon := a.ExifInfo.Latitude, a.ExifInfo.Longitude
+		e.Latitude, e.Longitude = &lat, &lon
+	}
+	return e
+}
+
+// undo restores the values written into the journal. The dates and the locations that were missing can't be removed.
+func (app *FixMetadataCmd) undo(ctx context.Context) error {
+	f, err := os.Open(app.Undo)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var entries []journalEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return fmt.Errorf("can't read the journal %s: %w", app.Undo, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	restored := 0
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if e.DateTimeOriginal == "" && e.Latitude == nil {
+			fmt.Printf("%s: the previous values were missing, they can't be restored\n", e.FileName)
+			continue
+		}
+		fmt.Printf("%s: restore", e.FileName)
+		if e.DateTimeOriginal != "" {
+			fmt.Printf(" date %s", e.DateTimeOriginal)
+		}
+		if e.Latitude != nil {
+			fmt.Printf(" GPS %s", formatGPS(e.Latitude, e.Longitude))
+		}
+		fmt.Println()
+		if app.DryRun {
+			continue
+		}
+		err = app.Immich.UpdateAssetMetadata(ctx, e.ID, immich.AssetMetadataUpdate{DateTimeOriginal: e.DateTimeOriginal, Latitude: e.Latitude, Longitude: e.Longitude})
+		if err != nil {
+			app.Log.Error("can't restore the asset", "file", e.FileName, "id", e.ID, "error", err.Error())
+			continue
+		}
+		restored++
+	}
+	fmt.Printf("%d asset(s) restored\n", restored)
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+func formatGPS(lat, lon *float64) string {
+	if lat == nil || lon == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%f,%f", *lat, *lon)
+}
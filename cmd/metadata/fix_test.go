@@ -0,0 +1,145 @@
+package metadata
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icFix serves the assets and records the updates
+type icFix struct {
+	fakeimmich.MockedCLient
+	assets  []*immich.Asset
+	updates map[string]immich.AssetMetadataUpdate
+}
+
+func (c *icFix) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icFix) UpdateAssetMetadata(ctx context.Context, id string, update immich.AssetMetadataUpdate) error {
+	c.updates[id] = update
+	return nil
+}
+
+func newIcFix() *icFix {
+	ic := &icFix{updates: map[string]immich.AssetMetadataUpdate{}}
+	paris := time.FixedZone("UTC+2", 2*3600)
+	for _, a := range []struct {
+		id, name, camera string
+		date             time.Time
+		tz               string
+		lat, lon         float64
+	}{
+		{id: "1", name: "DSC_0001.JPG", camera: "NIKON", date: time.Date(2023, 7, 1, 10, 0, 0, 0, paris), tz: "UTC+2", lat: 48.85, lon: 2.35},
+		{id: "2", name: "IMG_0002.JPG", camera: "Canon", date: time.Date(2023, 7, 1, 11, 0, 0, 0, paris), tz: "UTC+2"},
+		{id: "3", name: "IMG_20230702_120000.jpg", camera: "Canon"},
+	} {
+		asset := &immich.Asset{ID: a.id, OriginalFileName: a.name, Type: "IMAGE"}
+		asset.ExifInfo.Make = a.camera
+		asset.ExifInfo.DateTimeOriginal.Time = a.date
+		asset.ExifInfo.TimeZone = a.tz
+		asset.ExifInfo.Latitude, asset.ExifInfo.Longitude = a.lat, a.lon
+		ic.assets = append(ic.assets, asset)
+	}
+	return ic
+}
+
+func ptr(f float64) *float64 { return &f }
+
+func TestFixMetadata(t *testing.T) {
+	bangkok, _ := parseTimeZone("+07:00")
+	tests := []struct {
+		name string
+		app  FixMetadataCmd
+		want map[string]immich.AssetMetadataUpdate
+	}{
+		{
+			name: "shift a camera",
+			app:  FixMetadataCmd{Make: "nikon", Shift: 7 * time.Hour},
+			want: map[string]immich.AssetMetadataUpdate{"1": {DateTimeOriginal: "2023-07-01T17:00:00+02:00"}},
+		},
+		{
+			name: "time zone",
+			app:  FixMetadataCmd{Make: "canon", TimeZone: bangkok},
+			want: map[string]immich.AssetMetadataUpdate{"2": {DateTimeOriginal: "2023-07-01T16:00:00+07:00"}},
+		},
+		{
+			name: "date from name",
+			app:  FixMetadataCmd{Make: "canon", DateFromName: true, TimeZone: bangkok},
+			want: map[string]immich.AssetMetadataUpdate{
+				"2": {DateTimeOriginal: "2023-07-01T16:00:00+07:00"},
+				"3": {DateTimeOriginal: "2023-07-02T12:00:00+07:00"},
+			},
+		},
+		{
+			name: "gps",
+			app:  FixMetadataCmd{Make: "canon", Latitude: ptr(13.75), Longitude: ptr(100.5)},
+			want: map[string]immich.AssetMetadataUpdate{
+				"2": {Latitude: ptr(13.75), Longitude: ptr(100.5)},
+				"3": {Latitude: ptr(13.75), Longitude: ptr(100.5)},
+			},
+		},
+		{
+			name: "dry run",
+			app:  FixMetadataCmd{Make: "nikon", Shift: 7 * time.Hour, DryRun: true},
+			want: map[string]immich.AssetMetadataUpdate{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := newIcFix()
+			app := tt.app
+			app.SharedFlags = &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			app.dl = download.NewDownloader(app.SharedFlags)
+			app.AssumeYes = true
+			app.Journal = filepath.Join(t.TempDir(), "journal.jsonl")
+			if err := app.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ic.updates, tt.want) {
+				t.Errorf("expected updates %+v, got %+v", tt.want, ic.updates)
+			}
+		})
+	}
+}
+
+func TestFixMetadataUndo(t *testing.T) {
+	ic := newIcFix()
+	app := FixMetadataCmd{
+		SharedFlags: &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))},
+		Make:        "nikon",
+		Shift:       time.Hour,
+		Latitude:    ptr(1),
+		Longitude:   ptr(2),
+		AssumeYes:   true,
+		Journal:     filepath.Join(t.TempDir(), "journal.jsonl"),
+	}
+	app.dl = download.NewDownloader(app.SharedFlags)
+	if err := app.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ic.updates = map[string]immich.AssetMetadataUpdate{}
+	app.Undo = app.Journal
+	if err := app.undo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]immich.AssetMetadataUpdate{"1": {DateTimeOriginal: "2023-07-01T10:00:00+02:00", Latitude: ptr(48.85), Longitude: ptr(2.35)}}
+	if !reflect.DeepEqual(ic.updates, want) {
+		t.Errorf("expected updates %+v, got %+v", want, ic.updates)
+	}
+}
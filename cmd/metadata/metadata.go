@@ -24,7 +24,7 @@ type MetadataCmd struct {
 
 func NewMetadataCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*MetadataCmd, error) {
 	var err error
-	cmd := flag.NewFlagSet("metadata", flag.ExitOnError)
+	cmd := flag.NewFlagSet("metadata", flag.ContinueOnError)
 	app := MetadataCmd{
 		SharedFlags: common,
 	}
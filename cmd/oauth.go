@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/immich"
+	"golang.org/x/term"
+)
+
+// DefaultRedirectURI receives the redirection of the identity provider after the login.
+// It must be allowed in the OAuth client settings of the provider.
+const DefaultRedirectURI = "http://localhost:2285/oauth-callback"
+
+// loginTimeout is the time given to the user to log in with the browser
+const loginTimeout = 5 * time.Minute
+
+// OAuthLogin opens a session on the server with its OAuth login: the user logs in with the browser, and the
+// identity provider redirects the browser to the redirect URI, served by immich-go during the login.
+// The session token is stored into the sessions file. The address of the login page is given to open, when not nil.
+func (app *SharedFlags) OAuthLogin(ctx context.Context, redirectURI string, open func(string) error) (immich.LoginResponse, error) {
+	var login immich.LoginResponse
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Scheme != "http" || u.Host == "" {
+		return login, fmt.Errorf("the redirect URI must be a http://host:port/path address: %q", redirectURI)
+	}
+	ln, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return login, fmt.Errorf("can't receive the redirection at %s: %w", redirectURI, err)
+	}
+	defer ln.Close()
+	if u.Port() == "0" {
+		// the port chosen by the system
+		u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(ln.Addr().(*net.TCPAddr).Port))
+		redirectURI = u.String()
+	}
+
+	app.AccessToken = ""
+	client, err := app.NewClient()
+	if err != nil {
+		return login, err
+	}
+	loginURL, err := client.OAuthAuthorize(ctx, redirectURI)
+	if err != nil {
+		return login, fmt.Errorf("the server doesn't give its OAuth login page: %w", err)
+	}
+
+	callback := make(chan string, 1)
+	srv := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != u.Path {
+				http.NotFound(w, r)
+				return
+			}
+			if e := r.URL.Query().Get("error"); e != "" {
+				fmt.Fprintf(w, "The login has failed: %s %s", e, r.URL.Query().Get("error_description"))
+			} else {
+				fmt.Fprint(w, "immich-go is logged in, you can close this window.")
+			}
+			received := *u
+			received.RawQuery = r.URL.RawQuery
+			select {
+			case callback <- received.String():
+			default:
+			}
+		}),
+	}
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	defer srv.Close()
+
+	fmt.Println("Log in with your browser at this address:")
+	fmt.Println(loginURL)
+	if open != nil {
+		_ = open(loginURL)
+	}
+
+	var received string
+	select {
+	case <-ctx.Done():
+		return login, ctx.Err()
+	case <-time.After(loginTimeout):
+		return login, errors.New("the login has timed out")
+	case received = <-callback:
+	}
+	if e, _ := url.Parse(received); e != nil && e.Query().Get("error") != "" {
+		return login, fmt.Errorf("the login has failed: %s", e.Query().Get("error"))
+	}
+
+	login, err = client.OAuthCallback(ctx, received)
+	if err != nil {
+		return login, fmt.Errorf("the server has refused the login: %w", err)
+	}
+
+	sessions, err := configuration.ReadSessions(app.SessionsFile)
+	if err != nil {
+		return login, err
+	}
+	sessions[app.sessionServer()] = configuration.Session{
+		AccessToken: login.AccessToken,
+		UserEmail:   login.UserEmail,
+		RedirectURI: redirectURI,
+		Created:     time.Now(),
+	}
+	err = sessions.Write(app.SessionsFile)
+	if err != nil {
+		return login, fmt.Errorf("can't write the sessions file: %w", err)
+	}
+	app.AccessToken = login.AccessToken
+	return login, nil
+}
+
+// refreshSession logs in again when the session has expired, and connects with the new session.
+// The login needs the user, so the session is refreshed only when the input is a terminal.
+func (app *SharedFlags) refreshSession(ctx context.Context, save bool) error {
+	expired := fmt.Errorf("the session of %s has expired, log in again with the command login", app.sessionServer())
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return expired
+	}
+	app.sessionRefreshed = true
+	redirectURI := app.session().RedirectURI
+	if redirectURI == "" {
+		redirectURI = DefaultRedirectURI
+	}
+	fmt.Println("The session has expired.")
+	_, err := app.OAuthLogin(ctx, redirectURI, OpenBrowser)
+	if err != nil {
+		return errors.Join(expired, err)
+	}
+	app.Immich = nil
+	return app.connect(ctx, save)
+}
+
+// session gives the session of the server, if any
+func (app *SharedFlags) session() configuration.Session {
+	if app.SessionsFile == "" {
+		return configuration.Session{}
+	}
+	sessions, err := configuration.ReadSessions(app.SessionsFile)
+	if err != nil {
+		return configuration.Session{}
+	}
+	return sessions[app.sessionServer()]
+}
+
+// sessionServer gives the address identifying the server in the sessions file
+func (app *SharedFlags) sessionServer() string {
+	if app.Server != "" {
+		return app.Server
+	}
+	return app.API
+}
+
+// OpenBrowser opens the address with the user's browser
+func OpenBrowser(address string) error {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", address).Start()
+	case "darwin":
+		return exec.Command("open", address).Start()
+	default:
+		return exec.Command("xdg-open", address).Start()
+	}
+}
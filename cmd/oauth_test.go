@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+)
+
+// oauthServer simulates an immich server with the OAuth login. The identity provider
+// redirects the browser directly to the redirect URI with the code.
+func oauthServer(t *testing.T, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/oauth/authorize":
+			var body struct {
+				RedirectURI string `json:"redirectUri"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": body.RedirectURI + "?code=THE_CODE&state=S"})
+		case "/api/oauth/callback":
+			var body struct {
+				URL string `json:"url"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			u, err := url.Parse(body.URL)
+			if err != nil || u.Query().Get("code") != "THE_CODE" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"accessToken": token, "userEmail": "me@example.com"})
+		case "/api/server/ping":
+			_, _ = w.Write([]byte(`{"res":"pong"}`))
+		case "/api/users/me":
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"email":"me@example.com"}`))
+		case "/api/server/version":
+			_, _ = w.Write([]byte(`{"major":1,"minor":118,"patch":2}`))
+		case "/api/server/features":
+			_, _ = w.Write([]byte(`{}`))
+		case "/api/server/media-types":
+			_, _ = w.Write([]byte(`{"image":[".jpg"]}`))
+		default:
+			t.Errorf("unexpected call %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// browse follows the address like a browser
+func browse(address string) error {
+	resp, err := http.Get(address)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func TestOAuthLogin(t *testing.T) {
+	server := oauthServer(t, "THE_TOKEN")
+	defer server.Close()
+
+	dir := t.TempDir()
+	app := &SharedFlags{
+		Server:            server.URL,
+		SessionsFile:      filepath.Join(dir, "sessions.json"),
+		ConfigurationFile: filepath.Join(dir, "immich-go.json"),
+		Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	login, err := app.OAuthLogin(context.Background(), "http://127.0.0.1:0/oauth-callback", browse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.AccessToken != "THE_TOKEN" {
+		t.Errorf("unexpected token %q", login.AccessToken)
+	}
+
+	sessions, err := configuration.ReadSessions(app.SessionsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sessions[server.URL]
+	if s.AccessToken != "THE_TOKEN" || s.UserEmail != "me@example.com" || !strings.HasPrefix(s.RedirectURI, "http://127.0.0.1:") {
+		t.Errorf("unexpected session %+v", s)
+	}
+	fi, err := os.Stat(app.SessionsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 && os.PathSeparator == '/' {
+		t.Errorf("the sessions file should be readable by the user only, got %v", perm)
+	}
+
+	// the next commands use the session
+	next := &SharedFlags{
+		Server:            server.URL,
+		SessionsFile:      app.SessionsFile,
+		ConfigurationFile: app.ConfigurationFile,
+		Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	err = next.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.AccessToken != "THE_TOKEN" {
+		t.Errorf("the session should be used, got %q", next.AccessToken)
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	server := oauthServer(t, "NEW_TOKEN")
+	defer server.Close()
+
+	dir := t.TempDir()
+	sessions := configuration.Sessions{server.URL: {AccessToken: "OLD_TOKEN"}}
+	err := sessions.Write(filepath.Join(dir, "sessions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &SharedFlags{
+		Server:            server.URL,
+		SessionsFile:      filepath.Join(dir, "sessions.json"),
+		ConfigurationFile: filepath.Join(dir, "immich-go.json"),
+		Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	// the tests' input isn't a terminal: the user is asked to log in again
+	err = app.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "log in again") {
+		t.Errorf("an expired session error is expected, got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package orphans
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/ui"
+)
+
+// OrphansCmd lists the server's assets not belonging to any album
+type OrphansCmd struct {
+	*cmd.SharedFlags
+	NotFavorite bool   // Report only the assets that aren't favorite
+	NotTagged   bool   // Report only the assets without tags
+	CSV         string // Write the list into this CSV file
+	Into        string // Add the assets to this album, created when missing
+	AssumeYes   bool
+	DryRun      bool
+
+	dl *download.DownloadCmd // selection of the assets
+}
+
+func NewOrphansCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*OrphansCmd, error) {
+	app := &OrphansCmd{
+		SharedFlags: common,
+		dl:          download.NewDownloader(common),
+	}
+	cmd := flag.NewFlagSet("orphans", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.Var(&app.dl.DateRange, "date", "Process only the assets having a capture date in that range.")
+	cmd.BoolFunc("not-favorite", "Report only the assets that aren't favorite (default: FALSE)", myflag.BoolFlagFn(&app.NotFavorite, false))
+	cmd.BoolFunc("not-tagged", "Report only the assets without tags (default: FALSE)", myflag.BoolFlagFn(&app.NotTagged, false))
+	cmd.StringVar(&app.CSV, "csv", "", "Write the list of the assets into this CSV file")
+	cmd.StringVar(&app.Into, "into", "", "Add the assets to this album, created when missing, ex: Triage")
+	cmd.BoolFunc("yes", "When true, assume Yes to all actions", myflag.BoolFlagFn(&app.AssumeYes, false))
+	cmd.BoolFunc("dry-run", "Display the assets but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func OrphansCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewOrphansCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *OrphansCmd) run(ctx context.Context) error {
+	fmt.Println("Get server's albums...")
+	err := app.dl.ReadAlbums(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Get server's assets...")
+	var orphans []*immich.Asset
+	err = app.dl.GetAssets(ctx, func(a *immich.Asset) error {
+		if len(app.dl.AssetAlbums(a.ID)) > 0 {
+			return nil
+		}
+		if (app.NotFavorite && a.IsFavorite) || (app.NotTagged && len(a.Tags) > 0) {
+			return nil
+		}
+		orphans = append(orphans, a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range orphans {
+		fmt.Printf("  %s %s\n", download.AssetDate(a).Format(time.DateTime), a.OriginalFileName)
+	}
+	fmt.Printf("%d asset(s) not belonging to any album\n", len(orphans))
+
+	if app.CSV != "" {
+		err = writeCSV(app.CSV, orphans)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Check the list:", app.CSV)
+	}
+	if app.Into == "" || len(orphans) == 0 || app.DryRun {
+		return nil
+	}
+
+	fmt.Printf("Add the assets to the album '%s'\n", app.Into)
+	if !app.AssumeYes {
+		r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
+		if err != nil {
+			return err
+		}
+		if r != "y" {
+			return nil
+		}
+	}
+	return app.addToAlbum(ctx, orphans)
+}
+
+// addToAlbum adds the assets to the -into album, created when missing
+func (app *OrphansCmd) addToAlbum(ctx context.Context, assets []*immich.Asset) error {
+	ids := make([]string, len(assets))
+	for i, a := range assets {
+		ids[i] = a.ID
+	}
+	albums, err := app.Immich.GetAllAlbums(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get the albums list: %w", err)
+	}
+	for _, al := range albums {
+		if al.AlbumName == app.Into {
+			_, err = app.Immich.AddAssetToAlbum(ctx, al.ID, ids)
+			if err != nil {
+				return fmt.Errorf("can't add the assets to the album '%s': %w", app.Into, err)
+			}
+			fmt.Printf("%d asset(s) added to the album '%s'\n", len(ids), app.Into)
+			return nil
+		}
+	}
+	_, err = app.Immich.CreateAlbum(ctx, app.Into, "", ids)
+	if err != nil {
+		return fmt.Errorf("can't create the album '%s': %w", app.Into, err)
+	}
+	fmt.Printf("Album '%s' created with %d asset(s)\n", app.Into, len(ids))
+	return nil
+}
+
+func writeCSV(name string, assets []*immich.Asset) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"id", "file name", "path", "date", "type", "favorite", "archived", "tags"})
+	for _, a := range assets {
+		tags := make([]string, len(a.Tags))
+		for i, t := range a.Tags {
+			tags[i] = t.Value
+		}
+		_ = w.Write([]string{
+			a.ID,
+			a.OriginalFileName,
+			a.OriginalPath,
+			download.AssetDate(a).Format(time.RFC3339),
+			a.Type,
+			strconv.FormatBool(a.IsFavorite),
+			strconv.FormatBool(a.IsArchived),
+			strings.Join(tags, ","),
+		})
+	}
+	w.Flush()
+	return errors.Join(w.Error(), f.Close())
+}
@@ -0,0 +1,128 @@
+package orphans
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icOrphans serves the assets and the albums
+type icOrphans struct {
+	fakeimmich.MockedCLient
+	assets []*immich.Asset
+	albums map[string][]string // album -> asset IDs
+}
+
+func (c *icOrphans) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icOrphans) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	var albums []immich.AlbumSimplified
+	for name := range c.albums {
+		albums = append(albums, immich.AlbumSimplified{ID: name, AlbumName: name})
+	}
+	return albums, nil
+}
+
+func (c *icOrphans) GetAlbumInfo(ctx context.Context, id string, withoutAssets bool) (immich.AlbumContent, error) {
+	content := immich.AlbumContent{ID: id, AlbumName: id}
+	for _, a := range c.albums[id] {
+		content.Assets = append(content.Assets, immich.AssetSimplified{ID: a})
+	}
+	return content, nil
+}
+
+func (c *icOrphans) AddAssetToAlbum(ctx context.Context, id string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	c.albums[id] = append(c.albums[id], ids...)
+	return nil, nil
+}
+
+func (c *icOrphans) CreateAlbum(ctx context.Context, name string, description string, ids []string) (immich.AlbumSimplified, error) {
+	c.albums[name] = ids
+	return immich.AlbumSimplified{ID: name, AlbumName: name}, nil
+}
+
+func TestOrphans(t *testing.T) {
+	tests := []struct {
+		name        string
+		notFavorite bool
+		notTagged   bool
+		into        string
+		dryRun      bool
+		want        []string
+		albums      map[string][]string
+	}{
+		{name: "all", want: []string{"2", "3", "4"}, albums: map[string][]string{"Summer": {"1"}}},
+		{name: "not favorite", notFavorite: true, want: []string{"2", "4"}, albums: map[string][]string{"Summer": {"1"}}},
+		{name: "not tagged", notFavorite: true, notTagged: true, want: []string{"2"}, albums: map[string][]string{"Summer": {"1"}}},
+		{name: "triage", into: "Triage", want: []string{"2", "3", "4"}, albums: map[string][]string{"Summer": {"1"}, "Triage": {"2", "3", "4"}}},
+		{name: "existing album", into: "Summer", want: []string{"2", "3", "4"}, albums: map[string][]string{"Summer": {"1", "2", "3", "4"}}},
+		{name: "dry run", into: "Triage", dryRun: true, want: []string{"2", "3", "4"}, albums: map[string][]string{"Summer": {"1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icOrphans{albums: map[string][]string{"Summer": {"1"}}}
+			for _, a := range []struct {
+				id       string
+				favorite bool
+				tag      string
+			}{{id: "1"}, {id: "2"}, {id: "3", favorite: true}, {id: "4", tag: "scanned"}} {
+				asset := &immich.Asset{ID: a.id, OriginalFileName: "IMG_000" + a.id + ".jpg", Type: "IMAGE", IsFavorite: a.favorite}
+				asset.ExifInfo.DateTimeOriginal.Time = time.Date(2023, 7, 1, 10, 0, 0, 0, time.UTC)
+				if a.tag != "" {
+					asset.Tags = []immich.Tag{{ID: a.tag, Name: a.tag, Value: a.tag}}
+				}
+				ic.assets = append(ic.assets, asset)
+			}
+			app := &OrphansCmd{
+				SharedFlags: &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))},
+				NotFavorite: tt.notFavorite,
+				NotTagged:   tt.notTagged,
+				CSV:         filepath.Join(t.TempDir(), "orphans.csv"),
+				Into:        tt.into,
+				AssumeYes:   true,
+				DryRun:      tt.dryRun,
+			}
+			app.dl = download.NewDownloader(app.SharedFlags)
+			if err := app.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(app.CSV)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			lines, err := csv.NewReader(f).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, l := range lines[1:] {
+				got = append(got, l[0])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected orphans %v, got %v", tt.want, got)
+			}
+			if !reflect.DeepEqual(ic.albums, tt.albums) {
+				t.Errorf("expected albums %v, got %v", tt.albums, ic.albums)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package people
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/ui"
+)
+
+// faceScale gives the dimensions of the image when a person is attached to the whole image
+const faceScale = 10000
+
+// PeopleCmd merges, renames the persons known by the server, and attaches them to assets
+type PeopleCmd struct {
+	*cmd.SharedFlags
+	Albums    namematcher.List // assign: select the assets of the albums matching these patterns
+	Names     namematcher.List // assign: select the assets whose name matches these patterns
+	AssumeYes bool
+	DryRun    bool
+
+	dl     *download.DownloadCmd // selection of the assets
+	people []immich.Person
+}
+
+// PeopleCommand dispatches the merge, rename and assign commands
+func PeopleCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "merge", "rename", "assign":
+			app, names, err := newPeopleCmd(ctx, common, args[0], args[1:])
+			if err != nil {
+				return err
+			}
+			switch args[0] {
+			case "merge":
+				return app.merge(ctx, names)
+			case "rename":
+				return app.rename(ctx, names)
+			default:
+				return app.assign(ctx, names)
+			}
+		}
+	}
+	return fmt.Errorf("people needs a command: merge|rename|assign")
+}
+
+func newPeopleCmd(ctx context.Context, common *cmd.SharedFlags, action string, args []string) (*PeopleCmd, []string, error) {
+	app := &PeopleCmd{
+		SharedFlags: common,
+		dl:          download.NewDownloader(common),
+	}
+	cmd := flag.NewFlagSet("people "+action, flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	if action == "assign" {
+		cmd.Var(&app.dl.DateRange, "date", "Select the assets having a capture date in that range.")
+		cmd.Var(&app.Albums, "album", "Select the assets of the albums whose name matches the pattern, can be repeated.")
+		cmd.Var(&app.Names, "name", "Select the assets whose file name matches the pattern, can be repeated.")
+	}
+	cmd.BoolFunc("yes", "When true, assume Yes to all actions", myflag.BoolFlagFn(&app.AssumeYes, false))
+	cmd.BoolFunc("dry-run", "Display the actions but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	names, err := parseArgs(cmd, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	app.people, err = app.Immich.GetAllPeople(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't get the people: %w", err)
+	}
+	return app, names, nil
+}
+
+// parseArgs parses the flags placed before, between or after the names, and gives the names
+func parseArgs(cmd *flag.FlagSet, args []string) ([]string, error) {
+	var names []string
+	for {
+		err := cmd.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.NArg() == 0 {
+			return names, nil
+		}
+		names = append(names, cmd.Arg(0))
+		args = cmd.Args()[1:]
+	}
+}
+
+// byName gives the persons having the name
+func (app *PeopleCmd) byName(name string) []immich.Person {
+	var l []immich.Person
+	for _, p := range app.people {
+		if strings.EqualFold(p.Name, name) {
+			l = append(l, p)
+		}
+	}
+	return l
+}
+
+// confirm asks the user, unless -yes is given
+func (app *PeopleCmd) confirm(ctx context.Context) (bool, error) {
+	if app.AssumeYes {
+		return true, nil
+	}
+	r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
+	return r == "y", err
+}
+
+// merge merges the persons named like the arguments into the person named like the last one.
+// The persons having the same name as the last one are merged too.
+func (app *PeopleCmd) merge(ctx context.Context, names []string) error {
+	if len(names) == 0 {
+		return errors.New("the people merge command needs the names of the persons, the last one receives the others")
+	}
+	targetName := names[len(names)-1]
+	targets := app.byName(targetName)
+	if len(targets) == 0 {
+		return fmt.Errorf("person %q not found", targetName)
+	}
+	target := targets[0]
+	var ids []string
+	for _, p := range targets[1:] {
+		ids = append(ids, p.ID)
+	}
+	for _, name := range names[:len(names)-1] {
+		l := app.byName(name)
+		if len(l) == 0 {
+			return fmt.Errorf("person %q not found", name)
+		}
+		for _, p := range l {
+			if p.ID != target.ID {
+				ids = append(ids, p.ID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Println("No person to merge")
+		return nil
+	}
+	fmt.Printf("Merge %d person(s) into '%s'\n", len(ids), target.Name)
+	if app.DryRun {
+		return nil
+	}
+	if ok, err := app.confirm(ctx); err != nil || !ok {
+		return err
+	}
+	r, err := app.Immich.MergePeople(ctx, target.ID, ids)
+	if err != nil {
+		return fmt.Errorf("can't merge the persons: %w", err)
+	}
+	merged := 0
+	for _, res := range r {
+		if res.Success {
+			merged++
+		} else {
+			app.Log.Error("can't merge the person", "id", res.ID, "error", res.Error)
+		}
+	}
+	fmt.Printf("%d person(s) merged into '%s'\n", merged, target.Name)
+	return nil
+}
+
+// rename renames the persons having the name
+func (app *PeopleCmd) rename(ctx context.Context, names []string) error {
+	if len(names) != 2 {
+		return errors.New("the people rename command needs the name of the person and its new name")
+	}
+	l := app.byName(names[0])
+	if len(l) == 0 {
+		return fmt.Errorf("person %q not found", names[0])
+	}
+	if !strings.EqualFold(names[0], names[1]) && len(app.byName(names[1])) > 0 {
+		return fmt.Errorf("the person %q exists, merge the persons with the people merge command", names[1])
+	}
+	fmt.Printf("Rename %d person(s) '%s' into '%s'\n", len(l), names[0], names[1])
+	if app.DryRun {
+		return nil
+	}
+	if ok, err := app.confirm(ctx); err != nil || !ok {
+		return err
+	}
+	for _, p := range l {
+		_, err := app.Immich.UpdatePerson(ctx, p.ID, names[1])
+		if err != nil {
+			return fmt.Errorf("can't rename the person '%s': %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// assign attaches the person to the selected assets, on the whole image. The person is created when missing.
+func (app *PeopleCmd) assign(ctx context.Context, names []string) error {
+	if len(names) != 1 {
+		return errors.New("the people assign command needs the name of the person")
+	}
+	if !app.Albums.IsSet() && !app.Names.IsSet() && !app.dl.DateRange.IsSet() {
+		return errors.New("select the assets with -album, -date or -name")
+	}
+	name := names[0]
+
+	// the assets already showing the person are skipped
+	showing := map[string]bool{}
+	var person immich.Person
+	if l := app.byName(name); len(l) > 0 {
+		person = l[0]
+		err := app.Immich.SearchAssets(ctx, immich.SearchQuery{PersonIDs: []string{person.ID}}, func(a *immich.Asset) error {
+			showing[a.ID] = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if app.Albums.IsSet() {
+		err := app.dl.SelectAlbums(ctx, app.Albums)
+		if err != nil {
+			return err
+		}
+	}
+	var assets []*immich.Asset
+	err := app.dl.GetAssets(ctx, func(a *immich.Asset) error {
+		if showing[a.ID] || (app.Names.IsSet() && !app.Names.Match(a.OriginalFileName)) {
+			return nil
+		}
+		assets = append(assets, a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, a := range assets {
+		fmt.Printf("  %s\n", a.OriginalFileName)
+	}
+	fmt.Printf("Attach '%s' to %d asset(s)\n", name, len(assets))
+	if len(assets) == 0 || app.DryRun {
+		return nil
+	}
+	if ok, err := app.confirm(ctx); err != nil || !ok {
+		return err
+	}
+	if person.ID == "" {
+		person, err = app.Immich.CreatePerson(ctx, name)
+		if err != nil {
+			return fmt.Errorf("can't create the person '%s': %w", name, err)
+		}
+	}
+	assigned := 0
+	for _, a := range assets {
+		err = app.Immich.CreateFace(ctx, immich.Face{
+			AssetID:     a.ID,
+			PersonID:    person.ID,
+			ImageWidth:  faceScale,
+			ImageHeight: faceScale,
+			Width:       faceScale,
+			Height:      faceScale,
+		})
+		if err != nil {
+			app.Log.Error("can't attach the person", "file", a.OriginalFileName, "id", a.ID, "error", err.Error())
+			continue
+		}
+		assigned++
+	}
+	fmt.Printf("'%s' attached to %d asset(s)\n", name, assigned)
+	return nil
+}
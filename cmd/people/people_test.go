@@ -0,0 +1,167 @@
+package people
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icPeople serves the people and the assets, and records the changes
+type icPeople struct {
+	fakeimmich.MockedCLient
+	people  []immich.Person
+	assets  []*immich.Asset
+	showing map[string][]string // person ID -> asset IDs
+	merged  map[string][]string
+	renamed map[string]string
+	faces   []immich.Face
+}
+
+func (c *icPeople) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if len(q.PersonIDs) > 0 && !slices.Contains(c.showing[q.PersonIDs[0]], a.ID) {
+			continue
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icPeople) MergePeople(ctx context.Context, id string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	c.merged[id] = append(c.merged[id], ids...)
+	return nil, nil
+}
+
+func (c *icPeople) UpdatePerson(ctx context.Context, id string, name string) (immich.Person, error) {
+	c.renamed[id] = name
+	return immich.Person{ID: id, Name: name}, nil
+}
+
+func (c *icPeople) CreatePerson(ctx context.Context, name string) (immich.Person, error) {
+	return immich.Person{ID: "new", Name: name}, nil
+}
+
+func (c *icPeople) CreateFace(ctx context.Context, face immich.Face) error {
+	c.faces = append(c.faces, face)
+	return nil
+}
+
+func newTestCmd() (*PeopleCmd, *icPeople) {
+	ic := &icPeople{
+		people: []immich.Person{
+			{ID: "p1", Name: "John D."},
+			{ID: "p2", Name: "John Doe"},
+			{ID: "p3", Name: "john doe"},
+			{ID: "p4", Name: "Grandma"},
+		},
+		assets: []*immich.Asset{
+			{ID: "1", OriginalFileName: "scan_001.jpg"},
+			{ID: "2", OriginalFileName: "scan_002.jpg"},
+			{ID: "3", OriginalFileName: "IMG_0001.jpg"},
+		},
+		showing: map[string][]string{"p4": {"2"}},
+		merged:  map[string][]string{},
+		renamed: map[string]string{},
+	}
+	common := &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	app := &PeopleCmd{SharedFlags: common, dl: download.NewDownloader(common), AssumeYes: true, people: ic.people}
+	return app, ic
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{name: "merge", args: []string{"John D.", "John Doe"}, want: map[string][]string{"p2": {"p3", "p1"}}},
+		{name: "same name", args: []string{"John Doe"}, want: map[string][]string{"p2": {"p3"}}},
+		{name: "unknown", args: []string{"Jane", "John Doe"}, want: map[string][]string{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, ic := newTestCmd()
+			err := app.merge(context.Background(), tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ic.merged, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ic.merged)
+			}
+		})
+	}
+}
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "rename", args: []string{"grandma", "Granny"}, want: map[string]string{"p4": "Granny"}},
+		{name: "case", args: []string{"John D.", "john d."}, want: map[string]string{"p1": "john d."}},
+		{name: "existing", args: []string{"John D.", "John Doe"}, want: map[string]string{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, ic := newTestCmd()
+			err := app.rename(context.Background(), tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ic.renamed, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ic.renamed)
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		name   string
+		person string
+		names  []string
+		dryRun bool
+		want   []immich.Face
+	}{
+		{name: "existing person", person: "Grandma", names: []string{"scan_*"}, want: []immich.Face{
+			{AssetID: "1", PersonID: "p4", ImageWidth: faceScale, ImageHeight: faceScale, Width: faceScale, Height: faceScale},
+		}},
+		{name: "new person", person: "Grandpa", names: []string{"scan_*"}, want: []immich.Face{
+			{AssetID: "1", PersonID: "new", ImageWidth: faceScale, ImageHeight: faceScale, Width: faceScale, Height: faceScale},
+			{AssetID: "2", PersonID: "new", ImageWidth: faceScale, ImageHeight: faceScale, Width: faceScale, Height: faceScale},
+		}},
+		{name: "dry run", person: "Grandpa", names: []string{"scan_*"}, dryRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, ic := newTestCmd()
+			app.DryRun = tt.dryRun
+			var err error
+			app.Names, err = namematcher.New(tt.names...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = app.assign(context.Background(), []string{tt.person})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ic.faces, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ic.faces)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+)
+
+// actions gives the number of action words following the commands having actions, ex: album delete, tool album delete
+var actions = map[string]int{
+	"album":  1,
+	"people": 1,
+	"tag":    1,
+	"tool":   2,
+}
+
+// Target is a server receiving the assets, given by a profile
+type Target struct {
+	Name   string // name of the profile
+	Server string
+	API    string
+	Key    string
+}
+
+// UseProfile takes the server and the key of the profile, unless they are given on the command line,
+// and gives the arguments of the command with the default flags of the profile placed before the flags of the user.
+func (app *SharedFlags) UseProfile(p configuration.Profile, given map[string]bool, command string, args []string) ([]string, error) {
+	if !given["server"] && !given["api"] && p.Server+p.API != "" {
+		app.Server, app.API = p.Server, p.API
+	}
+	if !given["key"] && p.Key != "" {
+		app.Key = p.Key
+	}
+
+	var paths []string
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			paths = append(paths, a)
+		}
+	}
+	flags, err := p.CommandFlags(command, paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(flags) == 0 {
+		return args, nil
+	}
+
+	// the flags are given after the action words
+	at := min(actions[command], len(args))
+	result := make([]string, 0, len(args)+len(flags))
+	result = append(result, args[:at]...)
+	result = append(result, flags...)
+	return append(result, args[at:]...), nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+)
+
+func TestUseProfile(t *testing.T) {
+	p := configuration.Profile{
+		Server: "http://nas.local:2283",
+		Key:    "HOME_KEY",
+		Flags: map[string][]string{
+			configuration.AllCommands: {"-client-timeout=20m"},
+			"album":                   {"-yes"},
+		},
+	}
+	tests := []struct {
+		name    string
+		given   map[string]bool
+		command string
+		args    []string
+		want    []string
+		server  string
+		key     string
+	}{
+		{name: "upload", command: "upload", args: []string{"-dry-run", "photos"}, want: []string{"-client-timeout=20m", "-dry-run", "photos"}, server: p.Server, key: p.Key},
+		{name: "action", command: "album", args: []string{"delete", "Trip"}, want: []string{"delete", "-client-timeout=20m", "-yes", "Trip"}, server: p.Server, key: p.Key},
+		{name: "tool action", command: "tool", args: []string{"album", "delete", "Trip"}, want: []string{"album", "delete", "-client-timeout=20m", "Trip"}, server: p.Server, key: p.Key},
+		{name: "server given", given: map[string]bool{"server": true, "key": true}, command: "upload", args: []string{"photos"}, want: []string{"-client-timeout=20m", "photos"}, server: "http://given", key: "GIVEN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := SharedFlags{}
+			if tt.given["server"] {
+				app.Server, app.Key = "http://given", "GIVEN"
+			}
+			got, err := app.UseProfile(p, tt.given, tt.command, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if app.Server != tt.server || app.Key != tt.key {
+				t.Errorf("unexpected server %q and key %q", app.Server, app.Key)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// proxyURL checks the address of the proxy. An empty address gives no proxy.
+func proxyURL(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy address %q, the address must begin with http://, https://, socks5:// or socks5h://", u.Redacted())
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy address %q, the host is missing", u.Redacted())
+	}
+	return u, nil
+}
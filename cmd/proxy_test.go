@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// httpProxy forwards the calls to the handler when the credentials are given
+func httpProxy(t *testing.T, user, password string, calls *atomic.Int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() {
+			t.Errorf("the proxy should receive absolute addresses: %s", r.URL)
+		}
+		r.Header.Set("Authorization", r.Header.Get("Proxy-Authorization"))
+		u, p, ok := r.BasicAuth()
+		if !ok || u != user || p != password {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		calls.Add(1)
+		immichHandler().ServeHTTP(w, r)
+	}))
+}
+
+// socksProxy is a SOCKS5 proxy with user name and password authentication (RFC 1928 and RFC 1929)
+func socksProxy(t *testing.T, user, password string, calls *atomic.Int64) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				buf := make([]byte, 256)
+				// greeting: version, methods
+				if _, err := io.ReadFull(r, buf[:2]); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(r, buf[:buf[1]]); err != nil {
+					return
+				}
+				_, _ = c.Write([]byte{5, 2}) // user name and password
+				// authentication: version, user, password
+				if _, err := io.ReadFull(r, buf[:2]); err != nil {
+					return
+				}
+				u := make([]byte, buf[1])
+				_, _ = io.ReadFull(r, u)
+				_, _ = io.ReadFull(r, buf[:1])
+				p := make([]byte, buf[0])
+				_, _ = io.ReadFull(r, p)
+				if string(u) != user || string(p) != password {
+					_, _ = c.Write([]byte{1, 1})
+					return
+				}
+				_, _ = c.Write([]byte{1, 0})
+				// request: version, connect, reserved, address type, address, port
+				if _, err := io.ReadFull(r, buf[:4]); err != nil {
+					return
+				}
+				var host string
+				switch buf[3] {
+				case 1:
+					_, _ = io.ReadFull(r, buf[:4])
+					host = net.IP(buf[:4]).String()
+				case 3:
+					_, _ = io.ReadFull(r, buf[:1])
+					name := make([]byte, buf[0])
+					_, _ = io.ReadFull(r, name)
+					host = string(name)
+				default:
+					return
+				}
+				_, _ = io.ReadFull(r, buf[:2])
+				port := binary.BigEndian.Uint16(buf[:2])
+				target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+				if err != nil {
+					_, _ = c.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				calls.Add(1)
+				_, _ = c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go func() {
+					_, _ = io.Copy(target, r)
+				}()
+				_, _ = io.Copy(c, target)
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestProxy(t *testing.T) {
+	server := httptest.NewServer(immichHandler())
+	defer server.Close()
+
+	var httpCalls, socksCalls atomic.Int64
+	hp := httpProxy(t, "me", "secret", &httpCalls)
+	defer hp.Close()
+	sp := socksProxy(t, "me", "secret", &socksCalls)
+	defer sp.Close()
+
+	tests := []struct {
+		name  string
+		proxy string
+		ok    bool
+		calls *atomic.Int64
+	}{
+		{name: "http proxy", proxy: "http://me:secret@" + hp.Listener.Addr().String(), ok: true, calls: &httpCalls},
+		{name: "http proxy without credentials", proxy: "http://" + hp.Listener.Addr().String()},
+		{name: "socks proxy", proxy: "socks5://me:secret@" + sp.Addr().String(), ok: true, calls: &socksCalls},
+		{name: "socks proxy resolving the name", proxy: "socks5h://me:secret@" + sp.Addr().String(), ok: true, calls: &socksCalls},
+		{name: "socks proxy with wrong credentials", proxy: "socks5://me:wrong@" + sp.Addr().String()},
+		{name: "unsupported proxy", proxy: "ftp://" + sp.Addr().String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &SharedFlags{
+				Server:            server.URL,
+				Key:               "KEY",
+				Proxy:             tt.proxy,
+				ConfigurationFile: filepath.Join(t.TempDir(), "immich-go.json"),
+				Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			err := app.Start(context.Background())
+			if tt.ok && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("an error is expected")
+			}
+			if tt.calls != nil && tt.calls.Load() == 0 {
+				t.Error("the calls should go through the proxy")
+			}
+		})
+	}
+}
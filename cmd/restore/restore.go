@@ -0,0 +1,93 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/helpers/myflag"
+)
+
+// RestoreCmd undoes the last operation that moved server's assets to the trash
+type RestoreCmd struct {
+	*cmd.SharedFlags
+	List bool // List the operations of the journal instead of restoring
+
+	stdout io.Writer
+}
+
+func NewRestoreCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*RestoreCmd, error) {
+	app := &RestoreCmd{
+		SharedFlags: common,
+		stdout:      os.Stdout,
+	}
+	cmd := flag.NewFlagSet("restore", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.BoolFunc("list", "List the operations of the trash journal instead of restoring the last one (default: FALSE)", myflag.BoolFlagFn(&app.List, false))
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if app.TrashJournal == "" {
+		return nil, errors.New("the option -trash-journal is needed")
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func RestoreCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewRestoreCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *RestoreCmd) run(ctx context.Context) error {
+	jnl, err := configuration.ReadTrashJournal(app.TrashJournal)
+	if err != nil {
+		return fmt.Errorf("can't read the trash journal: %w", err)
+	}
+	server := app.ServerName()
+
+	if app.List {
+		for _, op := range jnl {
+			if op.Server != server {
+				continue
+			}
+			state := ""
+			if op.Restored {
+				state = ", restored"
+			}
+			fmt.Fprintf(app.stdout, "%s  %-10s %d asset(s)%s\n", op.Time.Format(time.DateTime), op.Command, len(op.IDs), state)
+		}
+		return nil
+	}
+
+	i := jnl.LastOperation(server)
+	if i < 0 {
+		fmt.Fprintln(app.stdout, "No operation to restore")
+		return nil
+	}
+	op := jnl[i]
+	err = app.Immich.RestoreAssets(ctx, op.IDs)
+	if err != nil {
+		return fmt.Errorf("can't restore the assets: %w", err)
+	}
+	jnl[i].Restored = true
+	err = jnl.Write(app.TrashJournal)
+	if err != nil {
+		return fmt.Errorf("can't write the trash journal: %w", err)
+	}
+	fmt.Fprintf(app.stdout, "%d asset(s) restored, moved to the trash by the command %s on %s\n", len(op.IDs), op.Command, op.Time.Format(time.DateTime))
+	return nil
+}
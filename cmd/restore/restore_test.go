@@ -0,0 +1,94 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/configuration"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icTrash records the deleted and restored assets
+type icTrash struct {
+	fakeimmich.MockedCLient
+	deleted  []string
+	restored []string
+}
+
+func (c *icTrash) DeleteAssets(ctx context.Context, ids []string, force bool) error {
+	c.deleted = append(c.deleted, ids...)
+	return nil
+}
+
+func (c *icTrash) RestoreAssets(ctx context.Context, ids []string) error {
+	c.restored = append(c.restored, ids...)
+	return nil
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	ic := &icTrash{}
+	jnlFile := filepath.Join(t.TempDir(), "trash.json")
+	common := &cmd.SharedFlags{Immich: ic, Server: "http://photos:2283", TrashJournal: jnlFile}
+
+	// an operation on another server is ignored
+	other := &cmd.SharedFlags{Immich: &icTrash{}, Server: "http://other:2283", TrashJournal: jnlFile}
+	for _, op := range []struct {
+		app     *cmd.SharedFlags
+		command string
+		ids     []string
+	}{
+		{common, "duplicate", []string{"1", "2"}},
+		{common, "sync", []string{"3"}},
+		{other, "sync", []string{"4"}},
+	} {
+		if err := op.app.TrashAssets(ctx, op.command, op.ids); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(ic.deleted, []string{"1", "2", "3"}) {
+		t.Errorf("expected the deleted assets [1 2 3], got %v", ic.deleted)
+	}
+
+	out := &bytes.Buffer{}
+	app := &RestoreCmd{SharedFlags: common, stdout: out}
+	expected := [][]string{{"3"}, {"3", "1", "2"}, {"3", "1", "2"}}
+	for i, want := range expected {
+		if err := app.run(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ic.restored, want) {
+			t.Errorf("run %d: expected the restored assets %v, got %v", i, want, ic.restored)
+		}
+	}
+	if !strings.HasSuffix(out.String(), "No operation to restore\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	jnl, err := configuration.ReadTrashJournal(jnlFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jnl) != 3 || !jnl[0].Restored || !jnl[1].Restored || jnl[2].Restored {
+		t.Errorf("unexpected journal %+v", jnl)
+	}
+
+	out.Reset()
+	app.List = true
+	if err := app.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "duplicate  2 asset(s), restored") || !strings.Contains(lines[1], "sync       1 asset(s), restored") {
+		t.Errorf("unexpected list:\n%s", out.String())
+	}
+	if !strings.HasPrefix(lines[0], jnl[0].Time.Format(time.DateTime)) {
+		t.Errorf("the list doesn't give the time of the operation:\n%s", out.String())
+	}
+}
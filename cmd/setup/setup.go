@@ -0,0 +1,168 @@
+/*
+Ask the server's address and the API key to the user, check them, and store them into the configuration.
+*/
+package setup
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/ui"
+	"golang.org/x/term"
+)
+
+type InitCmd struct {
+	*cmd.SharedFlags
+	Profile      string // name of the profile receiving the server and the key
+	ProfilesFile string // YAML configuration file
+
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func InitCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app := &InitCmd{
+		SharedFlags: common,
+		stdin:       os.Stdin,
+		stdout:      os.Stdout,
+	}
+	cmd := flag.NewFlagSet("init", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.StringVar(&app.Profile, "profile", "", "Store the server and the key into this profile of the YAML configuration file")
+	cmd.StringVar(&app.ProfilesFile, "config", configuration.DefaultProfilesFile(), "YAML configuration file with the profiles")
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *InitCmd) run(ctx context.Context) error {
+	if app.Server == "" && app.Key == "" {
+		if conf, err := configuration.ConfigRead(app.ConfigurationFile); err == nil {
+			app.Server, app.Key = conf.ServerURL, conf.APIKey
+		}
+	}
+
+	in := bufio.NewReader(app.stdin)
+	server, err := app.ask(in, "Address of the immich server, ex: http://192.168.1.10:2283", app.Server)
+	if err != nil {
+		return err
+	}
+	key, err := app.askSecret(in, "API key, created in the Account Settings of the immich web site", app.Key)
+	if err != nil {
+		return err
+	}
+	if server == "" || key == "" {
+		return errors.New("the server address and the API key are needed")
+	}
+	app.Server, app.Key, app.API = strings.TrimSuffix(server, "/"), key, ""
+
+	// Start connects to the server, validates the key, and writes the configuration file
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("can't connect to the server: %w", err)
+	}
+	err = app.describe(ctx)
+	if err != nil {
+		return err
+	}
+
+	if app.Profile != "" {
+		profiles, err := configuration.ReadProfiles(app.ProfilesFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		profiles.SetServer(app.Profile, app.Server, app.API, app.Key)
+		err = profiles.Write(app.ProfilesFile)
+		if err != nil {
+			return fmt.Errorf("can't write the configuration file: %w", err)
+		}
+		fmt.Fprintf(app.stdout, "\nThe server and the key are stored into the profile %q of %s\n", app.Profile, app.ProfilesFile)
+		return nil
+	}
+	fmt.Fprintf(app.stdout, "\nThe server and the key are stored into %s\n", app.ConfigurationFile)
+	return nil
+}
+
+// describe prints the server's version, the user, the disk usage and the user's quota
+func (app *InitCmd) describe(ctx context.Context) error {
+	user, err := app.Immich.ValidateConnection(ctx)
+	if err != nil {
+		return err
+	}
+	version, err := app.Immich.GetServerVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(app.stdout, "\nServer version: %s\n", version)
+	role := ""
+	if user.IsAdmin {
+		role = " (administrator)"
+	}
+	fmt.Fprintf(app.stdout, "Connected as:   %s%s\n", user.Email, role)
+
+	storage, err := app.Immich.GetServerStorage(ctx)
+	if err == nil && storage.DiskSizeRaw > 0 {
+		fmt.Fprintf(app.stdout, "Server disk:    %s used of %s (%.0f%%)\n", ui.FormatBytes(int(storage.DiskUseRaw)), ui.FormatBytes(int(storage.DiskSizeRaw)), storage.DiskUsagePercentage)
+	}
+	if user.QuotaSizeInBytes != nil && *user.QuotaSizeInBytes > 0 {
+		fmt.Fprintf(app.stdout, "Quota:          %s used of %s\n", ui.FormatBytes(int(user.QuotaUsageInBytes)), ui.FormatBytes(int(*user.QuotaSizeInBytes)))
+	} else {
+		fmt.Fprintf(app.stdout, "Quota:          none\n")
+	}
+	return nil
+}
+
+// ask prints the question and reads the answer. The current value is kept when the answer is empty.
+func (app *InitCmd) ask(in *bufio.Reader, question string, current string) (string, error) {
+	if current != "" {
+		question += fmt.Sprintf(" [%s]", current)
+	}
+	fmt.Fprint(app.stdout, question+": ")
+	line, err := in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return current, nil
+	}
+	return line, nil
+}
+
+// askSecret reads the answer without echoing it when the input is a terminal.
+// The current value is kept when the answer is empty.
+func (app *InitCmd) askSecret(in *bufio.Reader, question string, current string) (string, error) {
+	if current != "" {
+		question += " (empty to keep the current key)"
+	}
+	var answer string
+	if f, ok := app.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(app.stdout, question+": ")
+		b, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(app.stdout)
+		if err != nil {
+			return "", err
+		}
+		answer = strings.TrimSpace(string(b))
+	} else {
+		var err error
+		answer, err = app.ask(in, question, "")
+		if err != nil {
+			return "", err
+		}
+	}
+	if answer == "" {
+		return current, nil
+	}
+	return answer, nil
+}
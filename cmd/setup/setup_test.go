@@ -0,0 +1,92 @@
+package setup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+type icServer struct {
+	fakeimmich.MockedCLient
+}
+
+func (c *icServer) ValidateConnection(ctx context.Context) (immich.User, error) {
+	quota := int64(100 << 30)
+	return immich.User{Email: "me@example.com", QuotaSizeInBytes: &quota, QuotaUsageInBytes: 10 << 30}, nil
+}
+
+func (c *icServer) GetServerVersion(ctx context.Context) (immich.ServerVersion, error) {
+	return immich.ServerVersion{Major: 1, Minor: 106, Patch: 4}, nil
+}
+
+func (c *icServer) GetServerStorage(ctx context.Context) (immich.ServerStorage, error) {
+	return immich.ServerStorage{DiskSizeRaw: 4 << 40, DiskUseRaw: 1 << 40, DiskUsagePercentage: 25}, nil
+}
+
+func TestInit(t *testing.T) {
+	profilesFile := filepath.Join(t.TempDir(), "config.yaml")
+	var out bytes.Buffer
+	app := &InitCmd{
+		SharedFlags: &cmd.SharedFlags{
+			Immich:            &icServer{},
+			Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+			ConfigurationFile: filepath.Join(t.TempDir(), "immich-go.json"),
+		},
+		Profile:      "home",
+		ProfilesFile: profilesFile,
+		stdin:        strings.NewReader("http://nas.local:2283/\nTHE_KEY\n"),
+		stdout:       &out,
+	}
+	err := app.run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"Server version: v1.106.4", "me@example.com", "1.0 TB used of 4.0 TB (25%)", "10.0 GB used of 100.0 GB"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("the output should contain %q:\n%s", s, out.String())
+		}
+	}
+
+	profiles, err := configuration.ReadProfiles(profilesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := profiles.Get("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Server != "http://nas.local:2283" || p.Key != "THE_KEY" {
+		t.Errorf("unexpected profile: %+v", p)
+	}
+}
+
+func TestInitKeepsCurrentValues(t *testing.T) {
+	var out bytes.Buffer
+	app := &InitCmd{
+		SharedFlags: &cmd.SharedFlags{
+			Immich: &icServer{},
+			Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Server: "http://current:2283",
+			Key:    "CURRENT_KEY",
+		},
+		stdin:  strings.NewReader("\n\n"),
+		stdout: &out,
+	}
+	err := app.run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Server != "http://current:2283" || app.Key != "CURRENT_KEY" {
+		t.Errorf("the current values should be kept: %q, %q", app.Server, app.Key)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
@@ -12,9 +13,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/simulot/immich-go/helpers/clientcert"
 	"github.com/simulot/immich-go/helpers/configuration"
 	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/logrotate"
 	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/helpers/sysloghandler"
 	"github.com/simulot/immich-go/helpers/tzone"
 	"github.com/simulot/immich-go/immich"
 	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
@@ -24,43 +28,86 @@ import (
 
 // SharedFlags collect all parameters that are common to all commands
 type SharedFlags struct {
-	ConfigurationFile string        // Path to the configuration file to use
-	Server            string        // Immich server address (http://<your-ip>:2283/api or https://<your-domain>/api)
-	API               string        // Immich api endpoint (http://container_ip:3301)
-	Key               string        // API Key
-	DeviceUUID        string        // Set a device UUID
-	APITrace          bool          // Enable API call traces
-	LogLevel          string        // Indicate the log level (string)
-	Level             slog.Level    // Set the log level
-	Debug             bool          // Enable the debug mode
-	TimeZone          string        // Override default TZ
-	SkipSSL           bool          // Skip SSL Verification
-	ClientTimeout     time.Duration // Set the client request timeout
-	NoUI              bool          // Disable user interface
-	JSONLog           bool          // Enable JSON structured log
-	DebugCounters     bool          // Enable CSV action counters per file
-	DebugFileList     bool          // When true, the file argument is a file wile the list of Takeout files
+	ConfigurationFile string            // Path to the configuration file to use
+	Server            string            // Immich server address (http://<your-ip>:2283/api or https://<your-domain>/api)
+	API               string            // Immich api endpoint (http://container_ip:3301)
+	Key               string            // API Key
+	KeyFile           string            // File containing the API key, ex: a docker secret
+	AccessToken       string            // Session token of the OAuth login, used when no key is given
+	SessionsFile      string            // File of the sessions opened by the OAuth login
+	TrashJournal      string            // File of the operations moving assets to the trash
+	DeviceUUID        string            // Set a device UUID
+	APITrace          bool              // Enable API call traces
+	LogLevel          string            // Indicate the log level (string)
+	Level             slog.Level        // Set the log level
+	Debug             bool              // Enable the debug mode
+	TimeZone          string            // Override default TZ
+	SkipSSL           bool              // Skip SSL Verification
+	ClientCert        string            // Client certificate for the servers requiring mutual TLS, PEM or PKCS#12 file
+	ClientKey         string            // Private key of the client certificate, PEM file
+	CACert            string            // PEM bundle of the certificate authorities trusted in addition to the system's ones
+	TLSServerName     string            // Server name sent and verified during the TLS handshake, instead of the server's host name
+	TLSMinVersion     string            // Minimum TLS version: 1.2 or 1.3
+	Proxy             string            // Proxy of the server's calls, instead of the one given by the environment
+	HTTPVersion       string            // HTTP version of the server's calls: 1.1 or 2
+	MaxConnsPerHost   int               // Maximum number of connections to the server, 0 for no limit
+	MaxIdleConns      int               // Number of idle connections kept open
+	NoKeepAlive       bool              // Close the connection after each call
+	ClientTimeout     time.Duration     // Set the client request timeout
+	UploadRetries     int               // Number of attempts to upload a file
+	UploadRetryDelay  time.Duration     // Delay before retrying an upload
+	ThrottleAll       bool              // Pause all the server's calls when the server asks to slow down
+	NoUI              bool              // Disable user interface
+	Quiet             bool              // Print only the final summary on the console
+	Verbose           bool              // Echo the decision taken for each file on the console
+	NoColor           bool              // Don't use colors on the console
+	JSONLog           bool              // Enable JSON structured log
+	DebugCounters     bool              // Enable CSV action counters per file
+	DebugFileList     bool              // When true, the file argument is a file wile the list of Takeout files
+	Offline           bool              // Don't connect to the server
+	ExtraMedia        immich.ExtraMedia // Extensions added to the supported media
 
 	Immich             immich.ImmichInterface // Immich client
 	Log                *slog.Logger           // Logger
 	Jnl                *fileevent.Recorder    // Program's logger
 	LogFile            string                 // Log file name
+	LogMaxSize         int                    // Size of the log file in MB triggering its rotation
+	LogMaxAge          time.Duration          // Age of the log file triggering its rotation
+	LogMaxFiles        int                    // Number of rotated log files kept
+	LogStderr          bool                   // Write the log on the standard error instead of the log file
+	LogSyslog          bool                   // Send the log to syslog instead of the log file
+	LogSyslogAddress   string                 // Address of the syslog daemon, network://host:port, the local daemon when empty
 	LogWriterCloser    io.WriteCloser         // the log writer
 	APITraceWriter     io.WriteCloser         // API tracer
 	APITraceWriterName string
 	Banner             ui.Banner
+	OnFlagSet          func(fs *flag.FlagSet)    // called with the flag set of the command, used by the completion
+	Targets            []Target                  // servers receiving the same assets, when several profiles are given
+	Capabilities       immich.ServerCapabilities // version and features of the server
+
+	sessionRefreshed  bool             // the expired session has been refreshed once
+	clientCertificate *tls.Certificate // client certificate, loaded once
 }
 
 func (app *SharedFlags) InitSharedFlags() {
 	app.ConfigurationFile = configuration.DefaultConfigFile()
+	app.SessionsFile = configuration.DefaultSessionsFile()
+	app.TrashJournal = configuration.DefaultTrashJournalFile()
 	app.LogFile = configuration.DefaultLogFile()
 	app.APITrace = false
 	app.Debug = false
 	app.SkipSSL = false
 	app.LogLevel = "INFO"
 	app.NoUI = false
+	app.NoColor = os.Getenv("NO_COLOR") != "" // https://no-color.org
 	app.JSONLog = false
 	app.ClientTimeout = 5 * time.Minute
+	app.LogMaxFiles = 5
+	app.UploadRetries = 3
+	app.UploadRetryDelay = 10 * time.Second
+	app.HTTPVersion = "1.1"
+	app.MaxConnsPerHost = 100
+	app.MaxIdleConns = 100
 }
 
 // SetFlag add common flags to a flagset
@@ -68,22 +115,53 @@ func (app *SharedFlags) SetFlags(fs *flag.FlagSet) {
 	fs.StringVar(&app.ConfigurationFile, "use-configuration", app.ConfigurationFile, "Specifies the configuration to use")
 	fs.StringVar(&app.Server, "server", app.Server, "Immich server address (http://<your-ip>:2283 or https://<your-domain>)")
 	fs.StringVar(&app.API, "api", app.API, "Immich api endpoint (http://container_ip:3301)")
-	fs.StringVar(&app.Key, "key", app.Key, "API Key")
+	fs.StringVar(&app.Key, "key", app.Key, "API Key, - to read it from the standard input")
+	fs.StringVar(&app.KeyFile, "key-file", app.KeyFile, "File containing the API key, ex: /run/secrets/immich_key, takes precedence over -key")
 	fs.StringVar(&app.DeviceUUID, "device-uuid", app.DeviceUUID, "Set a device UUID")
 	fs.StringVar(&app.LogLevel, "log-level", app.LogLevel, "Log level (DEBUG|INFO|WARN|ERROR), default INFO")
+	fs.StringVar(&app.TrashJournal, "trash-journal", app.TrashJournal, "File recording the assets moved to the trash, used by the command restore")
 	fs.StringVar(&app.LogFile, "log-file", app.LogFile, "Write log messages into the file")
+	fs.IntVar(&app.LogMaxSize, "log-max-size", app.LogMaxSize, "Rotate the log file when its size reaches this number of MB, default 0: no rotation")
+	fs.Func("log-max-age", "Rotate the log file when it is older than this duration (ex: 24h), default no rotation", myflag.DurationFlagFn(&app.LogMaxAge, app.LogMaxAge))
+	fs.IntVar(&app.LogMaxFiles, "log-max-files", app.LogMaxFiles, "Number of rotated log files kept, default 5")
+	fs.BoolFunc("log-stderr", "Write the log on the standard error instead of the log file, default FALSE", myflag.BoolFlagFn(&app.LogStderr, app.LogStderr))
+	fs.BoolFunc("log-syslog", "Send the log to syslog or journald instead of the log file, default FALSE", myflag.BoolFlagFn(&app.LogSyslog, app.LogSyslog))
+	fs.StringVar(&app.LogSyslogAddress, "log-syslog-address", app.LogSyslogAddress, "Address of the syslog daemon: network://host:port, default the local daemon")
 	fs.BoolFunc("log-json", "Output line-delimited JSON file, default FALSE", myflag.BoolFlagFn(&app.JSONLog, app.JSONLog))
 	fs.BoolFunc("api-trace", "enable trace of api calls", myflag.BoolFlagFn(&app.APITrace, app.APITrace))
 	fs.BoolFunc("debug", "enable debug messages", myflag.BoolFlagFn(&app.Debug, app.Debug))
 	fs.StringVar(&app.TimeZone, "time-zone", app.TimeZone, "Override the system time zone")
 	fs.BoolFunc("skip-verify-ssl", "Skip SSL verification", myflag.BoolFlagFn(&app.SkipSSL, app.SkipSSL))
+	fs.StringVar(&app.ClientCert, "client-cert", app.ClientCert, "Client certificate for the servers requiring mutual TLS: PEM file, or PKCS#12 file (.p12, .pfx) containing the key")
+	fs.StringVar(&app.ClientKey, "client-key", app.ClientKey, "Private key of the client certificate, PEM file, default the certificate file")
+	fs.StringVar(&app.CACert, "ca-cert", app.CACert, "PEM file of the certificate authorities trusted in addition to the system's ones, for the servers using a private CA")
+	fs.StringVar(&app.TLSServerName, "tls-server-name", app.TLSServerName, "Server name sent and verified during the TLS handshake (SNI), default the host name of the server's address")
+	fs.StringVar(&app.TLSMinVersion, "tls-min-version", app.TLSMinVersion, "Minimum TLS version: 1.2 or 1.3, default 1.2")
+	fs.StringVar(&app.Proxy, "proxy", app.Proxy, "Proxy of the server's calls: http://, https://, socks5:// or socks5h://[user:password@]host:port, default the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables")
+	fs.StringVar(&app.HTTPVersion, "http-version", app.HTTPVersion, "HTTP version of the server's calls: 1.1 or 2 (HTTPS only), default 1.1")
+	fs.IntVar(&app.MaxConnsPerHost, "max-conns-per-host", app.MaxConnsPerHost, "Maximum number of connections opened to the server, 0 for no limit, default 100")
+	fs.IntVar(&app.MaxIdleConns, "max-idle-conns", app.MaxIdleConns, "Number of idle connections kept open for the next calls, default 100")
+	fs.BoolFunc("no-keep-alive", "Close the connection after each call, for the reverse proxies mishandling the persistent connections (default: FALSE)", myflag.BoolFlagFn(&app.NoKeepAlive, app.NoKeepAlive))
 	fs.BoolFunc("no-ui", "Disable the user interface", myflag.BoolFlagFn(&app.NoUI, app.NoUI))
+	fs.BoolFunc("quiet", "Print only the final summary on the console", myflag.BoolFlagFn(&app.Quiet, app.Quiet))
+	fs.BoolFunc("verbose", "Echo the decision taken for each file on the console", myflag.BoolFlagFn(&app.Verbose, app.Verbose))
+	fs.BoolFunc("no-color", "Don't use colors on the console, default TRUE when the NO_COLOR environment variable is set", myflag.BoolFlagFn(&app.NoColor, app.NoColor))
 	fs.Func("client-timeout", "Set server calls timeout, default 1m", myflag.DurationFlagFn(&app.ClientTimeout, app.ClientTimeout))
+	fs.IntVar(&app.UploadRetries, "upload-retries", app.UploadRetries, "Number of attempts to upload a file when the connection fails, default 3")
+	fs.Func("upload-retry-delay", "Delay before retrying a failed upload, increased at each attempt, default 10s", myflag.DurationFlagFn(&app.UploadRetryDelay, app.UploadRetryDelay))
+	fs.BoolFunc("throttle-all", "Pause all the server's calls, not only the throttled one, when the server asks to slow down (default: FALSE)", myflag.BoolFlagFn(&app.ThrottleAll, app.ThrottleAll))
+	fs.Var(&app.ExtraMedia, "media-type", "Register an extension missing in the server's list of supported media: .EXT=image, .EXT=video, or .EXT=.SUPPORTED_EXT to upload the file as a supported one (repeatable)")
 	fs.BoolFunc("debug-counters", "generate a CSV file with actions per handled files", myflag.BoolFlagFn(&app.DebugCounters, false))
+	if app.OnFlagSet != nil {
+		app.OnFlagSet(fs)
+	}
 }
 
 func (app *SharedFlags) Start(ctx context.Context) error {
 	var joinedErr error
+	if app.Quiet && app.Verbose {
+		return errors.New("the -quiet and -verbose options are exclusive")
+	}
 	if app.Server != "" {
 		app.Server = strings.TrimSuffix(app.Server, "/")
 	}
@@ -95,19 +173,74 @@ func (app *SharedFlags) Start(ctx context.Context) error {
 	if app.Jnl == nil {
 		app.Jnl = fileevent.NewRecorder(nil, app.DebugCounters)
 	}
+	if app.Verbose {
+		app.Jnl.SetEcho(os.Stdout)
+	}
 
 	if app.DebugFileList {
 		app.Immich = &fakeimmich.MockedCLient{}
 		_ = os.Remove(app.LogFile)
 	}
 
-	if app.LogFile != "" {
+	if app.LogMaxSize < 0 || app.LogMaxAge < 0 || app.LogMaxFiles < 0 {
+		return errors.New("the options -log-max-size, -log-max-age and -log-max-files must be positive")
+	}
+
+	err := app.readKey(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if app.ClientKey != "" && app.ClientCert == "" {
+		return errors.New("the option -client-key needs the option -client-cert")
+	}
+	if app.CACert != "" && app.SkipSSL {
+		return errors.New("the options -ca-cert and -skip-verify-ssl can't be used together")
+	}
+	if _, err := tlsVersion(app.TLSMinVersion); err != nil {
+		return err
+	}
+	if _, err := proxyURL(app.Proxy); err != nil {
+		return err
+	}
+	if err := app.checkTransport(); err != nil {
+		return err
+	}
+
+	if app.LogStderr && app.LogSyslog {
+		return errors.New("the options -log-stderr and -log-syslog are exclusive")
+	}
+
+	switch {
+	case app.LogSyslog:
+		err := app.Level.UnmarshalText([]byte(strings.ToUpper(app.LogLevel)))
+		if err != nil {
+			return err
+		}
+		w, err := sysloghandler.Dial(app.LogSyslogAddress, "immich-go")
+		if err != nil {
+			return fmt.Errorf("can't connect to syslog: %w", err)
+		}
+		app.Log = slog.New(sysloghandler.New(w, app.Level))
+		app.Jnl.SetLogger(app.Log)
+		app.LogWriterCloser = w
+	case app.LogStderr:
+		err := app.Level.UnmarshalText([]byte(strings.ToUpper(app.LogLevel)))
+		if err != nil {
+			return err
+		}
+		app.SetLogWriter(os.Stderr)
+	case app.LogFile != "":
 		if app.LogWriterCloser == nil {
 			err := configuration.MakeDirForFile(app.LogFile)
 			if err != nil {
 				return err
 			}
-			f, err := os.OpenFile(app.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o664)
+			f, err := logrotate.Open(app.LogFile, logrotate.Options{
+				MaxSize:  int64(app.LogMaxSize) << 20,
+				MaxAge:   app.LogMaxAge,
+				MaxFiles: app.LogMaxFiles,
+			})
 			if err != nil {
 				return err
 			}
@@ -121,31 +254,55 @@ func (app *SharedFlags) Start(ctx context.Context) error {
 	}
 
 	// If the client isn't yet initialized
-	if app.Immich == nil {
-		if app.Server == "" && app.API == "" && app.Key == "" {
-			conf, err := configuration.ConfigRead(app.ConfigurationFile)
-			confExist := err == nil
-			if confExist && app.Server == "" && app.Key == "" && app.API == "" {
-				app.Server = conf.ServerURL
-				app.Key = conf.APIKey
-				app.API = conf.APIURL
-			}
+	if app.Immich == nil && !app.Offline {
+		if joinedErr != nil {
+			return joinedErr
 		}
+		return app.connect(ctx, true)
+	}
 
-		switch {
-		case app.Server == "" && app.API == "":
-			joinedErr = errors.Join(joinedErr, errors.New("missing -server, Immich server address (http://<your-ip>:2283 or https://<your-domain>)"))
-		case app.Server != "" && app.API != "":
-			joinedErr = errors.Join(joinedErr, errors.New("give either the -server or the -api option"))
-		}
-		if app.Key == "" {
-			joinedErr = errors.Join(joinedErr, errors.New("missing -key"))
-		}
+	return nil
+}
 
-		if joinedErr != nil {
-			return joinedErr
+// SwitchServer connects to the server of the target. The configuration file is left untouched.
+func (app *SharedFlags) SwitchServer(ctx context.Context, t Target) error {
+	app.Server, app.API, app.Key = strings.TrimSuffix(t.Server, "/"), t.API, t.Key
+	app.Immich = nil
+	return app.connect(ctx, false)
+}
+
+// connect creates the client of the server, and checks the connection.
+// The connection details are saved into the configuration file when save is true.
+func (app *SharedFlags) connect(ctx context.Context, save bool) error {
+	var joinedErr error
+	if app.Server == "" && app.API == "" && app.Key == "" {
+		conf, err := configuration.ConfigRead(app.ConfigurationFile)
+		confExist := err == nil
+		if confExist && app.Server == "" && app.Key == "" && app.API == "" {
+			app.Server = conf.ServerURL
+			app.Key = conf.APIKey
+			app.API = conf.APIURL
 		}
+	}
 
+	switch {
+	case app.Server == "" && app.API == "":
+		joinedErr = errors.Join(joinedErr, errors.New("missing -server, Immich server address (http://<your-ip>:2283 or https://<your-domain>)"))
+	case app.Server != "" && app.API != "":
+		joinedErr = errors.Join(joinedErr, errors.New("give either the -server or the -api option"))
+	}
+	if app.Key == "" && app.AccessToken == "" {
+		app.AccessToken = app.session().AccessToken
+	}
+	if app.Key == "" && app.AccessToken == "" {
+		joinedErr = errors.Join(joinedErr, errors.New("missing -key, give an API key or log in with the command login"))
+	}
+
+	if joinedErr != nil {
+		return joinedErr
+	}
+
+	if save {
 		// Connection details are saved into the configuration file
 		conf := configuration.Configuration{
 			ServerURL: app.Server,
@@ -160,48 +317,96 @@ func (app *SharedFlags) Start(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("can't write into the configuration file: %w", err)
 		}
-		app.Log.Info("Connection to the server " + app.Server)
+	}
+	msg := "Connection to the server " + app.Server
+	if proxy, _ := proxyURL(app.Proxy); proxy != nil {
+		msg += " through the proxy " + proxy.Redacted()
+	}
+	app.Log.Info(msg)
 
-		app.Immich, err = immich.NewImmichClient(app.Server, app.Key, immich.OptionVerifySSL(app.SkipSSL), immich.OptionConnectionTimeout(app.ClientTimeout))
-		if err != nil {
-			return err
-		}
-		if app.API != "" {
-			app.Immich.SetEndPoint(app.API)
-		}
-		if app.DeviceUUID != "" {
-			app.Immich.SetDeviceUUID(app.DeviceUUID)
+	client, err := app.NewClient()
+	if err != nil {
+		return err
+	}
+	app.Immich = client
+
+	err = app.Immich.PingServer(ctx)
+	if err != nil {
+		return err
+	}
+	app.Log.Info("Server status: OK")
+
+	app.Capabilities, err = client.NegotiateServer(ctx)
+	if err != nil {
+		return err
+	}
+	app.Log.Info(fmt.Sprintf("Server version: %s", app.Capabilities.Version))
+
+	user, err := app.Immich.ValidateConnection(ctx)
+	if err != nil {
+		if app.Key == "" && !app.sessionRefreshed && immich.IsUnauthorized(err) {
+			return app.refreshSession(ctx, save)
 		}
+		return err
+	}
+	app.Log.Info(fmt.Sprintf("Connected, user: %s", user.Email))
+
+	return nil
+}
 
-		if app.APITrace {
-			if app.APITraceWriter == nil {
-				err := configuration.MakeDirForFile(app.LogFile)
-				if err != nil {
-					return err
-				}
-				app.APITraceWriterName = strings.TrimSuffix(app.LogFile, filepath.Ext(app.LogFile)) + ".trace.log"
-				app.APITraceWriter, err = os.OpenFile(app.APITraceWriterName, os.O_CREATE|os.O_WRONLY, 0o664)
-				if err != nil {
-					return err
-				}
-				app.Immich.EnableAppTrace(app.APITraceWriter)
+// NewClient creates the client of the server with the options of the command line
+func (app *SharedFlags) NewClient() (*immich.ImmichClient, error) {
+	client, err := immich.NewImmichClient(app.Server, app.Key, immich.OptionVerifySSL(app.SkipSSL), immich.OptionConnectionTimeout(app.ClientTimeout), immich.OptionUploadRetries(app.UploadRetries, app.UploadRetryDelay), immich.OptionThrottleAll(app.ThrottleAll), immich.OptionExtraMedia(app.ExtraMedia))
+	if err != nil {
+		return nil, err
+	}
+	if app.Key == "" && app.AccessToken != "" {
+		client.SetAccessToken(app.AccessToken)
+	}
+	if app.ClientCert != "" {
+		if app.clientCertificate == nil {
+			cert, err := clientcert.Load(app.ClientCert, app.ClientKey, app.certificatePassphrase)
+			if err != nil {
+				return nil, err
 			}
+			app.clientCertificate = &cert
 		}
-
-		err = app.Immich.PingServer(ctx)
+		client.SetClientCertificate(*app.clientCertificate)
+	}
+	err = app.configureTLS(client)
+	if err != nil {
+		return nil, err
+	}
+	if app.Proxy != "" {
+		proxy, err := proxyURL(app.Proxy)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		app.Log.Info("Server status: OK")
+		client.SetProxy(proxy)
+	}
+	app.configureTransport(client)
+	if app.API != "" {
+		client.SetEndPoint(app.API)
+	}
+	if app.DeviceUUID != "" {
+		client.SetDeviceUUID(app.DeviceUUID)
+	}
 
-		user, err := app.Immich.ValidateConnection(ctx)
-		if err != nil {
-			return err
+	if app.APITrace {
+		if app.APITraceWriter == nil {
+			err := configuration.MakeDirForFile(app.LogFile)
+			if err != nil {
+				return nil, err
+			}
+			app.APITraceWriterName = strings.TrimSuffix(app.LogFile, filepath.Ext(app.LogFile)) + ".trace.log"
+			app.APITraceWriter, err = os.OpenFile(app.APITraceWriterName, os.O_CREATE|os.O_WRONLY, 0o664)
+			if err != nil {
+				return nil, err
+			}
 		}
-		app.Log.Info(fmt.Sprintf("Connected, user: %s", user.Email))
+		client.EnableAppTrace(app.APITraceWriter)
 	}
-
-	return nil
+	return client, nil
 }
 
 func (app *SharedFlags) SetLogWriter(w io.Writer) {
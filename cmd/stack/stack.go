@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/myflag"
 	"github.com/simulot/immich-go/helpers/stacking"
 	"github.com/simulot/immich-go/immich"
 	"github.com/simulot/immich-go/ui"
@@ -15,12 +16,17 @@ import (
 
 type StackCmd struct {
 	*cmd.SharedFlags
-	AssumeYes bool
-	DateRange immich.DateRange // Set capture date range
+	AssumeYes     bool
+	DateRange     immich.DateRange // Set capture date range
+	StackJpgRaws  bool             // Stack jpg/raw (Default: TRUE)
+	StackBurst    bool             // Stack burst (Default: TRUE)
+	StackVariants bool             // Stack the numbered variants IMG_1234~2.jpg (Default: FALSE)
+	StackEdited   bool             // Stack the edited copies IMG_1234-edited.jpg (Default: FALSE)
+	DryRun        bool             // Display the stacks but don't change anything
 }
 
 func initStack(ctx context.Context, common *cmd.SharedFlags, args []string) (*StackCmd, error) {
-	cmd := flag.NewFlagSet("stack", flag.ExitOnError)
+	cmd := flag.NewFlagSet("stack", flag.ContinueOnError)
 	validRange := immich.DateRange{}
 
 	_ = validRange.Set("1850-01-04,2030-01-01")
@@ -35,10 +41,18 @@ func initStack(ctx context.Context, common *cmd.SharedFlags, args []string) (*St
 		return err
 	})
 	cmd.Var(&app.DateRange, "date", "Process only documents having a capture date in that range.")
+	cmd.BoolFunc("stack-jpg-raw", "Stack the jpg and raw files having the same name (default TRUE)", myflag.BoolFlagFn(&app.StackJpgRaws, true))
+	cmd.BoolFunc("stack-burst", "Stack the images of a burst (default TRUE)", myflag.BoolFlagFn(&app.StackBurst, true))
+	cmd.BoolFunc("stack-variants", "Stack the numbered variants of an image, like IMG_1234~2.jpg with IMG_1234.jpg (default FALSE)", myflag.BoolFlagFn(&app.StackVariants, false))
+	cmd.BoolFunc("stack-edited", "Stack the edited copies of an image, like IMG_1234-edited.jpg with IMG_1234.jpg (default FALSE)", myflag.BoolFlagFn(&app.StackEdited, false))
+	cmd.BoolFunc("dry-run", "Display the stacks but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
 	err := cmd.Parse(args)
 	if err != nil {
 		return nil, err
 	}
+	if !app.StackJpgRaws && !app.StackBurst && !app.StackVariants && !app.StackEdited {
+		return nil, fmt.Errorf("all the stacking rules are disabled")
+	}
 	err = app.SharedFlags.Start(ctx)
 	if err != nil {
 		return nil, err
@@ -51,15 +65,28 @@ func NewStackCommand(ctx context.Context, common *cmd.SharedFlags, args []string
 	if err != nil {
 		return err
 	}
+	return app.run(ctx)
+}
 
+func (app *StackCmd) run(ctx context.Context) error {
 	sb := stacking.NewStackBuilder(app.Immich.SupportedMedia())
+	if app.StackVariants {
+		sb.StackVariants()
+	}
+	if app.StackEdited {
+		sb.StackEdited()
+	}
 	fmt.Println("Get server's assets...")
 	assetCount := 0
 
-	err = app.Immich.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
+	err := app.Immich.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
 		if a.IsTrashed {
 			return nil
 		}
+		if a.StackParentID != "" || a.Stack != nil {
+			// already stacked
+			return nil
+		}
 		if !app.DateRange.InRange(a.ExifInfo.DateTimeOriginal.Time) {
 			return nil
 		}
@@ -74,6 +101,9 @@ func NewStackCommand(ctx context.Context, common *cmd.SharedFlags, args []string
 	app.Log.Info(fmt.Sprintf(" %d received, %d stack(s) possible\n", assetCount, len(stacks)))
 
 	for _, s := range stacks {
+		if !app.ruleEnabled(s.StackType) {
+			continue
+		}
 		fmt.Printf("Stack following images taken on %s\n", s.Date)
 		cover := s.CoverID
 		names := s.Names
@@ -81,6 +111,9 @@ func NewStackCommand(ctx context.Context, common *cmd.SharedFlags, args []string
 		for _, n := range names {
 			fmt.Printf("  %s\n", n)
 		}
+		if app.DryRun {
+			continue
+		}
 		yes := app.AssumeYes
 		if !app.AssumeYes {
 			r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
@@ -101,3 +134,18 @@ func NewStackCommand(ctx context.Context, common *cmd.SharedFlags, args []string
 
 	return nil
 }
+
+// ruleEnabled tells if the stacks of that type are requested
+func (app *StackCmd) ruleEnabled(t stacking.StackType) bool {
+	switch t {
+	case stacking.StackRawJpg:
+		return app.StackJpgRaws
+	case stacking.StackBurst:
+		return app.StackBurst
+	case stacking.StackVariant:
+		return app.StackVariants
+	case stacking.StackEdited:
+		return app.StackEdited
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package stack
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icStack serves the assets and records the changes of the stacks
+type icStack struct {
+	fakeimmich.MockedCLient
+	assets    []*immich.Asset
+	stacked   map[string][]string
+	unstacked []string
+	covers    map[string]string
+}
+
+func (c *icStack) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icStack) StackAssets(ctx context.Context, cover string, ids []string) error {
+	c.stacked[cover] = ids
+	return nil
+}
+
+func (c *icStack) UnstackAssets(ctx context.Context, ids []string) error {
+	c.unstacked = append(c.unstacked, ids...)
+	return nil
+}
+
+func (c *icStack) SetStackCover(ctx context.Context, oldCover string, newCover string) error {
+	c.covers[oldCover] = newCover
+	return nil
+}
+
+func TestStack(t *testing.T) {
+	date := time.Date(2023, 10, 1, 10, 15, 0, 0, time.UTC)
+	assets := []struct {
+		id, name, parent string
+	}{
+		{id: "1", name: "IMG_0001.JPG"},
+		{id: "2", name: "IMG_0001.DNG"},
+		{id: "3", name: "IMG_0002.jpg"},
+		{id: "4", name: "IMG_0002~2.jpg"},
+		{id: "5", name: "IMG_0003.jpg"},
+		{id: "6", name: "IMG_0003-edited.jpg"},
+		{id: "7", name: "IMG_0004.JPG"},
+		{id: "8", name: "IMG_0004.DNG", parent: "7"},
+	}
+	tests := []struct {
+		name    string
+		app     StackCmd
+		stacked map[string][]string
+	}{
+		{
+			name:    "jpg raw",
+			app:     StackCmd{StackJpgRaws: true, StackBurst: true},
+			stacked: map[string][]string{"1": {"2"}},
+		},
+		{
+			name:    "variants and edited",
+			app:     StackCmd{StackVariants: true, StackEdited: true},
+			stacked: map[string][]string{"3": {"4"}, "5": {"6"}},
+		},
+		{
+			name:    "dry run",
+			app:     StackCmd{StackJpgRaws: true, StackVariants: true, StackEdited: true, DryRun: true},
+			stacked: map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icStack{stacked: map[string][]string{}}
+			for _, a := range assets {
+				asset := &immich.Asset{ID: a.id, OriginalFileName: a.name, StackParentID: a.parent}
+				asset.ExifInfo.DateTimeOriginal.Time = date
+				ic.assets = append(ic.assets, asset)
+			}
+			app := tt.app
+			app.SharedFlags = &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			app.AssumeYes = true
+			_ = app.DateRange.Set("1850-01-04,2030-01-01")
+			if err := app.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ic.stacked, tt.stacked) {
+				t.Errorf("expected stacks %v, got %v", tt.stacked, ic.stacked)
+			}
+		})
+	}
+}
+
+func TestUnstack(t *testing.T) {
+	date := time.Date(2023, 10, 1, 10, 15, 0, 0, time.UTC)
+	assets := []struct {
+		id, name, parent string
+	}{
+		{id: "1", name: "IMG_0001.JPG"},
+		{id: "2", name: "IMG_0001.DNG", parent: "1"},
+		{id: "3", name: "IMG_0002_BURST001_COVER.jpg"},
+		{id: "4", name: "IMG_0002_BURST002.jpg", parent: "3"},
+		{id: "5", name: "IMG_0002_BURST003.jpg", parent: "3"},
+		{id: "6", name: "IMG_0003.jpg"},
+	}
+	tests := []struct {
+		name      string
+		names     []string
+		cover     string
+		dryRun    bool
+		unstacked []string
+		covers    map[string]string
+	}{
+		{name: "all", unstacked: []string{"2", "4", "5"}, covers: map[string]string{}},
+		{name: "bursts", names: []string{"*_BURST*"}, unstacked: []string{"4", "5"}, covers: map[string]string{}},
+		{name: "dry run", dryRun: true, covers: map[string]string{}},
+		{name: "cover", cover: "*.DNG", covers: map[string]string{"1": "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icStack{covers: map[string]string{}}
+			for _, a := range assets {
+				asset := &immich.Asset{ID: a.id, OriginalFileName: a.name, StackParentID: a.parent}
+				asset.ExifInfo.DateTimeOriginal.Time = date
+				ic.assets = append(ic.assets, asset)
+			}
+			app := UnstackCmd{
+				SharedFlags: &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))},
+				AssumeYes:   true,
+				DryRun:      tt.dryRun,
+			}
+			_ = app.DateRange.Set("1850-01-04,2030-01-01")
+			for _, n := range tt.names {
+				_ = app.Names.Set(n)
+			}
+			_ = app.Cover.Set(tt.cover)
+			if err := app.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ic.unstacked, tt.unstacked) {
+				t.Errorf("expected unstacked %v, got %v", tt.unstacked, ic.unstacked)
+			}
+			if !reflect.DeepEqual(ic.covers, tt.covers) {
+				t.Errorf("expected covers %v, got %v", tt.covers, ic.covers)
+			}
+		})
+	}
+}
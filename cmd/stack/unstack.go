@@ -0,0 +1,186 @@
+package stack
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/ui"
+)
+
+type UnstackCmd struct {
+	*cmd.SharedFlags
+	AssumeYes bool
+	DateRange immich.DateRange // Set capture date range
+	Names     namematcher.List // Process only the stacks having an image matching one of these patterns
+	Cover     namematcher.List // Make the image matching the pattern the cover of the stack, instead of dissolving it
+	DryRun    bool             // Display the stacks but don't change anything
+}
+
+// serverStack is a stack found on the server
+type serverStack struct {
+	cover  *immich.Asset
+	assets []*immich.Asset // the other assets of the stack
+}
+
+func initUnstack(ctx context.Context, common *cmd.SharedFlags, args []string) (*UnstackCmd, error) {
+	cmd := flag.NewFlagSet("unstack", flag.ContinueOnError)
+	validRange := immich.DateRange{}
+
+	_ = validRange.Set("1850-01-04,2030-01-01")
+	app := UnstackCmd{
+		SharedFlags: common,
+		DateRange:   validRange,
+	}
+	app.SharedFlags.SetFlags(cmd)
+	cmd.BoolFunc("yes", "When true, assume Yes to all actions", myflag.BoolFlagFn(&app.AssumeYes, false))
+	cmd.Var(&app.DateRange, "date", "Process only the stacks having a capture date in that range.")
+	cmd.Var(&app.Names, "name", "Process only the stacks having an image whose name matches the pattern. Can be repeated.")
+	cmd.Var(&app.Cover, "cover", "Make the image whose name matches the pattern the cover of the stack, instead of dissolving the stack")
+	cmd.BoolFunc("dry-run", "Display the stacks but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &app, err
+}
+
+// UnstackCommand dissolves the server's stacks, or changes their cover
+func UnstackCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := initUnstack(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *UnstackCmd) run(ctx context.Context) error {
+	fmt.Println("Get server's assets...")
+	byID := map[string]*immich.Asset{}
+	children := map[string][]*immich.Asset{}
+	err := app.Immich.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
+		if a.IsTrashed {
+			return nil
+		}
+		byID[a.ID] = a
+		if a.StackCoverID() != "" {
+			children[a.StackCoverID()] = append(children[a.StackCoverID()], a)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	stacks := app.selectStacks(byID, children)
+	app.Log.Info(fmt.Sprintf(" %d received, %d stack(s) selected\n", len(byID), len(stacks)))
+
+	changed := 0
+	for _, s := range stacks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Printf("Stack of %d images taken on %s\n", len(s.assets)+1, s.cover.ExifInfo.DateTimeOriginal.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  cover %s\n", s.cover.OriginalFileName)
+		for _, a := range s.assets {
+			fmt.Printf("        %s\n", a.OriginalFileName)
+		}
+
+		var newCover *immich.Asset
+		if app.Cover.IsSet() {
+			if app.Cover.Match(s.cover.OriginalFileName) {
+				fmt.Println("  the cover already matches")
+				continue
+			}
+			for _, a := range s.assets {
+				if app.Cover.Match(a.OriginalFileName) {
+					newCover = a
+					break
+				}
+			}
+			if newCover == nil {
+				fmt.Println("  no image matches the cover pattern")
+				continue
+			}
+			fmt.Printf("  new cover %s\n", newCover.OriginalFileName)
+		}
+		if app.DryRun {
+			continue
+		}
+		yes := app.AssumeYes
+		if !app.AssumeYes {
+			r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
+			if err != nil {
+				return err
+			}
+			if r == "y" {
+				yes = true
+			}
+		}
+		if !yes {
+			continue
+		}
+		if newCover != nil {
+			err = app.Immich.SetStackCover(ctx, s.cover.ID, newCover.ID)
+		} else {
+			ids := make([]string, len(s.assets))
+			for i, a := range s.assets {
+				ids[i] = a.ID
+			}
+			err = app.Immich.UnstackAssets(ctx, ids)
+		}
+		if err != nil {
+			fmt.Printf("Can't change the stack: %s\n", err)
+			continue
+		}
+		changed++
+	}
+	if app.Cover.IsSet() {
+		fmt.Printf("%d stack(s) with a new cover\n", changed)
+	} else {
+		fmt.Printf("%d stack(s) dissolved\n", changed)
+	}
+	return nil
+}
+
+// selectStacks gives the stacks matching the date range and the names, sorted by date
+func (app *UnstackCmd) selectStacks(byID map[string]*immich.Asset, children map[string][]*immich.Asset) []serverStack {
+	var stacks []serverStack
+	for id, l := range children {
+		cover, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if !app.DateRange.InRange(cover.ExifInfo.DateTimeOriginal.Time) {
+			continue
+		}
+		if app.Names.IsSet() {
+			match := app.Names.Match(cover.OriginalFileName)
+			for _, a := range l {
+				match = match || app.Names.Match(a.OriginalFileName)
+			}
+			if !match {
+				continue
+			}
+		}
+		sort.Slice(l, func(i, j int) bool { return l[i].OriginalFileName < l[j].OriginalFileName })
+		stacks = append(stacks, serverStack{cover: cover, assets: l})
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		c := stacks[i].cover.ExifInfo.DateTimeOriginal.Compare(stacks[j].cover.ExifInfo.DateTimeOriginal.Time)
+		if c != 0 {
+			return c < 0
+		}
+		return stacks[i].cover.OriginalFileName < stacks[j].cover.OriginalFileName
+	})
+	return stacks
+}
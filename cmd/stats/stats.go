@@ -0,0 +1,228 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/ui"
+)
+
+// StatsCmd prints the statistics of the server, a health check before and after a large import
+type StatsCmd struct {
+	*cmd.SharedFlags
+	JSON bool // Print the statistics in JSON
+
+	stdout io.Writer
+}
+
+// Stats are the statistics of the server. The parts reserved to the administrators are nil for the other users.
+type Stats struct {
+	Version string            `json:"version,omitempty"`
+	Storage *storageStats     `json:"storage,omitempty"`
+	Users   []userStats       `json:"users,omitempty"`
+	Assets  assetStats        `json:"assets"`
+	ByYear  []yearStats       `json:"byYear"`
+	Jobs    []jobStats        `json:"jobs,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"` // the statistics that can't be read
+}
+
+type storageStats struct {
+	Size      int64   `json:"size"`
+	Used      int64   `json:"used"`
+	Available int64   `json:"available"`
+	Usage     float64 `json:"usagePercentage"`
+}
+
+type userStats struct {
+	Name   string `json:"name"`
+	Photos int    `json:"photos"`
+	Videos int    `json:"videos"`
+	Usage  int64  `json:"usage"`
+	Quota  int64  `json:"quota,omitempty"` // 0 when the user has no quota
+}
+
+type assetStats struct {
+	Images int `json:"images"`
+	Videos int `json:"videos"`
+	Total  int `json:"total"`
+}
+
+type yearStats struct {
+	Year  int `json:"year"`
+	Count int `json:"count"`
+}
+
+type jobStats struct {
+	Name    string `json:"name"`
+	Active  int    `json:"active"`
+	Waiting int    `json:"waiting"`
+	Delayed int    `json:"delayed"`
+	Failed  int    `json:"failed"`
+	Paused  bool   `json:"paused"`
+}
+
+func NewStatsCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*StatsCmd, error) {
+	app := &StatsCmd{
+		SharedFlags: common,
+		stdout:      os.Stdout,
+	}
+	cmd := flag.NewFlagSet("stats", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.BoolFunc("json", "Print the statistics in JSON (default: FALSE)", myflag.BoolFlagFn(&app.JSON, false))
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+func StatsCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewStatsCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	s, err := app.collect(ctx)
+	if err != nil {
+		return err
+	}
+	if app.JSON {
+		e := json.NewEncoder(app.stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(s)
+	}
+	app.print(s)
+	return nil
+}
+
+// collect reads the statistics. The user's assets are mandatory, the other parts are reported as errors.
+func (app *StatsCmd) collect(ctx context.Context) (Stats, error) {
+	var s Stats
+	s.Errors = map[string]string{}
+
+	a, err := app.Immich.GetAssetStatistics(ctx)
+	if err != nil {
+		return s, fmt.Errorf("can't get the statistics of the assets: %w", err)
+	}
+	s.Assets = assetStats{Images: a.Images, Videos: a.Videos, Total: a.Total}
+
+	if !app.Capabilities.Version.IsZero() {
+		s.Version = app.Capabilities.Version.String()
+	}
+
+	if st, err := app.Immich.GetServerStorage(ctx); err != nil {
+		s.Errors["storage"] = err.Error()
+	} else {
+		s.Storage = &storageStats{Size: st.DiskSizeRaw, Used: st.DiskUseRaw, Available: st.DiskAvailableRaw, Usage: st.DiskUsagePercentage}
+	}
+
+	if buckets, err := app.Immich.GetTimeBuckets(ctx); err != nil {
+		s.Errors["byYear"] = err.Error()
+	} else {
+		years := map[int]int{}
+		for _, b := range buckets {
+			years[b.Year()] += b.Count
+		}
+		for y, n := range years {
+			s.ByYear = append(s.ByYear, yearStats{Year: y, Count: n})
+		}
+		sort.Slice(s.ByYear, func(i, j int) bool { return s.ByYear[i].Year < s.ByYear[j].Year })
+	}
+
+	// reserved to the administrators
+	if ss, err := app.Immich.GetServerStatistics(ctx); err != nil {
+		s.Errors["users"] = err.Error()
+	} else {
+		for _, u := range ss.UsageByUser {
+			s.Users = append(s.Users, userStats{Name: u.UserName, Photos: u.Photos, Videos: u.Videos, Usage: u.Usage, Quota: quota(u.QuotaSizeInBytes)})
+		}
+	}
+	if jobs, err := app.Immich.GetJobs(ctx); err != nil {
+		s.Errors["jobs"] = err.Error()
+	} else {
+		for name, j := range jobs {
+			s.Jobs = append(s.Jobs, jobStats{
+				Name:    name,
+				Active:  j.JobCounts.Active,
+				Waiting: j.JobCounts.Waiting,
+				Delayed: j.JobCounts.Delayed,
+				Failed:  j.JobCounts.Failed,
+				Paused:  j.QueueStatus.IsPaused,
+			})
+		}
+		sort.Slice(s.Jobs, func(i, j int) bool { return s.Jobs[i].Name < s.Jobs[j].Name })
+	}
+	if len(s.Errors) == 0 {
+		s.Errors = nil
+	}
+	for part, e := range s.Errors {
+		app.Log.Debug("can't get the statistics", "part", part, "error", e)
+	}
+	return s, nil
+}
+
+// quota reads the quota of the user, given as a number or null
+func quota(q any) int64 {
+	if f, ok := q.(float64); ok {
+		return int64(f)
+	}
+	return 0
+}
+
+// print writes the statistics as tables
+func (app *StatsCmd) print(s Stats) {
+	w := tabwriter.NewWriter(app.stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	if s.Version != "" {
+		fmt.Fprintf(w, "Server version:\t%s\n", s.Version)
+	}
+	if s.Storage != nil {
+		fmt.Fprintf(w, "Storage:\t%s used of %s (%.1f%%), %s available\n", ui.FormatBytes(int(s.Storage.Used)), ui.FormatBytes(int(s.Storage.Size)), s.Storage.Usage, ui.FormatBytes(int(s.Storage.Available)))
+	}
+	fmt.Fprintf(w, "Your assets:\t%d image(s), %d video(s), %d in total\n", s.Assets.Images, s.Assets.Videos, s.Assets.Total)
+
+	if len(s.ByYear) > 0 {
+		fmt.Fprintln(w, "\nYear\tAssets")
+		for _, y := range s.ByYear {
+			fmt.Fprintf(w, "%d\t%d\n", y.Year, y.Count)
+		}
+	}
+
+	if s.Users != nil {
+		fmt.Fprintln(w, "\nUser\tPhotos\tVideos\tUsage\tQuota")
+		for _, u := range s.Users {
+			q := "-"
+			if u.Quota > 0 {
+				q = ui.FormatBytes(int(u.Quota))
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", u.Name, u.Photos, u.Videos, ui.FormatBytes(int(u.Usage)), q)
+		}
+	}
+
+	if s.Jobs != nil {
+		fmt.Fprintln(w, "\nJob queue\tActive\tWaiting\tDelayed\tFailed\tPaused")
+		for _, j := range s.Jobs {
+			paused := ""
+			if j.Paused {
+				paused = "paused"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", j.Name, j.Active, j.Waiting, j.Delayed, j.Failed, paused)
+		}
+	}
+
+	if s.Users == nil || s.Jobs == nil {
+		fmt.Fprintln(w, "\nThe users' statistics and the job queues are given to the administrators only.")
+	}
+}
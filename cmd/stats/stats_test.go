@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icStats serves the statistics, the administrator's ones when admin is true
+type icStats struct {
+	fakeimmich.MockedCLient
+	admin bool
+}
+
+var errForbidden = errors.New("403 Forbidden")
+
+func (c *icStats) GetAssetStatistics(ctx context.Context) (immich.UserStatistics, error) {
+	return immich.UserStatistics{Images: 10, Videos: 2, Total: 12}, nil
+}
+
+func (c *icStats) GetServerStorage(ctx context.Context) (immich.ServerStorage, error) {
+	return immich.ServerStorage{DiskSizeRaw: 1 << 40, DiskUseRaw: 1 << 39, DiskAvailableRaw: 1 << 39, DiskUsagePercentage: 50}, nil
+}
+
+func (c *icStats) GetTimeBuckets(ctx context.Context) ([]immich.TimeBucket, error) {
+	return []immich.TimeBucket{
+		{TimeBucket: "2024-06-01T00:00:00.000Z", Count: 3},
+		{TimeBucket: "2024-01-01T00:00:00.000Z", Count: 4},
+		{TimeBucket: "2019-08-01", Count: 5},
+	}, nil
+}
+
+func (c *icStats) GetServerStatistics(ctx context.Context) (immich.ServerStatistics, error) {
+	var s immich.ServerStatistics
+	if !c.admin {
+		return s, errForbidden
+	}
+	err := json.Unmarshal([]byte(`{"photos":10,"videos":2,"usage":1000,"usageByUser":[{"userName":"Admin","photos":10,"videos":2,"usage":1000,"quotaSizeInBytes":null},{"userName":"Grandma","photos":0,"videos":0,"usage":0,"quotaSizeInBytes":2048}]}`), &s)
+	return s, err
+}
+
+func (c *icStats) GetJobs(ctx context.Context) (map[string]immich.Job, error) {
+	if !c.admin {
+		return nil, errForbidden
+	}
+	var j immich.Job
+	j.JobCounts.Active = 1
+	j.JobCounts.Waiting = 250
+	return map[string]immich.Job{"thumbnailGeneration": j, "metadataExtraction": {}}, nil
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		admin  bool
+		users  []userStats
+		jobs   []string
+		errors []string
+	}{
+		{
+			name:  "administrator",
+			admin: true,
+			users: []userStats{{Name: "Admin", Photos: 10, Videos: 2, Usage: 1000}, {Name: "Grandma", Quota: 2048}},
+			jobs:  []string{"metadataExtraction", "thumbnailGeneration"},
+		},
+		{
+			name:   "user",
+			errors: []string{"jobs", "users"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &StatsCmd{SharedFlags: &cmd.SharedFlags{Immich: &icStats{admin: tt.admin}, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}}
+			s, err := app.collect(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s.ByYear, []yearStats{{Year: 2019, Count: 5}, {Year: 2024, Count: 7}}) {
+				t.Errorf("unexpected years %v", s.ByYear)
+			}
+			if !reflect.DeepEqual(s.Users, tt.users) {
+				t.Errorf("expected the users %v, got %v", tt.users, s.Users)
+			}
+			var jobs []string
+			for _, j := range s.Jobs {
+				jobs = append(jobs, j.Name)
+			}
+			if !reflect.DeepEqual(jobs, tt.jobs) {
+				t.Errorf("expected the jobs %v, got %v", tt.jobs, jobs)
+			}
+			var errs []string
+			for part := range s.Errors {
+				errs = append(errs, part)
+			}
+			if len(errs) != len(tt.errors) {
+				t.Errorf("expected the errors %v, got %v", tt.errors, s.Errors)
+			}
+
+			out := &bytes.Buffer{}
+			app.stdout = out
+			app.print(s)
+			for _, want := range []string{"Your assets:", "12 in total", "2019", "512.0 GB used"} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("the output doesn't contain %q:\n%s", want, out.String())
+				}
+			}
+			if tt.admin != strings.Contains(out.String(), "thumbnailGeneration  1") {
+				t.Errorf("unexpected job queues:\n%s", out.String())
+			}
+		})
+	}
+}
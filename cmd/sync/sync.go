@@ -0,0 +1,400 @@
+/*
+Keep a local folder and the server in sync: the new local files are uploaded, and the new server's assets are downloaded.
+*/
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/browser/files"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/ui"
+)
+
+// Values of the -conflict option, when a local file and a server's asset have the same name and date of capture but different contents
+const (
+	ConflictBoth   = "BOTH"   // upload the local file and download the server's asset
+	ConflictLocal  = "LOCAL"  // upload the local file only
+	ConflictServer = "SERVER" // download the server's asset only
+	ConflictSkip   = "SKIP"   // report the conflict and leave both sides untouched
+)
+
+// StateFile is the name of the file recording the assets in sync, in the synchronized folder
+const StateFile = ".immich-go-sync.json"
+
+type SyncCmd struct {
+	*cmd.SharedFlags
+	Conflict  string // What to do with conflicting files: BOTH, LOCAL, SERVER or SKIP (default: BOTH)
+	Mirror    bool   // Move to the trash the server's assets whose local files have been deleted
+	AssumeYes bool   // When true, doesn't ask to the user before trashing
+
+	dl         *download.DownloadCmd // downloader of the server's assets, holds the -date, -folder-template, -sidecar and -dry-run options
+	fsys       fs.FS                 // the local folder
+	root       string                // path of the local folder
+	assets     []*immich.Asset       // server's assets
+	byChecksum map[string]*immich.Asset
+	byKey      map[assetKey][]*immich.Asset
+	local      map[string]bool   // checksums of the local files
+	conflicts  map[string]string // server's asset ID -> conflicting local file
+	wasInSync  map[string]bool   // checksums in sync at the end of the previous run
+	nowInSync  map[string]bool   // checksums in sync at the end of this run
+
+	inSync, uploaded, downloaded, conflicted, trashed, failed int
+
+	localErrors int // local files that couldn't be read during this run
+}
+
+// assetKey identifies the versions of the same photo
+type assetKey struct {
+	name string
+	date time.Time
+}
+
+func newKey(name string, date time.Time) assetKey {
+	return assetKey{name: strings.ToLower(path.Base(name)), date: date.Truncate(time.Second).UTC()}
+}
+
+func NewSyncCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*SyncCmd, error) {
+	cmd := flag.NewFlagSet("sync", flag.ContinueOnError)
+	app := SyncCmd{
+		SharedFlags: common,
+		dl:          download.NewDownloader(common),
+	}
+	app.SharedFlags.SetFlags(cmd)
+	app.dl.SetFlags(cmd)
+	cmd.StringVar(&app.Conflict, "conflict", ConflictBoth, " When a local file and a server's asset have the same name and date but different contents, upload and download BOTH, keep the LOCAL file, keep the SERVER's asset, or SKIP them. (default: BOTH)")
+	cmd.BoolFunc("mirror", "Move to the trash the server's assets whose local files have been deleted since the previous sync", myflag.BoolFlagFn(&app.Mirror, false))
+	cmd.BoolFunc("yes", "When true, trash the assets without asking", myflag.BoolFlagFn(&app.AssumeYes, false))
+
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.NArg() != 1 {
+		return nil, errors.New("the sync command needs the folder to synchronize")
+	}
+	app.Conflict = strings.ToUpper(app.Conflict)
+	switch app.Conflict {
+	case ConflictBoth, ConflictLocal, ConflictServer, ConflictSkip:
+	default:
+		return nil, fmt.Errorf("the -conflict accepts BOTH, LOCAL, SERVER or SKIP")
+	}
+	err = app.dl.Validate(cmd.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	fsyss, err := fshelper.ParsePath([]string{cmd.Arg(0)})
+	if err != nil {
+		return nil, err
+	}
+	if len(fsyss) != 1 {
+		return nil, fmt.Errorf("the sync command needs one folder")
+	}
+	if _, ok := fsyss[0].(fshelper.OSDirFS); !ok {
+		_ = fshelper.CloseFSs(fsyss)
+		return nil, fmt.Errorf("the sync command needs a folder, not an archive")
+	}
+	app.fsys = fsyss[0]
+	app.root = cmd.Arg(0)
+
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+func SyncCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewSyncCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *SyncCmd) run(ctx context.Context) error {
+	app.assets = nil
+	app.byChecksum = map[string]*immich.Asset{}
+	app.byKey = map[assetKey][]*immich.Asset{}
+	app.local = map[string]bool{}
+	app.conflicts = map[string]string{}
+	app.nowInSync = map[string]bool{}
+	app.localErrors = 0
+	err := app.readState()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Get server's albums...")
+	err = app.dl.ReadAlbums(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Get server's assets...")
+	err = app.dl.GetAssets(ctx, func(a *immich.Asset) error {
+		app.assets = append(app.assets, a)
+		if a.Checksum != "" {
+			app.byChecksum[a.Checksum] = a
+		}
+		k := newKey(a.OriginalFileName, a.ExifInfo.DateTimeOriginal.Time)
+		app.byKey[k] = append(app.byKey[k], a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Compare the local files with the server...")
+	err = app.syncLocal(ctx)
+	if err != nil {
+		return err
+	}
+	err = app.syncServer(ctx)
+	if err != nil {
+		return err
+	}
+	if app.Mirror {
+		err = app.trashDeleted(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d in sync, %d uploaded, %d downloaded, %d conflict(s), %d trashed, %d error(s)\n", app.inSync, app.uploaded, app.downloaded, app.conflicted, app.trashed, app.failed)
+	if app.dl.DryRun {
+		return nil
+	}
+	return app.writeState()
+}
+
+// syncLocal uploads the local files missing on the server
+func (app *SyncCmd) syncLocal(ctx context.Context) error {
+	b, err := files.NewLocalFiles(ctx, app.Jnl, app.fsys)
+	if err != nil {
+		return err
+	}
+	b.SetSupportedMedia(app.Immich.SupportedMedia())
+	err = b.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+	// the files the browser can't read are reported in the journal
+	browseErrors := app.Jnl.GetCounts()[fileevent.Error]
+	defer func() {
+		n := int(app.Jnl.GetCounts()[fileevent.Error] - browseErrors)
+		app.localErrors += n
+		app.failed += n
+	}()
+	for a := range b.Browse(ctx) {
+		if ctx.Err() != nil {
+			a.Close()
+			return ctx.Err()
+		}
+		err = a.Err
+		if err == nil {
+			err = a.ComputeChecksum()
+		}
+		if err != nil {
+			app.failed++
+			app.localErrors++
+			app.Log.Error("can't read the file", "file", a.FileName, "error", err.Error())
+			a.Close()
+			continue
+		}
+		if app.dl.DateRange.IsSet() && !app.dl.DateRange.InRange(a.Metadata.DateTaken) {
+			// the server selects the assets by their own date: the file is known,
+			// so its asset isn't trashed nor downloaded again when the two dates disagree
+			app.local[a.Checksum] = true
+			if _, ok := app.byChecksum[a.Checksum]; ok {
+				app.nowInSync[a.Checksum] = true
+			}
+			a.Close()
+			continue
+		}
+		err = app.syncLocalAsset(ctx, a)
+		if err != nil {
+			app.failed++
+			app.Log.Error("can't upload the file", "file", a.FileName, "error", err.Error())
+		}
+		a.Close()
+	}
+	return nil
+}
+
+func (app *SyncCmd) syncLocalAsset(ctx context.Context, a *browser.LocalAssetFile) error {
+	app.local[a.Checksum] = true
+	if _, ok := app.byChecksum[a.Checksum]; ok {
+		app.inSync++
+		app.nowInSync[a.Checksum] = true
+		return nil
+	}
+
+	upload := true
+	for _, sa := range app.byKey[newKey(a.FileName, a.Metadata.DateTaken)] {
+		if _, done := app.conflicts[sa.ID]; done {
+			continue
+		}
+		app.conflicts[sa.ID] = a.FileName
+		app.conflicted++
+		app.Log.Warn("conflict", "file", a.FileName, "id", sa.ID, "rule", app.Conflict)
+		upload = app.Conflict == ConflictBoth || app.Conflict == ConflictLocal
+		break
+	}
+	if !upload {
+		return nil
+	}
+
+	app.Log.Info("upload", "file", a.FileName)
+	if !app.dl.DryRun {
+		if a.LivePhoto != nil {
+			r, err := app.Immich.AssetUpload(ctx, a.LivePhoto)
+			if err != nil {
+				return err
+			}
+			a.LivePhotoID = r.ID
+		}
+		_, err := app.Immich.AssetUpload(ctx, a)
+		if err != nil {
+			return err
+		}
+		app.nowInSync[a.Checksum] = true
+	}
+	app.uploaded++
+	return nil
+}
+
+// syncServer downloads the server's assets missing in the local folder
+func (app *SyncCmd) syncServer(ctx context.Context) error {
+	for _, a := range app.assets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if app.local[a.Checksum] {
+			continue
+		}
+		if app.Mirror && app.wasInSync[a.Checksum] {
+			// the local file has been deleted, trashDeleted takes care of the asset
+			continue
+		}
+		if _, ok := app.conflicts[a.ID]; ok && (app.Conflict == ConflictLocal || app.Conflict == ConflictSkip) {
+			continue
+		}
+		r, err := app.dl.DownloadAsset(ctx, a)
+		if err != nil {
+			app.failed++
+			app.Log.Error("can't download the asset", "file", a.OriginalFileName, "id", a.ID, "error", err.Error())
+			continue
+		}
+		if r.Downloaded {
+			app.downloaded++
+		}
+		if !app.dl.DryRun {
+			app.nowInSync[a.Checksum] = true
+		}
+	}
+	return nil
+}
+
+// trashDeleted moves to the trash the server's assets that were in sync at the end of the previous run,
+// and whose local files have been deleted since. The assets are never deleted permanently.
+//
+// Nothing is trashed when some local files couldn't be read: they would be taken for deleted files.
+func (app *SyncCmd) trashDeleted(ctx context.Context) error {
+	var ids []string
+	var checksums []string
+	for _, a := range app.assets {
+		if app.local[a.Checksum] || !app.wasInSync[a.Checksum] {
+			continue
+		}
+		if app.localErrors > 0 {
+			// keep them in the state, so they are checked again at the next run
+			app.nowInSync[a.Checksum] = true
+			continue
+		}
+		fmt.Printf("  trash %s, %s\n", a.OriginalFileName, a.ID)
+		app.Log.Info("trash", "file", a.OriginalFileName, "id", a.ID)
+		ids = append(ids, a.ID)
+		checksums = append(checksums, a.Checksum)
+	}
+	if app.localErrors > 0 {
+		fmt.Printf("%d local file(s) can't be read, the deleted files aren't mirrored on the server during this run\n", app.localErrors)
+		app.Log.Warn("the deleted files aren't mirrored because of read errors", "errors", app.localErrors)
+		return nil
+	}
+	if len(ids) == 0 || app.dl.DryRun {
+		return nil
+	}
+	if !app.AssumeYes {
+		r, err := ui.ConfirmYesNo(ctx, fmt.Sprintf("Move %d asset(s) to the trash?", len(ids)), "n")
+		if err != nil {
+			return err
+		}
+		if r != "y" {
+			// keep them in the state, so they are proposed again at the next run
+			for _, c := range checksums {
+				app.nowInSync[c] = true
+			}
+			return nil
+		}
+	}
+	err := app.TrashAssets(ctx, "sync", ids)
+	if err != nil {
+		return err
+	}
+	app.trashed += len(ids)
+	return nil
+}
+
+// syncState is the content of the state file
+type syncState struct {
+	InSync []string `json:"inSync"` // checksums of the files present in the folder and on the server
+}
+
+func (app *SyncCmd) readState() error {
+	app.wasInSync = map[string]bool{}
+	b, err := os.ReadFile(filepath.Join(app.root, StateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var s syncState
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return fmt.Errorf("can't read the state file: %w", err)
+	}
+	for _, c := range s.InSync {
+		app.wasInSync[c] = true
+	}
+	return nil
+}
+
+func (app *SyncCmd) writeState() error {
+	s := syncState{InSync: make([]string, 0, len(app.nowInSync))}
+	for c := range app.nowInSync {
+		s.InSync = append(s.InSync, c)
+	}
+	sort.Strings(s.InSync)
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(app.root, StateFile), b, 0o644)
+}
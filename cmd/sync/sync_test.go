@@ -0,0 +1,308 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icSync serves the assets and records the uploads
+type icSync struct {
+	fakeimmich.MockedCLient
+	assets   []*immich.Asset
+	content  map[string]string
+	uploaded []string
+	trashed  []string
+}
+
+func (c *icSync) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icSync) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	return c.GetAllAssetsWithFilter(ctx, fn)
+}
+
+func (c *icSync) DownloadAsset(ctx context.Context, id string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(c.content[id])), nil
+}
+
+func (c *icSync) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.uploaded = append(c.uploaded, a.FileName)
+	return immich.AssetResponse{ID: "new"}, nil
+}
+
+func (c *icSync) DeleteAssets(ctx context.Context, ids []string, force bool) error {
+	if force {
+		return errors.New("assets must not be deleted permanently")
+	}
+	c.trashed = append(c.trashed, ids...)
+	return nil
+}
+
+func checksum(s string) string {
+	h := sha1.Sum([]byte(s))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func (c *icSync) addAsset(id, name, content string, date time.Time) {
+	a := &immich.Asset{ID: id, OriginalFileName: name, Type: "IMAGE", Checksum: checksum(content)}
+	a.ExifInfo.FileSizeInByte = len(content)
+	a.ExifInfo.DateTimeOriginal.Time = date
+	c.assets = append(c.assets, a)
+	c.content[id] = content
+}
+
+func TestSync(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		conflict   string
+		uploaded   int
+		downloaded int
+	}{
+		{conflict: ConflictBoth, uploaded: 2, downloaded: 2},
+		{conflict: ConflictLocal, uploaded: 2, downloaded: 1},
+		{conflict: ConflictServer, uploaded: 1, downloaded: 2},
+		{conflict: ConflictSkip, uploaded: 1, downloaded: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.conflict, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range map[string]string{
+				"2023/a.jpg":              "aaa",
+				"b.jpg":                   "bbb",
+				"PXL_20230601_100000.jpg": "local version",
+			} {
+				file := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ic := &icSync{content: map[string]string{}}
+			ic.addAsset("a", "a.jpg", "aaa", date)
+			ic.addAsset("c", "c.jpg", "ccc", date)
+			ic.addAsset("p", "PXL_20230601_100000.jpg", "server version", date)
+
+			fsyss, err := fshelper.ParsePath([]string{dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			common := &cmd.SharedFlags{
+				Immich: ic,
+				Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+				Jnl:    fileevent.NewRecorder(nil, false),
+			}
+			app := &SyncCmd{
+				SharedFlags: common,
+				Conflict:    tt.conflict,
+				dl:          download.NewDownloader(common),
+				fsys:        fsyss[0],
+				root:        dir,
+			}
+			if err = app.dl.Validate(dir); err != nil {
+				t.Fatal(err)
+			}
+			err = app.run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if app.inSync != 1 || app.conflicted != 1 || app.failed != 0 {
+				t.Errorf("in sync %d, conflicts %d, errors %d", app.inSync, app.conflicted, app.failed)
+			}
+			if app.uploaded != tt.uploaded || len(ic.uploaded) != tt.uploaded {
+				t.Errorf("uploaded %d %v, want %d", app.uploaded, ic.uploaded, tt.uploaded)
+			}
+			if app.downloaded != tt.downloaded {
+				t.Errorf("downloaded %d, want %d", app.downloaded, tt.downloaded)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "2023", "2023-06", "c.jpg")); err != nil {
+				t.Errorf("the server's asset isn't downloaded: %v", err)
+			}
+		})
+	}
+}
+
+// unreadableFS fails to open a file
+type unreadableFS struct {
+	fs.FS
+	name string
+}
+
+func (u unreadableFS) Open(name string) (fs.File, error) {
+	if name == u.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return u.FS.Open(name)
+}
+
+func TestMirror(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.Local)
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.jpg": "aaa", "b.jpg": "bbb"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ic := &icSync{content: map[string]string{}}
+	ic.addAsset("a", "a.jpg", "aaa", date)
+	ic.addAsset("b", "b.jpg", "bbb", date)
+	unreadable := ""
+
+	sync := func(dryRun bool) *SyncCmd {
+		t.Helper()
+		fsyss, err := fshelper.ParsePath([]string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		common := &cmd.SharedFlags{
+			Immich: ic,
+			Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Jnl:    fileevent.NewRecorder(nil, false),
+		}
+		app := &SyncCmd{
+			SharedFlags: common,
+			Conflict:    ConflictBoth,
+			Mirror:      true,
+			AssumeYes:   true,
+			dl:          download.NewDownloader(common),
+			fsys:        fsyss[0],
+			root:        dir,
+		}
+		if unreadable != "" {
+			app.fsys = unreadableFS{FS: app.fsys, name: unreadable}
+		}
+		app.dl.DryRun = dryRun
+		if err = app.dl.Validate(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err = app.run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return app
+	}
+
+	// a and b are in sync, c is new on the server
+	ic.addAsset("c", "c.jpg", "ccc", date)
+	app := sync(false)
+	if app.inSync != 2 || app.downloaded != 1 || app.trashed != 0 {
+		t.Fatalf("first run: in sync %d, downloaded %d, trashed %d", app.inSync, app.downloaded, app.trashed)
+	}
+
+	// b is deleted locally, d is new on the server: b is trashed and d downloaded
+	if err := os.Remove(filepath.Join(dir, "b.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	ic.addAsset("d", "d.jpg", "ddd", date)
+	app = sync(true)
+	if app.trashed != 0 || len(ic.trashed) != 0 {
+		t.Errorf("dry run: trashed %d %v", app.trashed, ic.trashed)
+	}
+	app = sync(false)
+	if app.trashed != 1 || len(ic.trashed) != 1 || ic.trashed[0] != "b" {
+		t.Errorf("trashed %d %v, want [b]", app.trashed, ic.trashed)
+	}
+	if app.downloaded != 1 {
+		t.Errorf("downloaded %d, want 1", app.downloaded)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2023", "2023-06", "b.jpg")); err == nil {
+		t.Errorf("the deleted file is downloaded again")
+	}
+
+	// a can't be read: it isn't taken for a deleted file
+	ic.trashed = nil
+	unreadable = "a.jpg"
+	app = sync(false)
+	if app.localErrors == 0 || app.trashed != 0 || len(ic.trashed) != 0 {
+		t.Errorf("unreadable file: errors %d, trashed %d %v", app.localErrors, app.trashed, ic.trashed)
+	}
+	unreadable = ""
+	app = sync(false)
+	if app.trashed != 0 || len(ic.trashed) != 0 {
+		t.Errorf("a is still in sync: trashed %d %v", app.trashed, ic.trashed)
+	}
+}
+
+// the local date of a file can disagree with the server's date of its asset:
+// the file found out of the range stays known, and its asset isn't trashed
+func TestMirrorDateRange(t *testing.T) {
+	date := time.Date(2023, 6, 1, 10, 0, 0, 0, time.Local)
+	dir := t.TempDir()
+	// without metadata, the local date is the file's modification time
+	if err := os.WriteFile(filepath.Join(dir, "a.jpg"), []byte("aaa"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ic := &icSync{content: map[string]string{}}
+	ic.addAsset("a", "a.jpg", "aaa", date)
+
+	sync := func(dateRange string) *SyncCmd {
+		t.Helper()
+		fsyss, err := fshelper.ParsePath([]string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		common := &cmd.SharedFlags{
+			Immich: ic,
+			Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Jnl:    fileevent.NewRecorder(nil, false),
+		}
+		app := &SyncCmd{
+			SharedFlags: common,
+			Conflict:    ConflictBoth,
+			Mirror:      true,
+			AssumeYes:   true,
+			dl:          download.NewDownloader(common),
+			fsys:        fsyss[0],
+			root:        dir,
+		}
+		if dateRange != "" {
+			if err = app.dl.DateRange.Set(dateRange); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = app.dl.Validate(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err = app.run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return app
+	}
+
+	app := sync("")
+	if app.inSync != 1 {
+		t.Fatalf("first run: in sync %d", app.inSync)
+	}
+	app = sync("2023-06-01")
+	if app.trashed != 0 || len(ic.trashed) != 0 || app.downloaded != 0 {
+		t.Errorf("with the date range: trashed %d %v, downloaded %d", app.trashed, ic.trashed, app.downloaded)
+	}
+	app = sync("")
+	if app.trashed != 0 || app.inSync != 1 {
+		t.Errorf("a is still in sync: in sync %d, trashed %d", app.inSync, app.trashed)
+	}
+}
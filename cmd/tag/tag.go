@@ -0,0 +1,239 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/ui"
+)
+
+// Actions of the tag command
+const (
+	ActionAdd    = "add"
+	ActionRemove = "remove"
+	ActionList   = "list"
+)
+
+type TagCmd struct {
+	*cmd.SharedFlags
+	Action    string
+	Albums    namematcher.List // Select the assets of the albums matching these patterns
+	Names     namematcher.List // Select the assets whose name matches these patterns
+	AssumeYes bool
+	DryRun    bool     // Display the selected assets but don't change anything
+	Tags      []string // Tags to add or remove
+
+	dl *download.DownloadCmd // selection of the assets
+}
+
+// TagCommand adds tags to the selected assets, removes them, or lists the tags
+func TagCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case ActionAdd, ActionRemove, ActionList:
+			app, err := NewTagCmd(ctx, common, args[0], args[1:])
+			if err != nil {
+				return err
+			}
+			return app.run(ctx)
+		}
+	}
+	return fmt.Errorf("tag needs a command: add|remove|list")
+}
+
+func NewTagCmd(ctx context.Context, common *cmd.SharedFlags, action string, args []string) (*TagCmd, error) {
+	app := &TagCmd{
+		SharedFlags: common,
+		Action:      action,
+		dl:          download.NewDownloader(common),
+	}
+	cmd := flag.NewFlagSet("tag "+action, flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.Var(&app.dl.DateRange, "date", "Select the assets having a capture date in that range.")
+	cmd.Var(&app.Albums, "album", "Select the assets of the albums whose name matches the pattern, can be repeated.")
+	cmd.Func("person", "Select the assets showing the person, can be repeated.", func(s string) error {
+		app.dl.People = append(app.dl.People, s)
+		return nil
+	})
+	cmd.Var(&app.Names, "name", "Select the assets whose file name matches the pattern, can be repeated.")
+	cmd.BoolFunc("yes", "When true, assume Yes to all actions", myflag.BoolFlagFn(&app.AssumeYes, false))
+	cmd.BoolFunc("dry-run", "Display the selected assets but don't change anything", myflag.BoolFlagFn(&app.DryRun, false))
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	app.Tags = cmd.Args()
+	if action != ActionList {
+		if len(app.Tags) == 0 {
+			return nil, fmt.Errorf("the tag %s command needs the names of the tags", action)
+		}
+		if !app.isSelection() {
+			return nil, errors.New("select the assets with -album, -date, -person or -name")
+		}
+	}
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !app.Capabilities.Tags() {
+		return nil, fmt.Errorf("the server version %s doesn't manage the tags, the tag command needs the server version v1.113.0 or newer", app.Capabilities.Version)
+	}
+	return app, nil
+}
+
+// isSelection tells if the assets are selected by an option
+func (app *TagCmd) isSelection() bool {
+	return app.Albums.IsSet() || app.Names.IsSet() || app.dl.DateRange.IsSet() || len(app.dl.People) > 0
+}
+
+func (app *TagCmd) run(ctx context.Context) error {
+	if app.Action == ActionList && !app.isSelection() {
+		return app.listServerTags(ctx)
+	}
+
+	assets, err := app.selectAssets(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d asset(s) selected\n", len(assets))
+
+	if app.Action == ActionList {
+		return app.listAssetTags(assets)
+	}
+	if len(assets) == 0 {
+		return nil
+	}
+	if app.DryRun {
+		for _, a := range assets {
+			fmt.Printf("  %s %s\n", a.ExifInfo.DateTimeOriginal.Format("2006-01-02 15:04:05"), a.OriginalFileName)
+		}
+		return nil
+	}
+	if !app.AssumeYes {
+		fmt.Printf("%s the tag(s) %s\n", app.Action, strings.Join(app.Tags, ", "))
+		r, err := ui.ConfirmYesNo(ctx, "Proceed?", "n")
+		if err != nil {
+			return err
+		}
+		if r != "y" {
+			return nil
+		}
+	}
+
+	ids := make([]string, len(assets))
+	for i, a := range assets {
+		ids[i] = a.ID
+	}
+	tags, err := app.getTags(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range tags {
+		var r []immich.UpdateAlbumResult
+		if app.Action == ActionAdd {
+			r, err = app.Immich.TagAssets(ctx, t.ID, ids)
+		} else {
+			r, err = app.Immich.UntagAssets(ctx, t.ID, ids)
+		}
+		if err != nil {
+			return fmt.Errorf("can't %s the tag %s: %w", app.Action, t.Value, err)
+		}
+		changed := 0
+		for _, res := range r {
+			if res.Success {
+				changed++
+			}
+		}
+		if app.Action == ActionAdd {
+			fmt.Printf("%d asset(s) tagged with %s\n", changed, t.Value)
+		} else {
+			fmt.Printf("%d asset(s) untagged from %s\n", changed, t.Value)
+		}
+	}
+	return nil
+}
+
+// selectAssets gives the assets selected by the options
+func (app *TagCmd) selectAssets(ctx context.Context) ([]*immich.Asset, error) {
+	if app.Albums.IsSet() {
+		err := app.dl.SelectAlbums(ctx, app.Albums)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var assets []*immich.Asset
+	err := app.dl.GetAssets(ctx, func(a *immich.Asset) error {
+		if app.Names.IsSet() && !app.Names.Match(a.OriginalFileName) {
+			return nil
+		}
+		assets = append(assets, a)
+		return nil
+	})
+	return assets, err
+}
+
+// getTags gives the server's tags to add or remove. The missing tags are created when adding them.
+func (app *TagCmd) getTags(ctx context.Context) ([]immich.Tag, error) {
+	if app.Action == ActionAdd {
+		return app.Immich.UpsertTags(ctx, app.Tags)
+	}
+	all, err := app.Immich.GetAllTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tags []immich.Tag
+	for _, name := range app.Tags {
+		found := false
+		for _, t := range all {
+			if strings.EqualFold(t.Value, name) {
+				tags = append(tags, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("tag %q not found", name)
+		}
+	}
+	return tags, nil
+}
+
+func (app *TagCmd) listServerTags(ctx context.Context) error {
+	tags, err := app.Immich.GetAllTags(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Value < tags[j].Value })
+	for _, t := range tags {
+		fmt.Println(t.Value)
+	}
+	return nil
+}
+
+// listAssetTags prints the tags of the selected assets, with the number of assets having them
+func (app *TagCmd) listAssetTags(assets []*immich.Asset) error {
+	counts := map[string]int{}
+	for _, a := range assets {
+		for _, t := range a.Tags {
+			counts[t.Value]++
+		}
+	}
+	names := make([]string, 0, len(counts))
+	for n := range counts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Printf("%s: %d\n", n, counts[n])
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package tag
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/download"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icTag serves the assets and the albums, and records the tagged assets
+type icTag struct {
+	fakeimmich.MockedCLient
+	assets []*immich.Asset
+	albums map[string][]string // album -> asset IDs
+	tags   map[string][]string // tag -> asset IDs
+}
+
+func (c *icTag) SearchAssets(ctx context.Context, q immich.SearchQuery, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icTag) GetAllAlbums(ctx context.Context) ([]immich.AlbumSimplified, error) {
+	var albums []immich.AlbumSimplified
+	for name := range c.albums {
+		albums = append(albums, immich.AlbumSimplified{ID: name, AlbumName: name})
+	}
+	return albums, nil
+}
+
+func (c *icTag) GetAlbumInfo(ctx context.Context, id string, withoutAssets bool) (immich.AlbumContent, error) {
+	content := immich.AlbumContent{ID: id, AlbumName: id}
+	for _, a := range c.albums[id] {
+		content.Assets = append(content.Assets, immich.AssetSimplified{ID: a})
+	}
+	return content, nil
+}
+
+func (c *icTag) GetAllTags(ctx context.Context) ([]immich.Tag, error) {
+	var tags []immich.Tag
+	for name := range c.tags {
+		tags = append(tags, immich.Tag{ID: name, Name: name, Value: name})
+	}
+	return tags, nil
+}
+
+func (c *icTag) UpsertTags(ctx context.Context, names []string) ([]immich.Tag, error) {
+	var tags []immich.Tag
+	for _, name := range names {
+		if _, ok := c.tags[name]; !ok {
+			c.tags[name] = nil
+		}
+		tags = append(tags, immich.Tag{ID: name, Name: name, Value: name})
+	}
+	return tags, nil
+}
+
+func (c *icTag) TagAssets(ctx context.Context, tagID string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	var r []immich.UpdateAlbumResult
+	for _, id := range ids {
+		c.tags[tagID] = append(c.tags[tagID], id)
+		r = append(r, immich.UpdateAlbumResult{ID: id, Success: true})
+	}
+	return r, nil
+}
+
+func (c *icTag) UntagAssets(ctx context.Context, tagID string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	var r []immich.UpdateAlbumResult
+	var kept []string
+	for _, id := range c.tags[tagID] {
+		if !slices.Contains(ids, id) {
+			kept = append(kept, id)
+		}
+	}
+	c.tags[tagID] = kept
+	for _, id := range ids {
+		r = append(r, immich.UpdateAlbumResult{ID: id, Success: true})
+	}
+	return r, nil
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		albums  []string
+		names   []string
+		tags    []string
+		dryRun  bool
+		initial map[string][]string
+		want    map[string][]string
+	}{
+		{
+			name:    "add to scans albums",
+			action:  ActionAdd,
+			albums:  []string{"Scans*"},
+			tags:    []string{"scanned"},
+			initial: map[string][]string{},
+			want:    map[string][]string{"scanned": {"1", "2", "3"}},
+		},
+		{
+			name:    "add by name",
+			action:  ActionAdd,
+			names:   []string{"*.tif"},
+			tags:    []string{"scanned"},
+			initial: map[string][]string{},
+			want:    map[string][]string{"scanned": {"1", "3"}},
+		},
+		{
+			name:    "dry run",
+			action:  ActionAdd,
+			names:   []string{"*.tif"},
+			tags:    []string{"scanned"},
+			dryRun:  true,
+			initial: map[string][]string{},
+			want:    map[string][]string{},
+		},
+		{
+			name:    "remove",
+			action:  ActionRemove,
+			albums:  []string{"Scans 1990"},
+			tags:    []string{"scanned"},
+			initial: map[string][]string{"scanned": {"1", "2", "3", "4"}},
+			want:    map[string][]string{"scanned": {"3", "4"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icTag{
+				albums: map[string][]string{"Scans 1990": {"1", "2"}, "Scans 1995": {"3"}, "Holidays": {"4"}},
+				tags:   tt.initial,
+			}
+			for i, name := range []string{"scan1.tif", "scan2.jpg", "scan3.tif", "holidays.jpg"} {
+				a := &immich.Asset{ID: string(rune('1' + i)), OriginalFileName: name, Type: "IMAGE"}
+				a.ExifInfo.DateTimeOriginal.Time = time.Date(1990, 7, 1, 10, 0, 0, 0, time.UTC)
+				ic.assets = append(ic.assets, a)
+			}
+			app := &TagCmd{
+				SharedFlags: &cmd.SharedFlags{Immich: ic, Log: slog.New(slog.NewTextHandler(io.Discard, nil))},
+				Action:      tt.action,
+				AssumeYes:   true,
+				DryRun:      tt.dryRun,
+				Tags:        tt.tags,
+			}
+			app.dl = download.NewDownloader(app.SharedFlags)
+			for _, p := range tt.albums {
+				_ = app.Albums.Set(p)
+			}
+			for _, p := range tt.names {
+				_ = app.Names.Set(p)
+			}
+			if err := app.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			for _, l := range ic.tags {
+				sort.Strings(l)
+			}
+			if !reflect.DeepEqual(ic.tags, tt.want) {
+				t.Errorf("expected tags %v, got %v", tt.want, ic.tags)
+			}
+		})
+	}
+}
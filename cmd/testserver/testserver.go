@@ -0,0 +1,86 @@
+/*
+Serve in memory the part of the Immich API used by immich-go, to try the commands without a real server.
+*/
+package testserver
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/fakeserver"
+)
+
+type TestServerCmd struct {
+	*cmd.SharedFlags
+	Listen  string // address of the server
+	Version string // version announced by the server
+
+	stdout   io.Writer
+	onListen func(addr string) // called when the server is ready
+}
+
+func TestServerCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app := &TestServerCmd{
+		SharedFlags: common,
+		stdout:      os.Stdout,
+	}
+	cmd := flag.NewFlagSet("test-server", flag.ContinueOnError)
+	app.SharedFlags.SetFlags(cmd)
+	cmd.StringVar(&app.Listen, "listen", "127.0.0.1:2283", "Address of the test server")
+	cmd.StringVar(&app.Version, "server-version", "1.118.0", "Version announced by the test server")
+	err := cmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	return app.run(ctx)
+}
+
+func (app *TestServerCmd) run(ctx context.Context) error {
+	var v immich.ServerVersion
+	_, err := fmt.Sscanf(app.Version, "%d.%d.%d", &v.Major, &v.Minor, &v.Patch)
+	if err != nil {
+		return fmt.Errorf("invalid server version %q, expected MAJOR.MINOR.PATCH", app.Version)
+	}
+	s := fakeserver.New(app.Key)
+	s.SetVersion(v)
+
+	ln, err := net.Listen("tcp", app.Listen)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s} //nolint:gosec
+	addr := "http://" + ln.Addr().String()
+	fmt.Fprintf(app.stdout, "Immich test server listening on %s, ", addr)
+	if app.Key == "" {
+		fmt.Fprintln(app.stdout, "any API key accepted")
+	} else {
+		fmt.Fprintln(app.stdout, "API key:", app.Key)
+	}
+	fmt.Fprintln(app.stdout, "The assets are kept in memory until the server is stopped with Ctrl+C")
+	if app.onListen != nil {
+		app.onListen(addr)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ln)
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = srv.Shutdown(context.Background())
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	fmt.Fprintf(app.stdout, "%d uploads received, %d assets, %d albums\n", s.Uploads(), len(s.Assets()), len(s.Albums()))
+	return err
+}
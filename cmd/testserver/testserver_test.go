@@ -0,0 +1,62 @@
+package testserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestTestServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := bytes.NewBuffer(nil)
+	result := make(chan error, 1)
+	app := &TestServerCmd{
+		SharedFlags: &cmd.SharedFlags{Key: "KEY"},
+		Listen:      "127.0.0.1:0",
+		Version:     "1.120.1",
+		stdout:      out,
+		onListen: func(addr string) {
+			go func() {
+				result <- checkServer(ctx, addr)
+				cancel()
+			}()
+		},
+	}
+	err := app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(out.String(), "API key: KEY") || !strings.Contains(out.String(), "0 uploads received") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	app.Version = "latest"
+	if err := app.run(ctx); err == nil {
+		t.Error("an error is expected for an invalid version")
+	}
+}
+
+func checkServer(ctx context.Context, addr string) error {
+	ic, err := immich.NewImmichClient(addr, "KEY")
+	if err != nil {
+		return err
+	}
+	c, err := ic.NegotiateServer(ctx)
+	if err != nil {
+		return err
+	}
+	if c.Version != (immich.ServerVersion{Major: 1, Minor: 120, Patch: 1}) {
+		return fmt.Errorf("unexpected version %s", c.Version)
+	}
+	_, err = ic.ValidateConnection(ctx)
+	return err
+}
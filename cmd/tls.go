@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/simulot/immich-go/immich"
+	"golang.org/x/term"
+)
+
+// PassphraseEnv gives the passphrase of the client certificate when the input isn't a terminal
+const PassphraseEnv = "IMMICH_GO_CLIENT_CERT_PASSPHRASE"
+
+// certificatePassphrase asks the passphrase of the client certificate without echoing it,
+// or takes it from the environment when the input isn't a terminal
+func (app *SharedFlags) certificatePassphrase() (string, error) {
+	if p, ok := os.LookupEnv(PassphraseEnv); ok {
+		return p, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("the client certificate is protected by a passphrase, give it with the environment variable " + PassphraseEnv)
+	}
+	fmt.Printf("Passphrase of the client certificate %s: ", app.ClientCert)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// configureTLS applies the TLS options to the client
+func (app *SharedFlags) configureTLS(client *immich.ImmichClient) error {
+	if app.CACert != "" {
+		b, err := os.ReadFile(app.CACert)
+		if err != nil {
+			return err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no certificate found in the file %s", app.CACert)
+		}
+		client.SetRootCAs(pool)
+	}
+	if app.TLSServerName != "" {
+		client.SetTLSServerName(app.TLSServerName)
+	}
+	version, err := tlsVersion(app.TLSMinVersion)
+	if err != nil {
+		return err
+	}
+	client.SetMinTLSVersion(version)
+	return nil
+}
+
+// tlsVersion gives the TLS version by its number, TLS 1.2 when empty
+func tlsVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q, use 1.2 or 1.3", v)
+}
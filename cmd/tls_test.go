@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simulot/immich-go/helpers/clientcert"
+)
+
+// mtlsServer simulates an immich server behind a reverse proxy requiring the client certificate of the PKCS#12 file
+func mtlsServer(t *testing.T, p12 string, passphrase string) *httptest.Server {
+	t.Setenv(PassphraseEnv, passphrase)
+	cert, err := clientcert.Load(p12, "", nil)
+	if err == nil {
+		t.Fatal("the PKCS#12 file should be protected")
+	}
+	cert, err = clientcert.Load(p12, "", func() (string, error) { return passphrase, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	server := httptest.NewUnstartedServer(immichHandler())
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	return server
+}
+
+func TestClientCertificate(t *testing.T) {
+	p12 := "../helpers/clientcert/TEST_DATA/client.p12"
+	server := mtlsServer(t, p12, "secret")
+	defer server.Close()
+
+	dir := t.TempDir()
+	newApp := func(cert string) *SharedFlags {
+		return &SharedFlags{
+			Server:            server.URL,
+			Key:               "KEY",
+			SkipSSL:           true,
+			ClientCert:        cert,
+			ConfigurationFile: filepath.Join(dir, "immich-go.json"),
+			Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+	}
+
+	err := newApp(p12).Start(context.Background())
+	if err != nil {
+		t.Errorf("the connection with the client certificate should succeed: %s", err)
+	}
+	err = newApp("").Start(context.Background())
+	if err == nil {
+		t.Error("the connection without the client certificate should fail")
+	}
+
+	os.Unsetenv(PassphraseEnv)
+	err = newApp(p12).Start(context.Background())
+	if err == nil {
+		t.Error("the passphrase is needed when the input isn't a terminal")
+	}
+}
+
+// immichHandler answers to the calls of the connection
+func immichHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/server/ping":
+			_, _ = w.Write([]byte(`{"res":"pong"}`))
+		case "/api/users/me":
+			_, _ = w.Write([]byte(`{"email":"me@example.com"}`))
+		case "/api/server/version":
+			_, _ = w.Write([]byte(`{"major":1,"minor":118,"patch":2}`))
+		case "/api/server/features":
+			_, _ = w.Write([]byte(`{}`))
+		case "/api/server/media-types":
+			_, _ = w.Write([]byte(`{"image":[".jpg"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestTLSOptions(t *testing.T) {
+	server := httptest.NewUnstartedServer(immichHandler())
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	// the test server's certificate acts as a private CA, and is valid for example.com and 127.0.0.1
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "ca.pem")
+	err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		caCert     string
+		serverName string
+		minVersion string
+		skipSSL    bool
+		ok         bool
+	}{
+		{name: "unknown CA"},
+		{name: "private CA", caCert: ca, ok: true},
+		{name: "server name", caCert: ca, serverName: "example.com", ok: true},
+		{name: "wrong server name", caCert: ca, serverName: "photos.example.org"},
+		{name: "TLS 1.3", caCert: ca, minVersion: "1.3"},
+		{name: "unsupported version", caCert: ca, minVersion: "1.1"},
+		{name: "skip verify and CA", caCert: ca, skipSSL: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &SharedFlags{
+				Server:            server.URL,
+				Key:               "KEY",
+				CACert:            tt.caCert,
+				TLSServerName:     tt.serverName,
+				TLSMinVersion:     tt.minVersion,
+				SkipSSL:           tt.skipSSL,
+				ConfigurationFile: filepath.Join(dir, "immich-go.json"),
+				Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			err := app.Start(context.Background())
+			if tt.ok && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("an error is expected")
+			}
+		})
+	}
+}
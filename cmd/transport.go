@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// checkTransport checks the tuning of the connections to the server
+func (app *SharedFlags) checkTransport() error {
+	switch app.HTTPVersion {
+	case "", "1.1", "2":
+	default:
+		return fmt.Errorf("unsupported HTTP version %q, use 1.1 or 2", app.HTTPVersion)
+	}
+	if app.MaxConnsPerHost < 0 {
+		return errors.New("the option -max-conns-per-host must be positive, or 0 for no limit")
+	}
+	if app.MaxIdleConns < 0 {
+		return errors.New("the option -max-idle-conns must be positive, use -no-keep-alive to close the connections")
+	}
+	return nil
+}
+
+// configureTransport applies the tuning of the connections to the client,
+// the client's pool is kept when the options aren't initialized
+func (app *SharedFlags) configureTransport(client *immich.ImmichClient) {
+	client.SetHTTP2(app.HTTPVersion == "2")
+	if app.MaxIdleConns > 0 {
+		client.SetConnectionPool(app.MaxConnsPerHost, app.MaxIdleConns)
+	}
+	client.SetKeepAlive(!app.NoKeepAlive)
+}
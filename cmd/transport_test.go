@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransportOptions(t *testing.T) {
+	var proto atomic.Value
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto.Store(r.Proto)
+		immichHandler().ServeHTTP(w, r)
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		httpVersion string
+		noKeepAlive bool
+		maxConns    int
+		proto       string
+		newConns    bool
+		ok          bool
+	}{
+		{name: "default", proto: "HTTP/1.1", ok: true},
+		{name: "HTTP/1.1", httpVersion: "1.1", proto: "HTTP/1.1", ok: true},
+		{name: "HTTP/2", httpVersion: "2", proto: "HTTP/2.0", ok: true},
+		{name: "no keep alive", noKeepAlive: true, proto: "HTTP/1.1", newConns: true, ok: true},
+		{name: "unsupported version", httpVersion: "3"},
+		{name: "negative pool", maxConns: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proto.Store("")
+			conns.Store(0)
+			app := &SharedFlags{
+				Server:            server.URL,
+				Key:               "KEY",
+				SkipSSL:           true,
+				HTTPVersion:       tt.httpVersion,
+				MaxConnsPerHost:   tt.maxConns,
+				MaxIdleConns:      10,
+				NoKeepAlive:       tt.noKeepAlive,
+				ConfigurationFile: filepath.Join(dir, "immich-go.json"),
+				Log:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			err := app.Start(context.Background())
+			if !tt.ok {
+				if err == nil {
+					t.Error("an error is expected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if p := proto.Load(); p != tt.proto {
+				t.Errorf("expected %s, got %s", tt.proto, p)
+			}
+			// the connection is reused for the calls of the connection validation, unless the keep alive is disabled
+			if n := conns.Load(); (n > 1) != tt.newConns {
+				t.Errorf("unexpected number of connections: %d", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+)
+
+// TrashAssets moves the server's assets to the trash, and records the operation in the trash journal,
+// so the command restore can undo it.
+func (app *SharedFlags) TrashAssets(ctx context.Context, command string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if !app.Capabilities.Trash() {
+		return errors.New("the trash is disabled on the server, immich-go doesn't delete the assets permanently")
+	}
+	err := app.Immich.DeleteAssets(ctx, ids, false)
+	if err != nil {
+		return err
+	}
+	if app.TrashJournal == "" {
+		return nil
+	}
+	jnl, err := configuration.ReadTrashJournal(app.TrashJournal)
+	if err != nil {
+		return err
+	}
+	jnl = append(jnl, configuration.TrashOperation{
+		Time:    time.Now(),
+		Server:  app.ServerName(),
+		Command: command,
+		IDs:     ids,
+	})
+	return jnl.Write(app.TrashJournal)
+}
+
+// ServerName gives the address identifying the server in the journals
+func (app *SharedFlags) ServerName() string {
+	if app.Server != "" {
+		return app.Server
+	}
+	return app.API
+}
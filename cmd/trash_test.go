@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+// icDeleting counts the deletions
+type icDeleting struct {
+	fakeimmich.MockedCLient
+	calls int
+}
+
+func (c *icDeleting) DeleteAssets(ctx context.Context, ids []string, force bool) error {
+	c.calls++
+	return nil
+}
+
+func TestTrashAssets(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities immich.ServerCapabilities
+		calls        int
+		wantErr      bool
+	}{
+		{name: "server not queried", calls: 1},
+		{name: "trash enabled", capabilities: immich.ServerCapabilities{Version: immich.MinServerVersion, Features: immich.ServerFeatures{Trash: true}}, calls: 1},
+		{name: "trash disabled", capabilities: immich.ServerCapabilities{Version: immich.MinServerVersion}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icDeleting{}
+			app := &SharedFlags{Immich: ic, Capabilities: tt.capabilities}
+			err := app.TrashAssets(context.Background(), "test", []string{"1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ic.calls != tt.calls {
+				t.Errorf("expected %d deletion(s), got %d", tt.calls, ic.calls)
+			}
+		})
+	}
+}
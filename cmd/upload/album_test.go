@@ -0,0 +1,93 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icCatchAlbumProperties records the description and the order of the created albums
+type icCatchAlbumProperties struct {
+	icCatchUploadsAssets
+
+	descriptions map[string]string
+	orders       map[string]string
+}
+
+func (c *icCatchAlbumProperties) CreateAlbum(ctx context.Context, album string, description string, ids []string) (immich.AlbumSimplified, error) {
+	c.descriptions[album] = description
+	return c.icCatchUploadsAssets.CreateAlbum(ctx, album, description, ids)
+}
+
+func (c *icCatchAlbumProperties) UpdateAlbum(ctx context.Context, id string, update immich.AlbumUpdate) (immich.AlbumSimplified, error) {
+	c.orders[id] = update.Order
+	return immich.AlbumSimplified{ID: id}, nil
+}
+
+func TestAlbumProperties(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"AlbumA/PXL_20231006_063000139.jpg", "AlbumB/PXL_20231006_063528961.jpg"} {
+		copyFile(t, filepath.Join("TEST_DATA/folder/high", f), filepath.Join(root, f))
+	}
+	err := os.WriteFile(filepath.Join(root, "AlbumA", "README.txt"), []byte("Summer holidays\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name                 string
+		args                 []string
+		expectedDescriptions map[string]string
+		expectedOrders       map[string]string
+	}{
+		{
+			name:                 "default",
+			args:                 []string{"-create-album-folder"},
+			expectedDescriptions: map[string]string{"AlbumA": "", "AlbumB": ""},
+			expectedOrders:       map[string]string{},
+		},
+		{
+			name:                 "description and order",
+			args:                 []string{"-create-album-folder", "-album-description-file=README.txt", "-album-order=DESC"},
+			expectedDescriptions: map[string]string{"AlbumA": "Summer holidays", "AlbumB": ""},
+			expectedOrders:       map[string]string{"AlbumA": "desc", "AlbumB": "desc"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ic := &icCatchAlbumProperties{
+				icCatchUploadsAssets: icCatchUploadsAssets{
+					albums: map[string][]string{},
+				},
+				descriptions: map[string]string{},
+				orders:       map[string]string{},
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: ic,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			err := UploadCommand(context.Background(), &serv, append(append([]string{"-no-ui"}, tc.args...), root))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !cmpMaps(tc.expectedDescriptions, ic.descriptions) {
+				t.Errorf("unexpected descriptions")
+				pretty.Ldiff(t, tc.expectedDescriptions, ic.descriptions)
+			}
+			if !cmpMaps(tc.expectedOrders, ic.orders) {
+				t.Errorf("unexpected orders")
+				pretty.Ldiff(t, tc.expectedOrders, ic.orders)
+			}
+		})
+	}
+}
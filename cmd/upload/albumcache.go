@@ -0,0 +1,142 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+)
+
+// albumCache keeps the albums of the server by title. The list is read once at the start of the run,
+// and kept up to date with the albums created by the upload.
+//
+// The creation of an album is done once even when several goroutines need it at the same time:
+// the others wait for the album's ID.
+type albumCache struct {
+	lock     sync.Mutex
+	albums   map[string]immich.AlbumSimplified
+	creating map[string]*albumCreation // creations in progress by title
+}
+
+// albumCreation is the outcome of a creation, given when done is closed
+type albumCreation struct {
+	done  chan struct{}
+	album immich.AlbumSimplified
+	err   error
+}
+
+func newAlbumCache(albums []immich.AlbumSimplified) *albumCache {
+	c := &albumCache{
+		albums:   map[string]immich.AlbumSimplified{},
+		creating: map[string]*albumCreation{},
+	}
+	for _, a := range albums {
+		// the first album wins when the server has several albums with the same title
+		if _, exist := c.albums[a.AlbumName]; !exist {
+			c.albums[a.AlbumName] = a
+		}
+	}
+	return c
+}
+
+// get gives the server's album with this title
+func (c *albumCache) get(title string) (immich.AlbumSimplified, bool) {
+	if c == nil {
+		return immich.AlbumSimplified{}, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	a, ok := c.albums[title]
+	return a, ok
+}
+
+// ensure gives the album with this title, it is created with the create function when missing.
+// It tells if the album has been created by this call.
+func (c *albumCache) ensure(ctx context.Context, title string, create func(ctx context.Context) (immich.AlbumSimplified, error)) (immich.AlbumSimplified, bool, error) {
+	c.lock.Lock()
+	if a, ok := c.albums[title]; ok {
+		c.lock.Unlock()
+		return a, false, nil
+	}
+	if cr, ok := c.creating[title]; ok {
+		c.lock.Unlock()
+		select {
+		case <-ctx.Done():
+			return immich.AlbumSimplified{}, false, ctx.Err()
+		case <-cr.done:
+			return cr.album, false, cr.err
+		}
+	}
+	cr := &albumCreation{done: make(chan struct{})}
+	c.creating[title] = cr
+	c.lock.Unlock()
+
+	cr.album, cr.err = create(ctx)
+
+	c.lock.Lock()
+	delete(c.creating, title)
+	if cr.err == nil {
+		c.albums[title] = cr.album
+	}
+	c.lock.Unlock()
+	close(cr.done)
+	return cr.album, cr.err == nil, cr.err
+}
+
+// missing gives the titles not found in the cache, sorted
+func (c *albumCache) missing(titles map[string]browser.LocalAlbum) []string {
+	l := []string{}
+	for t := range titles {
+		if _, ok := c.get(t); !ok {
+			l = append(l, t)
+		}
+	}
+	sort.Strings(l)
+	return l
+}
+
+// createMissingAlbums creates at once the albums of the assets that aren't on the server yet,
+// so the assets are added to them by batches during the upload.
+// The album list is read again before, in case another client has created some of them since the start of the run.
+func (app *UpCmd) createMissingAlbums(ctx context.Context, assets []*browser.LocalAssetFile, keep func(a *browser.LocalAssetFile) bool) error {
+	if app.DryRun {
+		return nil
+	}
+	albums := map[string]browser.LocalAlbum{}
+	for _, a := range assets {
+		if a.Err != nil || !keep(a) {
+			continue
+		}
+		targets, _ := app.albumTargets(a)
+		for _, t := range targets {
+			if _, ok := albums[t.album.Title]; !ok {
+				albums[t.album.Title] = t.album
+			}
+		}
+	}
+	if len(albums) == 0 || len(app.albums.missing(albums)) == 0 {
+		return nil
+	}
+	app.albums = nil
+	err := app.getImmichAlbums(ctx)
+	if err != nil {
+		return err
+	}
+	for _, title := range app.albums.missing(albums) {
+		album := albums[title]
+		_, _, err := app.albums.ensure(ctx, title, func(ctx context.Context) (immich.AlbumSimplified, error) {
+			return app.createAlbum(ctx, nil, album)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// the album is created again with its first asset
+			app.Log.Error(fmt.Sprintf("can't create the album %q: %s", title, err))
+		}
+	}
+	return nil
+}
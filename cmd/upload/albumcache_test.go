@@ -0,0 +1,130 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestAlbumCacheEnsure(t *testing.T) {
+	c := newAlbumCache([]immich.AlbumSimplified{{ID: "1", AlbumName: "existing"}, {ID: "2", AlbumName: "existing"}})
+	ctx := context.Background()
+
+	if a, ok := c.get("existing"); !ok || a.ID != "1" {
+		t.Errorf("expected the first album of the server, got %v %v", a, ok)
+	}
+
+	// the concurrent uploads needing the same album create it once
+	var creations atomic.Int32
+	create := func(ctx context.Context) (immich.AlbumSimplified, error) {
+		creations.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return immich.AlbumSimplified{ID: "new-id", AlbumName: "new"}, nil
+	}
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a, ok, err := c.ensure(ctx, "new", create)
+			if err != nil || a.ID != "new-id" {
+				t.Errorf("ensure() = %v, %v", a, err)
+			}
+			if ok {
+				created.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if creations.Load() != 1 || created.Load() != 1 {
+		t.Errorf("expected one creation, got %d calls and %d creations", creations.Load(), created.Load())
+	}
+
+	// a failed creation is tried again
+	_, _, err := c.ensure(ctx, "failing", func(ctx context.Context) (immich.AlbumSimplified, error) {
+		return immich.AlbumSimplified{}, errors.New("server error")
+	})
+	if err == nil {
+		t.Error("expected an error")
+	}
+	_, ok, err := c.ensure(ctx, "failing", func(ctx context.Context) (immich.AlbumSimplified, error) {
+		return immich.AlbumSimplified{ID: "3", AlbumName: "failing"}, nil
+	})
+	if err != nil || !ok {
+		t.Errorf("expected the album created at the second attempt, got %v %v", ok, err)
+	}
+}
+
+// icAlbumsCreatedElsewhere gives the albums created by an other client after the start of the run
+type icAlbumsCreatedElsewhere struct {
+	icCountAlbumCalls
+	lists       int
+	createdLate []immich.AlbumSimplified
+	emptyAlbums []string
+}
+
+func (c *icAlbumsCreatedElsewhere) GetAllAlbums(context.Context) ([]immich.AlbumSimplified, error) {
+	c.lists++
+	if c.lists > 1 {
+		return c.createdLate, nil
+	}
+	return nil, nil
+}
+
+func (c *icAlbumsCreatedElsewhere) CreateAlbum(ctx context.Context, album string, description string, ids []string) (immich.AlbumSimplified, error) {
+	if len(ids) == 0 {
+		c.emptyAlbums = append(c.emptyAlbums, album)
+	}
+	return c.icCountAlbumCalls.CreateAlbum(ctx, album, description, ids)
+}
+
+func TestCreateMissingAlbums(t *testing.T) {
+	ic := &icAlbumsCreatedElsewhere{
+		icCountAlbumCalls: icCountAlbumCalls{icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}}},
+		createdLate:       []immich.AlbumSimplified{{ID: "AlbumA", AlbumName: "AlbumA"}},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	ctx := context.Background()
+	app, err := newCommand(ctx, &serv, []string{"-no-ui", "-create-album-folder", "TEST_DATA/folder/high"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	app.confirm, app.stdin, app.stdout = true, strings.NewReader("y\n"), &out
+	err = app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the list is read for the plan, and again before creating the albums; AlbumA has been created by an other client meanwhile
+	if ic.lists != 2 {
+		t.Errorf("expected the album list read twice, got %d", ic.lists)
+	}
+	if len(ic.emptyAlbums) != 1 || ic.emptyAlbums[0] != "AlbumB" || ic.creates != 1 {
+		t.Errorf("expected only AlbumB created before the upload, got %v and %d creations", ic.emptyAlbums, ic.creates)
+	}
+	// both albums are populated by batches
+	if ic.adds != 2 {
+		t.Errorf("expected 2 calls adding the assets to the albums, got %d", ic.adds)
+	}
+	if n := len(ic.albums["AlbumA"]) + len(ic.albums["AlbumB"]); n != 8 {
+		t.Errorf("expected 8 assets in the albums, got %d", n)
+	}
+}
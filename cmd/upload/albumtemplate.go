@@ -0,0 +1,75 @@
+package upload
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// AlbumTemplateData gives the values available in the -album-template option
+type AlbumTemplateData struct {
+	Year, Month, Day string    // Date of capture, empty when unknown
+	Date             time.Time // Date of capture
+	Folder           string    // Name of the asset's folder, or the name of the source when the asset is at its root
+	Path             string    // Path of the asset's folder within the source
+	Folders          []string  // Components of the folder's path
+	RootName         string    // Name of the source: folder or archive
+	FileName         string    // Asset's file name
+}
+
+// PathJoin joins the components of the folder's path with the separator
+func (d AlbumTemplateData) PathJoin(sep string) string {
+	return strings.Join(d.Folders, sep)
+}
+
+func parseAlbumTemplate(s string) (*template.Template, error) {
+	t, err := template.New("album").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -album-template: %w", err)
+	}
+	// check the template against the available values
+	err = t.Execute(&strings.Builder{}, AlbumTemplateData{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid -album-template: %w", err)
+	}
+	return t, nil
+}
+
+func newAlbumTemplateData(a *browser.LocalAssetFile) AlbumTemplateData {
+	d := AlbumTemplateData{
+		Date:     a.Metadata.DateTaken,
+		FileName: path.Base(a.FileName),
+	}
+	if fsys, ok := a.FSys.(fshelper.NameFS); ok {
+		d.RootName = fsys.Name()
+	}
+	if !d.Date.IsZero() {
+		d.Year = d.Date.Format("2006")
+		d.Month = d.Date.Format("01")
+		d.Day = d.Date.Format("02")
+	}
+	dir := path.Dir(a.FileName)
+	if dir != "." {
+		d.Path = dir
+		d.Folders = strings.Split(dir, "/")
+		d.Folder = path.Base(dir)
+	} else {
+		d.Folder = d.RootName
+	}
+	return d
+}
+
+// albumFromTemplate gives the album name of the asset, evaluated with the -album-template option
+func (app *UpCmd) albumFromTemplate(a *browser.LocalAssetFile) (string, error) {
+	b := strings.Builder{}
+	err := app.albumTemplate.Execute(&b, newAlbumTemplateData(a))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,95 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+func TestAlbumTemplate(t *testing.T) {
+	fsys := fshelper.NewFSWithName(fstest.MapFS{}, "photos")
+	date := time.Date(2023, 10, 6, 6, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		template    string
+		file        string
+		date        time.Time
+		expected    string
+		expectedErr bool
+	}{
+		{template: "{{.Year}}/{{.Folder}}", file: "holidays/beach/IMG_001.jpg", date: date, expected: "2023/beach"},
+		{template: "{{.Year}}-{{.Month}}-{{.Day}}", file: "IMG_001.jpg", date: date, expected: "2023-10-06"},
+		{template: `{{.RootName}} – {{.PathJoin " > "}}`, file: "holidays/beach/IMG_001.jpg", date: date, expected: "photos – holidays > beach"},
+		{template: "{{.Folder}}", file: "IMG_001.jpg", date: date, expected: "photos"},
+		{template: `{{if .Year}}{{.Year}}{{else}}Unknown date{{end}}`, file: "IMG_001.jpg", expected: "Unknown date"},
+		{template: `{{.Date.Format "Jan 2006"}}`, file: "IMG_001.jpg", date: date, expected: "Oct 2023"},
+		{template: "{{.Path}}", file: "IMG_001.jpg", date: date, expected: ""},
+		{template: "{{.Unknown}}", expectedErr: true},
+		{template: "{{.Year", expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			tmpl, err := parseAlbumTemplate(tc.template)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			app := &UpCmd{albumTemplate: tmpl}
+			a := &browser.LocalAssetFile{FSys: fsys, FileName: tc.file}
+			a.Metadata.DateTaken = tc.date
+			album, err := app.albumFromTemplate(a)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if album != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, album)
+			}
+		})
+	}
+}
+
+func TestUploadAlbumTemplate(t *testing.T) {
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-album-template={{.Year}} {{.Folder}}", "TEST_DATA/folder/high"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string][]string{
+		"2023 AlbumA": {
+			"AlbumA/PXL_20231006_063000139.jpg",
+			"AlbumA/PXL_20231006_063029647.jpg",
+			"AlbumA/PXL_20231006_063108407.jpg",
+			"AlbumA/PXL_20231006_063121958.jpg",
+			"AlbumA/PXL_20231006_063357420.jpg",
+		},
+		"2023 AlbumB": {
+			"AlbumB/PXL_20231006_063528961.jpg",
+			"AlbumB/PXL_20231006_063536303.jpg",
+			"AlbumB/PXL_20231006_063851485.jpg",
+		},
+	}
+	if !cmpAlbums(expected, ic.albums) {
+		t.Errorf("unexpected albums")
+		pretty.Ldiff(t, expected, ic.albums)
+	}
+}
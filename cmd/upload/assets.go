@@ -51,11 +51,29 @@ func (ai *AssetIndex) AddLocalAsset(la *browser.LocalAssetFile, immichID string)
 			Latitude:         la.Metadata.Latitude,
 			Longitude:        la.Metadata.Longitude,
 		},
+		Checksum:     la.Checksum,
 		JustUploaded: true,
 	}
 	ai.assets = append(ai.assets, sa)
 	ai.byID[sa.DeviceAssetID] = sa
+	if sa.Checksum != "" {
+		ai.byHash[sa.Checksum] = append(ai.byHash[sa.Checksum], sa)
+	}
 	l := ai.byName[sa.OriginalFileName]
 	l = append(l, sa)
 	ai.byName[sa.OriginalFileName] = l
 }
+
+// AddServerChecksum makes sure the server's asset is found by its checksum,
+// even when it wasn't listed at the beginning of the upload
+func (ai *AssetIndex) AddServerChecksum(immichID string, checksum string) {
+	if len(ai.byHash[checksum]) > 0 {
+		return
+	}
+	sa := &immich.Asset{
+		ID:       immichID,
+		Checksum: checksum,
+	}
+	ai.assets = append(ai.assets, sa)
+	ai.byHash[checksum] = append(ai.byHash[checksum], sa)
+}
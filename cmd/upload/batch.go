@@ -0,0 +1,177 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+// albumBatchSize is the number of assets waiting for an album that triggers the call adding them
+const albumBatchSize = immich.MaxAssetsPerCall
+
+// albumBatch collects the assets to add to the albums, so each album is populated with few calls
+type albumBatch struct {
+	titles  []string                 // albums in the order of their first asset
+	pending map[string]*pendingAlbum // pending assets by album title
+
+	favorites []string // server's assets to mark as favorite
+	archives  []string // server's assets to archive
+}
+
+// pendingAlbum gives the assets waiting to be added to the album
+type pendingAlbum struct {
+	album  browser.LocalAlbum
+	ids    []string
+	assets []*browser.LocalAssetFile
+	queued map[string]bool // assets queued for the album
+}
+
+func newAlbumBatch() *albumBatch {
+	return &albumBatch{pending: map[string]*pendingAlbum{}}
+}
+
+// queueAlbumAsset puts the asset in the album's batch, the batch is sent when full.
+// A missing album is created at once with the asset, so its ID is known. It tells if the album exists.
+func (app *UpCmd) queueAlbumAsset(ctx context.Context, id string, a *browser.LocalAssetFile, album browser.LocalAlbum) bool {
+	if app.albums == nil {
+		app.albums = newAlbumCache(nil)
+	}
+	_, created, err := app.albums.ensure(ctx, album.Title, func(ctx context.Context) (immich.AlbumSimplified, error) {
+		return app.createAlbum(ctx, []string{id}, album)
+	})
+	if err != nil {
+		app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+		app.Jnl.RecordAlbum(album.Title, fileevent.AlbumFailed)
+		return false
+	}
+	if created {
+		app.Jnl.RecordAlbum(album.Title, fileevent.AlbumAdded)
+		return true
+	}
+	if app.batch == nil {
+		app.batch = newAlbumBatch()
+	}
+	p, ok := app.batch.pending[album.Title]
+	if !ok {
+		p = &pendingAlbum{album: album, queued: map[string]bool{}}
+		app.batch.pending[album.Title] = p
+		app.batch.titles = append(app.batch.titles, album.Title)
+	}
+	if p.queued[id] {
+		// a local duplicate of an asset already queued
+		app.Jnl.RecordAlbum(album.Title, fileevent.AlbumPresent)
+		return true
+	}
+	p.queued[id] = true
+	p.ids = append(p.ids, id)
+	p.assets = append(p.assets, a)
+	if len(p.ids) >= albumBatchSize {
+		app.flushAlbum(ctx, p)
+	}
+	return true
+}
+
+// createAlbum creates the album with the given assets, possibly none
+func (app *UpCmd) createAlbum(ctx context.Context, ids []string, album browser.LocalAlbum) (immich.AlbumSimplified, error) {
+	a, err := app.Immich.CreateAlbum(ctx, album.Title, album.Description, ids)
+	if err != nil {
+		return immich.AlbumSimplified{}, err
+	}
+	al := immich.AlbumSimplified{ID: a.ID, AlbumName: a.AlbumName, Description: a.Description}
+	if app.ShareLink {
+		app.shareAlbum(ctx, al)
+	}
+	order := album.Order
+	if order == "" {
+		order = app.AlbumOrder
+	}
+	if order != "" {
+		// the album exists, it is kept even when its order can't be set
+		_, err = app.Immich.UpdateAlbum(ctx, a.ID, immich.AlbumUpdate{Order: order})
+		if err != nil {
+			app.Log.Error(fmt.Sprintf("can't set the order of the album %q: %s", album.Title, err))
+		}
+	}
+	return al, nil
+}
+
+// queueAssetFlags marks the server's asset as favorite or archived like the local one
+func (app *UpCmd) queueAssetFlags(a *browser.LocalAssetFile, serverAsset *immich.Asset) {
+	if app.DryRun || serverAsset == nil {
+		return
+	}
+	if app.batch == nil {
+		app.batch = newAlbumBatch()
+	}
+	if a.Favorite && !serverAsset.IsFavorite {
+		app.batch.favorites = append(app.batch.favorites, serverAsset.ID)
+	}
+	if app.AutoArchive && a.Archived && !serverAsset.IsArchived {
+		app.batch.archives = append(app.batch.archives, serverAsset.ID)
+	}
+}
+
+// flushBatch sends the pending album additions and asset updates
+func (app *UpCmd) flushBatch(ctx context.Context) {
+	if app.batch == nil {
+		return
+	}
+	for _, title := range app.batch.titles {
+		if p := app.batch.pending[title]; len(p.ids) > 0 {
+			app.flushAlbum(ctx, p)
+		}
+	}
+	yes := true
+	if len(app.batch.favorites) > 0 {
+		app.Log.Info(fmt.Sprintf("Marking %d server's asset(s) as favorite", len(app.batch.favorites)))
+		err := app.Immich.BatchUpdateAssets(ctx, app.batch.favorites, immich.AssetsUpdate{IsFavorite: &yes})
+		if err != nil {
+			app.Log.Error(fmt.Sprintf("can't mark the assets as favorite: %s", err))
+		}
+	}
+	if len(app.batch.archives) > 0 {
+		app.Log.Info(fmt.Sprintf("Archiving %d server's asset(s)", len(app.batch.archives)))
+		err := app.Immich.BatchUpdateAssets(ctx, app.batch.archives, immich.AssetsUpdate{IsArchived: &yes})
+		if err != nil {
+			app.Log.Error(fmt.Sprintf("can't archive the assets: %s", err))
+		}
+	}
+	app.batch = nil
+}
+
+// flushAlbum adds the pending assets to the album, and records the outcome of each asset
+func (app *UpCmd) flushAlbum(ctx context.Context, p *pendingAlbum) {
+	ids, assets := p.ids, p.assets
+	p.ids, p.assets = nil, nil
+	title := p.album.Title
+
+	al, _ := app.albums.get(title)
+	r, err := app.Immich.AddAssetToAlbum(ctx, al.ID, ids)
+	if err != nil {
+		for _, a := range assets {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+			app.Jnl.RecordAlbum(title, fileevent.AlbumFailed)
+		}
+		return
+	}
+	results := map[string]immich.UpdateAlbumResult{}
+	for _, res := range r {
+		results[res.ID] = res
+	}
+	for i, id := range ids {
+		res, ok := results[id]
+		switch {
+		case !ok || res.Success:
+			app.Jnl.RecordAlbum(title, fileevent.AlbumAdded)
+		case res.Error == immich.ErrorDuplicate:
+			app.Jnl.RecordAlbum(title, fileevent.AlbumPresent)
+		default:
+			a := assets[i]
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", fmt.Sprintf("can't add the asset to the album %q: %s", title, res.Error))
+			app.Jnl.RecordAlbum(title, fileevent.AlbumFailed)
+		}
+	}
+}
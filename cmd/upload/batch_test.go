@@ -0,0 +1,82 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+type icCountAlbumCalls struct {
+	icCatchUploadsAssets
+	creates, adds int
+	updates       map[string][]string
+}
+
+func (c *icCountAlbumCalls) CreateAlbum(ctx context.Context, album string, description string, ids []string) (immich.AlbumSimplified, error) {
+	c.creates++
+	return c.icCatchUploadsAssets.CreateAlbum(ctx, album, description, ids)
+}
+
+func (c *icCountAlbumCalls) AddAssetToAlbum(ctx context.Context, album string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	c.adds++
+	return c.icCatchUploadsAssets.AddAssetToAlbum(ctx, album, ids)
+}
+
+func (c *icCountAlbumCalls) BatchUpdateAssets(ctx context.Context, ids []string, update immich.AssetsUpdate) error {
+	switch {
+	case update.IsFavorite != nil:
+		c.updates["favorite"] = append(c.updates["favorite"], ids...)
+	case update.IsArchived != nil:
+		c.updates["archived"] = append(c.updates["archived"], ids...)
+	}
+	return nil
+}
+
+func TestAlbumBatch(t *testing.T) {
+	ic := &icCountAlbumCalls{icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-album=the album", "TEST_DATA/folder/high"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ic.creates != 1 || ic.adds != 1 {
+		t.Errorf("expected the album created with one call and populated with one call, got %d and %d calls", ic.creates, ic.adds)
+	}
+	if n := len(ic.albums["the album"]); n != 8 {
+		t.Errorf("expected 8 assets in the album, got %d", n)
+	}
+	if s := serv.Jnl.Albums()["the album"]; s.Added != 8 {
+		t.Errorf("expected 8 assets added to the album, got %+v", s)
+	}
+}
+
+func TestAssetFlagsBatch(t *testing.T) {
+	ic := &icCountAlbumCalls{updates: map[string][]string{}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &UpCmd{
+		SharedFlags: &cmd.SharedFlags{Immich: ic, Log: log, Jnl: fileevent.NewRecorder(log, false)},
+		AutoArchive: true,
+	}
+	app.queueAssetFlags(&browser.LocalAssetFile{Favorite: true}, &immich.Asset{ID: "1"})
+	app.queueAssetFlags(&browser.LocalAssetFile{Favorite: true}, &immich.Asset{ID: "2", IsFavorite: true})
+	app.queueAssetFlags(&browser.LocalAssetFile{Favorite: true, Archived: true}, &immich.Asset{ID: "3"})
+	app.flushBatch(context.Background())
+
+	if got := ic.updates["favorite"]; !cmpSlices([]string{"1", "3"}, got) {
+		t.Errorf("unexpected favorites %v", got)
+	}
+	if got := ic.updates["archived"]; !cmpSlices([]string{"3"}, got) {
+		t.Errorf("unexpected archived assets %v", got)
+	}
+}
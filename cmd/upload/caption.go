@@ -0,0 +1,37 @@
+package upload
+
+import (
+	"context"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// captionAsset gives the asset the caption found in its XMP sidecar or in its EXIF data
+// when it hasn't a description yet.
+func (app *UpCmd) captionAsset(ctx context.Context, a *browser.LocalAssetFile) {
+	if a.Metadata.Description != "" {
+		return
+	}
+	caption := ""
+	if a.SideCar.IsSet() {
+		f, err := a.SideCar.FSys.Open(a.SideCar.FileName)
+		if err == nil {
+			caption, err = metadata.ReadXMPDescription(f)
+			f.Close()
+		}
+		if err != nil {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.SideCar.FileName, "error", err.Error())
+		}
+	}
+	if caption == "" {
+		if md, ok := app.readFileMetadata(a); ok {
+			caption = md.Description
+		}
+	}
+	if caption != "" {
+		a.Metadata.Description = caption
+		app.Jnl.Record(ctx, fileevent.INFO, a, a.FileName, "caption", caption)
+	}
+}
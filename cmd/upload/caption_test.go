@@ -0,0 +1,73 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+type icCatchDescriptions struct {
+	icCatchMetadata
+	descriptions map[string]string
+}
+
+func (c *icCatchDescriptions) UpdateAsset(ctx context.Context, id string, a *browser.LocalAssetFile) (*immich.Asset, error) {
+	c.descriptions[a.FileName] = a.Metadata.Description
+	return &immich.Asset{ID: id}, nil
+}
+
+func TestImportCaptions(t *testing.T) {
+	const sidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <dc:description><rdf:Alt><rdf:li xml:lang="x-default">The Eiffel tower</rdf:li></rdf:Alt></dc:description>
+ </rdf:Description></rdf:RDF></x:xmpmeta>`
+
+	for _, enabled := range []bool{true, false} {
+		dir := t.TempDir()
+		copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063000139.jpg", filepath.Join(dir, "PXL_20231006_063000139.jpg"))
+		copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063029647.jpg", filepath.Join(dir, "PXL_20231006_063029647.jpg"))
+		err := os.WriteFile(filepath.Join(dir, "PXL_20231006_063000139.jpg.xmp"), []byte(sidecar), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ic := &icCatchDescriptions{
+			icCatchMetadata: icCatchMetadata{
+				icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+				metadata:             map[string]metadata.Metadata{},
+			},
+			descriptions: map[string]string{},
+		}
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+		serv := cmd.SharedFlags{
+			Immich: ic,
+			Jnl:    fileevent.NewRecorder(log, false),
+			Log:    log,
+		}
+		args := []string{"-no-ui"}
+		if enabled {
+			args = append(args, "-import-captions")
+		}
+		err = UploadCommand(context.Background(), &serv, append(args, dir))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		expected := map[string]string{}
+		if enabled {
+			expected["PXL_20231006_063000139.jpg"] = "The Eiffel tower"
+		}
+		if len(ic.descriptions) != len(expected) || ic.descriptions["PXL_20231006_063000139.jpg"] != expected["PXL_20231006_063000139.jpg"] {
+			t.Errorf("-import-captions=%v: expected descriptions %v, got %v", enabled, expected, ic.descriptions)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/immich"
+)
+
+// Cover selections accepted by the -album-cover option. Any other value is a file name pattern.
+const (
+	CoverNone   = ""       // the server chooses the cover
+	CoverFirst  = "first"  // the first asset added to the album
+	CoverNewest = "newest" // the asset with the most recent date of capture
+	CoverRandom = "random" // any asset added to the album
+)
+
+// coverSelector chooses the cover of the albums among the assets added during the upload
+type coverSelector struct {
+	policy  string
+	pattern namematcher.PathList       // file name pattern
+	covers  map[string]*coverCandidate // by album title
+}
+
+type coverCandidate struct {
+	assetID string
+	date    time.Time
+	seen    int // number of assets added to the album, for the random selection
+}
+
+func newCoverSelector(policy string) (*coverSelector, error) {
+	cs := &coverSelector{
+		covers: map[string]*coverCandidate{},
+	}
+	switch strings.ToLower(policy) {
+	case CoverNone, CoverFirst, CoverNewest, CoverRandom:
+		cs.policy = strings.ToLower(policy)
+	default:
+		pattern := policy
+		if !strings.Contains(pattern, "/") && !strings.HasPrefix(pattern, "re:") && !strings.HasPrefix(pattern, "^") {
+			// a file name in any folder
+			pattern = "**/" + pattern
+		}
+		l, err := namematcher.NewPathList(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("the -album-cover accepts %s, %s, %s or a file name pattern: %w", CoverFirst, CoverNewest, CoverRandom, err)
+		}
+		cs.policy = policy
+		cs.pattern = l
+	}
+	return cs, nil
+}
+
+// candidate considers the asset as the cover of the albums it has been added to
+func (cs *coverSelector) candidate(a *browser.LocalAssetFile, assetID string, albums []string) {
+	if cs == nil || cs.policy == CoverNone || assetID == "" {
+		return
+	}
+	if cs.pattern.IsSet() {
+		p := a.FileName
+		if fsys, ok := a.FSys.(fshelper.NameFS); ok {
+			p = path.Join(fsys.Name(), a.FileName)
+		}
+		if !cs.pattern.Match(p) {
+			return
+		}
+	}
+	for _, album := range albums {
+		c := cs.covers[album]
+		if c == nil {
+			cs.covers[album] = &coverCandidate{assetID: assetID, date: a.Metadata.DateTaken, seen: 1}
+			continue
+		}
+		c.seen++
+		switch cs.policy {
+		case CoverNewest:
+			if a.Metadata.DateTaken.After(c.date) {
+				c.assetID, c.date = assetID, a.Metadata.DateTaken
+			}
+		case CoverRandom:
+			// each asset has the same chance to be the cover
+			if rand.IntN(c.seen) == 0 {
+				c.assetID, c.date = assetID, a.Metadata.DateTaken
+			}
+		}
+	}
+}
+
+// setAlbumCovers updates the thumbnail of the albums
+func (app *UpCmd) setAlbumCovers(ctx context.Context) {
+	if app.covers == nil || len(app.covers.covers) == 0 {
+		return
+	}
+	titles := gen.MapKeys(app.covers.covers)
+	sort.Strings(titles)
+	for _, title := range titles {
+		al, ok := app.albums.get(title)
+		if !ok || al.ID == "" {
+			continue
+		}
+		c := app.covers.covers[title]
+		app.Log.Info(fmt.Sprintf("Setting the cover of the album %q", title))
+		if app.DryRun {
+			continue
+		}
+		_, err := app.Immich.UpdateAlbum(ctx, al.ID, immich.AlbumUpdate{AlbumThumbnailAssetID: c.assetID})
+		if err != nil {
+			app.Log.Error(fmt.Sprintf("can't set the cover of the album %q: %s", title, err))
+		}
+	}
+	app.covers.covers = map[string]*coverCandidate{}
+}
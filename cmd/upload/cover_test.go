@@ -0,0 +1,117 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icCatchCovers records the album covers
+type icCatchCovers struct {
+	icCatchUploadsAssets
+
+	covers map[string]string
+}
+
+func (c *icCatchCovers) UpdateAlbum(ctx context.Context, id string, update immich.AlbumUpdate) (immich.AlbumSimplified, error) {
+	c.covers[id] = update.AlbumThumbnailAssetID
+	return immich.AlbumSimplified{ID: id}, nil
+}
+
+func TestAlbumCover(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cover    string
+		expected map[string]string
+	}{
+		{
+			name:     "none",
+			cover:    "",
+			expected: map[string]string{},
+		},
+		{
+			name:  "first",
+			cover: CoverFirst,
+			expected: map[string]string{
+				"AlbumA": "AlbumA/PXL_20231006_063000139.jpg",
+				"AlbumB": "AlbumB/PXL_20231006_063528961.jpg",
+			},
+		},
+		{
+			name:  "newest",
+			cover: CoverNewest,
+			expected: map[string]string{
+				"AlbumA": "AlbumA/PXL_20231006_063357420.jpg",
+				"AlbumB": "AlbumB/PXL_20231006_063851485.jpg",
+			},
+		},
+		{
+			name:  "file name",
+			cover: "*063108407.JPG",
+			expected: map[string]string{
+				"AlbumA": "AlbumA/PXL_20231006_063108407.jpg",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ic := runCoverTest(t, tc.cover)
+			if !cmpMaps(tc.expected, ic.covers) {
+				t.Errorf("unexpected covers")
+				pretty.Ldiff(t, tc.expected, ic.covers)
+			}
+		})
+	}
+}
+
+func TestAlbumCoverRandom(t *testing.T) {
+	ic := runCoverTest(t, CoverRandom)
+	if len(ic.covers) != 2 {
+		t.Errorf("expected 2 covers, got %v", ic.covers)
+	}
+	for album, cover := range ic.covers {
+		if !strings.HasPrefix(cover, album+"/") {
+			t.Errorf("the cover %s doesn't belong to the album %s", cover, album)
+		}
+	}
+}
+
+func runCoverTest(t *testing.T, cover string) *icCatchCovers {
+	ic := &icCatchCovers{
+		icCatchUploadsAssets: icCatchUploadsAssets{
+			albums: map[string][]string{},
+		},
+		covers: map[string]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-create-album-folder", "-album-cover=" + cover, "TEST_DATA/folder/high"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return ic
+}
+
+func cmpMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
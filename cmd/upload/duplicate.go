@@ -0,0 +1,160 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/keeprules"
+)
+
+// Policies accepted by the -on-duplicate option, applied when the server has another version of an asset
+const (
+	DuplicateSkip            = "skip"              // never replace the server's asset
+	DuplicateReplaceIfLarger = "replace-if-larger" // replace the server's asset when the local file is larger
+	DuplicateReplaceIfNewer  = "replace-if-newer"  // replace the server's asset when the local file has been modified after it
+	DuplicateAlwaysAsk       = "always-ask"        // ask the user for each asset
+	DuplicateKeepRules       = "keep-rules"        // apply the -keep-rules
+)
+
+func validateOnDuplicate(policy string) (string, error) {
+	policy = strings.ToLower(policy)
+	switch policy {
+	case DuplicateSkip, DuplicateReplaceIfLarger, DuplicateReplaceIfNewer, DuplicateAlwaysAsk, DuplicateKeepRules:
+		return policy, nil
+	}
+	return "", fmt.Errorf("the -on-duplicate accepts %s, %s, %s, %s or %s", DuplicateSkip, DuplicateReplaceIfLarger, DuplicateReplaceIfNewer, DuplicateAlwaysAsk, DuplicateKeepRules)
+}
+
+// replacement describes a server's asset replaced by a local file
+type replacement struct {
+	fileName string // local file
+	serverID string // ID of the replaced server's asset
+	message  string
+}
+
+// shouldReplace applies the -on-duplicate policy when the server has a different version of the asset
+func (app *UpCmd) shouldReplace(ctx context.Context, a *browser.LocalAssetFile, advice *Advice) (bool, error) {
+	switch app.OnDuplicate {
+	case DuplicateSkip:
+		return false, nil
+	case DuplicateReplaceIfNewer:
+		s, err := fs.Stat(a.FSys, a.FileName)
+		if err != nil {
+			return false, err
+		}
+		return s.ModTime().After(advice.ServerAsset.FileModifiedAt.Time), nil
+	case DuplicateAlwaysAsk:
+		return app.askReplace(ctx, a, advice)
+	case DuplicateKeepRules:
+		return app.keepRulesReplace(ctx, a, advice), nil
+	default:
+		return advice.Advice == SmallerOnServer, nil
+	}
+}
+
+// keepRulesReplace tells if the -keep-rules prefer the local file to the server's asset.
+// The server's asset is kept when no rule decides.
+func (app *UpCmd) keepRulesReplace(ctx context.Context, a *browser.LocalAssetFile, advice *Advice) bool {
+	sa := advice.ServerAsset
+	local := keeprules.Candidate{
+		Name:   a.Title,
+		Size:   int64(a.FileSize),
+		Albums: len(a.Albums),
+	}
+	if local.Name == "" {
+		local.Name = path.Base(a.FileName)
+	}
+	uploadedAt := sa.CreatedAt.Time
+	if uploadedAt.IsZero() {
+		uploadedAt = sa.UpdatedAt.Time
+	}
+	server := keeprules.Candidate{
+		Name:       sa.OriginalFileName,
+		Size:       int64(sa.ExifInfo.FileSizeInByte),
+		Width:      sa.ExifInfo.ExifImageWidth,
+		Height:     sa.ExifInfo.ExifImageHeight,
+		UploadedAt: uploadedAt,
+		Albums:     -1,
+	}
+	if app.KeepRules.NeedAlbums() {
+		albums, err := app.Immich.GetAssetAlbums(ctx, sa.ID)
+		if err == nil {
+			server.Albums = len(albums)
+		}
+	}
+	c, rule := app.KeepRules.Compare(local, server)
+	if rule != "" {
+		app.Log.Info("keep rule applied", "file", a.FileName, "rule", rule, "replace", c > 0)
+	}
+	return c > 0
+}
+
+// askReplace asks the user if the server's asset must be replaced.
+// The answers "all" and "none" are applied to the next assets without asking again.
+// The end of the input is taken as "none".
+func (app *UpCmd) askReplace(ctx context.Context, a *browser.LocalAssetFile, advice *Advice) (bool, error) {
+	if app.askAnswer != "" {
+		return app.askAnswer == "a", nil
+	}
+	if app.askReader == nil {
+		app.askReader = bufio.NewReader(app.stdin)
+	}
+
+	app.console.Lock()
+	defer app.console.Unlock()
+
+	sa := advice.ServerAsset
+	for {
+		fmt.Fprintf(app.stdout, "\nThe server has a different version of %s\n", a.FileName)
+		fmt.Fprintf(app.stdout, "  server: %s, %s, modified %s\n", sa.OriginalFileName, formatBytes(sa.ExifInfo.FileSizeInByte), sa.FileModifiedAt.Format(time.DateTime))
+		fmt.Fprintf(app.stdout, "  local:  %s, %s\n", a.Title, formatBytes(a.FileSize))
+		fmt.Fprint(app.stdout, "Replace the server's asset? [y]es, [n]o, [a]ll, n[o]ne: ")
+
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		answer, err := app.askReader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		switch answer {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		case "a", "all":
+			app.askAnswer = "a"
+			return true, nil
+		case "o", "none":
+			app.askAnswer = "o"
+			return false, nil
+		}
+		if errors.Is(err, io.EOF) {
+			// no more answers: the server's assets are kept
+			fmt.Fprintln(app.stdout)
+			app.askAnswer = "o"
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("can't read the answer: %w", err)
+		}
+	}
+}
+
+// reportReplacements logs the list of server's assets replaced during the upload
+func (app *UpCmd) reportReplacements() {
+	if len(app.replaced) == 0 {
+		return
+	}
+	app.Log.Info(fmt.Sprintf("%d asset(s) replaced on the server:", len(app.replaced)))
+	for _, r := range app.replaced {
+		app.Log.Info(fmt.Sprintf("  %s replaced the server's asset %s: %s", r.fileName, r.serverID, r.message))
+	}
+	app.replaced = nil
+}
@@ -0,0 +1,154 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icWithAssets simulates a server having some assets
+type icWithAssets struct {
+	icCatchUploadsAssets
+
+	serverAssets []*immich.Asset
+	deleted      []string
+}
+
+func (c *icWithAssets) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.serverAssets {
+		err := fn(a)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *icWithAssets) DeleteAssets(ctx context.Context, ids []string, force bool) error {
+	c.deleted = append(c.deleted, ids...)
+	return nil
+}
+
+func TestOnDuplicate(t *testing.T) {
+	// The local file has 147869 bytes, and was modified after 2020
+	const localFile = "TEST_DATA/folder/high/AlbumA/PXL_20231006_063000139.jpg"
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Now().AddDate(1, 0, 0)
+
+	testCases := []struct {
+		name       string
+		policy     string
+		keepRules  string
+		serverName string
+		serverSize int
+		serverMod  time.Time
+		replaced   bool
+	}{
+		{name: "default, smaller on server", policy: "", serverSize: 100000, serverMod: old, replaced: true},
+		{name: "default, bigger on server", policy: "", serverSize: 200000, serverMod: old, replaced: false},
+		{name: "skip, smaller on server", policy: DuplicateSkip, serverSize: 100000, serverMod: old, replaced: false},
+		{name: "larger, smaller on server", policy: DuplicateReplaceIfLarger, serverSize: 100000, serverMod: future, replaced: true},
+		{name: "newer, bigger and older on server", policy: DuplicateReplaceIfNewer, serverSize: 200000, serverMod: old, replaced: true},
+		{name: "newer, smaller and newer on server", policy: DuplicateReplaceIfNewer, serverSize: 100000, serverMod: future, replaced: false},
+		{name: "keep rules, bigger locally", keepRules: "size", serverSize: 100000, serverMod: old, replaced: true},
+		{name: "keep rules, bigger on server", keepRules: "size", serverSize: 200000, serverMod: old, replaced: false},
+		{name: "keep rules, pattern", keepRules: "name:*.dng,earlier", serverSize: 200000, serverMod: old, replaced: false},
+		{name: "keep rules, explicit policy", policy: DuplicateSkip, keepRules: "size", serverSize: 100000, serverMod: old, replaced: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.serverName == "" {
+				tc.serverName = "PXL_20231006_063000139.jpg"
+			}
+			ic := &icWithAssets{
+				icCatchUploadsAssets: icCatchUploadsAssets{
+					albums: map[string][]string{},
+				},
+				serverAssets: []*immich.Asset{
+					{
+						ID:               "server-asset",
+						OriginalFileName: tc.serverName,
+						FileModifiedAt:   immich.ImmichTime{Time: tc.serverMod},
+						ExifInfo: immich.ExifInfo{
+							FileSizeInByte:   tc.serverSize,
+							DateTimeOriginal: immich.ImmichTime{Time: time.Date(2023, 10, 6, 6, 30, 0, 0, time.UTC)},
+						},
+					},
+				},
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: ic,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			args := []string{"-no-ui"}
+			if tc.policy != "" {
+				args = append(args, "-on-duplicate="+tc.policy)
+			}
+			if tc.keepRules != "" {
+				args = append(args, "-keep-rules="+tc.keepRules)
+			}
+			err := UploadCommand(context.Background(), &serv, append(args, localFile))
+			if err != nil && cmd.ExitCode(err) != cmd.ExitServerDuplicates {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if tc.replaced {
+				if len(ic.assets) != 1 || len(ic.deleted) != 1 || ic.deleted[0] != "server-asset" {
+					t.Errorf("the server's asset should be replaced: uploaded %v, deleted %v", ic.assets, ic.deleted)
+				}
+			} else {
+				if len(ic.assets) != 0 || len(ic.deleted) != 0 {
+					t.Errorf("the server's asset should be kept: uploaded %v, deleted %v", ic.assets, ic.deleted)
+				}
+			}
+		})
+	}
+}
+
+func TestAskReplace(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []bool
+	}{
+		{name: "yes then no", input: "y\nn\n", expected: []bool{true, false}},
+		{name: "wrong answer repeated", input: "maybe\nyes\n", expected: []bool{true}},
+		{name: "all", input: "a\n", expected: []bool{true, true, true}},
+		{name: "none", input: "o\n", expected: []bool{false, false, false}},
+		{name: "end of input", input: "", expected: []bool{false, false}},
+		{name: "yes then end of input", input: "y\nmaybe", expected: []bool{true, false, false}},
+	}
+	advice := &Advice{
+		Advice:      SmallerOnServer,
+		ServerAsset: &immich.Asset{ID: "server-asset", OriginalFileName: "photo.jpg"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := &UpCmd{
+				OnDuplicate: DuplicateAlwaysAsk,
+				stdin:       strings.NewReader(tc.input),
+				stdout:      io.Discard,
+			}
+			for i, e := range tc.expected {
+				r, err := app.shouldReplace(context.Background(), &browser.LocalAssetFile{FileName: "photo.jpg", Title: "photo.jpg"}, advice)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if r != e {
+					t.Errorf("answer %d: expected %v, got %v", i, e, r)
+				}
+			}
+		})
+	}
+}
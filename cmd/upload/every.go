@@ -0,0 +1,100 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/lockfile"
+)
+
+// runLocked runs the upload while holding the lock file, when one is given
+func (app *UpCmd) runLocked(ctx context.Context) error {
+	if app.LockFile != "" {
+		lock, err := lockfile.Acquire(app.LockFile)
+		if err != nil {
+			return fmt.Errorf("can't start the upload: %w", err)
+		}
+		defer func() {
+			_ = lock.Release()
+		}()
+	}
+	return app.run(ctx)
+}
+
+// runEvery rescans the sources periodically and uploads the new assets.
+// A run is skipped when an other process holds the lock file.
+func (app *UpCmd) runEvery(ctx context.Context) error {
+	for {
+		start := time.Now()
+		err := app.runScheduled(ctx)
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if err != nil {
+			app.Log.Error(err.Error())
+		}
+
+		next := start.Add(app.Every)
+		msg := fmt.Sprintf("Next run at %s", next.Format(time.DateTime))
+		app.Log.Info(msg)
+		if !app.Quiet {
+			fmt.Println(msg)
+		}
+		wait := app.waitNextRun
+		if wait == nil {
+			wait = waitUntil
+		}
+		err = wait(ctx, next)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// waitUntil waits for the given time, or for the end of the context
+func waitUntil(ctx context.Context, next time.Time) error {
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-time.After(time.Until(next)):
+		return nil
+	}
+}
+
+func (app *UpCmd) runScheduled(ctx context.Context) error {
+	var err error
+	lock, err := lockfile.Acquire(app.LockFile)
+	if err != nil {
+		if errors.Is(err, lockfile.ErrLocked) {
+			app.Log.Warn(fmt.Sprintf("Run skipped: %s", err))
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		_ = lock.Release()
+	}()
+
+	if app.fsyss == nil {
+		app.fsyss, err = app.openFS()
+		if err != nil {
+			return err
+		}
+	}
+	// The file system is closed at the end of the run, and reopened at the next one
+	defer func() {
+		app.fsyss = nil
+	}()
+	if len(app.fsyss) == 0 {
+		return nil
+	}
+
+	// Each run has its own counters
+	app.Jnl = fileevent.NewRecorder(app.Log, app.DebugCounters)
+	app.configureRecorder()
+	app.Log.Info(fmt.Sprintf("Scheduled run started at %s", time.Now().Format(time.DateTime)))
+	return app.run(ctx)
+}
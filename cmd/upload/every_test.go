@@ -0,0 +1,89 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/lockfile"
+)
+
+// errStopRuns ends the scheduled runs of the tests
+var errStopRuns = errors.New("stop the runs")
+
+// runEveryTimes runs the scheduled upload the given number of times, without waiting between the runs
+func runEveryTimes(t *testing.T, args []string, runs int) *icCatchUploadsAssets {
+	t.Helper()
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	ctx := context.Background()
+	app, err := newCommand(ctx, &serv, append([]string{"-no-ui", "-every=1h"}, args...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	app.waitNextRun = func(ctx context.Context, next time.Time) error {
+		n++
+		if n == runs {
+			return errStopRuns
+		}
+		return nil
+	}
+	err = app.runEvery(ctx)
+	if !errors.Is(err, errStopRuns) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	return ic
+}
+
+func TestEvery(t *testing.T) {
+	lock := filepath.Join(t.TempDir(), "immich-go.lock")
+	ic := runEveryTimes(t, []string{"-lock-file=" + lock, "TEST_DATA/folder/low/PXL_20231006_063000139.jpg"}, 2)
+
+	// The stub server doesn't remember uploaded assets: the file is uploaded at each run
+	if len(ic.assets) != 2 {
+		t.Errorf("expecting 2 runs, got %d uploads", len(ic.assets))
+	}
+	if _, err := os.Stat(lock); err == nil {
+		t.Errorf("the lock file should be removed after the run")
+	}
+}
+
+func TestEveryLocked(t *testing.T) {
+	lock := filepath.Join(t.TempDir(), "immich-go.lock")
+	// the lock is held by an other run
+	l, err := lockfile.Acquire(lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Release() }()
+	ic := runEveryTimes(t, []string{"-lock-file=" + lock, "TEST_DATA/folder/low/PXL_20231006_063000139.jpg"}, 3)
+	if len(ic.assets) != 0 {
+		t.Errorf("runs should be skipped when the lock is held, got %d uploads", len(ic.assets))
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := waitUntil(ctx, time.Now()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	cancel()
+	if err := waitUntil(ctx, time.Now().Add(time.Hour)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expecting context.Canceled, got %v", err)
+	}
+}
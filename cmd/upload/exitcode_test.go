@@ -0,0 +1,76 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestExitStatus(t *testing.T) {
+	duplicate := "TEST_DATA/folder/low/PXL_20231006_063000139.jpg"
+	tests := []struct {
+		name string
+		ic   immich.ImmichInterface
+		code int
+	}{
+		{name: "all good", ic: &icCatchUploadsAssets{albums: map[string][]string{}}, code: cmd.ExitOK},
+		{name: "file errors", ic: &icFailingUploads{icCatchUploadsAssets{albums: map[string][]string{}}}, code: cmd.ExitFileErrors},
+		{
+			name: "server duplicates",
+			ic: &icBulkCheck{
+				icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+				onServer:             map[string]string{fileChecksum(t, duplicate): "server1"},
+			},
+			code: cmd.ExitServerDuplicates,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: tt.ic,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-bulk-check",
+				duplicate,
+				"TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+			})
+			if code := cmd.ExitCode(err); code != tt.code {
+				t.Errorf("expected exit code %d, got %d: %v", tt.code, code, err)
+			}
+		})
+	}
+}
+
+func TestExitStatusPriority(t *testing.T) {
+	fatal := errors.New("server unreachable")
+	tests := []struct {
+		name       string
+		err        error
+		fileErrors bool
+		code       int
+	}{
+		{name: "fatal error and file errors", err: fatal, fileErrors: true, code: cmd.ExitFatal},
+		{name: "interrupted with file errors", err: &cmd.ExitError{Code: cmd.ExitInterrupted, Err: context.Canceled}, fileErrors: true, code: cmd.ExitInterrupted},
+		{name: "file errors", fileErrors: true, code: cmd.ExitFileErrors},
+		{name: "fatal error", err: fatal, code: cmd.ExitFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &UpCmd{SharedFlags: &cmd.SharedFlags{Jnl: fileevent.NewRecorder(nil, false)}}
+			if tt.fileErrors {
+				app.Jnl.Record(context.Background(), fileevent.Error, nil, "photo.jpg", "error", "can't read the file")
+			}
+			if code := cmd.ExitCode(app.exitStatus(tt.err)); code != tt.code {
+				t.Errorf("expected exit code %d, got %d", tt.code, code)
+			}
+		})
+	}
+}
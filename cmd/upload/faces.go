@@ -0,0 +1,95 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// faceScale gives the image dimensions used when the regions don't tell them
+const faceScale = 10000
+
+// readFaceRegions reads the named faces of the XMP sidecar, or of the XMP embedded into a JPEG file
+func readFaceRegions(a *browser.LocalAssetFile) (metadata.FaceRegions, error) {
+	packets, err := xmpPackets(a)
+	if err != nil {
+		return metadata.FaceRegions{}, err
+	}
+	for _, x := range packets {
+		fr, err := metadata.ReadXMPFaceRegions(bytes.NewReader(x))
+		if err != nil || len(fr.Faces) > 0 {
+			return fr, err
+		}
+	}
+	return metadata.FaceRegions{}, nil
+}
+
+// importFaces attaches the persons named in the face regions of the asset to the uploaded asset.
+// The persons unknown by the server are created.
+func (app *UpCmd) importFaces(ctx context.Context, a *browser.LocalAssetFile, assetID string) error {
+	fr, err := readFaceRegions(a)
+	if err != nil || len(fr.Faces) == 0 {
+		return err
+	}
+	w, h := fr.ImageWidth, fr.ImageHeight
+	if w <= 0 || h <= 0 {
+		w, h = faceScale, faceScale
+	}
+	names := []string{}
+	for _, f := range fr.Faces {
+		id, err := app.personID(ctx, f.Name)
+		if err != nil {
+			return err
+		}
+		err = app.Immich.CreateFace(ctx, immich.Face{
+			AssetID:     assetID,
+			PersonID:    id,
+			ImageWidth:  w,
+			ImageHeight: h,
+			X:           int(math.Round(f.X * float64(w))),
+			Y:           int(math.Round(f.Y * float64(h))),
+			Width:       int(math.Round(f.Width * float64(w))),
+			Height:      int(math.Round(f.Height * float64(h))),
+		})
+		if err != nil {
+			return err
+		}
+		names = append(names, f.Name)
+	}
+	app.Jnl.Record(ctx, fileevent.INFO, a, a.FileName, "people", strings.Join(names, ", "))
+	return nil
+}
+
+// personID gives the ID of the person with the name, created when needed
+func (app *UpCmd) personID(ctx context.Context, name string) (string, error) {
+	app.peopleLock.Lock()
+	defer app.peopleLock.Unlock()
+	if app.people == nil {
+		people, err := app.Immich.GetAllPeople(ctx)
+		if err != nil {
+			return "", err
+		}
+		app.people = map[string]string{}
+		for _, p := range people {
+			if p.Name != "" {
+				app.people[strings.ToLower(p.Name)] = p.ID
+			}
+		}
+	}
+	if id, ok := app.people[strings.ToLower(name)]; ok {
+		return id, nil
+	}
+	p, err := app.Immich.CreatePerson(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	app.people[strings.ToLower(name)] = p.ID
+	app.Jnl.Record(ctx, fileevent.INFO, nil, "", "person created", name)
+	return p.ID, nil
+}
@@ -0,0 +1,89 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+type icCatchFaces struct {
+	icCatchUploadsAssets
+	created []string
+	faces   []immich.Face
+}
+
+func (c *icCatchFaces) GetAllPeople(ctx context.Context) ([]immich.Person, error) {
+	return []immich.Person{{ID: "alice-id", Name: "Alice"}}, nil
+}
+
+func (c *icCatchFaces) CreatePerson(ctx context.Context, name string) (immich.Person, error) {
+	c.created = append(c.created, name)
+	return immich.Person{ID: name + "-id", Name: name}, nil
+}
+
+func (c *icCatchFaces) CreateFace(ctx context.Context, face immich.Face) error {
+	c.faces = append(c.faces, face)
+	return nil
+}
+
+func TestImportFaces(t *testing.T) {
+	const sidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about=""
+   xmlns:mwg-rs="http://www.metadataworkinggroup.com/schemas/regions/"
+   xmlns:stDim="http://ns.adobe.com/xap/1.0/sType/Dimensions#"
+   xmlns:stArea="http://ns.adobe.com/xmp/sType/Area#">
+  <mwg-rs:Regions rdf:parseType="Resource">
+   <mwg-rs:AppliedToDimensions stDim:w="4000" stDim:h="3000" stDim:unit="pixel"/>
+   <mwg-rs:RegionList><rdf:Bag>
+     <rdf:li><rdf:Description mwg-rs:Name="alice" mwg-rs:Type="Face">
+       <mwg-rs:Area stArea:x="0.5" stArea:y="0.5" stArea:w="0.1" stArea:h="0.2" stArea:unit="normalized"/>
+     </rdf:Description></rdf:li>
+     <rdf:li><rdf:Description mwg-rs:Name="Bob" mwg-rs:Type="Face">
+       <mwg-rs:Area stArea:x="0.25" stArea:y="0.5" stArea:w="0.1" stArea:h="0.2" stArea:unit="normalized"/>
+     </rdf:Description></rdf:li>
+   </rdf:Bag></mwg-rs:RegionList>
+  </mwg-rs:Regions>
+ </rdf:Description></rdf:RDF></x:xmpmeta>`
+
+	dir := t.TempDir()
+	copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063000139.jpg", filepath.Join(dir, "PXL_20231006_063000139.jpg"))
+	copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063029647.jpg", filepath.Join(dir, "PXL_20231006_063029647.jpg"))
+	err := os.WriteFile(filepath.Join(dir, "PXL_20231006_063000139.jpg.xmp"), []byte(sidecar), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic := &icCatchFaces{icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err = UploadCommand(context.Background(), &serv, []string{"-no-ui", "-import-faces", dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(ic.created, []string{"Bob"}) {
+		t.Errorf("expected the creation of Bob, got %v", ic.created)
+	}
+	if len(ic.faces) != 2 {
+		t.Fatalf("expected 2 faces, got %v", ic.faces)
+	}
+	expected := immich.Face{AssetID: ic.faces[0].AssetID, PersonID: "alice-id", ImageWidth: 4000, ImageHeight: 3000, X: 1800, Y: 1200, Width: 400, Height: 600}
+	if ic.faces[0] != expected {
+		t.Errorf("expected %+v, got %+v", expected, ic.faces[0])
+	}
+	if ic.faces[1].PersonID != "Bob-id" || ic.faces[1].X != 800 {
+		t.Errorf("unexpected face: %+v", ic.faces[1])
+	}
+}
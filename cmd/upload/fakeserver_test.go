@@ -0,0 +1,168 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/metacache"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/fakefs"
+	"github.com/simulot/immich-go/internal/fakeserver"
+)
+
+// TestUploadTakeoutToFakeServer uploads a generated takeout to the in memory server, through the real client
+func TestUploadTakeoutToFakeServer(t *testing.T) {
+	opt := fakefs.DefaultTakeoutOptions()
+	opt.Photos, opt.Albums, opt.MediaSize, opt.PartSize = 300, 4, 1000, 100_000
+	opt.MissingJSONRatio = 0 // the upload fails when JSONs are missing
+	fsyss, assets := fakefs.GenerateTakeout(opt)
+
+	s := fakeserver.New("KEY")
+	server := httptest.NewServer(s)
+	defer server.Close()
+	ic, err := immich.NewImmichClient(server.URL, "KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ic.ValidateConnection(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich:   ic,
+		Jnl:      fileevent.NewRecorder(log, false),
+		Log:      log,
+		LogLevel: "INFO",
+	}
+	args := []string{"-google-photos", "-no-ui", "-log-file=" + filepath.Join(t.TempDir(), "upload.log")}
+	app, err := newCommand(ctx, &serv, args, func() ([]fs.FS, error) { return fsyss, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.stdout = io.Discard
+	err = app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the photos having a JSON are uploaded once, and put in their albums
+	uploaded := map[string]int{}
+	for _, a := range s.Assets() {
+		uploaded[a.FileName]++
+	}
+	albums := s.Albums()
+	if len(s.Assets()) != opt.Photos || len(albums) != opt.Albums {
+		t.Errorf("expected %d assets and %d albums, got %d and %d", opt.Photos, opt.Albums, len(s.Assets()), len(albums))
+	}
+	for _, a := range assets {
+		if !a.HasJSON {
+			continue
+		}
+		if uploaded[a.Title] == 0 {
+			t.Errorf("%s not uploaded", a.Title)
+		}
+		for _, al := range a.Albums {
+			found := false
+			for _, n := range albums[al] {
+				found = found || n == a.Title
+			}
+			if !found {
+				t.Errorf("%s not in the album %s", a.Title, al)
+			}
+		}
+	}
+}
+
+// TestChecksumWhileUploading checks that the checksums computed during the upload are kept in the metadata cache
+func TestChecksumWhileUploading(t *testing.T) {
+	s := fakeserver.New("KEY")
+	server := httptest.NewServer(s)
+	defer server.Close()
+	ic, err := immich.NewImmichClient(server.URL, "KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ic.ValidateConnection(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich:   ic,
+		Jnl:      fileevent.NewRecorder(log, false),
+		Log:      log,
+		LogLevel: "INFO",
+	}
+	cacheFile := filepath.Join(t.TempDir(), "metadata.jsonl")
+	folder := "TEST_DATA/folder/high/AlbumA"
+	err = UploadCommand(ctx, &serv, []string{"-no-ui", "-metadata-cache", "-metadata-cache-file=" + cacheFile, folder})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := metacache.Open(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		name := filepath.Join(folder, e.Name())
+		key, _ := filepath.Abs(name)
+		i, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum, ok := c.GetChecksum(filepath.ToSlash(key), i.Size(), i.ModTime()); !ok || sum != fileChecksum(t, name) {
+			t.Errorf("%s: wrong checksum in the cache %q", name, sum)
+		}
+	}
+}
+
+// TestUploadOverMemoryBudget checks that a memory budget always exceeded slows down the upload without losing assets
+func TestUploadOverMemoryBudget(t *testing.T) {
+	s := fakeserver.New("KEY")
+	server := httptest.NewServer(s)
+	defer server.Close()
+	ic, err := immich.NewImmichClient(server.URL, "KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ic.ValidateConnection(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich:   ic,
+		Jnl:      fileevent.NewRecorder(log, false),
+		Log:      log,
+		LogLevel: "INFO",
+	}
+	folder := "TEST_DATA/folder/high/AlbumA"
+	err = UploadCommand(ctx, &serv, []string{"-no-ui", "-max-memory=1K", "-bulk-check", folder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Assets()) != len(entries) {
+		t.Errorf("expected %d assets, got %d", len(entries), len(s.Assets()))
+	}
+}
@@ -0,0 +1,91 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// fixSourceExif writes the date of capture found in the JSON file or in the file name
+// into the EXIF data of the source file, when the file hasn't its own date.
+// Only JPEG files are modified, and only when they aren't on the server yet.
+func (app *UpCmd) fixSourceExif(ctx context.Context, a *browser.LocalAssetFile) error {
+	ext := strings.ToLower(a.Ext())
+	if a.Metadata.DateTaken.IsZero() || (ext != ".jpg" && ext != ".jpeg") {
+		return nil
+	}
+	if md, ok := app.readFileMetadata(a); ok && !md.DateTaken.IsZero() {
+		return nil
+	}
+	d, ok := a.FSys.(fshelper.OSDirFS)
+	if !ok {
+		return fmt.Errorf("can't fix the EXIF data of %s: the source isn't a folder", a.FileName)
+	}
+	if !app.DryRun {
+		// release the partial reads of the former content
+		err := a.Close()
+		if err != nil {
+			return err
+		}
+		size, err := writeJPEGDate(filepath.Join(d.Dir(), filepath.FromSlash(a.FileName)), a)
+		if err != nil {
+			return err
+		}
+		a.FileSize = size
+		a.Checksum = ""
+	}
+	app.Jnl.Record(ctx, fileevent.EXIFFixed, a, a.FileName, "date", a.Metadata.DateTaken.String())
+	return nil
+}
+
+// writeJPEGDate replaces the file with a copy carrying the date, and returns the new size
+func writeJPEGDate(name string, a *browser.LocalAssetFile) (int, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	s, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".immich-go_*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	err = metadata.SetJPEGDateTaken(src, tmp, a.Metadata.DateTaken)
+	if err == nil {
+		err = tmp.Chmod(s.Mode())
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	src.Close()
+	err = os.Rename(tmp.Name(), name)
+	if err != nil {
+		return 0, err
+	}
+	err = os.Chtimes(name, s.ModTime(), s.ModTime())
+	if err != nil {
+		return 0, err
+	}
+	s, err = os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return int(s.Size()), nil
+}
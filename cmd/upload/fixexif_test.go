@@ -0,0 +1,104 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestFixSourceExif(t *testing.T) {
+	const (
+		folder = "Google Photos/Photos from 2023"
+		asset  = "PXL_20231006_063000139.jpg"
+	)
+	testCases := []struct {
+		name     string
+		args     []string
+		onServer bool
+		expected bool
+	}{
+		{name: "fix", args: []string{"-fix-source-exif"}, expected: true},
+		{name: "dry-run", args: []string{"-fix-source-exif", "-dry-run"}},
+		{name: "no fix"},
+		{name: "already on the server", args: []string{"-fix-source-exif"}, onServer: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			err := os.MkdirAll(filepath.Join(dir, folder), 0o755)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range []string{asset, asset + ".json"} {
+				copyFile(t, filepath.Join("TEST_DATA/Takeout2", folder, f), filepath.Join(dir, folder, f))
+			}
+			before, err := metadata.GetFileMetaData(os.DirFS(dir), folder+"/"+asset)
+			if err == nil && !before.DateTaken.IsZero() {
+				t.Fatalf("the test file shouldn't have a date of capture")
+			}
+
+			ic := &icCatchMetadata{
+				icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+				metadata:             map[string]metadata.Metadata{},
+			}
+			var server immich.ImmichInterface = ic
+			if tc.onServer {
+				// the server has the file as it is on the disk
+				server = &icWithAssets{
+					icCatchUploadsAssets: ic.icCatchUploadsAssets,
+					serverAssets: []*immich.Asset{
+						{
+							ID:               "server-asset",
+							DeviceAssetID:    asset + "-136452",
+							OriginalFileName: asset,
+							ExifInfo: immich.ExifInfo{
+								FileSizeInByte:   136452,
+								DateTimeOriginal: immich.ImmichTime{Time: time.Date(2023, 10, 6, 6, 30, 0, 0, time.UTC)},
+							},
+						},
+					},
+				}
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: server,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			err = UploadCommand(context.Background(), &serv, append(append([]string{"-no-ui", "-google-photos"}, tc.args...), dir))
+			if err != nil && cmd.ExitCode(err) != cmd.ExitServerDuplicates {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			md, ok := ic.metadata[folder+"/"+asset]
+			if !tc.expected {
+				b1, _ := os.ReadFile(filepath.Join("TEST_DATA/Takeout2", folder, asset))
+				b2, _ := os.ReadFile(filepath.Join(dir, folder, asset))
+				if !bytes.Equal(b1, b2) {
+					t.Errorf("the source file shouldn't be modified")
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("the asset hasn't been uploaded")
+			}
+			after, err := metadata.GetFileMetaData(os.DirFS(dir), folder+"/"+asset)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !after.DateTaken.Equal(md.DateTaken) {
+				t.Errorf("expected date %s in the file, got %s", md.DateTaken, after.DateTaken)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/metacache"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// Values of the -reverse-geocode-into option
+const (
+	GeocodeIntoTags        = "tags"        // the place names are added to the asset's keywords
+	GeocodeIntoDescription = "description" // the place is given as description, when the asset hasn't any
+)
+
+func validateGeocodeInto(s string) (string, error) {
+	s = strings.ToLower(s)
+	switch s {
+	case GeocodeIntoTags, GeocodeIntoDescription:
+		return s, nil
+	}
+	return "", fmt.Errorf("the option -reverse-geocode-into accepts %s or %s", GeocodeIntoTags, GeocodeIntoDescription)
+}
+
+// readFileMetadata reads the metadata embedded in the asset's file, or takes them from the metadata cache
+func (app *UpCmd) readFileMetadata(a *browser.LocalAssetFile) (metadata.Metadata, bool) {
+	key := metacache.Key(a.FSys, a.FileName)
+	i, err := fs.Stat(a.FSys, a.FileName)
+	if err == nil {
+		if md, found, ok := app.metaCache.Get(key, i.Size(), i.ModTime()); ok {
+			return md, found
+		}
+	}
+	r, err := a.PartialSourceReader()
+	if err != nil {
+		return metadata.Metadata{}, false
+	}
+	md, err := metadata.GetFromReader(r, a.Ext())
+	_ = a.ClosePartialSourceReader()
+	if i != nil {
+		app.metaCache.Put(key, i.Size(), i.ModTime(), md, err == nil)
+	}
+	return md, err == nil
+}
+
+// geocodeAsset converts the GPS location of the asset into place names.
+// The location is taken from the metadata gathered so far, or read from the file.
+// The names are transmitted with the XMP data of the asset. The server reads the asset's own sidecar instead,
+// the place isn't searched for those assets, unless -write-xmp replaces the sidecar.
+func (app *UpCmd) geocodeAsset(ctx context.Context, a *browser.LocalAssetFile) {
+	if app.geocoder == nil {
+		return
+	}
+	if a.SideCar.IsSet() && !app.overwritesSidecar(a) {
+		app.Jnl.Record(ctx, fileevent.INFO, nil, a.FileName, "place", "not named, the asset has its own XMP sidecar")
+		return
+	}
+	latitude, longitude := a.Metadata.Latitude, a.Metadata.Longitude
+	if latitude == 0 && longitude == 0 {
+		md, ok := app.readFileMetadata(a)
+		if !ok {
+			return
+		}
+		latitude, longitude = md.Latitude, md.Longitude
+		if latitude == 0 && longitude == 0 {
+			return
+		}
+	}
+
+	p, err := app.geocoder.Reverse(ctx, latitude, longitude)
+	if err != nil {
+		app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", "reverse geocoding: "+err.Error())
+		return
+	}
+	if !p.IsSet() {
+		return
+	}
+	switch app.ReverseGeocodeInto {
+	case GeocodeIntoTags:
+		for _, n := range p.Names() {
+			if !slices.Contains(a.Metadata.Tags, n) {
+				a.Metadata.Tags = append(a.Metadata.Tags, n)
+			}
+		}
+	case GeocodeIntoDescription:
+		if a.Metadata.Description != "" {
+			return
+		}
+		a.Metadata.Description = p.String()
+	}
+	app.Jnl.Record(ctx, fileevent.INFO, nil, a.FileName, "place", p.String())
+}
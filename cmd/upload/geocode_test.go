@@ -0,0 +1,153 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/geocode"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+type icCatchMetadata struct {
+	icCatchUploadsAssets
+	metadata map[string]metadata.Metadata
+}
+
+func (c *icCatchMetadata) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	c.metadata[a.FileName] = a.Metadata
+	return c.icCatchUploadsAssets.AssetUpload(ctx, a)
+}
+
+func TestReverseGeocode(t *testing.T) {
+	const eiffelTower = "Google Photos/Photos from 2023/PXL_20231006_063000139.jpg"
+	testCases := []struct {
+		name                string
+		into                string
+		expectedTags        []string
+		expectedDescription string
+	}{
+		{
+			name:         "tags",
+			into:         "tags",
+			expectedTags: []string{"Paris", "Ile-de-France", "France"},
+		},
+		{
+			name:                "description",
+			into:                "description",
+			expectedDescription: "Paris, Ile-de-France, France",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ic := &icCatchMetadata{
+				icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+				metadata:             map[string]metadata.Metadata{},
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: ic,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			err := UploadCommand(context.Background(), &serv, []string{
+				"-no-ui", "-google-photos",
+				"-reverse-geocode=../../helpers/geocode/TEST_DATA/cities.txt", "-reverse-geocode-into=" + tc.into,
+				"TEST_DATA/Takeout2",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			md, ok := ic.metadata[eiffelTower]
+			if !ok {
+				t.Fatalf("the asset %s hasn't been uploaded", eiffelTower)
+			}
+			if !reflect.DeepEqual(md.Tags, tc.expectedTags) {
+				t.Errorf("expected tags %v, got %v", tc.expectedTags, md.Tags)
+			}
+			if md.Description != tc.expectedDescription {
+				t.Errorf("expected description %q, got %q", tc.expectedDescription, md.Description)
+			}
+		})
+	}
+}
+
+func TestReverseGeocodeValidation(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, args := range [][]string{
+		{"-reverse-geocode=TEST_DATA/missing.txt"},
+		{"-reverse-geocode-into=city"},
+	} {
+		serv := cmd.SharedFlags{
+			Immich: &icCatchUploadsAssets{albums: map[string][]string{}},
+			Jnl:    fileevent.NewRecorder(log, false),
+			Log:    log,
+		}
+		err := UploadCommand(context.Background(), &serv, append(args, "TEST_DATA/folder/low"))
+		if err == nil {
+			t.Errorf("an error was expected for %v", args)
+		}
+	}
+}
+
+// countingGeocoder counts the queries
+type countingGeocoder struct {
+	queries int
+}
+
+func (g *countingGeocoder) Reverse(ctx context.Context, latitude, longitude float64) (geocode.Place, error) {
+	g.queries++
+	return geocode.Place{City: "Paris", Country: "France"}, nil
+}
+
+func TestReverseGeocodeOwnSidecar(t *testing.T) {
+	var immichGo strings.Builder
+	_ = metadata.Metadata{Description: "written by immich-go"}.WriteSidecar(&immichGo)
+	fsys := fstest.MapFS{
+		"user.jpg.xmp":      {Data: []byte("<x:xmpmeta/>")},
+		"immich-go.jpg.xmp": {Data: []byte(immichGo.String())},
+	}
+	testCases := []struct {
+		name     string
+		sidecar  string
+		writeXMP string
+		expected bool // the place is named
+	}{
+		{name: "no sidecar", expected: true},
+		{name: "user's sidecar", sidecar: "user.jpg.xmp"},
+		{name: "user's sidecar, overwrite", sidecar: "user.jpg.xmp", writeXMP: XMPOverwrite},
+		{name: "immich-go's sidecar", sidecar: "immich-go.jpg.xmp", writeXMP: XMPMissing},
+		{name: "immich-go's sidecar, overwrite", sidecar: "immich-go.jpg.xmp", writeXMP: XMPOverwrite, expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &countingGeocoder{}
+			app := &UpCmd{
+				SharedFlags:        &cmd.SharedFlags{Jnl: fileevent.NewRecorder(nil, false)},
+				ReverseGeocodeInto: GeocodeIntoTags,
+				WriteXMP:           tc.writeXMP,
+				geocoder:           g,
+			}
+			a := &browser.LocalAssetFile{
+				FSys:     fsys,
+				FileName: "photo.jpg",
+				Metadata: metadata.Metadata{Latitude: 48.8583736, Longitude: 2.291901},
+			}
+			if tc.sidecar != "" {
+				a.SideCar = metadata.SideCarFile{FSys: fsys, FileName: tc.sidecar}
+			}
+			app.geocodeAsset(context.Background(), a)
+			if named := len(a.Metadata.Tags) > 0; named != tc.expected || (g.queries > 0) != tc.expected {
+				t.Errorf("expected named %v, got tags %v after %d queries", tc.expected, a.Metadata.Tags, g.queries)
+			}
+		})
+	}
+}
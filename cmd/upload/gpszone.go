@@ -0,0 +1,50 @@
+package upload
+
+import (
+	"context"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+// zoneTime interprets the date of capture given without time zone in the time zone of the asset's GPS location,
+// instead of the local time zone. Travel photos get their right time.
+func (app *UpCmd) zoneTime(ctx context.Context, a *browser.LocalAssetFile) {
+	if app.gpsZones == nil || !a.Metadata.LocalTime || a.Metadata.DateTaken.IsZero() {
+		return
+	}
+	latitude, longitude := a.Metadata.Latitude, a.Metadata.Longitude
+	if latitude == 0 && longitude == 0 {
+		md, ok := app.readFileMetadata(a)
+		if !ok {
+			return
+		}
+		latitude, longitude = md.Latitude, md.Longitude
+		if latitude == 0 && longitude == 0 {
+			return
+		}
+	}
+	loc, err := app.gpsZones.TimeZone(latitude, longitude)
+	if err != nil {
+		app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", "time zone: "+err.Error())
+		return
+	}
+	if loc == nil {
+		return
+	}
+	before := a.Metadata.DateTaken
+	a.Metadata.DateTaken, a.Metadata.LocalTime = inZone(before, loc), false
+	if a.LivePhoto != nil && a.LivePhoto.Metadata.LocalTime {
+		a.LivePhoto.Metadata.DateTaken, a.LivePhoto.Metadata.LocalTime = inZone(a.LivePhoto.Metadata.DateTaken, loc), false
+	}
+	if !a.Metadata.DateTaken.Equal(before) {
+		a.DateFixed = true
+		app.Jnl.Record(ctx, fileevent.INFO, a, a.FileName, "time zone", loc.String(), "date", a.Metadata.DateTaken.String())
+	}
+}
+
+// inZone gives the time having the same clock reading in the time zone
+func inZone(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
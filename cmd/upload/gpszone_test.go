@@ -0,0 +1,68 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/geocode"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestZoneTime(t *testing.T) {
+	cities, err := geocode.OpenCitiesFile("../../helpers/geocode/TEST_DATA/cities.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	naive := time.Date(2023, 10, 6, 8, 30, 0, 0, time.UTC)
+	zoned := time.Date(2023, 10, 6, 8, 30, 0, 0, newYork)
+
+	tests := []struct {
+		name     string
+		md       metadata.Metadata
+		expected time.Time
+	}{
+		{
+			name:     "local time in New York",
+			md:       metadata.Metadata{DateTaken: naive, LocalTime: true, Latitude: 40.75, Longitude: -73.99},
+			expected: zoned,
+		},
+		{
+			name:     "date with time zone",
+			md:       metadata.Metadata{DateTaken: naive, Latitude: 40.75, Longitude: -73.99},
+			expected: naive,
+		},
+		{
+			name:     "far from the cities",
+			md:       metadata.Metadata{DateTaken: naive, LocalTime: true, Latitude: 40, Longitude: -40},
+			expected: naive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			app := &UpCmd{
+				SharedFlags: &cmd.SharedFlags{Jnl: fileevent.NewRecorder(log, false), Log: log},
+				gpsZones:    cities,
+			}
+			a := &browser.LocalAssetFile{FileName: "photo.jpg", Metadata: tt.md}
+			app.zoneTime(context.Background(), a)
+			if !a.Metadata.DateTaken.Equal(tt.expected) {
+				t.Errorf("expected %s, got %s", tt.expected, a.Metadata.DateTaken)
+			}
+			// the corrected date is set on the server when the asset has its own sidecar
+			if fixed := !tt.expected.Equal(naive); a.DateFixed != fixed {
+				t.Errorf("expected the date marked as fixed %v, got %v", fixed, a.DateFixed)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package upload
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"image"
+	"image/color"
+	_ "image/gif" // decode the GIF files for the thumbnails
+	"image/jpeg"
+	_ "image/png" // decode the PNG files for the thumbnails
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+const (
+	thumbnailSize = 160 // size of the longest side of the thumbnails
+	maxThumbnails = 200 // the thumbnails are embedded in the report, their number is limited to keep the report small
+)
+
+type htmlCounter struct {
+	Name  string
+	Count int64
+}
+
+type htmlAlbum struct {
+	Title     string
+	ShareLink string // shared link of the album created during the run
+	Entries   []htmlEntry
+}
+
+type htmlEntry struct {
+	reportEntry
+	Link      string       // link to the server's asset
+	Thumbnail template.URL // embedded thumbnail of the file
+	Ext       string       // extension of the file, shown when there is no thumbnail
+}
+
+type htmlReport struct {
+	Date     string
+	Server   string
+	DryRun   bool
+	Counters []htmlCounter
+	Albums   []htmlAlbum
+	Issues   []htmlEntry
+}
+
+// writeHTMLReport writes a self-contained HTML page summarizing the run: the counters, the content of the albums,
+// and a gallery of the files in error or skipped
+func (app *UpCmd) writeHTMLReport() error {
+	r := htmlReport{
+		Date:   time.Now().Format(time.DateTime),
+		Server: app.Server,
+		DryRun: app.DryRun,
+	}
+	counts := app.Jnl.GetCounts()
+	for c := fileevent.Code(0); c < fileevent.MaxCode; c++ {
+		if counts[c] > 0 {
+			r.Counters = append(r.Counters, htmlCounter{Name: c.String(), Count: counts[c]})
+		}
+	}
+
+	albums := map[string][]htmlEntry{}
+	thumbnails := 0
+	for _, e := range app.reportEntries() {
+		he := htmlEntry{reportEntry: e, Ext: strings.ToUpper(strings.TrimPrefix(path.Ext(e.File), "."))}
+		if e.AssetID != "" && app.Server != "" && !app.DryRun {
+			he.Link = app.Server + "/photos/" + e.AssetID
+		}
+		for _, al := range e.Albums {
+			albums[al] = append(albums[al], he)
+		}
+		switch e.Action {
+		case ReportError, ReportDiscarded, ReportDuplicate:
+			if thumbnails < maxThumbnails {
+				if t, err := app.thumbnail(e.File); err == nil {
+					he.Thumbnail = t
+					thumbnails++
+				}
+			}
+			r.Issues = append(r.Issues, he)
+		}
+	}
+	for title, entries := range albums {
+		r.Albums = append(r.Albums, htmlAlbum{Title: title, ShareLink: app.shareLink(title), Entries: entries})
+	}
+	sort.Slice(r.Albums, func(i, j int) bool { return r.Albums[i].Title < r.Albums[j].Title })
+
+	err := configuration.MakeDirForFile(app.HTMLReport)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(app.HTMLReport)
+	if err != nil {
+		return err
+	}
+	err = htmlReportTemplate.Execute(f, r)
+	return errors.Join(err, f.Close())
+}
+
+// thumbnail gives a small JPEG of the file as a data URL. Only the JPEG, PNG and GIF files are decoded.
+func (app *UpCmd) thumbnail(name string) (template.URL, error) {
+	for _, fsys := range app.fsyss {
+		f, err := fsys.Open(name)
+		if err != nil {
+			continue
+		}
+		img, _, err := image.Decode(f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		var b bytes.Buffer
+		err = jpeg.Encode(&b, reduce(img, thumbnailSize), &jpeg.Options{Quality: 70})
+		if err != nil {
+			return "", err
+		}
+		return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(b.Bytes())), nil
+	}
+	return "", fs.ErrNotExist
+}
+
+// reduce gives the image reduced to fit in a square of the given size, each pixel being the average of the pixels it covers
+func reduce(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	tw, th = max(tw, 1), max(th, 1)
+	type sum struct{ r, g, b, n uint64 }
+	sums := make([]sum, tw*th)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		ty := (y - b.Min.Y) * th / h
+		for x := b.Min.X; x < b.Max.X; x++ {
+			tx := (x - b.Min.X) * tw / w
+			r, g, bl, _ := img.At(x, y).RGBA()
+			s := &sums[ty*tw+tx]
+			s.r += uint64(r)
+			s.g += uint64(g)
+			s.b += uint64(bl)
+			s.n++
+		}
+	}
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for i, s := range sums {
+		if s.n == 0 {
+			continue
+		}
+		thumb.Set(i%tw, i/tw, color.RGBA64{uint16(s.r / s.n), uint16(s.g / s.n), uint16(s.b / s.n), 0xffff})
+	}
+	return thumb
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>immich-go upload report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+td.count { text-align: right; }
+details { margin-bottom: 0.5em; }
+summary { cursor: pointer; font-weight: bold; }
+.gallery { display: flex; flex-wrap: wrap; gap: 1em; }
+.gallery figure { margin: 0; width: 180px; padding: 0.5em; border: 1px solid #ccc; border-radius: 4px; }
+.gallery figure.error { border-color: #c00; background: #fee; }
+.gallery figure.discarded { background: #eee; }
+.gallery figure.duplicate { background: #eef; }
+.gallery img, .gallery .noimg { display: block; margin: auto; max-width: 160px; max-height: 160px; }
+.gallery .noimg { width: 160px; height: 100px; line-height: 100px; text-align: center; background: #ccc; color: #666; font-size: 1.5em; }
+figcaption { font-size: 0.8em; overflow-wrap: anywhere; }
+</style>
+</head>
+<body>
+<h1>immich-go upload report</h1>
+<p>Run ended at {{.Date}}{{if .Server}}, server <a href="{{.Server}}">{{.Server}}</a>{{end}}{{if .DryRun}}, dry run{{end}}.</p>
+
+<h2>Counters</h2>
+<table>
+{{range .Counters}}<tr><td>{{.Name}}</td><td class="count">{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Albums</h2>
+{{range .Albums}}<details>
+<summary>{{.Title}} ({{len .Entries}})</summary>
+{{if .ShareLink}}<p>Shared link: <a href="{{.ShareLink}}">{{.ShareLink}}</a></p>{{end}}
+<table>
+<tr><th>File</th><th>Action</th><th>Date</th><th>Asset</th></tr>
+{{range .Entries}}<tr><td>{{.Path}}</td><td>{{.Action}}</td><td>{{if not .Date.IsZero}}{{.Date.Format "2006-01-02 15:04:05"}}{{end}}</td><td>{{if .Link}}<a href="{{.Link}}">{{.AssetID}}</a>{{else}}{{.AssetID}}{{end}}</td></tr>
+{{end}}</table>
+</details>
+{{else}}<p>No album.</p>
+{{end}}
+<h2>Errors and skipped files</h2>
+{{if .Issues}}<div class="gallery">
+{{range .Issues}}<figure class="{{.Action}}">
+{{if .Thumbnail}}<img src="{{.Thumbnail}}" alt="{{.Path}}">{{else}}<div class="noimg">{{.Ext}}</div>{{end}}
+<figcaption><b>{{.Action}}</b> {{.Path}}{{if .Reason}}<br>{{.Reason}}{{end}}{{if .Link}}<br><a href="{{.Link}}">server's asset</a>{{end}}</figcaption>
+</figure>
+{{end}}</div>
+{{else}}<p>No error, no skipped file.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,51 @@
+package upload
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/metacache"
+	"github.com/simulot/immich-go/helpers/scandb"
+)
+
+// openScanDB opens the database of the files uploaded to the server by the previous runs.
+// The returned function writes it back.
+func (app *UpCmd) openScanDB() (func(), error) {
+	var err error
+	file := scandb.FileName(app.IncrementalDir, targetServer(app.Server, app.API))
+	app.scanDB, err = scandb.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't open the scan database: %w", err)
+	}
+	return func() {
+		if n := app.Jnl.GetCounts()[fileevent.UploadUnchanged]; n > 0 {
+			app.Log.Info(fmt.Sprintf("%d files unchanged since their upload have been skipped", n))
+		}
+		err := app.scanDB.Close()
+		if err != nil {
+			app.Log.Error("can't write the scan database: " + err.Error())
+		}
+		app.scanDB = nil
+	}, nil
+}
+
+// rememberUpload records the server's asset of the file, and of its live photo video, for the next runs
+func (app *UpCmd) rememberUpload(a *browser.LocalAssetFile, assetID string) {
+	if app.scanDB == nil || app.DryRun {
+		return
+	}
+	app.rememberFile(a, assetID)
+	if a.LivePhoto != nil && a.LivePhotoID != "" {
+		app.rememberFile(a.LivePhoto, a.LivePhotoID)
+	}
+}
+
+func (app *UpCmd) rememberFile(a *browser.LocalAssetFile, assetID string) {
+	i, err := fs.Stat(a.FSys, a.FileName)
+	if err != nil {
+		return
+	}
+	app.scanDB.Put(metacache.Key(a.FSys, a.FileName), i.Size(), i.ModTime(), a.Checksum, assetID)
+}
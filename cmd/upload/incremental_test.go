@@ -0,0 +1,90 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/fakeserver"
+)
+
+func TestIncremental(t *testing.T) {
+	s := fakeserver.New("KEY")
+	server := httptest.NewServer(s)
+	defer server.Close()
+	ic, err := immich.NewImmichClient(server.URL, "KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ic.ValidateConnection(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	folder := t.TempDir()
+	entries, err := os.ReadDir("TEST_DATA/folder/high/AlbumA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		copyFile(t, filepath.Join("TEST_DATA/folder/high/AlbumA", e.Name()), filepath.Join(folder, e.Name()))
+	}
+	scanDir := t.TempDir()
+
+	upload := func() []int64 {
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+		serv := cmd.SharedFlags{
+			Immich:   ic,
+			Server:   server.URL,
+			Jnl:      fileevent.NewRecorder(log, false),
+			Log:      log,
+			LogLevel: "INFO",
+		}
+		err := UploadCommand(ctx, &serv, []string{"-no-ui", "-incremental", "-incremental-dir=" + scanDir, folder})
+		var exitErr *cmd.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.Code == cmd.ExitServerDuplicates) {
+			t.Fatal(err)
+		}
+		return serv.Jnl.GetCounts()
+	}
+
+	counts := upload()
+	if counts[fileevent.Uploaded] != int64(len(entries)) || counts[fileevent.UploadUnchanged] != 0 {
+		t.Fatalf("first run: expected %d uploads, got %d, and %d unchanged", len(entries), counts[fileevent.Uploaded], counts[fileevent.UploadUnchanged])
+	}
+
+	// the files are skipped without being compared with the server's assets
+	counts = upload()
+	if counts[fileevent.UploadUnchanged] != int64(len(entries)) || counts[fileevent.UploadServerDuplicate] != 0 || s.Uploads() != len(entries) {
+		t.Errorf("second run: expected %d unchanged files, got %d, %d duplicates and %d uploads",
+			len(entries), counts[fileevent.UploadUnchanged], counts[fileevent.UploadServerDuplicate], s.Uploads())
+	}
+
+	// a modified file is processed again
+	touched := filepath.Join(folder, entries[0].Name())
+	mtime := time.Now().Add(-time.Hour)
+	err = os.Chtimes(touched, mtime, mtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts = upload()
+	if counts[fileevent.UploadUnchanged] != int64(len(entries)-1) || counts[fileevent.UploadServerDuplicate] != 1 {
+		t.Errorf("third run: expected %d unchanged files and the modified one found on the server, got %d and %d",
+			len(entries)-1, counts[fileevent.UploadUnchanged], counts[fileevent.UploadServerDuplicate])
+	}
+
+	// and recorded again
+	counts = upload()
+	if counts[fileevent.UploadUnchanged] != int64(len(entries)) {
+		t.Errorf("fourth run: expected %d unchanged files, got %d", len(entries), counts[fileevent.UploadUnchanged])
+	}
+}
@@ -0,0 +1,117 @@
+package upload
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// Status of the assets written in the mapping file
+const (
+	MappingUploaded        = "uploaded"         // the asset has been uploaded
+	MappingReplaced        = "replaced"         // the asset has replaced a server's asset
+	MappingServerDuplicate = "server-duplicate" // the server has the same asset
+	MappingServerBetter    = "server-better"    // the server has a better version of the asset
+	MappingServerKept      = "server-kept"      // the server has another version of the asset, kept by the -on-duplicate policy
+	MappingLocalDuplicate  = "local-duplicate"  // the asset has been uploaded from another file of the input
+)
+
+// assetMapping associates a local file to its Immich asset
+type assetMapping struct {
+	Path     string   `json:"path"`
+	AssetID  string   `json:"assetId"`
+	Status   string   `json:"status"`
+	AlbumIDs []string `json:"albumIds,omitempty"`
+}
+
+// mappingWriter writes the mapping of the local files to the Immich assets as they are processed.
+// The format is given by the file extension: JSON for .json files, CSV otherwise.
+type mappingWriter struct {
+	f     *os.File
+	csv   *csv.Writer
+	json  *json.Encoder
+	count int
+}
+
+func newMappingWriter(name string) (*mappingWriter, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	mw := &mappingWriter{f: f}
+	if strings.ToLower(filepath.Ext(name)) == ".json" {
+		mw.json = json.NewEncoder(f)
+		_, err = f.WriteString("[\n")
+	} else {
+		mw.csv = csv.NewWriter(f)
+		err = mw.csv.Write([]string{"path", "asset_id", "status", "album_ids"})
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return mw, nil
+}
+
+func (mw *mappingWriter) Write(m assetMapping) error {
+	defer func() { mw.count++ }()
+	if mw.json != nil {
+		if mw.count > 0 {
+			if _, err := mw.f.WriteString(","); err != nil {
+				return err
+			}
+		}
+		return mw.json.Encode(m)
+	}
+	err := mw.csv.Write([]string{m.Path, m.AssetID, m.Status, strings.Join(m.AlbumIDs, ";")})
+	if err != nil {
+		return err
+	}
+	// flush each line to keep the file usable when the program is interrupted
+	mw.csv.Flush()
+	return mw.csv.Error()
+}
+
+func (mw *mappingWriter) Close() error {
+	var err error
+	if mw.json != nil {
+		_, err = mw.f.WriteString("]\n")
+	}
+	if cErr := mw.f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// mapAsset writes the mapping of the asset, and of its live photo video, into the mapping file
+func (app *UpCmd) mapAsset(a *browser.LocalAssetFile, assetID string, status string, albums []string) {
+	if app.mapping == nil {
+		return
+	}
+	albumIDs := []string{}
+	for _, title := range albums {
+		if al, ok := app.albums.get(title); ok && al.ID != "" {
+			albumIDs = append(albumIDs, al.ID)
+		}
+	}
+	write := func(a *browser.LocalAssetFile, id string) {
+		p := a.FileName
+		if fsys, ok := a.FSys.(fshelper.NameFS); ok {
+			p = path.Join(fsys.Name(), a.FileName)
+		}
+		err := app.mapping.Write(assetMapping{Path: p, AssetID: id, Status: status, AlbumIDs: albumIDs})
+		if err != nil {
+			app.Log.Error("can't write into the mapping file: " + err.Error())
+		}
+	}
+	if a.LivePhoto != nil && a.LivePhotoID != "" {
+		write(a.LivePhoto, a.LivePhotoID)
+	}
+	write(a, assetID)
+}
@@ -0,0 +1,72 @@
+package upload
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestMappingFile(t *testing.T) {
+	expected := []assetMapping{
+		{Path: "low/PXL_20231006_063000139.jpg", AssetID: "PXL_20231006_063000139.jpg", Status: MappingUploaded, AlbumIDs: []string{"the album"}},
+		{Path: "low/PXL_20231006_063029647.jpg", AssetID: "PXL_20231006_063029647.jpg", Status: MappingUploaded, AlbumIDs: []string{"the album"}},
+	}
+
+	for _, ext := range []string{".csv", ".json"} {
+		t.Run(ext, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "mapping"+ext)
+			ic := &icCatchUploadsAssets{
+				albums: map[string][]string{},
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: ic,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			err := UploadCommand(context.Background(), &serv, []string{
+				"-no-ui", "-album=the album", "-mapping-file=" + name,
+				"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			f, err := os.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got := []assetMapping{}
+			if ext == ".json" {
+				err = json.NewDecoder(f).Decode(&got)
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				records, err := csv.NewReader(f).ReadAll()
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, r := range records[1:] {
+					got = append(got, assetMapping{Path: r[0], AssetID: r[1], Status: r[2], AlbumIDs: []string{r[3]}})
+				}
+			}
+			if !reflect.DeepEqual(expected, got) {
+				t.Errorf("unexpected mapping")
+				pretty.Ldiff(t, expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/notify"
+)
+
+// notifyCheckPeriod is the period of the check of the number of errors
+var notifyCheckPeriod = 5 * time.Second
+
+// notifier sends the notifications of a run
+type notifier struct {
+	*notify.Notifier
+	started    time.Time
+	errorsSent bool // the errors notification is sent once per run
+	lock       sync.Mutex
+}
+
+// startNotifications watches the number of errors when a threshold is given, and gives the function to call at the end of the run
+func (app *UpCmd) startNotifications(ctx context.Context) func() {
+	n := &notifier{Notifier: notify.New(app.NotifyURL, app.NotifyFormat), started: time.Now()}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if app.NotifyErrors > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t := time.NewTicker(notifyCheckPeriod)
+			defer t.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					app.checkErrors(ctx, n)
+				}
+			}
+		}()
+	}
+	return func() {
+		close(done)
+		wg.Wait()
+		// the notifications are sent even when the run is interrupted
+		ctx := context.WithoutCancel(ctx)
+		if app.NotifyErrors > 0 {
+			app.checkErrors(ctx, n)
+		}
+		app.sendNotification(ctx, n, notify.EventCompleted)
+	}
+}
+
+// checkErrors sends the errors notification when the number of errors reaches the threshold
+func (app *UpCmd) checkErrors(ctx context.Context, n *notifier) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.errorsSent || countErrors(app.Jnl.GetCounts()) < int64(app.NotifyErrors) {
+		return
+	}
+	n.errorsSent = true
+	app.sendNotification(ctx, n, notify.EventErrors)
+}
+
+func countErrors(counts []int64) int64 {
+	return counts[fileevent.UploadServerError] + counts[fileevent.Error]
+}
+
+// sendNotification sends the event with the counters of the run. The failures are logged.
+func (app *UpCmd) sendNotification(ctx context.Context, n *notifier, event string) {
+	counts := app.Jnl.GetCounts()
+	e := notify.Event{
+		Event:   event,
+		Command: "upload",
+		Errors:  countErrors(counts),
+		Counts:  map[string]int64{},
+		Time:    time.Now(),
+	}
+	for c := fileevent.Code(0); c < fileevent.MaxCode; c++ {
+		if counts[c] > 0 {
+			e.Counts[c.Key()] = counts[c]
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d uploaded, %d already on the server, %d upgraded, %d discarded, %d errors",
+		counts[fileevent.Uploaded],
+		counts[fileevent.UploadServerDuplicate]+counts[fileevent.UploadServerBetter]+counts[fileevent.UploadUnchanged],
+		counts[fileevent.UploadUpgraded],
+		counts[fileevent.DiscoveredDiscarded]+counts[fileevent.UploadNotSelected],
+		e.Errors)
+	switch event {
+	case notify.EventErrors:
+		e.Title = fmt.Sprintf("immich-go upload: %d errors", e.Errors)
+		fmt.Fprintf(&sb, ", the upload continues")
+	default:
+		e.Title = "immich-go upload completed"
+		fmt.Fprintf(&sb, " in %s", time.Since(n.started).Round(time.Second))
+	}
+	e.Message = sb.String()
+	err := n.Send(ctx, e)
+	if err != nil {
+		app.Log.Error("can't send the notification: " + err.Error())
+	}
+}
@@ -0,0 +1,87 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/notify"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icFailingUploads rejects all uploads
+type icFailingUploads struct {
+	icCatchUploadsAssets
+}
+
+func (c *icFailingUploads) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	return immich.AssetResponse{}, errors.New("server error")
+}
+
+func TestNotifications(t *testing.T) {
+	tests := []struct {
+		name   string
+		ic     immich.ImmichInterface
+		args   []string
+		events []string
+		errors int64
+	}{
+		{name: "completed", ic: &icCatchUploadsAssets{albums: map[string][]string{}}, events: []string{notify.EventCompleted}},
+		{name: "errors", ic: &icFailingUploads{icCatchUploadsAssets{albums: map[string][]string{}}}, args: []string{"-notify-errors=2"}, events: []string{notify.EventErrors, notify.EventCompleted}, errors: 2},
+		{name: "below threshold", ic: &icFailingUploads{icCatchUploadsAssets{albums: map[string][]string{}}}, args: []string{"-notify-errors=3"}, events: []string{notify.EventCompleted}, errors: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var got []notify.Event
+			server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+				var e notify.Event
+				if err := json.NewDecoder(req.Body).Decode(&e); err != nil {
+					t.Error(err)
+				}
+				lock.Lock()
+				got = append(got, e)
+				lock.Unlock()
+			}))
+			defer server.Close()
+
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: tt.ic,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			args := append([]string{"-no-ui", "-notify-url=" + server.URL}, tt.args...)
+			args = append(args, "TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg")
+			err := UploadCommand(context.Background(), &serv, args)
+			if (err != nil) != (tt.errors > 0) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var events []string
+			for _, e := range got {
+				events = append(events, e.Event)
+			}
+			if !reflect.DeepEqual(events, tt.events) {
+				t.Fatalf("expected events %v, got %v", tt.events, events)
+			}
+			last := got[len(got)-1]
+			if last.Errors != tt.errors || last.Command != "upload" || last.Message == "" {
+				t.Errorf("unexpected event: %+v", last)
+			}
+			if tt.errors == 0 && last.Counts["uploaded"] != 2 {
+				t.Errorf("unexpected counts: %v", last.Counts)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package upload
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strings"
 	"sync/atomic"
@@ -12,6 +11,9 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// progressLogPeriod is the period of the progress messages written in the log
+const progressLogPeriod = time.Minute
+
 func (app *UpCmd) runNoUI(ctx context.Context) error {
 	ctx, cancel := context.WithCancelCause(ctx)
 	defer cancel(nil)
@@ -29,6 +31,10 @@ func (app *UpCmd) runNoUI(ctx context.Context) error {
 
 	progressString := func() string {
 		counts := app.Jnl.GetCounts()
+		paused := ""
+		if app.pause != nil && app.pause.Paused() {
+			paused = " (paused)"
+		}
 		defer func() {
 			spinIdx++
 			if spinIdx == len(spinner) {
@@ -54,30 +60,55 @@ func (app *UpCmd) runNoUI(ctx context.Context) error {
 			upTotal := app.Jnl.TotalAssets()
 			upPercent := 100 * upProcessed / upTotal
 
-			return fmt.Sprintf("\rImmich read %d%%, Assets found: %d, Google Photos Analysis: %d%%, Upload errors: %d, Uploaded %d%% %s",
-				immichPct, app.Jnl.TotalAssets(), gpPercent, counts[fileevent.UploadServerError], upPercent, string(spinner[spinIdx]))
+			return fmt.Sprintf("\rImmich read %d%%, Assets found: %d, Google Photos Analysis: %d%%, Upload errors: %d, Uploaded %d%%, %s%s %s",
+				immichPct, app.Jnl.TotalAssets(), gpPercent, counts[fileevent.UploadServerError], upPercent, app.progressStats(), paused, string(spinner[spinIdx]))
 		}
 
-		return fmt.Sprintf("\rImmich read %d%%, Assets found: %d, Upload errors: %d, Uploaded %d %s", immichPct, app.Jnl.TotalAssets(), counts[fileevent.UploadServerError], counts[fileevent.Uploaded], string(spinner[spinIdx]))
+		return fmt.Sprintf("\rImmich read %d%%, Assets found: %d, Upload errors: %d, Uploaded %d, %s%s %s", immichPct, app.Jnl.TotalAssets(), counts[fileevent.UploadServerError], counts[fileevent.Uploaded], app.progressStats(), paused, string(spinner[spinIdx]))
 	}
 	uiGrp := errgroup.Group{}
 
+	// the progress line would be mixed with the decisions of the verbose mode
+	showProgress := !app.Quiet && !app.Verbose
+
+	printProgress := func() {
+		if !showProgress {
+			return
+		}
+		app.console.Lock()
+		defer app.console.Unlock()
+		fmt.Print(progressString())
+	}
+
+	// the progress is also logged periodically, for the runs without a terminal
+	logProgress := func() {
+		s := app.progressStats()
+		app.Log.Info("progress", "stage", s.Stage, "found", app.Jnl.TotalAssets(), "processed", app.Jnl.TotalProcessed(app.ForceUploadWhenNoJSON),
+			"throughput", formatBytes(int(s.BytesPerSecond))+"/s", "files/min", fmt.Sprintf("%.0f", s.FilesPerMinute), "ETA", s.eta())
+	}
+
 	uiGrp.Go(func() error {
 		ticker := time.NewTicker(500 * time.Millisecond)
+		logTicker := time.NewTicker(progressLogPeriod)
 		defer func() {
 			ticker.Stop()
-			fmt.Println(progressString())
+			logTicker.Stop()
+			if showProgress {
+				fmt.Println(progressString())
+			}
 		}()
 		for {
 			select {
 			case <-stopProgress:
-				fmt.Print(progressString())
+				printProgress()
 				return nil
 			case <-ctx.Done():
-				fmt.Print(progressString())
+				printProgress()
 				return ctx.Err()
 			case <-ticker.C:
-				fmt.Print(progressString())
+				printProgress()
+			case <-logTicker.C:
+				logProgress()
 			}
 		}
 	})
@@ -113,6 +144,7 @@ func (app *UpCmd) runNoUI(ctx context.Context) error {
 			}
 		}
 		preparationDone.Store(true)
+		app.progress.startUpload()
 		err = app.uploadLoop(ctx)
 		if err != nil {
 			cancel(err)
@@ -129,7 +161,7 @@ func (app *UpCmd) runNoUI(ctx context.Context) error {
 			messages.WriteString("- Request another takeout, either for one year at a time or in smaller increments.\n")
 		}
 		if messages.Len() > 0 {
-			cancel(errors.New(messages.String()))
+			cancel(runSummary(messages.String()))
 		}
 		close(stopProgress)
 		return err
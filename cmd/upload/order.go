@@ -0,0 +1,90 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+// Upload orders accepted by the -order option
+const (
+	OrderNone        = ""             // assets are uploaded as they are discovered
+	OrderOldestFirst = "oldest-first" // assets are sorted by date of capture, the oldest first
+	OrderNewestFirst = "newest-first" // assets are sorted by date of capture, the newest first
+	OrderPath        = "path"         // assets are sorted by their path
+)
+
+func validateOrder(order string) (string, error) {
+	order = strings.ToLower(order)
+	switch order {
+	case OrderNone, OrderOldestFirst, OrderNewestFirst, OrderPath:
+		return order, nil
+	}
+	return "", fmt.Errorf("the -order accepts %s, %s or %s", OrderOldestFirst, OrderNewestFirst, OrderPath)
+}
+
+// sortAssets sorts the assets in place accordingly to the given order.
+// The sort is stable: assets with the same date keep the browsing order.
+// Assets without date of capture are placed at the end of the list.
+func sortAssets(assets []*browser.LocalAssetFile, order string) {
+	switch order {
+	case OrderOldestFirst, OrderNewestFirst:
+		slices.SortStableFunc(assets, func(a, b *browser.LocalAssetFile) int {
+			da, db := a.Metadata.DateTaken, b.Metadata.DateTaken
+			switch {
+			case da.IsZero() && db.IsZero():
+				return 0
+			case da.IsZero():
+				return 1
+			case db.IsZero():
+				return -1
+			}
+			if order == OrderNewestFirst {
+				return db.Compare(da)
+			}
+			return da.Compare(db)
+		})
+	case OrderPath:
+		slices.SortStableFunc(assets, func(a, b *browser.LocalAssetFile) int {
+			return strings.Compare(a.FileName, b.FileName)
+		})
+	}
+}
+
+// orderAssets collects all assets coming from the browser, sorts them and
+// sends them back in the requested order. The dates are corrected by fixDate before sorting.
+//
+// Files opened during the browsing are closed while waiting the end of the browsing
+// to avoid keeping too many file descriptors open. They are reopened when uploaded.
+func orderAssets(ctx context.Context, in chan *browser.LocalAssetFile, order string, fixDate func(a *browser.LocalAssetFile)) chan *browser.LocalAssetFile {
+	if order == OrderNone {
+		return in
+	}
+	out := make(chan *browser.LocalAssetFile)
+	go func() {
+		defer close(out)
+		var assets []*browser.LocalAssetFile
+		for a := range in {
+			if a.Err == nil && fixDate != nil && order != OrderPath {
+				fixDate(a)
+			}
+			a.Close()
+			if a.LivePhoto != nil {
+				a.LivePhoto.Close()
+			}
+			assets = append(assets, a)
+		}
+		sortAssets(assets, order)
+		for _, a := range assets {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- a:
+			}
+		}
+	}()
+	return out
+}
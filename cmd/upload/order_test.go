@@ -0,0 +1,92 @@
+package upload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestSortAssets(t *testing.T) {
+	mk := func(name string, date string) *browser.LocalAssetFile {
+		a := &browser.LocalAssetFile{FileName: name}
+		if date != "" {
+			a.Metadata.DateTaken, _ = time.Parse(time.DateOnly, date)
+		}
+		return a
+	}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{OrderNone, []string{"b/2.jpg", "a/3.jpg", "c/nodate.jpg", "a/1.jpg", "b/4.jpg"}},
+		{OrderOldestFirst, []string{"a/1.jpg", "b/2.jpg", "a/3.jpg", "b/4.jpg", "c/nodate.jpg"}},
+		{OrderNewestFirst, []string{"b/4.jpg", "a/3.jpg", "b/2.jpg", "a/1.jpg", "c/nodate.jpg"}},
+		{OrderPath, []string{"a/1.jpg", "a/3.jpg", "b/2.jpg", "b/4.jpg", "c/nodate.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			in := make(chan *browser.LocalAssetFile)
+			go func() {
+				in <- mk("b/2.jpg", "2022-02-01")
+				in <- mk("a/3.jpg", "2023-03-01")
+				in <- mk("c/nodate.jpg", "")
+				in <- mk("a/1.jpg", "2021-01-01")
+				in <- mk("b/4.jpg", "2024-04-01")
+				close(in)
+			}()
+			got := []string{}
+			for a := range orderAssets(context.Background(), in, tt.order, nil) {
+				got = append(got, a.FileName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	for _, o := range []string{"", "Oldest-First", "newest-first", "PATH"} {
+		if _, err := validateOrder(o); err != nil {
+			t.Errorf("unexpected error for %q: %s", o, err)
+		}
+	}
+	if _, err := validateOrder("random"); err == nil {
+		t.Errorf("expecting an error")
+	}
+}
+
+// the assets are ordered by their corrected date
+func TestOrderCorrectedDates(t *testing.T) {
+	app := &UpCmd{SharedFlags: &cmd.SharedFlags{Jnl: fileevent.NewRecorder(nil, false)}}
+	if err := app.TimeShifts.Set("folder:**/camera=-48h"); err != nil {
+		t.Fatal(err)
+	}
+	in := make(chan *browser.LocalAssetFile)
+	go func() {
+		for name, date := range map[string]time.Time{
+			"phone/1.jpg":  time.Date(2023, 10, 6, 8, 0, 0, 0, time.UTC),
+			"camera/2.jpg": time.Date(2023, 10, 7, 8, 0, 0, 0, time.UTC), // taken the 5th
+		} {
+			in <- &browser.LocalAssetFile{FileName: name, Metadata: metadata.Metadata{DateTaken: date}}
+		}
+		close(in)
+	}()
+	got := []string{}
+	for a := range orderAssets(context.Background(), in, OrderOldestFirst, func(a *browser.LocalAssetFile) { app.fixDate(context.Background(), a) }) {
+		// the selection doesn't shift the date again
+		app.fixDate(context.Background(), a)
+		got = append(got, a.FileName+" "+a.Metadata.DateTaken.Format(time.DateOnly))
+	}
+	want := []string{"camera/2.jpg 2023-10-05", "phone/1.jpg 2023-10-06"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
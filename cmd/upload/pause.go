@@ -0,0 +1,84 @@
+package upload
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate holds the upload loop while the upload is paused.
+//
+// The pause takes effect between two assets: the transfer in progress
+// is completed before the loop stops.
+type pauseGate struct {
+	lock   sync.Mutex
+	paused bool
+	resume chan struct{} // closed when the upload is resumed
+}
+
+func newPauseGate() *pauseGate {
+	return &pauseGate{}
+}
+
+// Toggle pauses a running upload or resumes a paused one.
+// It returns true when the upload is paused.
+func (p *pauseGate) Toggle() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused {
+		p.resumeLocked()
+	} else {
+		p.pauseLocked()
+	}
+	return p.paused
+}
+
+func (p *pauseGate) Pause() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.paused {
+		p.pauseLocked()
+	}
+}
+
+func (p *pauseGate) Resume() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused {
+		p.resumeLocked()
+	}
+}
+
+func (p *pauseGate) pauseLocked() {
+	p.paused = true
+	p.resume = make(chan struct{})
+}
+
+func (p *pauseGate) resumeLocked() {
+	p.paused = false
+	close(p.resume)
+}
+
+func (p *pauseGate) Paused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused
+}
+
+// Wait returns immediately when the upload isn't paused,
+// otherwise it blocks until the upload is resumed or the context is cancelled.
+func (p *pauseGate) Wait(ctx context.Context) error {
+	p.lock.Lock()
+	if !p.paused {
+		p.lock.Unlock()
+		return nil
+	}
+	resume := p.resume
+	p.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resume:
+		return nil
+	}
+}
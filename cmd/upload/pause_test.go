@@ -0,0 +1,55 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPauseGate(t *testing.T) {
+	ctx := context.Background()
+	p := newPauseGate()
+
+	if err := p.Wait(ctx); err != nil {
+		t.Fatalf("Wait on a running gate: %s", err)
+	}
+
+	if !p.Toggle() {
+		t.Fatalf("Toggle should pause the gate")
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- p.Wait(ctx)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Wait should block while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if p.Toggle() {
+		t.Fatalf("Toggle should resume the gate")
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Wait should return once resumed")
+	}
+
+	p.Pause()
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := p.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait should return the context error, got %v", err)
+	}
+	p.Resume()
+	if p.Paused() {
+		t.Fatalf("the gate should be running")
+	}
+}
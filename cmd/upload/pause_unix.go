@@ -0,0 +1,30 @@
+//go:build !windows
+
+package upload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// pauseSignalName is the name of the signal that toggles the pause
+const pauseSignalName = "SIGUSR1"
+
+// notifyPauseSignal calls fn each time the process receives the SIGUSR1 signal
+func notifyPauseSignal(ctx context.Context, fn func()) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(c)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c:
+				fn()
+			}
+		}
+	}()
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package upload
+
+import "context"
+
+// pauseSignalName is empty: windows doesn't have user signals
+const pauseSignalName = ""
+
+// notifyPauseSignal does nothing on windows. Use the key [p] of the user interface instead.
+func notifyPauseSignal(ctx context.Context, fn func()) {}
@@ -0,0 +1,131 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+	"golang.org/x/term"
+)
+
+// errUploadCancelled is returned when the user doesn't confirm the upload plan
+var errUploadCancelled = errors.New("the upload has been cancelled")
+
+// uploadPlan summarizes what the upload is about to do
+type uploadPlan struct {
+	servers        []string // the servers receiving the assets
+	images, videos int      // number of assets found, by type
+	size           int64    // size of the assets
+	newAlbums      []string // albums created on the server
+	existingAlbums int      // albums of the server receiving assets
+}
+
+// newUploadPlan counts the assets kept for the upload, and the albums they go into
+func (app *UpCmd) newUploadPlan(assets []*browser.LocalAssetFile, keep func(a *browser.LocalAssetFile) bool) uploadPlan {
+	var p uploadPlan
+	if len(app.Targets) > 1 {
+		for _, t := range app.Targets {
+			p.servers = append(p.servers, fmt.Sprintf("%s (profile %s)", targetServer(t.Server, t.API), t.Name))
+		}
+	} else {
+		p.servers = []string{targetServer(app.Server, app.API)}
+	}
+
+	sm := app.supportedMedia()
+	albums := map[string]bool{}
+	for _, a := range assets {
+		if a.Err != nil || !keep(a) {
+			continue
+		}
+		switch sm.TypeFromExt(a.Ext()) {
+		case immich.TypeVideo:
+			p.videos++
+		default:
+			p.images++
+		}
+		p.size += int64(a.FileSize)
+		if a.LivePhoto != nil {
+			p.videos++
+			p.size += int64(a.LivePhoto.FileSize)
+		}
+		targets, _ := app.albumTargets(a)
+		for _, t := range targets {
+			albums[t.album.Title] = true
+		}
+	}
+	for title := range albums {
+		if _, ok := app.albums.get(title); ok {
+			p.existingAlbums++
+		} else {
+			p.newAlbums = append(p.newAlbums, title)
+		}
+	}
+	sort.Strings(p.newAlbums)
+	return p
+}
+
+func targetServer(server, api string) string {
+	if server == "" {
+		return api
+	}
+	return server
+}
+
+// maxPlanAlbums is the number of new album names written in the plan
+const maxPlanAlbums = 5
+
+func (p uploadPlan) write(w io.Writer) {
+	fmt.Fprintln(w, "Upload plan:")
+	fmt.Fprintf(w, "  Server:  %s\n", strings.Join(p.servers, ", "))
+	fmt.Fprintf(w, "  Assets:  %d image(s), %d video(s), %s\n", p.images, p.videos, formatBytes(int(p.size)))
+	albums := fmt.Sprintf("%d new album(s), %d existing album(s)", len(p.newAlbums), p.existingAlbums)
+	if len(p.newAlbums) > 0 {
+		names := p.newAlbums
+		more := ""
+		if len(names) > maxPlanAlbums {
+			names, more = names[:maxPlanAlbums], fmt.Sprintf(" and %d more", len(p.newAlbums)-maxPlanAlbums)
+		}
+		albums += fmt.Sprintf(": %s%s", strings.Join(names, ", "), more)
+	}
+	fmt.Fprintf(w, "  Albums:  %s\n", albums)
+}
+
+// confirmPlan prints the upload plan and asks the user to confirm it
+func (app *UpCmd) confirmPlan(ctx context.Context, assets []*browser.LocalAssetFile, keep func(a *browser.LocalAssetFile) bool) error {
+	// the albums already on the server aren't created
+	err := app.getImmichAlbums(ctx)
+	if err != nil {
+		return err
+	}
+	p := app.newUploadPlan(assets, keep)
+	p.write(app.stdout)
+	app.Log.Info("upload plan", "images", p.images, "videos", p.videos, "size", formatBytes(int(p.size)), "new albums", len(p.newAlbums))
+
+	if app.askReader == nil {
+		app.askReader = bufio.NewReader(app.stdin)
+	}
+	fmt.Fprint(app.stdout, "Proceed with the upload? [y/N]: ")
+	answer, err := app.askReader.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && answer != "") {
+		fmt.Fprintln(app.stdout)
+		return errUploadCancelled
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errUploadCancelled
+}
+
+// isTerminal tells if the reader is a terminal
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
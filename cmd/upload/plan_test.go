@@ -0,0 +1,96 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestConfirmPlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		answer    string
+		wantErr   error
+		uploaded  int
+		wantLines []string
+	}{
+		{
+			name:     "confirmed",
+			args:     []string{"-no-ui", "-create-album-folder", "TEST_DATA/folder/high"},
+			answer:   "y\n",
+			uploaded: 8,
+			wantLines: []string{
+				"  Server:  http://nas:2283",
+				"  Assets:  8 image(s), 0 video(s), ",
+				"  Albums:  2 new album(s), 0 existing album(s): AlbumA, AlbumB",
+			},
+		},
+		{
+			name:    "refused",
+			args:    []string{"-no-ui", "TEST_DATA/folder/high"},
+			answer:  "n\n",
+			wantErr: errUploadCancelled,
+		},
+		{
+			name:    "no answer",
+			args:    []string{"-no-ui", "TEST_DATA/folder/high"},
+			wantErr: errUploadCancelled,
+		},
+		{
+			name:     "after the selection",
+			args:     []string{"-no-ui", "-select", "TEST_DATA/folder/high"},
+			answer:   "yes\n",
+			uploaded: 5,
+			wantLines: []string{
+				"  Assets:  5 image(s), 0 video(s), ",
+				"  Albums:  0 new album(s), 0 existing album(s)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchUploadsAssets{albums: map[string][]string{}}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: ic,
+				Server: "http://nas:2283",
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			ctx := context.Background()
+			app, err := newCommand(ctx, &serv, tt.args, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if app.confirm {
+				t.Error("the plan shouldn't be confirmed without terminal")
+			}
+			var out bytes.Buffer
+			app.confirm, app.stdin, app.stdout = true, strings.NewReader(tt.answer), &out
+			app.chooseAssets = func(ctx context.Context, tree *selectionTree) error {
+				tree.folders.byName["AlbumB"].toggle()
+				return nil
+			}
+
+			err = app.run(ctx)
+			if err != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(ic.assets) != tt.uploaded {
+				t.Errorf("expected %d uploads, got %d", tt.uploaded, len(ic.assets))
+			}
+			for _, l := range tt.wantLines {
+				if !strings.Contains(out.String(), l) {
+					t.Errorf("the plan should contain %q:\n%s", l, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strconv"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/metacache"
+	"github.com/simulot/immich-go/immich"
+	"golang.org/x/sync/errgroup"
+)
+
+// bulkCheckSize is the number of assets checked with one request
+const bulkCheckSize = 500
+
+// bulkCheckAssets computes the checksums of the assets by batches, and asks the server which ones it already has.
+// The checksums of the assets present on the server are kept in app.bulkDuplicates with the server's asset ID.
+// The assets are emitted in the same order.
+//
+// The batches are shorter when the memory exceeds the budget given by -max-memory.
+//
+// When the check fails, the assets are emitted unchanged and the upload falls back to the usual comparisons.
+func (app *UpCmd) bulkCheckAssets(ctx context.Context, in chan *browser.LocalAssetFile) chan *browser.LocalAssetFile {
+	out := make(chan *browser.LocalAssetFile)
+	go func() {
+		defer close(out)
+		batch := make([]*browser.LocalAssetFile, 0, bulkCheckSize)
+
+		flush := func() bool {
+			app.bulkCheck(ctx, batch)
+			for _, a := range batch {
+				select {
+				case out <- a:
+				case <-ctx.Done():
+					a.Close()
+					continue
+				}
+			}
+			batch = batch[:0]
+			return ctx.Err() == nil
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				for _, a := range batch {
+					a.Close()
+				}
+				return
+			case a, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, a)
+				// the batch is checked early when the memory exceeds the budget
+				if (len(batch) == bulkCheckSize || app.memory.Over()) && !flush() {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// bulkCheck computes the checksums of a batch of assets and submits them to the server
+func (app *UpCmd) bulkCheck(ctx context.Context, batch []*browser.LocalAssetFile) {
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(app.ReadWorkers)
+	for _, a := range batch {
+		if a.Err != nil {
+			continue
+		}
+		g.Go(func() error {
+			if gCtx.Err() != nil {
+				return gCtx.Err()
+			}
+			if app.cachedChecksum(a) || app.storedChecksum(a) {
+				return nil
+			}
+			err := a.ComputeChecksum()
+			if err != nil {
+				app.Log.Error(fmt.Sprintf("can't compute the checksum of %s: %s", a.FileName, err))
+				return nil
+			}
+			app.keepChecksum(a)
+			app.storeChecksum(a)
+			app.progress.hashed(a.Size())
+			return nil
+		})
+	}
+	if g.Wait() != nil {
+		return
+	}
+
+	items := []immich.AssetBulkUploadCheckItem{}
+	for i, a := range batch {
+		if a.Checksum == "" {
+			continue
+		}
+		items = append(items, immich.AssetBulkUploadCheckItem{ID: strconv.Itoa(i), Checksum: a.Checksum})
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	results, err := app.Immich.CheckBulkUpload(ctx, items)
+	if err != nil {
+		app.Log.Error(fmt.Sprintf("can't check the assets on the server: %s", err))
+		return
+	}
+	for _, r := range results {
+		if r.Action != immich.BulkCheckReject || r.Reason != immich.BulkCheckReasonDuplicate || r.AssetID == "" {
+			continue
+		}
+		i, err := strconv.Atoi(r.ID)
+		if err != nil || i < 0 || i >= len(batch) {
+			continue
+		}
+		app.bulkDuplicates.Store(batch[i].Checksum, r.AssetID)
+	}
+}
+
+// storedChecksum sets the checksum of the asset when the metadata cache has it for this version of the file
+func (app *UpCmd) storedChecksum(a *browser.LocalAssetFile) bool {
+	if app.metaCache == nil {
+		return false
+	}
+	i, err := fs.Stat(a.FSys, a.FileName)
+	if err != nil {
+		return false
+	}
+	c, ok := app.metaCache.GetChecksum(metacache.Key(a.FSys, a.FileName), i.Size(), i.ModTime())
+	if ok {
+		a.Checksum = c
+		app.keepChecksum(a)
+	}
+	return ok
+}
+
+// storeChecksum keeps the checksum of the asset in the metadata cache, for the next runs
+func (app *UpCmd) storeChecksum(a *browser.LocalAssetFile) {
+	if app.metaCache == nil || a.Checksum == "" {
+		return
+	}
+	i, err := fs.Stat(a.FSys, a.FileName)
+	if err != nil {
+		return
+	}
+	app.metaCache.PutChecksum(metacache.Key(a.FSys, a.FileName), i.Size(), i.ModTime(), a.Checksum)
+}
+
+// uploadedChecksum keeps the checksum computed while the asset was uploaded, for the next servers and the next runs
+func (app *UpCmd) uploadedChecksum(a *browser.LocalAssetFile) {
+	app.keepChecksum(a)
+	app.storeChecksum(a)
+}
@@ -0,0 +1,186 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/metacache"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icBulkCheck simulates a server having some files, identified by their checksums
+type icBulkCheck struct {
+	icCatchUploadsAssets
+
+	onServer map[string]string // server's asset ID by checksum
+	checked  int
+	err      error
+}
+
+func (c *icBulkCheck) CheckBulkUpload(ctx context.Context, items []immich.AssetBulkUploadCheckItem) ([]immich.AssetBulkUploadCheckResult, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	r := []immich.AssetBulkUploadCheckResult{}
+	for _, i := range items {
+		c.checked++
+		if id, ok := c.onServer[i.Checksum]; ok {
+			r = append(r, immich.AssetBulkUploadCheckResult{ID: i.ID, Action: immich.BulkCheckReject, Reason: immich.BulkCheckReasonDuplicate, AssetID: id})
+			continue
+		}
+		r = append(r, immich.AssetBulkUploadCheckResult{ID: i.ID, Action: immich.BulkCheckAccept})
+	}
+	return r, nil
+}
+
+func fileChecksum(t *testing.T, name string) string {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha1.Sum(b)
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func TestBulkCheck(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedAssets []string
+		expectedCheck  int
+	}{
+		{
+			name: "duplicates skipped",
+			expectedAssets: []string{
+				"PXL_20231006_063029647.jpg",
+				"PXL_20231006_063108407.jpg",
+				"PXL_20231006_063121958.jpg",
+			},
+			expectedCheck: 5,
+		},
+		{
+			name: "server error",
+			err:  errors.New("server error"),
+			expectedAssets: []string{
+				"PXL_20231006_063000139.jpg",
+				"PXL_20231006_063029647.jpg",
+				"PXL_20231006_063108407.jpg",
+				"PXL_20231006_063121958.jpg",
+				"PXL_20231006_063357420.jpg",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ic := &icBulkCheck{
+				icCatchUploadsAssets: icCatchUploadsAssets{
+					albums: map[string][]string{},
+				},
+				onServer: map[string]string{
+					fileChecksum(t, "TEST_DATA/folder/high/AlbumA/PXL_20231006_063000139.jpg"): "server1",
+					fileChecksum(t, "TEST_DATA/folder/high/AlbumA/PXL_20231006_063357420.jpg"): "server2",
+				},
+				err: tc.err,
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: ic,
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-bulk-check", "TEST_DATA/folder/high/AlbumA"})
+			if err != nil && cmd.ExitCode(err) != cmd.ExitServerDuplicates {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			if !cmpSlices(tc.expectedAssets, ic.assets) {
+				t.Errorf("expected upload differs ")
+				pretty.Ldiff(t, tc.expectedAssets, ic.assets)
+			}
+			if ic.checked != tc.expectedCheck {
+				t.Errorf("expected %d checked files, got %d", tc.expectedCheck, ic.checked)
+			}
+			if tc.err == nil {
+				counts := serv.Jnl.GetCounts()
+				if counts[fileevent.UploadServerDuplicate] != 2 {
+					t.Errorf("expected 2 server duplicates, got %d", counts[fileevent.UploadServerDuplicate])
+				}
+			}
+		})
+	}
+}
+
+// TestBulkCheckFromCache checks that the checksums kept in the metadata cache are used by the bulk check,
+// and that the computed ones are kept for the next runs
+func TestBulkCheckFromCache(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "metadata.jsonl")
+	folder := "TEST_DATA/folder/high/AlbumA"
+	cached := filepath.Join(folder, "PXL_20231006_063000139.jpg")
+	key, err := filepath.Abs(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key = filepath.ToSlash(key)
+	i, err := os.Stat(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := metacache.Open(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a checksum that can't be computed from the file
+	c.PutChecksum(key, i.Size(), i.ModTime(), "cached-checksum")
+	err = c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic := &icBulkCheck{
+		icCatchUploadsAssets: icCatchUploadsAssets{
+			albums: map[string][]string{},
+		},
+		onServer: map[string]string{"cached-checksum": "server1"},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err = UploadCommand(context.Background(), &serv, []string{"-no-ui", "-bulk-check", "-metadata-cache", "-metadata-cache-file=" + cacheFile, folder})
+	if err != nil && cmd.ExitCode(err) != cmd.ExitServerDuplicates {
+		t.Fatal(err)
+	}
+	for _, a := range ic.assets {
+		if a == path.Base(cached) {
+			t.Errorf("%s is uploaded, the cached checksum isn't used", a)
+		}
+	}
+
+	c, err = metacache.Open(cacheFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(folder, "PXL_20231006_063029647.jpg")
+	key, _ = filepath.Abs(other)
+	i, err = os.Stat(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, ok := c.GetChecksum(filepath.ToSlash(key), i.Size(), i.ModTime()); !ok || sum != fileChecksum(t, other) {
+		t.Errorf("the checksum of %s isn't kept in the cache: %q", other, sum)
+	}
+}
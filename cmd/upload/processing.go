@@ -0,0 +1,161 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// eventListener is a client receiving the events of the server
+type eventListener interface {
+	ListenEvents(ctx context.Context, fn func(immich.ServerEvent)) error
+}
+
+// processingPoll is the delay between two readings of the server's jobs
+const processingPoll = 2 * time.Second
+
+// processingWatch follows the processing of the uploaded assets by the server:
+// the thumbnails of each asset are announced by the websocket, and the jobs queues give the backlog.
+type processingWatch struct {
+	lock      sync.Mutex
+	uploaded  map[string]bool // assets uploaded during the run
+	processed map[string]bool // assets whose thumbnails are generated
+	listening bool            // the events of the server are received
+	stop      func()          // stops listening
+}
+
+func newProcessingWatch() *processingWatch {
+	return &processingWatch{uploaded: map[string]bool{}, processed: map[string]bool{}}
+}
+
+// add counts the asset uploaded
+func (w *processingWatch) add(id string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.uploaded[id] = true
+}
+
+// event handles the event sent by the server
+func (w *processingWatch) event(e immich.ServerEvent) {
+	if e.Name != immich.EventUploadSuccess {
+		return
+	}
+	var a struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(e.Data, &a) != nil || a.ID == "" {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.processed[a.ID] = true
+}
+
+// counts gives the number of uploaded assets, and how many of them are processed
+func (w *processingWatch) counts() (uploaded int, processed int, listening bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for id := range w.uploaded {
+		if w.processed[id] {
+			processed++
+		}
+	}
+	return len(w.uploaded), processed, w.listening
+}
+
+func (w *processingWatch) setListening(l bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.listening = l
+}
+
+// startProcessingWatch listens to the server's events during the upload, so no asset processed early is missed.
+// The listening ends with the stop function of the watch.
+func (app *UpCmd) startProcessingWatch(ctx context.Context) {
+	w := newProcessingWatch()
+	app.processing = w
+	ctx, w.stop = context.WithCancel(ctx)
+	l, ok := app.Immich.(eventListener)
+	if !ok {
+		return
+	}
+	w.setListening(true)
+	go func() {
+		err := l.ListenEvents(ctx, w.event)
+		if err != nil && ctx.Err() == nil {
+			app.Log.Warn("can't follow the processing of the assets with the server's events, only the server's jobs are watched: " + err.Error())
+		}
+		w.setListening(false)
+	}()
+}
+
+// waitProcessing reports the backlog of the server until the uploaded assets are processed
+func (app *UpCmd) waitProcessing(ctx context.Context) error {
+	uploaded, _, _ := app.processing.counts()
+	if uploaded == 0 {
+		return nil
+	}
+	app.Log.Info("Waiting for the server to process the uploaded assets...")
+	poll := app.processingPoll
+	if poll == 0 {
+		poll = processingPoll
+	}
+	idle := 0
+	last := ""
+	for {
+		pending := 0
+		jobs, err := app.Immich.GetJobs(ctx)
+		if err == nil {
+			for _, j := range jobs {
+				pending += j.JobCounts.Active + j.JobCounts.Waiting + j.JobCounts.Delayed
+			}
+		}
+		uploaded, processed, listening := app.processing.counts()
+
+		if err != nil && !listening {
+			return fmt.Errorf("can't follow the processing of the assets: %w", err)
+		}
+
+		var msg string
+		switch {
+		case !listening:
+			msg = fmt.Sprintf("Server processing: %d job(s) pending", pending)
+		case err != nil:
+			// only the administrators read the jobs
+			msg = fmt.Sprintf("Server processing: %d of %d uploaded asset(s) with their thumbnails", processed, uploaded)
+		default:
+			msg = fmt.Sprintf("Server processing: %d of %d uploaded asset(s) with their thumbnails, %d job(s) pending", processed, uploaded, pending)
+		}
+		if msg != last {
+			app.Log.Info(msg)
+			if !app.Quiet {
+				fmt.Fprintln(app.stdout, msg)
+			}
+			last = msg
+		}
+
+		if err == nil && pending == 0 {
+			idle++
+		} else {
+			idle = 0
+		}
+		// the queues may be empty for a moment between two jobs of an asset
+		if (listening && processed >= uploaded && (err != nil || idle > 0)) || idle > 1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+	app.Log.Info("The uploaded assets are processed by the server")
+	if !app.Quiet {
+		fmt.Fprintln(app.stdout, "The uploaded assets are processed by the server")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icProcessing sends the thumbnails events of the uploaded assets, while the jobs queues empty
+type icProcessing struct {
+	icCatchUploadsAssets
+	lock  sync.Mutex
+	fn    func(immich.ServerEvent)
+	ready chan struct{}
+	polls int
+}
+
+func (c *icProcessing) ListenEvents(ctx context.Context, fn func(immich.ServerEvent)) error {
+	c.lock.Lock()
+	c.fn = fn
+	c.lock.Unlock()
+	close(c.ready)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (c *icProcessing) GetJobs(ctx context.Context) (map[string]immich.Job, error) {
+	<-c.ready
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.polls++
+	var j immich.Job
+	if c.polls == 1 {
+		for _, id := range c.assets {
+			c.fn(immich.ServerEvent{Name: immich.EventUploadSuccess, Data: []byte(fmt.Sprintf(`{"id":%q}`, id))})
+		}
+		j.JobCounts.Active = 1
+	}
+	return map[string]immich.Job{"thumbnailGeneration": j}, nil
+}
+
+func TestWaitProcessing(t *testing.T) {
+	ic := &icProcessing{icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}}, ready: make(chan struct{})}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	ctx := context.Background()
+	app, err := newCommand(ctx, &serv, []string{"-no-ui", "-wait-processing", "TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBuffer(nil)
+	app.stdout = out
+	app.processingPoll = time.Millisecond
+	err = app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Server processing: 2 of 2 uploaded asset(s) with their thumbnails, 1 job(s) pending",
+		"Server processing: 2 of 2 uploaded asset(s) with their thumbnails, 0 job(s) pending",
+		"The uploaded assets are processed by the server",
+	}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), out.String())
+	}
+}
+
+func TestWaitProcessingOptions(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, args := range [][]string{
+		{"-wait-processing", "-watch", "TEST_DATA/folder/low"},
+		{"-wait-processing", "-offline", "-spool-dir=" + t.TempDir(), "TEST_DATA/folder/low"},
+	} {
+		serv := cmd.SharedFlags{
+			Immich: &icCatchUploadsAssets{albums: map[string][]string{}},
+			Jnl:    fileevent.NewRecorder(log, false),
+			Log:    log,
+		}
+		_, err := newCommand(context.Background(), &serv, args, nil)
+		if err == nil || !strings.Contains(err.Error(), "-wait-processing") {
+			t.Errorf("%v: expected an error about -wait-processing, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package upload
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stages of the upload
+const (
+	stageDiscovery = "discovery" // the input is scanned, and the server's assets are read
+	stageUploading = "uploading" // the files are hashed when -bulk-check is set, and uploaded
+)
+
+// progress measures the throughput of the upload and estimates the remaining time.
+// The methods of a nil progress do nothing.
+type progress struct {
+	started       time.Time
+	uploadStarted atomic.Int64 // unix nano time of the beginning of the upload stage, 0 during the discovery
+	hashedFiles   atomic.Int64
+	hashedBytes   atomic.Int64
+	uploadedFiles atomic.Int64
+	uploadedBytes atomic.Int64
+	throttles     atomic.Int64 // number of calls throttled by the server
+	pausedUntil   atomic.Int64 // unix nano time of the end of the pause asked by the server
+}
+
+func newProgress() *progress {
+	return &progress{started: time.Now()}
+}
+
+// startUpload marks the end of the discovery
+func (p *progress) startUpload() {
+	if p != nil {
+		p.uploadStarted.CompareAndSwap(0, time.Now().UnixNano())
+	}
+}
+
+// hashed counts a file hashed by the bulk check
+func (p *progress) hashed(size int64) {
+	if p != nil {
+		p.hashedFiles.Add(1)
+		p.hashedBytes.Add(size)
+	}
+}
+
+// uploaded counts a file sent to the server
+func (p *progress) uploaded(size int64) {
+	if p != nil {
+		p.uploadedFiles.Add(1)
+		p.uploadedBytes.Add(size)
+	}
+}
+
+// throttled records a pause asked by the server
+func (p *progress) throttled(until time.Time) {
+	if p == nil {
+		return
+	}
+	p.throttles.Add(1)
+	u := until.UnixNano()
+	for {
+		old := p.pausedUntil.Load()
+		if old >= u || p.pausedUntil.CompareAndSwap(old, u) {
+			return
+		}
+	}
+}
+
+// throttleNotifier is a client telling when the server asks to slow down
+type throttleNotifier interface {
+	SetThrottleHandler(fn func(endPoint string, wait time.Duration))
+}
+
+// watchThrottle shows the pauses asked by the server in the progress
+func (app *UpCmd) watchThrottle() {
+	n, ok := app.Immich.(throttleNotifier)
+	if !ok {
+		return
+	}
+	n.SetThrottleHandler(func(endPoint string, wait time.Duration) {
+		app.progress.throttled(time.Now().Add(wait))
+		app.Log.Warn("the server asks to slow down", "endpoint", endPoint, "wait", wait.String())
+	})
+}
+
+// progressStats gives the progress of the current run
+func (app *UpCmd) progressStats() progressStats {
+	return app.progress.stats(app.Jnl.TotalProcessed(app.ForceUploadWhenNoJSON), app.Jnl.TotalAssets(), time.Now())
+}
+
+// progressStats is a snapshot of the progress
+type progressStats struct {
+	Stage          string
+	Discovery      time.Duration // duration of the discovery stage
+	Uploading      time.Duration // duration of the upload stage
+	HashedFiles    int64
+	HashedBytes    int64
+	UploadedBytes  int64
+	BytesPerSecond float64       // upload throughput
+	FilesPerMinute float64       // files processed by minute, uploaded or not
+	Remaining      time.Duration // estimated remaining time, -1 when unknown
+	Throttles      int64         // number of calls throttled by the server
+	Throttled      time.Duration // remaining pause asked by the server
+}
+
+// stats gives the snapshot of the progress, processed being the number of files handled by the upload stage among total
+func (p *progress) stats(processed, total int64, now time.Time) progressStats {
+	s := progressStats{Stage: stageDiscovery, Remaining: -1}
+	if p == nil {
+		return s
+	}
+	s.HashedFiles, s.HashedBytes, s.UploadedBytes = p.hashedFiles.Load(), p.hashedBytes.Load(), p.uploadedBytes.Load()
+	s.Throttles = p.throttles.Load()
+	if pu := p.pausedUntil.Load(); pu > now.UnixNano() {
+		s.Throttled = time.Unix(0, pu).Sub(now)
+	}
+	us := p.uploadStarted.Load()
+	if us == 0 {
+		s.Discovery = now.Sub(p.started)
+		return s
+	}
+	s.Stage = stageUploading
+	uploadStarted := time.Unix(0, us)
+	s.Discovery = uploadStarted.Sub(p.started)
+	s.Uploading = now.Sub(uploadStarted)
+	if s.Uploading < time.Second {
+		return s
+	}
+	s.BytesPerSecond = float64(s.UploadedBytes) / s.Uploading.Seconds()
+	s.FilesPerMinute = float64(processed) / s.Uploading.Minutes()
+	if processed > 0 && total >= processed {
+		s.Remaining = time.Duration(float64(total-processed) / float64(processed) * float64(s.Uploading)).Round(time.Second)
+	}
+	return s
+}
+
+// eta gives the estimated remaining time, or unknown
+func (s progressStats) eta() string {
+	if s.Remaining < 0 {
+		return "unknown"
+	}
+	return s.Remaining.String()
+}
+
+func (s progressStats) String() string {
+	var sb strings.Builder
+	if s.Stage == stageDiscovery {
+		fmt.Fprintf(&sb, "discovery %s", s.Discovery.Round(time.Second))
+		s.writeThrottle(&sb)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "discovery %s, uploading %s", s.Discovery.Round(time.Second), s.Uploading.Round(time.Second))
+	if s.HashedFiles > 0 {
+		fmt.Fprintf(&sb, ", hashed %d files (%s)", s.HashedFiles, formatBytes(int(s.HashedBytes)))
+	}
+	fmt.Fprintf(&sb, ", %s/s, %.0f files/min", formatBytes(int(s.BytesPerSecond)), s.FilesPerMinute)
+	if s.Remaining >= 0 {
+		fmt.Fprintf(&sb, ", ETA %s", s.Remaining)
+	}
+	s.writeThrottle(&sb)
+	return sb.String()
+}
+
+// writeThrottle tells when the server has asked to slow down
+func (s progressStats) writeThrottle(sb *strings.Builder) {
+	if s.Throttled > 0 {
+		fmt.Fprintf(sb, ", throttled by the server for %s", s.Throttled.Round(time.Second))
+	} else if s.Throttles > 0 {
+		fmt.Fprintf(sb, ", throttled %d times", s.Throttles)
+	}
+}
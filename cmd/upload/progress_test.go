@@ -0,0 +1,48 @@
+package upload
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressStats(t *testing.T) {
+	start := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	p := &progress{started: start}
+	if s := p.stats(0, 0, start.Add(10*time.Second)); s.String() != "discovery 10s" {
+		t.Errorf("unexpected discovery stats: %s", s)
+	}
+
+	p.uploadStarted.Store(start.Add(30 * time.Second).UnixNano())
+	p.hashed(5_000_000)
+	p.uploaded(3_000_000)
+	p.uploaded(3_000_000)
+	s := p.stats(20, 100, start.Add(90*time.Second))
+	if s.Stage != stageUploading || s.Discovery != 30*time.Second || s.Uploading != time.Minute {
+		t.Errorf("unexpected stages: %+v", s)
+	}
+	if s.BytesPerSecond != 100_000 || s.FilesPerMinute != 20 || s.Remaining != 4*time.Minute {
+		t.Errorf("unexpected throughput: %+v", s)
+	}
+	expected := "discovery 30s, uploading 1m0s, hashed 1 files (4.8 MB), 97.7 KB/s, 20 files/min, ETA 4m0s"
+	if s.String() != expected {
+		t.Errorf("expected %q, got %q", expected, s.String())
+	}
+
+	p.throttled(start.Add(100 * time.Second))
+	p.throttled(start.Add(95 * time.Second))
+	s = p.stats(20, 100, start.Add(90*time.Second))
+	if s.Throttles != 2 || s.Throttled != 10*time.Second || !strings.HasSuffix(s.String(), ", throttled by the server for 10s") {
+		t.Errorf("unexpected throttling: %s", s)
+	}
+	if s = p.stats(20, 100, start.Add(110*time.Second)); !strings.HasSuffix(s.String(), ", throttled 2 times") {
+		t.Errorf("unexpected throttling: %s", s)
+	}
+
+	var nilProgress *progress
+	nilProgress.uploaded(10)
+	nilProgress.throttled(start)
+	if s := nilProgress.stats(1, 2, start); s.Remaining != -1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,103 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// RatingThreshold is the minimum number of stars given by the -rating-to-favorite option.
+// It is written N, >=N, >N or =N.
+type RatingThreshold struct {
+	min   int // 0 when not set
+	exact bool
+}
+
+func (rt *RatingThreshold) Set(s string) error {
+	v := strings.TrimSpace(s)
+	r := RatingThreshold{}
+	inc := 0
+	switch {
+	case strings.HasPrefix(v, ">="):
+		v = v[2:]
+	case strings.HasPrefix(v, ">"):
+		v = v[1:]
+		inc = 1
+	case strings.HasPrefix(v, "="):
+		v = v[1:]
+		r.exact = true
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n+inc < 1 || n+inc > 5 {
+		return fmt.Errorf("invalid rating %q, expected a number of stars from 1 to 5, like >=4", s)
+	}
+	r.min = n + inc
+	*rt = r
+	return nil
+}
+
+func (rt RatingThreshold) String() string {
+	switch {
+	case rt.min == 0:
+		return ""
+	case rt.exact:
+		return "=" + strconv.Itoa(rt.min)
+	}
+	return ">=" + strconv.Itoa(rt.min)
+}
+
+func (rt RatingThreshold) IsSet() bool { return rt.min > 0 }
+
+func (rt RatingThreshold) Match(rating int) bool {
+	if rt.exact {
+		return rating == rt.min
+	}
+	return rt.IsSet() && rating >= rt.min
+}
+
+// ratingTag is the tag given to an asset rated with stars
+func ratingTag(rating int) string {
+	return fmt.Sprintf("Rating/%d", rating)
+}
+
+// rateAsset applies the -rating-to-favorite and -rating-to-tag options to the asset,
+// with the xmp:Rating of its sidecar or of the XMP embedded into the file
+func (app *UpCmd) rateAsset(ctx context.Context, a *browser.LocalAssetFile) {
+	if !app.RatingToFavorite.IsSet() && !app.RatingToTag {
+		return
+	}
+	packets, err := xmpPackets(a)
+	if err != nil {
+		app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+		return
+	}
+	for _, x := range packets {
+		rating, ok, err := metadata.ReadXMPRating(bytes.NewReader(x))
+		if err != nil {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+			return
+		}
+		if !ok {
+			continue
+		}
+		if rating <= 0 {
+			// unrated or rejected
+			return
+		}
+		if app.RatingToFavorite.Match(rating) && !a.Favorite {
+			a.Favorite = true
+			app.Jnl.Record(ctx, fileevent.INFO, a, a.FileName, "favorite", "rated "+strconv.Itoa(rating))
+		}
+		if t := ratingTag(rating); app.RatingToTag && !slices.Contains(a.Metadata.Tags, t) {
+			a.Metadata.Tags = append(a.Metadata.Tags, t)
+		}
+		return
+	}
+}
@@ -0,0 +1,110 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestRatingThreshold(t *testing.T) {
+	tests := []struct {
+		value   string
+		match   []int
+		wantErr bool
+	}{
+		{value: "4", match: []int{4, 5}},
+		{value: ">=4", match: []int{4, 5}},
+		{value: ">3", match: []int{4, 5}},
+		{value: "=5", match: []int{5}},
+		{value: ">=0", wantErr: true},
+		{value: ">5", wantErr: true},
+		{value: "four", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var rt RatingThreshold
+			err := rt.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var match []int
+			for r := -1; r <= 5; r++ {
+				if rt.Match(r) {
+					match = append(match, r)
+				}
+			}
+			if !reflect.DeepEqual(match, tt.match) {
+				t.Errorf("expected matches %v, got %v", tt.match, match)
+			}
+		})
+	}
+}
+
+type icCatchRatings struct {
+	icCatchUploadsAssets
+	favorites []string
+	tags      map[string][]string
+}
+
+func (c *icCatchRatings) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	if a.Favorite {
+		c.favorites = append(c.favorites, a.FileName)
+	}
+	if len(a.Metadata.Tags) > 0 {
+		c.tags[a.FileName] = a.Metadata.Tags
+	}
+	return c.icCatchUploadsAssets.AssetUpload(ctx, a)
+}
+
+func TestRatings(t *testing.T) {
+	const sidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="%d"/>
+</rdf:RDF></x:xmpmeta>`
+	dir := t.TempDir()
+	for name, rating := range map[string]int{
+		"PXL_20231006_063000139.jpg": 5,
+		"PXL_20231006_063029647.jpg": 3,
+		"PXL_20231006_063108407.jpg": -1,
+	} {
+		copyFile(t, filepath.Join("TEST_DATA/folder/low", name), filepath.Join(dir, name))
+		err := os.WriteFile(filepath.Join(dir, name+".xmp"), []byte(fmt.Sprintf(sidecar, rating)), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ic := &icCatchRatings{
+		icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+		tags:                 map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-rating-to-favorite=>=4", "-rating-to-tag", dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ic.favorites, []string{"PXL_20231006_063000139.jpg"}) {
+		t.Errorf("unexpected favorites: %v", ic.favorites)
+	}
+	expectedTags := map[string][]string{
+		"PXL_20231006_063000139.jpg": {"Rating/5"},
+		"PXL_20231006_063029647.jpg": {"Rating/3"},
+	}
+	if !reflect.DeepEqual(ic.tags, expectedTags) {
+		t.Errorf("expected tags %v, got %v", expectedTags, ic.tags)
+	}
+}
@@ -0,0 +1,174 @@
+package upload
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// Actions written in the CSV report
+const (
+	ReportUploaded     = "uploaded"      // the file has been uploaded, or has replaced a server's asset
+	ReportDuplicate    = "duplicate"     // the server has the asset, or the file has been uploaded from another file of the input
+	ReportDiscarded    = "discarded"     // the file is excluded, not selected or not supported
+	ReportError        = "error"         // the file can't be processed
+	ReportSidecar      = "sidecar"       // the file gives the metadata of another file
+	ReportNotProcessed = "not processed" // the run has ended before processing the file
+)
+
+// fileOutcome is the result of the processing of an asset
+type fileOutcome struct {
+	path    string
+	status  string // Mapping status
+	assetID string
+	date    time.Time
+	albums  []string
+}
+
+// reportEntry is the outcome of a file seen during the run
+type reportEntry struct {
+	File    string // name of the file in the journal
+	Path    string
+	Action  string
+	Reason  string
+	Date    time.Time
+	Albums  []string
+	AssetID string
+}
+
+func (e reportEntry) record() []string {
+	date := ""
+	if !e.Date.IsZero() {
+		date = e.Date.Format(time.RFC3339)
+	}
+	return []string{e.Path, e.Action, e.Reason, date, strings.Join(e.Albums, ";"), e.AssetID}
+}
+
+// reportOutcomes collects the outcomes of the assets for the CSV and HTML reports, keyed by file name as recorded in the journal
+type reportOutcomes struct {
+	lock   sync.Mutex
+	byFile map[string]fileOutcome
+}
+
+func newReportOutcomes() *reportOutcomes {
+	return &reportOutcomes{byFile: map[string]fileOutcome{}}
+}
+
+// reportAsset keeps the outcome of the asset and of its live photo video
+func (app *UpCmd) reportAsset(a *browser.LocalAssetFile, assetID string, status string, albums []string) {
+	if app.outcomes == nil {
+		return
+	}
+	app.outcomes.lock.Lock()
+	defer app.outcomes.lock.Unlock()
+	keep := func(f *browser.LocalAssetFile, id string) {
+		p := f.FileName
+		if fsys, ok := f.FSys.(fshelper.NameFS); ok {
+			p = path.Join(fsys.Name(), f.FileName)
+		}
+		app.outcomes.byFile[f.FileName] = fileOutcome{path: p, status: status, assetID: id, date: a.Metadata.DateTaken, albums: albums}
+	}
+	if a.LivePhoto != nil && a.LivePhotoID != "" {
+		keep(a.LivePhoto, a.LivePhotoID)
+	}
+	keep(a, assetID)
+}
+
+// writeCSVReport writes one row per file seen during the run: path, action, reason, date, albums, asset ID
+func (app *UpCmd) writeCSVReport() error {
+	err := configuration.MakeDirForFile(app.CSVReport)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(app.CSVReport)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	err = w.Write([]string{"path", "action", "reason", "date", "albums", "asset_id"})
+	if err == nil {
+		for _, e := range app.reportEntries() {
+			err = w.Write(e.record())
+			if err != nil {
+				break
+			}
+		}
+	}
+	w.Flush()
+	return errors.Join(err, w.Error(), f.Close())
+}
+
+// reportEntries gives the outcomes of all files seen during the run, sorted by file name
+func (app *UpCmd) reportEntries() []reportEntry {
+	app.outcomes.lock.Lock()
+	defer app.outcomes.lock.Unlock()
+	var entries []reportEntry
+	for _, fr := range app.Jnl.Summary().Files {
+		entries = append(entries, newReportEntry(fr, app.outcomes.byFile))
+	}
+	return entries
+}
+
+// newReportEntry gives the outcome of the file, from the outcome of the asset when it has been processed, from its events otherwise
+func newReportEntry(fr fileevent.FileReport, outcomes map[string]fileOutcome) reportEntry {
+	detail := func(e fileevent.FileEvent, keys ...string) string {
+		for _, k := range keys {
+			if v, ok := e.Details[k]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+	has := func(keys ...string) (fileevent.FileEvent, bool) {
+		for _, e := range fr.Events {
+			for _, k := range keys {
+				if e.Event == k {
+					return e, true
+				}
+			}
+		}
+		return fileevent.FileEvent{}, false
+	}
+
+	if o, ok := outcomes[fr.File]; ok {
+		action, reason := ReportDuplicate, o.status
+		switch o.status {
+		case MappingUploaded, MappingReplaced:
+			action = ReportUploaded
+		}
+		if e, ok := has(fileevent.UploadServerDuplicate.Key(), fileevent.UploadServerBetter.Key(), fileevent.UploadUpgraded.Key(), fileevent.UploadNotSelected.Key()); ok {
+			if r := detail(e, "reason"); r != "" {
+				reason = o.status + ": " + r
+			}
+		}
+		if e, ok := has(fileevent.Error.Key(), fileevent.UploadServerError.Key()); ok {
+			reason = detail(e, "error", "message")
+		}
+		return reportEntry{File: fr.File, Path: o.path, Action: action, Reason: reason, Date: o.date, Albums: o.albums, AssetID: o.assetID}
+	}
+
+	action, reason, assetID := ReportNotProcessed, "", ""
+	if e, ok := has(fileevent.UploadServerError.Key(), fileevent.Error.Key()); ok {
+		action, reason = ReportError, detail(e, "error", "message")
+	} else if e, ok := has(fileevent.DiscoveredDiscarded.Key(), fileevent.DiscoveredUnsupported.Key(), fileevent.UploadNotSelected.Key(),
+		fileevent.AnalysisMissingAssociatedMetadata.Key(), fileevent.DiscoveredMismatch.Key(), fileevent.DiscoveredHidden.Key()); ok {
+		action, reason = ReportDiscarded, detail(e, "reason")
+		if reason == "" {
+			reason = e.Event
+		}
+	} else if e, ok := has(fileevent.UploadUnchanged.Key()); ok {
+		action, reason, assetID = ReportDuplicate, fileevent.UploadUnchanged.String(), detail(e, "assetID")
+	} else if _, ok := has(fileevent.DiscoveredSidecar.Key(), fileevent.AnalysisAssociatedMetadata.Key(), fileevent.Metadata.Key()); ok {
+		action = ReportSidecar
+	}
+	return reportEntry{File: fr.File, Path: fr.File, Action: action, Reason: reason, AssetID: assetID}
+}
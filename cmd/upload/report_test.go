@@ -0,0 +1,277 @@
+package upload
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"image"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestJSONReport(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "report.json")
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{
+		"-no-ui", "-album=the album", "-report=" + name,
+		"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report fileevent.JSONReport
+	err = json.Unmarshal(b, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Counts["uploaded"] != 2 || report.Counts["discovered_image"] != 2 {
+		t.Errorf("unexpected counts: %v", report.Counts)
+	}
+	if len(report.Files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(report.Files))
+	}
+	for _, f := range report.Files {
+		var events []string
+		for _, e := range f.Events {
+			events = append(events, e.Event)
+			if e.Event == "added_to_album" && e.Details["album"] != "the album" {
+				t.Errorf("%s: unexpected album: %v", f.File, e.Details)
+			}
+		}
+		if !slices.Contains(events, "uploaded") || !slices.Contains(events, "added_to_album") {
+			t.Errorf("%s: unexpected events: %v", f.File, events)
+		}
+	}
+}
+
+func TestCSVReport(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "report.csv")
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{
+		"-no-ui", "-album=the album", "-csv-report=" + name, "-exclude-files=*063029647*",
+		"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 lines, got %v", records)
+	}
+	if !reflect.DeepEqual(records[0], []string{"path", "action", "reason", "date", "albums", "asset_id"}) {
+		t.Errorf("unexpected header: %v", records[0])
+	}
+	uploaded, discarded := records[1], records[2]
+	if uploaded[1] != ReportUploaded || uploaded[4] != "the album" || uploaded[5] != "PXL_20231006_063000139.jpg" || uploaded[3] == "" {
+		t.Errorf("unexpected uploaded row: %v", uploaded)
+	}
+	if discarded[1] != ReportDiscarded || discarded[2] != "banned file" || discarded[5] != "" {
+		t.Errorf("unexpected discarded row: %v", discarded)
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "report.html")
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+		Server: "http://immich",
+	}
+	err := UploadCommand(context.Background(), &serv, []string{
+		"-no-ui", "-album=the album", "-html-report=" + name, "-exclude-files=*063029647*",
+		"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(b)
+	for _, want := range []string{
+		"<summary>the album (1)</summary>",
+		`<a href="http://immich/photos/PXL_20231006_063000139.jpg">`,
+		`<figure class="discarded">`,
+		`<img src="data:image/jpeg;base64,`,
+		"banned file",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("the report doesn't contain %q", want)
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		wantW, wantH int
+	}{
+		{w: 800, h: 600, wantW: 160, wantH: 120},
+		{w: 600, h: 800, wantW: 120, wantH: 160},
+		{w: 100, h: 50, wantW: 100, wantH: 50},
+		{w: 5000, h: 10, wantW: 160, wantH: 1},
+	}
+	for _, tt := range tests {
+		img := reduce(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), thumbnailSize)
+		if img.Bounds().Dx() != tt.wantW || img.Bounds().Dy() != tt.wantH {
+			t.Errorf("%dx%d: expected %dx%d, got %v", tt.w, tt.h, tt.wantW, tt.wantH, img.Bounds())
+		}
+	}
+}
+
+func TestNDJSONOutput(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err = UploadCommand(context.Background(), &serv, []string{
+		"-output=ndjson",
+		"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if os.Stdout != f {
+		t.Error("the standard output isn't restored")
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploaded := 0
+	var last fileevent.StreamEvent
+	for _, l := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var e fileevent.StreamEvent
+		err = json.Unmarshal([]byte(l), &e)
+		if err != nil {
+			t.Fatalf("the line %q isn't JSON: %s", l, err)
+		}
+		if e.Event == "uploaded" {
+			uploaded++
+		}
+		last = e
+	}
+	if uploaded != 2 {
+		t.Errorf("expected 2 uploaded events, got %d", uploaded)
+	}
+	if last.Event != "summary" || last.Counts["uploaded"] != 2 {
+		t.Errorf("unexpected summary: %+v", last)
+	}
+}
+
+// icAlbumResults serves an album already holding one of the assets, and refusing another one
+type icAlbumResults struct {
+	icCatchUploadsAssets
+}
+
+func (c *icAlbumResults) GetAllAlbums(context.Context) ([]immich.AlbumSimplified, error) {
+	return []immich.AlbumSimplified{{ID: "A", AlbumName: "the album"}}, nil
+}
+
+func (c *icAlbumResults) AddAssetToAlbum(ctx context.Context, album string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	var r []immich.UpdateAlbumResult
+	for _, id := range ids {
+		switch id {
+		case "PXL_20231006_063000139.jpg":
+			r = append(r, immich.UpdateAlbumResult{ID: id, Error: immich.ErrorDuplicate})
+		case "PXL_20231006_063029647.jpg":
+			r = append(r, immich.UpdateAlbumResult{ID: id, Error: "no_permission"})
+		default:
+			r = append(r, immich.UpdateAlbumResult{ID: id, Success: true})
+		}
+	}
+	return r, nil
+}
+
+func TestAlbumStats(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: &icAlbumResults{icCatchUploadsAssets{albums: map[string][]string{}}},
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{
+		"-no-ui", "-album=the album",
+		"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg", "TEST_DATA/folder/low/PXL_20231006_063108407.jpg",
+	})
+	if err == nil {
+		t.Error("expected an error for the asset refused by the album")
+	}
+	expected := map[string]fileevent.AlbumStats{"the album": {Added: 1, Present: 1, Failed: 1}}
+	if got := serv.Jnl.Albums(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestReportUnchanged(t *testing.T) {
+	fr := fileevent.FileReport{
+		File:   "photos/a.jpg",
+		Events: []fileevent.FileEvent{{Event: fileevent.UploadUnchanged.Key(), Details: map[string]string{"assetID": "asset-a"}}},
+	}
+	got := newReportEntry(fr, nil)
+	if got.Action != ReportDuplicate || got.AssetID != "asset-a" || got.Reason != fileevent.UploadUnchanged.String() {
+		t.Errorf("unexpected entry for an unchanged file: %+v", got)
+	}
+}
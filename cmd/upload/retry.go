@@ -0,0 +1,88 @@
+package upload
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// failedFiles gives the names of the files having an error during the run, sorted
+func (app *UpCmd) failedFiles() []string {
+	var names []string
+	for _, fr := range app.Jnl.Summary().Files {
+		for _, e := range fr.Events {
+			if e.Event == fileevent.Error.Key() || e.Event == fileevent.UploadServerError.Key() {
+				names = append(names, fr.File)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// writeFailedList writes the names of the files in error, one per line, relative to the folders given to the upload command
+func (app *UpCmd) writeFailedList() (int, error) {
+	names := app.failedFiles()
+	err := configuration.MakeDirForFile(app.FailedList)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Create(app.FailedList)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	_, err = fmt.Fprintln(w, "# files in error, retry them with: immich-go upload -retry-from", app.FailedList, "FOLDERS...")
+	for _, n := range names {
+		if err != nil {
+			break
+		}
+		_, err = fmt.Fprintln(w, n)
+	}
+	err = errors.Join(err, w.Flush(), f.Close())
+	return len(names), err
+}
+
+// readFailedList reads the list of files written by -failed-list. The empty lines and the comments are ignored.
+func readFailedList(name string) ([]string, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		names = append(names, l)
+	}
+	return names, nil
+}
+
+// retryOpener opens the file systems, limited to the listed files and their XMP sidecars
+func retryOpener(open fsOpener, names []string) fsOpener {
+	visible := make([]string, 0, 5*len(names))
+	for _, n := range names {
+		base := strings.TrimSuffix(n, path.Ext(n))
+		visible = append(visible, n, n+".xmp", n+".XMP", base+".xmp", base+".XMP")
+	}
+	return func() ([]fs.FS, error) {
+		fsyss, err := open()
+		if err != nil {
+			return nil, err
+		}
+		for i, fsys := range fsyss {
+			fsyss[i] = fshelper.NewFileListFS(fsys, visible)
+		}
+		return fsyss, nil
+	}
+}
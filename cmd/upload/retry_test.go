@@ -0,0 +1,70 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+// icFailOne rejects the upload of one file
+type icFailOne struct {
+	icCatchUploadsAssets
+	fail string
+}
+
+func (c *icFailOne) AssetUpload(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
+	if path.Base(a.FileName) == c.fail {
+		return immich.AssetResponse{}, errors.New("connection reset")
+	}
+	return c.icCatchUploadsAssets.AssetUpload(ctx, a)
+}
+
+func TestRetryFrom(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "failed.lst")
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// first run: one file fails
+	ic := &icFailOne{icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}}, fail: "PXL_20231006_063108407.jpg"}
+	serv := cmd.SharedFlags{Immich: ic, Jnl: fileevent.NewRecorder(log, false), Log: log}
+	err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-failed-list=" + list, "TEST_DATA/folder/low"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	names, err := readFailedList(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"PXL_20231006_063108407.jpg"}) {
+		t.Fatalf("unexpected failed list: %v", names)
+	}
+
+	// second run: only the failed file is processed
+	retry := &icCatchUploadsAssets{albums: map[string][]string{}}
+	serv = cmd.SharedFlags{Immich: retry, Jnl: fileevent.NewRecorder(log, false), Log: log}
+	err = UploadCommand(context.Background(), &serv, []string{"-no-ui", "-retry-from=" + list, "TEST_DATA/folder/low"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(retry.assets, []string{"PXL_20231006_063108407.jpg"}) {
+		t.Errorf("unexpected uploads: %v", retry.assets)
+	}
+}
+
+func TestRetryWithGooglePhotos(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{Immich: &icCatchUploadsAssets{}, Jnl: fileevent.NewRecorder(log, false), Log: log}
+	err := UploadCommand(context.Background(), &serv, []string{"-no-ui", "-google-photos", "-retry-from=failed.lst", "TEST_DATA/folder/low"})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
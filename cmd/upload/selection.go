@@ -0,0 +1,358 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+)
+
+// errSelectionCancelled is returned when the user leaves the selection without uploading
+var errSelectionCancelled = errors.New("the selection has been cancelled")
+
+// selectionNode is a folder or an album of the selection tree
+type selectionNode struct {
+	name     string
+	parent   *selectionNode
+	children []*selectionNode
+	byName   map[string]*selectionNode
+	selected bool  // the assets of the node itself are selected
+	files    int   // number of assets of the node and its children
+	size     int64 // size of the assets of the node and its children
+}
+
+func newSelectionNode(name string, parent *selectionNode) *selectionNode {
+	return &selectionNode{name: name, parent: parent, byName: map[string]*selectionNode{}, selected: true}
+}
+
+// child gives the child node of that name, created when missing
+func (n *selectionNode) child(name string) *selectionNode {
+	c, ok := n.byName[name]
+	if !ok {
+		c = newSelectionNode(name, n)
+		n.byName[name] = c
+		n.children = append(n.children, c)
+	}
+	return c
+}
+
+// add counts the asset in the node and its parents
+func (n *selectionNode) add(size int) {
+	for ; n != nil; n = n.parent {
+		n.files++
+		n.size += int64(size)
+	}
+}
+
+// state gives 1 when the node and all its children are selected, 0 when none of them is, -1 otherwise
+func (n *selectionNode) state() int {
+	all, none := n.selected, !n.selected
+	for _, c := range n.children {
+		switch c.state() {
+		case 1:
+			none = false
+		case 0:
+			all = false
+		default:
+			return -1
+		}
+	}
+	switch {
+	case all:
+		return 1
+	case none:
+		return 0
+	}
+	return -1
+}
+
+// toggle selects the node and all its children, or deselects them when they are all selected
+func (n *selectionNode) toggle() {
+	n.set(n.state() != 1)
+}
+
+func (n *selectionNode) set(selected bool) {
+	n.selected = selected
+	for _, c := range n.children {
+		c.set(selected)
+	}
+}
+
+// selectedCounts gives the number and the size of the selected assets of the node and its children
+func (n *selectionNode) selectedCounts() (files int, size int64) {
+	if n.selected {
+		files, size = n.files, n.size
+		for _, c := range n.children {
+			files -= c.files
+			size -= c.size
+		}
+	}
+	for _, c := range n.children {
+		f, s := c.selectedCounts()
+		files += f
+		size += s
+	}
+	return files, size
+}
+
+// label gives the checkbox, the name and the counts of the node
+func (n *selectionNode) label() string {
+	box := "[-]"
+	switch n.state() {
+	case 1:
+		box = "[x]"
+	case 0:
+		box = "[ ]"
+	}
+	s := "s"
+	if n.files == 1 {
+		s = ""
+	}
+	return fmt.Sprintf("%s %s (%d file%s, %s)", box, n.name, n.files, s, formatBytes(int(n.size)))
+}
+
+func (n *selectionNode) sort() {
+	sort.Slice(n.children, func(i, j int) bool { return n.children[i].name < n.children[j].name })
+	for _, c := range n.children {
+		c.sort()
+	}
+}
+
+// selectionTree gives the folders and the albums of the discovered assets, and lets the user deselect some of them.
+// An asset is uploaded when its folder is selected, and when one of its albums at least is selected.
+type selectionTree struct {
+	folders    *selectionNode
+	albums     *selectionNode
+	withSource bool // the folders are placed under the name of their source
+}
+
+func newSelectionTree(withSource bool) *selectionTree {
+	return &selectionTree{
+		folders:    newSelectionNode("Folders", nil),
+		albums:     newSelectionNode("Albums", nil),
+		withSource: withSource,
+	}
+}
+
+// add counts the asset into its folder and its albums
+func (t *selectionTree) add(a *browser.LocalAssetFile) {
+	t.folder(a).add(a.FileSize)
+	for _, al := range a.Albums {
+		if al.Title != "" {
+			t.albums.child(al.Title).add(a.FileSize)
+		}
+	}
+}
+
+// folder gives the node of the asset's folder
+func (t *selectionTree) folder(a *browser.LocalAssetFile) *selectionNode {
+	n := t.folders
+	if t.withSource {
+		n = n.child(sourceName(a.FSys))
+	}
+	dir := path.Dir(a.FileName)
+	if dir == "." {
+		return n
+	}
+	for _, name := range strings.Split(dir, "/") {
+		n = n.child(name)
+	}
+	return n
+}
+
+// isSelected tells if the asset is in a selected folder, and in a selected album when it has albums
+func (t *selectionTree) isSelected(a *browser.LocalAssetFile) bool {
+	if !t.folder(a).selected {
+		return false
+	}
+	inAlbum := false
+	for _, al := range a.Albums {
+		if al.Title == "" {
+			continue
+		}
+		if t.albums.child(al.Title).selected {
+			return true
+		}
+		inAlbum = true
+	}
+	return !inAlbum
+}
+
+// roots gives the top level nodes of the tree
+func (t *selectionTree) roots() []*selectionNode {
+	roots := []*selectionNode{t.folders}
+	if len(t.albums.children) > 0 {
+		roots = append(roots, t.albums)
+	}
+	return roots
+}
+
+// sourceName gives the name of the file system, ex: the folder or the zip file given on the command line
+func sourceName(fsys any) string {
+	if n, ok := fsys.(fshelper.NameFS); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", fsys)
+}
+
+// selectedBrowser gives the assets collected before the upload, without browsing the sources again.
+// The assets rejected by keep are recorded as not selected.
+type selectedBrowser struct {
+	assets []*browser.LocalAssetFile
+	keep   func(a *browser.LocalAssetFile) bool
+	jnl    *fileevent.Recorder
+}
+
+func (b *selectedBrowser) Prepare(ctx context.Context) error {
+	return nil
+}
+
+func (b *selectedBrowser) Browse(ctx context.Context) chan *browser.LocalAssetFile {
+	out := make(chan *browser.LocalAssetFile)
+	go func() {
+		defer close(out)
+		for _, a := range b.assets {
+			if a.Err == nil && !b.keep(a) {
+				b.jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "deselected in the selection tree")
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- a:
+			}
+		}
+	}()
+	return out
+}
+
+// collectAssets browses the sources and gives all the assets
+func collectAssets(ctx context.Context, b browser.Browser) ([]*browser.LocalAssetFile, error) {
+	err := b.Prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var assets []*browser.LocalAssetFile
+	for a := range b.Browse(ctx) {
+		// the files are reopened when uploaded
+		a.Close()
+		if a.LivePhoto != nil {
+			a.LivePhoto.Close()
+		}
+		assets = append(assets, a)
+	}
+	return assets, ctx.Err()
+}
+
+// selectAssets lets the user choose the folders and the albums to upload.
+// It gives the function telling if an asset is selected.
+func (app *UpCmd) selectAssets(ctx context.Context, assets []*browser.LocalAssetFile) (func(a *browser.LocalAssetFile) bool, error) {
+	tree := newSelectionTree(!app.GooglePhotos && len(app.fsyss) > 1)
+	for _, a := range assets {
+		if a.Err == nil {
+			tree.add(a)
+		}
+	}
+	tree.folders.sort()
+	tree.albums.sort()
+
+	choose := app.chooseAssets
+	if choose == nil {
+		choose = app.runSelectionUI
+	}
+	err := choose(ctx, tree)
+	if err != nil {
+		return nil, err
+	}
+	files, size := tree.folders.selectedCounts()
+	app.Log.Info(fmt.Sprintf("%d file(s) selected in the tree, %s", files, formatBytes(int(size))))
+	return tree.isSelected, nil
+}
+
+// runSelectionUI shows the selection tree until the user starts the upload or leaves
+func (app *UpCmd) runSelectionUI(ctx context.Context, tree *selectionTree) error {
+	_, err := tcell.NewScreen()
+	if err != nil {
+		return fmt.Errorf("the option -select needs a terminal: %w", err)
+	}
+	if app.NoColor {
+		monochrome()
+	}
+	uiApp := tview.NewApplication()
+
+	root := tview.NewTreeNode("")
+	var addNodes func(parent *tview.TreeNode, nodes []*selectionNode, expanded bool)
+	addNodes = func(parent *tview.TreeNode, nodes []*selectionNode, expanded bool) {
+		for _, n := range nodes {
+			tn := tview.NewTreeNode(tview.Escape(n.label())).SetReference(n).SetExpanded(expanded)
+			parent.AddChild(tn)
+			addNodes(tn, n.children, false)
+		}
+	}
+	addNodes(root, tree.roots(), true)
+
+	view := tview.NewTreeView().SetRoot(root).SetTopLevel(1).SetCurrentNode(root.GetChildren()[0])
+	view.SetBorder(true).SetTitle(tview.Escape(" Select what to upload: [space] select/deselect, [enter] open/close, [u] upload, [q] quit "))
+	footer := tview.NewTextView()
+	updateFooter := func() {
+		files, size := tree.folders.selectedCounts()
+		footer.SetText(fmt.Sprintf("Selected: %d of %d files, %s of %s", files, tree.folders.files, formatBytes(int(size)), formatBytes(int(tree.folders.size))))
+	}
+	updateFooter()
+	relabel := func() {
+		root.Walk(func(tn, parent *tview.TreeNode) bool {
+			if n, ok := tn.GetReference().(*selectionNode); ok {
+				tn.SetText(tview.Escape(n.label()))
+			}
+			return true
+		})
+		updateFooter()
+	}
+
+	view.SetSelectedFunc(func(tn *tview.TreeNode) {
+		tn.SetExpanded(!tn.IsExpanded())
+	})
+	done := false
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyRune && event.Rune() == ' ':
+			if n, ok := view.GetCurrentNode().GetReference().(*selectionNode); ok {
+				n.toggle()
+				relabel()
+			}
+			return nil
+		case event.Key() == tcell.KeyRune && (event.Rune() == 'u' || event.Rune() == 'U'):
+			done = true
+			uiApp.Stop()
+			return nil
+		case event.Key() == tcell.KeyRune && (event.Rune() == 'q' || event.Rune() == 'Q'),
+			event.Key() == tcell.KeyEscape, event.Key() == tcell.KeyCtrlC:
+			uiApp.Stop()
+			return nil
+		}
+		return event
+	})
+
+	stop := context.AfterFunc(ctx, uiApp.Stop)
+	defer stop()
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).AddItem(view, 0, 1, true).AddItem(footer, 1, 0, false)
+	err = uiApp.SetRoot(layout, true).Run()
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if !done {
+		return errSelectionCancelled
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestSelectionTree(t *testing.T) {
+	holidays := browser.LocalAlbum{Title: "Holidays"}
+	blurry := browser.LocalAlbum{Title: "Blurry"}
+	assets := []*browser.LocalAssetFile{
+		{FileName: "2023/beach.jpg", FileSize: 100, Albums: []browser.LocalAlbum{holidays}},
+		{FileName: "2023/blurry.jpg", FileSize: 200, Albums: []browser.LocalAlbum{blurry}},
+		{FileName: "2023/both.jpg", FileSize: 300, Albums: []browser.LocalAlbum{holidays, blurry}},
+		{FileName: "2023/Screenshots/screen.png", FileSize: 400},
+		{FileName: "root.jpg", FileSize: 500},
+	}
+	tree := newSelectionTree(false)
+	for _, a := range assets {
+		tree.add(a)
+	}
+	tree.folders.sort()
+	tree.albums.sort()
+
+	year := tree.folders.byName["2023"]
+	screenshots := year.byName["Screenshots"]
+	if got := year.label(); got != "[x] 2023 (4 files, 1000 B)" {
+		t.Errorf("unexpected label %q", got)
+	}
+
+	screenshots.toggle()
+	if got := screenshots.label(); got != "[ ] Screenshots (1 file, 400 B)" {
+		t.Errorf("unexpected label %q", got)
+	}
+	if got := year.label(); got != "[-] 2023 (4 files, 1000 B)" {
+		t.Errorf("unexpected label %q", got)
+	}
+	tree.albums.byName["Blurry"].toggle()
+
+	var selected []string
+	for _, a := range assets {
+		if tree.isSelected(a) {
+			selected = append(selected, a.FileName)
+		}
+	}
+	expected := []string{"2023/beach.jpg", "2023/both.jpg", "root.jpg"}
+	if !cmpSlices(expected, selected) {
+		t.Errorf("unexpected selection")
+		pretty.Ldiff(t, expected, selected)
+	}
+	if files, size := tree.folders.selectedCounts(); files != 4 || size != 1100 {
+		t.Errorf("expected 4 files and 1100 bytes in the selected folders, got %d and %d", files, size)
+	}
+
+	// a partially selected folder is selected entirely
+	year.toggle()
+	if year.state() != 1 || !screenshots.selected {
+		t.Errorf("the folder and its children should be selected")
+	}
+	tree.folders.toggle()
+	if tree.folders.state() != 0 {
+		t.Errorf("all the folders should be deselected")
+	}
+}
+
+func TestSelectUpload(t *testing.T) {
+	ic := &icCatchUploadsAssets{albums: map[string][]string{}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	ctx := context.Background()
+	app, err := newCommand(ctx, &serv, []string{"-no-ui", "-select", "TEST_DATA/folder/high"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.chooseAssets = func(ctx context.Context, tree *selectionTree) error {
+		if tree.folders.files != 8 {
+			t.Errorf("expected 8 files in the tree, got %d", tree.folders.files)
+		}
+		tree.folders.byName["AlbumB"].toggle()
+		return nil
+	}
+	err = app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"AlbumA/PXL_20231006_063000139.jpg",
+		"AlbumA/PXL_20231006_063029647.jpg",
+		"AlbumA/PXL_20231006_063108407.jpg",
+		"AlbumA/PXL_20231006_063121958.jpg",
+		"AlbumA/PXL_20231006_063357420.jpg",
+	}
+	if !cmpSlices(expected, ic.assets) {
+		t.Errorf("unexpected uploads")
+		pretty.Ldiff(t, expected, ic.assets)
+	}
+	if n := app.Jnl.GetCounts()[fileevent.UploadNotSelected]; n != 3 {
+		t.Errorf("expected 3 files not selected, got %d", n)
+	}
+}
+
+func TestSelectCancelled(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: &icCatchUploadsAssets{albums: map[string][]string{}},
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	ctx := context.Background()
+	app, err := newCommand(ctx, &serv, []string{"-no-ui", "-select", "TEST_DATA/folder/high"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.chooseAssets = func(ctx context.Context, tree *selectionTree) error {
+		return errSelectionCancelled
+	}
+	err = app.run(ctx)
+	if err != errSelectionCancelled {
+		t.Errorf("expected the cancellation, got %v", err)
+	}
+}
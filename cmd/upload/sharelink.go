@@ -0,0 +1,64 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// albumShareLink is the shared link of an album created during the upload
+type albumShareLink struct {
+	album     string
+	url       string
+	expiresAt *time.Time
+}
+
+// shareAlbum creates the shared link of the album just created
+func (app *UpCmd) shareAlbum(ctx context.Context, album immich.AlbumSimplified) {
+	link := immich.SharedLinkCreate{
+		Type:          immich.SharedLinkTypeAlbum,
+		AlbumID:       album.ID,
+		Password:      app.SharePassword,
+		AllowDownload: true,
+		ShowMetadata:  true,
+	}
+	if app.ShareExpiry > 0 {
+		t := time.Now().Add(app.ShareExpiry).UTC()
+		link.ExpiresAt = &t
+	}
+	l, err := app.Immich.CreateSharedLink(ctx, link)
+	if err != nil {
+		app.Log.Error(fmt.Sprintf("can't create the shared link of the album %q: %s", album.AlbumName, err))
+		return
+	}
+	s := albumShareLink{album: album.AlbumName, url: l.URL(app.ServerName()), expiresAt: link.ExpiresAt}
+	app.Log.Info(fmt.Sprintf("Shared link of the album %q: %s", s.album, s.url))
+	app.shareLinks = append(app.shareLinks, s)
+}
+
+// shareLink gives the shared link of the album, if any
+func (app *UpCmd) shareLink(album string) string {
+	for _, s := range app.shareLinks {
+		if s.album == album {
+			return s.url
+		}
+	}
+	return ""
+}
+
+// printShareLinks prints the shared links of the albums created during the upload
+func (app *UpCmd) printShareLinks() {
+	if len(app.shareLinks) == 0 {
+		return
+	}
+	fmt.Println("\nShared links of the new albums:")
+	for _, s := range app.shareLinks {
+		expiry := ""
+		if s.expiresAt != nil {
+			expiry = ", expires on " + s.expiresAt.Local().Format(time.DateTime)
+		}
+		fmt.Printf("  %s: %s%s\n", s.album, s.url, expiry)
+	}
+}
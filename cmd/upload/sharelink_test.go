@@ -0,0 +1,78 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+)
+
+type icSharedLinks struct {
+	icCatchUploadsAssets
+	links []immich.SharedLinkCreate
+}
+
+func (c *icSharedLinks) CreateSharedLink(ctx context.Context, link immich.SharedLinkCreate) (immich.SharedLink, error) {
+	c.links = append(c.links, link)
+	return immich.SharedLink{ID: "l1", Key: "key-" + link.AlbumID, Type: link.Type}, nil
+}
+
+func TestShareLink(t *testing.T) {
+	ctx := context.Background()
+	ic := &icSharedLinks{icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Server: "http://photos:2283",
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	app, err := newCommand(ctx, &serv, []string{"-no-ui", "-album=Wedding", "-share-link", "-share-password=grandma", "-share-expiry=720h", "TEST_DATA/folder/high"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ic.links) != 1 {
+		t.Fatalf("expected one shared link, got %d", len(ic.links))
+	}
+	l := ic.links[0]
+	if l.Type != immich.SharedLinkTypeAlbum || l.AlbumID != "Wedding" || l.Password != "grandma" {
+		t.Errorf("unexpected shared link %+v", l)
+	}
+	if l.ExpiresAt == nil || l.ExpiresAt.Before(start.Add(720*time.Hour)) || l.ExpiresAt.After(time.Now().Add(720*time.Hour)) {
+		t.Errorf("unexpected expiry %v", l.ExpiresAt)
+	}
+	if got := app.shareLink("Wedding"); got != "http://photos:2283/share/key-Wedding" {
+		t.Errorf("unexpected URL %q", got)
+	}
+}
+
+func TestShareLinkOptions(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, args := range [][]string{
+		{"-share-password=secret", "TEST_DATA/folder/low"},
+		{"-share-expiry=24h", "TEST_DATA/folder/low"},
+		{"-share-link", "-share-expiry=-1h", "TEST_DATA/folder/low"},
+		{"-share-link", "-offline", "-spool-dir=" + t.TempDir(), "TEST_DATA/folder/low"},
+	} {
+		serv := cmd.SharedFlags{
+			Immich: &icCatchUploadsAssets{albums: map[string][]string{}},
+			Jnl:    fileevent.NewRecorder(log, false),
+			Log:    log,
+		}
+		_, err := newCommand(context.Background(), &serv, args, nil)
+		if err == nil || !strings.Contains(err.Error(), "-share-") {
+			t.Errorf("%v: expected an error about the shared links, got %v", args, err)
+		}
+	}
+}
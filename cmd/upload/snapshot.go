@@ -0,0 +1,141 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/configuration"
+)
+
+// snapshotDefaultPeriod is the period of the snapshots when only the -snapshot-file option is given
+const snapshotDefaultPeriod = 10 * time.Minute
+
+// snapshot is the state of a long run, written periodically into the log and the status file
+type snapshot struct {
+	Time           time.Time        `json:"time"`
+	Elapsed        string           `json:"elapsed"`
+	Stage          string           `json:"stage"`
+	Found          int64            `json:"found"`     // assets found in the input
+	Processed      int64            `json:"processed"` // assets handled by the upload stage
+	BytesPerSecond float64          `json:"bytes_per_second"`
+	FilesPerMinute float64          `json:"files_per_minute"`
+	ETA            string           `json:"eta"`
+	Counts         map[string]int64 `json:"counts"`
+	Delta          map[string]int64 `json:"delta"`   // counters changed since the previous snapshot
+	Stalled        bool             `json:"stalled"` // no asset processed since the previous snapshot during the upload stage
+}
+
+// takeSnapshot gives the state of the run, compared to the previous snapshot when given
+func (app *UpCmd) takeSnapshot(prev *snapshot, now time.Time) *snapshot {
+	found, processed := app.Jnl.TotalAssets(), app.Jnl.TotalProcessed(app.ForceUploadWhenNoJSON)
+	stats := app.progress.stats(processed, found, now)
+	s := &snapshot{
+		Time:           now,
+		Stage:          stats.Stage,
+		Found:          found,
+		Processed:      processed,
+		BytesPerSecond: stats.BytesPerSecond,
+		FilesPerMinute: stats.FilesPerMinute,
+		ETA:            stats.eta(),
+		Counts:         app.Jnl.CountsByKey(),
+		Delta:          map[string]int64{},
+	}
+	if app.progress != nil {
+		s.Elapsed = now.Sub(app.progress.started).Round(time.Second).String()
+	}
+	for k, v := range s.Counts {
+		var before int64
+		if prev != nil {
+			before = prev.Counts[k]
+		}
+		if v != before {
+			s.Delta[k] = v - before
+		}
+	}
+	s.Stalled = prev != nil && s.Stage == stageUploading && prev.Stage == stageUploading && processed == prev.Processed && processed < found
+	return s
+}
+
+// startSnapshots writes a snapshot periodically, and gives the function to call at the end of the run to write the last one
+func (app *UpCmd) startSnapshots(ctx context.Context) func() {
+	var prev *snapshot
+	var lock sync.Mutex
+	write := func() {
+		lock.Lock()
+		defer lock.Unlock()
+		prev = app.takeSnapshot(prev, time.Now())
+		app.writeSnapshot(prev)
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(app.SnapshotEvery)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				write()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		write()
+	}
+}
+
+// writeSnapshot logs the snapshot, and replaces the status file. The failures are logged.
+func (app *UpCmd) writeSnapshot(s *snapshot) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		app.Log.Error("can't encode the snapshot: " + err.Error())
+		return
+	}
+	if s.Stalled {
+		app.Log.Warn("no asset processed since the previous snapshot", "snapshot", string(b))
+	} else {
+		app.Log.Info("snapshot", "snapshot", string(b))
+	}
+	if app.SnapshotFile == "" {
+		return
+	}
+	err = writeFileAtomic(app.SnapshotFile, append(b, '\n'))
+	if err != nil {
+		app.Log.Error("can't write the snapshot file: " + err.Error())
+	}
+}
+
+// writeFileAtomic replaces the file, so the readers never see a partial content
+func writeFileAtomic(name string, b []byte) error {
+	err := configuration.MakeDirForFile(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+	return err
+}
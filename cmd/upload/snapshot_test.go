@@ -0,0 +1,78 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := &UpCmd{
+		SharedFlags: &cmd.SharedFlags{Jnl: fileevent.NewRecorder(log, false), Log: log},
+		progress:    newProgress(),
+	}
+	for _, f := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		app.Jnl.Record(ctx, fileevent.DiscoveredImage, nil, f)
+	}
+	app.progress.startUpload()
+	app.Jnl.Record(ctx, fileevent.Uploaded, nil, "a.jpg")
+
+	now := time.Now()
+	first := app.takeSnapshot(nil, now)
+	if first.Stage != stageUploading || first.Found != 3 || first.Processed != 1 || first.Stalled {
+		t.Errorf("unexpected first snapshot: %+v", first)
+	}
+	if first.Delta["uploaded"] != 1 || first.Delta["discovered_image"] != 3 {
+		t.Errorf("unexpected delta: %v", first.Delta)
+	}
+
+	second := app.takeSnapshot(first, now.Add(time.Minute))
+	if !second.Stalled || len(second.Delta) != 0 {
+		t.Errorf("the second snapshot should be stalled without delta: %+v", second)
+	}
+
+	app.Jnl.Record(ctx, fileevent.Uploaded, nil, "b.jpg")
+	third := app.takeSnapshot(second, now.Add(2*time.Minute))
+	if third.Stalled || third.Delta["uploaded"] != 1 || third.Counts["uploaded"] != 2 {
+		t.Errorf("unexpected third snapshot: %+v", third)
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	status := filepath.Join(t.TempDir(), "status", "immich-go.json")
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: &icCatchUploadsAssets{albums: map[string][]string{}},
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+	err := UploadCommand(context.Background(), &serv, []string{
+		"-no-ui", "-snapshot-file=" + status,
+		"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := os.ReadFile(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s snapshot
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Counts["uploaded"] != 2 || s.Found != 2 || s.Processed != 2 {
+		t.Errorf("unexpected snapshot: %s", b)
+	}
+}
@@ -0,0 +1,404 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/configuration"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/helpers/myflag"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+const (
+	spoolFileName = "spool.jsonl" // list of the spooled assets, one JSON object per line
+	spoolFilesDir = "files"       // copies of the spooled files
+)
+
+// spoolPath locates a file on the disk
+type spoolPath struct {
+	Root     string `json:"root"`     // OS folder
+	FileName string `json:"fileName"` // path of the file in the folder
+}
+
+// spoolFile describes a file prepared for the upload
+type spoolFile struct {
+	spoolPath
+	Title      string            `json:"title"`
+	ContentExt string            `json:"contentExt,omitempty"`
+	Metadata   metadata.Metadata `json:"metadata"`
+	Archived   bool              `json:"archived,omitempty"`
+	Favorite   bool              `json:"favorite,omitempty"`
+	FileSize   int               `json:"fileSize"`
+	Checksum   string            `json:"checksum"`
+	SideCar    *spoolPath        `json:"sidecar,omitempty"`
+}
+
+// spoolEntry is an asset prepared while offline, with the albums it belongs to
+type spoolEntry struct {
+	spoolFile
+	LivePhoto *spoolFile           `json:"livePhoto,omitempty"`
+	Albums    []browser.LocalAlbum `json:"albums,omitempty"`
+}
+
+// spoolWriter appends the prepared assets to the spool.
+// The files are referenced in place, or copied into the spool folder
+// when requested or when they are read from an archive.
+type spoolWriter struct {
+	dir  string
+	copy bool
+	f    *os.File
+	enc  *json.Encoder
+}
+
+func newSpoolWriter(dir string, copy bool) (*spoolWriter, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, spoolFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &spoolWriter{
+		dir:  dir,
+		copy: copy,
+		f:    f,
+		enc:  json.NewEncoder(f),
+	}, nil
+}
+
+func (sw *spoolWriter) Close() error {
+	return sw.f.Close()
+}
+
+func (sw *spoolWriter) Write(a *browser.LocalAssetFile, albums []browser.LocalAlbum) error {
+	stage := filepath.Join(sw.dir, spoolFilesDir, uuid.NewString())
+	e := spoolEntry{Albums: albums}
+	var err error
+	e.spoolFile, err = sw.file(a, stage)
+	if err != nil {
+		return err
+	}
+	if a.LivePhoto != nil {
+		lp, err := sw.file(a.LivePhoto, stage)
+		if err != nil {
+			return err
+		}
+		e.LivePhoto = &lp
+	}
+	return sw.enc.Encode(e)
+}
+
+func (sw *spoolWriter) file(a *browser.LocalAssetFile, stage string) (spoolFile, error) {
+	sf := spoolFile{
+		Title:      a.Title,
+		ContentExt: a.ContentExt,
+		Metadata:   a.Metadata,
+		Archived:   a.Archived,
+		Favorite:   a.Favorite,
+		FileSize:   a.FileSize,
+		Checksum:   a.Checksum,
+	}
+	var err error
+	sf.spoolPath, err = sw.locate(a.FSys, a.FileName, stage)
+	if err != nil {
+		return sf, err
+	}
+	if a.SideCar.IsSet() {
+		sc, err := sw.locate(a.SideCar.FSys, a.SideCar.FileName, stage)
+		if err != nil {
+			return sf, err
+		}
+		sf.SideCar = &sc
+	}
+	return sf, nil
+}
+
+// locate gives the OS folder of the file, after copying it into the stage folder when needed
+func (sw *spoolWriter) locate(fsys fs.FS, name string, stage string) (spoolPath, error) {
+	if d, ok := fsys.(fshelper.OSDirFS); ok && !sw.copy {
+		root, err := filepath.Abs(d.Dir())
+		return spoolPath{Root: root, FileName: name}, err
+	}
+	src, err := fsys.Open(name)
+	if err != nil {
+		return spoolPath{}, err
+	}
+	defer src.Close()
+	dst := filepath.Join(stage, filepath.FromSlash(name))
+	err = configuration.MakeDirForFile(dst)
+	if err != nil {
+		return spoolPath{}, err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return spoolPath{}, err
+	}
+	_, err = io.Copy(f, src)
+	err = errors.Join(err, f.Close())
+	return spoolPath{Root: stage, FileName: name}, err
+}
+
+// runSpool prepares the assets without the server, and writes them into the spool
+func (app *UpCmd) runSpool(ctx context.Context) error {
+	var err error
+	app.spool, err = newSpoolWriter(app.SpoolDir, app.SpoolCopy)
+	if err != nil {
+		return fmt.Errorf("can't open the spool: %w", err)
+	}
+	defer func() {
+		_ = app.spool.Close()
+		app.spool = nil
+	}()
+
+	err = app.localLoop(ctx, app.spoolAsset)
+	if err != nil {
+		return err
+	}
+	fmt.Println("The assets are ready in the spool " + app.SpoolDir + ". Run the flush command to send them to the server.")
+	return nil
+}
+
+// spoolAsset hashes the asset and writes it into the spool with its albums
+func (app *UpCmd) spoolAsset(ctx context.Context, a *browser.LocalAssetFile) error {
+	defer a.Close()
+	if !app.isSelected(ctx, a) {
+		return nil
+	}
+	if !app.AutoArchive && a.Archived {
+		a.Archived = false
+	}
+	if app.ImportCaptions {
+		app.captionAsset(ctx, a)
+	}
+	app.rateAsset(ctx, a)
+	app.geocodeAsset(ctx, a)
+	if app.WriteXMP != "" {
+		err := app.writeXMP(ctx, a)
+		if err != nil {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+		}
+	}
+	if app.FixSourceExif {
+		err := app.fixSourceExif(ctx, a)
+		if err != nil {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+		}
+	}
+	err := a.ComputeChecksum()
+	if err != nil {
+		return err
+	}
+	if a.LivePhoto != nil {
+		err = a.LivePhoto.ComputeChecksum()
+		if err != nil {
+			return err
+		}
+	}
+
+	albums := []browser.LocalAlbum{}
+	titles := []string{}
+	for _, t := range app.assetAlbums(ctx, a) {
+		al := t.album
+		if al.Order == "" {
+			al.Order = app.AlbumOrder
+		}
+		albums = append(albums, al)
+		titles = append(titles, al.Title)
+	}
+	if !app.DryRun {
+		err = app.spool.Write(a, albums)
+		if err != nil {
+			return err
+		}
+	}
+	app.Jnl.Record(ctx, fileevent.Spooled, a, a.FileName, "albums", strings.Join(titles, ", "))
+	return nil
+}
+
+// readSpool reads the assets of the spool
+func readSpool(dir string) ([]spoolEntry, error) {
+	f, err := os.Open(filepath.Join(dir, spoolFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []spoolEntry{}
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for s.Scan() {
+		line++
+		if len(strings.TrimSpace(s.Text())) == 0 {
+			continue
+		}
+		var e spoolEntry
+		err = json.Unmarshal(s.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("%s, line %d: %w", f.Name(), line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+// clearSpool removes the spooled assets and their copies
+func clearSpool(dir string) error {
+	err := os.Remove(filepath.Join(dir, spoolFileName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(dir, spoolFilesDir))
+}
+
+// spoolBrowser gives the assets of the spool
+type spoolBrowser struct {
+	entries []spoolEntry
+	jnl     *fileevent.Recorder
+	sm      immich.SupportedMedia
+}
+
+func (sb *spoolBrowser) Prepare(ctx context.Context) error {
+	return nil
+}
+
+func (sb *spoolBrowser) Browse(ctx context.Context) chan *browser.LocalAssetFile {
+	c := make(chan *browser.LocalAssetFile)
+	go func() {
+		defer close(c)
+		for _, e := range sb.entries {
+			a := sb.asset(ctx, e.spoolFile)
+			if e.LivePhoto != nil {
+				a.LivePhoto = sb.asset(ctx, *e.LivePhoto)
+			}
+			a.Albums = e.Albums
+			select {
+			case <-ctx.Done():
+				return
+			case c <- a:
+			}
+		}
+	}()
+	return c
+}
+
+func (sb *spoolBrowser) asset(ctx context.Context, f spoolFile) *browser.LocalAssetFile {
+	a := &browser.LocalAssetFile{
+		FileName:   f.FileName,
+		Title:      f.Title,
+		ContentExt: f.ContentExt,
+		Metadata:   f.Metadata,
+		Archived:   f.Archived,
+		Favorite:   f.Favorite,
+		FSys:       fshelper.NewFSWithName(os.DirFS(f.Root), filepath.Base(f.Root)),
+		FileSize:   f.FileSize,
+		Checksum:   f.Checksum,
+	}
+	if f.SideCar != nil {
+		a.SideCar = metadata.SideCarFile{
+			FSys:     os.DirFS(f.SideCar.Root),
+			FileName: f.SideCar.FileName,
+		}
+	}
+	if _, err := fs.Stat(a.FSys, a.FileName); err != nil {
+		// the source isn't available anymore
+		a.Err = err
+	}
+	if sb.sm.TypeFromExt(a.Ext()) == immich.TypeVideo {
+		sb.jnl.Record(ctx, fileevent.DiscoveredVideo, a, a.FileName)
+	} else {
+		sb.jnl.Record(ctx, fileevent.DiscoveredImage, a, a.FileName)
+	}
+	return a
+}
+
+// FlushCommand uploads the assets prepared by the upload command with the -offline option
+func FlushCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := newFlushCommand(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	return app.flush(ctx)
+}
+
+func newFlushCommand(ctx context.Context, common *cmd.SharedFlags, args []string) (*UpCmd, error) {
+	cmd := flag.NewFlagSet("flush", flag.ContinueOnError)
+
+	app := UpCmd{
+		SharedFlags: common,
+		stdin:       os.Stdin,
+		stdout:      os.Stdout,
+	}
+	app.SharedFlags.SetFlags(cmd)
+	cmd.StringVar(&app.SpoolDir,
+		"spool-dir",
+		configuration.DefaultSpoolDir(),
+		" Folder of the spool")
+	cmd.BoolFunc(
+		"dry-run",
+		"display actions but don't touch source or destination",
+		myflag.BoolFlagFn(&app.DryRun, false))
+
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// The selection and the albums have been determined when the assets were spooled
+	app.CreateAlbums = true
+	app.AutoArchive = true
+	app.KeepPartner = true
+	app.KeepTrashed = true
+	app.KeepUntitled = true
+	app.OnDuplicate = DuplicateReplaceIfLarger
+	app.ReadWorkers = 1
+	app.NoUI = true
+
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// flush uploads the spooled assets, and empties the spool when all of them have been sent
+func (app *UpCmd) flush(ctx context.Context) error {
+	entries, err := readSpool(app.SpoolDir)
+	if err != nil {
+		return fmt.Errorf("can't read the spool: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("The spool " + app.SpoolDir + " is empty")
+		return nil
+	}
+	app.Log.Info(fmt.Sprintf("Flushing %d asset(s) from the spool %s", len(entries), app.SpoolDir))
+
+	app.browser = &spoolBrowser{entries: entries, jnl: app.Jnl, sm: app.supportedMedia()}
+	err = app.runNoUI(ctx)
+	if err != nil {
+		return fmt.Errorf("the spool is kept for a next flush: %w", err)
+	}
+	if app.DryRun {
+		return nil
+	}
+	return clearSpool(app.SpoolDir)
+}
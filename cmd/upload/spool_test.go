@@ -0,0 +1,127 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestSpoolAndFlush(t *testing.T) {
+	expectedAssets := []string{
+		"PXL_20231006_063000139.jpg",
+		"PXL_20231006_063029647.jpg",
+		"PXL_20231006_063108407.jpg",
+		"PXL_20231006_063121958.jpg",
+		"PXL_20231006_063357420.jpg",
+	}
+
+	for _, copy := range []bool{false, true} {
+		name := "in place"
+		if copy {
+			name = "copy"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			dir := t.TempDir()
+
+			args := []string{"-offline", "-spool-dir", dir, "-create-album-folder", "-album-order=desc"}
+			if copy {
+				args = append(args, "-spool-copy")
+			}
+			args = append(args, "TEST_DATA/folder/high/AlbumA")
+			err := UploadCommand(ctx, &cmd.SharedFlags{Jnl: fileevent.NewRecorder(log, false), Log: log}, args)
+			if err != nil {
+				t.Fatalf("can't spool the assets: %s", err)
+			}
+
+			entries, err := readSpool(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(expectedAssets) {
+				t.Fatalf("expected %d spooled assets, got %d", len(expectedAssets), len(entries))
+			}
+			for _, e := range entries {
+				if e.Checksum == "" {
+					t.Errorf("the checksum of %s is missing", e.FileName)
+				}
+				if len(e.Albums) != 1 || e.Albums[0].Title != "AlbumA" || e.Albums[0].Order != "desc" {
+					t.Errorf("unexpected albums for %s: %v", e.FileName, e.Albums)
+				}
+				if copy != strings.HasPrefix(e.Root, dir) {
+					t.Errorf("unexpected root for %s: %s", e.FileName, e.Root)
+				}
+			}
+
+			ic := &icCatchUploadsAssets{
+				albums: map[string][]string{},
+			}
+			err = FlushCommand(ctx, &cmd.SharedFlags{Immich: ic, Jnl: fileevent.NewRecorder(log, false), Log: log}, []string{"-spool-dir", dir})
+			if err != nil {
+				t.Fatalf("can't flush the spool: %s", err)
+			}
+
+			sort.Strings(ic.assets)
+			if !cmpSlices(expectedAssets, ic.assets) {
+				t.Errorf("expected uploaded assets %v, got %v", expectedAssets, ic.assets)
+			}
+			albums := ic.albums["AlbumA"]
+			sort.Strings(albums)
+			if !cmpSlices(expectedAssets, albums) {
+				t.Errorf("expected album's assets %v, got %v", expectedAssets, albums)
+			}
+
+			_, err = os.Stat(filepath.Join(dir, spoolFileName))
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("the spool should be emptied after the flush: %v", err)
+			}
+			_, err = os.Stat(filepath.Join(dir, spoolFilesDir))
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("the copies should be removed after the flush: %v", err)
+			}
+		})
+	}
+}
+
+func TestFlushKeepsMissingFiles(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	src := t.TempDir()
+	copyFile(t, "TEST_DATA/folder/high/AlbumA/PXL_20231006_063000139.jpg", filepath.Join(src, "PXL_20231006_063000139.jpg"))
+
+	err := UploadCommand(ctx, &cmd.SharedFlags{Jnl: fileevent.NewRecorder(log, false), Log: log}, []string{"-offline", "-spool-dir", dir, src})
+	if err != nil {
+		t.Fatalf("can't spool the assets: %s", err)
+	}
+	err = os.Remove(filepath.Join(src, "PXL_20231006_063000139.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	err = FlushCommand(ctx, &cmd.SharedFlags{Immich: ic, Jnl: fileevent.NewRecorder(log, false), Log: log}, []string{"-spool-dir", dir})
+	if err == nil {
+		t.Errorf("an error was expected")
+	}
+	entries, err := readSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("the spool should be kept, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,130 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/stacking"
+)
+
+// checkTargets rejects the options incompatible with the upload to several servers
+func (app *UpCmd) checkTargets() error {
+	if len(app.Targets) < 2 {
+		return nil
+	}
+	switch {
+	case app.Delete:
+		return fmt.Errorf("the option -delete can't be used with several profiles")
+	case app.Watch:
+		return fmt.Errorf("the option -watch can't be used with several profiles")
+	case app.Every > 0:
+		return fmt.Errorf("the option -every can't be used with several profiles")
+	case app.Offline || app.XMPOnly:
+		return fmt.Errorf("the options -offline and -xmp-only can't be used with several profiles")
+	case app.Incremental:
+		return fmt.Errorf("the option -incremental can't be used with several profiles")
+	}
+	// the user interface shows a single server
+	app.NoUI = true
+	return nil
+}
+
+// runTargets uploads the assets to the server of each profile. The sources are browsed once,
+// and the checksums computed for a server are reused for the next ones.
+func (app *UpCmd) runTargets(ctx context.Context) error {
+	app.browser = &preparedBrowser{Browser: app.browser}
+	switchServer := app.switchServer
+	if switchServer == nil {
+		switchServer = app.SwitchServer
+	}
+	var errs error
+	for i, t := range app.Targets {
+		if i > 0 {
+			app.resetServerState()
+			err := switchServer(ctx, t)
+			if err != nil {
+				err = fmt.Errorf("profile %s: %w", t.Name, err)
+				app.Log.Error(err.Error())
+				errs = errors.Join(errs, err)
+				continue
+			}
+		}
+		server := t.Server
+		if server == "" {
+			server = t.API
+		}
+		msg := fmt.Sprintf("Upload to the profile %s, server %s", t.Name, server)
+		app.Log.Info(msg)
+		if !app.Quiet {
+			fmt.Println(msg)
+		}
+		err := app.runNoUI(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return errors.Join(errs, err)
+			}
+			err = fmt.Errorf("profile %s: %w", t.Name, err)
+			app.Log.Error(err.Error())
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
+
+// resetServerState forgets what is known about the previous server
+func (app *UpCmd) resetServerState() {
+	app.AssetIndex = nil
+	app.albums = nil
+	app.deleteServerList = nil
+	app.batch = nil
+	app.bulkDuplicates = sync.Map{}
+	app.peopleLock.Lock()
+	app.people = nil
+	app.peopleLock.Unlock()
+	if app.stacks != nil {
+		app.stacks = stacking.NewStackBuilder(app.supportedMedia())
+	}
+}
+
+// preparedBrowser prepares the sources only once, so they are browsed again for each server without walking them again
+type preparedBrowser struct {
+	browser.Browser
+	once sync.Once
+	err  error
+}
+
+func (b *preparedBrowser) Prepare(ctx context.Context) error {
+	b.once.Do(func() {
+		b.err = b.Browser.Prepare(ctx)
+	})
+	return b.err
+}
+
+// cachedChecksum sets the checksum of the asset when it has been computed for a previous server
+func (app *UpCmd) cachedChecksum(a *browser.LocalAssetFile) bool {
+	if c, ok := app.checksums.Load(checksumKey(a)); ok {
+		a.Checksum = c.(string)
+		return true
+	}
+	return false
+}
+
+// keepChecksum remembers the checksum of the asset for the next servers
+func (app *UpCmd) keepChecksum(a *browser.LocalAssetFile) {
+	if len(app.Targets) > 1 && a.Checksum != "" {
+		app.checksums.Store(checksumKey(a), a.Checksum)
+	}
+}
+
+// checksumKey identifies the file by its source, its name, its size and its modification time
+func checksumKey(a *browser.LocalAssetFile) string {
+	key := fmt.Sprintf("%s|%s|%d", sourceName(a.FSys), a.FileName, a.FileSize)
+	if fi, err := fs.Stat(a.FSys, a.FileName); err == nil {
+		key += "|" + fi.ModTime().String()
+	}
+	return key
+}
@@ -0,0 +1,157 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestTargets(t *testing.T) {
+	home := &icBulkCheck{
+		icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+		onServer: map[string]string{
+			fileChecksum(t, "TEST_DATA/folder/high/AlbumA/PXL_20231006_063000139.jpg"): "server1",
+		},
+	}
+	offsite := &icBulkCheck{
+		icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+		onServer:             map[string]string{},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: home,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+		Targets: []cmd.Target{
+			{Name: "home", Server: "http://home:2283", Key: "K1"},
+			{Name: "offsite", Server: "http://offsite:2283", Key: "K2"},
+		},
+	}
+	ctx := context.Background()
+	app, err := newCommand(ctx, &serv, []string{"-bulk-check", "TEST_DATA/folder/high/AlbumA"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !app.NoUI {
+		t.Error("the user interface should be disabled with several targets")
+	}
+	var switched []string
+	app.switchServer = func(ctx context.Context, target cmd.Target) error {
+		switched = append(switched, target.Name)
+		app.Immich = offsite
+		return nil
+	}
+	err = app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmpSlices([]string{"offsite"}, switched) {
+		t.Errorf("unexpected switches: %v", switched)
+	}
+	expectedHome := []string{
+		"PXL_20231006_063029647.jpg",
+		"PXL_20231006_063108407.jpg",
+		"PXL_20231006_063121958.jpg",
+		"PXL_20231006_063357420.jpg",
+	}
+	if !cmpSlices(expectedHome, home.assets) {
+		t.Errorf("expected upload to home differs")
+		pretty.Ldiff(t, expectedHome, home.assets)
+	}
+	expectedOffsite := append([]string{"PXL_20231006_063000139.jpg"}, expectedHome...)
+	if !cmpSlices(expectedOffsite, offsite.assets) {
+		t.Errorf("expected upload to offsite differs")
+		pretty.Ldiff(t, expectedOffsite, offsite.assets)
+	}
+	if home.checked != 5 || offsite.checked != 5 {
+		t.Errorf("each server should check the 5 files, got %d and %d", home.checked, offsite.checked)
+	}
+	if n := app.progress.hashedFiles.Load(); n != 5 {
+		t.Errorf("the files should be hashed once, got %d hashes", n)
+	}
+}
+
+func TestTargetsIncompatibleOptions(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, option := range []string{"-delete", "-watch"} {
+		serv := cmd.SharedFlags{
+			Immich:  &icCatchUploadsAssets{albums: map[string][]string{}},
+			Log:     log,
+			Targets: []cmd.Target{{Name: "home"}, {Name: "offsite"}},
+		}
+		_, err := newCommand(context.Background(), &serv, []string{option, "TEST_DATA/folder/high/AlbumA"}, nil)
+		if err == nil {
+			t.Errorf("the option %s should be rejected with several profiles", option)
+		}
+	}
+}
+
+// the assets collected for the confirmation of the plan are shared by the servers: they are corrected once
+func TestTargetsCorrectOnce(t *testing.T) {
+	const sidecar = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="4"/>
+</rdf:RDF></x:xmpmeta>`
+	const name = "PXL_20231006_063000139.jpg"
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("TEST_DATA/folder/low", name), filepath.Join(dir, name))
+	err := os.WriteFile(filepath.Join(dir, name+".xmp"), []byte(sidecar), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newServer := func() *icCatchMetadata {
+		return &icCatchMetadata{
+			icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+			metadata:             map[string]metadata.Metadata{},
+		}
+	}
+	home, offsite := newServer(), newServer()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: home,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+		Targets: []cmd.Target{
+			{Name: "home", Server: "http://home:2283", Key: "K1"},
+			{Name: "offsite", Server: "http://offsite:2283", Key: "K2"},
+		},
+	}
+	ctx := context.Background()
+	app, err := newCommand(ctx, &serv, []string{"-time-shift=+2h", "-rating-to-tag", dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	app.confirm, app.stdin, app.stdout = true, strings.NewReader("y\n"), &out
+	app.switchServer = func(ctx context.Context, target cmd.Target) error {
+		app.Immich = offsite
+		return nil
+	}
+	err = app.run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, o := home.metadata[name], offsite.metadata[name]
+	if h.DateTaken.IsZero() || !h.DateTaken.Equal(o.DateTaken) {
+		t.Errorf("expected the same date on both servers, got %s and %s", h.DateTaken, o.DateTaken)
+	}
+	for _, md := range []metadata.Metadata{h, o} {
+		if !reflect.DeepEqual(md.Tags, []string{"Rating/4"}) {
+			t.Errorf("expected the rating tag once, got %v", md.Tags)
+		}
+	}
+}
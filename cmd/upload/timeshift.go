@@ -0,0 +1,141 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/immich"
+)
+
+// timeShift corrects the date of capture of the assets taken by a camera with a wrong clock
+type timeShift struct {
+	model  string               // camera model, when the shift is scoped by model
+	folder namematcher.PathList // folder pattern, when the shift is scoped by folder
+	shift  time.Duration
+	value  string
+}
+
+// TimeShifts is the list of the shifts given by the -time-shift options.
+// The first matching shift is applied.
+//
+// A shift is given as:
+//   - DURATION: applies to all assets, ex: -1h30m
+//   - model:MODEL=DURATION: applies to the assets taken by the camera model, ex: model:Canon EOS 5D=+2h
+//   - folder:PATTERN=DURATION: applies to the assets of the folders matching the pattern, ex: folder:**/Trip=-1h
+type TimeShifts []timeShift
+
+func (ts *TimeShifts) Set(s string) error {
+	t := timeShift{value: s}
+	d := s
+	switch {
+	case strings.HasPrefix(strings.ToLower(s), "model:"):
+		i := strings.LastIndex(s, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid time shift %q, expected model:MODEL=DURATION", s)
+		}
+		t.model = strings.TrimSpace(s[len("model:"):i])
+		d = s[i+1:]
+	case strings.HasPrefix(strings.ToLower(s), "folder:"):
+		i := strings.LastIndex(s, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid time shift %q, expected folder:PATTERN=DURATION", s)
+		}
+		err := t.folder.Set(s[len("folder:"):i])
+		if err != nil {
+			return err
+		}
+		d = s[i+1:]
+	}
+	var err error
+	t.shift, err = time.ParseDuration(strings.TrimSpace(d))
+	if err != nil {
+		return fmt.Errorf("invalid time shift %q: %w", s, err)
+	}
+	*ts = append(*ts, t)
+	return nil
+}
+
+func (ts TimeShifts) String() string {
+	l := []string{}
+	for _, t := range ts {
+		l = append(l, t.value)
+	}
+	return strings.Join(l, ", ")
+}
+
+// needsModel is true when a shift is scoped by camera model
+func (ts TimeShifts) needsModel() bool {
+	for _, t := range ts {
+		if t.model != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// shiftFor gives the shift matching the asset
+func (ts TimeShifts) shiftFor(folder string, model string) (timeShift, bool) {
+	for _, t := range ts {
+		switch {
+		case t.model != "":
+			if strings.EqualFold(t.model, strings.TrimSpace(model)) {
+				return t, true
+			}
+		case t.folder.IsSet():
+			if t.folder.Match(folder) {
+				return t, true
+			}
+		default:
+			return t, true
+		}
+	}
+	return timeShift{}, false
+}
+
+// fixDate corrects the date of capture with the time zone of the GPS location and the -time-shift options.
+// Both corrections are applied once, the date can be corrected before being ordered.
+func (app *UpCmd) fixDate(ctx context.Context, a *browser.LocalAssetFile) {
+	app.zoneTime(ctx, a)
+	app.shiftTime(ctx, a)
+}
+
+// shiftTime applies the -time-shift options to the date of capture of the asset.
+// The asset is shifted once, even when it is uploaded to several servers.
+func (app *UpCmd) shiftTime(ctx context.Context, a *browser.LocalAssetFile) {
+	if len(app.TimeShifts) == 0 || a.Shifted || a.Metadata.DateTaken.IsZero() {
+		return
+	}
+	a.Shifted = true
+	if app.TimeShifts.needsModel() && a.Metadata.Model == "" {
+		if md, ok := app.readFileMetadata(a); ok {
+			a.Metadata.Model = md.Model
+		}
+	}
+	t, ok := app.TimeShifts.shiftFor(path.Dir(a.FileName), a.Metadata.Model)
+	if !ok {
+		return
+	}
+	a.Metadata.DateTaken, a.DateFixed = a.Metadata.DateTaken.Add(t.shift), true
+	if a.LivePhoto != nil && !a.LivePhoto.Metadata.DateTaken.IsZero() {
+		a.LivePhoto.Metadata.DateTaken = a.LivePhoto.Metadata.DateTaken.Add(t.shift)
+	}
+	app.Jnl.Record(ctx, fileevent.INFO, nil, a.FileName, "time shift", t.value, "capture date", a.Metadata.DateTaken.String())
+}
+
+// sendFixedDate gives the corrected date of capture to the server. The server reads the date from the asset's own sidecar,
+// the one transmitted with the XMP data of the asset is ignored.
+func (app *UpCmd) sendFixedDate(ctx context.Context, a *browser.LocalAssetFile, id string) {
+	if !a.DateFixed || !a.SideCar.IsSet() {
+		return
+	}
+	err := app.Immich.UpdateAssetMetadata(ctx, id, immich.AssetMetadataUpdate{DateTimeOriginal: a.Metadata.DateTaken.Format(time.RFC3339)})
+	if err != nil {
+		app.Jnl.Record(ctx, fileevent.UploadServerError, a, a.FileName, "error", "can't set the capture date: "+err.Error())
+	}
+}
@@ -0,0 +1,135 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestTimeShiftsSet(t *testing.T) {
+	tests := []struct {
+		value   string
+		folder  string
+		model   string
+		shift   time.Duration
+		match   bool
+		wantErr bool
+	}{
+		{value: "-1h30m", folder: "a/b", shift: -90 * time.Minute, match: true},
+		{value: "model:Pixel 7=+2h", model: "pixel 7", shift: 2 * time.Hour, match: true},
+		{value: "model:Pixel 7=+2h", model: "Pixel 6"},
+		{value: "folder:**/Trip=-1h", folder: "2023/Trip", shift: -time.Hour, match: true},
+		{value: "folder:**/Trip=-1h", folder: "2023/Home"},
+		{value: "model:Pixel 7", wantErr: true},
+		{value: "folder:**/Trip", wantErr: true},
+		{value: "one hour", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var ts TimeShifts
+			err := ts.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			s, ok := ts.shiftFor(tt.folder, tt.model)
+			if ok != tt.match {
+				t.Fatalf("expected match %v, got %v", tt.match, ok)
+			}
+			if s.shift != tt.shift {
+				t.Errorf("expected shift %s, got %s", tt.shift, s.shift)
+			}
+		})
+	}
+}
+
+func TestTimeShiftUpload(t *testing.T) {
+	const asset = "Google Photos/Photos from 2023/PXL_20231006_063000139.jpg"
+	upload := func(args ...string) metadata.Metadata {
+		ic := &icCatchMetadata{
+			icCatchUploadsAssets: icCatchUploadsAssets{albums: map[string][]string{}},
+			metadata:             map[string]metadata.Metadata{},
+		}
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+		serv := cmd.SharedFlags{
+			Immich: ic,
+			Jnl:    fileevent.NewRecorder(log, false),
+			Log:    log,
+		}
+		err := UploadCommand(context.Background(), &serv, append(append([]string{"-no-ui", "-google-photos"}, args...), "TEST_DATA/Takeout2"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return ic.metadata[asset]
+	}
+
+	original := upload().DateTaken
+	if original.IsZero() {
+		t.Fatalf("the asset %s hasn't been uploaded", asset)
+	}
+	tests := []struct {
+		args     []string
+		expected time.Time
+	}{
+		{args: []string{"-time-shift=-1h30m"}, expected: original.Add(-90 * time.Minute)},
+		{args: []string{"-time-shift=folder:**/Photos from 2023=+2h", "-time-shift=-1h"}, expected: original.Add(2 * time.Hour)},
+		{args: []string{"-time-shift=folder:**/Trip=+2h"}, expected: original},
+	}
+	for _, tt := range tests {
+		got := upload(tt.args...).DateTaken
+		if !got.Equal(tt.expected) {
+			t.Errorf("%v: expected %s, got %s", tt.args, tt.expected, got)
+		}
+	}
+}
+
+// icCatchDateUpdates keeps the dates of capture set after the upload
+type icCatchDateUpdates struct {
+	icCatchUploadsAssets
+	dates map[string]string
+}
+
+func (c *icCatchDateUpdates) UpdateAssetMetadata(ctx context.Context, id string, update immich.AssetMetadataUpdate) error {
+	c.dates[id] = update.DateTimeOriginal
+	return nil
+}
+
+func TestSendFixedDate(t *testing.T) {
+	date := time.Date(2023, 10, 6, 8, 30, 0, 0, time.UTC)
+	fsys := fstest.MapFS{"photo.jpg.xmp": {Data: []byte("<x:xmpmeta/>")}}
+	tests := []struct {
+		name     string
+		sidecar  bool
+		fixed    bool
+		expected string
+	}{
+		{name: "own sidecar, date fixed", sidecar: true, fixed: true, expected: "2023-10-06T08:30:00Z"},
+		{name: "own sidecar", sidecar: true},
+		{name: "date fixed, transmitted with the XMP data", fixed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ic := &icCatchDateUpdates{dates: map[string]string{}}
+			app := &UpCmd{SharedFlags: &cmd.SharedFlags{Immich: ic, Jnl: fileevent.NewRecorder(nil, false)}}
+			a := &browser.LocalAssetFile{FileName: "photo.jpg", DateFixed: tt.fixed, Metadata: metadata.Metadata{DateTaken: date}}
+			if tt.sidecar {
+				a.SideCar = metadata.SideCarFile{FSys: fsys, FileName: "photo.jpg.xmp"}
+			}
+			app.sendFixedDate(context.Background(), a, "id")
+			if got := ic.dates["id"]; got != tt.expected {
+				t.Errorf("expected the date %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
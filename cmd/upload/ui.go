@@ -31,6 +31,7 @@ type uiPage struct {
 	// prevLogFile   io.WriteCloser
 	lastTimeServerActive atomic.Int64
 
+	progress      *tview.TextView // throughput and remaining time
 	immichReading *tvxwidgets.PercentageModeGauge
 	immichPrepare *tvxwidgets.PercentageModeGauge
 	immichUpload  *tvxwidgets.PercentageModeGauge
@@ -44,6 +45,9 @@ func (app *UpCmd) runUI(ctx context.Context) error {
 	ctx, cancel := context.WithCancelCause(ctx)
 
 	uiApp := tview.NewApplication()
+	if app.NoColor {
+		monochrome()
+	}
 	ui := newUI(ctx, app)
 
 	defer cancel(nil)
@@ -75,6 +79,10 @@ func (app *UpCmd) runUI(ctx context.Context) error {
 			if uploadDone.Load() {
 				stopUI(nil)
 			}
+		case tcell.KeyRune:
+			if (event.Rune() == 'p' || event.Rune() == 'P') && !uploadDone.Load() {
+				app.togglePause()
+			}
 		}
 		return event
 	})
@@ -123,10 +131,16 @@ func (app *UpCmd) runUI(ctx context.Context) error {
 				return
 			case <-tick.C:
 				uiApp.QueueUpdateDraw(func() {
+					if app.pause.Paused() {
+						ui.uploadCounts.SetTitle("Uploading (paused, press [p] to resume)")
+					} else {
+						ui.uploadCounts.SetTitle("Uploading ([p] to pause)")
+					}
 					counts := app.Jnl.GetCounts()
 					for c := range ui.counts {
 						ui.getCountView(c, counts[c])
 					}
+					ui.progress.SetText(app.progressStats().String())
 					if app.GooglePhotos {
 						ui.immichPrepare.SetMaxValue(int(app.Jnl.TotalAssets()))
 						ui.immichPrepare.SetValue(int(app.Jnl.TotalProcessedGP()))
@@ -186,6 +200,7 @@ func (app *UpCmd) runUI(ctx context.Context) error {
 			return context.Cause(ctx)
 		}
 		preparationDone.Store(true)
+		app.progress.startUpload()
 
 		// we can upload assets
 		err = app.uploadLoop(ctx)
@@ -225,6 +240,23 @@ func (app *UpCmd) runUI(ctx context.Context) error {
 	return err
 }
 
+// monochrome makes the user interface use the colors of the terminal
+func monochrome() {
+	tview.Styles = tview.Theme{
+		PrimitiveBackgroundColor:    tcell.ColorDefault,
+		ContrastBackgroundColor:     tcell.ColorDefault,
+		MoreContrastBackgroundColor: tcell.ColorDefault,
+		BorderColor:                 tcell.ColorDefault,
+		TitleColor:                  tcell.ColorDefault,
+		GraphicsColor:               tcell.ColorDefault,
+		PrimaryTextColor:            tcell.ColorDefault,
+		SecondaryTextColor:          tcell.ColorDefault,
+		TertiaryTextColor:           tcell.ColorDefault,
+		InverseTextColor:            tcell.ColorDefault,
+		ContrastSecondaryTextColor:  tcell.ColorDefault,
+	}
+}
+
 func newModal(message string) tview.Primitive {
 	message += "\nYou can quit the program safely.\n\nPress the [enter] key to exit."
 	lines := strings.Count(message, "\n")
@@ -279,7 +311,8 @@ func newUI(ctx context.Context, app *UpCmd) *uiPage {
 	ui.addCounter(ui.uploadCounts, 3, "Server's asset upgraded", fileevent.UploadUpgraded)
 	ui.addCounter(ui.uploadCounts, 4, "Server has same quality", fileevent.UploadServerDuplicate)
 	ui.addCounter(ui.uploadCounts, 5, "Server has better quality", fileevent.UploadServerBetter)
-	ui.uploadCounts.SetSize(6, 2, 1, 1).SetColumns(30, 10)
+	ui.addCounter(ui.uploadCounts, 6, "Unchanged since their upload", fileevent.UploadUnchanged)
+	ui.uploadCounts.SetSize(7, 2, 1, 1).SetColumns(30, 10)
 
 	if _, err := app.Immich.GetJobs(ctx); err == nil {
 		ui.watchJobs = true
@@ -287,8 +320,10 @@ func newUI(ctx context.Context, app *UpCmd) *uiPage {
 		ui.serverJobs = tvxwidgets.NewSparkline()
 		ui.serverJobs.SetBorder(true).SetTitle("Server pending jobs")
 		ui.serverJobs.SetData(ui.serverActivity)
-		ui.serverJobs.SetDataTitleColor(tcell.ColorDarkOrange)
-		ui.serverJobs.SetLineColor(tcell.ColorSteelBlue)
+		if !app.NoColor {
+			ui.serverJobs.SetDataTitleColor(tcell.ColorDarkOrange)
+			ui.serverJobs.SetLineColor(tcell.ColorSteelBlue)
+		}
 	}
 
 	counts := tview.NewGrid()
@@ -341,10 +376,12 @@ func newUI(ctx context.Context, app *UpCmd) *uiPage {
 	} else {
 		ui.footer.SetColumns(25, 0)
 	}
-	ui.screen.AddItem(ui.footer, 3, 0, 1, 1, 0, 0, false)
+	ui.progress = tview.NewTextView().SetTextAlign(tview.AlignCenter)
+	ui.screen.AddItem(ui.progress, 3, 0, 1, 1, 0, 0, false)
+	ui.screen.AddItem(ui.footer, 4, 0, 1, 1, 0, 0, false)
 
 	// Adjust section's height
-	ui.screen.SetRows(4, 10, 0, 1)
+	ui.screen.SetRows(4, 10, 0, 1, 1)
 	return ui
 }
 
@@ -3,15 +3,21 @@
 package upload
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"math"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -20,11 +26,18 @@ import (
 	"github.com/simulot/immich-go/browser/files"
 	"github.com/simulot/immich-go/browser/gp"
 	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/configuration"
 	"github.com/simulot/immich-go/helpers/fileevent"
 	"github.com/simulot/immich-go/helpers/fshelper"
 	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/geocode"
+	"github.com/simulot/immich-go/helpers/keeprules"
+	"github.com/simulot/immich-go/helpers/membudget"
+	"github.com/simulot/immich-go/helpers/metacache"
 	"github.com/simulot/immich-go/helpers/myflag"
 	"github.com/simulot/immich-go/helpers/namematcher"
+	"github.com/simulot/immich-go/helpers/notify"
+	"github.com/simulot/immich-go/helpers/scandb"
 	"github.com/simulot/immich-go/helpers/stacking"
 	"github.com/simulot/immich-go/immich"
 	"github.com/simulot/immich-go/internal/fakefs"
@@ -33,45 +46,145 @@ import (
 type UpCmd struct {
 	*cmd.SharedFlags // shared flags and immich client
 
-	fsyss []fs.FS // pseudo file system to browse
-
-	GooglePhotos           bool             // For reading Google Photos takeout files
-	Delete                 bool             // Delete original file after import
-	CreateAlbumAfterFolder bool             // Create albums for assets based on the parent folder or a given name
-	UseFullPathAsAlbumName bool             // Create albums for assets based on the full path to the asset
-	AlbumNamePathSeparator string           // Determines how multiple (sub) folders, if any, will be joined
-	ImportIntoAlbum        string           // All assets will be added to this album
-	PartnerAlbum           string           // Partner's assets will be added to this album
-	Import                 bool             // Import instead of upload
-	DeviceUUID             string           // Set a device UUID
-	Paths                  []string         // Path to explore
-	DateRange              immich.DateRange // Set capture date range
-	ImportFromAlbum        string           // Import assets from this albums
-	CreateAlbums           bool             // Create albums when exists in the source
-	KeepTrashed            bool             // Import trashed assets
-	KeepPartner            bool             // Import partner's assets
-	KeepUntitled           bool             // Keep untitled albums
-	UseFolderAsAlbumName   bool             // Use folder's name instead of metadata's title as Album name
-	DryRun                 bool             // Display actions but don't change anything
-	CreateStacks           bool             // Stack jpg/raw/burst (Default: TRUE)
-	StackJpgRaws           bool             // Stack jpg/raw (Default: TRUE)
-	StackBurst             bool             // Stack burst (Default: TRUE)
-	DiscardArchived        bool             // Don't import archived assets (Default: FALSE)
-	AutoArchive            bool             // Automatically archive photos that are also archived in google photos (Default: TRUE)
-	WhenNoDate             string           // When the date can't be determined use the FILE's date or NOW (default: FILE)
-	ForceUploadWhenNoJSON  bool             // Some takeout don't supplies all JSON. When true, files are uploaded without any additional metadata
-	BannedFiles            namematcher.List // List of banned file name patterns
+	fsyss  []fs.FS  // pseudo file system to browse
+	openFS fsOpener // open the file systems to browse
+
+	GooglePhotos           bool                 // For reading Google Photos takeout files
+	Delete                 bool                 // Delete original file after import
+	CreateAlbumAfterFolder bool                 // Create albums for assets based on the parent folder or a given name
+	UseFullPathAsAlbumName bool                 // Create albums for assets based on the full path to the asset
+	AlbumNamePathSeparator string               // Determines how multiple (sub) folders, if any, will be joined
+	ImportIntoAlbum        string               // All assets will be added to this album
+	PartnerAlbum           string               // Partner's assets will be added to this album
+	Import                 bool                 // Import instead of upload
+	DeviceUUID             string               // Set a device UUID
+	Paths                  []string             // Path to explore
+	DateRange              immich.DateRange     // Set capture date range
+	ImportFromAlbum        string               // Import assets from this albums
+	CreateAlbums           bool                 // Create albums when exists in the source
+	KeepTrashed            bool                 // Import trashed assets
+	KeepPartner            bool                 // Import partner's assets
+	KeepUntitled           bool                 // Keep untitled albums
+	UseFolderAsAlbumName   bool                 // Use folder's name instead of metadata's title as Album name
+	DryRun                 bool                 // Display actions but don't change anything
+	CreateStacks           bool                 // Stack jpg/raw/burst (Default: TRUE)
+	StackJpgRaws           bool                 // Stack jpg/raw (Default: TRUE)
+	StackBurst             bool                 // Stack burst (Default: TRUE)
+	DiscardArchived        bool                 // Don't import archived assets (Default: FALSE)
+	AutoArchive            bool                 // Automatically archive photos that are also archived in google photos (Default: TRUE)
+	WhenNoDate             string               // When the date can't be determined use the FILE's date or NOW (default: FILE)
+	DateFrom               StringList           // Sources of the date of take, by order of priority (default: json,filename,exif)
+	TypeDetection          string               // Detect the type of the files by their EXTENSION, CHECK the content, or trust the CONTENT (default: EXTENSION)
+	ForceUploadWhenNoJSON  bool                 // Some takeout don't supplies all JSON. When true, files are uploaded without any additional metadata
+	BannedFiles            namematcher.List     // List of banned file name patterns
+	IncludePaths           namematcher.PathList // Only files matching those full path patterns are imported
+	ExcludePaths           namematcher.PathList // Files matching those full path patterns are ignored
+	Order                  string               // Upload order: oldest-first, newest-first, path (default: as discovered)
+	Select                 bool                 // Choose the folders and albums to upload in a tree view
+	Yes                    bool                 // Upload without asking the confirmation of the plan
+	Watch                  bool                 // Stay running and upload new files appearing in the folders
+	WatchDelay             time.Duration        // Wait this delay after the last change of a file before uploading it
+	Every                  time.Duration        // Rescan the sources and upload new assets periodically
+	LockFile               string               // Lock file preventing overlapping runs
+	ReadWorkers            int                  // Number of files read concurrently to prepare the assets
+	MaxMemory              int64                // Memory budget in bytes, 0 for no limit
+	BulkCheck              bool                 // Check the checksums of the assets against the server by batches
+	OnDuplicate            string               // Policy when the server has another version of the asset: skip, replace-if-larger, replace-if-newer, always-ask, keep-rules
+	KeepRules              keeprules.Rules      // Rules choosing between the local file and the server's asset
+	MappingFile            string               // Write the mapping of the local files to the Immich assets into this file
+	Report                 string               // Write the final report in JSON into this file
+	CSVReport              string               // Write the outcome of each file into this CSV file
+	HTMLReport             string               // Write a summary of the run into this HTML file
+	Output                 string               // Format of the standard output: text or ndjson
+	FailedList             string               // Write the names of the files in error into this file
+	RetryFrom              string               // Process only the files listed in this file
+	NotifyURL              string               // Webhook notified at the end of the run
+	NotifyFormat           string               // Format of the notifications: json, discord, slack, ntfy, gotify
+	NotifyErrors           int                  // Notify when the number of errors reaches this threshold, 0 to disable
+	SnapshotEvery          time.Duration        // Period of the snapshots of the counters, 0 to disable
+	SnapshotFile           string               // File replaced by each snapshot
+	AlbumCover             string               // Cover of the albums: first, newest, random or a file name pattern
+	AlbumOrder             string               // Order of the assets in the created albums: asc, desc
+	AlbumDescriptionFile   string               // Name of the file giving the description of folder albums
+	AlbumTemplate          string               // Template giving the album name of each asset
+	SpoolDir               string               // Folder of the offline spool
+	SpoolCopy              bool                 // Copy the files into the spool folder when offline
+	WriteXMP               string               // Write the gathered metadata into XMP sidecars: missing, overwrite
+	XMPOnly                bool                 // Write the XMP sidecars without uploading the assets
+	ReverseGeocode         string               // GeoNames cities file or Nominatim URL used to name the places of the assets
+	ReverseGeocodeInto     string               // Where the place names are written: tags, description
+	TimeShifts             TimeShifts           // Corrections of the date of capture
+	TimeZoneFromGPS        string               // GeoNames cities file giving the time zone of the GPS locations
+	FixSourceExif          bool                 // Write the date found in JSON files or file names into the source files
+	ImportCaptions         bool                 // Use the captions of the EXIF data and XMP sidecars as descriptions
+	ImportFaces            bool                 // Attach the persons named in the XMP face regions
+	RatingToFavorite       RatingThreshold      // Minimum xmp:Rating of the favorite assets
+	RatingToTag            bool                 // Tag the assets with their xmp:Rating
+	LivePhotoByID          bool                 // Pair the Live Photos by their content identifier
+	ExiftoolSidecars       bool                 // Read the .txt and .json sidecars exported by exiftool
+	FollowSymlinks         bool                 // Enter the folders given by symbolic links and junctions
+	IncludeHidden          bool                 // Browse the hidden files and folders
+	OneFileSystem          bool                 // Don't browse the folders mounted from other file systems
+	MetadataCache          bool                 // Keep the metadata read from the files between the runs
+	MetadataCacheFile      string               // File of the metadata cache
+	Incremental            bool                 // Skip the files unchanged since their upload by a previous run
+	IncrementalDir         string               // Folder of the databases of the uploaded files, one per server
+	WaitProcessing         bool                 // After the upload, wait for the server to process the uploaded assets
+	ShareLink              bool                 // Create a shared link for each album created
+	SharePassword          string               // Password of the shared links
+	ShareExpiry            time.Duration        // Validity of the shared links, 0 for no expiry
 
 	BrowserConfig Configuration
 
-	albums map[string]immich.AlbumSimplified // Albums by title
+	albums *albumCache // Albums of the server by title
 
 	AssetIndex       *AssetIndex               // List of assets present on the server
 	deleteServerList []*immich.Asset           // List of server assets to remove
 	deleteLocalList  []*browser.LocalAssetFile // List of local assets to remove
 	// updateAlbums     map[string]map[string]any // track immich albums changes
-	stacks  *stacking.StackBuilder
-	browser browser.Browser
+	stacks    *stacking.StackBuilder
+	metaCache *metacache.Cache // metadata read during the previous runs
+	scanDB    *scandb.DB       // files uploaded by the previous runs
+	browser   browser.Browser
+	pause     *pauseGate        // hold the upload loop when paused
+	memory    *membudget.Budget // memory allowed to the assets read in advance
+	progress  *progress         // throughput and remaining time
+
+	eventStream io.Writer // NDJSON stream of the events, the standard output by default
+
+	bulkDuplicates sync.Map                                             // server's asset ID by checksum, given by the bulk check
+	checksums      sync.Map                                             // checksums of the files, kept for the next servers
+	switchServer   func(ctx context.Context, t cmd.Target) error        // connects to the next server, SharedFlags.SwitchServer by default
+	chooseAssets   func(ctx context.Context, tree *selectionTree) error // lets the user choose the assets, runSelectionUI by default
+	confirm        bool                                                 // ask the confirmation of the plan before uploading
+	waitNextRun    func(ctx context.Context, next time.Time) error      // waits for the next run of -every, waitUntil by default
+
+	replaced  []replacement // server's assets replaced during the upload
+	stdin     io.Reader     // user's answers for the always-ask policy
+	stdout    io.Writer     // questions of the always-ask policy
+	askReader *bufio.Reader
+	askAnswer string     // answer given for all assets
+	console   sync.Mutex // prevent the progress line to overwrite a question
+
+	mapping  *mappingWriter  // local path to asset ID mapping
+	outcomes *reportOutcomes // outcomes of the assets for the CSV and HTML reports
+	covers   *coverSelector  // albums cover candidates
+	batch    *albumBatch     // album additions and asset updates waiting to be sent
+
+	shareLinks []albumShareLink // shared links of the albums created during the run
+
+	processing     *processingWatch // processing of the uploaded assets by the server
+	processingPoll time.Duration    // delay between two readings of the server's jobs
+
+	folderDescriptions map[string]string // description of folder albums by folder
+	albumTemplate      *template.Template
+
+	spool    *spoolWriter     // assets prepared while offline
+	geocoder geocode.Geocoder // place names of the GPS locations
+	gpsZones *geocode.Cities  // time zones of the GPS locations
+
+	people     map[string]string // person IDs by lower case name, read when the first face is imported
+	peopleLock sync.Mutex
 }
 
 func UploadCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
@@ -79,22 +192,63 @@ func UploadCommand(ctx context.Context, common *cmd.SharedFlags, args []string)
 	if err != nil {
 		return err
 	}
+	if app.Output == OutputNDJSON {
+		// the standard output is reserved to the events
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = stdout }()
+	}
+	if app.Every > 0 {
+		return app.runEvery(ctx)
+	}
 	if len(app.fsyss) == 0 {
 		return nil
 	}
-	return app.run(ctx)
+	return app.exitStatus(app.runLocked(ctx))
+}
+
+// runSummary is the message ending a run whose files have all been processed, it isn't a fatal error
+type runSummary string
+
+func (s runSummary) Error() string { return string(s) }
+
+// exitStatus gives the exit code of the upload: a fatal error first, then the files in error, then the duplicates
+func (app *UpCmd) exitStatus(err error) error {
+	counts := app.Jnl.GetCounts()
+	code := cmd.ExitOK
+	var summary runSummary
+	switch {
+	case err != nil && !errors.As(err, &summary):
+		// a fatal error, or the interruption reported by main, prevails over the errors of the files
+		return err
+	case counts[fileevent.Error]+counts[fileevent.UploadServerError] > 0:
+		code = cmd.ExitFileErrors
+		if err == nil {
+			err = errors.New("some files are in error")
+		}
+	case err != nil:
+		return err
+	case counts[fileevent.UploadServerDuplicate]+counts[fileevent.UploadServerBetter] > 0:
+		code = cmd.ExitServerDuplicates
+		err = fmt.Errorf("%d file(s) not uploaded because the server already has them", counts[fileevent.UploadServerDuplicate]+counts[fileevent.UploadServerBetter])
+	default:
+		return nil
+	}
+	return &cmd.ExitError{Code: code, Err: err}
 }
 
 type fsOpener func() ([]fs.FS, error)
 
 func newCommand(ctx context.Context, common *cmd.SharedFlags, args []string, fsOpener fsOpener) (*UpCmd, error) {
 	var err error
-	cmd := flag.NewFlagSet("upload", flag.ExitOnError)
+	cmd := flag.NewFlagSet("upload", flag.ContinueOnError)
 
 	app := UpCmd{
 		SharedFlags: common,
+		stdin:       os.Stdin,
+		stdout:      os.Stdout,
 	}
-	app.BannedFiles, err = namematcher.New(
+	app.BannedFiles = namematcher.MustList(
 		`@eaDir/`,
 		`@__thumb/`,          // QNAP
 		`SYNOFILE_THUMB_*.*`, // SYNOLOGY
@@ -102,9 +256,6 @@ func newCommand(ctx context.Context, common *cmd.SharedFlags, args []string, fsO
 		`thumbnails/`,        // Android photo
 		`.DS_Store/`,         // Mac OS custom attributes
 	)
-	if err != nil {
-		return nil, err
-	}
 
 	app.SharedFlags.SetFlags(cmd)
 	cmd.BoolFunc(
@@ -187,7 +338,275 @@ func newCommand(ctx context.Context, common *cmd.SharedFlags, args []string, fsO
 		"FILE",
 		" When the date of take can't be determined, use the FILE's date or the current time NOW. (default: FILE)")
 
-	cmd.Var(&app.BannedFiles, "exclude-files", "Ignore files based on a pattern. Case insensitive. Add one option for each pattern do you need.")
+	cmd.Var(&app.DateFrom,
+		"date-from",
+		" Sources of the date of take by order of priority, separated by a comma: exif, xmp, json, filename, filesystem. (default: json,filename,exif)")
+
+	cmd.StringVar(&app.TypeDetection,
+		"type-detection",
+		"EXTENSION",
+		" Detect the type of the files by their EXTENSION, CHECK the content and report the mismatches, or trust the file's CONTENT over its extension. (default: EXTENSION)")
+
+	cmd.StringVar(&app.Order,
+		"order",
+		OrderNone,
+		" Upload order: oldest-first, newest-first or path. (default: as discovered, folder by folder)")
+	cmd.BoolFunc(
+		"select",
+		" Before uploading, choose the folders and the albums to upload in a tree view (default: FALSE)",
+		myflag.BoolFlagFn(&app.Select, false))
+	cmd.BoolFunc(
+		"yes",
+		" Upload without asking the confirmation of the upload plan (default: FALSE)",
+		myflag.BoolFlagFn(&app.Yes, false))
+
+	cmd.BoolFunc(
+		"wait-processing",
+		" After the upload, follow the thumbnails and the jobs of the server until the uploaded assets are processed (default: FALSE)",
+		myflag.BoolFlagFn(&app.WaitProcessing, false))
+
+	cmd.BoolFunc(
+		"share-link",
+		" Create a shared link for each album created by the upload, and print it at the end (default: FALSE)",
+		myflag.BoolFlagFn(&app.ShareLink, false))
+	cmd.StringVar(&app.SharePassword,
+		"share-password",
+		"",
+		" Password protecting the shared links")
+	cmd.Func("share-expiry",
+		" Validity of the shared links, ex: 720h for 30 days (default: no expiry)",
+		myflag.DurationFlagFn(&app.ShareExpiry, 0))
+
+	cmd.BoolFunc(
+		"follow-symlinks",
+		" folder import only: Enter the folders given by symbolic links and junctions, the loops are detected (default: FALSE)",
+		myflag.BoolFlagFn(&app.FollowSymlinks, false))
+	cmd.BoolFunc(
+		"include-hidden",
+		" folder import only: Browse the hidden files and folders, their name begins with a dot or they have the hidden attribute on Windows (default: FALSE)",
+		myflag.BoolFlagFn(&app.IncludeHidden, false))
+	cmd.BoolFunc(
+		"one-file-system",
+		" folder import only: Don't browse the folders mounted from other file systems, like network shares or snapshots (default: FALSE)",
+		myflag.BoolFlagFn(&app.OneFileSystem, false))
+	cmd.BoolFunc(
+		"watch",
+		" folder import only: Stay running and upload new files as they appear in the folders (default: FALSE)",
+		myflag.BoolFlagFn(&app.Watch, false))
+	cmd.Func(
+		"watch-delay",
+		" with -watch: Upload a file when it hasn't changed during this delay (default: 10s)",
+		myflag.DurationFlagFn(&app.WatchDelay, 10*time.Second))
+
+	cmd.Func(
+		"every",
+		" Stay running, rescan the sources and upload the new assets periodically. Ex: 6h, 30m (default: run once)",
+		myflag.DurationFlagFn(&app.Every, 0))
+	cmd.StringVar(&app.LockFile,
+		"lock-file",
+		"",
+		" Prevent overlapping runs with this lock file (default with -every: "+configuration.DefaultLockFile()+")")
+
+	cmd.IntVar(&app.ReadWorkers,
+		"read-workers",
+		files.DefaultWorkers,
+		" folder import only: Number of files read concurrently to extract their metadata ahead of the upload")
+
+	cmd.Func("max-memory",
+		" Memory budget, ex: 512M, 2G. The files are read ahead of the upload only within the budget (default: no limit)",
+		func(s string) error {
+			n, err := membudget.ParseSize(s)
+			if err != nil {
+				return fmt.Errorf("can't parse the -max-memory parameter: %w", err)
+			}
+			app.MaxMemory = n
+			return nil
+		})
+
+	cmd.BoolFunc(
+		"bulk-check",
+		" Compute the checksums of the files and check them against the server by batches before uploading (default: FALSE)",
+		myflag.BoolFlagFn(&app.BulkCheck, false))
+
+	cmd.StringVar(&app.OnDuplicate,
+		"on-duplicate",
+		DuplicateReplaceIfLarger,
+		" When the server has another version of an asset: skip, replace-if-larger, replace-if-newer, always-ask or keep-rules")
+
+	cmd.Func("keep-rules",
+		" Ordered list of rules choosing between the local file and the server's asset: raw, size, resolution, earlier, albums, name:PATTERN. Implies -on-duplicate=keep-rules",
+		func(s string) error {
+			var err error
+			app.KeepRules, err = keeprules.Parse(s)
+			return err
+		})
+
+	cmd.StringVar(&app.MappingFile,
+		"mapping-file",
+		"",
+		" Write the path of each file with its Immich asset ID and album IDs into this file. The format is JSON when the file name ends with .json, CSV otherwise")
+
+	cmd.StringVar(&app.Report,
+		"report",
+		"",
+		" Write the counters of the run and the events of each file into this JSON file")
+
+	cmd.StringVar(&app.CSVReport,
+		"csv-report",
+		"",
+		" Write one row per file into this CSV file: path, action (uploaded, duplicate, discarded, error...), reason, date, albums and server asset ID")
+
+	cmd.StringVar(&app.HTMLReport,
+		"html-report",
+		"",
+		" Write a summary of the run into this HTML file: counters, albums, and thumbnails of the files in error or skipped")
+
+	cmd.StringVar(&app.Output,
+		"output",
+		OutputText,
+		" Format of the standard output: text for humans, or ndjson to write each event as a JSON object per line for the scripts")
+
+	cmd.StringVar(&app.FailedList,
+		"failed-list",
+		"",
+		" Write the names of the files in error into this file, to retry them with -retry-from")
+
+	cmd.StringVar(&app.RetryFrom,
+		"retry-from",
+		"",
+		" Process only the files listed in this file, written by -failed-list. Give the same folders as the first run")
+
+	cmd.StringVar(&app.NotifyURL,
+		"notify-url",
+		"",
+		" Post a notification to this webhook at the end of the run")
+	cmd.StringVar(&app.NotifyFormat,
+		"notify-format",
+		notify.FormatJSON,
+		" Format of the notifications: json, discord, slack, ntfy or gotify")
+	cmd.IntVar(&app.NotifyErrors,
+		"notify-errors",
+		0,
+		" Post a notification when the number of errors reaches this threshold, 0 to disable")
+	cmd.Func("snapshot-every",
+		" Write a JSON snapshot of the counters into the log at this period (ex: 10m), default no snapshot",
+		myflag.DurationFlagFn(&app.SnapshotEvery, 0))
+	cmd.StringVar(&app.SnapshotFile,
+		"snapshot-file",
+		"",
+		" Write the last snapshot of the counters into this file, every 10m unless -snapshot-every is given")
+
+	cmd.StringVar(&app.AlbumCover,
+		"album-cover",
+		"",
+		" Set the cover of the albums with the first, newest or a random asset added to them, or with the file matching a pattern, ex: cover.jpg (default: chosen by the server)")
+
+	cmd.StringVar(&app.AlbumOrder,
+		"album-order",
+		"",
+		" Order of the assets in the albums created by immich-go: asc or desc (default: chosen by the server)")
+	cmd.StringVar(&app.AlbumDescriptionFile,
+		"album-description-file",
+		"",
+		" with -create-album-folder: Use the content of this file found in the folder as the album's description, ex: README.txt")
+
+	cmd.StringVar(&app.AlbumTemplate,
+		"album-template",
+		"",
+		` Add the assets into the album named by this template, ex: "{{.Year}}/{{.Folder}}" or "{{.RootName}} - {{.PathJoin \" > \"}}"`)
+
+	cmd.BoolFunc(
+		"offline",
+		" Prepare the assets without connecting to the server. They are written into the spool folder and sent later with the flush command (default: FALSE)",
+		myflag.BoolFlagFn(&app.Offline, false))
+	cmd.StringVar(&app.SpoolDir,
+		"spool-dir",
+		configuration.DefaultSpoolDir(),
+		" with -offline: Folder of the spool")
+	cmd.BoolFunc(
+		"spool-copy",
+		" with -offline: Copy the files into the spool folder, so they can be sent even when the source isn't available anymore (default: FALSE)",
+		myflag.BoolFlagFn(&app.SpoolCopy, false))
+
+	cmd.BoolFunc(
+		"metadata-cache",
+		" Keep the metadata read from the files, so the next runs don't read them again. A file is read again when its size or its modification time change (default: FALSE)",
+		myflag.BoolFlagFn(&app.MetadataCache, false))
+	cmd.StringVar(&app.MetadataCacheFile,
+		"metadata-cache-file",
+		configuration.DefaultMetadataCacheFile(),
+		" with -metadata-cache: File of the metadata cache")
+
+	cmd.BoolFunc(
+		"incremental",
+		" folder import only: Skip without reading them the files uploaded by the previous runs, when their size and their modification time are unchanged (default: FALSE)",
+		myflag.BoolFlagFn(&app.Incremental, false))
+	cmd.StringVar(&app.IncrementalDir,
+		"incremental-dir",
+		configuration.DefaultIncrementalDir(),
+		" with -incremental: Folder of the databases of the uploaded files, one per server")
+
+	cmd.StringVar(&app.WriteXMP,
+		"write-xmp",
+		"",
+		" Write the date, the location, the description and the albums gathered for each asset into an XMP sidecar next to the file: missing (only for files without sidecar) or overwrite (also replace entirely the sidecars written by immich-go)")
+	cmd.BoolFunc(
+		"xmp-only",
+		" Write the XMP sidecars without uploading the assets. Implies -write-xmp=missing when not given (default: FALSE)",
+		myflag.BoolFlagFn(&app.XMPOnly, false))
+
+	cmd.StringVar(&app.ReverseGeocode,
+		"reverse-geocode",
+		"",
+		" Convert the GPS location of the assets into place names, using a GeoNames cities file (ex: cities15000.txt) or the URL of a Nominatim server")
+	cmd.StringVar(&app.ReverseGeocodeInto,
+		"reverse-geocode-into",
+		GeocodeIntoTags,
+		" with -reverse-geocode: Add the place names to the tags, or use them as description when the asset hasn't any: tags or description")
+	cmd.StringVar(&app.TimeZoneFromGPS,
+		"time-zone-from-gps",
+		"",
+		" Interpret the dates of capture given without time zone in the time zone of the GPS location, found with a GeoNames cities file (ex: cities15000.txt)")
+
+	cmd.Var(&app.TimeShifts,
+		"time-shift",
+		" Shift the date of capture of the assets, ex: -1h30m. Scope the shift with model:MODEL=DURATION or folder:PATTERN=DURATION. Add one option for each shift, the first matching one is applied")
+
+	cmd.BoolFunc(
+		"fix-source-exif",
+		" Write the date of capture found in the JSON file or in the file name into the EXIF data of the source JPEG files that haven't their own date (default: FALSE)",
+		myflag.BoolFlagFn(&app.FixSourceExif, false))
+
+	cmd.BoolFunc(
+		"import-captions",
+		" Use the caption found in the XMP sidecar (dc:description) or in the EXIF data (ImageDescription, UserComment) as the description of the assets that haven't any (default: FALSE)",
+		myflag.BoolFlagFn(&app.ImportCaptions, false))
+
+	cmd.BoolFunc(
+		"import-faces",
+		" Attach the persons named in the face regions of the XMP data (mwg-rs:Regions, written by Picasa, digiKam or Lightroom) to the uploaded assets. The missing persons are created (default: FALSE)",
+		myflag.BoolFlagFn(&app.ImportFaces, false))
+
+	cmd.Var(&app.RatingToFavorite,
+		"rating-to-favorite",
+		" Mark as favorite the assets whose XMP rating matches the number of stars, ex: >=4, =5")
+	cmd.BoolFunc(
+		"rating-to-tag",
+		" Tag the assets with their XMP rating, like Rating/4 (default: FALSE)",
+		myflag.BoolFlagFn(&app.RatingToTag, false))
+	cmd.BoolFunc(
+		"live-photo-by-id",
+		" Pair the photos and the videos of Live Photos by the content identifier written by Apple devices, when their names don't match. The files are read during the discovery (default: FALSE)",
+		myflag.BoolFlagFn(&app.LivePhotoByID, false))
+	cmd.BoolFunc(
+		"exiftool-sidecars",
+		" Read the captions of the .txt files and the metadata of the .json files exported by exiftool -json, named like the XMP sidecars, and the .picasa.ini files (default: FALSE)",
+		myflag.BoolFlagFn(&app.ExiftoolSidecars, false))
+
+	cmd.Var(&app.BannedFiles, "exclude-files", "Ignore files based on a pattern. Case insensitive. Add one option for each pattern do you need. Use ** to match any folders, re: for a regular expression, ! to include again the files excluded by a previous pattern.")
+
+	cmd.Var(&app.IncludePaths, "include-path", "Import only files whose path matches a pattern (glob with ** or regular expression prefixed by re: or starting with ^). Case insensitive. Add one option for each pattern do you need.")
+	cmd.Var(&app.ExcludePaths, "exclude-path", "Ignore files whose path matches a pattern (glob with ** or regular expression prefixed by re: or starting with ^). Case insensitive. Add one option for each pattern do you need.")
 
 	cmd.BoolVar(&app.ForceUploadWhenNoJSON, "upload-when-missing-JSON", app.ForceUploadWhenNoJSON, "when true, photos are upload even without associated JSON file.")
 	cmd.BoolVar(&app.DebugFileList, "debug-file-list", app.DebugFileList, "Check how the your file list would be processed")
@@ -217,17 +636,247 @@ func newCommand(ctx context.Context, common *cmd.SharedFlags, args []string, fsO
 		return nil, fmt.Errorf("the -when-no-date accepts FILE or NOW")
 	}
 
+	for i, src := range app.DateFrom {
+		src = strings.ToLower(strings.TrimSpace(src))
+		switch src {
+		case files.DateFromEXIF, files.DateFromXMP, files.DateFromJSON, files.DateFromFilename, files.DateFromFilesystem:
+		default:
+			return nil, fmt.Errorf("the -date-from accepts exif, xmp, json, filename or filesystem")
+		}
+		if slices.Contains(app.DateFrom[:i], src) {
+			return nil, fmt.Errorf("the -date-from source %s is given twice", src)
+		}
+		app.DateFrom[i] = src
+	}
+
+	app.TypeDetection = strings.ToUpper(app.TypeDetection)
+	switch app.TypeDetection {
+	case "EXTENSION", "CHECK", "CONTENT":
+	default:
+		return nil, fmt.Errorf("the -type-detection accepts EXTENSION, CHECK or CONTENT")
+	}
+
+	app.Order, err = validateOrder(app.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	onDuplicateSet := false
+	cmd.Visit(func(f *flag.Flag) { onDuplicateSet = onDuplicateSet || f.Name == "on-duplicate" })
+	if app.KeepRules.IsSet() && !onDuplicateSet {
+		app.OnDuplicate = DuplicateKeepRules
+	}
+	app.OnDuplicate, err = validateOnDuplicate(app.OnDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	if app.RetryFrom != "" && app.GooglePhotos {
+		return nil, fmt.Errorf("the option -retry-from can't be used with -google-photos, run the upload of the takeout again: the assets already on the server are skipped")
+	}
+
+	switch app.Output {
+	case OutputText:
+	case OutputNDJSON:
+		// the user interface would overwrite the stream
+		app.NoUI = true
+	default:
+		return nil, fmt.Errorf("the -output accepts %s or %s", OutputText, OutputNDJSON)
+	}
+
+	if app.NotifyURL != "" {
+		err = notify.Validate(app.NotifyFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if app.NotifyErrors < 0 {
+		return nil, fmt.Errorf("the option -notify-errors must be positive")
+	}
+	if app.NotifyErrors > 0 && app.NotifyURL == "" {
+		return nil, fmt.Errorf("the option -notify-errors needs the -notify-url option")
+	}
+
+	if app.SnapshotEvery < 0 {
+		return nil, fmt.Errorf("the option -snapshot-every must be positive")
+	}
+	if app.SnapshotFile != "" && app.SnapshotEvery == 0 {
+		app.SnapshotEvery = snapshotDefaultPeriod
+	}
+
+	if app.OnDuplicate == DuplicateKeepRules && !app.KeepRules.IsSet() {
+		return nil, fmt.Errorf("the -on-duplicate=%s needs the -keep-rules option", DuplicateKeepRules)
+	}
+	app.AlbumOrder = strings.ToLower(app.AlbumOrder)
+	switch app.AlbumOrder {
+	case "", immich.AlbumOrderAsc, immich.AlbumOrderDesc:
+	default:
+		return nil, fmt.Errorf("the -album-order accepts %s or %s", immich.AlbumOrderAsc, immich.AlbumOrderDesc)
+	}
+
+	if app.AlbumTemplate != "" {
+		app.albumTemplate, err = parseAlbumTemplate(app.AlbumTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	app.covers, err = newCoverSelector(app.AlbumCover)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.OnDuplicate == DuplicateAlwaysAsk {
+		// the questions are asked on the console
+		app.NoUI = true
+	}
+	if app.Quiet || app.Verbose {
+		// only the summary or the decisions are written on the console
+		app.NoUI = true
+	}
+
+	if app.Watch {
+		if app.GooglePhotos {
+			return nil, fmt.Errorf("the option -watch can't be used with -google-photos")
+		}
+		err = validateWatchedFolders(cmd.Args())
+		if err != nil {
+			return nil, err
+		}
+		if app.WatchDelay <= 0 {
+			return nil, fmt.Errorf("the option -watch-delay must be positive")
+		}
+		app.Paths = cmd.Args()
+		// the user interface isn't suitable for a long running process
+		app.NoUI = true
+	}
+
+	if app.ReadWorkers < 1 {
+		return nil, fmt.Errorf("the option -read-workers must be at least 1")
+	}
+	app.memory = membudget.New(app.MaxMemory)
+	if app.Incremental && app.XMPOnly {
+		return nil, fmt.Errorf("the option -incremental can't be used with -xmp-only")
+	}
+
+	app.ReverseGeocodeInto, err = validateGeocodeInto(app.ReverseGeocodeInto)
+	if err != nil {
+		return nil, err
+	}
+	if app.ReverseGeocode != "" {
+		app.geocoder, err = geocode.New(app.ReverseGeocode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if app.TimeZoneFromGPS != "" {
+		app.gpsZones, err = geocode.OpenCitiesFile(app.TimeZoneFromGPS)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the cities file: %w", err)
+		}
+	}
+
+	app.WriteXMP, err = validateWriteXMP(app.WriteXMP)
+	if err != nil {
+		return nil, err
+	}
+	if app.XMPOnly {
+		switch {
+		case app.Offline:
+			return nil, fmt.Errorf("the options -xmp-only and -offline can't be used together")
+		case app.Watch:
+			return nil, fmt.Errorf("the options -xmp-only and -watch can't be used together")
+		case app.Every > 0:
+			return nil, fmt.Errorf("the options -xmp-only and -every can't be used together")
+		}
+		if app.WriteXMP == "" {
+			app.WriteXMP = XMPMissing
+		}
+		// the server isn't needed
+		app.Offline = true
+		app.NoUI = true
+	}
+
+	if app.Offline && !app.XMPOnly {
+		switch {
+		case app.Watch:
+			return nil, fmt.Errorf("the options -offline and -watch can't be used together")
+		case app.Every > 0:
+			return nil, fmt.Errorf("the options -offline and -every can't be used together")
+		case app.SpoolDir == "":
+			return nil, fmt.Errorf("the option -offline requires a -spool-dir")
+		}
+		// nothing to show on the user interface
+		app.NoUI = true
+	}
+
+	if app.Every < 0 {
+		return nil, fmt.Errorf("the option -every must be positive")
+	}
+	if app.Select {
+		switch {
+		case app.Watch:
+			return nil, fmt.Errorf("the options -select and -watch can't be used together")
+		case app.Every > 0:
+			return nil, fmt.Errorf("the options -select and -every can't be used together")
+		}
+	}
+	err = app.checkTargets()
+	if err != nil {
+		return nil, err
+	}
+	if app.WaitProcessing {
+		switch {
+		case app.Watch:
+			return nil, fmt.Errorf("the options -wait-processing and -watch can't be used together")
+		case app.Offline:
+			return nil, fmt.Errorf("the options -wait-processing and -offline can't be used together")
+		case len(app.Targets) > 1:
+			return nil, fmt.Errorf("the option -wait-processing can't be used with several servers")
+		}
+	}
+	if !app.ShareLink && (app.SharePassword != "" || app.ShareExpiry != 0) {
+		return nil, fmt.Errorf("the options -share-password and -share-expiry need the option -share-link")
+	}
+	if app.ShareExpiry < 0 {
+		return nil, fmt.Errorf("the option -share-expiry must be positive")
+	}
+	if app.ShareLink && app.Offline {
+		return nil, fmt.Errorf("the options -share-link and -offline can't be used together")
+	}
+	if app.Every > 0 {
+		if app.Watch {
+			return nil, fmt.Errorf("the options -every and -watch can't be used together")
+		}
+		if app.LockFile == "" {
+			app.LockFile = configuration.DefaultLockFile()
+		}
+		// the user interface isn't suitable for a long running process
+		app.NoUI = true
+	}
+
+	// the plan is confirmed by the user in front of the terminal before any change on the server
+	app.confirm = !app.Yes && !app.DryRun && !app.Offline && !app.Watch && app.Every == 0 && app.Output != OutputNDJSON && isTerminal(app.stdin)
+
 	app.BrowserConfig.Validate()
 	err = app.SharedFlags.Start(ctx)
 	if err != nil {
 		return nil, err
 	}
+	app.configureRecorder()
 
 	if fsOpener == nil {
 		fsOpener = func() ([]fs.FS, error) {
 			return fshelper.ParsePath(cmd.Args())
 		}
 	}
+	if app.RetryFrom != "" {
+		names, err := readFailedList(app.RetryFrom)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the list of files to retry: %w", err)
+		}
+		fsOpener = retryOpener(fsOpener, names)
+	}
+	app.openFS = fsOpener
 	app.fsyss, err = fsOpener()
 	if err != nil {
 		return nil, err
@@ -239,16 +888,117 @@ func newCommand(ctx context.Context, common *cmd.SharedFlags, args []string, fsO
 	return &app, nil
 }
 
+// Formats of the standard output
+const (
+	OutputText   = "text"   // messages and progress for humans
+	OutputNDJSON = "ndjson" // one JSON object per event and per line, the messages are written on the standard error
+)
+
+// configureRecorder prepares the recorder of the events for the reports, the NDJSON stream and the verbose console
+func (app *UpCmd) configureRecorder() {
+	if app.keepFileEvents() {
+		app.Jnl.KeepFileEvents()
+	}
+	if app.Output == OutputNDJSON {
+		if app.eventStream == nil {
+			app.eventStream = os.Stdout
+		}
+		app.Jnl.SetStream(app.eventStream)
+	}
+	if app.Verbose {
+		if app.Output == OutputNDJSON {
+			app.Jnl.SetEcho(os.Stderr)
+		} else {
+			app.Jnl.SetEcho(os.Stdout)
+		}
+	}
+}
+
+// keepFileEvents tells if the reports need the events of each file
+func (app *UpCmd) keepFileEvents() bool {
+	return app.Report != "" || app.CSVReport != "" || app.HTMLReport != "" || app.FailedList != ""
+}
+
+// writeReport writes the counters and the events of each file into the report file
+func (app *UpCmd) writeReport() error {
+	err := configuration.MakeDirForFile(app.Report)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(app.Report)
+	if err != nil {
+		return err
+	}
+	err = app.Jnl.WriteJSONReport(f)
+	return errors.Join(err, f.Close())
+}
+
 func (app *UpCmd) run(ctx context.Context) error {
 	defer func() {
 		_ = fshelper.CloseFSs(app.fsyss)
 	}()
 
 	if app.CreateStacks || app.StackBurst || app.StackJpgRaws {
-		app.stacks = stacking.NewStackBuilder(app.Immich.SupportedMedia())
+		app.stacks = stacking.NewStackBuilder(app.supportedMedia())
 	}
 
+	app.pause = newPauseGate()
+	app.progress = newProgress()
+	app.albums = nil
+	defer app.memory.Apply()()
+	app.watchThrottle()
+	notifyPauseSignal(ctx, app.togglePause)
+
 	var err error
+	if app.MetadataCache {
+		app.metaCache, err = metacache.Open(app.MetadataCacheFile)
+		if err != nil {
+			return fmt.Errorf("can't open the metadata cache: %w", err)
+		}
+		defer func() {
+			app.Log.Info(fmt.Sprintf("Metadata of %d files taken from the cache", app.metaCache.Hits()))
+			err := app.metaCache.Close()
+			if err != nil {
+				app.Log.Error("can't write the metadata cache: " + err.Error())
+			}
+			app.metaCache = nil
+		}()
+	}
+
+	if app.Incremental {
+		closeScanDB, err := app.openScanDB()
+		if err != nil {
+			return err
+		}
+		defer closeScanDB()
+	}
+
+	if app.CSVReport != "" || app.HTMLReport != "" {
+		app.outcomes = newReportOutcomes()
+	}
+
+	if app.NotifyURL != "" {
+		defer app.startNotifications(ctx)()
+	}
+
+	if app.SnapshotEvery > 0 {
+		defer app.startSnapshots(ctx)()
+	}
+
+	if app.MappingFile != "" {
+		app.mapping, err = newMappingWriter(app.MappingFile)
+		if err != nil {
+			return fmt.Errorf("can't create the mapping file: %w", err)
+		}
+		defer func() {
+			err := app.mapping.Close()
+			if err != nil {
+				app.Log.Error("can't close the mapping file: " + err.Error())
+			}
+			app.mapping = nil
+		}()
+	}
+
 	switch {
 	case app.GooglePhotos:
 		app.Log.Info("Browsing google take out archive...")
@@ -272,36 +1022,139 @@ func (app *UpCmd) run(ctx context.Context) error {
 				f.Close()
 			}
 		}
+		if app.Report != "" {
+			err := app.writeReport()
+			if err != nil {
+				app.Log.Error("can't write the report: " + err.Error())
+			} else {
+				fmt.Println("\nCheck the report file: ", app.Report)
+			}
+		}
+		if app.CSVReport != "" {
+			err := app.writeCSVReport()
+			if err != nil {
+				app.Log.Error("can't write the CSV report: " + err.Error())
+			} else {
+				fmt.Println("\nCheck the CSV report: ", app.CSVReport)
+			}
+		}
+		if app.FailedList != "" {
+			n, err := app.writeFailedList()
+			if err != nil {
+				app.Log.Error("can't write the list of failed files: " + err.Error())
+			} else if n > 0 {
+				fmt.Printf("\n%d file(s) in error listed in %s, retry them with -retry-from\n", n, app.FailedList)
+			}
+		}
+		if app.HTMLReport != "" {
+			err := app.writeHTMLReport()
+			if err != nil {
+				app.Log.Error("can't write the HTML report: " + err.Error())
+			} else {
+				fmt.Println("\nCheck the HTML report: ", app.HTMLReport)
+			}
+		}
+		app.printShareLinks()
 	}()
 
-	if app.NoUI {
-		return app.runNoUI(ctx)
+	if app.Select || app.confirm {
+		assets, err := collectAssets(ctx, app.browser)
+		if err != nil {
+			return err
+		}
+		keep := func(a *browser.LocalAssetFile) bool { return true }
+		if app.Select {
+			keep, err = app.selectAssets(ctx, assets)
+			if err != nil {
+				return err
+			}
+		}
+		if app.confirm {
+			err = app.confirmPlan(ctx, assets, keep)
+			if err != nil {
+				return err
+			}
+		}
+		app.browser = &selectedBrowser{assets: assets, keep: keep, jnl: app.Jnl}
+		if !app.XMPOnly && !app.Offline && len(app.Targets) <= 1 {
+			// the albums are known before the upload, the missing ones are created at once
+			err = app.createMissingAlbums(ctx, assets, keep)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	_, err = tcell.NewScreen()
-	if err != nil {
-		app.Log.Error("can't initialize the screen for the UI mode. Falling back to no-gui mode")
-		fmt.Println("can't initialize the screen for the UI mode. Falling back to no-gui mode")
-		return app.runNoUI(ctx)
+	if app.XMPOnly {
+		return app.localLoop(ctx, app.xmpOnlyAsset)
+	}
+	if app.Offline {
+		return app.runSpool(ctx)
+	}
+
+	if app.Watch {
+		err = app.runNoUI(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			app.Log.Error(err.Error())
+		}
+		return app.watchFolders(ctx)
+	}
+
+	if len(app.Targets) > 1 {
+		return app.runTargets(ctx)
+	}
+
+	if app.WaitProcessing && !app.DryRun {
+		app.startProcessingWatch(ctx)
+		defer app.processing.stop()
+	}
+
+	if !app.NoUI {
+		_, err = tcell.NewScreen()
+		if err != nil {
+			app.Log.Error("can't initialize the screen for the UI mode. Falling back to no-gui mode")
+			fmt.Println("can't initialize the screen for the UI mode. Falling back to no-gui mode")
+		}
+	}
+	if app.NoUI || err != nil {
+		err = app.runNoUI(ctx)
+	} else {
+		err = app.runUI(ctx)
+	}
+	if err != nil || app.processing == nil {
+		return err
 	}
-	return app.runUI(ctx)
+	return app.waitProcessing(ctx)
 }
 
+// togglePause pauses or resumes the upload loop
+func (app *UpCmd) togglePause() {
+	if app.pause.Toggle() {
+		msg := "Upload paused, the current transfer is completed before pausing."
+		if pauseSignalName != "" {
+			msg += " Send " + pauseSignalName + " again to resume."
+		}
+		app.Log.Info(msg)
+	} else {
+		app.Log.Info("Upload resumed")
+	}
+}
+
+// getImmichAlbums reads the album list of the server once per run
 func (app *UpCmd) getImmichAlbums(ctx context.Context) error {
+	if app.albums != nil {
+		return nil
+	}
 	serverAlbums, err := app.Immich.GetAllAlbums(ctx)
-	app.albums = map[string]immich.AlbumSimplified{}
 	if err != nil {
+		app.albums = newAlbumCache(nil)
 		return fmt.Errorf("can't get the album list from the server: %w", err)
 	}
-	for _, a := range serverAlbums {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			app.albums[a.AlbumName] = a
-		}
-	}
-	return nil
+	app.albums = newAlbumCache(serverAlbums)
+	return ctx.Err()
 }
 
 func (app *UpCmd) getImmichAssets(ctx context.Context, updateFn progressUpdate) error {
@@ -342,7 +1195,10 @@ func (app *UpCmd) getImmichAssets(ctx context.Context, updateFn progressUpdate)
 
 func (app *UpCmd) uploadLoop(ctx context.Context) error {
 	var err error
-	assetChan := app.browser.Browse(ctx)
+	assetChan := orderAssets(ctx, app.browser.Browse(ctx), app.Order, func(a *browser.LocalAssetFile) { app.fixDate(ctx, a) })
+	if app.BulkCheck {
+		assetChan = app.bulkCheckAssets(ctx, assetChan)
+	}
 assetLoop:
 	for {
 		select {
@@ -353,17 +1209,25 @@ assetLoop:
 			if !ok {
 				break assetLoop
 			}
+			if app.pause != nil {
+				if err := app.pause.Wait(ctx); err != nil {
+					a.Close()
+					return err
+				}
+			}
 			if a.Err != nil {
-				app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, a.Err.Error())
+				app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", a.Err.Error())
 			} else {
 				err = app.handleAsset(ctx, a)
 				if err != nil {
-					app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, a.Err.Error())
+					app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
 				}
 			}
 		}
 	}
 
+	app.flushBatch(ctx)
+
 	if app.CreateStacks {
 		stacks := app.stacks.Stacks()
 		if len(stacks) > 0 {
@@ -396,6 +1260,9 @@ assetLoop:
 	// 	}
 	// }
 
+	app.setAlbumCovers(ctx)
+	app.reportReplacements()
+
 	if len(app.deleteServerList) > 0 {
 		ids := []string{}
 		for _, da := range app.deleteServerList {
@@ -414,49 +1281,70 @@ assetLoop:
 	return err
 }
 
-func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) error {
-	defer func() {
-		a.Close()
-	}()
-	ext := path.Ext(a.FileName)
+// localLoop handles the assets without the server, and reports the results
+func (app *UpCmd) localLoop(ctx context.Context, handle func(context.Context, *browser.LocalAssetFile) error) error {
+	err := app.browser.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+	for a := range orderAssets(ctx, app.browser.Browse(ctx), app.Order, func(a *browser.LocalAssetFile) { app.fixDate(ctx, a) }) {
+		if a.Err != nil {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", a.Err.Error())
+			a.Close()
+			continue
+		}
+		err = handle(ctx, a)
+		if err != nil {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+		}
+	}
+	app.Jnl.Report()
+	return ctx.Err()
+}
+
+// isSelected applies the selection options to the asset
+func (app *UpCmd) isSelected(ctx context.Context, a *browser.LocalAssetFile) bool {
+	ext := a.Ext()
 	if app.BrowserConfig.ExcludeExtensions.Exclude(ext) {
 		app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "extension in rejection list")
-		return nil
+		return false
 	}
 	if !app.BrowserConfig.SelectExtensions.Include(ext) {
 		app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "extension not in selection list")
-		return nil
+		return false
 	}
 
 	if !app.KeepPartner && a.FromPartner {
 		app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "partners asset excluded")
-		return nil
+		return false
 	}
 
 	if !app.KeepTrashed && a.Trashed {
 		app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "trashed asset excluded")
-		return nil
+		return false
 	}
 
 	if app.ImportFromAlbum != "" && !app.isInAlbum(a, app.ImportFromAlbum) {
-		app.Jnl.Record(ctx, fileevent.UploadNotSelected, a.FileName, "reason", "doesn't belong to required album")
-		return nil
+		app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "doesn't belong to required album")
+		return false
 	}
 
 	if app.DiscardArchived && a.Archived {
 		app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "archived asset are discarded")
-		return nil
+		return false
 	}
 
+	// the date is corrected before being checked against the range
+	app.fixDate(ctx, a)
 	if app.DateRange.IsSet() {
 		d := a.Metadata.DateTaken
 		if d.IsZero() {
 			app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "date of capture is unknown")
-			return nil
+			return false
 		}
 		if !app.DateRange.InRange(d) {
 			app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "date of capture is out of the given range")
-			return nil
+			return false
 		}
 	}
 
@@ -465,6 +1353,34 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 			return i.Title != ""
 		})
 	}
+	return true
+}
+
+func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) error {
+	defer func() {
+		a.Close()
+	}()
+	if !app.isSelected(ctx, a) {
+		return nil
+	}
+	if app.ImportCaptions {
+		app.captionAsset(ctx, a)
+	}
+	app.rateAsset(ctx, a)
+	app.geocodeAsset(ctx, a)
+
+	if app.WriteXMP != "" {
+		err := app.writeXMP(ctx, a)
+		if err != nil {
+			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+		}
+	}
+
+	if a.Checksum != "" {
+		if id, ok := app.bulkDuplicates.LoadAndDelete(a.Checksum); ok {
+			app.AssetIndex.AddServerChecksum(id.(string), a.Checksum)
+		}
+	}
 
 	advice, err := app.AssetIndex.ShouldUpload(a)
 	if err != nil {
@@ -473,63 +1389,150 @@ func (app *UpCmd) handleAsset(ctx context.Context, a *browser.LocalAssetFile) er
 
 	switch advice.Advice {
 	case NotOnServer: // Upload and manage albums
-		ID, err := app.UploadAsset(ctx, a)
+		if app.FixSourceExif {
+			// the file is modified only when it goes to the server
+			err := app.fixSourceExif(ctx, a)
+			if err != nil {
+				app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+			}
+		}
+		resp, err := app.UploadAsset(ctx, a)
 		if err != nil {
 			return nil
 		}
-		app.manageAssetAlbum(ctx, ID, a, advice)
+		albums := app.manageAssetAlbum(ctx, resp.ID, a, advice)
+		status := MappingUploaded
+		if resp.Status == immich.UploadDuplicate {
+			status = MappingServerDuplicate
+		}
+		app.assetDone(a, resp.ID, status, albums)
 
-	case SmallerOnServer: // Upload, manage albums and delete the server's asset
-		app.Jnl.Record(ctx, fileevent.UploadUpgraded, a, a.FileName, "reason", advice.Message)
-		// add the superior asset into albums of the original asset.
-		ID, err := app.UploadAsset(ctx, a)
+	case SmallerOnServer, BetterOnServer: // apply the -on-duplicate policy
+		replace, err := app.shouldReplace(ctx, a, advice)
+		if err != nil {
+			return err
+		}
+		if !replace {
+			if advice.Advice == BetterOnServer {
+				app.Jnl.Record(ctx, fileevent.UploadServerBetter, a, a.FileName, "reason", advice.Message)
+			} else {
+				app.Jnl.Record(ctx, fileevent.UploadNotSelected, a, a.FileName, "reason", "the server's asset is kept by the -on-duplicate policy")
+			}
+			albums := app.manageAssetAlbum(ctx, advice.ServerAsset.ID, a, advice)
+			app.queueAssetFlags(a, advice.ServerAsset)
+			status := MappingServerKept
+			if advice.Advice == BetterOnServer {
+				status = MappingServerBetter
+			}
+			app.assetDone(a, advice.ServerAsset.ID, status, albums)
+			return nil
+		}
+		// Upload, manage albums and delete the server's asset
+		app.Jnl.Record(ctx, fileevent.UploadUpgraded, a, a.FileName, "reason", advice.Message, "policy", app.OnDuplicate)
+		// add the new asset into albums of the original asset.
+		resp, err := app.UploadAsset(ctx, a)
 		if err != nil {
 			return nil
 		}
-		app.manageAssetAlbum(ctx, ID, a, advice)
-		// delete the existing lower quality asset
+		albums := app.manageAssetAlbum(ctx, resp.ID, a, advice)
+		app.assetDone(a, resp.ID, MappingReplaced, albums)
+		// delete the replaced asset
 		err = app.deleteAsset(ctx, advice.ServerAsset.ID)
 		if err != nil {
 			app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
 		}
+		app.replaced = append(app.replaced, replacement{fileName: a.FileName, serverID: advice.ServerAsset.ID, message: advice.Message})
 
 	case SameOnServer: // manage albums
 		// Set add the server asset into albums determined locally
+		status := MappingServerDuplicate
 		if !advice.ServerAsset.JustUploaded {
 			app.Jnl.Record(ctx, fileevent.UploadServerDuplicate, a, a.FileName, "reason", advice.Message)
 		} else {
 			app.Jnl.Record(ctx, fileevent.AnalysisLocalDuplicate, a, a.FileName)
+			status = MappingLocalDuplicate
 		}
-		app.manageAssetAlbum(ctx, advice.ServerAsset.ID, a, advice)
-
-	case BetterOnServer: // and manage albums
-		app.Jnl.Record(ctx, fileevent.UploadServerBetter, a, a.FileName, "reason", advice.Message)
-		app.manageAssetAlbum(ctx, advice.ServerAsset.ID, a, advice)
+		albums := app.manageAssetAlbum(ctx, advice.ServerAsset.ID, a, advice)
+		if !advice.ServerAsset.JustUploaded {
+			app.queueAssetFlags(a, advice.ServerAsset)
+		}
+		app.assetDone(a, advice.ServerAsset.ID, status, albums)
 	}
 
 	return nil
 }
 
+// assetDone keeps track of the asset once handled
+func (app *UpCmd) assetDone(a *browser.LocalAssetFile, assetID string, status string, albums []string) {
+	app.mapAsset(a, assetID, status, albums)
+	app.reportAsset(a, assetID, status, albums)
+	app.covers.candidate(a, assetID, albums)
+	app.rememberUpload(a, assetID)
+}
+
 func (app *UpCmd) deleteAsset(ctx context.Context, id string) error {
-	return app.Immich.DeleteAssets(ctx, []string{id}, true)
+	return app.TrashAssets(ctx, "upload", []string{id})
 }
 
 // manageAssetAlbum keep the albums updated
-// errors are logged, but not returned
-func (app *UpCmd) manageAssetAlbum(ctx context.Context, assetID string, a *browser.LocalAssetFile, advice *Advice) {
+// errors are logged, but not returned. It returns the titles of the albums the asset has been added to.
+func (app *UpCmd) manageAssetAlbum(ctx context.Context, assetID string, a *browser.LocalAssetFile, advice *Advice) []string {
 	addedTo := map[string]any{}
+	albums := []string{}
+
+	add := func(album browser.LocalAlbum, reason string) {
+		if _, exist := addedTo[album.Title]; exist {
+			return
+		}
+		addedTo[album.Title] = nil
+		if reason != "" {
+			app.Jnl.Record(ctx, fileevent.UploadAddToAlbum, a, a.FileName, "album", album.Title, "reason", reason)
+		} else {
+			app.Jnl.Record(ctx, fileevent.UploadAddToAlbum, a, a.FileName, "album", album.Title)
+		}
+		if !app.DryRun {
+			// the album is populated by batches, the outcome is recorded when the batch is sent
+			if app.queueAlbumAsset(ctx, assetID, a, album) {
+				albums = append(albums, album.Title)
+			}
+		} else {
+			outcome := fileevent.AlbumAdded
+			if advice.ServerAsset != nil && slices.ContainsFunc(advice.ServerAsset.Albums, func(al immich.AlbumSimplified) bool { return al.AlbumName == album.Title }) {
+				outcome = fileevent.AlbumPresent
+			}
+			app.Jnl.RecordAlbum(album.Title, outcome)
+		}
+	}
+
 	if advice.ServerAsset != nil {
 		for _, al := range advice.ServerAsset.Albums {
-			app.Jnl.Record(ctx, fileevent.UploadAddToAlbum, a, a.FileName, "album", al.AlbumName, "reason", "lower quality asset's album")
-			if !app.DryRun {
-				err := app.AddToAlbum(ctx, assetID, browser.LocalAlbum{Title: al.AlbumName, Description: al.Description})
-				if err != nil {
-					app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
-				}
-			}
-			addedTo[al.AlbumName] = nil
+			add(browser.LocalAlbum{Title: al.AlbumName, Description: al.Description}, "lower quality asset's album")
 		}
 	}
+	for _, t := range app.assetAlbums(ctx, a) {
+		add(t.album, t.reason)
+	}
+	return albums
+}
+
+// albumTarget is an album where the asset must be added
+type albumTarget struct {
+	album  browser.LocalAlbum
+	reason string
+}
+
+// assetAlbums gives the albums of the asset, accordingly to the options
+func (app *UpCmd) assetAlbums(ctx context.Context, a *browser.LocalAssetFile) []albumTarget {
+	targets, err := app.albumTargets(a)
+	if err != nil {
+		app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
+	}
+	return targets
+}
+
+// albumTargets gives the albums of the asset, and the error of the album template
+func (app *UpCmd) albumTargets(a *browser.LocalAssetFile) ([]albumTarget, error) {
+	targets := []albumTarget{}
 
 	if app.CreateAlbums {
 		for _, al := range a.Albums {
@@ -537,43 +1540,23 @@ func (app *UpCmd) manageAssetAlbum(ctx context.Context, assetID string, a *brows
 			if app.GooglePhotos && (app.CreateAlbumAfterFolder || app.UseFolderAsAlbumName || album == "") {
 				album = filepath.Base(al.Path)
 			}
-			if _, exist := addedTo[album]; !exist {
-				app.Jnl.Record(ctx, fileevent.UploadAddToAlbum, a, a.FileName, "album", album)
-				if !app.DryRun {
-					err := app.AddToAlbum(ctx, assetID, browser.LocalAlbum{Title: album})
-					if err != nil {
-						app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
-					}
-				}
-			}
+			targets = append(targets, albumTarget{album: browser.LocalAlbum{Title: album, Description: al.Description, Order: al.Order}})
 		}
 	}
 	if app.ImportIntoAlbum != "" {
-		app.Jnl.Record(ctx, fileevent.UploadAddToAlbum, a, a.FileName, "album", app.ImportIntoAlbum, "reason", "option -album")
-		if !app.DryRun {
-			err := app.AddToAlbum(ctx, assetID, browser.LocalAlbum{Title: app.ImportIntoAlbum})
-			if err != nil {
-				app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
-			}
-		}
+		targets = append(targets, albumTarget{album: browser.LocalAlbum{Title: app.ImportIntoAlbum}, reason: "option -album"})
 	}
 
 	if app.GooglePhotos {
 		if app.PartnerAlbum != "" && a.FromPartner {
-			app.Jnl.Record(ctx, fileevent.UploadAddToAlbum, a, a.FileName, "album", app.PartnerAlbum, "reason", "option -partner-album")
-			if !app.DryRun {
-				err := app.AddToAlbum(ctx, assetID, browser.LocalAlbum{Title: app.PartnerAlbum})
-				if err != nil {
-					app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
-				}
-			}
+			targets = append(targets, albumTarget{album: browser.LocalAlbum{Title: app.PartnerAlbum}, reason: "option -partner-album"})
 		}
 	} else {
 		if app.CreateAlbumAfterFolder {
 			album := path.Base(path.Dir(a.FileName))
 			if !app.GooglePhotos && app.UseFullPathAsAlbumName {
 				// full path
-				album = strings.Replace(filepath.Dir(a.FileName), string(os.PathSeparator), app.AlbumNamePathSeparator, -1)
+				album = strings.ReplaceAll(path.Dir(a.FileName), "/", app.AlbumNamePathSeparator)
 			}
 			if album == "" || album == "." {
 				if fsys, ok := a.FSys.(fshelper.NameFS); ok {
@@ -582,15 +1565,44 @@ func (app *UpCmd) manageAssetAlbum(ctx context.Context, assetID string, a *brows
 					album = "no-folder-name"
 				}
 			}
-			app.Jnl.Record(ctx, fileevent.UploadAddToAlbum, a, a.FileName, "album", album, "reason", "option -create-album-folder")
-			if !app.DryRun {
-				err := app.AddToAlbum(ctx, assetID, browser.LocalAlbum{Title: album})
-				if err != nil {
-					app.Jnl.Record(ctx, fileevent.Error, a, a.FileName, "error", err.Error())
-				}
-			}
+			targets = append(targets, albumTarget{album: browser.LocalAlbum{Title: album, Description: app.folderDescription(a)}, reason: "option -create-album-folder"})
 		}
 	}
+	if app.albumTemplate != nil {
+		album, err := app.albumFromTemplate(a)
+		if err != nil {
+			return targets, err
+		}
+		if album != "" {
+			targets = append(targets, albumTarget{album: browser.LocalAlbum{Title: album}, reason: "option -album-template"})
+		}
+	}
+	return targets, nil
+}
+
+// folderDescription reads the description of the album of the asset's folder from the file given by the option -album-description-file
+func (app *UpCmd) folderDescription(a *browser.LocalAssetFile) string {
+	if app.AlbumDescriptionFile == "" {
+		return ""
+	}
+	dir := path.Dir(a.FileName)
+	key := dir
+	if fsys, ok := a.FSys.(fshelper.NameFS); ok {
+		key = path.Join(fsys.Name(), dir)
+	}
+	if d, ok := app.folderDescriptions[key]; ok {
+		return d
+	}
+	if app.folderDescriptions == nil {
+		app.folderDescriptions = map[string]string{}
+	}
+	b, err := fs.ReadFile(a.FSys, path.Join(dir, app.AlbumDescriptionFile))
+	d := strings.TrimSpace(string(b))
+	if err != nil {
+		d = ""
+	}
+	app.folderDescriptions[key] = d
+	return d
 }
 
 func (app *UpCmd) isInAlbum(a *browser.LocalAssetFile, album string) bool {
@@ -604,12 +1616,15 @@ func (app *UpCmd) isInAlbum(a *browser.LocalAssetFile, album string) bool {
 
 func (app *UpCmd) ReadGoogleTakeOut(ctx context.Context, fsyss []fs.FS) (browser.Browser, error) {
 	app.Delete = false
-	b, err := gp.NewTakeout(ctx, app.Jnl, app.Immich.SupportedMedia(), fsyss...)
+	b, err := gp.NewTakeout(ctx, app.Jnl, app.supportedMedia(), fsyss...)
 	if err != nil {
 		return nil, err
 	}
 	b.SetBannedFiles(app.BannedFiles)
+	b.SetPathFilters(app.IncludePaths, app.ExcludePaths)
 	b.SetAcceptMissingJSON(app.ForceUploadWhenNoJSON)
+	b.SetWorkers(app.ReadWorkers)
+	b.SetMemoryBudget(app.memory)
 	return b, err
 }
 
@@ -618,16 +1633,36 @@ func (app *UpCmd) ExploreLocalFolder(ctx context.Context, fsyss []fs.FS) (browse
 	if err != nil {
 		return nil, err
 	}
-	b.SetSupportedMedia(app.Immich.SupportedMedia())
+	b.SetSupportedMedia(app.supportedMedia())
 	b.SetWhenNoDate(app.WhenNoDate)
+	b.SetDateSources(app.DateFrom)
+	b.SetTypeDetection(app.TypeDetection)
+	b.SetLinkByContentID(app.LivePhotoByID)
+	b.SetOtherSidecars(app.ExiftoolSidecars)
+	b.SetFollowSymlinks(app.FollowSymlinks)
+	b.SetIncludeHidden(app.IncludeHidden)
+	b.SetOneFileSystem(app.OneFileSystem)
+	b.SetMetadataCache(app.metaCache)
+	b.SetScanDB(app.scanDB)
+	b.SetWorkers(app.ReadWorkers)
+	b.SetMemoryBudget(app.memory)
 	b.SetBannedFiles(app.BannedFiles)
+	b.SetPathFilters(app.IncludePaths, app.ExcludePaths)
 	return b, nil
 }
 
+// supportedMedia gives the media supported by the server, or the default ones when offline
+func (app *UpCmd) supportedMedia() immich.SupportedMedia {
+	if app.Immich == nil {
+		return app.ExtraMedia.Apply(immich.DefaultSupportedMedia)
+	}
+	return app.ExtraMedia.Apply(app.Immich.SupportedMedia())
+}
+
 // UploadAsset upload the asset on the server
 // Add the assets into listed albums
 // return ID of the asset
-func (app *UpCmd) UploadAsset(ctx context.Context, a *browser.LocalAssetFile) (string, error) {
+func (app *UpCmd) UploadAsset(ctx context.Context, a *browser.LocalAssetFile) (immich.AssetResponse, error) {
 	var resp, liveResp immich.AssetResponse
 	var err error
 	if !app.AutoArchive && a.Archived {
@@ -637,10 +1672,12 @@ func (app *UpCmd) UploadAsset(ctx context.Context, a *browser.LocalAssetFile) (s
 		if a.LivePhoto != nil {
 			liveResp, err = app.Immich.AssetUpload(ctx, a.LivePhoto)
 			if err == nil {
+				app.uploadedChecksum(a.LivePhoto)
 				if liveResp.Status == immich.UploadDuplicate {
 					app.Jnl.Record(ctx, fileevent.UploadServerDuplicate, a.LivePhoto, a.LivePhoto.FileName, "info", "the server has this file")
 				} else {
 					app.Jnl.Record(ctx, fileevent.Uploaded, a.LivePhoto, a.LivePhoto.FileName)
+					app.progress.uploaded(a.LivePhoto.Size())
 				}
 				a.LivePhotoID = liveResp.ID
 			} else {
@@ -650,15 +1687,35 @@ func (app *UpCmd) UploadAsset(ctx context.Context, a *browser.LocalAssetFile) (s
 		b := *a // Keep a copy of the asset to log errors specifically on the image
 		resp, err = app.Immich.AssetUpload(ctx, a)
 		if err == nil {
+			app.uploadedChecksum(a)
 			if resp.Status == immich.UploadDuplicate {
 				app.Jnl.Record(ctx, fileevent.UploadServerDuplicate, a, a.FileName, "info", "the server has this file")
 			} else {
+				app.progress.uploaded(a.Size())
 				b.LivePhoto = nil
-				app.Jnl.Record(ctx, fileevent.Uploaded, &b, b.FileName, "capture date", b.Metadata.DateTaken.String())
+				kv := []any{"capture date", b.Metadata.DateTaken.String()}
+				if b.SideCar.IsSet() {
+					kv = append(kv, "sidecar", b.SideCar.FileName)
+				}
+				app.Jnl.Record(ctx, fileevent.Uploaded, &b, b.FileName, kv...)
+				app.sendFixedDate(ctx, a, resp.ID)
+				if app.ImportCaptions && a.Metadata.Description != "" {
+					// make sure the caption is the asset's description, even when the server doesn't read it from the file
+					_, err = app.Immich.UpdateAsset(ctx, resp.ID, a)
+					if err != nil {
+						app.Jnl.Record(ctx, fileevent.UploadServerError, a, a.FileName, "error", err.Error())
+					}
+				}
+				if app.ImportFaces {
+					err = app.importFaces(ctx, a, resp.ID)
+					if err != nil {
+						app.Jnl.Record(ctx, fileevent.UploadServerError, a, a.FileName, "error", err.Error())
+					}
+				}
 			}
 		} else {
 			app.Jnl.Record(ctx, fileevent.UploadServerError, a, a.FileName, "error", err.Error())
-			return "", err
+			return resp, err
 		}
 	} else {
 		// dry-run mode
@@ -673,12 +1730,15 @@ func (app *UpCmd) UploadAsset(ctx context.Context, a *browser.LocalAssetFile) (s
 			app.AssetIndex.AddLocalAsset(a, liveResp.ID)
 		}
 		app.AssetIndex.AddLocalAsset(a, resp.ID)
+		if app.processing != nil {
+			app.processing.add(resp.ID)
+		}
 		if app.CreateStacks {
 			app.stacks.ProcessAsset(resp.ID, a.FileName, a.Metadata.DateTaken)
 		}
 	}
 
-	return resp.ID, nil
+	return resp, nil
 }
 
 func (app *UpCmd) albumName(al browser.LocalAlbum) string {
@@ -694,26 +1754,6 @@ func (app *UpCmd) albumName(al browser.LocalAlbum) string {
 	return Name
 }
 
-// AddToAlbum add the ID to the immich album having the same name as the local album
-func (app *UpCmd) AddToAlbum(ctx context.Context, id string, album browser.LocalAlbum) error {
-	title := album.Title
-
-	l, exist := app.albums[title]
-	if !exist {
-		a, err := app.Immich.CreateAlbum(ctx, title, album.Description, []string{id})
-		if err != nil {
-			return err
-		}
-		app.albums[title] = immich.AlbumSimplified{ID: a.ID, AlbumName: a.AlbumName, Description: a.Description}
-	} else {
-		_, err := app.Immich.AddAssetToAlbum(ctx, l.ID, []string{id})
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func (app *UpCmd) DeleteLocalAssets() error {
 	app.Log.Info(fmt.Sprintf("%d local assets to delete.", len(app.deleteLocalList)))
 
@@ -735,8 +1775,7 @@ func (app *UpCmd) DeleteServerAssets(ctx context.Context, ids []string) error {
 	app.Log.Info(fmt.Sprintf("%d server assets to delete.", len(ids)))
 
 	if !app.DryRun {
-		err := app.Immich.DeleteAssets(ctx, ids, false)
-		return err
+		return app.TrashAssets(ctx, "upload", ids)
 	}
 	app.Log.Info(fmt.Sprintf("%d server assets to delete. skipped dry-run mode", len(ids)))
 	return nil
@@ -899,6 +1938,13 @@ func (ai *AssetIndex) ShouldUpload(la *browser.LocalAssetFile) (*Advice, error)
 		return ai.adviceSameOnServer(sa), nil
 	}
 
+	if la.Checksum != "" {
+		if l := ai.byHash[la.Checksum]; len(l) > 0 {
+			// the server has the very same file
+			return ai.adviceSameOnServer(l[0]), nil
+		}
+	}
+
 	var l []*immich.Asset
 
 	// check all files with the same name
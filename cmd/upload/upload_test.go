@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/kr/pretty"
 	"github.com/simulot/immich-go/browser"
@@ -23,14 +24,66 @@ func (c *stubIC) GetAllAssetsWithFilter(context.Context, func(*immich.Asset) err
 	return nil
 }
 
+func (c *stubIC) SearchAssets(context.Context, immich.SearchQuery, func(*immich.Asset) error) error {
+	return nil
+}
+
 func (c *stubIC) AssetUpload(context.Context, *browser.LocalAssetFile) (immich.AssetResponse, error) {
 	return immich.AssetResponse{}, nil
 }
 
+func (c *stubIC) CheckBulkUpload(context.Context, []immich.AssetBulkUploadCheckItem) ([]immich.AssetBulkUploadCheckResult, error) {
+	return nil, nil
+}
+
+func (c *stubIC) UpdateAlbum(context.Context, string, immich.AlbumUpdate) (immich.AlbumSimplified, error) {
+	return immich.AlbumSimplified{}, nil
+}
+
+func (c *stubIC) CreateSharedLink(ctx context.Context, link immich.SharedLinkCreate) (immich.SharedLink, error) {
+	return immich.SharedLink{}, nil
+}
+
+func (c *stubIC) GetAllLibraries(ctx context.Context) ([]immich.Library, error) {
+	return nil, nil
+}
+
+func (c *stubIC) CreateLibrary(ctx context.Context, library immich.LibraryCreate) (immich.Library, error) {
+	return immich.Library{}, nil
+}
+
+func (c *stubIC) ScanLibrary(ctx context.Context, id string) error {
+	return nil
+}
+
+func (c *stubIC) GetAllUsers(ctx context.Context) ([]immich.User, error) {
+	return nil, nil
+}
+
+func (c *stubIC) GetOnThisDayMemories(ctx context.Context, day time.Time) ([]immich.Memory, error) {
+	return nil, nil
+}
+
+func (c *stubIC) GetTimeBuckets(ctx context.Context) ([]immich.TimeBucket, error) {
+	return nil, nil
+}
+
 func (c *stubIC) DeleteAssets(context.Context, []string, bool) error {
 	return nil
 }
 
+func (c *stubIC) RestoreAssets(ctx context.Context, ids []string) error {
+	return nil
+}
+
+func (c *stubIC) DownloadAsset(context.Context, string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (c *stubIC) GetAssetPreview(context.Context, string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
 func (c *stubIC) GetAllAlbums(context.Context) ([]immich.AlbumSimplified, error) {
 	return nil, nil
 }
@@ -47,10 +100,70 @@ func (c *stubIC) UpdateAssets(ctx context.Context, ids []string, isArchived bool
 	return nil
 }
 
+func (c *stubIC) BatchUpdateAssets(ctx context.Context, ids []string, update immich.AssetsUpdate) error {
+	return nil
+}
+
 func (c *stubIC) StackAssets(ctx context.Context, cover string, ids []string) error {
 	return nil
 }
 
+func (c *stubIC) UnstackAssets(ctx context.Context, ids []string) error {
+	return nil
+}
+
+func (c *stubIC) GetServerVersion(ctx context.Context) (immich.ServerVersion, error) {
+	return immich.ServerVersion{}, nil
+}
+
+func (c *stubIC) GetServerStorage(ctx context.Context) (immich.ServerStorage, error) {
+	return immich.ServerStorage{}, nil
+}
+
+func (c *stubIC) UpdatePerson(ctx context.Context, id string, name string) (immich.Person, error) {
+	return immich.Person{ID: id, Name: name}, nil
+}
+
+func (c *stubIC) MergePeople(ctx context.Context, id string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	return nil, nil
+}
+
+func (c *stubIC) UpdateAssetMetadata(ctx context.Context, id string, update immich.AssetMetadataUpdate) error {
+	return nil
+}
+
+func (c *stubIC) SetStackCover(ctx context.Context, oldCover string, newCover string) error {
+	return nil
+}
+
+func (c *stubIC) GetAllTags(ctx context.Context) ([]immich.Tag, error) {
+	return nil, nil
+}
+
+func (c *stubIC) UpsertTags(ctx context.Context, names []string) ([]immich.Tag, error) {
+	return nil, nil
+}
+
+func (c *stubIC) TagAssets(ctx context.Context, tagID string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	return nil, nil
+}
+
+func (c *stubIC) UntagAssets(ctx context.Context, tagID string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	return nil, nil
+}
+
+func (c *stubIC) GetAllPeople(ctx context.Context) ([]immich.Person, error) {
+	return nil, nil
+}
+
+func (c *stubIC) CreatePerson(ctx context.Context, name string) (immich.Person, error) {
+	return immich.Person{ID: name, Name: name}, nil
+}
+
+func (c *stubIC) CreateFace(ctx context.Context, face immich.Face) error {
+	return nil
+}
+
 func (c *stubIC) UpdateAsset(ctx context.Context, id string, a *browser.LocalAssetFile) (*immich.Asset, error) {
 	return nil, nil
 }
@@ -445,6 +558,70 @@ func TestUpload(t *testing.T) {
 				"PXL_20231006_063851485.jpg",
 			},
 		},
+		{
+			name: "folder, oldest first",
+			args: []string{
+				"-order=oldest-first",
+				"TEST_DATA/folder/high",
+			},
+			expectedErr: false,
+			expectedAssets: []string{
+				"AlbumA/PXL_20231006_063000139.jpg",
+				"AlbumA/PXL_20231006_063029647.jpg",
+				"AlbumA/PXL_20231006_063108407.jpg",
+				"AlbumA/PXL_20231006_063121958.jpg",
+				"AlbumA/PXL_20231006_063357420.jpg",
+				"AlbumB/PXL_20231006_063528961.jpg",
+				"AlbumB/PXL_20231006_063536303.jpg",
+				"AlbumB/PXL_20231006_063851485.jpg",
+			},
+		},
+		{
+			name: "folder, exclude path",
+			args: []string{
+				"-exclude-path=**/AlbumB/**",
+				"TEST_DATA/folder/high",
+			},
+			expectedErr: false,
+			expectedAssets: []string{
+				"AlbumA/PXL_20231006_063000139.jpg",
+				"AlbumA/PXL_20231006_063029647.jpg",
+				"AlbumA/PXL_20231006_063108407.jpg",
+				"AlbumA/PXL_20231006_063121958.jpg",
+				"AlbumA/PXL_20231006_063357420.jpg",
+			},
+		},
+		{
+			name: "folder, include path regular expression",
+			args: []string{
+				`-include-path=^AlbumB/.*_0635`,
+				"TEST_DATA/folder/high",
+			},
+			expectedErr: false,
+			expectedAssets: []string{
+				"AlbumB/PXL_20231006_063528961.jpg",
+				"AlbumB/PXL_20231006_063536303.jpg",
+			},
+		},
+		{
+			name: "google photos, exclude path",
+			args: []string{
+				"-google-photos",
+				"-create-albums=FALSE",
+				"-exclude-path=**/*.mp4",
+				"TEST_DATA/Takeout1",
+			},
+			expectedErr: false,
+			expectedAssets: []string{
+				"Google Photos/Album test 6-10-23/PXL_20231006_063000139.jpg",
+				"Google Photos/Album test 6-10-23/PXL_20231006_063029647.jpg",
+				"Google Photos/Album test 6-10-23/PXL_20231006_063108407.jpg",
+				"Google Photos/Album test 6-10-23/PXL_20231006_063121958.jpg",
+				"Google Photos/Album test 6-10-23/PXL_20231006_063357420.jpg",
+				"Google Photos/Album test 6-10-23/PXL_20231006_063536303.jpg",
+				"Google Photos/Album test 6-10-23/PXL_20231006_063851485.jpg",
+			},
+		},
 		{
 			name: "folder and albums creation",
 			args: []string{
@@ -0,0 +1,69 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func TestVerbosity(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		notWant []string
+		err     bool
+	}{
+		{name: "default", want: []string{"Immich read", "Input analysis"}, notWant: []string{"PXL_20231006_063000139.jpg"}},
+		{name: "quiet", args: []string{"-quiet"}, want: []string{"Input analysis"}, notWant: []string{"Immich read", "PXL_20231006_063000139.jpg"}},
+		{name: "verbose", args: []string{"-verbose"}, want: []string{"Input analysis", "PXL_20231006_063000139.jpg", "PXL_20231006_063029647.jpg"}, notWant: []string{"Immich read"}},
+		{name: "quiet and verbose", args: []string{"-quiet", "-verbose"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			stdout := os.Stdout
+			os.Stdout = f
+			defer func() { os.Stdout = stdout }()
+
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Immich: &icCatchUploadsAssets{albums: map[string][]string{}},
+				Jnl:    fileevent.NewRecorder(log, false),
+				Log:    log,
+			}
+			args := append([]string{"-no-ui"}, tt.args...)
+			err = UploadCommand(context.Background(), &serv, append(args,
+				"TEST_DATA/folder/low/PXL_20231006_063000139.jpg", "TEST_DATA/folder/low/PXL_20231006_063029647.jpg",
+			))
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b, err := os.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(string(b), s) {
+					t.Errorf("the console should contain %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(string(b), s) {
+					t.Errorf("the console shouldn't contain %q", s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/helpers/gen"
+	"github.com/simulot/immich-go/helpers/stacking"
+)
+
+// validateWatchedFolders checks that all paths given with the -watch option are plain folders
+func validateWatchedFolders(paths []string) error {
+	var errs error
+	if len(paths) == 0 {
+		return errors.New("the option -watch requires at least one folder")
+	}
+	for _, p := range paths {
+		if fshelper.HasMagic(p) {
+			errs = errors.Join(errs, fmt.Errorf("the option -watch doesn't accept patterns: %s", p))
+			continue
+		}
+		s, err := os.Stat(p)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if !s.IsDir() {
+			errs = errors.Join(errs, fmt.Errorf("the option -watch requires a folder: %s", p))
+		}
+	}
+	return errs
+}
+
+// folderWatcher tracks the files changed under the watched folders
+type folderWatcher struct {
+	w       *fsnotify.Watcher
+	roots   []string                        // watched folders
+	pending map[string]map[string]time.Time // last change of files by root, by relative name
+}
+
+func newFolderWatcher(roots []string) (*folderWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	fw := &folderWatcher{
+		w:       w,
+		pending: map[string]map[string]time.Time{},
+	}
+	for _, r := range roots {
+		r, err = filepath.Abs(r)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		fw.roots = append(fw.roots, r)
+		err = fw.addTree(r, false)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	return fw, nil
+}
+
+func (fw *folderWatcher) Close() error {
+	return fw.w.Close()
+}
+
+// addTree watches the folder and its sub folders.
+// When track is true, the files found in the tree are considered as new.
+func (fw *folderWatcher) addTree(dir string, track bool) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return fw.w.Add(p)
+		}
+		if track {
+			fw.touch(p)
+		}
+		return nil
+	})
+}
+
+// touch records the last change of the file
+func (fw *folderWatcher) touch(name string) {
+	for _, r := range fw.roots {
+		rel, err := filepath.Rel(r, name)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		files := fw.pending[r]
+		if files == nil {
+			files = map[string]time.Time{}
+			fw.pending[r] = files
+		}
+		files[filepath.ToSlash(rel)] = time.Now()
+		return
+	}
+}
+
+func (fw *folderWatcher) handleEvent(e fsnotify.Event) {
+	if !e.Has(fsnotify.Create) && !e.Has(fsnotify.Write) {
+		return
+	}
+	s, err := os.Stat(e.Name)
+	if err != nil {
+		return
+	}
+	if s.IsDir() {
+		// a new folder, or a folder moved into the watched folder
+		_ = fw.addTree(e.Name, true)
+		return
+	}
+	fw.touch(e.Name)
+}
+
+// ready returns the files that haven't changed since the given delay.
+// The files sharing a base name, like a photo, its sidecar and the video of a Live Photo, are returned together
+// once none of them has changed during the delay, so they are paired in the same batch.
+func (fw *folderWatcher) ready(delay time.Duration) map[string][]string {
+	r := map[string][]string{}
+	limit := time.Now().Add(-delay)
+	for root, files := range fw.pending {
+		last := map[string]time.Time{} // last change by base name
+		for name, t := range files {
+			if b := baseName(name); t.After(last[b]) {
+				last[b] = t
+			}
+		}
+		for name := range files {
+			if last[baseName(name)].Before(limit) {
+				r[root] = append(r[root], name)
+				delete(files, name)
+			}
+		}
+		if len(files) == 0 {
+			delete(fw.pending, root)
+		}
+	}
+	return r
+}
+
+// baseName gives the file name without its extensions: IMG_1234.HEIC, IMG_1234.MOV and IMG_1234.HEIC.xmp share IMG_1234
+func baseName(name string) string {
+	dir, file := path.Split(name)
+	if b, _, _ := strings.Cut(file, "."); b != "" {
+		file = b
+	}
+	return dir + file
+}
+
+// watchFolders monitors the folders given on the command line and uploads new files as they appear.
+// A file is uploaded once it and the files sharing its base name haven't been modified for the duration given by the -watch-delay option.
+func (app *UpCmd) watchFolders(ctx context.Context) error {
+	fw, err := newFolderWatcher(app.Paths)
+	if err != nil {
+		return err
+	}
+	defer fw.Close()
+
+	app.Log.Info(fmt.Sprintf("Watching the folder(s) %s for new files. Press Ctrl+C to stop.", strings.Join(fw.roots, ", ")))
+	fmt.Println("Watching the folder(s) for new files. Press Ctrl+C to stop.")
+
+	tick := time.NewTicker(min(time.Second, app.WatchDelay))
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-fw.w.Events:
+			if !ok {
+				return nil
+			}
+			fw.handleEvent(e)
+		case err, ok := <-fw.w.Errors:
+			if !ok {
+				return nil
+			}
+			app.Log.Error(fmt.Sprintf("watch error: %s", err))
+		case <-tick.C:
+			batches := fw.ready(app.WatchDelay)
+			roots := gen.MapKeys(batches)
+			sort.Strings(roots)
+			for _, root := range roots {
+				err = app.uploadFileList(ctx, root, batches[root])
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					app.Log.Error(err.Error())
+				}
+			}
+		}
+	}
+}
+
+// uploadFileList uploads the given files of the root folder
+func (app *UpCmd) uploadFileList(ctx context.Context, root string, names []string) error {
+	sort.Strings(names)
+	app.Log.Info(fmt.Sprintf("%d new file(s) detected in %s", len(names), root))
+
+	fsys := fshelper.NewFileListFS(fshelper.NewFSWithName(os.DirFS(root), filepath.Base(root)), names)
+	b, err := app.ExploreLocalFolder(ctx, []fs.FS{fsys})
+	if err != nil {
+		return err
+	}
+	err = b.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+	app.browser = b
+	if app.stacks != nil {
+		// stacks are built only with the files of the batch
+		app.stacks = stacking.NewStackBuilder(app.supportedMedia())
+	}
+	return app.uploadLoop(ctx)
+}
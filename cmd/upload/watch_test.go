@@ -0,0 +1,108 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(dst, b, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063000139.jpg", filepath.Join(dir, "PXL_20231006_063000139.jpg"))
+
+	ic := &icCatchUploadsAssets{
+		albums: map[string][]string{},
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serv := cmd.SharedFlags{
+		Immich: ic,
+		Jnl:    fileevent.NewRecorder(log, false),
+		Log:    log,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- UploadCommand(ctx, &serv, []string{"-no-ui", "-watch", "-watch-delay=100ms", dir})
+	}()
+
+	// let the initial upload and the watcher start
+	time.Sleep(500 * time.Millisecond)
+	copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063029647.jpg", filepath.Join(dir, "PXL_20231006_063029647.jpg"))
+	copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063108407.jpg", filepath.Join(dir, "sub", "PXL_20231006_063108407.jpg"))
+	time.Sleep(1500 * time.Millisecond)
+	cancel()
+	<-done
+
+	expected := []string{
+		"PXL_20231006_063000139.jpg",
+		"PXL_20231006_063029647.jpg",
+		"sub/PXL_20231006_063108407.jpg",
+	}
+	if !cmpSlices(expected, ic.assets) {
+		t.Errorf("expected %v, got %v", expected, ic.assets)
+	}
+}
+
+func TestWatchValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"-watch", "-google-photos", "TEST_DATA/Takeout1"},
+		{"-watch", "TEST_DATA/folder/low/PXL_20231006_063000139.jpg"},
+		{"-watch", "TEST_DATA/folder/*/AlbumA"},
+		{"-watch"},
+	} {
+		serv := cmd.SharedFlags{
+			Immich: &stubIC{},
+			Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		_, err := newCommand(context.Background(), &serv, args, nil)
+		if err == nil {
+			t.Errorf("expecting an error for %v", args)
+		}
+	}
+}
+
+func TestWatchReady(t *testing.T) {
+	now := time.Now()
+	fw := &folderWatcher{
+		pending: map[string]map[string]time.Time{
+			"/ingest": {
+				"IMG_0001.HEIC":     now.Add(-time.Minute),
+				"IMG_0001.MOV":      now, // the video of the Live Photo is still being copied
+				"IMG_0002.jpg":      now.Add(-time.Minute),
+				"IMG_0002.jpg.xmp":  now.Add(-time.Minute),
+				"sub/IMG_0001.HEIC": now.Add(-time.Minute),
+			},
+		},
+	}
+	got := fw.ready(10 * time.Second)["/ingest"]
+	expected := []string{"IMG_0002.jpg", "IMG_0002.jpg.xmp", "sub/IMG_0001.HEIC"}
+	if !cmpSlices(expected, got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if len(fw.pending["/ingest"]) != 2 {
+		t.Errorf("expected the Live Photo pending, got %v", fw.pending["/ingest"])
+	}
+}
@@ -0,0 +1,132 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// Values of the -write-xmp option
+const (
+	XMPMissing   = "missing"   // write the sidecar of the assets that don't have one
+	XMPOverwrite = "overwrite" // write the sidecar of all assets, replacing the ones written by immich-go
+)
+
+func validateWriteXMP(s string) (string, error) {
+	s = strings.ToLower(s)
+	switch s {
+	case "", XMPMissing, XMPOverwrite:
+		return s, nil
+	}
+	return "", fmt.Errorf("the option -write-xmp accepts %s or %s", XMPMissing, XMPOverwrite)
+}
+
+// writeXMP writes the metadata gathered for the asset into its XMP sidecar, next to the source file.
+// The albums of the asset are written as keywords.
+//
+// The sidecar is replaced entirely: only the sidecars written by immich-go are overwritten,
+// the others may hold data immich-go doesn't know, like the ratings or the faces.
+func (app *UpCmd) writeXMP(ctx context.Context, a *browser.LocalAssetFile) error {
+	fsys, name := a.FSys, a.FileName+".xmp"
+	if a.SideCar.IsSet() {
+		if app.WriteXMP != XMPOverwrite {
+			return nil
+		}
+		fsys, name = a.SideCar.FSys, a.SideCar.FileName
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if !metadata.IsImmichGoSidecar(b) {
+			app.Log.Warn("the XMP sidecar isn't overwritten, it hasn't been written by immich-go", "file", a.FileName, "sidecar", name)
+			return nil
+		}
+	}
+	d, ok := fsys.(fshelper.OSDirFS)
+	if !ok {
+		return fmt.Errorf("can't write the XMP sidecar %s: the source isn't a folder", name)
+	}
+
+	md := a.Metadata
+	md.Tags = slices.Clone(md.Tags)
+	for _, t := range app.assetAlbums(ctx, a) {
+		md.Tags = append(md.Tags, t.album.Title)
+	}
+	if !md.IsSet() {
+		return nil
+	}
+	if !app.DryRun {
+		var b strings.Builder
+		_ = md.WriteSidecar(&b)
+		err := os.WriteFile(filepath.Join(d.Dir(), filepath.FromSlash(name)), []byte(b.String()), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	app.Jnl.Record(ctx, fileevent.XMPWritten, nil, a.FileName, "sidecar", name)
+	return nil
+}
+
+// overwritesSidecar tells if -write-xmp replaces the asset's own sidecar by the gathered metadata
+func (app *UpCmd) overwritesSidecar(a *browser.LocalAssetFile) bool {
+	if app.WriteXMP != XMPOverwrite {
+		return false
+	}
+	b, err := fs.ReadFile(a.SideCar.FSys, a.SideCar.FileName)
+	return err == nil && metadata.IsImmichGoSidecar(b)
+}
+
+// xmpOnlyAsset writes the sidecar of the asset without uploading it
+func (app *UpCmd) xmpOnlyAsset(ctx context.Context, a *browser.LocalAssetFile) error {
+	defer a.Close()
+	if !app.isSelected(ctx, a) {
+		return nil
+	}
+	if app.ImportCaptions {
+		app.captionAsset(ctx, a)
+	}
+	app.rateAsset(ctx, a)
+	app.geocodeAsset(ctx, a)
+	return app.writeXMP(ctx, a)
+}
+
+// xmpPackets gives the XMP data of the asset: the content of its sidecar,
+// followed by the XMP data embedded into a JPEG file.
+func xmpPackets(a *browser.LocalAssetFile) ([][]byte, error) {
+	var packets [][]byte
+	if a.SideCar.IsSet() {
+		b, err := fs.ReadFile(a.SideCar.FSys, a.SideCar.FileName)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, b)
+	}
+	switch strings.ToLower(a.Ext()) {
+	case ".jpg", ".jpeg":
+	default:
+		return packets, nil
+	}
+	// the asset may have been read by the upload, open the file again
+	f, err := a.FSys.Open(a.FileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	x, err := metadata.ReadJPEGXMP(f)
+	if err != nil {
+		return nil, err
+	}
+	if x != nil {
+		packets = append(packets, x)
+	}
+	return packets, nil
+}
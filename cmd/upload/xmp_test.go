@@ -0,0 +1,122 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestWriteXMP(t *testing.T) {
+	const usersSidecar = "existing sidecar"
+	var b strings.Builder
+	_ = metadata.Metadata{Description: "written by a previous run"}.WriteSidecar(&b)
+	immichGoSidecar := b.String()
+
+	testCases := []struct {
+		name           string
+		args           []string
+		existing       string // content of the existing sidecar, the user's one when empty
+		upload         bool
+		expectedNew    bool // a sidecar is written for the file without sidecar
+		expectedUpdate bool // the existing sidecar is replaced
+	}{
+		{
+			name:        "xmp-only",
+			args:        []string{"-xmp-only"},
+			expectedNew: true,
+		},
+		{
+			name:        "xmp-only overwrite, user's sidecar",
+			args:        []string{"-xmp-only", "-write-xmp=overwrite"},
+			expectedNew: true,
+		},
+		{
+			name:           "xmp-only overwrite, immich-go's sidecar",
+			args:           []string{"-xmp-only", "-write-xmp=overwrite"},
+			existing:       immichGoSidecar,
+			expectedNew:    true,
+			expectedUpdate: true,
+		},
+		{
+			name:        "upload",
+			args:        []string{"-write-xmp=missing"},
+			upload:      true,
+			expectedNew: true,
+		},
+		{
+			name:   "upload without xmp",
+			args:   []string{},
+			upload: true,
+		},
+		{
+			name: "dry-run",
+			args: []string{"-xmp-only", "-dry-run"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			existing := tc.existing
+			if existing == "" {
+				existing = usersSidecar
+			}
+			dir := t.TempDir()
+			copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063000139.jpg", filepath.Join(dir, "PXL_20231006_063000139.jpg"))
+			copyFile(t, "TEST_DATA/folder/low/PXL_20231006_063029647.jpg", filepath.Join(dir, "PXL_20231006_063029647.jpg"))
+			err := os.WriteFile(filepath.Join(dir, "PXL_20231006_063029647.jpg.xmp"), []byte(existing), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ic := &icCatchUploadsAssets{
+				albums: map[string][]string{},
+			}
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			serv := cmd.SharedFlags{
+				Jnl: fileevent.NewRecorder(log, false),
+				Log: log,
+			}
+			if tc.upload {
+				serv.Immich = ic
+			}
+			args := append([]string{"-no-ui", "-album=Holidays"}, tc.args...)
+			err = UploadCommand(context.Background(), &serv, append(args, dir))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			b, err := os.ReadFile(filepath.Join(dir, "PXL_20231006_063000139.jpg.xmp"))
+			switch {
+			case tc.expectedNew && err != nil:
+				t.Errorf("the sidecar should be written: %s", err)
+			case tc.expectedNew:
+				for _, s := range []string{"<exif:DateTimeOriginal>2023-10-06T06:30:00Z</exif:DateTimeOriginal>", "<rdf:li>Holidays</rdf:li>", "x:xmptk='immich-go'"} {
+					if !strings.Contains(string(b), s) {
+						t.Errorf("the sidecar should contain %s", s)
+					}
+				}
+			case err == nil:
+				t.Errorf("no sidecar should be written")
+			}
+
+			b, err = os.ReadFile(filepath.Join(dir, "PXL_20231006_063029647.jpg.xmp"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.expectedUpdate == (string(b) == existing) {
+				t.Errorf("unexpected content of the existing sidecar: %s", b)
+			}
+
+			if tc.upload != (len(ic.assets) == 2) {
+				t.Errorf("unexpected uploaded assets: %v", ic.assets)
+			}
+		})
+	}
+}
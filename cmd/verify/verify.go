@@ -0,0 +1,179 @@
+/*
+Compare the files of a local folder or archive with the server's assets.
+*/
+package verify
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich"
+)
+
+type VerifyCmd struct {
+	*cmd.SharedFlags
+
+	fsyss []fs.FS // the folders and archives to verify
+
+	byChecksum map[string]*immich.Asset
+	byName     map[string][]*immich.Asset // server's assets by lower case file name
+	matched    map[string]bool            // IDs of the server's assets found locally
+
+	inSync, missingOnServer, missingLocally, mismatches int
+}
+
+func NewVerifyCmd(ctx context.Context, common *cmd.SharedFlags, args []string) (*VerifyCmd, error) {
+	cmd := flag.NewFlagSet("verify", flag.ContinueOnError)
+	app := VerifyCmd{
+		SharedFlags: common,
+	}
+	app.SharedFlags.SetFlags(cmd)
+
+	err := cmd.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.NArg() == 0 {
+		return nil, errors.New("the verify command needs the folders or the archives to verify")
+	}
+	app.fsyss, err = fshelper.ParsePath(cmd.Args())
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.SharedFlags.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+func VerifyCommand(ctx context.Context, common *cmd.SharedFlags, args []string) error {
+	app, err := NewVerifyCmd(ctx, common, args)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = fshelper.CloseFSs(app.fsyss) }()
+	return app.run(ctx)
+}
+
+func (app *VerifyCmd) run(ctx context.Context) error {
+	app.byChecksum = map[string]*immich.Asset{}
+	app.byName = map[string][]*immich.Asset{}
+	app.matched = map[string]bool{}
+
+	fmt.Println("Get server's assets...")
+	err := app.Immich.GetAllAssetsWithFilter(ctx, func(a *immich.Asset) error {
+		if a.IsTrashed {
+			return nil
+		}
+		if a.Checksum != "" {
+			app.byChecksum[a.Checksum] = a
+		}
+		name := strings.ToLower(path.Base(a.OriginalFileName))
+		app.byName[name] = append(app.byName[name], a)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Hash the local files...")
+	sm := app.Immich.SupportedMedia()
+	for _, fsys := range app.fsyss {
+		err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if d.IsDir() || !sm.IsMedia(path.Ext(name)) {
+				return nil
+			}
+			return app.verifyFile(fsys, name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var missing []*immich.Asset
+	for _, a := range app.byChecksum {
+		if !app.matched[a.ID] {
+			missing = append(missing, a)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].OriginalFileName < missing[j].OriginalFileName })
+	for _, a := range missing {
+		app.missingLocally++
+		fmt.Printf("missing locally: %s (%s)\n", a.OriginalFileName, a.ID)
+		app.Log.Info("missing locally", "file", a.OriginalFileName, "id", a.ID)
+	}
+
+	fmt.Printf("%d file(s) on the server, %d missing on the server, %d checksum mismatch(es), %d asset(s) missing locally\n", app.inSync, app.missingOnServer, app.mismatches, app.missingLocally)
+	if app.missingOnServer > 0 || app.mismatches > 0 {
+		return fmt.Errorf("%d local file(s) aren't on the server", app.missingOnServer+app.mismatches)
+	}
+	return nil
+}
+
+// verifyFile looks for the local file on the server, by its checksum, then by its name
+func (app *VerifyCmd) verifyFile(fsys fs.FS, name string) error {
+	checksum, err := fileChecksum(fsys, name)
+	if err != nil {
+		return err
+	}
+	display := name
+	if n, ok := fsys.(interface{ Name() string }); ok {
+		display = path.Join(n.Name(), name)
+	}
+
+	if a, ok := app.byChecksum[checksum]; ok {
+		app.inSync++
+		app.matched[a.ID] = true
+		app.Log.Debug("on the server", "file", display, "id", a.ID)
+		return nil
+	}
+	// a server's asset with the same name, but another content
+	for _, a := range app.byName[strings.ToLower(path.Base(name))] {
+		if app.matched[a.ID] {
+			continue
+		}
+		app.mismatches++
+		app.matched[a.ID] = true
+		fmt.Printf("checksum mismatch: %s, %s (%s)\n", display, a.OriginalFileName, a.ID)
+		app.Log.Warn("checksum mismatch", "file", display, "id", a.ID)
+		return nil
+	}
+	app.missingOnServer++
+	fmt.Printf("missing on the server: %s\n", display)
+	app.Log.Warn("missing on the server", "file", display)
+	return nil
+}
+
+// fileChecksum gives the base64 encoded SHA1 of the file, like the server's checksums
+func fileChecksum(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
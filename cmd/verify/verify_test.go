@@ -0,0 +1,73 @@
+package verify
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"log/slog"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/helpers/fileevent"
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+)
+
+type icVerify struct {
+	fakeimmich.MockedCLient
+	assets []*immich.Asset
+}
+
+func (c *icVerify) GetAllAssetsWithFilter(ctx context.Context, fn func(*immich.Asset) error) error {
+	for _, a := range c.assets {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checksum(s string) string {
+	h := sha1.Sum([]byte(s))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func TestVerify(t *testing.T) {
+	ic := &icVerify{}
+	for _, a := range []struct{ id, name, content string }{
+		{"a", "a.jpg", "aaa"},
+		{"b", "b.jpg", "server bbb"},
+		{"c", "c.jpg", "ccc"},
+	} {
+		ic.assets = append(ic.assets, &immich.Asset{ID: a.id, OriginalFileName: a.name, Checksum: checksum(a.content)})
+	}
+	ic.assets = append(ic.assets, &immich.Asset{ID: "t", OriginalFileName: "t.jpg", Checksum: checksum("ttt"), IsTrashed: true})
+
+	fsys := fstest.MapFS{
+		"Photos/a.jpg":   {Data: []byte("aaa")},
+		"Photos/b.jpg":   {Data: []byte("local bbb")},
+		"Photos/d.jpg":   {Data: []byte("ddd")},
+		"Photos/t.jpg":   {Data: []byte("ttt")},
+		"Photos/a.json":  {Data: []byte("{}")},
+		"Album/a(1).jpg": {Data: []byte("aaa")},
+	}
+	app := &VerifyCmd{
+		SharedFlags: &cmd.SharedFlags{
+			Immich: ic,
+			Log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Jnl:    fileevent.NewRecorder(nil, false),
+		},
+		fsyss: []fs.FS{fsys},
+	}
+	err := app.run(context.Background())
+	if err == nil {
+		t.Error("expecting an error for the files missing on the server")
+	}
+	if app.inSync != 2 || app.mismatches != 1 || app.missingOnServer != 2 || app.missingLocally != 1 {
+		t.Errorf("in sync %d, mismatches %d, missing on server %d, missing locally %d, want 2, 1, 2, 1",
+			app.inSync, app.mismatches, app.missingOnServer, app.missingLocally)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Package clientcert loads the client certificate presented to the servers behind a reverse proxy requiring mutual TLS.
+*/
+package clientcert
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// Load reads the client certificate and its private key.
+// The certificate file is a PEM file, or a PKCS#12 file (.p12, .pfx) containing the key.
+// The key of a PEM certificate is read from the key file, or from the certificate file when no key file is given.
+// The passphrase of a protected PKCS#12 file is given by passphrase.
+func Load(certFile, keyFile string, passphrase func() (string, error)) (tls.Certificate, error) {
+	b, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if isPKCS12(certFile, b) {
+		if keyFile != "" {
+			return tls.Certificate{}, fmt.Errorf("the PKCS#12 file %s contains the key, no key file is needed", certFile)
+		}
+		cert, err := loadPKCS12(b, passphrase)
+		if err != nil {
+			// the AES encryption of recent OpenSSL versions isn't supported
+			return tls.Certificate{}, fmt.Errorf("can't read the PKCS#12 file %s: %w, convert it into PEM files, or export it with the -legacy option of openssl", certFile, err)
+		}
+		return cert, nil
+	}
+
+	k := b
+	if keyFile != "" {
+		k, err = os.ReadFile(keyFile)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	cert, err := tls.X509KeyPair(b, k)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("can't read the client certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// isPKCS12 tells if the file is a PKCS#12 file, by its extension or by its binary content
+func isPKCS12(name string, b []byte) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".p12", ".pfx":
+		return true
+	}
+	block, _ := pem.Decode(b)
+	return block == nil
+}
+
+// loadPKCS12 decodes the PKCS#12 content. The passphrase is asked only when the file is protected.
+func loadPKCS12(b []byte, passphrase func() (string, error)) (tls.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(b, "")
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		if passphrase == nil {
+			return tls.Certificate{}, err
+		}
+		var p string
+		p, err = passphrase()
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		blocks, err = pkcs12.ToPEM(b, p)
+	}
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	var certs [][]byte
+	var key []byte
+	for _, block := range blocks {
+		switch {
+		case block.Type == "CERTIFICATE":
+			certs = append(certs, pem.EncodeToMemory(block))
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			key = pem.EncodeToMemory(block)
+		}
+	}
+	if key == nil || len(certs) == 0 {
+		return tls.Certificate{}, errors.New("the file must contain a certificate and its private key")
+	}
+
+	// the certificate of the key comes first, followed by the certificates of the chain
+	for i := range certs {
+		chain := append([][]byte{certs[i]}, certs[:i]...)
+		chain = append(chain, certs[i+1:]...)
+		cert, err := tls.X509KeyPair(joinPEM(chain), key)
+		if err == nil {
+			return cert, nil
+		}
+	}
+	return tls.Certificate{}, errors.New("no certificate matches the private key")
+}
+
+func joinPEM(blocks [][]byte) []byte {
+	var b []byte
+	for _, block := range blocks {
+		b = append(b, block...)
+	}
+	return b
+}
@@ -0,0 +1,113 @@
+package clientcert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePEM writes a self signed certificate and its key into two PEM files
+func writePEM(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "immich-go test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: k}), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestLoadPEM(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePEM(t, dir)
+
+	cert, err := Load(certFile, keyFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.Certificate) != 1 {
+		t.Errorf("one certificate expected, got %d", len(cert.Certificate))
+	}
+
+	// the certificate and the key in the same file
+	c, _ := os.ReadFile(certFile)
+	k, _ := os.ReadFile(keyFile)
+	both := filepath.Join(dir, "client.pem")
+	err = os.WriteFile(both, append(c, k...), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(both, "", nil)
+	if err != nil {
+		t.Errorf("the key should be read from the certificate file: %s", err)
+	}
+
+	_, err = Load(certFile, "", nil)
+	if err == nil {
+		t.Error("an error is expected without the key")
+	}
+}
+
+func TestLoadPKCS12(t *testing.T) {
+	asked := 0
+	passphrase := func(p string) func() (string, error) {
+		return func() (string, error) {
+			asked++
+			return p, nil
+		}
+	}
+
+	cert, err := Load("TEST_DATA/client.p12", "", passphrase("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asked != 1 || cert.PrivateKey == nil {
+		t.Errorf("the passphrase should be asked once, and the key read: %d", asked)
+	}
+
+	_, err = Load("TEST_DATA/client.p12", "", passphrase("wrong"))
+	if err == nil {
+		t.Error("an error is expected with a wrong passphrase")
+	}
+
+	_, err = Load("TEST_DATA/client.p12", "", func() (string, error) { return "", errors.New("no terminal") })
+	if err == nil {
+		t.Error("the error of the passphrase prompt is expected")
+	}
+
+	_, err = Load("TEST_DATA/client.p12", "client.key", passphrase("secret"))
+	if err == nil {
+		t.Error("a key file is not expected with a PKCS#12 file")
+	}
+}
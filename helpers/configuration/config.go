@@ -71,6 +71,46 @@ func DefaultLogFile() string {
 	return filepath.Join(d, "immich-go", f)
 }
 
+// DefaultLockFile gives the default lock file used to prevent overlapping runs,
+// immich-go.lock in the current folder when the user's cache folder is unknown
+func DefaultLockFile() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "immich-go.lock"
+	}
+	return filepath.Join(d, "immich-go", "immich-go.lock")
+}
+
+// DefaultSpoolDir gives the default folder of the offline spool, under the user's cache folder.
+// The folder immich-go-spool of the current folder is used when the cache folder can't be determined.
+func DefaultSpoolDir() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "immich-go-spool"
+	}
+	return filepath.Join(d, "immich-go", "spool")
+}
+
+// DefaultMetadataCacheFile gives the default file of the metadata cache.
+// Without a user's cache folder, the file is created in the current folder.
+func DefaultMetadataCacheFile() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "immich-go-metadata.jsonl"
+	}
+	return filepath.Join(d, "immich-go", "metadata.jsonl")
+}
+
+// DefaultIncrementalDir gives the default folder of the databases of the files uploaded by the previous runs.
+// It falls back to the current folder when neither $XDG_CACHE_HOME nor $HOME is set.
+func DefaultIncrementalDir() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "immich-go-scan"
+	}
+	return filepath.Join(d, "immich-go", "scan")
+}
+
 // MakeDirForFile create all dirs to write the given file
 func MakeDirForFile(f string) error {
 	dir := filepath.Dir(f)
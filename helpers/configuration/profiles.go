@@ -0,0 +1,159 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AllCommands is the key of the flags given to all commands
+const AllCommands = "all"
+
+// Profiles is the content of the YAML configuration file: the servers and the default flags, by profile name
+//
+//	default: home
+//	profiles:
+//	  home:
+//	    server: http://nas.local:2283
+//	    key: API_KEY
+//	    flags:
+//	      all: [-client-timeout=20m]
+//	      upload: [-create-album-folder]
+//	    sources:
+//	      /mnt/photos/takeout: [-google-photos]
+type Profiles struct {
+	Default  string             `yaml:"default,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile gives a server and the default flags used with it
+type Profile struct {
+	Server  string              `yaml:"server,omitempty"`
+	API     string              `yaml:"api,omitempty"`
+	Key     string              `yaml:"key,omitempty"`
+	Flags   map[string][]string `yaml:"flags,omitempty"`   // default flags by command name, or for all commands
+	Sources map[string][]string `yaml:"sources,omitempty"` // flags of the upload command, by source folder
+}
+
+// DefaultProfilesFile gives the default name of the YAML configuration file, next to immich-go.json.
+// A local file is used when the user's configuration folder can't be determined.
+func DefaultProfilesFile() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "./config.yaml"
+	}
+	return filepath.Join(config, "immich-go", "config.yaml")
+}
+
+// ReadProfiles reads the YAML configuration file
+func ReadProfiles(name string) (Profiles, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return Profiles{}, err
+	}
+	var p Profiles
+	err = yaml.Unmarshal(b, &p)
+	if err != nil {
+		return Profiles{}, fmt.Errorf("can't read the configuration file %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// Get gives the profile by its name, or the default profile when the name is empty.
+// It gives an empty profile when no name is given and the file has no default profile.
+func (p Profiles) Get(name string) (Profile, error) {
+	if name == "" {
+		name = p.Default
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	profile, ok := p.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(p.Profiles))
+		for n := range p.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("unknown profile %q, the profiles are: %s", name, strings.Join(names, ", "))
+	}
+	return profile, nil
+}
+
+// CommandFlags gives the default flags of the command: the flags of all commands, the flags of the command,
+// and for the upload command, the flags of the sources containing one of the paths
+func (p Profile) CommandFlags(command string, paths []string) ([]string, error) {
+	flags := append([]string{}, p.Flags[AllCommands]...)
+	flags = append(flags, p.Flags[command]...)
+	if command != "upload" {
+		return flags, nil
+	}
+	sources := make([]string, 0, len(p.Sources))
+	for s := range p.Sources {
+		sources = append(sources, s)
+	}
+	// the most generic folders first, so the flags of the deepest ones win
+	sort.Strings(sources)
+	for _, s := range sources {
+		for _, path := range paths {
+			in, err := inFolder(s, path)
+			if err != nil {
+				return nil, err
+			}
+			if in {
+				flags = append(flags, p.Sources[s]...)
+				break
+			}
+		}
+	}
+	return flags, nil
+}
+
+// inFolder tells if the path is the folder or is inside it
+func inFolder(folder, path string) (bool, error) {
+	folder, err := filepath.Abs(folder)
+	if err != nil {
+		return false, err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(folder, path)
+	if err != nil {
+		// not on the same volume
+		return false, nil
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// SetServer sets the server and the key of the profile, and makes it the default profile when there is none.
+// The flags of an existing profile are kept.
+func (p *Profiles) SetServer(name, server, api, key string) {
+	if p.Profiles == nil {
+		p.Profiles = map[string]Profile{}
+	}
+	profile := p.Profiles[name]
+	profile.Server, profile.API, profile.Key = server, api, key
+	p.Profiles[name] = profile
+	if p.Default == "" {
+		p.Default = name
+	}
+}
+
+// Write writes the profiles into the YAML file. The comments of the file are lost.
+func (p Profiles) Write(name string) error {
+	b, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+	err = MakeDirForFile(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, b, 0o600)
+}
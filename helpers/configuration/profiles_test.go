@@ -0,0 +1,79 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testProfiles = `
+default: home
+profiles:
+  home:
+    server: http://nas.local:2283
+    key: HOME_KEY
+    flags:
+      all: [-client-timeout=20m]
+      upload: [-create-album-folder]
+    sources:
+      photos: [-album=Photos]
+      photos/takeout: [-google-photos]
+  parents:
+    server: https://parents.example.com
+    key: PARENTS_KEY
+`
+
+func TestProfiles(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(name, []byte(testProfiles), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := ReadProfiles(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := profiles.Get("")
+	if err != nil || p.Key != "HOME_KEY" {
+		t.Errorf("the default profile is expected, got %+v, %v", p, err)
+	}
+	p, err = profiles.Get("parents")
+	if err != nil || p.Server != "https://parents.example.com" {
+		t.Errorf("the parents profile is expected, got %+v, %v", p, err)
+	}
+	_, err = profiles.Get("office")
+	if err == nil {
+		t.Errorf("an error is expected for an unknown profile")
+	}
+
+	home, _ := profiles.Get("home")
+	tests := []struct {
+		command string
+		paths   []string
+		want    []string
+	}{
+		{command: "download", want: []string{"-client-timeout=20m"}},
+		{command: "upload", paths: []string{"videos"}, want: []string{"-client-timeout=20m", "-create-album-folder"}},
+		{command: "upload", paths: []string{"photos/2023"}, want: []string{"-client-timeout=20m", "-create-album-folder", "-album=Photos"}},
+		{command: "upload", paths: []string{"photos/takeout/takeout-1.zip"}, want: []string{"-client-timeout=20m", "-create-album-folder", "-album=Photos", "-google-photos"}},
+		{command: "upload", paths: []string{"photos-old"}, want: []string{"-client-timeout=20m", "-create-album-folder"}},
+	}
+	for _, tt := range tests {
+		got, err := home.CommandFlags(tt.command, tt.paths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %v: expected %v, got %v", tt.command, tt.paths, tt.want, got)
+		}
+	}
+}
+
+func TestNoDefaultProfile(t *testing.T) {
+	p, err := Profiles{}.Get("")
+	if err != nil || !reflect.DeepEqual(p, Profile{}) {
+		t.Errorf("an empty profile is expected, got %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,65 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Session is the token obtained by the OAuth login on a server
+type Session struct {
+	AccessToken string    `json:"accessToken"`
+	UserEmail   string    `json:"userEmail,omitempty"`
+	RedirectURI string    `json:"redirectUri,omitempty"` // used to log in again when the session expires
+	Created     time.Time `json:"created"`
+}
+
+// Sessions are the sessions by server address
+type Sessions map[string]Session
+
+// DefaultSessionsFile gives the default name of the file of the sessions, in the user's configuration folder,
+// or in the current folder when it can't be determined
+func DefaultSessionsFile() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "./sessions.json"
+	}
+	return filepath.Join(config, "immich-go", "sessions.json")
+}
+
+// ReadSessions reads the file of the sessions. A missing file gives no session.
+func ReadSessions(name string) (Sessions, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return Sessions{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := Sessions{}
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write writes the sessions into a file readable by the user only
+func (s Sessions) Write(name string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = MakeDirForFile(name)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(name, b, 0o600)
+	if err != nil {
+		return err
+	}
+	// the permissions of an existing file are not changed by WriteFile
+	return os.Chmod(name, 0o600)
+}
@@ -0,0 +1,46 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "immich-go", "sessions.json")
+	s, err := ReadSessions(name)
+	if err != nil || len(s) != 0 {
+		t.Fatalf("a missing file gives no session, got %v, %v", s, err)
+	}
+
+	// an existing file readable by everybody
+	err = os.MkdirAll(filepath.Dir(name), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(name, []byte("{}"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s["http://nas:2283"] = Session{AccessToken: "TOKEN", Created: time.Now()}
+	err = s.Write(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSessions(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read["http://nas:2283"].AccessToken != "TOKEN" {
+		t.Errorf("unexpected sessions %+v", read)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o600 && os.PathSeparator == '/' {
+		t.Errorf("the sessions file should be readable by the user only, got %v", perm)
+	}
+}
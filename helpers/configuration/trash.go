@@ -0,0 +1,71 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// TrashOperation is a move of server's assets to the trash, recorded so it can be undone
+type TrashOperation struct {
+	Time     time.Time `json:"time"`
+	Server   string    `json:"server"`
+	Command  string    `json:"command"`
+	IDs      []string  `json:"ids"`
+	Restored bool      `json:"restored,omitempty"`
+}
+
+// TrashJournal are the operations moving assets to the trash, the oldest first
+type TrashJournal []TrashOperation
+
+// DefaultTrashJournalFile gives the default file of the trash journal, kept with the other cached files,
+// or in the current folder when os.UserCacheDir fails
+func DefaultTrashJournalFile() string {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "immich-go-trash.json"
+	}
+	return filepath.Join(d, "immich-go", "trash.json")
+}
+
+// ReadTrashJournal reads the journal. A missing file gives no operation.
+func ReadTrashJournal(name string) (TrashJournal, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return TrashJournal{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	j := TrashJournal{}
+	err = json.Unmarshal(b, &j)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// LastOperation gives the index of the last operation on the server not restored yet, -1 when none
+func (j TrashJournal) LastOperation(server string) int {
+	for i := len(j) - 1; i >= 0; i-- {
+		if j[i].Server == server && !j[i].Restored {
+			return i
+		}
+	}
+	return -1
+}
+
+// Write writes the journal into a file readable by the user only
+func (j TrashJournal) Write(name string) error {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = MakeDirForFile(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, b, 0o600)
+}
@@ -0,0 +1,45 @@
+package configuration
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrashJournal(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "immich-go", "trash.json")
+	j, err := ReadTrashJournal(name)
+	if err != nil || len(j) != 0 {
+		t.Fatalf("a missing file gives no operation, got %v, %v", j, err)
+	}
+	now := time.Now().Truncate(time.Second)
+	j = append(j,
+		TrashOperation{Time: now, Server: "http://nas:2283", Command: "duplicate", IDs: []string{"1"}},
+		TrashOperation{Time: now, Server: "http://other:2283", Command: "sync", IDs: []string{"2"}},
+		TrashOperation{Time: now, Server: "http://nas:2283", Command: "sync", IDs: []string{"3", "4"}, Restored: true},
+	)
+	err = j.Write(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadTrashJournal(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 3 || !read[0].Time.Equal(now) || read[2].IDs[1] != "4" {
+		t.Errorf("unexpected journal %+v", read)
+	}
+	tests := []struct {
+		server   string
+		expected int
+	}{
+		{"http://nas:2283", 0},
+		{"http://other:2283", 1},
+		{"http://unknown:2283", -1},
+	}
+	for _, tt := range tests {
+		if got := read.LastOperation(tt.server); got != tt.expected {
+			t.Errorf("%s: expected the operation %d, got %d", tt.server, tt.expected, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package fileevent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/simulot/immich-go/browser"
 	"github.com/simulot/immich-go/helpers/gen"
@@ -26,12 +28,15 @@ const (
 	DiscoveredSidecar                 // = "Scanned side car file"
 	DiscoveredDiscarded               // = "Discarded"
 	DiscoveredUnsupported             // = "File type not supported"
+	DiscoveredMismatch                // = "File content not matching its extension"
+	DiscoveredHidden                  // = "Hidden file or folder skipped"
 
 	AnalysisAssociatedMetadata
 	AnalysisMissingAssociatedMetadata
 	AnalysisLocalDuplicate
 
 	UploadNotSelected
+	UploadUnchanged       // = "Unchanged since its upload"
 	UploadUpgraded        // = "Server's asset upgraded"
 	UploadServerDuplicate // = "Server has photo"
 	UploadServerBetter    // = "Server's asset is better"
@@ -39,11 +44,14 @@ const (
 	UploadAddToAlbum  // = "Added to an album"
 	UploadServerError // = "Server error"
 
-	Uploaded  // = "Uploaded"
-	Stacked   // = "Stacked"
-	LivePhoto // = "Live photo"
-	Metadata  // = "Metadata files"
-	INFO      // = "Info"
+	Uploaded   // = "Uploaded"
+	Spooled    // = "Spooled"
+	XMPWritten // = "XMP sidecar written"
+	EXIFFixed  // = "Date written into the source file"
+	Stacked    // = "Stacked"
+	LivePhoto  // = "Live photo"
+	Metadata   // = "Metadata files"
+	INFO       // = "Info"
 	Error
 	MaxCode
 )
@@ -54,12 +62,15 @@ var _code = map[Code]string{
 	DiscoveredSidecar:     "scanned sidecar file",
 	DiscoveredDiscarded:   "discarded file",
 	DiscoveredUnsupported: "unsupported file",
+	DiscoveredMismatch:    "file content not matching its extension",
+	DiscoveredHidden:      "hidden file or folder skipped",
 
 	AnalysisAssociatedMetadata:        "associated metadata file",
 	AnalysisMissingAssociatedMetadata: "missing associated metadata file",
 	AnalysisLocalDuplicate:            "file duplicated in the input",
 
 	UploadNotSelected:     "file not selected",
+	UploadUnchanged:       "unchanged since its upload",
 	UploadUpgraded:        "server's asset upgraded with the input",
 	UploadAddToAlbum:      "added to an album",
 	UploadServerDuplicate: "server has same asset",
@@ -67,6 +78,9 @@ var _code = map[Code]string{
 	UploadAlbumCreated:    "album created/updated",
 	UploadServerError:     "upload error",
 	Uploaded:              "uploaded",
+	Spooled:               "spooled for a later upload",
+	XMPWritten:            "XMP sidecar written",
+	EXIFFixed:             "date written into the source file",
 
 	Stacked:   "Stacked",
 	LivePhoto: "Live photo",
@@ -75,6 +89,48 @@ var _code = map[Code]string{
 	Error:     "error",
 }
 
+// _key gives the stable names of the events used in the JSON report
+var _key = map[Code]string{
+	DiscoveredImage:       "discovered_image",
+	DiscoveredVideo:       "discovered_video",
+	DiscoveredSidecar:     "discovered_sidecar",
+	DiscoveredDiscarded:   "discarded",
+	DiscoveredUnsupported: "unsupported",
+	DiscoveredMismatch:    "content_mismatch",
+	DiscoveredHidden:      "hidden",
+
+	AnalysisAssociatedMetadata:        "associated_metadata",
+	AnalysisMissingAssociatedMetadata: "missing_metadata",
+	AnalysisLocalDuplicate:            "local_duplicate",
+
+	UploadNotSelected:     "not_selected",
+	UploadUnchanged:       "unchanged",
+	UploadUpgraded:        "server_upgraded",
+	UploadAddToAlbum:      "added_to_album",
+	UploadServerDuplicate: "server_duplicate",
+	UploadServerBetter:    "server_better",
+	UploadAlbumCreated:    "album_created",
+	UploadServerError:     "upload_error",
+	Uploaded:              "uploaded",
+	Spooled:               "spooled",
+	XMPWritten:            "xmp_written",
+	EXIFFixed:             "exif_fixed",
+
+	Stacked:   "stacked",
+	LivePhoto: "live_photo",
+	Metadata:  "metadata",
+	INFO:      "info",
+	Error:     "error",
+}
+
+// Key gives the stable name of the event, used in the reports read by programs
+func (e Code) Key() string {
+	if s, ok := _key[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("code_%d", int(e))
+}
+
 func (e Code) String() string {
 	if s, ok := _code[e]; ok {
 		return s
@@ -82,20 +138,50 @@ func (e Code) String() string {
 	return fmt.Sprintf("unknown event code: %d", int(e))
 }
 
+// FileEvent is an event recorded on a file, with its details
+type FileEvent struct {
+	Event   string            `json:"event"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// newFileEvent reads the details given as key, value pairs. A lonely value is kept under the key "message".
+func newFileEvent(code Code, args []any) FileEvent {
+	e := FileEvent{Event: code.Key()}
+	if len(args) > 0 {
+		e.Details = map[string]string{}
+	}
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			e.Details["message"] = fmt.Sprint(args[i])
+			break
+		}
+		e.Details[fmt.Sprint(args[i])] = fmt.Sprint(args[i+1])
+	}
+	return e
+}
+
+// Recorder counts the events, and keeps the events of each file when created with debug
 type Recorder struct {
-	lock       sync.RWMutex
-	counts     []int64
-	fileEvents map[string]map[Code]int
-	log        *slog.Logger
-	debug      bool
+	lock        sync.RWMutex
+	counts      []int64
+	fileEvents  map[string]map[Code]int
+	fileDetails map[string][]FileEvent
+	log         *slog.Logger
+	debug       bool
+	albums      map[string]*AlbumStats
+	stream      *json.Encoder // NDJSON stream of the events
+	echo        io.Writer     // console where the events of the files are echoed
+	streamLock  sync.Mutex
 }
 
 func NewRecorder(l *slog.Logger, debug bool) *Recorder {
 	r := &Recorder{
-		counts:     make([]int64, MaxCode),
-		fileEvents: map[string]map[Code]int{},
-		log:        l,
-		debug:      debug,
+		counts:      make([]int64, MaxCode),
+		fileEvents:  map[string]map[Code]int{},
+		fileDetails: map[string][]FileEvent{},
+		albums:      map[string]*AlbumStats{},
+		log:         l,
+		debug:       debug,
 	}
 	return r
 }
@@ -111,8 +197,18 @@ func (r *Recorder) Record(ctx context.Context, code Code, object any, file strin
 		v := events[code] + 1
 		events[code] = v
 		r.fileEvents[file] = events
+		r.fileDetails[file] = append(r.fileDetails[file], newFileEvent(code, args))
 		r.lock.Unlock()
 	}
+	r.streamLock.Lock()
+	if r.stream != nil {
+		e := newFileEvent(code, args)
+		_ = r.stream.Encode(StreamEvent{Time: time.Now(), Event: e.Event, File: file, Details: e.Details})
+	}
+	if r.echo != nil && file != "" {
+		fmt.Fprintln(r.echo, echoLine(code, file, newFileEvent(code, args).Details))
+	}
+	r.streamLock.Unlock()
 	if r.log != nil {
 		level := slog.LevelInfo
 		if file != "" {
@@ -138,6 +234,95 @@ func (r *Recorder) Record(ctx context.Context, code Code, object any, file strin
 	}
 }
 
+// AlbumStats counts the assets of an album handled during the run
+type AlbumStats struct {
+	Added   int64 `json:"added"`   // assets added to the album
+	Present int64 `json:"present"` // assets already in the album
+	Failed  int64 `json:"failed"`  // assets that can't be added to the album
+}
+
+// Outcomes of the addition of an asset to an album
+type AlbumOutcome int
+
+const (
+	AlbumAdded AlbumOutcome = iota
+	AlbumPresent
+	AlbumFailed
+)
+
+// RecordAlbum counts the outcome of the addition of an asset to the album
+func (r *Recorder) RecordAlbum(album string, outcome AlbumOutcome) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	s := r.albums[album]
+	if s == nil {
+		s = &AlbumStats{}
+		r.albums[album] = s
+	}
+	switch outcome {
+	case AlbumAdded:
+		s.Added++
+	case AlbumPresent:
+		s.Present++
+	case AlbumFailed:
+		s.Failed++
+	}
+}
+
+// Albums gives the statistics of the albums handled during the run
+func (r *Recorder) Albums() map[string]AlbumStats {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	albums := make(map[string]AlbumStats, len(r.albums))
+	for k, v := range r.albums {
+		albums[k] = *v
+	}
+	return albums
+}
+
+// StreamEvent is a line of the NDJSON stream of the events
+type StreamEvent struct {
+	Time    time.Time         `json:"time"`
+	Event   string            `json:"event"`
+	File    string            `json:"file,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	Counts  map[string]int64  `json:"counts,omitempty"`
+}
+
+// SetStream writes each event as a JSON object on a line of w, as soon as it is recorded.
+// The final report is written as a "summary" event with the counters.
+func (r *Recorder) SetStream(w io.Writer) {
+	r.streamLock.Lock()
+	r.stream = json.NewEncoder(w)
+	r.streamLock.Unlock()
+}
+
+// SetEcho writes a line on w for each event of a file, as soon as it is recorded
+func (r *Recorder) SetEcho(w io.Writer) {
+	r.streamLock.Lock()
+	r.echo = w
+	r.streamLock.Unlock()
+}
+
+// echoLine gives the event, the file and the details sorted by key
+func echoLine(code Code, file string, details map[string]string) string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%-40s: %s", code.String(), file))
+	keys := gen.MapKeys(details)
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf(", %s: %s", k, details[k]))
+	}
+	return sb.String()
+}
+
+// KeepFileEvents makes the recorder keep the events of each file, as when created with debug
+func (r *Recorder) KeepFileEvents() {
+	r.lock.Lock()
+	r.debug = true
+	r.lock.Unlock()
+}
+
 func (r *Recorder) SetLogger(l *slog.Logger) {
 	r.log = l
 }
@@ -160,6 +345,12 @@ func (r *Recorder) Report() {
 	} {
 		sb.WriteString(fmt.Sprintf("%-40s: %7d\n", c.String(), r.counts[c]))
 	}
+	if r.counts[DiscoveredMismatch] > 0 {
+		sb.WriteString(fmt.Sprintf("%-40s: %7d\n", DiscoveredMismatch.String(), r.counts[DiscoveredMismatch]))
+	}
+	if r.counts[DiscoveredHidden] > 0 {
+		sb.WriteString(fmt.Sprintf("%-40s: %7d\n", DiscoveredHidden.String(), r.counts[DiscoveredHidden]))
+	}
 
 	sb.WriteString("\n")
 	sb.WriteString("Uploading:\n")
@@ -174,9 +365,46 @@ func (r *Recorder) Report() {
 	} {
 		sb.WriteString(fmt.Sprintf("%-40s: %7d\n", c.String(), r.counts[c]))
 	}
+	// reported only when used
+	for _, c := range []Code{
+		UploadUnchanged,
+		Spooled,
+		XMPWritten,
+		EXIFFixed,
+	} {
+		if r.counts[c] > 0 {
+			sb.WriteString(fmt.Sprintf("%-40s: %7d\n", c.String(), r.counts[c]))
+		}
+	}
+
+	albums := r.Albums()
+	if len(albums) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("%-40s  %7s %7s %7s\n", "Albums:", "added", "present", "failed"))
+		sb.WriteString("-------\n")
+		titles := gen.MapKeys(albums)
+		sort.Strings(titles)
+		for _, t := range titles {
+			a := albums[t]
+			name := t
+			if len([]rune(name)) > 40 {
+				name = string([]rune(name)[:39]) + "…"
+			}
+			mark := ""
+			if a.Failed > 0 {
+				mark = "  incomplete"
+			}
+			sb.WriteString(fmt.Sprintf("%-40s: %7d %7d %7d%s\n", name, a.Added, a.Present, a.Failed, mark))
+		}
+	}
 
 	r.log.Info(sb.String())
 	fmt.Println(sb.String())
+	r.streamLock.Lock()
+	if r.stream != nil {
+		_ = r.stream.Encode(StreamEvent{Time: time.Now(), Event: "summary", Counts: r.CountsByKey()})
+	}
+	r.streamLock.Unlock()
 }
 
 func (r *Recorder) GetCounts() []int64 {
@@ -187,6 +415,15 @@ func (r *Recorder) GetCounts() []int64 {
 	return counts
 }
 
+// CountsByKey gives the counters by the stable names of the events
+func (r *Recorder) CountsByKey() map[string]int64 {
+	counts := map[string]int64{}
+	for c := Code(0); c < MaxCode; c++ {
+		counts[c.Key()] = atomic.LoadInt64(&r.counts[c])
+	}
+	return counts
+}
+
 func (r *Recorder) WriteFileCounts(w io.Writer) error {
 	reportCodes := []Code{
 		-1,
@@ -234,6 +471,43 @@ func (r *Recorder) WriteFileCounts(w io.Writer) error {
 	return nil
 }
 
+// JSONReport is the report of a run read by programs. The names of its fields and of the events are stable.
+type JSONReport struct {
+	Counts map[string]int64      `json:"counts"`
+	Albums map[string]AlbumStats `json:"albums"`
+	Files  []FileReport          `json:"files"`
+}
+
+// FileReport gives the events recorded on a file, in their order
+type FileReport struct {
+	File   string      `json:"file"`
+	Events []FileEvent `json:"events"`
+}
+
+// Summary gives the counts of the events and the events of each file, sorted by file name.
+// The files are listed only when the recorder has been created with debug.
+func (r *Recorder) Summary() JSONReport {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	report := JSONReport{Counts: r.CountsByKey(), Albums: map[string]AlbumStats{}, Files: []FileReport{}}
+	for k, v := range r.albums {
+		report.Albums[k] = *v
+	}
+	keys := gen.MapKeys(r.fileDetails)
+	sort.Strings(keys)
+	for _, f := range keys {
+		report.Files = append(report.Files, FileReport{File: f, Events: r.fileDetails[f]})
+	}
+	return report
+}
+
+// WriteJSONReport writes the summary of the run in JSON
+func (r *Recorder) WriteJSONReport(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Summary())
+}
+
 func (r *Recorder) TotalAssets() int64 {
 	return atomic.LoadInt64(&r.counts[DiscoveredImage]) + atomic.LoadInt64(&r.counts[DiscoveredVideo])
 }
@@ -248,6 +522,7 @@ func (r *Recorder) TotalProcessed(forcedMissingJSON bool) int64 {
 	v := atomic.LoadInt64(&r.counts[Uploaded]) +
 		atomic.LoadInt64(&r.counts[UploadServerError]) +
 		atomic.LoadInt64(&r.counts[UploadNotSelected]) +
+		atomic.LoadInt64(&r.counts[UploadUnchanged]) +
 		atomic.LoadInt64(&r.counts[UploadUpgraded]) +
 		atomic.LoadInt64(&r.counts[UploadServerDuplicate]) +
 		atomic.LoadInt64(&r.counts[UploadServerBetter]) +
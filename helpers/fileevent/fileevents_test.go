@@ -0,0 +1,115 @@
+package fileevent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	r := NewRecorder(nil, false)
+	r.Record(context.Background(), DiscoveredImage, nil, "a.jpg")
+	r.KeepFileEvents()
+	r.Record(context.Background(), Uploaded, nil, "a.jpg", "capture date", "2023-10-06")
+	r.Record(context.Background(), UploadNotSelected, nil, "b.jpg", "reason", "banned file")
+	r.Record(context.Background(), Error, nil, "b.jpg", "lonely value")
+
+	var b bytes.Buffer
+	err := r.WriteJSONReport(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got JSONReport
+	err = json.Unmarshal(b.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Counts) != int(MaxCode) || got.Counts["discovered_image"] != 1 || got.Counts["uploaded"] != 1 || got.Counts["not_selected"] != 1 {
+		t.Errorf("unexpected counts: %v", got.Counts)
+	}
+	expected := []FileReport{
+		{File: "a.jpg", Events: []FileEvent{{Event: "uploaded", Details: map[string]string{"capture date": "2023-10-06"}}}},
+		{File: "b.jpg", Events: []FileEvent{
+			{Event: "not_selected", Details: map[string]string{"reason": "banned file"}},
+			{Event: "error", Details: map[string]string{"message": "lonely value"}},
+		}},
+	}
+	if !reflect.DeepEqual(got.Files, expected) {
+		t.Errorf("expected %v, got %v", expected, got.Files)
+	}
+}
+
+func TestCodeKeys(t *testing.T) {
+	seen := map[string]bool{}
+	for c := Code(0); c < MaxCode; c++ {
+		k := c.Key()
+		if _, ok := _key[c]; !ok || seen[k] {
+			t.Errorf("code %d has no unique key: %s", c, k)
+		}
+		seen[k] = true
+	}
+}
+
+func TestStream(t *testing.T) {
+	var b bytes.Buffer
+	r := NewRecorder(slog.New(slog.NewTextHandler(io.Discard, nil)), false)
+	r.SetStream(&b)
+	r.Record(context.Background(), Uploaded, nil, "a.jpg", "capture date", "2023-10-06")
+	r.Report()
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", lines)
+	}
+	var e StreamEvent
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Event != "uploaded" || e.File != "a.jpg" || e.Details["capture date"] != "2023-10-06" || e.Time.IsZero() {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Event != "summary" || e.Counts["uploaded"] != 1 {
+		t.Errorf("unexpected summary: %+v", e)
+	}
+}
+
+func TestAlbums(t *testing.T) {
+	r := NewRecorder(slog.New(slog.NewTextHandler(io.Discard, nil)), false)
+	r.RecordAlbum("Summer", AlbumAdded)
+	r.RecordAlbum("Summer", AlbumAdded)
+	r.RecordAlbum("Summer", AlbumPresent)
+	r.RecordAlbum("Winter", AlbumFailed)
+
+	expected := map[string]AlbumStats{
+		"Summer": {Added: 2, Present: 1},
+		"Winter": {Failed: 1},
+	}
+	if got := r.Albums(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := r.Summary().Albums; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v in the summary, got %v", expected, got)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	var b bytes.Buffer
+	r := NewRecorder(slog.New(slog.NewTextHandler(io.Discard, nil)), false)
+	r.SetEcho(&b)
+	r.Record(context.Background(), Uploaded, nil, "a.jpg", "capture date", "2023-10-06", "album", "Summer")
+	r.Record(context.Background(), DiscoveredImage, nil, "")
+
+	want := fmt.Sprintf("%-40s: a.jpg, album: Summer, capture date: 2023-10-06\n", Uploaded.String())
+	if b.String() != want {
+		t.Errorf("expected %q, got %q", want, b.String())
+	}
+}
@@ -0,0 +1,67 @@
+package fshelper
+
+import (
+	"io/fs"
+	"path"
+)
+
+// FileListFS exposes only a given list of files of an underlying FS.
+// Directories leading to those files are visible, other entries are hidden
+// when walking the file system.
+//
+// It is used to browse only the files that have been changed in a folder.
+type FileListFS struct {
+	fsys  fs.FS
+	files map[string]bool // listed files
+	dirs  map[string]bool // directories leading to the listed files
+}
+
+func NewFileListFS(fsys fs.FS, names []string) *FileListFS {
+	fl := &FileListFS{
+		fsys:  fsys,
+		files: map[string]bool{},
+		dirs:  map[string]bool{".": true},
+	}
+	for _, name := range names {
+		fl.files[name] = true
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			fl.dirs[dir] = true
+		}
+	}
+	return fl
+}
+
+func (fl FileListFS) Open(name string) (fs.File, error) {
+	return fl.fsys.Open(name)
+}
+
+func (fl FileListFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(fl.fsys, name)
+}
+
+// ReadDir returns only the listed files and the directories leading to them
+func (fl FileListFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fl.dirs[name] {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := fs.ReadDir(fl.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	returned := []fs.DirEntry{}
+	for _, e := range entries {
+		p := path.Join(name, e.Name())
+		if (e.IsDir() && fl.dirs[p]) || (!e.IsDir() && fl.files[p]) {
+			returned = append(returned, e)
+		}
+	}
+	return returned, nil
+}
+
+// Name gives the name of the underlying FS
+func (fl FileListFS) Name() string {
+	if fsys, ok := fl.fsys.(NameFS); ok {
+		return fsys.Name()
+	}
+	return ""
+}
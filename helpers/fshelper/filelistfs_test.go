@@ -0,0 +1,30 @@
+package fshelper
+
+import (
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_FileListFS(t *testing.T) {
+	fsys := NewFileListFS(os.DirFS("TESTDATA"), []string{"A/T/10.jpg", "A/2.json", "C.JPG"})
+
+	files := []string{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"A/2.json", "A/T/10.jpg", "C.JPG"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
@@ -25,7 +25,7 @@ type GlobWalkFS struct {
 func NewGlobWalkFS(pattern string) (fs.FS, error) {
 	dir, magic := FixedPathAndMagic(pattern)
 	if magic == "" {
-		s, err := os.Stat(dir)
+		s, err := os.Stat(OSPath(dir))
 		if err != nil {
 			return nil, err
 		}
@@ -37,13 +37,13 @@ func NewGlobWalkFS(pattern string) (fs.FS, error) {
 				dir, _ = os.Getwd()
 			}
 			return &GlobWalkFS{
-				rootFS: NewFSWithName(os.DirFS(dir), filepath.Base(dir)),
+				rootFS: NewFSWithName(os.DirFS(OSPath(dir)), filepath.Base(dir)),
 				dir:    dir,
 				parts:  []string{magic},
 			}, nil
 		} else {
 			return &GlobWalkFS{
-				rootFS: NewFSWithName(os.DirFS(dir), filepath.Base(dir)),
+				rootFS: NewFSWithName(os.DirFS(OSPath(dir)), filepath.Base(dir)),
 				dir:    dir,
 			}, nil
 		}
@@ -51,13 +51,13 @@ func NewGlobWalkFS(pattern string) (fs.FS, error) {
 	if dir == "" {
 		dir, _ = os.Getwd()
 	}
-	parts := strings.Split(magic, string(os.PathSeparator))
+	parts := strings.Split(magic, "/")
 	for i := range parts {
 		parts[i] = strings.ToLower(parts[i])
 	}
 
 	return &GlobWalkFS{
-		rootFS: NewFSWithName(os.DirFS(dir), filepath.Base(dir)),
+		rootFS: NewFSWithName(os.DirFS(OSPath(dir)), filepath.Base(dir)),
 		dir:    dir,
 		parts:  parts,
 	}, nil
@@ -72,7 +72,8 @@ func (gw GlobWalkFS) match(name string) bool {
 		return true
 	}
 
-	parts := strings.Split(name, string(os.PathSeparator))
+	// the names of a fs.FS are separated by slashes, even on Windows
+	parts := strings.Split(name, "/")
 	for i := range parts {
 		parts[i] = strings.ToLower(parts[i])
 	}
@@ -81,7 +82,7 @@ func (gw GlobWalkFS) match(name string) bool {
 			return false
 		}
 	}
-	parts = strings.Split(name, string(os.PathSeparator))
+	parts = strings.Split(name, "/")
 	if len(gw.parts) > len(parts) {
 		s, err := fs.Stat(gw, path.Join(parts[:min(len(gw.parts), len(parts))]...))
 		if err != nil || !s.IsDir() {
@@ -140,6 +141,11 @@ func (gw GlobWalkFS) Name() string {
 	return filepath.Base(gw.dir)
 }
 
+// Dir gives the OS folder of the FS
+func (gw GlobWalkFS) Dir() string {
+	return gw.dir
+}
+
 // FixedPathAndMagic split the path with the fixed part and the variable part
 func FixedPathAndMagic(name string) (string, string) {
 	if !HasMagic(name) {
@@ -154,7 +160,11 @@ func FixedPathAndMagic(name string) (string, string) {
 		}
 	}
 	fixed := ""
-	if name[0] == '/' {
+	switch {
+	case isUNC(name):
+		// \\server\share\folder
+		fixed = "//"
+	case name[0] == '/':
 		fixed = "/"
 	}
 	return fixed + path.Join(parts[:p]...), path.Join(parts[p:]...)
@@ -201,6 +211,11 @@ func (f FSWithName) ReadFile(name string) ([]byte, error) {
 	return fs.ReadFile(f.fsys, name)
 }
 
+// OSDirFS is implemented by the FS giving the OS folder of their files
+type OSDirFS interface {
+	Dir() string
+}
+
 type NameFS interface {
 	Name() string
 }
@@ -126,6 +126,21 @@ func TestFixedPathAndMagic(t *testing.T) {
 			want:  "",
 			want1: "*.JPG",
 		},
+		{
+			name:  "/mnt/photos/*/file",
+			want:  "/mnt/photos",
+			want1: "*/file",
+		},
+		{
+			name:  "//NAS/photos/2023/*.jpg",
+			want:  "//NAS/photos/2023",
+			want1: "*.jpg",
+		},
+		{
+			name:  "C:/Users/me/Pictures/*.jpg",
+			want:  "C:/Users/me/Pictures",
+			want1: "*.jpg",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,66 @@
+package fshelper
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// OSPath gives the form of the path opened by the OS functions.
+//
+// On Windows, the path is made absolute and given in its extended-length form \\?\,
+// so the files of deep folders, longer than MAX_PATH (260 characters), can be opened,
+// and the UNC paths \\server\share\folder are given in the form \\?\UNC\server\share\folder.
+// The other systems get the path unchanged.
+func OSPath(p string) string {
+	if runtime.GOOS != "windows" {
+		return p
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	return windowsLongPath(p)
+}
+
+// windowsLongPath gives the extended-length form of an absolute Windows path.
+// The relative paths and the paths already in that form are returned unchanged.
+func windowsLongPath(p string) string {
+	p = strings.ReplaceAll(p, "/", `\`)
+	switch {
+	case strings.HasPrefix(p, `\\?\`), strings.HasPrefix(p, `\\.\`):
+		return p
+	case strings.HasPrefix(p, `\\`):
+		// UNC path: \\server\share\folder
+		return `\\?\UNC\` + cleanWindowsPath(p[2:])
+	case len(p) >= 3 && p[1] == ':' && p[2] == '\\' && isLetter(p[0]):
+		return `\\?\` + p[:3] + cleanWindowsPath(p[3:])
+	}
+	return p
+}
+
+// cleanWindowsPath removes the . and .. elements and the duplicated separators,
+// because the extended-length paths are given to the system without being normalized
+func cleanWindowsPath(p string) string {
+	var parts []string
+	for _, e := range strings.Split(p, `\`) {
+		switch e {
+		case "", ".":
+		case "..":
+			if len(parts) > 0 {
+				parts = parts[:len(parts)-1]
+			}
+		default:
+			parts = append(parts, e)
+		}
+	}
+	return strings.Join(parts, `\`)
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// isUNC tells if the slash separated path is a UNC path: //server/share
+func isUNC(p string) bool {
+	return strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "///")
+}
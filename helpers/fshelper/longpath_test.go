@@ -0,0 +1,102 @@
+package fshelper
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWindowsLongPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: `C:\Users\me\Pictures`, want: `\\?\C:\Users\me\Pictures`},
+		{path: `c:/Users/me/Pictures/`, want: `\\?\c:\Users\me\Pictures`},
+		{path: `C:\Users\me\..\you\.\Pictures`, want: `\\?\C:\Users\you\Pictures`},
+		{path: `\\NAS\photos\very\deep\path`, want: `\\?\UNC\NAS\photos\very\deep\path`},
+		{path: `//NAS/photos/2023`, want: `\\?\UNC\NAS\photos\2023`},
+		{path: `\\?\C:\already\long`, want: `\\?\C:\already\long`},
+		{path: `\\?\UNC\NAS\photos`, want: `\\?\UNC\NAS\photos`},
+		{path: `\\.\pipe\name`, want: `\\.\pipe\name`},
+		{path: `relative\folder`, want: `relative\folder`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := windowsLongPath(tt.path); got != tt.want {
+				t.Errorf("windowsLongPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// The names of the files of a fs.FS are separated by slashes on all systems
+func TestGlobWalkFSDeepNames(t *testing.T) {
+	deep := strings.Repeat("very long folder name/", 15) + "photo.jpg"
+	fsys := &GlobWalkFS{
+		rootFS: fstest.MapFS{
+			deep:                   &fstest.MapFile{},
+			"2023/Holidays/a.jpg":  &fstest.MapFile{},
+			"2023/Holidays/a.json": &fstest.MapFile{},
+			"2024/Holidays/b.jpg":  &fstest.MapFile{},
+		},
+		parts: []string{"*", "holidays", "*.jpg"},
+	}
+	got := walk(t, fsys)
+	want := []string{"2023/Holidays/a.jpg", "2024/Holidays/b.jpg"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	fsys.parts = nil
+	got = walk(t, fsys)
+	if len(got) != 4 || got[len(got)-1] != deep {
+		t.Errorf("the deep file should be found, got %v", got)
+	}
+}
+
+// The files of folders longer than MAX_PATH are found
+func TestGlobWalkFSLongPath(t *testing.T) {
+	dir := t.TempDir()
+	deep := dir
+	for len(deep) < 300 {
+		deep = filepath.Join(deep, strings.Repeat("x", 50))
+	}
+	err := os.MkdirAll(OSPath(deep), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(OSPath(filepath.Join(deep, "photo.jpg")), []byte("jpg"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys, err := NewGlobWalkFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := walk(t, fsys)
+	if len(got) != 1 || !strings.HasSuffix(got[0], "/photo.jpg") {
+		t.Errorf("the deep file should be found, got %v", got)
+	}
+}
+
+func walk(t *testing.T, fsys fs.FS) []string {
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
@@ -33,7 +33,7 @@ func ParsePath(args []string) ([]fs.FS, error) {
 			case strings.HasSuffix(lowF, ".tgz") || strings.HasSuffix(lowF, ".tar.gz"):
 				errs = errors.Join(fmt.Errorf("immich-go cant use tgz archives: %s", filepath.Base(a)))
 			case strings.HasSuffix(lowF, ".zip"):
-				fsys, err := zip.OpenReader(f)
+				fsys, err := zip.OpenReader(OSPath(f))
 				if err != nil {
 					errs = errors.Join(errs, fmt.Errorf("%s: %w", a, err))
 					continue
@@ -28,6 +28,7 @@ type dirRemoveFS struct {
 }
 
 func DirRemoveFS(name string) fs.FS {
+	name = OSPath(name)
 	fsys := &dirRemoveFS{
 		FS:  os.DirFS(name),
 		dir: name,
@@ -37,9 +38,14 @@ func DirRemoveFS(name string) fs.FS {
 }
 
 func (fsys dirRemoveFS) Remove(name string) error {
-	return os.Remove(filepath.Join(fsys.dir, name))
+	return os.Remove(fsys.join(name))
 }
 
 func (fsys dirRemoveFS) Stat(name string) (fs.FileInfo, error) {
-	return os.Stat(filepath.Join(fsys.dir, name))
+	return os.Stat(fsys.join(name))
+}
+
+// join gives the OS path of the file, without cleaning the extended-length paths of Windows
+func (fsys dirRemoveFS) join(name string) string {
+	return fsys.dir + string(os.PathSeparator) + filepath.FromSlash(name)
 }
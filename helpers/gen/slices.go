@@ -19,3 +19,16 @@ func Filter[T any](s []T, f func(i T) bool) []T {
 	}
 	return r
 }
+
+// Chunks splits the slice in consecutive slices of size elements at most
+func Chunks[T any](s []T, size int) [][]T {
+	var r [][]T
+	for size > 0 && len(s) > size {
+		r = append(r, s[:size:size])
+		s = s[size:]
+	}
+	if len(s) > 0 {
+		r = append(r, s)
+	}
+	return r
+}
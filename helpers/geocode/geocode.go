@@ -0,0 +1,106 @@
+/*
+Package geocode converts GPS coordinates into place names without relying on the Immich server.
+
+Two sources are available:
+  - a GeoNames cities file (https://download.geonames.org/export/dump/), ex: cities15000.txt, searched offline
+  - a Nominatim server, ideally a local instance
+*/
+package geocode
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Place is the result of the reverse geocoding
+type Place struct {
+	City    string
+	Region  string
+	Country string
+}
+
+// Names gives the non empty names of the place, from the city to the country
+func (p Place) Names() []string {
+	names := []string{}
+	for _, n := range []string{p.City, p.Region, p.Country} {
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+func (p Place) String() string {
+	return strings.Join(p.Names(), ", ")
+}
+
+// IsSet is true when the place has a name
+func (p Place) IsSet() bool {
+	return p.City != "" || p.Region != "" || p.Country != ""
+}
+
+type Geocoder interface {
+	// Reverse gives the place at the coordinates. The returned place is empty when nothing is found
+	Reverse(ctx context.Context, latitude, longitude float64) (Place, error)
+}
+
+// New gives the geocoder for the source: a Nominatim server when it's an URL, a GeoNames cities file otherwise
+func New(source string) (Geocoder, error) {
+	var g Geocoder
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		g = NewNominatim(source)
+	} else {
+		g, err = OpenCitiesFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the cities file: %w", err)
+		}
+	}
+	return newCache(g), nil
+}
+
+// cache keeps the places of coordinates rounded to 3 decimals, about 100m
+type cache struct {
+	g      Geocoder
+	lock   sync.Mutex
+	places map[[2]int64]Place
+}
+
+func newCache(g Geocoder) *cache {
+	return &cache{
+		g:      g,
+		places: map[[2]int64]Place{},
+	}
+}
+
+func (c *cache) Reverse(ctx context.Context, latitude, longitude float64) (Place, error) {
+	key := [2]int64{int64(math.Round(latitude * 1000)), int64(math.Round(longitude * 1000))}
+	c.lock.Lock()
+	p, ok := c.places[key]
+	c.lock.Unlock()
+	if ok {
+		return p, nil
+	}
+	p, err := c.g.Reverse(ctx, latitude, longitude)
+	if err != nil {
+		return p, err
+	}
+	c.lock.Lock()
+	c.places[key] = p
+	c.lock.Unlock()
+	return p, nil
+}
+
+const earthRadius = 6371.0 // km
+
+// distance gives the distance in km between two points
+func distance(lat1, long1, lat2, long2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLong := (long2 - long1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLong/2)*math.Sin(dLong/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,140 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCities(t *testing.T) {
+	g, err := New("TEST_DATA/cities.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		expected  Place
+	}{
+		{name: "Eiffel tower", latitude: 48.8583736, longitude: 2.291901, expected: Place{City: "Paris", Region: "Ile-de-France", Country: "France"}},
+		{name: "Villeurbanne", latitude: 45.7667, longitude: 4.8833, expected: Place{City: "Lyon", Region: "Auvergne-Rhone-Alpes", Country: "France"}},
+		{name: "Greenwich", latitude: 51.4769, longitude: -0.0005, expected: Place{City: "London", Region: "England", Country: "United Kingdom"}},
+		{name: "no country name", latitude: 40.75, longitude: -73.99, expected: Place{City: "New York City", Country: "US"}},
+		{name: "Atlantic ocean", latitude: 40, longitude: -40, expected: Place{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := g.Reverse(context.Background(), tt.latitude, tt.longitude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != tt.expected {
+				t.Errorf("expected %#v, got %#v", tt.expected, p)
+			}
+		})
+	}
+}
+
+func TestNominatim(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/reverse" || r.URL.Query().Get("lat") != "48.8583736" || r.URL.Query().Get("lon") != "2.291901" {
+			w.Write([]byte(`{"error":"Unable to geocode"}`))
+			return
+		}
+		w.Write([]byte(`{"address":{"town":"Paris","state":"Ile-de-France","country":"France"}}`))
+	}))
+	defer srv.Close()
+
+	g, err := New(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		p, err := g.Reverse(context.Background(), 48.8583736, 2.291901)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.String() != "Paris, Ile-de-France, France" {
+			t.Errorf("unexpected place: %s", p)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("the place should be cached, got %d calls", calls)
+	}
+
+	p, err := g.Reverse(context.Background(), 0, 0)
+	if err != nil || p.IsSet() {
+		t.Errorf("an empty place was expected, got %v, %v", p, err)
+	}
+}
+
+func TestNominatimInterval(t *testing.T) {
+	if n := NewNominatim("https://nominatim.openstreetmap.org/"); n.interval != time.Second {
+		t.Errorf("the queries to the public server should be limited to one per second, got %s", n.interval)
+	}
+	if n := NewNominatim("http://localhost:8080"); n.interval != 0 {
+		t.Errorf("the queries to a local server shouldn't be limited, got %s", n.interval)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"address":{"town":"Paris","state":"Ile-de-France","country":"France"}}`))
+	}))
+	defer srv.Close()
+	n := NewNominatim(srv.URL)
+	n.interval = 50 * time.Millisecond
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := n.Reverse(context.Background(), 48.8583736, float64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 2*n.interval {
+		t.Errorf("3 queries should take at least %s, got %s", 2*n.interval, d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n.next = time.Now().Add(time.Hour)
+	if _, err := n.Reverse(ctx, 0, 0); err == nil {
+		t.Error("the wait should end with the context")
+	}
+}
+
+func TestCitiesTimeZone(t *testing.T) {
+	c, err := OpenCitiesFile("TEST_DATA/cities.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		latitude  float64
+		longitude float64
+		expected  string
+	}{
+		{name: "Eiffel tower", latitude: 48.8583736, longitude: 2.291901, expected: "Europe/Paris"},
+		{name: "Greenwich", latitude: 51.4769, longitude: -0.0005, expected: "Europe/London"},
+		{name: "Manhattan", latitude: 40.75, longitude: -73.99, expected: "America/New_York"},
+		{name: "Atlantic ocean", latitude: 40, longitude: -40, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := c.TimeZone(tt.latitude, tt.longitude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if l != nil {
+				got = l.String()
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
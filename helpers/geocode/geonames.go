@@ -0,0 +1,176 @@
+package geocode
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxCityDistance is the distance in km beyond which the nearest city isn't retained
+const MaxCityDistance = 100.0
+
+type city struct {
+	name      string
+	latitude  float64
+	longitude float64
+	country   string // country code
+	admin1    string // region code
+	timeZone  string // IANA time zone name
+}
+
+// Cities is an offline geocoder based on a GeoNames cities file.
+// The cities are indexed by cells of 1 degree.
+type Cities struct {
+	cells     map[[2]int][]city
+	countries map[string]string // country names by code
+	regions   map[string]string // region names by country code.admin1 code
+	zones     map[string]*time.Location
+	zoneLock  sync.Mutex
+}
+
+// OpenCitiesFile reads a GeoNames cities file, ex: cities15000.txt.
+// The country and region names are read from the files countryInfo.txt and admin1CodesASCII.txt
+// in the same folder, when present. Otherwise, the country is given by its ISO code.
+func OpenCitiesFile(name string) (*Cities, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Cities{
+		cells:     map[[2]int][]city{},
+		countries: map[string]string{},
+		regions:   map[string]string{},
+		zones:     map[string]*time.Location{},
+	}
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for s.Scan() {
+		line++
+		fields := strings.Split(s.Text(), "\t")
+		if len(fields) < 11 {
+			continue
+		}
+		ct := city{name: fields[1], country: fields[8], admin1: fields[10]}
+		if len(fields) > 17 {
+			ct.timeZone = fields[17]
+		}
+		ct.latitude, err = strconv.ParseFloat(fields[4], 64)
+		if err == nil {
+			ct.longitude, err = strconv.ParseFloat(fields[5], 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s, line %d: %w", name, line, err)
+		}
+		k := cellKey(ct.latitude, ct.longitude)
+		c.cells[k] = append(c.cells[k], ct)
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(name)
+	err = readCodes(filepath.Join(dir, "countryInfo.txt"), 0, 4, c.countries)
+	if err != nil {
+		return nil, err
+	}
+	err = readCodes(filepath.Join(dir, "admin1CodesASCII.txt"), 0, 1, c.regions)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readCodes reads the names by code of an optional GeoNames file
+func readCodes(name string, code, value int, m map[string]string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "#") {
+			continue
+		}
+		fields := strings.Split(s.Text(), "\t")
+		if len(fields) > max(code, value) {
+			m[fields[code]] = fields[value]
+		}
+	}
+	return s.Err()
+}
+
+func cellKey(latitude, longitude float64) [2]int {
+	return [2]int{int(math.Floor(latitude)), int(math.Floor(longitude))}
+}
+
+// Reverse gives the nearest city of the coordinates, within the MaxCityDistance
+func (c *Cities) Reverse(ctx context.Context, latitude, longitude float64) (Place, error) {
+	nearest := c.nearest(latitude, longitude)
+	if nearest == nil {
+		return Place{}, nil
+	}
+	p := Place{
+		City:    nearest.name,
+		Region:  c.regions[nearest.country+"."+nearest.admin1],
+		Country: nearest.country,
+	}
+	if n, ok := c.countries[nearest.country]; ok {
+		p.Country = n
+	}
+	return p, nil
+}
+
+// TimeZone gives the time zone of the nearest city of the coordinates, within the MaxCityDistance.
+// The location is nil when no city is found.
+func (c *Cities) TimeZone(latitude, longitude float64) (*time.Location, error) {
+	nearest := c.nearest(latitude, longitude)
+	if nearest == nil || nearest.timeZone == "" {
+		return nil, nil
+	}
+	c.zoneLock.Lock()
+	defer c.zoneLock.Unlock()
+	if l, ok := c.zones[nearest.timeZone]; ok {
+		return l, nil
+	}
+	l, err := time.LoadLocation(nearest.timeZone)
+	if err != nil {
+		return nil, err
+	}
+	c.zones[nearest.timeZone] = l
+	return l, nil
+}
+
+// nearest gives the nearest city of the coordinates, within the MaxCityDistance
+func (c *Cities) nearest(latitude, longitude float64) *city {
+	var nearest *city
+	best := MaxCityDistance
+	k := cellKey(latitude, longitude)
+	// a degree of latitude is about 111km, search the neighbor cells
+	for dLat := -1; dLat <= 1; dLat++ {
+		for dLong := -2; dLong <= 2; dLong++ {
+			long := (k[1]+dLong+180+360)%360 - 180
+			for i, ct := range c.cells[[2]int{k[0] + dLat, long}] {
+				d := distance(latitude, longitude, ct.latitude, ct.longitude)
+				if d < best {
+					best = d
+					nearest = &c.cells[[2]int{k[0] + dLat, long}][i]
+				}
+			}
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,121 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// publicNominatim is the host of the public Nominatim server. Its usage policy limits the queries to one per second.
+const publicNominatim = "nominatim.openstreetmap.org"
+
+// Nominatim queries the reverse endpoint of a Nominatim server.
+// Prefer a local instance: the queries to the public server are sent one per second.
+type Nominatim struct {
+	endPoint string
+	client   *http.Client
+	interval time.Duration // minimum delay between two queries, 0 for no limit
+
+	lock sync.Mutex
+	next time.Time // time of the next query
+}
+
+func NewNominatim(endPoint string) *Nominatim {
+	n := &Nominatim{
+		endPoint: strings.TrimSuffix(endPoint, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if u, err := url.Parse(endPoint); err == nil && strings.EqualFold(u.Hostname(), publicNominatim) {
+		n.interval = time.Second
+	}
+	return n
+}
+
+// wait delays the query to respect the interval between queries
+func (n *Nominatim) wait(ctx context.Context) error {
+	if n.interval == 0 {
+		return nil
+	}
+	n.lock.Lock()
+	now := time.Now()
+	at := n.next
+	if at.Before(now) {
+		at = now
+	}
+	n.next = at.Add(n.interval)
+	n.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+		return nil
+	}
+}
+
+type nominatimResponse struct {
+	Error   string `json:"error"`
+	Address struct {
+		City         string `json:"city"`
+		Town         string `json:"town"`
+		Village      string `json:"village"`
+		Municipality string `json:"municipality"`
+		State        string `json:"state"`
+		Country      string `json:"country"`
+	} `json:"address"`
+}
+
+func (n *Nominatim) Reverse(ctx context.Context, latitude, longitude float64) (Place, error) {
+	err := n.wait(ctx)
+	if err != nil {
+		return Place{}, err
+	}
+	q := url.Values{}
+	q.Set("format", "jsonv2")
+	q.Set("lat", strconv.FormatFloat(latitude, 'f', -1, 64))
+	q.Set("lon", strconv.FormatFloat(longitude, 'f', -1, 64))
+	q.Set("zoom", "10")
+	q.Set("addressdetails", "1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.endPoint+"/reverse?"+q.Encode(), nil)
+	if err != nil {
+		return Place{}, err
+	}
+	req.Header.Set("User-Agent", "immich-go")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return Place{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Place{}, fmt.Errorf("nominatim: %s", resp.Status)
+	}
+
+	var r nominatimResponse
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return Place{}, fmt.Errorf("nominatim: %w", err)
+	}
+	if r.Error != "" {
+		// nothing at this location, like in the middle of the ocean
+		return Place{}, nil
+	}
+	p := Place{
+		Region:  r.Address.State,
+		Country: r.Address.Country,
+	}
+	for _, c := range []string{r.Address.City, r.Address.Town, r.Address.Village, r.Address.Municipality} {
+		if c != "" {
+			p.City = c
+			break
+		}
+	}
+	return p, nil
+}
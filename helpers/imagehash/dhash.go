@@ -0,0 +1,52 @@
+/*
+Package imagehash computes perceptual hashes of images: visually identical images get close hashes,
+whatever their encoding, their compression or their resolution.
+*/
+package imagehash
+
+import (
+	"image"
+	"math/bits"
+)
+
+// Hash is the 64 bits difference hash (dHash) of an image
+type Hash uint64
+
+// DHash computes the difference hash of the image: the image is reduced to 9x8 gray pixels,
+// and each bit tells if a pixel is brighter than its right neighbor.
+func DHash(img image.Image) Hash {
+	const w, h = 9, 8
+	var gray [h][w]float64
+	var count [h][w]int
+
+	b := img.Bounds()
+	if b.Empty() {
+		return 0
+	}
+	// average the pixels of each cell of the reduced image
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		cy := (y - b.Min.Y) * h / b.Dy()
+		for x := b.Min.X; x < b.Max.X; x++ {
+			cx := (x - b.Min.X) * w / b.Dx()
+			r, g, bl, _ := img.At(x, y).RGBA()
+			gray[cy][cx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			count[cy][cx]++
+		}
+	}
+
+	var hash Hash
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x]*float64(count[y][x+1]) > gray[y][x+1]*float64(count[y][x]) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Distance gives the number of different bits of the two hashes, 0 for identical images
+func Distance(a, b Hash) int {
+	return bits.OnesCount64(uint64(a ^ b))
+}
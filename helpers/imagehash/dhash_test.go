@@ -0,0 +1,56 @@
+package imagehash
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// gradient draws a picture of the given size, shifted by the offset
+func gradient(w, h int, offset int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*7*256/w + y*3*256/h + offset) % 256)
+			img.Set(x, y, color.RGBA{v, 255 - v, v / 2, 255})
+		}
+	}
+	return img
+}
+
+func recompress(t *testing.T, img image.Image, quality int) image.Image {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := jpeg.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestDHash(t *testing.T) {
+	original := gradient(800, 600, 0)
+	tests := []struct {
+		name    string
+		img     image.Image
+		similar bool
+	}{
+		{name: "same", img: original, similar: true},
+		{name: "recompressed", img: recompress(t, original, 30), similar: true},
+		{name: "resized", img: gradient(400, 300, 0), similar: true},
+		{name: "other", img: gradient(800, 600, 128), similar: false},
+	}
+	h := DHash(original)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Distance(h, DHash(tt.img))
+			if (d <= 4) != tt.similar {
+				t.Errorf("distance %d, similar %v", d, tt.similar)
+			}
+		})
+	}
+}
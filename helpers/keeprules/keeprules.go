@@ -0,0 +1,188 @@
+/*
+Package keeprules chooses the copy of an image to keep among duplicates, with an ordered list of rules given by the user.
+*/
+package keeprules
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/namematcher"
+)
+
+// Candidate is a copy of an image, described by the values used by the rules
+type Candidate struct {
+	Name       string    // file name
+	Size       int64     // file size
+	Width      int       // 0 when unknown
+	Height     int       // 0 when unknown
+	UploadedAt time.Time // zero when unknown or not yet uploaded
+	Albums     int       // number of albums of the copy, -1 when unknown
+}
+
+// rule compares two candidates, and gives a positive value when a must be kept rather than b, 0 when the rule can't decide
+type rule struct {
+	name    string
+	compare func(a, b Candidate) int
+}
+
+// Rules is an ordered list of rules: the first rule able to decide between two copies wins
+type Rules struct {
+	rules  []rule
+	albums bool
+}
+
+// Names of the rules
+const (
+	RuleRaw        = "raw"        // prefer the RAW files to the JPEG ones
+	RuleSize       = "size"       // prefer the bigger file
+	RuleResolution = "resolution" // prefer the higher resolution
+	RuleEarlier    = "earlier"    // prefer the copy uploaded first
+	RuleAlbums     = "albums"     // prefer the copy belonging to more albums
+	RuleName       = "name"       // name:PATTERN, prefer the copy whose name matches the pattern
+)
+
+var rawExtensions = []string{
+	".3fr", ".ari", ".arw", ".cap", ".cin", ".cr2", ".cr3", ".crw", ".dcr", ".dng", ".erf", ".fff", ".iiq", ".k25", ".kdc",
+	".mrw", ".nef", ".nrw", ".orf", ".ori", ".pef", ".raf", ".raw", ".rw2", ".rwl", ".sr2", ".srf", ".srw", ".x3f",
+}
+
+// Parse reads the comma separated list of rules, ex: raw,resolution,name:*_edited*,size
+func Parse(s string) (Rules, error) {
+	var r Rules
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		key, pattern, hasPattern := strings.Cut(name, ":")
+		key = strings.ToLower(key)
+		switch key {
+		case RuleRaw:
+			r.rules = append(r.rules, rule{name: key, compare: func(a, b Candidate) int {
+				return compareBool(isRaw(a.Name), isRaw(b.Name))
+			}})
+		case RuleSize:
+			r.rules = append(r.rules, rule{name: key, compare: func(a, b Candidate) int {
+				return compareInt(a.Size, b.Size)
+			}})
+		case RuleResolution:
+			r.rules = append(r.rules, rule{name: key, compare: func(a, b Candidate) int {
+				if a.Width == 0 || b.Width == 0 {
+					return 0
+				}
+				return compareInt(int64(a.Width*a.Height), int64(b.Width*b.Height))
+			}})
+		case RuleEarlier:
+			r.rules = append(r.rules, rule{name: key, compare: func(a, b Candidate) int {
+				switch {
+				case a.UploadedAt.IsZero() && b.UploadedAt.IsZero():
+					return 0
+				case b.UploadedAt.IsZero():
+					return 1
+				case a.UploadedAt.IsZero():
+					return -1
+				}
+				return b.UploadedAt.Compare(a.UploadedAt)
+			}})
+		case RuleAlbums:
+			r.albums = true
+			r.rules = append(r.rules, rule{name: key, compare: func(a, b Candidate) int {
+				if a.Albums < 0 || b.Albums < 0 {
+					return 0
+				}
+				return compareInt(int64(a.Albums), int64(b.Albums))
+			}})
+		case RuleName:
+			if !hasPattern || pattern == "" {
+				return Rules{}, fmt.Errorf("the keep rule name needs a pattern: name:PATTERN")
+			}
+			m, err := namematcher.New(pattern)
+			if err != nil {
+				return Rules{}, fmt.Errorf("the keep rule %q: %w", name, err)
+			}
+			r.rules = append(r.rules, rule{name: name, compare: func(a, b Candidate) int {
+				return compareBool(m.Match(a.Name), m.Match(b.Name))
+			}})
+		default:
+			return Rules{}, fmt.Errorf("unknown keep rule %q, the rules are %s, %s, %s, %s, %s and %s:PATTERN", name, RuleRaw, RuleSize, RuleResolution, RuleEarlier, RuleAlbums, RuleName)
+		}
+	}
+	return r, nil
+}
+
+// IsSet tells if the list has rules
+func (r Rules) IsSet() bool { return len(r.rules) > 0 }
+
+// NeedAlbums tells if the rules compare the albums of the candidates
+func (r Rules) NeedAlbums() bool { return r.albums }
+
+func (r Rules) String() string {
+	names := make([]string, len(r.rules))
+	for i, rl := range r.rules {
+		names[i] = rl.name
+	}
+	return strings.Join(names, ",")
+}
+
+// Compare gives a positive value when a must be kept rather than b, a negative value when b must be kept,
+// and the name of the deciding rule. It gives 0 and an empty name when no rule decides.
+func (r Rules) Compare(a, b Candidate) (int, string) {
+	for _, rl := range r.rules {
+		if c := rl.compare(a, b); c != 0 {
+			return c, rl.name
+		}
+	}
+	return 0, ""
+}
+
+// Best gives the index of the candidate to keep, and the rule that has decided.
+// The candidate def is kept when no rule prefers another one.
+func (r Rules) Best(candidates []Candidate, def int) (int, string) {
+	best, reason := def, ""
+	for i := range candidates {
+		if i == best {
+			continue
+		}
+		if c, name := r.Compare(candidates[i], candidates[best]); c > 0 {
+			best, reason = i, name
+		}
+	}
+	if best == def {
+		// the reason is the rule preferring the default candidate to another one
+		for i := range candidates {
+			if c, name := r.Compare(candidates[def], candidates[i]); i != def && c > 0 {
+				reason = name
+				break
+			}
+		}
+	}
+	return best, reason
+}
+
+func isRaw(name string) bool {
+	return slices.Contains(rawExtensions, strings.ToLower(path.Ext(name)))
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a && !b:
+		return 1
+	case !a && b:
+		return -1
+	}
+	return 0
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package keeprules
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rules string
+		want  string
+		err   bool
+	}{
+		{rules: "raw,size", want: "raw,size"},
+		{rules: " RAW , Resolution,earlier,albums", want: "raw,resolution,earlier,albums"},
+		{rules: "name:*_edited*,size", want: "name:*_edited*,size"},
+		{rules: "name", err: true},
+		{rules: "biggest", err: true},
+		{rules: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rules, func(t *testing.T) {
+			r, err := Parse(tt.rules)
+			if (err != nil) != tt.err {
+				t.Fatalf("error %v, want error %v", err, tt.err)
+			}
+			if err == nil && r.String() != tt.want {
+				t.Errorf("got %q, want %q", r.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBest(t *testing.T) {
+	t0 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	candidates := []Candidate{
+		{Name: "IMG_0001.JPG", Size: 3000, Width: 4000, Height: 3000, UploadedAt: t0.Add(time.Hour), Albums: 2},
+		{Name: "IMG_0001.DNG", Size: 2000, Width: 4000, Height: 3000, UploadedAt: t0.Add(2 * time.Hour), Albums: 0},
+		{Name: "IMG_0001_edited.jpg", Size: 1000, Width: 2000, Height: 1500, UploadedAt: t0, Albums: 1},
+	}
+	tests := []struct {
+		rules  string
+		best   int
+		reason string
+	}{
+		{rules: "raw,size", best: 1, reason: "raw"},
+		{rules: "size", best: 0, reason: "size"},
+		{rules: "resolution,earlier", best: 0, reason: "earlier"},
+		{rules: "earlier", best: 2, reason: "earlier"},
+		{rules: "albums", best: 0, reason: "albums"},
+		{rules: "name:*_edited*,raw", best: 2, reason: "name:*_edited*"},
+		{rules: "", best: 1, reason: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rules, func(t *testing.T) {
+			r, err := Parse(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			best, reason := r.Best(candidates, 1)
+			if best != tt.best || reason != tt.reason {
+				t.Errorf("got %d %q, want %d %q", best, reason, tt.best, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+var errBusy = syscall.EWOULDBLOCK
+
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if !errors.Is(err, syscall.EINTR) {
+			return err
+		}
+	}
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// release removes the file while it is locked: the next owner opens a new file
+func release(f *os.File, name string) error {
+	err := os.Remove(name)
+	return errors.Join(err, unlockFile(f), f.Close())
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package lockfile
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var errBusy = windows.ERROR_LOCK_VIOLATION
+
+// lockFile locks a byte far after the PID, so the other processes can read it
+func lockFile(f *os.File) error {
+	ol := windows.Overlapped{OffsetHigh: 1}
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+}
+
+func unlockFile(f *os.File) error {
+	ol := windows.Overlapped{OffsetHigh: 1}
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &ol)
+}
+
+// release unlocks and closes the file before removing it, windows doesn't remove open files.
+// The removal fails when the next owner has already opened the file, it is left to it.
+func release(f *os.File, name string) error {
+	err := errors.Join(unlockFile(f), f.Close())
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, windows.ERROR_SHARING_VIOLATION) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+Package lockfile prevents several immich-go processes from running the same job at the same time.
+
+The lock is a lock of the operating system on the lock file. It is released by the system when its owner ends,
+so the lock left by a crashed process doesn't block the next runs.
+The file contains the PID of its owner, given in the error message.
+*/
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrLocked = errors.New("locked by another process")
+
+type Lock struct {
+	name string
+	f    *os.File
+}
+
+// Acquire locks the file, it is created when missing.
+// It returns an error wrapping ErrLocked when the file is locked by another process, or by this one.
+func Acquire(name string) (*Lock, error) {
+	err := os.MkdirAll(filepath.Dir(name), 0o700)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		err = lockFile(f)
+		if err != nil {
+			f.Close()
+			if !errors.Is(err, errBusy) {
+				return nil, err
+			}
+			if pid, err := readPID(name); err == nil {
+				return nil, fmt.Errorf("%s: %w (pid %d)", name, ErrLocked, pid)
+			}
+			return nil, fmt.Errorf("%s: %w", name, ErrLocked)
+		}
+
+		// the previous owner may have removed the file between its opening and its locking
+		same, err := isCurrentFile(f, name)
+		if err != nil {
+			_ = unlockFile(f)
+			f.Close()
+			return nil, err
+		}
+		if !same {
+			_ = unlockFile(f)
+			f.Close()
+			continue
+		}
+
+		err = f.Truncate(0)
+		if err == nil {
+			_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+		}
+		if err != nil {
+			_ = unlockFile(f)
+			f.Close()
+			return nil, err
+		}
+		return &Lock{name: name, f: f}, nil
+	}
+}
+
+// Release removes the lock file and unlocks it
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	return release(l.f, l.name)
+}
+
+func isCurrentFile(f *os.File, name string) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	ni, err := os.Stat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(fi, ni), nil
+}
+
+func readPID(name string) (int, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
@@ -0,0 +1,68 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sub", "test.lock")
+
+	l, err := Acquire(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil || strings.TrimSpace(string(b)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("the lock file should contain the PID, got %q, %v", b, err)
+	}
+
+	// the lock is held, even by this process
+	_, err = Acquire(name)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expecting ErrLocked, got %v", err)
+	}
+
+	err = l.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(name); err == nil {
+		t.Errorf("the lock file should be removed")
+	}
+	l, err = Acquire(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Release()
+}
+
+func TestAcquireLeftFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "crashed owner", content: "999999999\n"},
+		{name: "empty file", content: ""},
+		{name: "running process", content: strconv.Itoa(os.Getppid())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a file left without lock doesn't block the next runs
+			name := filepath.Join(t.TempDir(), "test.lock")
+			err := os.WriteFile(name, []byte(tt.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := Acquire(name)
+			if err != nil {
+				t.Fatalf("a file left without lock should be taken over: %s", err)
+			}
+			_ = l.Release()
+		})
+	}
+}
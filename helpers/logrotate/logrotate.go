@@ -0,0 +1,164 @@
+/*
+Package logrotate writes the log into a file that is rotated when it becomes too big or too old.
+
+The rotated files are renamed with the time of the rotation, ex: immich-go.2024-06-01_10-20-30.000.log,
+and the oldest ones are removed to keep only the configured number of files.
+*/
+package logrotate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const timeFormat = "2006-01-02_15-04-05.000"
+
+// Options of the rotation. A zero value disables the matching rule.
+type Options struct {
+	MaxSize  int64         // size in bytes triggering the rotation
+	MaxAge   time.Duration // age of the file triggering the rotation
+	MaxFiles int           // number of rotated files kept beside the current one
+}
+
+// Writer is a log file rotated according the options
+type Writer struct {
+	name    string
+	options Options
+	now     func() time.Time
+
+	lock   sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+// Open opens the log file in append mode
+func Open(name string, options Options) (*Writer, error) {
+	w := &Writer{
+		name:    name,
+		options: options,
+		now:     time.Now,
+	}
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o664)
+	if err != nil {
+		return err
+	}
+	s, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f = f
+	w.size = s.Size()
+	w.opened = w.now()
+	return nil
+}
+
+// Write writes p into the file, after a rotation when the file is too big or too old
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && w.needRotation(int64(len(p))) {
+		err := w.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *Writer) needRotation(n int64) bool {
+	if w.options.MaxSize > 0 && w.size+n > w.options.MaxSize {
+		return true
+	}
+	return w.options.MaxAge > 0 && w.now().Sub(w.opened) >= w.options.MaxAge
+}
+
+// rotate renames the current file, removes the oldest ones and opens a new file
+func (w *Writer) rotate() error {
+	err := w.f.Close()
+	w.f = nil
+	if err != nil {
+		return err
+	}
+	ext := filepath.Ext(w.name)
+	rotated := strings.TrimSuffix(w.name, ext) + "." + w.now().Format(timeFormat) + ext
+	err = os.Rename(w.name, rotated)
+	if err != nil {
+		return err
+	}
+	return errors.Join(w.removeOldest(), w.open())
+}
+
+// removeOldest keeps only the MaxFiles most recent rotated files
+func (w *Writer) removeOldest() error {
+	if w.options.MaxFiles <= 0 {
+		return nil
+	}
+	rotated, err := w.Rotated()
+	if err != nil {
+		return err
+	}
+	var errs error
+	for len(rotated) > w.options.MaxFiles {
+		errs = errors.Join(errs, os.Remove(rotated[0]))
+		rotated = rotated[1:]
+	}
+	return errs
+}
+
+// Rotated gives the rotated files, the oldest first
+func (w *Writer) Rotated() ([]string, error) {
+	dir := filepath.Dir(w.name)
+	ext := filepath.Ext(w.name)
+	prefix := strings.TrimSuffix(filepath.Base(w.name), ext) + "."
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() || !strings.HasPrefix(n, prefix) || !strings.HasSuffix(n, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(n, prefix), ext)
+		if _, err := time.Parse(timeFormat, stamp); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(dir, n))
+	}
+	// the time stamps are sortable
+	sort.Strings(names)
+	return names, nil
+}
+
+// Close closes the current file
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
@@ -0,0 +1,90 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// clock gives a time advancing by one second at each call
+func clock() func() time.Time {
+	t := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+}
+
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		writes  int
+		rotated int
+	}{
+		{name: "no rotation", options: Options{}, writes: 10, rotated: 0},
+		{name: "size", options: Options{MaxSize: 40}, writes: 10, rotated: 4},
+		{name: "size, max files", options: Options{MaxSize: 40, MaxFiles: 2}, writes: 10, rotated: 2},
+		{name: "age", options: Options{MaxAge: 3 * time.Second}, writes: 10, rotated: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "immich-go.log")
+			w, err := Open(name, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.now = clock()
+			w.opened = w.now()
+			for i := 0; i < tt.writes; i++ {
+				_, err = w.Write([]byte("a line of the log\n")) // 18 bytes
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = w.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			rotated, err := w.Rotated()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rotated) != tt.rotated {
+				t.Errorf("expected %d rotated files, got %v", tt.rotated, rotated)
+			}
+			if _, err := os.Stat(name); err != nil {
+				t.Errorf("the current log file is missing: %s", err)
+			}
+		})
+	}
+}
+
+func TestRotatedKeepsNewest(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "immich-go.log")
+	w, err := Open(name, Options{MaxSize: 1, MaxFiles: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = clock()
+	for _, l := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(l)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = w.Close()
+
+	rotated, _ := w.Rotated()
+	if len(rotated) != 1 {
+		t.Fatalf("expected 1 rotated file, got %v", rotated)
+	}
+	b, _ := os.ReadFile(rotated[0])
+	if string(b) != "second\n" {
+		t.Errorf("the newest rotated file should be kept, got %q", b)
+	}
+	b, _ = os.ReadFile(name)
+	if string(b) != "third\n" {
+		t.Errorf("unexpected current file: %q", b)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Package membudget bounds the memory used by immich-go on large sources.
+
+The budget is enforced by backpressure: the stages reading ahead of the upload
+check the budget and slow down while the memory in use exceeds it. The Go
+runtime is also asked to collect the garbage more often when approaching the limit.
+*/
+package membudget
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readAheadShare is the part of the budget the read-ahead may use, the rest is left
+// to the catalogs, the garbage, and the memory not managed by the GC
+const readAheadShare = 0.75
+
+// poll is the delay between two readings of the memory in use while waiting
+const poll = 50 * time.Millisecond
+
+// Budget is the memory allowed to the process. A nil budget has no limit.
+type Budget struct {
+	limit int64
+	inUse func() int64 // memory in use, replaced by the tests
+	poll  time.Duration
+}
+
+// New gives a budget of limit bytes, nil when limit is 0
+func New(limit int64) *Budget {
+	if limit <= 0 {
+		return nil
+	}
+	return &Budget{
+		limit: limit,
+		inUse: liveHeap,
+		poll:  poll,
+	}
+}
+
+// Limit gives the budget in bytes, 0 for no limit
+func (b *Budget) Limit() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.limit
+}
+
+// Apply sets the soft memory limit of the Go runtime to the budget.
+// The returned function restores the previous limit.
+func (b *Budget) Apply() func() {
+	if b == nil {
+		return func() {}
+	}
+	previous := debug.SetMemoryLimit(b.limit)
+	return func() { debug.SetMemoryLimit(previous) }
+}
+
+// Over tells if the memory in use exceeds the part of the budget given to the read-ahead
+func (b *Budget) Over() bool {
+	if b == nil {
+		return false
+	}
+	return b.inUse() > int64(float64(b.limit)*readAheadShare)
+}
+
+// Wait blocks while the memory in use exceeds the budget and busy reports
+// that the next stages still hold items that will be released.
+// When nothing is pending downstream, waiting would never end: Wait returns and
+// the caller goes on one item at a time.
+func (b *Budget) Wait(ctx context.Context, busy func() bool) error {
+	if b == nil {
+		return nil
+	}
+	collected := false
+	for b.Over() && busy() {
+		if !collected {
+			// the garbage may be enough to get back under the budget
+			runtime.GC()
+			collected = true
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.poll):
+		}
+	}
+	return ctx.Err()
+}
+
+// liveHeap gives the size of the objects alive at the last garbage collection
+func liveHeap() int64 {
+	s := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return int64(m.HeapAlloc)
+	}
+	return int64(s[0].Value.Uint64())
+}
+
+// ParseSize reads a size in bytes with an optional unit: 512M, 2G, 1.5GB, 300KiB
+// The units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "B"), "I")
+	mul := 1.0
+	if v != "" {
+		switch v[len(v)-1] {
+		case 'K':
+			mul = 1 << 10
+		case 'M':
+			mul = 1 << 20
+		case 'G':
+			mul = 1 << 30
+		case 'T':
+			mul = 1 << 40
+		}
+		if mul > 1 {
+			v = v[:len(v)-1]
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || math.IsNaN(f) || f < 0 || math.IsInf(f*mul, 0) || f*mul >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(f * mul), nil
+}
@@ -0,0 +1,97 @@
+package membudget
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{s: "1024", want: 1024},
+		{s: "100B", want: 100},
+		{s: "300K", want: 300 << 10},
+		{s: "300KiB", want: 300 << 10},
+		{s: "512m", want: 512 << 20},
+		{s: "2G", want: 2 << 30},
+		{s: "1.5GB", want: 3 << 29},
+		{s: "1T", want: 1 << 40},
+		{s: "", wantErr: true},
+		{s: "G", wantErr: true},
+		{s: "-1G", wantErr: true},
+		{s: "two", wantErr: true},
+		{s: "NaN", wantErr: true},
+		{s: "1e30T", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseSize(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilBudget(t *testing.T) {
+	var b *Budget = New(0)
+	if b != nil {
+		t.Fatal("expected no budget")
+	}
+	if b.Over() {
+		t.Error("a nil budget is never over")
+	}
+	if err := b.Wait(context.Background(), func() bool { return true }); err != nil {
+		t.Error(err)
+	}
+	b.Apply()()
+}
+
+func TestWait(t *testing.T) {
+	inUse := int64(900)
+	b := &Budget{limit: 1000, inUse: func() int64 { return inUse }, poll: time.Millisecond}
+
+	if !b.Over() {
+		t.Fatal("900 bytes must exceed the read-ahead share of 1000")
+	}
+
+	// the wait ends when the next stages have released their items
+	pending := 3
+	err := b.Wait(context.Background(), func() bool {
+		pending--
+		return pending > 0
+	})
+	if err != nil || pending != 0 {
+		t.Errorf("Wait() = %v, pending = %d", err, pending)
+	}
+
+	// nothing pending downstream: no wait
+	err = b.Wait(context.Background(), func() bool { return false })
+	if err != nil {
+		t.Error(err)
+	}
+
+	// under the budget: no wait
+	inUse = 100
+	err = b.Wait(context.Background(), func() bool { t.Error("busy called under the budget"); return true })
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the wait is interrupted by the context
+	inUse = 2000
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = b.Wait(ctx, func() bool { return true })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() = %v, want a deadline error", err)
+	}
+}
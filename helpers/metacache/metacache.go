@@ -0,0 +1,205 @@
+// Package metacache keeps the metadata extracted from the files, and their checksum, between the runs.
+//
+// The entries are keyed by the file's path, size and modification time:
+// a file modified since the last run is read again.
+package metacache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/helpers/fshelper"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// entry is a line of the cache file
+type entry struct {
+	Path     string            `json:"path"`
+	Size     int64             `json:"size"`
+	ModTime  time.Time         `json:"modTime"`
+	Metadata metadata.Metadata `json:"metadata"`
+	Missing  bool              `json:"missing,omitempty"`  // the file has no readable metadata
+	Unread   bool              `json:"unread,omitempty"`   // the metadata haven't been read, only the checksum is known
+	Checksum string            `json:"checksum,omitempty"` // SHA1 of the file, base64 encoded
+}
+
+// Cache is a metadata cache backed by a JSON lines file
+type Cache struct {
+	file    string
+	lock    sync.Mutex
+	entries map[string]entry
+	dirty   bool
+	hits    int
+}
+
+// Open loads the cache file. A missing file gives an empty cache.
+func Open(file string) (*Cache, error) {
+	c := &Cache{
+		file:    file,
+		entries: map[string]entry{},
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var e entry
+		err = dec.Decode(&e)
+		if err != nil {
+			break
+		}
+		c.entries[e.Path] = e
+	}
+	if !errors.Is(err, io.EOF) {
+		// a damaged cache is rebuilt
+		c.entries = map[string]entry{}
+		c.dirty = true
+	}
+	return c, nil
+}
+
+// Key gives the key of the file in the cache, or "" when the file can't be identified across runs
+func Key(fsys fs.FS, name string) string {
+	switch fsys := fsys.(type) {
+	case fshelper.OSDirFS:
+		p, err := filepath.Abs(filepath.Join(fsys.Dir(), filepath.FromSlash(name)))
+		if err != nil {
+			return ""
+		}
+		return filepath.ToSlash(p)
+	case fshelper.NameFS:
+		// a file in an archive
+		return path.Join(fsys.Name()+":", name)
+	}
+	return ""
+}
+
+// Get gives the metadata of the file when the cache has them for this very version of the file.
+// found is false when the file has no readable metadata.
+func (c *Cache) Get(key string, size int64, modTime time.Time) (md metadata.Metadata, found bool, ok bool) {
+	if c == nil || key == "" {
+		return md, false, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.Unread || e.Size != size || !e.ModTime.Equal(modTime) {
+		return md, false, false
+	}
+	c.hits++
+	return e.Metadata, !e.Missing, true
+}
+
+// Put records the metadata of the file, found is false when the file has no readable metadata
+func (c *Cache) Put(key string, size int64, modTime time.Time, md metadata.Metadata, found bool) {
+	if c == nil || key == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e := entry{Path: key, Size: size, ModTime: modTime, Metadata: md, Missing: !found}
+	if old, ok := c.entries[key]; ok && old.Size == size && old.ModTime.Equal(modTime) {
+		e.Checksum = old.Checksum
+	}
+	c.entries[key] = e
+	c.dirty = true
+}
+
+// GetChecksum gives the checksum of the file when the cache has it for this very version of the file
+func (c *Cache) GetChecksum(key string, size int64, modTime time.Time) (string, bool) {
+	if c == nil || key == "" {
+		return "", false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.Checksum == "" || e.Size != size || !e.ModTime.Equal(modTime) {
+		return "", false
+	}
+	return e.Checksum, true
+}
+
+// PutChecksum records the checksum of the file, the metadata already known for this version of the file are kept
+func (c *Cache) PutChecksum(key string, size int64, modTime time.Time, checksum string) {
+	if c == nil || key == "" || checksum == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.Size != size || !e.ModTime.Equal(modTime) {
+		e = entry{Path: key, Size: size, ModTime: modTime, Unread: true}
+	}
+	if e.Checksum == checksum {
+		return
+	}
+	e.Checksum = checksum
+	c.entries[key] = e
+	c.dirty = true
+}
+
+// Hits gives the number of files whose metadata have been found in the cache
+func (c *Cache) Hits() int {
+	if c == nil {
+		return 0
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.hits
+}
+
+// Close writes the cache file when it has changed
+func (c *Cache) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(c.file), 0o700)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.file), filepath.Base(c.file)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range c.entries {
+		err = enc.Encode(e)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	err = errors.Join(err, tmp.Close())
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	err = os.Rename(tmp.Name(), c.file)
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,159 @@
+package metacache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestCache(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache", "metadata.jsonl")
+	mtime := time.Date(2023, 10, 6, 8, 30, 0, 0, time.UTC)
+	md := metadata.Metadata{DateTaken: mtime.Add(-time.Hour), Latitude: 48.8577, Model: "Pixel 5"}
+
+	c, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := c.Get("/photos/a.jpg", 10, mtime); ok {
+		t.Fatal("the cache should be empty")
+	}
+	c.Put("/photos/a.jpg", 10, mtime, md, true)
+	c.Put("/photos/b.png", 20, mtime, metadata.Metadata{}, false)
+	err = c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		key   string
+		size  int64
+		mtime time.Time
+		ok    bool
+		found bool
+	}{
+		{name: "same file", key: "/photos/a.jpg", size: 10, mtime: mtime, ok: true, found: true},
+		{name: "no metadata", key: "/photos/b.png", size: 20, mtime: mtime, ok: true},
+		{name: "size changed", key: "/photos/a.jpg", size: 11, mtime: mtime},
+		{name: "file modified", key: "/photos/a.jpg", size: 10, mtime: mtime.Add(time.Second)},
+		{name: "unknown file", key: "/photos/c.jpg", size: 10, mtime: mtime},
+		{name: "no key", key: "", size: 10, mtime: mtime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, ok := c.Get(tt.key, tt.size, tt.mtime)
+			if ok != tt.ok || found != tt.found {
+				t.Fatalf("Get() = found %v, ok %v, want found %v, ok %v", found, ok, tt.found, tt.ok)
+			}
+			if found && (!got.DateTaken.Equal(md.DateTaken) || got.Latitude != md.Latitude || got.Model != md.Model) {
+				t.Errorf("Get() = %+v, want %+v", got, md)
+			}
+		})
+	}
+	if c.Hits() != 2 {
+		t.Errorf("Hits() = %d, want 2", c.Hits())
+	}
+}
+
+func TestDamagedCache(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "metadata.jsonl")
+	err := os.WriteFile(file, []byte(`{"path":"/a.jpg","size":1}`+"\n{not json"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := c.Get("/a.jpg", 1, time.Time{}); ok {
+		t.Errorf("a damaged cache should be emptied")
+	}
+}
+
+type dirFS struct {
+	fstest.MapFS
+	dir string
+}
+
+func (d dirFS) Dir() string { return d.dir }
+
+func TestKey(t *testing.T) {
+	if got := Key(dirFS{dir: "/photos"}, "2023/a.jpg"); got != "/photos/2023/a.jpg" {
+		t.Errorf("Key() = %q", got)
+	}
+	if got := Key(fstest.MapFS{}, "a.jpg"); got != "" {
+		t.Errorf("Key() = %q, want no key", got)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "metadata.jsonl")
+	mtime := time.Date(2023, 10, 6, 8, 30, 0, 0, time.UTC)
+	md := metadata.Metadata{DateTaken: mtime.Add(-time.Hour)}
+
+	c, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a checksum without metadata doesn't give the metadata
+	c.PutChecksum("/photos/a.jpg", 10, mtime, "sha1-a")
+	if _, _, ok := c.Get("/photos/a.jpg", 10, mtime); ok {
+		t.Error("the metadata of a.jpg haven't been read")
+	}
+	// the metadata and the checksum are kept together
+	c.Put("/photos/a.jpg", 10, mtime, md, true)
+	c.Put("/photos/b.jpg", 20, mtime, md, true)
+	c.PutChecksum("/photos/b.jpg", 20, mtime, "sha1-b")
+	err = c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"/photos/a.jpg", "/photos/b.jpg"} {
+		if _, found, ok := c.Get(key, map[string]int64{"/photos/a.jpg": 10, "/photos/b.jpg": 20}[key], mtime); !ok || !found {
+			t.Errorf("%s: the metadata are lost", key)
+		}
+	}
+	if sum, ok := c.GetChecksum("/photos/a.jpg", 10, mtime); !ok || sum != "sha1-a" {
+		t.Errorf("GetChecksum(a.jpg) = %q, %v", sum, ok)
+	}
+	if sum, ok := c.GetChecksum("/photos/b.jpg", 20, mtime); !ok || sum != "sha1-b" {
+		t.Errorf("GetChecksum(b.jpg) = %q, %v", sum, ok)
+	}
+	// the checksum of a modified file is unknown
+	if _, ok := c.GetChecksum("/photos/b.jpg", 20, mtime.Add(time.Second)); ok {
+		t.Error("the file b.jpg is modified")
+	}
+	c.Put("/photos/b.jpg", 21, mtime, md, true)
+	if _, ok := c.GetChecksum("/photos/b.jpg", 21, mtime); ok {
+		t.Error("the checksum of the previous version of b.jpg is kept")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Put("/a.jpg", 1, time.Time{}, metadata.Metadata{}, true)
+	if _, _, ok := c.Get("/a.jpg", 1, time.Time{}); ok {
+		t.Errorf("a nil cache has no entry")
+	}
+	c.PutChecksum("/a.jpg", 1, time.Time{}, "sha1")
+	if _, ok := c.GetChecksum("/a.jpg", 1, time.Time{}); ok {
+		t.Errorf("a nil cache has no checksum")
+	}
+	if err := c.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -11,12 +11,34 @@ import (
 
 // List of file patterns used to ban unwanted files
 // Pattern can be a part of the path, a file name..
+//
+// A pattern can be:
+//   - a glob. The '*' matches any character but the '.' and the '/',
+//     '**' matches any characters, including the '/', '?' matches one character
+//     ex: SYNOFILE_THUMB_*.*, backup/**/*.tmp
+//   - a regular expression when prefixed with 're:'
+//     ex: re:_\d{3}\.jpg$
+//   - a negation when prefixed with '!': the names matching the pattern are re-included.
+//     The last pattern matching the name wins, ex: thumbnails/ then !thumbnails/keep/
+//     Use '\!' for a pattern starting with an exclamation mark.
+//
+// All patterns are case insensitive.
 
 type List struct {
 	re       []*regexp.Regexp
+	negate   []bool // the pattern re-includes the names it matches
 	patterns []string
 }
 
+// MustList gives the list of the patterns, it panics when a pattern is invalid
+func MustList(patterns ...string) List {
+	l, err := New(patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
 func New(patterns ...string) (List, error) {
 	l := List{}
 	for _, name := range patterns {
@@ -28,10 +50,16 @@ func New(patterns ...string) (List, error) {
 	return l, nil
 }
 
+// IsSet returns true when the list contains at least one pattern
+func (l List) IsSet() bool {
+	return len(l.re) > 0
+}
+
+// Match returns true when the last pattern matching the name isn't a negation
 func (l List) Match(name string) bool {
-	for _, re := range l.re {
-		if re.MatchString(name) {
-			return true
+	for i := len(l.re) - 1; i >= 0; i-- {
+		if l.re[i].MatchString(name) {
+			return !l.negate[i]
 		}
 	}
 	return false
@@ -45,21 +73,40 @@ func fetchRune(b []byte) ([]byte, rune) {
 
 // transform a glob styled pattern into a regular expression
 func patternToRe(pattern string) (*regexp.Regexp, error) {
+	expr, err := globToRe(pattern, `[^./]`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file name pattern: %s", pattern)
+	}
+	return regexp.Compile("(?i)" + expr)
+}
+
+// globToRe gives the regular expression of a glob. The '*' and '?' match the characters of the class,
+// '**' matches any characters, and '**/' zero or more directories.
+func globToRe(pattern string, class string) (string, error) {
 	var r strings.Builder
 	var inBrackets bool
 	var b rune
 	buf := []byte(pattern)
 
-	r.WriteString("(?i)") // make the pattern case insensitive
-
 	for len(buf) > 0 {
 		buf, b = fetchRune(buf)
 		switch b {
 		case '*':
-			r.WriteString(`[^./]*`)
+			if len(buf) > 0 && buf[0] == '*' {
+				buf = buf[1:]
+				if len(buf) > 0 && buf[0] == '/' {
+					// **/ matches zero or more directories
+					buf = buf[1:]
+					r.WriteString(`(?:.*/)?`)
+				} else {
+					r.WriteString(`.*`)
+				}
+				continue
+			}
+			r.WriteString(class + `*`)
 		case '?':
-			r.WriteString(`[^./]`)
-		case '.', '^', '$', '(', ')', '|':
+			r.WriteString(class)
+		case '.', '^', '$', '(', ')', '|', '+', '{', '}':
 			r.WriteRune('\\')
 			r.WriteRune(b)
 		case '\\':
@@ -90,13 +137,12 @@ func patternToRe(pattern string) (*regexp.Regexp, error) {
 		}
 	}
 	if inBrackets {
-		return nil, fmt.Errorf("invalid file name pattern: %s", pattern)
+		return "", errors.New("unclosed brackets")
 	}
-	re, err := regexp.Compile(r.String())
-	if err != nil {
-		return nil, fmt.Errorf("invalid file name pattern: %s", pattern)
+	if _, err := regexp.Compile(r.String()); err != nil {
+		return "", err
 	}
-	return re, nil
+	return r.String(), nil
 }
 
 /*
@@ -111,11 +157,25 @@ func (l *List) Set(s string) error {
 	if s == "" {
 		return nil
 	}
-	re, err := patternToRe(s)
-	if err != nil {
-		return err
+	pattern, negate := strings.CutPrefix(s, "!")
+	if pattern == "" {
+		return fmt.Errorf("invalid file name pattern: %s", s)
+	}
+	var re *regexp.Regexp
+	var err error
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err = regexp.Compile("(?i)" + expr)
+		if err != nil {
+			return fmt.Errorf("invalid file name regular expression: %s: %w", expr, err)
+		}
+	} else {
+		re, err = patternToRe(pattern)
+		if err != nil {
+			return err
+		}
 	}
 	l.re = append(l.re, re)
+	l.negate = append(l.negate, negate)
 	l.patterns = append(l.patterns, s)
 	return nil
 }
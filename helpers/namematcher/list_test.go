@@ -52,6 +52,13 @@ func TestList_Match(t *testing.T) {
 				{"/path/to/file", false},
 			},
 		},
+		{
+			name: "file+{1}.jpg",
+			want: []args{
+				{"/path/to/file+{1}.jpg", true},
+				{"/path/to/filee.jpg", false},
+			},
+		},
 		{
 			name: "file$.jpg",
 			want: []args{
@@ -129,6 +136,115 @@ func TestList_Match(t *testing.T) {
 	}
 }
 
+func TestList_Patterns(t *testing.T) {
+	type args struct {
+		name string
+		want bool
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []args
+	}{
+		{
+			name:     "double star",
+			patterns: []string{"backup/**.tmp"},
+			want: []args{
+				{"/photos/backup/file.tmp", true},
+				{"/photos/backup/2023/06/file.tmp", true},
+				{"/photos/backup/file.jpg", false},
+				{"/photos/file.tmp", false},
+			},
+		},
+		{
+			name:     "double star and slash",
+			patterns: []string{"photos/**/raw/"},
+			want: []args{
+				{"/photos/raw/file.dng", true},
+				{"/photos/2023/06/raw/file.dng", true},
+				{"/photos/2023/file.dng", false},
+			},
+		},
+		{
+			name:     "regular expression",
+			patterns: []string{`re:_\d{3}\.jpg$`},
+			want: []args{
+				{"/photos/IMG_123.JPG", true},
+				{"/photos/IMG_1234.jpg", false},
+				{"/photos/IMG_123.jpg.xmp", false},
+			},
+		},
+		{
+			name:     "negation",
+			patterns: []string{"thumbnails/", "!thumbnails/keep/"},
+			want: []args{
+				{"/photos/thumbnails/file.jpg", true},
+				{"/photos/thumbnails/keep/file.jpg", false},
+				{"/photos/file.jpg", false},
+			},
+		},
+		{
+			name:     "last pattern wins",
+			patterns: []string{"*.tmp", "!backup/", "backup/old/"},
+			want: []args{
+				{"/photos/file.tmp", true},
+				{"/photos/backup/file.tmp", false},
+				{"/photos/backup/old/file.jpg", true},
+			},
+		},
+		{
+			name:     "negated regular expression",
+			patterns: []string{"@eaDir/", `!re:@eaDir/.*\.xmp$`},
+			want: []args{
+				{"/photos/@eaDir/file.jpg", true},
+				{"/photos/@eaDir/file.xmp", false},
+			},
+		},
+		{
+			name:     "escaped exclamation mark",
+			patterns: []string{`\!draft`},
+			want: []args{
+				{"/photos/!draft/file.jpg", true},
+				{"/photos/draft/file.jpg", false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New(tt.patterns...)
+			if err != nil {
+				t.Fatalf("Error creating the list: %s", err.Error())
+			}
+			for _, arg := range tt.want {
+				if got := l.Match(arg.name); got != arg.want {
+					t.Errorf("Match(%q) = %v, want %v", arg.name, got, arg.want)
+				}
+			}
+		})
+	}
+}
+
+func TestList_InvalidPatterns(t *testing.T) {
+	for _, p := range []string{"file[s", "re:(unclosed", "!"} {
+		if _, err := New(p); err == nil {
+			t.Errorf("New(%q) should fail", p)
+		}
+	}
+}
+
+func TestMustList(t *testing.T) {
+	l := MustList("@eaDir/", "!re:keep")
+	if !l.Match("/photos/@eaDir/file.jpg") || l.Match("/photos/@eaDir/keep.jpg") {
+		t.Errorf("unexpected matches of %s", l)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("MustList should panic with an invalid pattern")
+		}
+	}()
+	MustList("re:(")
+}
+
 func BenchmarkPatternToRe(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = patternToRe("SYNOFILE_THUMB_*.*")
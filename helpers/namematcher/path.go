@@ -0,0 +1,122 @@
+package namematcher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathList is a list of patterns matched against the full path of the file
+// relatively to the root of the file system being browsed.
+//
+// A pattern can be:
+//   - a glob applied on the whole path. The '*' matches any character but the '/',
+//     '**' matches any number of directories, '?' matches one character
+//     ex: **/RAW/**, Archive/*/*.jpg
+//   - a regular expression when prefixed with 're:' or when it starts with '^'
+//     ex: ^Archive/(Keep|Old)/, re:\.orig\.
+//
+// Regular expressions are using the RE2 syntax: look-ahead aren't supported.
+// All patterns are case insensitive.
+
+type PathList struct {
+	re       []*regexp.Regexp
+	patterns []string
+}
+
+func NewPathList(patterns ...string) (PathList, error) {
+	l := PathList{}
+	for _, p := range patterns {
+		err := l.Set(p)
+		if err != nil {
+			return PathList{}, err
+		}
+	}
+	return l, nil
+}
+
+// IsSet returns true when the list contains at least one pattern
+func (l PathList) IsSet() bool {
+	return len(l.re) > 0
+}
+
+// Match returns true when the name matches one of the patterns
+func (l PathList) Match(name string) bool {
+	for _, re := range l.re {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Include returns true when the list is empty or when the name matches one of the patterns
+func (l PathList) Include(name string) bool {
+	if len(l.re) == 0 {
+		return true
+	}
+	return l.Match(name)
+}
+
+// transform a full path glob into a regular expression
+func pathGlobToRe(pattern string) (*regexp.Regexp, error) {
+	expr, err := globToRe(strings.TrimPrefix(pattern, "/"), `[^/]`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path pattern: %s", pattern)
+	}
+	return regexp.Compile("(?i)^" + expr + "$")
+}
+
+func pathPatternToRe(pattern string) (*regexp.Regexp, error) {
+	switch {
+	case strings.HasPrefix(pattern, "re:"):
+		pattern = strings.TrimPrefix(pattern, "re:")
+	case strings.HasPrefix(pattern, "^"):
+	default:
+		return pathGlobToRe(pattern)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path regular expression: %s: %w", pattern, err)
+	}
+	return re, nil
+}
+
+/*
+	Implements the flag.Value interface for the list of path patterns
+	Check the validity of the pattern
+*/
+
+func (l *PathList) Set(s string) error {
+	if l == nil {
+		return errors.New("namematcher path list not initialized")
+	}
+	if s == "" {
+		return nil
+	}
+	re, err := pathPatternToRe(s)
+	if err != nil {
+		return err
+	}
+	l.re = append(l.re, re)
+	l.patterns = append(l.patterns, s)
+	return nil
+}
+
+func (l PathList) String() string {
+	var s strings.Builder
+	for i, pattern := range l.patterns {
+		if i > 0 {
+			s.WriteString(", ")
+		}
+		s.WriteRune('\'')
+		s.WriteString(pattern)
+		s.WriteRune('\'')
+	}
+	return s.String()
+}
+
+func (l *PathList) Get() any {
+	return *l
+}
@@ -0,0 +1,96 @@
+package namematcher
+
+import "testing"
+
+func TestPathList_Match(t *testing.T) {
+	type args struct {
+		name string
+		want bool
+	}
+	tests := []struct {
+		name string
+		want []args
+	}{
+		{
+			name: "**/RAW/**",
+			want: []args{
+				{"RAW/file.cr3", true},
+				{"2023/RAW/file.cr3", true},
+				{"2023/summer/raw/sub/file.cr3", true},
+				{"2023/RAWS/file.cr3", false},
+				{"2023/file.cr3", false},
+			},
+		},
+		{
+			name: "Archive/*/*.jpg",
+			want: []args{
+				{"Archive/2023/file.jpg", true},
+				{"archive/2023/FILE.JPG", true},
+				{"Archive/file.jpg", false},
+				{"Archive/2023/sub/file.jpg", false},
+				{"Old/Archive/2023/file.jpg", false},
+			},
+		},
+		{
+			name: "**/*.orig.jpg",
+			want: []args{
+				{"file.orig.jpg", true},
+				{"a/b/file.orig.jpg", true},
+				{"a/b/file.jpg", false},
+			},
+		},
+		{
+			name: "^Archive/(Keep|Old)/",
+			want: []args{
+				{"Archive/Keep/file.jpg", true},
+				{"Archive/old/2023/file.jpg", true},
+				{"Archive/Other/file.jpg", false},
+				{"Backup/Archive/Keep/file.jpg", false},
+			},
+		},
+		{
+			name: `re:_\d{4}\.jpg$`,
+			want: []args{
+				{"a/IMG_1234.jpg", true},
+				{"a/IMG_123.jpg", false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewPathList(tt.name)
+			if err != nil {
+				t.Errorf("Error creating the list: %s", err.Error())
+				return
+			}
+			for _, arg := range tt.want {
+				if got := l.Match(arg.name); got != arg.want {
+					t.Errorf("PathList.Match(%v) = %v, want %v", arg.name, got, arg.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPathList_Invalid(t *testing.T) {
+	for _, p := range []string{"Archive/[a-", "^Archive/(?!Keep/)"} {
+		_, err := NewPathList(p)
+		if err == nil {
+			t.Errorf("expecting an error for the pattern %q", p)
+		}
+	}
+}
+
+func TestPathList_Include(t *testing.T) {
+	l := PathList{}
+	if !l.Include("any/file.jpg") {
+		t.Errorf("an empty list should include all files")
+	}
+	l, _ = NewPathList("2023/**")
+	if l.Include("2024/file.jpg") {
+		t.Errorf("2024/file.jpg should not be included")
+	}
+	if !l.Include("2023/file.jpg") {
+		t.Errorf("2023/file.jpg should be included")
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Package notify posts the notifications of immich-go to a webhook: a generic JSON one, or the ones of Discord, Slack, ntfy and Gotify.
+*/
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Formats of the notifications
+const (
+	FormatJSON    = "json"    // the Event in JSON
+	FormatDiscord = "discord" // Discord webhook
+	FormatSlack   = "slack"   // Slack incoming webhook
+	FormatNtfy    = "ntfy"    // ntfy topic URL, ex: https://ntfy.sh/my-topic
+	FormatGotify  = "gotify"  // Gotify message URL with its token, ex: https://gotify.example.com/message?token=xxx
+)
+
+// Kinds of events
+const (
+	EventCompleted = "completed" // the run is completed
+	EventErrors    = "errors"    // the number of errors has reached the threshold
+)
+
+// Event is the content of a notification
+type Event struct {
+	Event   string           `json:"event"`
+	Command string           `json:"command"`
+	Title   string           `json:"title"`
+	Message string           `json:"message"`
+	Errors  int64            `json:"errors"`
+	Counts  map[string]int64 `json:"counts,omitempty"`
+	Time    time.Time        `json:"time"`
+}
+
+// Notifier posts the events to the webhook
+type Notifier struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+// Validate checks the format of the notifications
+func Validate(format string) error {
+	switch strings.ToLower(format) {
+	case FormatJSON, FormatDiscord, FormatSlack, FormatNtfy, FormatGotify:
+		return nil
+	}
+	return fmt.Errorf("the notification format must be %s, %s, %s, %s or %s", FormatJSON, FormatDiscord, FormatSlack, FormatNtfy, FormatGotify)
+}
+
+func New(url string, format string) *Notifier {
+	return &Notifier{
+		url:    url,
+		format: strings.ToLower(format),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Send posts the event in the format of the webhook
+func (n *Notifier) Send(ctx context.Context, e Event) error {
+	var body []byte
+	var err error
+	contentType := "application/json"
+	switch n.format {
+	case FormatDiscord:
+		body, err = json.Marshal(map[string]string{"content": "**" + e.Title + "**\n" + e.Message})
+	case FormatSlack:
+		body, err = json.Marshal(map[string]string{"text": "*" + e.Title + "*\n" + e.Message})
+	case FormatGotify:
+		priority := 5
+		if e.Event == EventErrors {
+			priority = 8
+		}
+		body, err = json.Marshal(map[string]any{"title": e.Title, "message": e.Message, "priority": priority})
+	case FormatNtfy:
+		body, contentType = []byte(e.Message), "text/plain"
+	default:
+		body, err = json.Marshal(e)
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "immich-go")
+	req.Header.Set("Content-Type", contentType)
+	if n.format == FormatNtfy {
+		req.Header.Set("Title", e.Title)
+		if e.Event == EventErrors {
+			req.Header.Set("Priority", "high")
+			req.Header.Set("Tags", "warning")
+		}
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSend(t *testing.T) {
+	e := Event{
+		Event:   EventErrors,
+		Command: "upload",
+		Title:   "immich-go upload",
+		Message: "10 errors",
+		Errors:  10,
+		Time:    time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		format  string
+		want    string
+		headers map[string]string
+	}{
+		{format: FormatJSON, want: `{"event":"errors","command":"upload","title":"immich-go upload","message":"10 errors","errors":10,"time":"2024-07-01T10:00:00Z"}`},
+		{format: FormatDiscord, want: `{"content":"**immich-go upload**\n10 errors"}`},
+		{format: FormatSlack, want: `{"text":"*immich-go upload*\n10 errors"}`},
+		{format: FormatGotify, want: `{"message":"10 errors","priority":8,"title":"immich-go upload"}`},
+		{format: FormatNtfy, want: `10 errors`, headers: map[string]string{"Title": "immich-go upload", "Priority": "high"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got string
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+				b, _ := io.ReadAll(req.Body)
+				got = strings.TrimSpace(string(b))
+				header = req.Header
+				resp.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			err := New(server.URL, tt.format).Send(context.Background(), e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			for k, v := range tt.headers {
+				if header.Get(k) != v {
+					t.Errorf("header %s: expected %q, got %q", k, v, header.Get(k))
+				}
+			}
+		})
+	}
+}
+
+func TestSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	err := New(server.URL, FormatJSON).Send(context.Background(), Event{})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,147 @@
+// Package scandb keeps the files uploaded by the previous runs, with their checksum and the ID of their server's asset.
+//
+// The entries are keyed by the file's path, size and modification time:
+// a file unchanged since its upload can be skipped without reading it.
+// A database is kept for each server.
+package scandb
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entry is a line of the database file
+type entry struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	Checksum string    `json:"checksum,omitempty"` // SHA1 of the file, base64 encoded
+	AssetID  string    `json:"assetId"`            // ID of the server's asset
+}
+
+// DB is a scan database backed by a JSON lines file
+type DB struct {
+	file    string
+	lock    sync.Mutex
+	entries map[string]entry
+	dirty   bool
+}
+
+// FileName gives the file of the database of the server in the folder dir
+func FileName(dir string, server string) string {
+	h := sha1.Sum([]byte(strings.TrimSuffix(strings.ToLower(server), "/")))
+	return filepath.Join(dir, "scan-"+hex.EncodeToString(h[:8])+".jsonl")
+}
+
+// Open loads the database file. A missing file gives an empty database.
+func Open(file string) (*DB, error) {
+	db := &DB{
+		file:    file,
+		entries: map[string]entry{},
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var e entry
+		err = dec.Decode(&e)
+		if err != nil {
+			break
+		}
+		db.entries[e.Path] = e
+	}
+	if !errors.Is(err, io.EOF) {
+		// a damaged database is rebuilt, the files are checked again against the server
+		db.entries = map[string]entry{}
+		db.dirty = true
+	}
+	return db, nil
+}
+
+// Uploaded gives the ID of the server's asset when this very version of the file has been uploaded by a previous run
+func (db *DB) Uploaded(key string, size int64, modTime time.Time) (string, bool) {
+	if db == nil || key == "" {
+		return "", false
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	e, ok := db.entries[key]
+	if !ok || e.AssetID == "" || e.Size != size || !e.ModTime.Equal(modTime) {
+		return "", false
+	}
+	return e.AssetID, true
+}
+
+// Put records the server's asset of the file
+func (db *DB) Put(key string, size int64, modTime time.Time, checksum string, assetID string) {
+	if db == nil || key == "" || assetID == "" {
+		return
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	e := entry{Path: key, Size: size, ModTime: modTime, Checksum: checksum, AssetID: assetID}
+	if old, ok := db.entries[key]; ok && old.Size == size && old.ModTime.Equal(modTime) && old.Checksum == checksum && old.AssetID == assetID {
+		return
+	}
+	db.entries[key] = e
+	db.dirty = true
+}
+
+// Close writes the database file when it has changed
+func (db *DB) Close() error {
+	if db == nil {
+		return nil
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if !db.dirty {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(db.file), 0o700)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(db.file), filepath.Base(db.file)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range db.entries {
+		err = enc.Encode(e)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	err = errors.Join(err, tmp.Close())
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	err = os.Rename(tmp.Name(), db.file)
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	db.dirty = false
+	return nil
+}
@@ -0,0 +1,84 @@
+package scandb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDB(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "scan", "scan.jsonl")
+	mtime := time.Date(2023, 10, 6, 8, 30, 0, 0, time.UTC)
+
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.Uploaded("/photos/a.jpg", 10, mtime); ok {
+		t.Fatal("the database should be empty")
+	}
+	db.Put("/photos/a.jpg", 10, mtime, "sum-a", "asset-a")
+	db.Put("/photos/b.jpg", 20, mtime, "sum-b", "")
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		key   string
+		size  int64
+		mtime time.Time
+		want  string
+	}{
+		{name: "same file", key: "/photos/a.jpg", size: 10, mtime: mtime, want: "asset-a"},
+		{name: "no asset", key: "/photos/b.jpg", size: 20, mtime: mtime},
+		{name: "size changed", key: "/photos/a.jpg", size: 11, mtime: mtime},
+		{name: "file modified", key: "/photos/a.jpg", size: 10, mtime: mtime.Add(time.Second)},
+		{name: "unknown file", key: "/photos/c.jpg", size: 10, mtime: mtime},
+		{name: "no key", key: "", size: 10, mtime: mtime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := db.Uploaded(tt.key, tt.size, tt.mtime)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("Uploaded() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+	// the file isn't written again when nothing has changed
+	db.Put("/photos/a.jpg", 10, mtime, "sum-a", "asset-a")
+	if db.dirty {
+		t.Error("the database shouldn't be changed")
+	}
+}
+
+func TestDamagedDB(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "scan.jsonl")
+	err := os.WriteFile(file, []byte(`{"path":"/photos/a.jpg","size":10,"assetId":"a"}`+"\n{garbage"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.entries) != 0 || !db.dirty {
+		t.Errorf("a damaged database should be rebuilt")
+	}
+}
+
+func TestFileName(t *testing.T) {
+	a := FileName("dir", "http://nas:2283")
+	if b := FileName("dir", "HTTP://NAS:2283/"); a != b {
+		t.Errorf("the same server gives different files: %s, %s", a, b)
+	}
+	if b := FileName("dir", "http://other:2283"); a == b {
+		t.Errorf("two servers share the file %s", a)
+	}
+}
@@ -30,12 +30,16 @@ type StackType int
 const (
 	StackRawJpg StackType = iota
 	StackBurst
+	StackVariant // IMG_1234~2.jpg stacked with IMG_1234.jpg
+	StackEdited  // IMG_1234-edited.jpg stacked with IMG_1234.jpg
 )
 
 type StackBuilder struct {
 	dateRange      immich.DateRange // Set capture date range
 	stacks         map[Key]Stack
 	supportedMedia immich.SupportedMedia
+	variants       bool // stack the numbered variants
+	edited         bool // stack the edited copies
 }
 
 func NewStackBuilder(supportedMedia immich.SupportedMedia) *StackBuilder {
@@ -48,6 +52,16 @@ func NewStackBuilder(supportedMedia immich.SupportedMedia) *StackBuilder {
 	return &sb
 }
 
+// StackVariants enables the stacking of the numbered variants of an image, like IMG_1234~2.jpg with IMG_1234.jpg
+func (sb *StackBuilder) StackVariants() {
+	sb.variants = true
+}
+
+// StackEdited enables the stacking of the edited copies of an image, like IMG_1234-edited.jpg with IMG_1234.jpg
+func (sb *StackBuilder) StackEdited() {
+	sb.edited = true
+}
+
 func (sb *StackBuilder) ProcessAsset(id string, fileName string, captureDate time.Time) {
 	if !sb.dateRange.InRange(captureDate) {
 		return
@@ -76,6 +90,16 @@ func (sb *StackBuilder) ProcessAsset(id string, fileName string, captureDate tim
 		}
 	}
 
+	// may be a variant of the image
+	variant := StackType(-1)
+	if !burst {
+		if parts := variantRE.FindStringSubmatch(base); sb.variants && parts != nil {
+			base, variant = parts[1], StackVariant
+		} else if parts := editedRE.FindStringSubmatch(base); sb.edited && parts != nil {
+			base, variant = parts[1], StackEdited
+		}
+	}
+
 	k := Key{
 		date:     captureDate.Round(time.Minute),
 		baseName: base,
@@ -89,15 +113,22 @@ func (sb *StackBuilder) ProcessAsset(id string, fileName string, captureDate tim
 	s.Names = append(s.Names, path.Base(fileName))
 	if burst {
 		s.StackType = StackBurst
+	} else if variant >= 0 && s.StackType != StackBurst {
+		s.StackType = variant
 	}
 	if cover {
 		s.CoverID = id
-	} else if !burst && slices.Contains([]string{".jpeg", ".jpg", ".jpe"}, ext) {
+	} else if !burst && variant < 0 && slices.Contains([]string{".jpeg", ".jpg", ".jpe"}, ext) {
 		s.CoverID = id
 	}
 	sb.stacks[k] = s
 }
 
+var (
+	variantRE = regexp.MustCompile(`^(.*)~\d+$`)
+	editedRE  = regexp.MustCompile(`(?i)^(.*)-edited$`)
+)
+
 // stackMatcher analyze the name and return
 // bool -> true when name is a part of burst
 // string -> base name of the burst
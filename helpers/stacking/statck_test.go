@@ -19,9 +19,10 @@ type asset struct {
 
 func Test_Stack(t *testing.T) {
 	tc := []struct {
-		name  string
-		input []asset
-		want  []Stack
+		name     string
+		variants bool
+		input    []asset
+		want     []Stack
 	}{
 		{
 			name: "no stack JPG+DNG",
@@ -201,11 +202,50 @@ func Test_Stack(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "no stack of variants by default",
+			input: []asset{
+				{ID: "1", FileName: "IMG_1234.jpg", DateTaken: metadata.TakeTimeFromName("2023-10-01 10.15.00")},
+				{ID: "2", FileName: "IMG_1234~2.jpg", DateTaken: metadata.TakeTimeFromName("2023-10-01 10.15.00")},
+				{ID: "3", FileName: "IMG_1234-edited.jpg", DateTaken: metadata.TakeTimeFromName("2023-10-01 10.15.00")},
+			},
+			want: []Stack{},
+		},
+		{
+			name:     "stack variants",
+			variants: true,
+			input: []asset{
+				{ID: "1", FileName: "IMG_1234~2.jpg", DateTaken: metadata.TakeTimeFromName("2023-10-01 10.15.00")},
+				{ID: "2", FileName: "IMG_1234.jpg", DateTaken: metadata.TakeTimeFromName("2023-10-01 10.15.00")},
+				{ID: "3", FileName: "IMG_5678.jpg", DateTaken: metadata.TakeTimeFromName("2023-10-01 10.15.00")},
+				{ID: "4", FileName: "IMG_5678-edited.jpg", DateTaken: metadata.TakeTimeFromName("2023-10-01 10.15.00")},
+			},
+			want: []Stack{
+				{
+					CoverID:   "2",
+					IDs:       []string{"1"},
+					Date:      metadata.TakeTimeFromName("2023-10-01 10.15.00"),
+					Names:     []string{"IMG_1234~2.jpg", "IMG_1234.jpg"},
+					StackType: StackVariant,
+				},
+				{
+					CoverID:   "3",
+					IDs:       []string{"4"},
+					Date:      metadata.TakeTimeFromName("2023-10-01 10.15.00"),
+					Names:     []string{"IMG_5678.jpg", "IMG_5678-edited.jpg"},
+					StackType: StackEdited,
+				},
+			},
+		},
 	}
 
 	for _, tt := range tc {
 		t.Run(tt.name, func(t *testing.T) {
 			sb := NewStackBuilder(immich.DefaultSupportedMedia)
+			if tt.variants {
+				sb.StackVariants()
+				sb.StackEdited()
+			}
 			for _, a := range tt.input {
 				sb.ProcessAsset(a.ID, a.FileName, a.DateTaken)
 			}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package sysloghandler
+
+import "errors"
+
+// Dial isn't supported on this system
+func Dial(address string, tag string) (Writer, error) {
+	return nil, errors.New("syslog isn't supported on this system")
+}
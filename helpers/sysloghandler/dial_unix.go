@@ -0,0 +1,22 @@
+//go:build !windows && !plan9
+
+package sysloghandler
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+// Dial connects to the syslog daemon given by its address network://host:port, or to the local daemon when the address is empty.
+func Dial(address string, tag string) (Writer, error) {
+	network, raddr := "", ""
+	if address != "" {
+		var ok bool
+		network, raddr, ok = strings.Cut(address, "://")
+		if !ok {
+			return nil, fmt.Errorf("the syslog address %q must be network://host:port, ex: udp://localhost:514", address)
+		}
+	}
+	return syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+}
@@ -0,0 +1,96 @@
+/*
+Package sysloghandler sends the structured log to the syslog daemon, or to journald through its syslog socket.
+
+The records are formatted as key=value pairs, without time and level:
+the time is added by the daemon, and the level gives the priority of the message.
+*/
+package sysloghandler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Writer sends messages to the syslog daemon with a priority. The Write method uses the default priority.
+type Writer interface {
+	io.WriteCloser
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+}
+
+// Handler is a slog.Handler writing into syslog
+type Handler struct {
+	w     Writer
+	level slog.Leveler
+
+	lock *sync.Mutex   // the buffer is shared by the handlers derived by WithAttrs and WithGroup
+	buf  *bytes.Buffer // receives the formatted record
+	text slog.Handler  // formats the records into the buffer
+}
+
+// New gives a handler writing the records of the level or above into w
+func New(w Writer, level slog.Leveler) *Handler {
+	buf := &bytes.Buffer{}
+	return &Handler{
+		w:     w,
+		level: level,
+		lock:  &sync.Mutex{},
+		buf:   buf,
+		text: slog.NewTextHandler(buf, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+	}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.level != nil {
+		min = h.level.Level()
+	}
+	return level >= min
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	h.lock.Lock()
+	h.buf.Reset()
+	err := h.text.Handle(ctx, r)
+	m := strings.TrimSuffix(h.buf.String(), "\n")
+	h.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.w.Err(m)
+	case r.Level >= slog.LevelWarn:
+		return h.w.Warning(m)
+	case r.Level >= slog.LevelInfo:
+		return h.w.Info(m)
+	default:
+		return h.w.Debug(m)
+	}
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.text = h.text.WithAttrs(attrs)
+	return &c
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.text = h.text.WithGroup(name)
+	return &c
+}
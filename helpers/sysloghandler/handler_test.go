@@ -0,0 +1,43 @@
+package sysloghandler
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// fakeWriter records the messages with their priority
+type fakeWriter struct {
+	messages []string
+}
+
+func (w *fakeWriter) record(priority, m string) error {
+	w.messages = append(w.messages, priority+" "+m)
+	return nil
+}
+
+func (w *fakeWriter) Write(b []byte) (int, error) { return len(b), w.record("default", string(b)) }
+func (w *fakeWriter) Close() error                { return nil }
+func (w *fakeWriter) Debug(m string) error        { return w.record("debug", m) }
+func (w *fakeWriter) Info(m string) error         { return w.record("info", m) }
+func (w *fakeWriter) Warning(m string) error      { return w.record("warning", m) }
+func (w *fakeWriter) Err(m string) error          { return w.record("err", m) }
+
+func TestHandler(t *testing.T) {
+	w := &fakeWriter{}
+	log := slog.New(New(w, slog.LevelInfo))
+
+	log.Debug("hidden")
+	log.Info("uploaded", "file", "a.jpg")
+	log.Warn("server has a better asset", "file", "b.jpg")
+	log.With("command", "upload").WithGroup("asset").Error("upload error", "file", "c.jpg")
+
+	want := []string{
+		"info msg=uploaded file=a.jpg",
+		`warning msg="server has a better asset" file=b.jpg`,
+		`err msg="upload error" command=upload asset.file=c.jpg`,
+	}
+	if !reflect.DeepEqual(w.messages, want) {
+		t.Errorf("expected %q, got %q", want, w.messages)
+	}
+}
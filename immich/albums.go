@@ -3,6 +3,8 @@ package immich
 import (
 	"context"
 	"fmt"
+
+	"github.com/simulot/immich-go/helpers/gen"
 )
 
 type AlbumSimplified struct {
@@ -16,9 +18,9 @@ type AlbumSimplified struct {
 	// SharedUsers                []string  `json:"sharedUsers"`
 	// Owner                      User      `json:"owner"`
 	// Shared                     bool      `json:"shared"`
-	// AssetCount                 int       `json:"assetCount"`
 	// LastModifiedAssetTimestamp time.Time `json:"lastModifiedAssetTimestamp"
-	AssetIds []string `json:"assetIds,omitempty"`
+	AssetCount int      `json:"assetCount,omitempty"`
+	AssetIds   []string `json:"assetIds,omitempty"`
 }
 
 func (ic *ImmichClient) GetAllAlbums(ctx context.Context) ([]AlbumSimplified, error) {
@@ -100,22 +102,35 @@ type UpdateAlbumResult struct {
 	Error   string `json:"error,omitempty"`
 }
 
+// ErrorDuplicate is the error of the UpdateAlbumResult when the asset is already in the album
+const ErrorDuplicate = "duplicate"
+
+// MaxAssetsPerCall is the number of asset IDs sent in one call, the longer lists are sent in several calls
+const MaxAssetsPerCall = 1000
+
+// AddAssetToAlbum adds the assets to the album, in calls of MaxAssetsPerCall assets at most
 func (ic *ImmichClient) AddAssetToAlbum(ctx context.Context, albumID string, assets []string) ([]UpdateAlbumResult, error) {
-	var r []UpdateAlbumResult
-	body := UpdateAlbum{
-		IDS: assets,
-	}
-	err := ic.newServerCall(ctx, EndPointAddAsstToAlbum).do(
-		putRequest(fmt.Sprintf("/albums/%s/assets", albumID), setAcceptJSON(),
-			setJSONBody(body)),
-		responseJSON(&r))
-	if err != nil {
-		return nil, err
+	var results []UpdateAlbumResult
+	for _, ids := range gen.Chunks(assets, MaxAssetsPerCall) {
+		var r []UpdateAlbumResult
+		err := ic.newServerCall(ctx, EndPointAddAsstToAlbum).do(
+			putRequest(fmt.Sprintf("/albums/%s/assets", albumID), setAcceptJSON(),
+				setJSONBody(UpdateAlbum{IDS: ids})),
+			responseJSON(&r))
+		if err != nil {
+			return results, err
+		}
+		results = append(results, r...)
 	}
-	return r, nil
+	return results, nil
 }
 
+// CreateAlbum creates the album with the assets, the assets beyond MaxAssetsPerCall are added afterward
 func (ic *ImmichClient) CreateAlbum(ctx context.Context, name string, description string, assetsIDs []string) (AlbumSimplified, error) {
+	var more []string
+	if len(assetsIDs) > MaxAssetsPerCall {
+		assetsIDs, more = assetsIDs[:MaxAssetsPerCall], assetsIDs[MaxAssetsPerCall:]
+	}
 	body := AlbumContent{
 		AlbumName:   name,
 		Description: description,
@@ -128,7 +143,32 @@ func (ic *ImmichClient) CreateAlbum(ctx context.Context, name string, descriptio
 	if err != nil {
 		return AlbumSimplified{}, err
 	}
-	return r, nil
+	if len(more) > 0 {
+		_, err = ic.AddAssetToAlbum(ctx, r.ID, more)
+	}
+	return r, err
+}
+
+// AlbumUpdate gives the album's properties to change, empty fields are left unchanged
+type AlbumUpdate struct {
+	AlbumName             string `json:"albumName,omitempty"`
+	Description           string `json:"description,omitempty"`
+	AlbumThumbnailAssetID string `json:"albumThumbnailAssetId,omitempty"`
+	Order                 string `json:"order,omitempty"` // asc or desc
+}
+
+// Orders of the assets in an album
+const (
+	AlbumOrderAsc  = "asc"
+	AlbumOrderDesc = "desc"
+)
+
+func (ic *ImmichClient) UpdateAlbum(ctx context.Context, id string, update AlbumUpdate) (AlbumSimplified, error) {
+	var r AlbumSimplified
+	err := ic.newServerCall(ctx, EndPointUpdateAlbum).do(
+		patchRequest("/albums/"+id, setAcceptJSON(), setJSONBody(update)),
+		responseJSON(&r))
+	return r, err
 }
 
 func (ic *ImmichClient) GetAssetAlbums(ctx context.Context, id string) ([]AlbumSimplified, error) {
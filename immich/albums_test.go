@@ -0,0 +1,73 @@
+package immich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAlbumChunks(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var body struct {
+			IDs      []string `json:"ids"`
+			AssetIDs []string `json:"assetIds"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		calls = append(calls, fmt.Sprintf("%s %s %d", req.Method, req.URL.Path, len(body.IDs)+len(body.AssetIDs)))
+		switch req.URL.Path {
+		case "/api/albums":
+			_, _ = resp.Write([]byte(`{"id":"a1","albumName":"album"}`))
+		case "/api/albums/a1/assets":
+			r := []UpdateAlbumResult{}
+			for _, id := range body.IDs {
+				r = append(r, UpdateAlbumResult{ID: id, Success: true})
+			}
+			_ = json.NewEncoder(resp).Encode(r)
+		default:
+			_, _ = resp.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	ids := make([]string, 2500)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+	ctx := context.Background()
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ic.AddAssetToAlbum(ctx, "a1", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != len(ids) {
+		t.Errorf("expected %d results, got %d", len(ids), len(r))
+	}
+	_, err = ic.CreateAlbum(ctx, "album", "", ids[:1500])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.BatchUpdateAssets(ctx, ids[:1001], AssetsUpdate{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"PUT /api/albums/a1/assets 1000",
+		"PUT /api/albums/a1/assets 1000",
+		"PUT /api/albums/a1/assets 500",
+		"POST /api/albums 1000",
+		"PUT /api/albums/a1/assets 500",
+		"PUT /api/assets 1000",
+		"PUT /api/assets 1",
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(calls, "\n"))
+	}
+}
@@ -1,6 +1,7 @@
 package immich
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -11,10 +12,12 @@ import (
 	"net/textproto"
 	"net/url"
 	"path"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/helpers/gen"
 )
 
 type AssetResponse struct {
@@ -45,7 +48,7 @@ func formatDuration(duration time.Duration) string {
 
 func (ic *ImmichClient) AssetUpload(ctx context.Context, la *browser.LocalAssetFile) (AssetResponse, error) {
 	var ar AssetResponse
-	ext := path.Ext(la.FileName)
+	ext := la.Ext()
 	if strings.TrimSuffix(la.Title, ext) == "" {
 		la.Title = "No Name" + ext // fix #88, #128
 	}
@@ -54,129 +57,175 @@ func (ic *ImmichClient) AssetUpload(ctx context.Context, la *browser.LocalAssetF
 		ext = ".MP4" // #405
 		la.Title = la.Title + ".MP4"
 	}
+	if e := ic.extraMedia.UploadExt(ext); e != "" {
+		ext = e
+		la.Title = la.Title + e
+	}
 	mtype := ic.TypeFromExt(ext)
 	switch mtype {
 	case "video", "image":
 	default:
-		return ar, fmt.Errorf("type file not supported: %s", path.Ext(la.FileName))
+		return ar, fmt.Errorf("type file not supported: %s", la.Ext())
 	}
 
+	throttles := 0
+	for attempt := 1; ; {
+		ar, err := ic.uploadOnce(ctx, la, ext, mtype)
+		if err == nil || ctx.Err() != nil {
+			return ar, err
+		}
+		wait, throttled := throttledError(err)
+		switch {
+		case throttled && throttles < maxThrottleRetries:
+			// The server asks to slow down: wait for it without consuming an attempt
+			throttles++
+		case attempt >= ic.Retries || !isTransientError(err):
+			return ar, err
+		default:
+			wait = time.Duration(attempt) * ic.RetriesDelay
+			attempt++
+		}
+		// The server doesn't offer resumable uploads: restart the transfer from the beginning of the file
+		_ = la.Close()
+		select {
+		case <-ctx.Done():
+			return ar, errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// isTransientError tells if the upload failed because of the connection or the server availability
+func isTransientError(err error) bool {
+	var ce callError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	return ce.status == 0 || ce.status >= http.StatusInternalServerError
+}
+
+// uploadOnce makes one attempt to upload the asset
+func (ic *ImmichClient) uploadOnce(ctx context.Context, la *browser.LocalAssetFile, ext string, mtype string) (AssetResponse, error) {
+	var ar AssetResponse
 	f, err := la.Open()
 	if err != nil {
 		return ar, (err)
 	}
+	s, err := f.Stat()
+	if err != nil {
+		return ar, err
+	}
+	// the length of the file's content, to give the length of the request
+	content, err := fs.Stat(la.FSys, la.FileName)
+	if err != nil {
+		return ar, err
+	}
 
-	body, pw := io.Pipe()
-	m := multipart.NewWriter(pw)
+	// The multipart body is made of the form fields, the file's content streamed from its source, and the sidecar.
+	// The parts around the content are prepared in memory, so the length of the body is known before the sending.
+	var head, tail bytes.Buffer
+	w := &switchWriter{w: &head}
+	m := multipart.NewWriter(w)
 
-	go func() {
-		defer func() {
-			m.Close()
-			pw.Close()
-		}()
-		var s fs.FileInfo
-		s, err = f.Stat()
+	err = m.WriteField("deviceAssetId", fmt.Sprintf("%s-%d", path.Base(la.Title), s.Size()))
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("deviceId", ic.DeviceUUID)
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("assetType", mtype)
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("fileCreatedAt", la.Metadata.DateTaken.Format(time.RFC3339))
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("fileModifiedAt", s.ModTime().Format(time.RFC3339))
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("isFavorite", myBool(la.Favorite).String())
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("fileExtension", ext)
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("duration", formatDuration(0))
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("isReadOnly", "false")
+	if err != nil {
+		return ar, err
+	}
+	err = m.WriteField("isArchived", myBool(la.Archived).String())
+	if err != nil {
+		return ar, err
+	}
+	if la.LivePhotoID != "" {
+		err = m.WriteField("livePhotoVideoId", la.LivePhotoID)
 		if err != nil {
-			return
+			return ar, err
 		}
+	}
 
-		err = m.WriteField("deviceAssetId", fmt.Sprintf("%s-%d", path.Base(la.Title), s.Size()))
-		if err != nil {
-			return
-		}
-		err = m.WriteField("deviceId", ic.DeviceUUID)
-		if err != nil {
-			return
-		}
-		err = m.WriteField("assetType", mtype)
-		if err != nil {
-			return
-		}
-		err = m.WriteField("fileCreatedAt", la.Metadata.DateTaken.Format(time.RFC3339))
-		if err != nil {
-			return
-		}
-		err = m.WriteField("fileModifiedAt", s.ModTime().Format(time.RFC3339))
-		if err != nil {
-			return
-		}
-		err = m.WriteField("isFavorite", myBool(la.Favorite).String())
-		if err != nil {
-			return
-		}
-		err = m.WriteField("fileExtension", ext)
-		if err != nil {
-			return
-		}
-		err = m.WriteField("duration", formatDuration(0))
-		if err != nil {
-			return
-		}
-		err = m.WriteField("isReadOnly", "false")
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes("assetData"), escapeQuotes(path.Base(la.Title))))
+	h.Set("Content-Type", mtype)
+
+	_, err = m.CreatePart(h)
+	if err != nil {
+		return ar, err
+	}
+
+	// the file's content goes between the head and the tail
+	w.w = &tail
+	if la.SideCar.IsSet() {
+		scName := path.Base(la.FileName) + ".xmp"
+		h.Set("Content-Disposition",
+			fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+				escapeQuotes("sidecarData"), escapeQuotes(scName)))
+		h.Set("Content-Type", "application/xml")
+
+		var part io.Writer
+		part, err = m.CreatePart(h)
 		if err != nil {
-			return
+			return ar, err
 		}
-		err := m.WriteField("isArchived", myBool(la.Archived).String())
+		err = la.SideCar.Write(part)
 		if err != nil {
-			return
-		}
-		if la.LivePhotoID != "" {
-			err = m.WriteField("livePhotoVideoId", la.LivePhotoID)
-			if err != nil {
-				return
-			}
+			return ar, err
 		}
-
-		h := textproto.MIMEHeader{}
+	} else if la.Metadata.IsSet() {
+		scName := path.Base(la.FileName) + ".xmp"
 		h.Set("Content-Disposition",
 			fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
-				escapeQuotes("assetData"), escapeQuotes(path.Base(la.Title))))
-		h.Set("Content-Type", mtype)
+				escapeQuotes("sidecarData"), escapeQuotes(scName)))
+		h.Set("Content-Type", "application/xml")
 
 		var part io.Writer
 		part, err = m.CreatePart(h)
 		if err != nil {
-			return
+			return ar, err
 		}
-		_, err = io.Copy(part, f)
+		err = la.Metadata.Write(part)
 		if err != nil {
-			return
+			return ar, err
 		}
-
-		if la.SideCar.IsSet() {
-			scName := path.Base(la.FileName) + ".xmp"
-			h.Set("Content-Disposition",
-				fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
-					escapeQuotes("sidecarData"), escapeQuotes(scName)))
-			h.Set("Content-Type", "application/xml")
-
-			var part io.Writer
-			part, err = m.CreatePart(h)
-			if err != nil {
-				return
-			}
-			err = la.SideCar.Write(part)
-			if err != nil {
-				return
-			}
-		} else if la.Metadata.IsSet() {
-			scName := path.Base(la.FileName) + ".xmp"
-			h.Set("Content-Disposition",
-				fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
-					escapeQuotes("sidecarData"), escapeQuotes(scName)))
-			h.Set("Content-Type", "application/xml")
-
-			var part io.Writer
-			part, err = m.CreatePart(h)
-			if err != nil {
-				return
-			}
-			err = la.Metadata.Write(part)
-			if err != nil {
-				return
-			}
-		}
-	}()
+	}
+	err = m.Close()
+	if err != nil {
+		return ar, err
+	}
+	body := io.MultiReader(bytes.NewReader(head.Bytes()), f, bytes.NewReader(tail.Bytes()))
+	length := int64(head.Len()) + content.Size() + int64(tail.Len())
 
 	var callValues map[string]string
 	if ic.apiTraceWriter != nil {
@@ -192,10 +241,17 @@ func (ic *ImmichClient) AssetUpload(ctx context.Context, la *browser.LocalAssetF
 	}
 
 	errCall := ic.newServerCall(ctx, "AssetUpload").
-		do(postRequest("/assets", m.FormDataContentType(), setContextValue(callValues), setAcceptJSON(), setBody(body)), responseJSON(&ar))
+		do(postRequest("/assets", m.FormDataContentType(), setContextValue(callValues), setAcceptJSON(), setStreamBody(body, length)), responseJSON(&ar))
+	return ar, errCall
+}
+
+// switchWriter writes into a writer that can be changed
+type switchWriter struct {
+	w io.Writer
+}
 
-	err = errors.Join(err, errCall)
-	return ar, err
+func (w *switchWriter) Write(b []byte) (int, error) {
+	return w.w.Write(b)
 }
 
 const (
@@ -254,6 +310,17 @@ func (ic *ImmichClient) DeleteAssets(ctx context.Context, id []string, forceDele
 	return ic.newServerCall(ctx, "DeleteAsset").do(deleteRequest("/assets", setJSONBody(&req)))
 }
 
+// RestoreAssets moves the assets out of the trash
+func (ic *ImmichClient) RestoreAssets(ctx context.Context, ids []string) error {
+	for _, chunk := range gen.Chunks(ids, MaxAssetsPerCall) {
+		err := ic.newServerCall(ctx, EndPointRestoreAssets).do(postRequest("/trash/restore/assets", "application/json", setAcceptJSON(), setJSONBody(UpdateAlbum{IDS: chunk})))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (ic *ImmichClient) GetAssetByID(ctx context.Context, id string) (*Asset, error) {
 	body := struct {
 		WithExif  bool   `json:"withExif,omitempty"`
@@ -265,6 +332,26 @@ func (ic *ImmichClient) GetAssetByID(ctx context.Context, id string) (*Asset, er
 	return &r, err
 }
 
+// DownloadAsset gives the content of the original file of the asset, the caller must close it
+func (ic *ImmichClient) DownloadAsset(ctx context.Context, id string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := ic.newServerCall(ctx, EndPointDownloadAsset).do(getRequest("/assets/"+id+"/original"), responseBody(&body))
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// GetAssetPreview gives the JPEG preview of the asset generated by the server, the caller must close it
+func (ic *ImmichClient) GetAssetPreview(ctx context.Context, id string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := ic.newServerCall(ctx, EndPointGetAssetPreview).do(getRequest("/assets/"+id+"/thumbnail?size=preview"), responseBody(&body))
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
 func (ic *ImmichClient) UpdateAssets(ctx context.Context, ids []string,
 	isArchived bool, isFavorite bool,
 	latitude float64, longitude float64,
@@ -292,6 +379,27 @@ func (ic *ImmichClient) UpdateAssets(ctx context.Context, ids []string,
 	return ic.newServerCall(ctx, "updateAssets").do(putRequest("/assets", setJSONBody(param)))
 }
 
+// AssetsUpdate gives the properties to change on several assets, nil fields are left unchanged
+type AssetsUpdate struct {
+	IsFavorite *bool `json:"isFavorite,omitempty"`
+	IsArchived *bool `json:"isArchived,omitempty"`
+}
+
+// BatchUpdateAssets applies the same change to the assets, in calls of MaxAssetsPerCall assets at most
+func (ic *ImmichClient) BatchUpdateAssets(ctx context.Context, ids []string, update AssetsUpdate) error {
+	type updAssets struct {
+		IDs []string `json:"ids"`
+		AssetsUpdate
+	}
+	for _, chunk := range gen.Chunks(ids, MaxAssetsPerCall) {
+		err := ic.newServerCall(ctx, EndPointBatchUpdateAssets).do(putRequest("/assets", setJSONBody(updAssets{IDs: chunk, AssetsUpdate: update})))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (ic *ImmichClient) UpdateAsset(ctx context.Context, id string, a *browser.LocalAssetFile) (*Asset, error) {
 	type updAsset struct {
 		IsArchived  bool    `json:"isArchived"`
@@ -313,6 +421,20 @@ func (ic *ImmichClient) UpdateAsset(ctx context.Context, id string, a *browser.L
 }
 
 func (ic *ImmichClient) StackAssets(ctx context.Context, coverID string, ids []string) error {
+	if ic.capabilities.StackEndpoints() {
+		type createStack struct {
+			AssetIDs []string `json:"assetIds"`
+		}
+		// the first asset is the cover of the stack
+		assetIDs := []string{coverID}
+		for _, id := range ids {
+			if !slices.Contains(assetIDs, id) {
+				assetIDs = append(assetIDs, id)
+			}
+		}
+		return ic.newServerCall(ctx, EndPointCreateStack).do(postRequest("/stacks", "application/json", setAcceptJSON(), setJSONBody(createStack{AssetIDs: assetIDs})))
+	}
+
 	cover, err := ic.GetAssetByID(ctx, coverID)
 	if err != nil {
 		return err
@@ -320,3 +442,72 @@ func (ic *ImmichClient) StackAssets(ctx context.Context, coverID string, ids []s
 
 	return ic.UpdateAssets(ctx, ids, cover.IsArchived, cover.IsFavorite, cover.ExifInfo.Latitude, cover.ExifInfo.Longitude, false, coverID)
 }
+
+// UnstackAssets removes the assets from their stacks
+func (ic *ImmichClient) UnstackAssets(ctx context.Context, ids []string) error {
+	type unstackAssets struct {
+		IDs          []string `json:"ids"`
+		RemoveParent bool     `json:"removeParent"`
+	}
+	if ic.capabilities.StackEndpoints() {
+		var stackIDs []string
+		for _, id := range ids {
+			stack, err := ic.assetStack(ctx, id)
+			if err != nil {
+				return err
+			}
+			if stack != nil && !slices.Contains(stackIDs, stack.ID) {
+				stackIDs = append(stackIDs, stack.ID)
+			}
+		}
+		if len(stackIDs) == 0 {
+			return nil
+		}
+		return ic.newServerCall(ctx, EndPointUnstackAssets).do(deleteRequest("/stacks", setJSONBody(UpdateAlbum{IDS: stackIDs})))
+	}
+	return ic.newServerCall(ctx, EndPointUnstackAssets).do(putRequest("/assets", setJSONBody(unstackAssets{IDs: ids, RemoveParent: true})))
+}
+
+// SetStackCover makes the asset newCoverID the cover of the stack covered by oldCoverID
+func (ic *ImmichClient) SetStackCover(ctx context.Context, oldCoverID string, newCoverID string) error {
+	type stackParent struct {
+		OldParentID string `json:"oldParentId"`
+		NewParentID string `json:"newParentId"`
+	}
+	if ic.capabilities.StackEndpoints() {
+		type updateStack struct {
+			PrimaryAssetID string `json:"primaryAssetId"`
+		}
+		stack, err := ic.assetStack(ctx, oldCoverID)
+		if err != nil {
+			return err
+		}
+		if stack == nil {
+			return fmt.Errorf("the asset %s isn't stacked", oldCoverID)
+		}
+		return ic.newServerCall(ctx, EndPointSetStackCover).do(putRequest("/stacks/"+stack.ID, setAcceptJSON(), setJSONBody(updateStack{PrimaryAssetID: newCoverID})))
+	}
+	return ic.newServerCall(ctx, EndPointSetStackCover).do(putRequest("/assets/stack/parent", setJSONBody(stackParent{OldParentID: oldCoverID, NewParentID: newCoverID})))
+}
+
+// assetStack gives the stack of the asset, nil when the asset isn't stacked
+func (ic *ImmichClient) assetStack(ctx context.Context, id string) (*AssetStack, error) {
+	var a Asset
+	err := ic.newServerCall(ctx, EndPointGetAsset).do(getRequest("/assets/"+id, setAcceptJSON()), responseJSON(&a))
+	if err != nil {
+		return nil, err
+	}
+	return a.Stack, nil
+}
+
+// AssetMetadataUpdate gives the metadata of an asset to change, nil or empty fields are left unchanged
+type AssetMetadataUpdate struct {
+	DateTimeOriginal string   `json:"dateTimeOriginal,omitempty"` // RFC3339, the offset gives the time zone of the capture
+	Latitude         *float64 `json:"latitude,omitempty"`
+	Longitude        *float64 `json:"longitude,omitempty"`
+}
+
+// UpdateAssetMetadata changes the date of capture and the GPS location of the asset
+func (ic *ImmichClient) UpdateAssetMetadata(ctx context.Context, id string, update AssetMetadataUpdate) error {
+	return ic.newServerCall(ctx, EndPointUpdateAssetMetadata).do(putRequest("/assets/"+id, setAcceptJSON(), setJSONBody(update)))
+}
@@ -0,0 +1,300 @@
+package immich
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+// flakyServer fails the first uploads, and checks the integrity of the received file
+type flakyServer struct {
+	failures int
+	status   int
+	calls    int
+	received string
+}
+
+func (fs *flakyServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	fs.calls++
+	if fs.calls <= fs.failures {
+		resp.WriteHeader(fs.status)
+		return
+	}
+	f, _, err := req.FormFile("assetData")
+	if err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	b, _ := io.ReadAll(f)
+	fs.received = string(b)
+	resp.WriteHeader(http.StatusCreated)
+	_, _ = resp.Write([]byte(`{"id":"123","status":"created"}`))
+}
+
+func TestAssetUploadRetries(t *testing.T) {
+	tt := []struct {
+		name        string
+		failures    int
+		status      int
+		expectedErr bool
+		calls       int
+	}{
+		{name: "no failure", failures: 0, status: http.StatusServiceUnavailable, calls: 1},
+		{name: "recovered", failures: 2, status: http.StatusServiceUnavailable, calls: 3},
+		{name: "too many failures", failures: 3, status: http.StatusBadGateway, expectedErr: true, calls: 3},
+		{name: "client error not retried", failures: 1, status: http.StatusBadRequest, expectedErr: true, calls: 1},
+	}
+
+	content := "this is the content of the photo"
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			fs := &flakyServer{failures: tst.failures, status: tst.status}
+			server := httptest.NewServer(fs)
+			defer server.Close()
+
+			ic, err := NewImmichClient(server.URL, "1234", OptionUploadRetries(3, time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ic.supportedMediaTypes = DefaultSupportedMedia
+
+			la := &browser.LocalAssetFile{
+				FSys:     fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte(content)}},
+				FileName: "photo.jpg",
+				Title:    "photo.jpg",
+				FileSize: len(content),
+			}
+			ar, err := ic.AssetUpload(context.Background(), la)
+			la.Close()
+			if tst.expectedErr != (err != nil) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if fs.calls != tst.calls {
+				t.Errorf("expected %d calls, got %d", tst.calls, fs.calls)
+			}
+			if !tst.expectedErr {
+				if ar.ID != "123" {
+					t.Errorf("unexpected response: %+v", ar)
+				}
+				if fs.received != content {
+					t.Errorf("the received file is corrupted: %q", fs.received)
+				}
+			}
+		})
+	}
+}
+
+func TestAssetUploadSidecar(t *testing.T) {
+	var sidecar, sidecarName string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		f, h, err := req.FormFile("sidecarData")
+		if err == nil {
+			b, _ := io.ReadAll(f)
+			sidecar, sidecarName = string(b), h.Filename
+		}
+		resp.WriteHeader(http.StatusCreated)
+		_, _ = resp.Write([]byte(`{"id":"123","status":"created"}`))
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic.supportedMediaTypes = DefaultSupportedMedia
+
+	fsys := fstest.MapFS{
+		"video.mp4": &fstest.MapFile{Data: []byte("video")},
+		"video.xmp": &fstest.MapFile{Data: []byte("<xmp/>")},
+	}
+	la := &browser.LocalAssetFile{
+		FSys:     fsys,
+		FileName: "video.mp4",
+		Title:    "video.mp4",
+		FileSize: 5,
+		SideCar:  metadata.SideCarFile{FSys: fsys, FileName: "video.xmp"},
+	}
+	_, err = ic.AssetUpload(context.Background(), la)
+	la.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sidecar != "<xmp/>" || sidecarName != "video.mp4.xmp" {
+		t.Errorf("unexpected sidecar %q: %q", sidecarName, sidecar)
+	}
+}
+
+// TestAssetUploadFromZip checks that the file of a zip archive is streamed with the length of the request
+func TestAssetUploadFromZip(t *testing.T) {
+	content := bytes.Repeat([]byte("this is the content of the video "), 100_000)
+	b := bytes.NewBuffer(nil)
+	zw := zip.NewWriter(b)
+	w, err := zw.Create("Takeout/Google Photos/video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(content)
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received []byte
+	var length, bodyLength int64
+	var chunked bool
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		length, chunked = req.ContentLength, len(req.TransferEncoding) > 0
+		body, _ := io.ReadAll(req.Body)
+		bodyLength = int64(len(body))
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		f, _, err := req.FormFile("assetData")
+		if err == nil {
+			received, _ = io.ReadAll(f)
+		}
+		resp.WriteHeader(http.StatusCreated)
+		_, _ = resp.Write([]byte(`{"id":"123","status":"created"}`))
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic.supportedMediaTypes = DefaultSupportedMedia
+	la := &browser.LocalAssetFile{
+		FSys:     zr,
+		FileName: "Takeout/Google Photos/video.mp4",
+		Title:    "video.mp4",
+		FileSize: len(content),
+		Metadata: metadata.Metadata{DateTaken: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	_, err = ic.AssetUpload(context.Background(), la)
+	la.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunked || length != bodyLength {
+		t.Errorf("the body isn't sent with its length: content length %d, body length %d, chunked %v", length, bodyLength, chunked)
+	}
+	if !bytes.Equal(received, content) {
+		t.Errorf("the received file is corrupted")
+	}
+}
+
+func TestAssetUploadExtraMedia(t *testing.T) {
+	var name, assetType string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		_, h, err := req.FormFile("assetData")
+		if err == nil {
+			name = h.Filename
+		}
+		assetType = req.FormValue("assetType")
+		resp.WriteHeader(http.StatusCreated)
+		_, _ = resp.Write([]byte(`{"id":"123","status":"created"}`))
+	}))
+	defer server.Close()
+
+	em := ExtraMedia{}
+	_ = em.Set(".lrv=.mp4")
+	ic, err := NewImmichClient(server.URL, "1234", OptionExtraMedia(em))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic.supportedMediaTypes = em.Apply(DefaultSupportedMedia)
+
+	fsys := fstest.MapFS{
+		"video.LRV": &fstest.MapFile{Data: []byte("video")},
+	}
+	la := &browser.LocalAssetFile{
+		FSys:     fsys,
+		FileName: "video.LRV",
+		Title:    "video.LRV",
+		FileSize: 5,
+	}
+	_, err = ic.AssetUpload(context.Background(), la)
+	la.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "video.LRV.mp4" || assetType != TypeVideo {
+		t.Errorf("unexpected upload of %q as %q", name, assetType)
+	}
+}
+
+func TestDownloadAsset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/assets/123/original" {
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = resp.Write([]byte("the photo"))
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ic.DownloadAsset(context.Background(), "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(b) != "the photo" {
+		t.Errorf("unexpected content %q, %v", b, err)
+	}
+	_, err = ic.DownloadAsset(context.Background(), "456")
+	if err == nil {
+		t.Errorf("expected an error for a missing asset")
+	}
+}
+
+func TestUnstack(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		calls = append(calls, req.Method+" "+req.URL.Path+" "+string(b))
+		resp.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.UnstackAssets(context.Background(), []string{"2", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.SetStackCover(context.Background(), "1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`PUT /api/assets {"ids":["2","3"],"removeParent":true}`,
+		`PUT /api/assets/stack/parent {"oldParentId":"1","newParentId":"2"}`,
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if strings.TrimSpace(calls[i]) != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], calls[i])
+		}
+	}
+}
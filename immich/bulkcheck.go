@@ -0,0 +1,38 @@
+package immich
+
+import "context"
+
+// AssetBulkUploadCheckItem gives the checksum of a local file to the server
+type AssetBulkUploadCheckItem struct {
+	ID       string `json:"id"`       // Client's identifier of the file
+	Checksum string `json:"checksum"` // SHA1 of the file, base64 or hex encoded
+}
+
+// AssetBulkUploadCheckResult tells if the server accepts the file
+type AssetBulkUploadCheckResult struct {
+	ID        string `json:"id"`
+	Action    string `json:"action"`
+	Reason    string `json:"reason,omitempty"`
+	AssetID   string `json:"assetId,omitempty"`
+	IsTrashed bool   `json:"isTrashed,omitempty"`
+}
+
+const (
+	BulkCheckAccept          = "accept"
+	BulkCheckReject          = "reject"
+	BulkCheckReasonDuplicate = "duplicate"
+)
+
+// CheckBulkUpload asks the server which files it already has, based on their checksums
+func (ic *ImmichClient) CheckBulkUpload(ctx context.Context, items []AssetBulkUploadCheckItem) ([]AssetBulkUploadCheckResult, error) {
+	req := struct {
+		Assets []AssetBulkUploadCheckItem `json:"assets"`
+	}{
+		Assets: items,
+	}
+	resp := struct {
+		Results []AssetBulkUploadCheckResult `json:"results"`
+	}{}
+	err := ic.newServerCall(ctx, EndPointCheckBulkUpload).do(postRequest("/assets/bulk-upload-check", "application/json", setAcceptJSON(), setJSONBody(req)), responseJSON(&resp))
+	return resp.Results, err
+}
@@ -29,6 +29,38 @@ const (
 	EndPointGetAssetStatistics     = "GetAssetStatistics"
 	EndPointGetSupportedMediaTypes = "GetSupportedMediaTypes"
 	EndPointGetAllAssets           = "GetAllAssets"
+	EndPointCheckBulkUpload        = "CheckBulkUpload"
+	EndPointUpdateAlbum            = "UpdateAlbum"
+	EndPointGetAllPeople           = "GetAllPeople"
+	EndPointCreatePerson           = "CreatePerson"
+	EndPointCreateFace             = "CreateFace"
+	EndPointUpdatePerson           = "UpdatePerson"
+	EndPointMergePeople            = "MergePeople"
+	EndPointDownloadAsset          = "DownloadAsset"
+	EndPointGetAssetPreview        = "GetAssetPreview"
+	EndPointUnstackAssets          = "UnstackAssets"
+	EndPointSetStackCover          = "SetStackCover"
+	EndPointGetAllTags             = "GetAllTags"
+	EndPointUpsertTags             = "UpsertTags"
+	EndPointTagAssets              = "TagAssets"
+	EndPointUntagAssets            = "UntagAssets"
+	EndPointUpdateAssetMetadata    = "UpdateAssetMetadata"
+	EndPointGetServerVersion       = "GetServerVersion"
+	EndPointGetServerFeatures      = "GetServerFeatures"
+	EndPointCreateStack            = "CreateStack"
+	EndPointGetAsset               = "GetAsset"
+	EndPointBatchUpdateAssets      = "BatchUpdateAssets"
+	EndPointRestoreAssets          = "RestoreAssets"
+	EndPointCreateSharedLink       = "CreateSharedLink"
+	EndPointGetAllLibraries        = "GetAllLibraries"
+	EndPointCreateLibrary          = "CreateLibrary"
+	EndPointScanLibrary            = "ScanLibrary"
+	EndPointGetAllUsers            = "GetAllUsers"
+	EndPointGetMemories            = "GetMemories"
+	EndPointGetTimeBuckets         = "GetTimeBuckets"
+	EndPointGetServerStorage       = "GetServerStorage"
+	EndPointOAuthAuthorize         = "OAuthAuthorize"
+	EndPointOAuthCallback          = "OAuthCallback"
 )
 
 type TooManyInternalError struct {
@@ -46,6 +78,9 @@ type serverCall struct {
 	ic       *ImmichClient
 	err      error
 	ctx      context.Context
+
+	throttled  bool          // the server has asked to slow down
+	retryAfter time.Duration // pause asked by the server
 }
 
 // callError represents errors returned by the server
@@ -56,6 +91,9 @@ type callError struct {
 	status   int
 	err      error
 	message  *ServerMessage
+
+	throttled  bool          // the server has asked to slow down
+	retryAfter time.Duration // pause asked by the server
 }
 
 type ServerMessage struct {
@@ -122,6 +160,7 @@ func (sc *serverCall) Err(req *http.Request, resp *http.Response, msg *ServerMes
 		ce.status = resp.StatusCode
 	}
 	ce.message = msg
+	ce.throttled, ce.retryAfter = sc.throttled, sc.retryAfter
 	return ce
 }
 
@@ -190,6 +229,15 @@ func putRequest(url string, opts ...serverRequestOption) requestFunction {
 	}
 }
 
+func patchRequest(url string, opts ...serverRequestOption) requestFunction {
+	return func(sc *serverCall) *http.Request {
+		if sc.err != nil {
+			return nil
+		}
+		return sc.request(http.MethodPatch, sc.ic.endPoint+url, opts...)
+	}
+}
+
 func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption) error {
 	var (
 		resp *http.Response
@@ -205,11 +253,35 @@ func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption
 		_ = sc.joinError(setTraceRequest()(sc, req))
 	}
 
-	resp, err = sc.ic.client.Do(req)
-	// any non nil error must be returned
-	if err != nil {
-		_ = sc.joinError(err)
-		return sc.Err(req, nil, nil)
+	for attempt := 1; ; attempt++ {
+		err = sc.ic.throttle.wait(sc.ctx)
+		if err == nil {
+			resp, err = sc.ic.client.Do(req)
+		}
+		// any non nil error must be returned
+		if err != nil {
+			_ = sc.joinError(err)
+			return sc.Err(req, nil, nil)
+		}
+		wait, throttled := retryAfter(resp, time.Now())
+		if !throttled {
+			break
+		}
+		// the server is overloaded: pause, and send the call again when its body can be read again
+		sc.throttled, sc.retryAfter = true, wait
+		sc.ic.throttle.throttled(sc.endPoint, wait)
+		next, ok := rewind(req)
+		if attempt > maxThrottleRetries || !ok {
+			break
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		err = sleep(sc.ctx, wait)
+		if err != nil {
+			_ = sc.joinError(err)
+			return sc.Err(req, nil, nil)
+		}
+		req = next
 	}
 
 	// Any StatusCode above 300 denotes a problem
@@ -238,9 +310,11 @@ func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption
 
 type serverRequestOption func(sc *serverCall, req *http.Request) error
 
-func setBody(body io.ReadCloser) serverRequestOption {
+// setStreamBody sends the body read from r, with the given length
+func setStreamBody(r io.Reader, length int64) serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
-		req.Body = body
+		req.Body = io.NopCloser(r)
+		req.ContentLength = length
 		return nil
 	}
 }
@@ -252,8 +326,13 @@ func setAcceptJSON() serverRequestOption {
 	}
 }
 
+// setAPIKey authenticates the request with the session token obtained by the OAuth login, or with the API key
 func setAPIKey() serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
+		if sc.ic.accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+sc.ic.accessToken)
+			return nil
+		}
 		req.Header.Set("x-api-key", sc.ic.key)
 		return nil
 	}
@@ -270,7 +349,10 @@ func setJSONBody(object any) serverRequestOption {
 		if err != nil {
 			return err
 		}
-		req.Body = io.NopCloser(b)
+		data := b.Bytes()
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+		req.ContentLength = int64(len(data))
 		req.Header.Set("Content-Type", "application/json")
 		return err
 	}
@@ -311,6 +393,17 @@ func responseJSON[T any](object *T) serverResponseOption {
 	}
 }
 
+// responseBody gives the body of the response to the caller, who must close it
+func responseBody(body *io.ReadCloser) serverResponseOption {
+	return func(sc *serverCall, resp *http.Response) error {
+		if resp == nil || resp.Body == nil {
+			return errors.New("no response body")
+		}
+		*body = resp.Body
+		return nil
+	}
+}
+
 func responseCopy(buffer *bytes.Buffer) serverResponseOption {
 	return func(sc *serverCall, resp *http.Response) error {
 		if resp != nil {
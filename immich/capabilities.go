@@ -0,0 +1,107 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MinServerVersion is the oldest server version immich-go works with
+var MinServerVersion = ServerVersion{Major: 1, Minor: 106, Patch: 0}
+
+var (
+	stacksAPIVersion = ServerVersion{Major: 1, Minor: 112, Patch: 0} // the stacks have their own endpoints /stacks
+	tagsAPIVersion   = ServerVersion{Major: 1, Minor: 113, Patch: 0} // the tags are available
+)
+
+// AtLeast tells if the version is v or a newer one
+func (v ServerVersion) AtLeast(o ServerVersion) bool {
+	if v.Major != o.Major {
+		return v.Major > o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor > o.Minor
+	}
+	return v.Patch >= o.Patch
+}
+
+// IsZero tells if the version is unknown
+func (v ServerVersion) IsZero() bool {
+	return v == ServerVersion{}
+}
+
+// ServerFeatures are the features enabled on the server
+type ServerFeatures struct {
+	SmartSearch        bool `json:"smartSearch"`
+	FacialRecognition  bool `json:"facialRecognition"`
+	DuplicateDetection bool `json:"duplicateDetection"`
+	Map                bool `json:"map"`
+	ReverseGeocoding   bool `json:"reverseGeocoding"`
+	Sidecar            bool `json:"sidecar"`
+	Search             bool `json:"search"`
+	Trash              bool `json:"trash"`
+	OAuth              bool `json:"oauth"`
+	PasswordLogin      bool `json:"passwordLogin"`
+}
+
+// ServerCapabilities gives the version and the features of the server, to select the endpoints it understands.
+// The zero value stands for a server that hasn't been queried, the endpoints of the minimal version are used.
+type ServerCapabilities struct {
+	Version  ServerVersion
+	Features ServerFeatures
+}
+
+// Tags tells if the server manages the tags, a server not queried is supposed to do so
+func (c ServerCapabilities) Tags() bool {
+	return c.Version.IsZero() || c.Version.AtLeast(tagsAPIVersion)
+}
+
+// StackEndpoints tells if the stacks are managed with the endpoints /stacks instead of the asset's stack parent
+func (c ServerCapabilities) StackEndpoints() bool {
+	return !c.Version.IsZero() && c.Version.AtLeast(stacksAPIVersion)
+}
+
+// Trash tells if the deleted assets go to the trash, a server not queried is supposed to have it
+func (c ServerCapabilities) Trash() bool {
+	return c.Version.IsZero() || c.Features.Trash
+}
+
+// Capabilities gives the capabilities of the server found by NegotiateServer
+func (ic *ImmichClient) Capabilities() ServerCapabilities {
+	return ic.capabilities
+}
+
+// NegotiateServer queries the version and the features of the server, and selects the endpoints accordingly.
+// It fails when the server is too old for immich-go.
+func (ic *ImmichClient) NegotiateServer(ctx context.Context) (ServerCapabilities, error) {
+	var c ServerCapabilities
+	v, err := ic.GetServerVersion(ctx)
+	if err != nil {
+		if !isNotFound(err) {
+			return c, err
+		}
+		// the servers older than v1.106 answer on /server-info
+		err = ic.newServerCall(ctx, EndPointGetServerVersion).do(getRequest("/server-info/version", setAcceptJSON()), responseJSON(&v))
+		if err != nil {
+			return c, fmt.Errorf("can't get the server version, immich-go needs the version %s or newer: %w", MinServerVersion, err)
+		}
+	}
+	if !v.AtLeast(MinServerVersion) {
+		return c, fmt.Errorf("the server version %s is too old, immich-go needs the version %s or newer", v, MinServerVersion)
+	}
+	c.Version = v
+
+	err = ic.newServerCall(ctx, EndPointGetServerFeatures).do(getRequest("/server/features", setAcceptJSON()), responseJSON(&c.Features))
+	if err != nil {
+		return c, err
+	}
+	ic.capabilities = c
+	return c, nil
+}
+
+// isNotFound tells if the server doesn't know the endpoint
+func isNotFound(err error) bool {
+	var ce callError
+	return errors.As(err, &ce) && ce.status == http.StatusNotFound
+}
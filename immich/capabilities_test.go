@@ -0,0 +1,148 @@
+package immich
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		routes     map[string]string // path -> response, the other paths answer 404
+		expected   ServerVersion
+		tags       bool
+		stacks     bool
+		errMessage string
+	}{
+		{
+			name: "recent server",
+			routes: map[string]string{
+				"/api/server/version":  `{"major":1,"minor":118,"patch":2}`,
+				"/api/server/features": `{"smartSearch":true,"trash":true}`,
+			},
+			expected: ServerVersion{Major: 1, Minor: 118, Patch: 2},
+			tags:     true,
+			stacks:   true,
+		},
+		{
+			name: "server without the stacks endpoints",
+			routes: map[string]string{
+				"/api/server/version":  `{"major":1,"minor":106,"patch":4}`,
+				"/api/server/features": `{}`,
+			},
+			expected: ServerVersion{Major: 1, Minor: 106, Patch: 4},
+		},
+		{
+			name: "server too old",
+			routes: map[string]string{
+				"/api/server/version": `{"major":1,"minor":105,"patch":1}`,
+			},
+			errMessage: "the server version v1.105.1 is too old, immich-go needs the version v1.106.0 or newer",
+		},
+		{
+			name: "legacy server",
+			routes: map[string]string{
+				"/api/server-info/version": `{"major":1,"minor":98,"patch":0}`,
+			},
+			errMessage: "the server version v1.98.0 is too old, immich-go needs the version v1.106.0 or newer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+				r, ok := tt.routes[req.URL.Path]
+				if !ok {
+					resp.WriteHeader(http.StatusNotFound)
+					_, _ = resp.Write([]byte(`{"message":"Cannot GET ` + req.URL.Path + `","error":"Not Found","statusCode":404}`))
+					return
+				}
+				_, _ = resp.Write([]byte(r))
+			}))
+			defer server.Close()
+
+			ic, err := NewImmichClient(server.URL, "1234")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := ic.NegotiateServer(context.Background())
+			if tt.errMessage != "" {
+				if err == nil || err.Error() != tt.errMessage {
+					t.Fatalf("expected the error %q, got %v", tt.errMessage, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Version != tt.expected {
+				t.Errorf("expected the version %s, got %s", tt.expected, c.Version)
+			}
+			if c.Tags() != tt.tags {
+				t.Errorf("expected tags %v, got %v", tt.tags, c.Tags())
+			}
+			if c.StackEndpoints() != tt.stacks {
+				t.Errorf("expected stack endpoints %v, got %v", tt.stacks, c.StackEndpoints())
+			}
+			if ic.Capabilities() != c {
+				t.Errorf("the capabilities aren't kept by the client")
+			}
+		})
+	}
+}
+
+func TestStackEndpoints(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		calls = append(calls, strings.TrimSpace(req.Method+" "+req.URL.Path+" "+strings.TrimSpace(string(b))))
+		switch req.URL.Path {
+		case "/api/server/version":
+			_, _ = resp.Write([]byte(`{"major":1,"minor":118,"patch":2}`))
+		case "/api/server/features":
+			_, _ = resp.Write([]byte(`{}`))
+		case "/api/assets/1", "/api/assets/2":
+			_, _ = resp.Write([]byte(`{"id":"x","stack":{"id":"s1","primaryAssetId":"1","assetCount":3}}`))
+		default:
+			_, _ = resp.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ic.NegotiateServer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls = nil
+	err = ic.StackAssets(ctx, "1", []string{"2", "1", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.SetStackCover(ctx, "1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.UnstackAssets(ctx, []string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`POST /api/stacks {"assetIds":["1","2","3"]}`,
+		`GET /api/assets/1`,
+		`PUT /api/stacks/s1 {"primaryAssetId":"2"}`,
+		`GET /api/assets/1`,
+		`GET /api/assets/2`,
+		`DELETE /api/stacks {"ids":["s1"]}`,
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(calls, "\n"))
+	}
+}
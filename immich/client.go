@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"slices"
 	"sort"
@@ -26,11 +28,15 @@ type ImmichClient struct {
 	roundTripper        *http.Transport
 	endPoint            string        // Server API url
 	key                 string        // User KEY
+	accessToken         string        // Session token given by the OAuth login, used instead of the key
 	DeviceUUID          string        // Device
-	Retries             int           // Number of attempts on 500 errors
+	Retries             int           // Number of attempts on connection and 500 errors
 	RetriesDelay        time.Duration // Duration between retries
 	apiTraceWriter      io.Writer
-	supportedMediaTypes SupportedMedia // Server's list of supported medias
+	supportedMediaTypes SupportedMedia     // Server's list of supported medias
+	extraMedia          ExtraMedia         // Extensions added to the server's list
+	capabilities        ServerCapabilities // Server's version and features, given by NegotiateServer
+	throttle            throttle           // Pauses asked by the server
 }
 
 func (ic *ImmichClient) SetEndPoint(endPoint string) {
@@ -41,6 +47,54 @@ func (ic *ImmichClient) SetDeviceUUID(deviceUUID string) {
 	ic.DeviceUUID = deviceUUID
 }
 
+// SetClientCertificate presents the certificate to the servers requiring mutual TLS
+func (ic *ImmichClient) SetClientCertificate(cert tls.Certificate) {
+	ic.roundTripper.TLSClientConfig.Certificates = []tls.Certificate{cert}
+}
+
+// SetRootCAs sets the certificate authorities verifying the server's certificate
+func (ic *ImmichClient) SetRootCAs(pool *x509.CertPool) {
+	ic.roundTripper.TLSClientConfig.RootCAs = pool
+}
+
+// SetTLSServerName sets the name sent and verified during the TLS handshake, instead of the host name of the server's address
+func (ic *ImmichClient) SetTLSServerName(name string) {
+	ic.roundTripper.TLSClientConfig.ServerName = name
+}
+
+// SetMinTLSVersion sets the minimum TLS version accepted, ex: tls.VersionTLS13
+func (ic *ImmichClient) SetMinTLSVersion(version uint16) {
+	ic.roundTripper.TLSClientConfig.MinVersion = version
+}
+
+// SetProxy sends the calls through the proxy instead of the one given by the environment variables HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+// The proxy is http://, https://, socks5:// or socks5h://, with the credentials in the address if needed.
+func (ic *ImmichClient) SetProxy(proxy *url.URL) {
+	ic.roundTripper.Proxy = http.ProxyURL(proxy)
+}
+
+// SetHTTP2 negotiates HTTP/2 with the servers reached by HTTPS, or restricts the calls to HTTP/1.1
+func (ic *ImmichClient) SetHTTP2(enable bool) {
+	ic.roundTripper.ForceAttemptHTTP2 = enable
+	if enable {
+		ic.roundTripper.TLSNextProto = nil
+	} else {
+		ic.roundTripper.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+}
+
+// SetConnectionPool sets the number of connections opened to the server, 0 for no limit, and the number of idle connections kept open
+func (ic *ImmichClient) SetConnectionPool(maxConnsPerHost, maxIdleConns int) {
+	ic.roundTripper.MaxConnsPerHost = maxConnsPerHost
+	ic.roundTripper.MaxIdleConns = maxIdleConns
+	ic.roundTripper.MaxIdleConnsPerHost = maxIdleConns
+}
+
+// SetKeepAlive keeps the connections open between the calls, or closes them after each call
+func (ic *ImmichClient) SetKeepAlive(keep bool) {
+	ic.roundTripper.DisableKeepAlives = !keep
+}
+
 func (ic *ImmichClient) EnableAppTrace(w io.Writer) {
 	ic.apiTraceWriter = w
 }
@@ -65,6 +119,16 @@ func OptionConnectionTimeout(d time.Duration) clientOption {
 	}
 }
 
+// OptionUploadRetries sets the number of attempts to upload a file when the connection fails,
+// the delay between attempts grows with each attempt
+func OptionUploadRetries(retries int, delay time.Duration) clientOption {
+	return func(ic *ImmichClient) error {
+		ic.Retries = max(retries, 1)
+		ic.RetriesDelay = delay
+		return nil
+	}
+}
+
 // Create a new ImmichClient
 func NewImmichClient(endPoint string, key string, options ...clientOption) (*ImmichClient, error) {
 	var err error
@@ -82,6 +146,7 @@ func NewImmichClient(endPoint string, key string, options ...clientOption) (*Imm
 	ic := ImmichClient{
 		endPoint: endPoint + "/api",
 		roundTripper: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
 			MaxIdleConns:        100,
 			IdleConnTimeout:     90 * time.Second,
 			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
@@ -123,6 +188,14 @@ func (ic *ImmichClient) PingServer(ctx context.Context) error {
 	return nil
 }
 
+// OptionExtraMedia adds extensions to the list of media supported by the server
+func OptionExtraMedia(em ExtraMedia) clientOption {
+	return func(ic *ImmichClient) error {
+		ic.extraMedia = em
+		return nil
+	}
+}
+
 // ValidateConnection
 // Validate the connection by querying the identity of the user having the given key
 
@@ -139,7 +212,7 @@ func (ic *ImmichClient) ValidateConnection(ctx context.Context) (User, error) {
 	if err != nil {
 		return user, err
 	}
-	ic.supportedMediaTypes = sm
+	ic.supportedMediaTypes = ic.extraMedia.Apply(sm)
 	return user, nil
 }
 
@@ -182,6 +255,37 @@ func (ic *ImmichClient) GetAssetStatistics(ctx context.Context) (UserStatistics,
 	return s, err
 }
 
+// ServerVersion is the version of the immich server
+type ServerVersion struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+func (v ServerVersion) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func (ic *ImmichClient) GetServerVersion(ctx context.Context) (ServerVersion, error) {
+	var v ServerVersion
+	err := ic.newServerCall(ctx, EndPointGetServerVersion).do(getRequest("/server/version", setAcceptJSON()), responseJSON(&v))
+	return v, err
+}
+
+// ServerStorage is the usage of the disk of the server
+type ServerStorage struct {
+	DiskSizeRaw         int64   `json:"diskSizeRaw"`
+	DiskUseRaw          int64   `json:"diskUseRaw"`
+	DiskAvailableRaw    int64   `json:"diskAvailableRaw"`
+	DiskUsagePercentage float64 `json:"diskUsagePercentage"`
+}
+
+func (ic *ImmichClient) GetServerStorage(ctx context.Context) (ServerStorage, error) {
+	var s ServerStorage
+	err := ic.newServerCall(ctx, EndPointGetServerStorage).do(getRequest("/server/storage", setAcceptJSON()), responseJSON(&s))
+	return s, err
+}
+
 type SupportedMedia map[string]string
 
 const (
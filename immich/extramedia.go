@@ -0,0 +1,81 @@
+package immich
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ExtraMedia registers file extensions missing in the list of supported media,
+// like the files of niche cameras.
+//
+// The extension is given with its type (.insp=image, .lrv=video), or with the extension of
+// a supported media the file is uploaded as (.lrv=.mp4). In this case the extension
+// is added to the file name, like for the .MP files.
+type ExtraMedia map[string]string
+
+func (em *ExtraMedia) Set(s string) error {
+	ext, typ, ok := strings.Cut(s, "=")
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if !ok || ext == "" || typ == "" {
+		return fmt.Errorf("invalid media type %q, expecting .EXT=image, .EXT=video or .EXT=.SUPPORTED_EXT", s)
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	switch {
+	case typ == TypeImage, typ == TypeVideo:
+	case strings.HasPrefix(typ, ".") && len(typ) > 1:
+		if typ == ext {
+			return fmt.Errorf("invalid media type %q, the extension can't be uploaded as itself", s)
+		}
+	default:
+		return fmt.Errorf("invalid media type %q, the type must be image, video or a supported extension", s)
+	}
+	if *em == nil {
+		*em = ExtraMedia{}
+	}
+	(*em)[ext] = typ
+	return nil
+}
+
+func (em ExtraMedia) String() string {
+	l := make([]string, 0, len(em))
+	for ext, typ := range em {
+		l = append(l, ext+"="+typ)
+	}
+	slices.Sort(l)
+	return strings.Join(l, ",")
+}
+
+// Apply returns a copy of the supported media completed with the extra extensions.
+// An extension uploaded as an unsupported one is ignored.
+func (em ExtraMedia) Apply(sm SupportedMedia) SupportedMedia {
+	if len(em) == 0 {
+		return sm
+	}
+	r := make(SupportedMedia, len(sm)+len(em))
+	for ext, typ := range sm {
+		r[ext] = typ
+	}
+	for ext, typ := range em {
+		if strings.HasPrefix(typ, ".") {
+			typ = sm.TypeFromExt(typ)
+			if typ != TypeImage && typ != TypeVideo {
+				continue
+			}
+		}
+		r[ext] = typ
+	}
+	return r
+}
+
+// UploadExt gives the extension the file is uploaded as, or "" when the file keeps its extension
+func (em ExtraMedia) UploadExt(ext string) string {
+	typ := em[strings.ToLower(ext)]
+	if strings.HasPrefix(typ, ".") {
+		return typ
+	}
+	return ""
+}
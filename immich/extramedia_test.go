@@ -0,0 +1,68 @@
+package immich
+
+import "testing"
+
+func TestExtraMedia_Set(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{values: []string{".insp=image"}, want: ".insp=image"},
+		{values: []string{"LRV=Video", ".dng=image"}, want: ".dng=image,.lrv=video"},
+		{values: []string{".lrv=.mp4"}, want: ".lrv=.mp4"},
+		{values: []string{".lrv"}, wantErr: true},
+		{values: []string{".lrv=sound"}, wantErr: true},
+		{values: []string{".lrv=.lrv"}, wantErr: true},
+		{values: []string{"=image"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		var em ExtraMedia
+		var err error
+		for _, v := range tt.values {
+			err = em.Set(v)
+			if err != nil {
+				break
+			}
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%v) error = %v, wantErr %v", tt.values, err, tt.wantErr)
+			continue
+		}
+		if err == nil && em.String() != tt.want {
+			t.Errorf("Set(%v) = %q, want %q", tt.values, em.String(), tt.want)
+		}
+	}
+}
+
+func TestExtraMedia_Apply(t *testing.T) {
+	em := ExtraMedia{}
+	for _, v := range []string{".lrv=.mp4", ".thm=.unknown", ".sound=video", ".jpg=video"} {
+		if err := em.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm := em.Apply(DefaultSupportedMedia)
+	tests := []struct {
+		ext       string
+		want      string
+		uploadExt string
+	}{
+		{ext: ".LRV", want: TypeVideo, uploadExt: ".mp4"},
+		{ext: ".thm", want: TypeUnknown, uploadExt: ".unknown"},
+		{ext: ".sound", want: TypeVideo},
+		{ext: ".jpg", want: TypeVideo},
+		{ext: ".heic", want: TypeImage},
+	}
+	for _, tt := range tests {
+		if got := sm.TypeFromExt(tt.ext); got != tt.want {
+			t.Errorf("TypeFromExt(%s) = %q, want %q", tt.ext, got, tt.want)
+		}
+		if got := em.UploadExt(tt.ext); got != tt.uploadExt {
+			t.Errorf("UploadExt(%s) = %q, want %q", tt.ext, got, tt.uploadExt)
+		}
+	}
+	if DefaultSupportedMedia.TypeFromExt(".lrv") != TypeUnknown {
+		t.Error("the default supported media is modified")
+	}
+}
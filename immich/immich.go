@@ -22,22 +22,54 @@ type ImmichInterface interface {
 	ValidateConnection(ctx context.Context) (User, error)
 	GetServerStatistics(ctx context.Context) (ServerStatistics, error)
 	GetAssetStatistics(ctx context.Context) (UserStatistics, error)
+	GetServerVersion(ctx context.Context) (ServerVersion, error)
+	GetServerStorage(ctx context.Context) (ServerStorage, error)
+	GetTimeBuckets(ctx context.Context) ([]TimeBucket, error)
 
 	UpdateAsset(ctx context.Context, ID string, a *browser.LocalAssetFile) (*Asset, error)
 	GetAllAssets(ctx context.Context) ([]*Asset, error)
 	AddAssetToAlbum(context.Context, string, []string) ([]UpdateAlbumResult, error)
 	UpdateAssets(ctx context.Context, IDs []string, isArchived bool, isFavorite bool, latitude float64, longitude float64, removeParent bool, stackParentID string) error
+	BatchUpdateAssets(ctx context.Context, IDs []string, update AssetsUpdate) error
 	GetAllAssetsWithFilter(context.Context, func(*Asset) error) error
+	SearchAssets(ctx context.Context, q SearchQuery, filter func(*Asset) error) error
 	AssetUpload(context.Context, *browser.LocalAssetFile) (AssetResponse, error)
+	CheckBulkUpload(ctx context.Context, items []AssetBulkUploadCheckItem) ([]AssetBulkUploadCheckResult, error)
 	DeleteAssets(context.Context, []string, bool) error
+	RestoreAssets(ctx context.Context, IDs []string) error
+	DownloadAsset(ctx context.Context, id string) (io.ReadCloser, error)
+	GetAssetPreview(ctx context.Context, id string) (io.ReadCloser, error)
+	UpdateAssetMetadata(ctx context.Context, id string, update AssetMetadataUpdate) error
 
 	GetAllAlbums(ctx context.Context) ([]AlbumSimplified, error)
 	GetAlbumInfo(ctx context.Context, id string, withoutAssets bool) (AlbumContent, error)
 	CreateAlbum(ctx context.Context, tilte string, description string, ids []string) (AlbumSimplified, error)
 	GetAssetAlbums(ctx context.Context, ID string) ([]AlbumSimplified, error)
 	DeleteAlbum(ctx context.Context, id string) error
+	UpdateAlbum(ctx context.Context, id string, update AlbumUpdate) (AlbumSimplified, error)
+	CreateSharedLink(ctx context.Context, link SharedLinkCreate) (SharedLink, error)
 
 	StackAssets(ctx context.Context, cover string, IDs []string) error
+	UnstackAssets(ctx context.Context, IDs []string) error
+	SetStackCover(ctx context.Context, oldCover string, newCover string) error
+
+	GetAllTags(ctx context.Context) ([]Tag, error)
+	UpsertTags(ctx context.Context, names []string) ([]Tag, error)
+	TagAssets(ctx context.Context, tagID string, IDs []string) ([]UpdateAlbumResult, error)
+	UntagAssets(ctx context.Context, tagID string, IDs []string) ([]UpdateAlbumResult, error)
+
+	GetAllPeople(ctx context.Context) ([]Person, error)
+	CreatePerson(ctx context.Context, name string) (Person, error)
+	CreateFace(ctx context.Context, face Face) error
+	UpdatePerson(ctx context.Context, id string, name string) (Person, error)
+	MergePeople(ctx context.Context, id string, IDs []string) ([]UpdateAlbumResult, error)
+
+	GetAllLibraries(ctx context.Context) ([]Library, error)
+	CreateLibrary(ctx context.Context, library LibraryCreate) (Library, error)
+	ScanLibrary(ctx context.Context, id string) error
+	GetAllUsers(ctx context.Context) ([]User, error)
+
+	GetOnThisDayMemories(ctx context.Context, day time.Time) ([]Memory, error)
 
 	SupportedMedia() SupportedMedia
 	GetJobs(ctx context.Context) (map[string]Job, error)
@@ -63,6 +95,7 @@ type PingResponse struct {
 type User struct {
 	ID                   string    `json:"id"`
 	Email                string    `json:"email"`
+	Name                 string    `json:"name"`
 	FirstName            string    `json:"firstName"`
 	LastName             string    `json:"lastName"`
 	StorageLabel         string    `json:"storageLabel"`
@@ -74,6 +107,8 @@ type User struct {
 	DeletedAt            time.Time `json:"deletedAt"`
 	UpdatedAt            time.Time `json:"updatedAt"`
 	OauthID              string    `json:"oauthId"`
+	QuotaSizeInBytes     *int64    `json:"quotaSizeInBytes"` // nil when the user has no quota
+	QuotaUsageInBytes    int64     `json:"quotaUsageInBytes"`
 }
 
 type List[T comparable] struct {
@@ -134,19 +169,39 @@ type Asset struct {
 	FileCreatedAt    ImmichTime        `json:"fileCreatedAt"`
 	FileModifiedAt   ImmichTime        `json:"fileModifiedAt"`
 	UpdatedAt        ImmichTime        `json:"updatedAt"`
+	CreatedAt        ImmichTime        `json:"createdAt"` // upload date, given by the recent servers
 	IsFavorite       bool              `json:"isFavorite"`
 	IsArchived       bool              `json:"isArchived"`
 	IsTrashed        bool              `json:"isTrashed"`
 	Duration         string            `json:"duration"`
 	ExifInfo         ExifInfo          `json:"exifInfo"`
 	LivePhotoVideoID string            `json:"livePhotoVideoId"`
-	Tags             []any             `json:"tags"`
+	Tags             []Tag             `json:"tags"`
 	Checksum         string            `json:"checksum"`
 	StackParentID    string            `json:"stackParentId"`
+	Stack            *AssetStack       `json:"stack"` // given by the servers having the endpoints /stacks
 	JustUploaded     bool              `json:"-"`
 	Albums           []AlbumSimplified `json:"-"` // Albums that asset belong to
 }
 
+// AssetStack is the stack of an asset
+type AssetStack struct {
+	ID             string `json:"id"`
+	PrimaryAssetID string `json:"primaryAssetId"`
+	AssetCount     int    `json:"assetCount"`
+}
+
+// StackCoverID gives the cover of the stack, when the asset is stacked under another one
+func (a *Asset) StackCoverID() string {
+	if a.StackParentID != "" {
+		return a.StackParentID
+	}
+	if a.Stack != nil && a.Stack.PrimaryAssetID != a.ID {
+		return a.Stack.PrimaryAssetID
+	}
+	return ""
+}
+
 type ExifInfo struct {
 	Make             string     `json:"make"`
 	Model            string     `json:"model"`
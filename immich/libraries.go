@@ -0,0 +1,58 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Library is an external library: the server imports the files of its folders without uploading them
+type Library struct {
+	ID                string     `json:"id"`
+	OwnerID           string     `json:"ownerId"`
+	Name              string     `json:"name"`
+	ImportPaths       []string   `json:"importPaths"`
+	ExclusionPatterns []string   `json:"exclusionPatterns"`
+	AssetCount        int        `json:"assetCount"`
+	RefreshedAt       *time.Time `json:"refreshedAt"` // nil when the library has never been scanned
+}
+
+// LibraryCreate gives the parameters of a new external library
+type LibraryCreate struct {
+	OwnerID           string   `json:"ownerId"`
+	Name              string   `json:"name,omitempty"`
+	ImportPaths       []string `json:"importPaths"`
+	ExclusionPatterns []string `json:"exclusionPatterns"`
+}
+
+// GetAllLibraries gives the external libraries, only the administrators can read them
+func (ic *ImmichClient) GetAllLibraries(ctx context.Context) ([]Library, error) {
+	var r []Library
+	err := ic.newServerCall(ctx, EndPointGetAllLibraries).do(getRequest("/libraries", setAcceptJSON()), responseJSON(&r))
+	return r, err
+}
+
+// CreateLibrary creates an external library, the folders are paths seen by the server
+func (ic *ImmichClient) CreateLibrary(ctx context.Context, library LibraryCreate) (Library, error) {
+	var r Library
+	if library.ExclusionPatterns == nil {
+		library.ExclusionPatterns = []string{}
+	}
+	err := ic.newServerCall(ctx, EndPointCreateLibrary).do(
+		postRequest("/libraries", "application/json", setAcceptJSON(), setJSONBody(library)),
+		responseJSON(&r))
+	return r, err
+}
+
+// ScanLibrary asks the server to scan the folders of the library, the scan runs in the background
+func (ic *ImmichClient) ScanLibrary(ctx context.Context, id string) error {
+	return ic.newServerCall(ctx, EndPointScanLibrary).do(
+		postRequest(fmt.Sprintf("/libraries/%s/scan", id), "application/json", setAcceptJSON(), setJSONBody(struct{}{})))
+}
+
+// GetAllUsers gives the users of the server, only the administrators can read them
+func (ic *ImmichClient) GetAllUsers(ctx context.Context) ([]User, error) {
+	var r []User
+	err := ic.newServerCall(ctx, EndPointGetAllUsers).do(getRequest("/admin/users", setAcceptJSON()), responseJSON(&r))
+	return r, err
+}
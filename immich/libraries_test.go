@@ -0,0 +1,61 @@
+package immich
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLibraries(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		calls = append(calls, strings.TrimSpace(req.Method+" "+req.URL.Path+" "+strings.TrimSpace(string(b))))
+		switch req.URL.Path {
+		case "/api/libraries":
+			if req.Method == http.MethodPost {
+				_, _ = resp.Write([]byte(`{"id":"l1","name":"family","ownerId":"u1","importPaths":["/mnt/family"]}`))
+				return
+			}
+			_, _ = resp.Write([]byte(`[{"id":"l1","name":"family","assetCount":12,"refreshedAt":null}]`))
+		default:
+			resp.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ic.CreateLibrary(ctx, LibraryCreate{OwnerID: "u1", Name: "family", ImportPaths: []string{"/mnt/family"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.ID != "l1" {
+		t.Errorf("unexpected library %+v", l)
+	}
+	err = ic.ScanLibrary(ctx, l.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	libraries, err := ic.GetAllLibraries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(libraries) != 1 || libraries[0].AssetCount != 12 || libraries[0].RefreshedAt != nil {
+		t.Errorf("unexpected libraries %+v", libraries)
+	}
+	expected := []string{
+		`POST /api/libraries {"ownerId":"u1","name":"family","importPaths":["/mnt/family"],"exclusionPatterns":[]}`,
+		`POST /api/libraries/l1/scan {}`,
+		`GET /api/libraries`,
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(calls, "\n"))
+	}
+}
@@ -0,0 +1,101 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// memoriesAPIVersion is the first version generating the "on this day" memories, given by /memories
+var memoriesAPIVersion = ServerVersion{Major: 1, Minor: 124, Patch: 0}
+
+// MemoryTypeOnThisDay is the type of the memories giving the assets taken the same day of the previous years
+const MemoryTypeOnThisDay = "on_this_day"
+
+// Memory is a group of assets proposed by the server, ex: the photos taken on this day N years ago
+type Memory struct {
+	ID       string     `json:"id"`
+	Type     string     `json:"type"`
+	MemoryAt time.Time  `json:"memoryAt"`
+	ShowAt   *time.Time `json:"showAt"` // the memory is shown from this time
+	HideAt   *time.Time `json:"hideAt"` // until this time
+	Data     struct {
+		Year int `json:"year"` // year of the assets
+	} `json:"data"`
+	Assets []*Asset `json:"assets"`
+}
+
+// Memories tells if the server generates the "on this day" memories, a server not queried is supposed to do so
+func (c ServerCapabilities) Memories() bool {
+	return c.Version.IsZero() || c.Version.AtLeast(memoriesAPIVersion)
+}
+
+// GetOnThisDayMemories gives the assets taken on the same day as the day of the previous years,
+// the most recent year first.
+func (ic *ImmichClient) GetOnThisDayMemories(ctx context.Context, day time.Time) ([]Memory, error) {
+	var memories []Memory
+	var err error
+	if ic.capabilities.Memories() {
+		memories, err = ic.getMemories(ctx, day)
+	} else {
+		memories, err = ic.getMemoryLane(ctx, day)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(memories, func(i, j int) bool { return memories[i].Data.Year > memories[j].Data.Year })
+	return memories, nil
+}
+
+// getMemories reads the memories of the server, and keeps the ones shown on the day
+func (ic *ImmichClient) getMemories(ctx context.Context, day time.Time) ([]Memory, error) {
+	var r []Memory
+	q := url.Values{}
+	q.Set("type", MemoryTypeOnThisDay)
+	q.Set("for", time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).Format(time.RFC3339))
+	err := ic.newServerCall(ctx, EndPointGetMemories).do(getRequest("/memories?"+q.Encode(), setAcceptJSON()), responseJSON(&r))
+	if err != nil {
+		return nil, err
+	}
+	var memories []Memory
+	for _, m := range r {
+		if m.Type != MemoryTypeOnThisDay || len(m.Assets) == 0 {
+			continue
+		}
+		if m.ShowAt != nil && m.HideAt != nil {
+			if day.Before(*m.ShowAt) || !day.Before(*m.HideAt) {
+				continue
+			}
+		} else if m.MemoryAt.Month() != day.Month() || m.MemoryAt.Day() != day.Day() {
+			continue
+		}
+		memories = append(memories, m)
+	}
+	return memories, nil
+}
+
+// getMemoryLane reads the memories of the servers older than v1.124
+func (ic *ImmichClient) getMemoryLane(ctx context.Context, day time.Time) ([]Memory, error) {
+	var r []struct {
+		YearsAgo int      `json:"yearsAgo"`
+		Assets   []*Asset `json:"assets"`
+	}
+	err := ic.newServerCall(ctx, EndPointGetMemories).do(
+		getRequest(fmt.Sprintf("/assets/memory-lane?day=%d&month=%d", day.Day(), int(day.Month())), setAcceptJSON()),
+		responseJSON(&r))
+	if err != nil {
+		return nil, err
+	}
+	var memories []Memory
+	for _, l := range r {
+		if len(l.Assets) == 0 {
+			continue
+		}
+		m := Memory{Type: MemoryTypeOnThisDay, MemoryAt: day, Assets: l.Assets}
+		m.Data.Year = day.Year() - l.YearsAgo
+		memories = append(memories, m)
+	}
+	return memories, nil
+}
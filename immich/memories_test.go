@@ -0,0 +1,87 @@
+package immich
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetOnThisDayMemories(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		routes  map[string]string
+		want    []int // years of the memories
+	}{
+		{
+			name:    "memories",
+			version: `{"major":1,"minor":130,"patch":0}`,
+			routes: map[string]string{
+				"/api/memories": `[
+					{"id":"m1","type":"on_this_day","memoryAt":"2021-06-14T00:00:00Z","showAt":"2024-06-14T00:00:00Z","hideAt":"2024-06-15T00:00:00Z","data":{"year":2021},"assets":[{"id":"1"}]},
+					{"id":"m2","type":"on_this_day","memoryAt":"2023-06-13T00:00:00Z","showAt":"2024-06-13T00:00:00Z","hideAt":"2024-06-14T00:00:00Z","data":{"year":2023},"assets":[{"id":"2"}]},
+					{"id":"m3","type":"on_this_day","memoryAt":"2023-06-14T00:00:00Z","data":{"year":2023},"assets":[{"id":"3"}]},
+					{"id":"m4","type":"on_this_day","memoryAt":"2022-06-14T00:00:00Z","data":{"year":2022},"assets":[]}
+				]`,
+			},
+			want: []int{2023, 2021},
+		},
+		{
+			name:    "memory lane",
+			version: `{"major":1,"minor":110,"patch":0}`,
+			routes: map[string]string{
+				"/api/assets/memory-lane": `[{"yearsAgo":1,"title":"1 year ago","assets":[{"id":"1"}]},{"yearsAgo":4,"title":"4 years ago","assets":[{"id":"2"},{"id":"3"}]}]`,
+			},
+			want: []int{2023, 2020},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/api/server/version":
+					_, _ = resp.Write([]byte(tt.version))
+				case "/api/server/features":
+					_, _ = resp.Write([]byte(`{}`))
+				default:
+					r, ok := tt.routes[req.URL.Path]
+					if !ok {
+						resp.WriteHeader(http.StatusNotFound)
+						return
+					}
+					query = req.URL.RawQuery
+					_, _ = resp.Write([]byte(r))
+				}
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			ic, err := NewImmichClient(server.URL, "1234")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ic.NegotiateServer(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			memories, err := ic.GetOnThisDayMemories(ctx, time.Date(2024, 6, 14, 12, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var years []int
+			for _, m := range memories {
+				years = append(years, m.Data.Year)
+			}
+			if !reflect.DeepEqual(years, tt.want) {
+				t.Errorf("expected the years %v, got %v", tt.want, years)
+			}
+			if query == "" {
+				t.Errorf("the day isn't given to the server")
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package immich
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func Test_searchMetadataRequest(t *testing.T) {
@@ -139,3 +143,48 @@ func Test_searchMetadataRequest(t *testing.T) {
 		t.Errorf("expecting next page, got: %d", rest.Assets.NextPage)
 	}
 }
+
+func TestSearchAssets(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/search/metadata" {
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(req.Body).Decode(&got)
+		_, _ = resp.Write([]byte(`{"assets":{"total":1,"count":1,"items":[{"id":"1"}],"nextPage":null}}`))
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := SearchQuery{
+		TakenAfter:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAfter:  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		PersonIDs:     []string{"p1"},
+		FavoritesOnly: true,
+		Type:          "VIDEO",
+	}
+	count := 0
+	err = ic.SearchAssets(context.Background(), q, func(a *Asset) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expecting 1 asset, got: %d", count)
+	}
+	if got["takenAfter"] != "2023-01-01T00:00:00Z" || got["updatedAfter"] != "2024-02-01T00:00:00Z" || got["isFavorite"] != true || got["type"] != "VIDEO" {
+		t.Errorf("unexpected request: %v", got)
+	}
+	if _, ok := got["takenBefore"]; ok {
+		t.Errorf("unexpected takenBefore in the request: %v", got)
+	}
+	if _, ok := got["isArchived"]; ok {
+		t.Errorf("unexpected isArchived in the request: %v", got)
+	}
+}
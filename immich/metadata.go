@@ -2,6 +2,7 @@ package immich
 
 import (
 	"context"
+	"time"
 )
 
 type searchMetadataResponse struct {
@@ -14,11 +15,29 @@ type searchMetadataResponse struct {
 }
 
 type searchMetadataGetAllBody struct {
-	Page        int  `json:"page"`
-	WithExif    bool `json:"withExif,omitempty"`
-	IsVisible   bool `json:"isVisible,omitempty"`
-	WithDeleted bool `json:"withDeleted,omitempty"`
-	Size        int  `json:"size,omitempty"`
+	Page         int        `json:"page"`
+	WithExif     bool       `json:"withExif,omitempty"`
+	IsVisible    bool       `json:"isVisible,omitempty"`
+	WithDeleted  bool       `json:"withDeleted,omitempty"`
+	Size         int        `json:"size,omitempty"`
+	TakenAfter   *time.Time `json:"takenAfter,omitempty"`
+	TakenBefore  *time.Time `json:"takenBefore,omitempty"`
+	UpdatedAfter *time.Time `json:"updatedAfter,omitempty"`
+	PersonIDs    []string   `json:"personIds,omitempty"`
+	IsFavorite   bool       `json:"isFavorite,omitempty"`
+	IsArchived   bool       `json:"isArchived,omitempty"`
+	WithArchived bool       `json:"withArchived,omitempty"`
+	Type         string     `json:"type,omitempty"`
+}
+
+// SearchQuery gives the criteria of SearchAssets, the zero values don't filter
+type SearchQuery struct {
+	TakenAfter, TakenBefore time.Time
+	UpdatedAfter            time.Time // assets added or modified after this time
+	PersonIDs               []string  // assets showing all those persons
+	FavoritesOnly           bool
+	ArchivedOnly            bool
+	Type                    string // IMAGE or VIDEO
 }
 
 func (ic *ImmichClient) callSearchMetadata(ctx context.Context, req *searchMetadataGetAllBody, filter func(*Asset) error) error {
@@ -68,3 +87,27 @@ func (ic *ImmichClient) GetAllAssetsWithFilter(ctx context.Context, filter func(
 	req := searchMetadataGetAllBody{Page: 1, WithExif: true, IsVisible: true, WithDeleted: true}
 	return ic.callSearchMetadata(ctx, &req, filter)
 }
+
+// SearchAssets calls the filter for each of the server's assets matching the query. The trashed assets aren't given.
+func (ic *ImmichClient) SearchAssets(ctx context.Context, q SearchQuery, filter func(*Asset) error) error {
+	req := searchMetadataGetAllBody{
+		Page:         1,
+		WithExif:     true,
+		IsVisible:    true,
+		PersonIDs:    q.PersonIDs,
+		IsFavorite:   q.FavoritesOnly,
+		IsArchived:   q.ArchivedOnly,
+		WithArchived: q.ArchivedOnly,
+		Type:         q.Type,
+	}
+	if !q.TakenAfter.IsZero() {
+		req.TakenAfter = &q.TakenAfter
+	}
+	if !q.TakenBefore.IsZero() {
+		req.TakenBefore = &q.TakenBefore
+	}
+	if !q.UpdatedAfter.IsZero() {
+		req.UpdatedAfter = &q.UpdatedAfter
+	}
+	return ic.callSearchMetadata(ctx, &req, filter)
+}
@@ -0,0 +1,125 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// placeholderCaptions are written by cameras in the ImageDescription field
+var placeholderCaptions = []string{
+	"OLYMPUS DIGITAL CAMERA",
+	"SONY DSC",
+	"DIGITAL CAMERA",
+	"KONICA MINOLTA DIGITAL CAMERA",
+	"MINOLTA DIGITAL CAMERA",
+	"SAMSUNG",
+	"DCIM",
+	"default",
+}
+
+// getExifCaption gives the caption from the fields ImageDescription or UserComment
+func getExifCaption(x *exif.Exif) string {
+	if s, err := getTagSting(x, exif.ImageDescription); err == nil {
+		if s = cleanCaption(s); s != "" {
+			return s
+		}
+	}
+	if t, err := x.Get(exif.UserComment); err == nil && len(t.Val) > 8 {
+		return cleanCaption(decodeUserComment(t.Val, x.Tiff.Order))
+	}
+	return ""
+}
+
+// decodeUserComment decodes the UserComment field, prefixed by its character code
+func decodeUserComment(b []byte, order binary.ByteOrder) string {
+	code, b := string(bytes.TrimRight(b[:8], "\x00 ")), b[8:]
+	switch code {
+	case "UNICODE":
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			u[i] = order.Uint16(b[2*i:])
+		}
+		return string(utf16.Decode(u))
+	case "", "ASCII":
+		return string(b)
+	}
+	// JIS or unknown encoding
+	return ""
+}
+
+func cleanCaption(s string) string {
+	s = strings.TrimSpace(strings.Trim(s, "\x00"))
+	for _, p := range placeholderCaptions {
+		if strings.EqualFold(s, p) {
+			return ""
+		}
+	}
+	return s
+}
+
+// ReadXMPDescription gives the dc:description of a XMP file.
+// The x-default language is preferred when the description is given in several languages.
+func ReadXMPDescription(r io.Reader) (string, error) {
+	const dc = "http://purl.org/dc/elements/1.1/"
+	d := xml.NewDecoder(r)
+	inDescription := false
+	lang := ""
+	found := ""
+	text := strings.Builder{}
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space == dc && t.Name.Local == "description" {
+				inDescription = true
+				text.Reset()
+			}
+			if inDescription && t.Name.Local == "li" {
+				lang = ""
+				for _, a := range t.Attr {
+					if a.Name.Local == "lang" {
+						lang = a.Value
+					}
+				}
+				text.Reset()
+			}
+			// <rdf:Description dc:description="...">
+			for _, a := range t.Attr {
+				if a.Name.Space == dc && a.Name.Local == "description" && found == "" {
+					found = strings.TrimSpace(a.Value)
+				}
+			}
+		case xml.CharData:
+			if inDescription {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch {
+			case inDescription && t.Name.Local == "li":
+				s := strings.TrimSpace(text.String())
+				if s != "" && (found == "" || lang == "x-default") {
+					found = s
+				}
+			case t.Name.Space == dc && t.Name.Local == "description":
+				inDescription = false
+				if s := strings.TrimSpace(text.String()); found == "" && s != "" {
+					found = s
+				}
+			}
+		}
+	}
+	return found, nil
+}
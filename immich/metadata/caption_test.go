@@ -0,0 +1,84 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestReadXMPDescription(t *testing.T) {
+	tests := []struct {
+		name     string
+		xmp      string
+		expected string
+	}{
+		{
+			name: "alt",
+			xmp: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <dc:description><rdf:Alt>
+   <rdf:li xml:lang="fr-FR">La tour Eiffel</rdf:li>
+   <rdf:li xml:lang="x-default">The Eiffel tower</rdf:li>
+  </rdf:Alt></dc:description>
+ </rdf:Description></rdf:RDF></x:xmpmeta>`,
+			expected: "The Eiffel tower",
+		},
+		{
+			name: "attribute",
+			xmp: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" dc:description="Sunset"/>
+ </rdf:RDF></x:xmpmeta>`,
+			expected: "Sunset",
+		},
+		{
+			name: "none",
+			xmp: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about="" xmlns:exif="http://ns.adobe.com/exif/1.0/"><exif:DateTimeOriginal>2023-10-06T06:30:00Z</exif:DateTimeOriginal></rdf:Description>
+ </rdf:RDF></x:xmpmeta>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadXMPDescription(strings.NewReader(tt.xmp))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecodeUserComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{name: "ascii", data: []byte("ASCII\x00\x00\x00Birthday party"), expected: "Birthday party"},
+		{name: "unicode", data: []byte("UNICODE\x00C\x00a\x00f\x00\xe9\x00"), expected: "Café"},
+		{name: "undefined", data: []byte("\x00\x00\x00\x00\x00\x00\x00\x00Beach"), expected: "Beach"},
+		{name: "jis", data: []byte("JIS\x00\x00\x00\x00\x00xx"), expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeUserComment(tt.data, binary.LittleEndian)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCleanCaption(t *testing.T) {
+	for s, expected := range map[string]string{
+		"OLYMPUS DIGITAL CAMERA     ": "",
+		"  Grandma's garden\x00":      "Grandma's garden",
+		"sony dsc":                    "",
+	} {
+		if got := cleanCaption(s); got != expected {
+			t.Errorf("cleanCaption(%q): expected %q, got %q", s, expected, got)
+		}
+	}
+}
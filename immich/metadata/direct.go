@@ -6,7 +6,6 @@ import (
 	"io/fs"
 	"path"
 	"strings"
-	"time"
 )
 
 func GetFileMetaData(fsys fs.FS, name string) (Metadata, error) {
@@ -28,80 +27,40 @@ func GetFileMetaData(fsys fs.FS, name string) (Metadata, error) {
 
 func GetFromReader(rd io.Reader, ext string) (Metadata, error) {
 	r := newSliceReader(rd)
-	meta := Metadata{}
+	var meta Metadata
 	var err error
-	var dateTaken time.Time
 	switch strings.ToLower(ext) {
 	case ".heic", ".heif":
-		dateTaken, err = readHEIFDateTaken(r)
-	case ".jpg", ".jpeg", ".dng", ".cr2":
-		dateTaken, err = readExifDateTaken(r)
+		meta, err = readHEIFMetadata(r)
+	case ".jpg", ".jpeg", ".dng", ".cr2", ".tif", ".tiff", ".nef", ".arw":
+		meta, err = getExifFromReader(r)
+	case ".png":
+		meta, err = readPNGMetadata(r)
 	case ".mp4", ".mov":
-		dateTaken, err = readMP4DateTaken(r)
+		meta, err = readMP4Metadata(r)
 	case ".cr3":
-		dateTaken, err = readCR3DateTaken(r)
+		meta, err = readCR3Metadata(r)
 	default:
 		err = fmt.Errorf("can't determine the taken date from metadata (%s)", ext)
 	}
-	meta.DateTaken = dateTaken
 	return meta, err
 }
 
-// readExifDateTaken pase the file for Exif DateTaken
-func readExifDateTaken(r io.Reader) (time.Time, error) {
-	md, err := getExifFromReader(r)
-	return md.DateTaken, err
-}
-
 const searchBufferSize = 32 * 1024
 
-// readHEIFDateTaken locate the Exif part and return the date of capture
-func readHEIFDateTaken(r *sliceReader) (time.Time, error) {
-	b := make([]byte, searchBufferSize)
-	r, err := searchPattern(r, []byte{0x45, 0x78, 0x69, 0x66, 0, 0, 0x4d, 0x4d}, b)
-	if err != nil {
-		return time.Time{}, err
-	}
-
-	filler := make([]byte, 6)
-	_, err = r.Read(filler)
-	if err != nil {
-		return time.Time{}, err
-	}
-
-	md, err := getExifFromReader(r)
-	return md.DateTaken, err
-}
-
-// readMP4DateTaken locate the mvhd atom and decode the date of capture
-func readMP4DateTaken(r *sliceReader) (time.Time, error) {
-	b := make([]byte, searchBufferSize)
-
-	r, err := searchPattern(r, []byte{'m', 'v', 'h', 'd'}, b)
-	if err != nil {
-		return time.Time{}, err
-	}
-	atom, err := decodeMvhdAtom(r)
-	if err != nil {
-		return time.Time{}, err
-	}
-	return atom.CreationTime, nil
-}
-
-func readCR3DateTaken(r *sliceReader) (time.Time, error) {
+func readCR3Metadata(r *sliceReader) (Metadata, error) {
 	b := make([]byte, searchBufferSize)
 
 	r, err := searchPattern(r, []byte("CMT1"), b)
 	if err != nil {
-		return time.Time{}, err
+		return Metadata{}, err
 	}
 
 	filler := make([]byte, 4)
 	_, err = r.Read(filler)
 	if err != nil {
-		return time.Time{}, err
+		return Metadata{}, err
 	}
 
-	md, err := getExifFromReader(r)
-	return md.DateTaken, err
+	return getExifFromReader(r)
 }
@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -29,18 +30,58 @@ func getExifFromReader(r io.Reader) (Metadata, error) {
 		tag, err = getTagSting(x, exif.DateTimeOriginal)
 		if err == nil {
 			md.DateTaken, err = time.ParseInLocation("2006:01:02 15:04:05", tag, local)
+			md.LocalTime = err == nil
 		}
 	}
 	if err != nil {
 		tag, err = getTagSting(x, exif.DateTime)
 		if err == nil {
 			md.DateTaken, err = time.ParseInLocation("2006:01:02 15:04:05", tag, local)
+			md.LocalTime = err == nil
+		}
+	}
+	getExifLocation(x, &md)
+	md.Description = getExifCaption(x)
+	if t, err := x.Get(exif.MakerNote); err == nil {
+		md.ContentID = appleContentIdentifier(t.Val)
+	}
+	if model, err := getTagSting(x, exif.Model); err == nil {
+		md.Model = strings.TrimSpace(model)
+	}
+	if t, err := x.Get(exif.Orientation); err == nil {
+		if o, err := t.Int(0); err == nil {
+			md.Orientation = o
 		}
 	}
 
 	return md, err
 }
 
+// getExifLocation reads the GPS position, when present
+func getExifLocation(x *exif.Exif, md *Metadata) {
+	lat, long, err := x.LatLong()
+	if err != nil {
+		return
+	}
+	md.Latitude, md.Longitude = lat, long
+
+	t, err := x.Get(exif.GPSAltitude)
+	if err != nil {
+		return
+	}
+	num, den, err := t.Rat2(0)
+	if err != nil || den == 0 {
+		return
+	}
+	md.Altitude = float64(num) / float64(den)
+	if t, err := x.Get(exif.GPSAltitudeRef); err == nil {
+		if ref, err := t.Int(0); err == nil && ref == 1 {
+			// below the sea level
+			md.Altitude = -md.Altitude
+		}
+	}
+}
+
 func getTagSting(x *exif.Exif, tagName exif.FieldName) (string, error) {
 	t, err := x.Get(tagName)
 	if err != nil {
@@ -49,3 +90,33 @@ func getTagSting(x *exif.Exif, tagName exif.FieldName) (string, error) {
 	s := strings.TrimRight(strings.TrimLeft(t.String(), `"`), `"`)
 	return s, nil
 }
+
+// appleContentIdentifier reads the content identifier of the Apple maker notes,
+// it's shared by the photo and the video of a Live Photo.
+func appleContentIdentifier(note []byte) string {
+	const header = "Apple iOS\x00"
+	if len(note) < 14 || string(note[:len(header)]) != header || string(note[12:14]) != "MM" {
+		return ""
+	}
+	entries, _, err := readIFD(note, binary.BigEndian, 14)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if e.tag != 0x0011 || e.typ != typeASCII {
+			continue
+		}
+		var v []byte
+		if e.count <= 4 {
+			v = e.value[:e.count]
+		} else {
+			o := binary.BigEndian.Uint32(e.value[:])
+			if int(o)+int(e.count) > len(note) {
+				return ""
+			}
+			v = note[o : o+e.count]
+		}
+		return strings.TrimRight(string(v), "\x00")
+	}
+	return ""
+}
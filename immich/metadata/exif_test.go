@@ -0,0 +1,165 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// tiffSample builds a little endian TIFF block with a date, an orientation and a GPS position
+func tiffSample() []byte {
+	b := &bytes.Buffer{}
+	w := func(v any) { _ = binary.Write(b, binary.LittleEndian, v) }
+	entry := func(tag, typ uint16, count, value uint32) {
+		w(tag)
+		w(typ)
+		w(count)
+		w(value)
+	}
+	const (
+		typByte     = 1
+		typASCII    = 2
+		typShort    = 3
+		typLong     = 4
+		typRational = 5
+	)
+
+	b.WriteString("II")
+	w(uint16(42))
+	w(uint32(8))
+
+	// IFD0 at 8, date at 50, GPS IFD at 70, GPS values at 148
+	w(uint16(3))
+	entry(0x0112, typShort, 1, 6)   // Orientation
+	entry(0x0132, typASCII, 20, 50) // DateTime
+	entry(0x8825, typLong, 1, 70)   // GPS IFD
+	w(uint32(0))                    // next IFD
+	b.WriteString("2023:10:06 08:30:00\x00")
+
+	w(uint16(6))
+	entry(0x0001, typASCII, 2, 'N')    // GPSLatitudeRef
+	entry(0x0002, typRational, 3, 148) // GPSLatitude
+	entry(0x0003, typASCII, 2, 'W')    // GPSLongitudeRef
+	entry(0x0004, typRational, 3, 172) // GPSLongitude
+	entry(0x0005, typByte, 1, 0)       // GPSAltitudeRef
+	entry(0x0006, typRational, 1, 196) // GPSAltitude
+	w(uint32(0))
+	for _, v := range []uint32{48, 1, 51, 1, 2772, 100, 2, 1, 17, 1, 42, 1, 35, 1} {
+		w(v)
+	}
+	return b.Bytes()
+}
+
+func jpegSample() []byte {
+	t := tiffSample()
+	b := &bytes.Buffer{}
+	b.Write([]byte{0xff, 0xd8, 0xff, 0xe1})
+	_ = binary.Write(b, binary.BigEndian, uint16(len(t)+8))
+	b.WriteString("Exif\x00\x00")
+	b.Write(t)
+	b.Write([]byte{0xff, 0xd9})
+	return b.Bytes()
+}
+
+func pngSample() []byte {
+	b := &bytes.Buffer{}
+	chunk := func(typ string, data []byte) {
+		_ = binary.Write(b, binary.BigEndian, uint32(len(data)))
+		b.WriteString(typ)
+		b.Write(data)
+		b.Write([]byte{0, 0, 0, 0}) // CRC isn't checked
+	}
+	b.Write(pngSignature)
+	chunk("IHDR", make([]byte, 13))
+	chunk("eXIf", tiffSample())
+	chunk("IDAT", make([]byte, 10))
+	chunk("IEND", nil)
+	return b.Bytes()
+}
+
+func mp4Sample() []byte {
+	b := &bytes.Buffer{}
+	b.Write([]byte{0, 0, 0, 108})
+	b.WriteString("mvhd")
+	b.Write([]byte{0, 0, 0, 0})
+	// 2023-10-06 06:30:00 UTC since 1904
+	ts := uint32(time.Date(2023, 10, 6, 6, 30, 0, 0, time.UTC).Unix() + 2082844800)
+	_ = binary.Write(b, binary.BigEndian, ts)
+	_ = binary.Write(b, binary.BigEndian, ts)
+	b.Write(make([]byte, 88))
+	loc := "+48.8577-002.2950+035.000/"
+	_ = binary.Write(b, binary.BigEndian, uint32(12+len(loc)))
+	b.WriteString("\xa9xyz")
+	_ = binary.Write(b, binary.BigEndian, uint16(len(loc)))
+	b.Write([]byte{0x15, 0xc7})
+	b.WriteString(loc)
+	return b.Bytes()
+}
+
+func TestGetFromReaderNative(t *testing.T) {
+	date := time.Date(2023, 10, 6, 8, 30, 0, 0, local)
+	tests := []struct {
+		ext         string
+		data        []byte
+		date        time.Time
+		orientation int
+	}{
+		{ext: ".jpg", data: jpegSample(), date: date, orientation: 6},
+		{ext: ".tif", data: tiffSample(), date: date, orientation: 6},
+		{ext: ".png", data: pngSample(), date: date, orientation: 6},
+		{ext: ".mp4", data: mp4Sample(), date: time.Date(2023, 10, 6, 6, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			md, err := GetFromReader(bytes.NewReader(tt.data), tt.ext)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !md.DateTaken.Equal(tt.date) {
+				t.Errorf("expected date %s, got %s", tt.date, md.DateTaken)
+			}
+			if md.Orientation != tt.orientation {
+				t.Errorf("expected orientation %d, got %d", tt.orientation, md.Orientation)
+			}
+			if math.Abs(md.Latitude-48.8577) > 1e-4 || math.Abs(md.Longitude+2.295) > 1e-4 || math.Abs(md.Altitude-35) > 1e-4 {
+				t.Errorf("unexpected location: %f, %f, %f", md.Latitude, md.Longitude, md.Altitude)
+			}
+		})
+	}
+}
+
+func TestPNGWithoutExif(t *testing.T) {
+	b := pngSample()
+	// rename the eXIf chunk
+	i := bytes.Index(b, []byte("eXIf"))
+	copy(b[i:], "tEXt")
+	_, err := GetFromReader(bytes.NewReader(b), ".png")
+	if err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestParseISO6709(t *testing.T) {
+	tests := []struct {
+		s              string
+		lat, long, alt float64
+		wantErr        bool
+	}{
+		{s: "+48.8577+002.2950/", lat: 48.8577, long: 2.295},
+		{s: "-33.8688+151.2093+012.5/", lat: -33.8688, long: 151.2093, alt: 12.5},
+		{s: "garbage", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			lat, long, alt, err := parseISO6709(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lat != tt.lat || long != tt.long || alt != tt.alt {
+				t.Errorf("expected %f,%f,%f, got %f,%f,%f", tt.lat, tt.long, tt.alt, lat, long, alt)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// EXIF tags written by SetJPEGDateTaken
+const (
+	tagExifIFD            = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	typeASCII             = 2
+	typeLong              = 4
+)
+
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value [4]byte // the value or its offset
+}
+
+// SetJPEGDateTaken copies the JPEG file from r to w, with the date of capture written
+// into the EXIF fields DateTimeOriginal and OffsetTimeOriginal.
+//
+// The existing EXIF data are kept: the new IFDs are appended at the end of the EXIF block,
+// so the offsets of the other fields, including the maker notes, don't change.
+// An EXIF block is created when the file hasn't any.
+func SetJPEGDateTaken(r io.Reader, w io.Writer, t time.Time) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 {
+		return errors.New("not a JPEG file")
+	}
+
+	// walk the segments until the image data
+	insertAt := 2
+	pos := 2
+	for pos+4 <= len(b) {
+		if b[pos] != 0xff {
+			return errors.New("invalid JPEG segment")
+		}
+		marker := b[pos+1]
+		if marker == 0xda || marker < 0xe0 || marker > 0xef {
+			break
+		}
+		l := int(binary.BigEndian.Uint16(b[pos+2:]))
+		if pos+2+l > len(b) || l < 2 {
+			return errors.New("invalid JPEG segment")
+		}
+		seg := b[pos+4 : pos+2+l]
+		if marker == 0xe1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			tiff, err := setExifDate(seg[6:], t)
+			if err != nil {
+				return err
+			}
+			// the EXIF block is moved first, as readers only look at the first APP1 segment
+			return writeJPEG(w, b[:insertAt], tiff, b[insertAt:pos], b[pos+2+l:])
+		}
+		if marker == 0xe0 && insertAt == pos {
+			// keep the JFIF header first
+			insertAt = pos + 2 + l
+		}
+		pos += 2 + l
+	}
+
+	// no EXIF block, create it
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0}
+	tiff, err = setExifDate(tiff, t)
+	if err != nil {
+		return err
+	}
+	return writeJPEG(w, b[:insertAt], tiff, b[insertAt:])
+}
+
+// writeJPEG writes the EXIF block between the before part and the after parts
+func writeJPEG(w io.Writer, before []byte, tiff []byte, after ...[]byte) error {
+	l := len(tiff) + 8
+	if l > 0xffff {
+		return errors.New("the EXIF block is too large")
+	}
+	for _, p := range append([][]byte{before, {0xff, 0xe1, byte(l >> 8), byte(l)}, []byte("Exif\x00\x00"), tiff}, after...) {
+		_, err := w.Write(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setExifDate returns the TIFF block with new IFD0 and Exif IFD carrying the date
+func setExifDate(tiff []byte, t time.Time) ([]byte, error) {
+	if len(tiff) < 8 {
+		return nil, errors.New("invalid EXIF block")
+	}
+	var bo byteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return nil, errors.New("invalid EXIF byte order")
+	}
+
+	ifd0, next, err := readIFD(tiff, bo, bo.Uint32(tiff[4:]))
+	if err != nil {
+		return nil, err
+	}
+	var exifIFD []ifdEntry
+	for _, e := range ifd0 {
+		if e.tag == tagExifIFD {
+			exifIFD, _, err = readIFD(tiff, bo, bo.Uint32(e.value[:]))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	t = t.In(local)
+	out := slices.Clone(tiff)
+	if len(out)%2 == 1 {
+		out = append(out, 0)
+	}
+	appendValue := func(s string) uint32 {
+		o := uint32(len(out))
+		out = append(out, s...)
+		out = append(out, 0)
+		if len(out)%2 == 1 {
+			out = append(out, 0)
+		}
+		return o
+	}
+	date := t.Format("2006:01:02 15:04:05")
+	offset := t.Format("-07:00")
+	exifIFD = setEntry(exifIFD, ifdEntry{tag: tagDateTimeOriginal, typ: typeASCII, count: uint32(len(date) + 1)}, bo, appendValue(date))
+	exifIFD = setEntry(exifIFD, ifdEntry{tag: tagOffsetTimeOriginal, typ: typeASCII, count: uint32(len(offset) + 1)}, bo, appendValue(offset))
+
+	exifOffset := uint32(len(out))
+	out = appendIFD(out, bo, exifIFD, 0)
+	ifd0 = setEntry(ifd0, ifdEntry{tag: tagExifIFD, typ: typeLong, count: 1}, bo, exifOffset)
+	ifd0Offset := uint32(len(out))
+	out = appendIFD(out, bo, ifd0, next)
+	bo.PutUint32(out[4:], ifd0Offset)
+	return out, nil
+}
+
+func readIFD(tiff []byte, bo byteOrder, offset uint32) ([]ifdEntry, uint32, error) {
+	if offset == 0 {
+		return nil, 0, nil
+	}
+	if int(offset)+2 > len(tiff) {
+		return nil, 0, fmt.Errorf("invalid IFD offset: %d", offset)
+	}
+	n := int(bo.Uint16(tiff[offset:]))
+	p := int(offset) + 2
+	if p+12*n+4 > len(tiff) {
+		return nil, 0, fmt.Errorf("invalid IFD at %d", offset)
+	}
+	entries := make([]ifdEntry, n)
+	for i := range entries {
+		e := tiff[p+12*i:]
+		entries[i].tag = bo.Uint16(e)
+		entries[i].typ = bo.Uint16(e[2:])
+		entries[i].count = bo.Uint32(e[4:])
+		copy(entries[i].value[:], e[8:12])
+	}
+	return entries, bo.Uint32(tiff[p+12*n:]), nil
+}
+
+// setEntry replaces or adds the entry, keeping the entries sorted by tag
+func setEntry(entries []ifdEntry, e ifdEntry, bo byteOrder, value uint32) []ifdEntry {
+	bo.PutUint32(e.value[:], value)
+	entries = slices.DeleteFunc(slices.Clone(entries), func(i ifdEntry) bool { return i.tag == e.tag })
+	i, _ := slices.BinarySearchFunc(entries, e.tag, func(i ifdEntry, tag uint16) int { return int(i.tag) - int(tag) })
+	return slices.Insert(entries, i, e)
+}
+
+func appendIFD(b []byte, bo byteOrder, entries []ifdEntry, next uint32) []byte {
+	b = bo.AppendUint16(b, uint16(len(entries)))
+	for _, e := range entries {
+		b = bo.AppendUint16(b, e.tag)
+		b = bo.AppendUint16(b, e.typ)
+		b = bo.AppendUint32(b, e.count)
+		b = append(b, e.value[:]...)
+	}
+	return bo.AppendUint32(b, next)
+}
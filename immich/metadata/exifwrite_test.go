@@ -0,0 +1,52 @@
+package metadata
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSetJPEGDateTaken(t *testing.T) {
+	date := time.Date(2019, 7, 14, 22, 15, 30, 0, local)
+	tests := []struct {
+		name        string
+		data        []byte
+		orientation int
+		located     bool
+	}{
+		{name: "with exif", data: jpegSample(), orientation: 6, located: true},
+		{name: "without exif", data: []byte{0xff, 0xd8, 0xff, 0xd9}},
+		{name: "jfif without exif", data: []byte{0xff, 0xd8, 0xff, 0xe0, 0, 4, 'J', 'F', 0xff, 0xd9}},
+		{name: "exif after xmp", data: append([]byte{0xff, 0xd8, 0xff, 0xe1, 0, 5, 'x', 'm', 'p'}, jpegSample()[2:]...), orientation: 6, located: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			err := SetJPEGDateTaken(bytes.NewReader(tt.data), b, date)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			md, err := GetFromReader(bytes.NewReader(b.Bytes()), ".jpg")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !md.DateTaken.Equal(date) {
+				t.Errorf("expected date %s, got %s", date, md.DateTaken)
+			}
+			if md.Orientation != tt.orientation {
+				t.Errorf("expected orientation %d, got %d", tt.orientation, md.Orientation)
+			}
+			if tt.located && (math.Abs(md.Latitude-48.8577) > 1e-4 || math.Abs(md.Longitude+2.295) > 1e-4) {
+				t.Errorf("the location is lost: %f, %f", md.Latitude, md.Longitude)
+			}
+		})
+	}
+}
+
+func TestSetJPEGDateTakenNotJPEG(t *testing.T) {
+	err := SetJPEGDateTaken(bytes.NewReader(pngSample()), &bytes.Buffer{}, time.Now())
+	if err == nil {
+		t.Errorf("an error was expected")
+	}
+}
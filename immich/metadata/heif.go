@@ -0,0 +1,315 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+/*
+A HEIF file (.heic, .heif) is a sequence of boxes: ftyp, meta, mdat...
+
+The meta box describes the items of the file:
+  - iinf gives the type of each item, the EXIF data are in the item of type Exif
+  - iloc gives the location of each item, in the file or in the idat box
+  - idat holds the data of some items
+
+The Exif item starts with the offset of the TIFF header, usually following "Exif\0\0".
+*/
+
+// maxMetaBoxSize limits the size of the meta box read in memory
+const maxMetaBoxSize = 16 * 1024 * 1024
+
+type heifBox struct {
+	typ  string
+	size int64 // size of the content, -1 up to the end of the file
+}
+
+// readBoxHeader reads the size and the type of the next box, and gives the header size
+func readBoxHeader(r io.Reader) (heifBox, int64, error) {
+	var h [8]byte
+	_, err := io.ReadFull(r, h[:])
+	if err != nil {
+		return heifBox{}, 0, err
+	}
+	b := heifBox{typ: string(h[4:8])}
+	size := int64(binary.BigEndian.Uint32(h[:4]))
+	headerSize := int64(8)
+	switch size {
+	case 0:
+		b.size = -1
+		return b, headerSize, nil
+	case 1:
+		_, err = io.ReadFull(r, h[:])
+		if err != nil {
+			return heifBox{}, 0, err
+		}
+		size = int64(binary.BigEndian.Uint64(h[:]))
+		headerSize += 8
+	}
+	if size < headerSize {
+		return heifBox{}, 0, fmt.Errorf("invalid size of the box %q", b.typ)
+	}
+	b.size = size - headerSize
+	return b, headerSize, nil
+}
+
+// heifExtent is a part of an item's data
+type heifExtent struct {
+	offset int64
+	length int64
+}
+
+type heifItem struct {
+	construction int // 0: file offset, 1: idat offset
+	extents      []heifExtent
+}
+
+type heifMeta struct {
+	exifID uint32
+	items  map[uint32]heifItem
+	idat   []byte
+}
+
+// readHEIFMetadata locates the Exif item of the HEIF file and decodes it
+func readHEIFMetadata(r *sliceReader) (Metadata, error) {
+	var pos int64
+	var meta *heifMeta
+	for {
+		box, hs, err := readBoxHeader(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("no EXIF data in the HEIF file")
+			}
+			return Metadata{}, err
+		}
+		pos += hs
+		if box.typ == "meta" {
+			if box.size < 0 || box.size > maxMetaBoxSize {
+				return Metadata{}, errors.New("invalid HEIF meta box")
+			}
+			b, err := r.ReadSlice(int(box.size))
+			if err != nil {
+				return Metadata{}, err
+			}
+			pos += box.size
+			meta, err = parseHEIFMeta(b)
+			if err != nil {
+				return Metadata{}, err
+			}
+			break
+		}
+		if box.size < 0 {
+			return Metadata{}, errors.New("no HEIF meta box")
+		}
+		_, err = io.CopyN(io.Discard, r, box.size)
+		if err != nil {
+			return Metadata{}, err
+		}
+		pos += box.size
+	}
+
+	item, ok := meta.items[meta.exifID]
+	if meta.exifID == 0 || !ok || len(item.extents) == 0 {
+		return Metadata{}, errors.New("no EXIF data in the HEIF file")
+	}
+
+	// gather the extents of the Exif item, they follow the meta box
+	var data []byte
+	for _, e := range item.extents {
+		// the offsets are read from 8 bytes fields: a damaged file can give a negative or overflowing value
+		if e.offset < 0 || e.length <= 0 || e.length > maxMetaBoxSize || e.offset > math.MaxInt64-e.length {
+			return Metadata{}, errors.New("invalid HEIF Exif item")
+		}
+		switch item.construction {
+		case 0:
+			if e.offset < pos {
+				return Metadata{}, errors.New("the HEIF Exif item precedes the meta box")
+			}
+			_, err := io.CopyN(io.Discard, r, e.offset-pos)
+			if err != nil {
+				return Metadata{}, err
+			}
+			b, err := r.ReadSlice(int(e.length))
+			if err != nil {
+				return Metadata{}, err
+			}
+			pos = e.offset + e.length
+			data = append(data, b...)
+		case 1:
+			if e.offset+e.length > int64(len(meta.idat)) {
+				return Metadata{}, errors.New("invalid HEIF Exif item")
+			}
+			data = append(data, meta.idat[e.offset:e.offset+e.length]...)
+		default:
+			return Metadata{}, fmt.Errorf("unsupported HEIF item construction: %d", item.construction)
+		}
+	}
+	if len(data) < 4 {
+		return Metadata{}, errors.New("invalid HEIF Exif item")
+	}
+	tiffOffset := int(binary.BigEndian.Uint32(data)) + 4
+	if tiffOffset > len(data) {
+		return Metadata{}, errors.New("invalid HEIF Exif item")
+	}
+	return getExifFromReader(bytes.NewReader(data[tiffOffset:]))
+}
+
+// parseHEIFMeta reads the boxes iinf, iloc and idat of the meta box
+func parseHEIFMeta(b []byte) (*heifMeta, error) {
+	if len(b) < 4 {
+		return nil, errors.New("invalid HEIF meta box")
+	}
+	meta := &heifMeta{items: map[uint32]heifItem{}}
+	r := bytes.NewReader(b[4:]) // skip version and flags
+	for r.Len() > 0 {
+		box, _, err := readBoxHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		if box.size < 0 {
+			box.size = int64(r.Len())
+		}
+		if box.size > int64(r.Len()) {
+			return nil, fmt.Errorf("invalid size of the box %q", box.typ)
+		}
+		content := make([]byte, box.size)
+		_, _ = r.Read(content)
+		switch box.typ {
+		case "iinf":
+			meta.exifID, err = parseIinf(content)
+		case "iloc":
+			err = parseIloc(content, meta.items)
+		case "idat":
+			meta.idat = content
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid HEIF %s box: %w", box.typ, err)
+		}
+	}
+	return meta, nil
+}
+
+// boxReader reads the fields of a box
+type boxReader struct {
+	b   []byte
+	err error
+}
+
+func (br *boxReader) uint(size int) uint64 {
+	if br.err != nil {
+		return 0
+	}
+	if size > len(br.b) {
+		br.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	var v uint64
+	for _, c := range br.b[:size] {
+		v = v<<8 | uint64(c)
+	}
+	br.b = br.b[size:]
+	return v
+}
+
+func (br *boxReader) bytes(size int) []byte {
+	if br.err != nil {
+		return nil
+	}
+	if size > len(br.b) {
+		br.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	v := br.b[:size]
+	br.b = br.b[size:]
+	return v
+}
+
+// parseIinf gives the ID of the Exif item
+func parseIinf(b []byte) (uint32, error) {
+	br := &boxReader{b: b}
+	version := br.uint(1)
+	br.uint(3)
+	count := br.uint(2)
+	if version > 0 {
+		count = count<<16 | br.uint(2)
+	}
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		size := int(br.uint(4))
+		typ := string(br.bytes(4))
+		if br.err != nil || size < 8 || size-8 > len(br.b) {
+			return 0, errors.New("invalid infe box")
+		}
+		content := br.bytes(size - 8)
+		if typ != "infe" {
+			continue
+		}
+		ir := &boxReader{b: content}
+		v := ir.uint(1)
+		ir.uint(3)
+		if v < 2 {
+			// no item type before the version 2
+			continue
+		}
+		var id uint32
+		if v == 2 {
+			id = uint32(ir.uint(2))
+		} else {
+			id = uint32(ir.uint(4))
+		}
+		ir.uint(2) // protection index
+		if string(ir.bytes(4)) == "Exif" && ir.err == nil {
+			return id, nil
+		}
+	}
+	return 0, br.err
+}
+
+// parseIloc reads the location of the items
+func parseIloc(b []byte, items map[uint32]heifItem) error {
+	br := &boxReader{b: b}
+	version := br.uint(1)
+	br.uint(3)
+	sizes := br.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0xf)
+	sizes = br.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), int(sizes&0xf)
+	if version == 0 {
+		indexSize = 0
+	}
+	var count uint64
+	if version < 2 {
+		count = br.uint(2)
+	} else {
+		count = br.uint(4)
+	}
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		var id uint32
+		if version < 2 {
+			id = uint32(br.uint(2))
+		} else {
+			id = uint32(br.uint(4))
+		}
+		item := heifItem{}
+		if version > 0 {
+			item.construction = int(br.uint(2) & 0xf)
+		}
+		br.uint(2) // data reference index
+		base := int64(br.uint(baseOffsetSize))
+		extents := br.uint(2)
+		for j := uint64(0); j < extents && br.err == nil; j++ {
+			br.uint(indexSize)
+			offset := br.uint(offsetSize)
+			e := heifExtent{offset: base + int64(offset), length: int64(br.uint(lengthSize))}
+			if base < 0 || offset > math.MaxInt64-uint64(base) {
+				return errors.New("invalid HEIF item location")
+			}
+			item.extents = append(item.extents, e)
+		}
+		items[id] = item
+	}
+	return br.err
+}
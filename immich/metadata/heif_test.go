@@ -0,0 +1,141 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func box(typ string, content ...[]byte) []byte {
+	b := &bytes.Buffer{}
+	l := 8
+	for _, c := range content {
+		l += len(c)
+	}
+	_ = binary.Write(b, binary.BigEndian, uint32(l))
+	b.WriteString(typ)
+	for _, c := range content {
+		b.Write(c)
+	}
+	return b.Bytes()
+}
+
+func be(v ...any) []byte {
+	b := &bytes.Buffer{}
+	for _, x := range v {
+		_ = binary.Write(b, binary.BigEndian, x)
+	}
+	return b.Bytes()
+}
+
+// heifSample builds a HEIF file with an Exif item stored in the mdat box, or in the idat box
+func heifSample(inIdat bool) []byte {
+	exifItem := append(be(uint32(6)), append([]byte("Exif\x00\x00"), tiffSample()...)...)
+	ftyp := box("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	iinf := box("iinf", be(uint32(0), uint16(2)),
+		box("infe", be(uint32(2<<24), uint16(1), uint16(0)), []byte("hvc1\x00")),
+		box("infe", be(uint32(2<<24), uint16(2), uint16(0)), []byte("Exif\x00")),
+	)
+	iloc := func(offset uint32) []byte {
+		construction := uint16(0)
+		if inIdat {
+			construction = 1
+		}
+		return box("iloc", be(uint32(1<<24), uint8(0x44), uint8(0), uint16(2),
+			uint16(1), uint16(0), uint16(0), uint16(1), uint32(0), uint32(0),
+			uint16(2), construction, uint16(0), uint16(1), offset, uint32(len(exifItem))))
+	}
+	hdlr := box("hdlr", be(uint32(0), uint32(0)), []byte("pict"), make([]byte, 13))
+	meta := func(offset uint32) []byte {
+		if inIdat {
+			return box("meta", be(uint32(0)), hdlr, iinf, iloc(offset), box("idat", exifItem))
+		}
+		return box("meta", be(uint32(0)), hdlr, iinf, iloc(offset))
+	}
+	if inIdat {
+		return append(append(ftyp, meta(0)...), box("mdat", make([]byte, 100))...)
+	}
+	offset := uint32(len(ftyp) + len(meta(0)) + 8 + 100)
+	return append(append(ftyp, meta(offset)...), box("mdat", make([]byte, 100), exifItem)...)
+}
+
+func TestReadHEIFMetadata(t *testing.T) {
+	date := time.Date(2023, 10, 6, 8, 30, 0, 0, local)
+	for _, inIdat := range []bool{false, true} {
+		md, err := GetFromReader(bytes.NewReader(heifSample(inIdat)), ".HEIC")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !md.DateTaken.Equal(date) {
+			t.Errorf("expected date %s, got %s", date, md.DateTaken)
+		}
+		if math.Abs(md.Latitude-48.8577) > 1e-4 || math.Abs(md.Longitude+2.295) > 1e-4 {
+			t.Errorf("unexpected location: %f, %f", md.Latitude, md.Longitude)
+		}
+	}
+}
+
+func TestReadHEIFWithoutExif(t *testing.T) {
+	b := heifSample(false)
+	// rename the Exif item type
+	i := bytes.Index(b, []byte("Exif\x00"))
+	copy(b[i:], "mime")
+	_, err := GetFromReader(bytes.NewReader(b), ".heic")
+	if err == nil {
+		t.Errorf("an error was expected")
+	}
+}
+
+func TestAppleContentIdentifier(t *testing.T) {
+	const id = "9A4E1B52-6A7E-4A29-B1C4-2E1E0B7D3F10"
+	note := &bytes.Buffer{}
+	note.WriteString("Apple iOS\x00")
+	note.Write(be(uint16(1)))
+	note.WriteString("MM")
+	// IFD at 14: 2 entries, values at 14+2+24+4=44
+	note.Write(be(uint16(2)))
+	note.Write(be(uint16(0x0001), uint16(9), uint32(1), uint32(14)))
+	note.Write(be(uint16(0x0011), uint16(2), uint32(len(id)+1), uint32(44)))
+	note.Write(be(uint32(0)))
+	note.WriteString(id + "\x00")
+
+	if got := appleContentIdentifier(note.Bytes()); got != id {
+		t.Errorf("expected %q, got %q", id, got)
+	}
+	if got := appleContentIdentifier([]byte("Nikon\x00\x02\x10\x00\x00MM*\x00")); got != "" {
+		t.Errorf("no identifier was expected, got %q", got)
+	}
+}
+
+// a damaged iloc box gives extents out of the file, they are rejected without panic
+func TestReadHEIFMalformedIloc(t *testing.T) {
+	exifItem := append(be(uint32(6)), append([]byte("Exif\x00\x00"), tiffSample()...)...)
+	ftyp := box("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	iinf := box("iinf", be(uint32(0), uint16(1)),
+		box("infe", be(uint32(2<<24), uint16(2), uint16(0)), []byte("Exif\x00")),
+	)
+	hdlr := box("hdlr", be(uint32(0), uint32(0)), []byte("pict"), make([]byte, 13))
+	tests := []struct {
+		name   string
+		base   uint64
+		offset uint64
+	}{
+		{name: "negative offset", offset: math.MaxUint64 - 15},
+		{name: "overflowing extent", offset: math.MaxInt64 - 2},
+		{name: "overflowing base", base: math.MaxInt64, offset: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// version 1, 8 bytes offsets, lengths and base offsets, the item is in the idat box
+			iloc := box("iloc", be(uint32(1<<24), uint8(0x88), uint8(0x80), uint16(1),
+				uint16(2), uint16(1), uint16(0), tt.base, uint16(1), tt.offset, uint64(16)))
+			b := append(ftyp, box("meta", be(uint32(0)), hdlr, iinf, iloc, box("idat", exifItem))...)
+			_, err := GetFromReader(bytes.NewReader(b), ".heic")
+			if err == nil {
+				t.Errorf("an error was expected")
+			}
+		})
+	}
+}
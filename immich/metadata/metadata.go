@@ -1,6 +1,7 @@
 package metadata
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -11,17 +12,39 @@ import (
 type Metadata struct {
 	Description string
 	DateTaken   time.Time
+	LocalTime   bool // the date of capture has no time zone, it's read in the local time zone
 	Latitude    float64
 	Longitude   float64
 	Altitude    float64
+	Orientation int      // EXIF orientation, 0 when unknown
+	Model       string   // Camera model
+	ContentID   string   // Apple content identifier, shared by the photo and the video of a Live Photo
+	Tags        []string // Keywords written into the XMP dc:subject
 }
 
 func (m Metadata) IsSet() bool {
-	return m.Description != "" || !m.DateTaken.IsZero() || m.Latitude != 0 || m.Longitude != 0
+	return m.Description != "" || !m.DateTaken.IsZero() || m.Latitude != 0 || m.Longitude != 0 || len(m.Tags) > 0
 }
 
 func (m Metadata) Write(w io.Writer) error {
-	_, err := io.WriteString(w, header)
+	return m.write(w, "Image::ExifTool 12.40")
+}
+
+// SidecarToolkit marks the XMP sidecars written by immich-go
+const SidecarToolkit = "immich-go"
+
+// WriteSidecar writes the XMP data marked with the SidecarToolkit
+func (m Metadata) WriteSidecar(w io.Writer) error {
+	return m.write(w, SidecarToolkit)
+}
+
+// IsImmichGoSidecar tells if the XMP data has been written by WriteSidecar
+func IsImmichGoSidecar(b []byte) bool {
+	return bytes.Contains(b, []byte("x:xmptk='"+SidecarToolkit+"'"))
+}
+
+func (m Metadata) write(w io.Writer, toolkit string) error {
+	_, err := fmt.Fprintf(w, header, toolkit)
 	if err != nil {
 		return err
 	}
@@ -39,6 +62,41 @@ func (m Metadata) Write(w io.Writer) error {
 			return err
 		}
 	}
+	if len(m.Tags) > 0 {
+		_, err = io.WriteString(w, subjectHeader)
+		if err != nil {
+			return err
+		}
+		// the keywords are given as dc:subject and lr:hierarchicalSubject, for the tools reading only one of them
+		for _, bag := range []string{"dc:subject", "lr:hierarchicalSubject"} {
+			_, err = fmt.Fprintf(w, subjectBagHeader, bag)
+			if err != nil {
+				return err
+			}
+			for _, t := range m.Tags {
+				_, err = io.WriteString(w, subjectItemHeader)
+				if err != nil {
+					return err
+				}
+				err = xml.EscapeText(w, []byte(t))
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(w, subjectItemFooter)
+				if err != nil {
+					return err
+				}
+			}
+			_, err = fmt.Fprintf(w, subjectBagFooter, bag)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = io.WriteString(w, subjectFooter)
+		if err != nil {
+			return err
+		}
+	}
 
 	writeExifBlock := !m.DateTaken.IsZero() || m.Latitude != 0 || m.Longitude != 0
 	if writeExifBlock {
@@ -79,7 +137,7 @@ func (m Metadata) String() string {
 
 const (
 	header = `<?xpacket begin='?' id='W5M0MpCehiHzreSzNTczkc9d'?>
-<x:xmpmeta xmlns:x='adobe:ns:meta/' x:xmptk='Image::ExifTool 12.40'>
+<x:xmpmeta xmlns:x='adobe:ns:meta/' x:xmptk='%s'>
 <rdf:RDF xmlns:rdf='http://www.w3.org/1999/02/22-rdf-syntax-ns#'>
 `
 	descriptionHeader = ` <rdf:Description rdf:about=''
@@ -94,6 +152,22 @@ const (
  </rdf:Description>
 `
 
+	subjectHeader = ` <rdf:Description rdf:about=''
+  xmlns:dc='http://purl.org/dc/elements/1.1/'
+  xmlns:lr='http://ns.adobe.com/lightroom/1.0/'>
+`
+	subjectBagHeader = `  <%s>
+   <rdf:Bag>
+`
+	subjectItemHeader = `    <rdf:li>`
+	subjectItemFooter = `</rdf:li>
+`
+	subjectBagFooter = `   </rdf:Bag>
+  </%s>
+`
+	subjectFooter = ` </rdf:Description>
+`
+
 	exifHeader = ` <rdf:Description rdf:about=''
   xmlns:exif='http://ns.adobe.com/exif/1.0/'>
   <exif:ExifVersion>0220</exif:ExifVersion>`
@@ -11,6 +11,7 @@ func TestMetadata_String(t *testing.T) {
 		DateTaken   time.Time
 		Latitude    float64
 		Longitude   float64
+		Tags        []string
 	}
 	tests := []struct {
 		name   string
@@ -71,6 +72,34 @@ func TestMetadata_String(t *testing.T) {
  </rdf:Description>
 </rdf:RDF>
 </x:xmpmeta>
+<?xpacket end='w'?>`,
+		},
+		{
+			name: "TagsOnly",
+			fields: fields{
+				Tags: []string{"Holidays", "Rock & Roll"},
+			},
+			want: `<?xpacket begin='?' id='W5M0MpCehiHzreSzNTczkc9d'?>
+<x:xmpmeta xmlns:x='adobe:ns:meta/' x:xmptk='Image::ExifTool 12.40'>
+<rdf:RDF xmlns:rdf='http://www.w3.org/1999/02/22-rdf-syntax-ns#'>
+ <rdf:Description rdf:about=''
+  xmlns:dc='http://purl.org/dc/elements/1.1/'
+  xmlns:lr='http://ns.adobe.com/lightroom/1.0/'>
+  <dc:subject>
+   <rdf:Bag>
+    <rdf:li>Holidays</rdf:li>
+    <rdf:li>Rock &amp; Roll</rdf:li>
+   </rdf:Bag>
+  </dc:subject>
+  <lr:hierarchicalSubject>
+   <rdf:Bag>
+    <rdf:li>Holidays</rdf:li>
+    <rdf:li>Rock &amp; Roll</rdf:li>
+   </rdf:Bag>
+  </lr:hierarchicalSubject>
+ </rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
 <?xpacket end='w'?>`,
 		},
 		{
@@ -111,6 +140,7 @@ func TestMetadata_String(t *testing.T) {
 				DateTaken:   tt.fields.DateTaken,
 				Latitude:    tt.fields.Latitude,
 				Longitude:   tt.fields.Longitude,
+				Tags:        tt.fields.Tags,
 			}
 			if got := m.String(); got != tt.want {
 				t.Errorf("Meta.String() = %v, want %v", got, tt.want)
@@ -0,0 +1,41 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// readPNGMetadata reads the eXIf chunk of a PNG file.
+// The chunks are read until the image data, where the eXIf chunk is expected.
+func readPNGMetadata(r *sliceReader) (Metadata, error) {
+	b, err := r.ReadSlice(len(pngSignature))
+	if err != nil {
+		return Metadata{}, err
+	}
+	if !bytes.Equal(b, pngSignature) {
+		return Metadata{}, errors.New("not a PNG file")
+	}
+	for {
+		// chunk length and type
+		b, err = r.ReadSlice(8)
+		if err != nil {
+			return Metadata{}, err
+		}
+		l := int64(binary.BigEndian.Uint32(b[:4]))
+		switch string(b[4:8]) {
+		case "eXIf":
+			return getExifFromReader(io.LimitReader(r, l))
+		case "IDAT", "IEND":
+			return Metadata{}, errors.New("no eXIf chunk in the PNG file")
+		}
+		// skip the chunk's data and CRC
+		_, err = io.CopyN(io.Discard, r, l+4)
+		if err != nil {
+			return Metadata{}, err
+		}
+	}
+}
@@ -1,114 +1,268 @@
 package metadata
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
 /*
-The mvhd atom contains metadata and information about the entire movie or presentation, such as its duration,
-time scale, preferred playback rate, and more.
+A MP4 or a QuickTime file is a sequence of atoms (or boxes). The metadata are in the moov atom:
 
-Here are some of the main attributes found in the mvhd atom:
+  - moov/mvhd gives the creation time of the movie, in UTC, as seconds since 1904-01-01.
+    Some cameras write their local time instead.
+  - moov/udta/©day gives the date of the recording, with its time zone, ex: 2023-10-06T08:30:00+0200
+  - moov/udta/©xyz gives the location of the recording, ex: +48.8577+002.2950+035.000/
+  - moov/meta/keys and moov/meta/ilst give the QuickTime metadata written by iPhones:
+    com.apple.quicktime.creationdate, com.apple.quicktime.location.ISO6709, and
+    com.apple.quicktime.content.identifier, shared by the video and the photo of a Live Photo.
 
-- Timescale: This value indicates the time scale for the media presentation,
-   which represents the number of time units per second. It allows for accurate timing of media content in the file.
-
-- Duration: The duration is the total time the movie or presentation lasts,
-	expressed in the time scale units defined in the file.
+The date is taken from the Apple creation date, then from ©day, then from mvhd.
+*/
 
--  Preferred Rate: The preferred rate is the intended playback rate for the movie.
-	It can be used to set the default playback speed when the media is played.
+// maxMoovSize limits the size of the moov atom read in memory
+const maxMoovSize = 64 * 1024 * 1024
 
-- Preferred Volume: The preferred volume specifies the default audio volume for the media playback.
+// mp4Meta collects the metadata found in the atoms
+type mp4Meta struct {
+	mvhd      time.Time
+	day       time.Time
+	appleDate time.Time
+	location  string
+	contentID string
+	keys      []string // QuickTime metadata keys
+	hasMvhd   bool
+}
 
-- Matrix Structure: The mvhd atom may contain a matrix structure
-		that defines transformations to be applied when rendering the video content, such as scaling or rotation.
+// readMP4Metadata walks the atoms of the file up to the moov atom.
+func readMP4Metadata(r *sliceReader) (Metadata, error) {
+	var mm mp4Meta
+	for {
+		box, _, err := readBoxHeader(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Metadata{}, err
+		}
+		switch box.typ {
+		case "moov", "mvhd", "udta", "\xa9xyz":
+			if box.size < 0 || box.size > maxMoovSize {
+				return Metadata{}, fmt.Errorf("invalid size of the %q atom", box.typ)
+			}
+			b, err := r.ReadSlice(int(box.size))
+			if err != nil {
+				return Metadata{}, err
+			}
+			mm.parseAtom(box.typ, b)
+			if box.typ == "moov" {
+				return mm.metadata()
+			}
+			continue
+		}
+		if box.size < 0 {
+			break
+		}
+		_, err = io.CopyN(io.Discard, r, box.size)
+		if err != nil {
+			return Metadata{}, err
+		}
+	}
+	return mm.metadata()
+}
 
--  Creation and Modification Time: The mvhd atom also stores the creation time and modification time
-	of the movie or presentation.
+func (mm *mp4Meta) metadata() (Metadata, error) {
+	var md Metadata
+	if !mm.hasMvhd {
+		return md, errors.New("no movie header in the file")
+	}
+	switch {
+	case !mm.appleDate.IsZero():
+		md.DateTaken = mm.appleDate
+	case !mm.day.IsZero():
+		md.DateTaken = mm.day
+	default:
+		md.DateTaken = mm.mvhd
+	}
+	if mm.location != "" {
+		md.Latitude, md.Longitude, md.Altitude, _ = parseISO6709(mm.location)
+	}
+	md.ContentID = mm.contentID
+	return md, nil
+}
 
-In total, the minimum size of the mvhd atom is 108 bytes (version 0) or 112 bytes (version 1).
-If any of the optional fields are present, the size of the atom would increase accordingly.
-*/
+// parseAtoms parses the atoms of a container
+func (mm *mp4Meta) parseAtoms(b []byte) {
+	r := bytes.NewReader(b)
+	for r.Len() > 0 {
+		box, _, err := readBoxHeader(r)
+		if err != nil {
+			return
+		}
+		if box.size < 0 {
+			box.size = int64(r.Len())
+		}
+		if box.size > int64(r.Len()) {
+			return
+		}
+		content := make([]byte, box.size)
+		_, _ = r.Read(content)
+		mm.parseAtom(box.typ, content)
+	}
+}
 
-type MvhdAtom struct {
-	Marker           []byte // 4 bytes
-	Version          uint8
-	Flags            []byte // 3 bytes
-	CreationTime     time.Time
-	ModificationTime time.Time
-	// ignored fields:
-	// Timescale        uint32
-	// Duration         uint32
-	// Rate             float32
-	// Volume           float32
-	// Matrix           [9]int32
-	// NextTrackID      uint32
+func (mm *mp4Meta) parseAtom(typ string, b []byte) {
+	switch typ {
+	case "moov", "udta", "trak":
+		mm.parseAtoms(b)
+	case "meta":
+		// the meta atom of MP4 files starts with a version and flags, not the QuickTime one
+		if len(b) >= 8 && string(b[4:8]) != "hdlr" && binary.BigEndian.Uint32(b) == 0 {
+			b = b[4:]
+		}
+		mm.parseAtoms(b)
+	case "mvhd":
+		mm.mvhd = decodeMvhd(b)
+		mm.hasMvhd = true
+	case "\xa9day":
+		mm.day = parseMP4Date(atomString(b))
+	case "\xa9xyz":
+		mm.location = atomString(b)
+	case "keys":
+		mm.keys = decodeKeys(b)
+	case "ilst":
+		mm.parseIlst(b)
+	}
 }
 
-func decodeMvhdAtom(r *sliceReader) (*MvhdAtom, error) {
-	a := &MvhdAtom{}
-	var err error
-	// Read the mvhd marker (4 bytes)
-	a.Marker, err = r.ReadSlice(4)
-	if err != nil {
-		return nil, err
+// atomString gives the string of a QuickTime user data atom: a 2 bytes length, a 2 bytes language code and the string,
+// or of an iTunes item: a data atom.
+func atomString(b []byte) string {
+	if len(b) >= 16 && string(b[4:8]) == "data" {
+		return string(b[16:])
+	}
+	if len(b) < 4 {
+		return ""
 	}
+	l := int(binary.BigEndian.Uint16(b))
+	if 4+l > len(b) {
+		return ""
+	}
+	return string(b[4 : 4+l])
+}
 
-	// Read the mvhd version (1 byte)
-	a.Version, err = r.ReadByte()
-	if err != nil {
-		return nil, err
+// decodeMvhd gives the creation time of the movie header
+func decodeMvhd(b []byte) time.Time {
+	if len(b) < 12 {
+		return time.Time{}
+	}
+	var seconds uint64
+	if b[0] == 0 {
+		seconds = uint64(binary.BigEndian.Uint32(b[4:]))
+	} else {
+		if len(b) < 20 {
+			return time.Time{}
+		}
+		seconds = binary.BigEndian.Uint64(b[4:])
+	}
+	if seconds == 0 {
+		// not set
+		return time.Time{}
 	}
+	// the time is counted from 1904-01-01
+	return time.Unix(int64(seconds)-2082844800, 0).UTC()
+}
 
-	// Read the mvhd flags (3 bytes)
-	a.Flags, err = r.ReadSlice(3)
-	if err != nil {
-		return nil, err
+// decodeKeys reads the QuickTime metadata keys
+func decodeKeys(b []byte) []string {
+	br := &boxReader{b: b}
+	br.uint(4) // version and flags
+	count := br.uint(4)
+	var keys []string
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		size := int(br.uint(4))
+		if size < 8 {
+			break
+		}
+		br.uint(4) // name space
+		keys = append(keys, string(br.bytes(size-8)))
 	}
+	return keys
+}
 
-	if a.Version == 0 {
-		// Read the creation time (4 bytes)
-		b, err := r.ReadSlice(4)
-		if err != nil {
-			return nil, err
+// parseIlst reads the values of the QuickTime metadata, the type of each item is the index of its key
+func (mm *mp4Meta) parseIlst(b []byte) {
+	r := bytes.NewReader(b)
+	for r.Len() > 0 {
+		box, _, err := readBoxHeader(r)
+		if err != nil || box.size < 0 || box.size > int64(r.Len()) {
+			return
 		}
-		a.ModificationTime = convertTime32(binary.BigEndian.Uint32(b))
-		b, err = r.ReadSlice(4)
-		if err != nil {
-			return nil, err
+		content := make([]byte, box.size)
+		_, _ = r.Read(content)
+		if box.typ == "\xa9day" {
+			mm.day = parseMP4Date(atomString(content))
+			continue
 		}
-		a.CreationTime = convertTime32(binary.BigEndian.Uint32(b))
-	} else {
-		// Read the creation time (4 bytes)
-		b, err := r.ReadSlice(8)
-		if err != nil {
-			return nil, err
+		i := int(binary.BigEndian.Uint32([]byte(box.typ))) - 1
+		if i < 0 || i >= len(mm.keys) {
+			continue
 		}
-		a.ModificationTime = convertTime64(binary.BigEndian.Uint64(b))
-
-		b, err = r.ReadSlice(8)
-		if err != nil {
-			return nil, err
+		v := atomString(content)
+		switch mm.keys[i] {
+		case "com.apple.quicktime.creationdate":
+			mm.appleDate = parseMP4Date(v)
+		case "com.apple.quicktime.location.ISO6709":
+			mm.location = v
+		case "com.apple.quicktime.content.identifier":
+			mm.contentID = v
 		}
-		a.CreationTime = convertTime64(binary.BigEndian.Uint64(b))
 	}
-
-	return a, nil
 }
 
-func convertTime32(timestamp uint32) time.Time {
-	return time.Unix(int64(timestamp)-int64(2082844800), 0)
+var mp4DateLayouts = []string{
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
 }
 
-func convertTime64(timestamp uint64) time.Time {
-	// Unix epoch starts on January 1, 1970, subtracting the number of seconds from January 1, 1904 to January 1, 1970.
-	epochOffset := int64(2082844800)
+// parseMP4Date parses the dates of the ©day and com.apple.quicktime.creationdate atoms
+func parseMP4Date(s string) time.Time {
+	s = strings.TrimSpace(strings.TrimRight(s, "\x00"))
+	for _, l := range mp4DateLayouts {
+		if t, err := time.ParseInLocation(l, s, local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
 
-	// Convert the creation time to Unix timestamp
-	unixTimestamp := int64(timestamp>>32) - epochOffset
+var iso6709RE = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)?`)
 
-	// Convert the Unix timestamp to time.Time
-	return time.Unix(unixTimestamp, 0)
+// parseISO6709 parses a location given in decimal degrees, ex: +48.8577+002.2950+035.000/
+func parseISO6709(s string) (latitude, longitude, altitude float64, err error) {
+	m := iso6709RE.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, 0, fmt.Errorf("invalid ISO 6709 location: %q", s)
+	}
+	latitude, err = strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return
+	}
+	longitude, err = strconv.ParseFloat(m[2], 64)
+	if err != nil {
+		return
+	}
+	if m[3] != "" {
+		altitude, err = strconv.ParseFloat(m[3], 64)
+	}
+	return
 }
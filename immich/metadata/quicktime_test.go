@@ -0,0 +1,90 @@
+package metadata
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func mvhdAtom(version uint8, t time.Time) []byte {
+	seconds := uint64(t.Unix() + 2082844800)
+	if version == 0 {
+		return box("mvhd", be(uint32(0), uint32(seconds), uint32(seconds)), make([]byte, 88))
+	}
+	return box("mvhd", be(uint32(1<<24), seconds, seconds), make([]byte, 96))
+}
+
+func userDataString(typ string, s string) []byte {
+	return box(typ, be(uint16(len(s)), uint16(0x15c7)), []byte(s))
+}
+
+func TestReadQuickTimeMetadata(t *testing.T) {
+	utc := time.Date(2023, 10, 6, 6, 30, 0, 0, time.UTC)
+	ftyp := box("ftyp", []byte("qt  \x00\x00\x00\x00qt  "))
+	mdat := box("mdat", make([]byte, 1000))
+
+	keys := box("keys", be(uint32(0), uint32(3)),
+		box("mdta", []byte("com.apple.quicktime.location.ISO6709")),
+		box("mdta", []byte("com.apple.quicktime.creationdate")),
+		box("mdta", []byte("com.apple.quicktime.content.identifier")),
+	)
+	data := func(s string) []byte { return box("data", be(uint32(1), uint32(0)), []byte(s)) }
+	ilst := box("ilst",
+		box(string(be(uint32(1))), data("+48.8577-002.2950+035.000/")),
+		box(string(be(uint32(2))), data("2023-10-06T08:30:00+0200")),
+		box(string(be(uint32(3))), data("9A4E1B52-6A7E-4A29-B1C4-2E1E0B7D3F10")),
+	)
+	appleMeta := box("meta", box("hdlr", make([]byte, 25)), keys, ilst)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		date      time.Time
+		located   bool
+		contentID string
+	}{
+		{
+			name: "mvhd version 1, moov at the end",
+			data: append(append(ftyp, mdat...), box("moov", mvhdAtom(1, utc))...),
+			date: utc,
+		},
+		{
+			name:    "©day and ©xyz",
+			data:    append(ftyp, box("moov", mvhdAtom(0, utc.Add(time.Hour)), box("udta", userDataString("\xa9day", "2023-10-06T08:30:00+0200"), userDataString("\xa9xyz", "+48.8577-002.2950+035.000/")))...),
+			date:    utc,
+			located: true,
+		},
+		{
+			name:      "apple metadata",
+			data:      append(ftyp, box("moov", mvhdAtom(0, utc.Add(time.Hour)), appleMeta)...),
+			date:      utc,
+			located:   true,
+			contentID: "9A4E1B52-6A7E-4A29-B1C4-2E1E0B7D3F10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, err := GetFromReader(bytes.NewReader(tt.data), ".MOV")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !md.DateTaken.Equal(tt.date) {
+				t.Errorf("expected date %s, got %s", tt.date, md.DateTaken)
+			}
+			if tt.located != (math.Abs(md.Latitude-48.8577) < 1e-4 && math.Abs(md.Longitude+2.295) < 1e-4) {
+				t.Errorf("unexpected location: %f, %f", md.Latitude, md.Longitude)
+			}
+			if md.ContentID != tt.contentID {
+				t.Errorf("expected content identifier %q, got %q", tt.contentID, md.ContentID)
+			}
+		})
+	}
+}
+
+func TestReadQuickTimeWithoutHeader(t *testing.T) {
+	_, err := GetFromReader(bytes.NewReader(box("ftyp", []byte("isom"))), ".mp4")
+	if err == nil {
+		t.Errorf("an error was expected")
+	}
+}
@@ -0,0 +1,55 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const nsXMP = "http://ns.adobe.com/xap/1.0/"
+
+// ReadXMPRating gives the xmp:Rating of a XMP packet: from 1 to 5 stars, 0 when unrated, -1 when rejected.
+// ok is false when the packet hasn't rating.
+func ReadXMPRating(r io.Reader) (rating int, ok bool, err error) {
+	d := xml.NewDecoder(r)
+	inRating := false
+	text := strings.Builder{}
+	parse := func(s string) (int, bool, error) {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return 0, false, err
+		}
+		return int(f), true, nil
+	}
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, a := range t.Attr {
+				if a.Name.Space == nsXMP && a.Name.Local == "Rating" {
+					return parse(a.Value)
+				}
+			}
+			if t.Name.Space == nsXMP && t.Name.Local == "Rating" {
+				inRating = true
+				text.Reset()
+			}
+		case xml.CharData:
+			if inRating {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if inRating {
+				return parse(text.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadXMPRating(t *testing.T) {
+	tests := []struct {
+		xmp     string
+		rating  int
+		ok      bool
+		wantErr bool
+	}{
+		{xmp: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="4"/></rdf:RDF></x:xmpmeta>`, rating: 4, ok: true},
+		{xmp: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/"><xmp:Rating>-1</xmp:Rating></rdf:Description></rdf:RDF></x:xmpmeta>`, rating: -1, ok: true},
+		{xmp: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:CreatorTool="digiKam"/></rdf:RDF></x:xmpmeta>`},
+		{xmp: `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="five"/></rdf:RDF></x:xmpmeta>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		rating, ok, err := ReadXMPRating(strings.NewReader(tt.xmp))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if rating != tt.rating || ok != tt.ok {
+			t.Errorf("expected %d,%v, got %d,%v", tt.rating, tt.ok, rating, ok)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// XML name spaces of the Metadata Working Group regions
+const (
+	nsMWGRegions = "http://www.metadataworkinggroup.com/schemas/regions/"
+	nsDimensions = "http://ns.adobe.com/xap/1.0/sType/Dimensions#"
+	nsArea       = "http://ns.adobe.com/xmp/sType/Area#"
+	nsRDF        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+)
+
+// FaceRegion is a named face. The position of the face's top left corner
+// and its size are given as fractions of the image dimensions.
+type FaceRegion struct {
+	Name   string
+	X, Y   float64
+	Width  float64
+	Height float64
+}
+
+// FaceRegions are the faces of an image, with the image dimensions
+// the regions were applied to, when known.
+type FaceRegions struct {
+	ImageWidth  int
+	ImageHeight int
+	Faces       []FaceRegion
+}
+
+// region collects the fields of a mwg-rs:RegionList item
+type region struct {
+	name, typ  string
+	x, y, w, h float64
+}
+
+// ReadXMPFaceRegions reads the named faces of a XMP packet, as written by Picasa, digiKam or Lightroom
+// following the Metadata Working Group's guidelines (mwg-rs:Regions).
+// The fields can be given as attributes or as elements.
+func ReadXMPFaceRegions(r io.Reader) (FaceRegions, error) {
+	var fr FaceRegions
+	d := xml.NewDecoder(r)
+	depth := 0
+	inList := 0 // depth of the mwg-rs:RegionList element
+	inItem := 0 // depth of the current region
+	var reg region
+	text := strings.Builder{}
+
+	set := func(name xml.Name, value string) {
+		value = strings.TrimSpace(value)
+		switch name.Space {
+		case nsDimensions:
+			v, _ := strconv.ParseFloat(value, 64)
+			switch name.Local {
+			case "w":
+				fr.ImageWidth = int(v)
+			case "h":
+				fr.ImageHeight = int(v)
+			}
+		case nsMWGRegions:
+			if inItem == 0 {
+				return
+			}
+			switch name.Local {
+			case "Name":
+				reg.name = value
+			case "Type":
+				reg.typ = value
+			}
+		case nsArea:
+			if inItem == 0 {
+				return
+			}
+			v, _ := strconv.ParseFloat(value, 64)
+			switch name.Local {
+			case "x":
+				reg.x = v
+			case "y":
+				reg.y = v
+			case "w":
+				reg.w = v
+			case "h":
+				reg.h = v
+			}
+		}
+	}
+
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fr, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			text.Reset()
+			switch {
+			case t.Name.Space == nsMWGRegions && t.Name.Local == "RegionList":
+				inList = depth
+			case inList > 0 && inItem == 0 && t.Name.Space == nsRDF && t.Name.Local == "li":
+				inItem = depth
+				reg = region{}
+			}
+			for _, a := range t.Attr {
+				set(a.Name, a.Value)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			set(t.Name, text.String())
+			text.Reset()
+			switch depth {
+			case inItem:
+				inItem = 0
+				if reg.name != "" && (reg.typ == "" || strings.EqualFold(reg.typ, "Face")) && reg.w > 0 && reg.h > 0 {
+					// the area is given by its center
+					fr.Faces = append(fr.Faces, FaceRegion{
+						Name:   reg.name,
+						X:      max(reg.x-reg.w/2, 0),
+						Y:      max(reg.y-reg.h/2, 0),
+						Width:  reg.w,
+						Height: reg.h,
+					})
+				}
+			case inList:
+				inList = 0
+			}
+			depth--
+		}
+	}
+	return fr, nil
+}
+
+// ReadJPEGXMP gives the XMP packet embedded in a JPEG file, or nil when the file hasn't any
+func ReadJPEGXMP(r io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != 0xff || header[1] != 0xd8 {
+		return nil, errors.New("not a JPEG file")
+	}
+	xmpNS := []byte("http://ns.adobe.com/xap/1.0/\x00")
+	seg := make([]byte, 4)
+	for {
+		_, err = io.ReadFull(r, seg[:2])
+		if err != nil {
+			return nil, err
+		}
+		if seg[0] != 0xff {
+			return nil, errors.New("invalid JPEG segment")
+		}
+		if seg[1] == 0xda || seg[1] == 0xd9 {
+			// the image data starts, no XMP
+			return nil, nil
+		}
+		_, err = io.ReadFull(r, seg[2:])
+		if err != nil {
+			return nil, err
+		}
+		l := int(binary.BigEndian.Uint16(seg[2:]))
+		if l < 2 {
+			return nil, errors.New("invalid JPEG segment")
+		}
+		b := make([]byte, l-2)
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			return nil, err
+		}
+		if seg[1] == 0xe1 && bytes.HasPrefix(b, xmpNS) {
+			return b[len(xmpNS):], nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+const (
+	// as written by digiKam, with attributes
+	regionsAttributes = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about=""
+   xmlns:mwg-rs="http://www.metadataworkinggroup.com/schemas/regions/"
+   xmlns:stDim="http://ns.adobe.com/xap/1.0/sType/Dimensions#"
+   xmlns:stArea="http://ns.adobe.com/xmp/sType/Area#">
+  <mwg-rs:Regions rdf:parseType="Resource">
+   <mwg-rs:AppliedToDimensions stDim:w="4000" stDim:h="3000" stDim:unit="pixel"/>
+   <mwg-rs:RegionList>
+    <rdf:Bag>
+     <rdf:li>
+      <rdf:Description mwg-rs:Name="Alice" mwg-rs:Type="Face">
+       <mwg-rs:Area stArea:x="0.5" stArea:y="0.4" stArea:w="0.1" stArea:h="0.2" stArea:unit="normalized"/>
+      </rdf:Description>
+     </rdf:li>
+     <rdf:li>
+      <rdf:Description mwg-rs:Name="Pet" mwg-rs:Type="Pet">
+       <mwg-rs:Area stArea:x="0.2" stArea:y="0.2" stArea:w="0.1" stArea:h="0.1" stArea:unit="normalized"/>
+      </rdf:Description>
+     </rdf:li>
+     <rdf:li>
+      <rdf:Description mwg-rs:Type="Face">
+       <mwg-rs:Area stArea:x="0.8" stArea:y="0.8" stArea:w="0.1" stArea:h="0.1" stArea:unit="normalized"/>
+      </rdf:Description>
+     </rdf:li>
+    </rdf:Bag>
+   </mwg-rs:RegionList>
+  </mwg-rs:Regions>
+ </rdf:Description></rdf:RDF></x:xmpmeta>`
+
+	// as written by Lightroom, with elements
+	regionsElements = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description rdf:about=""
+   xmlns:mwg-rs="http://www.metadataworkinggroup.com/schemas/regions/"
+   xmlns:stDim="http://ns.adobe.com/xap/1.0/sType/Dimensions#"
+   xmlns:stArea="http://ns.adobe.com/xmp/sType/Area#">
+  <mwg-rs:Regions rdf:parseType="Resource">
+   <mwg-rs:AppliedToDimensions rdf:parseType="Resource"><stDim:w>4000</stDim:w><stDim:h>3000</stDim:h></mwg-rs:AppliedToDimensions>
+   <mwg-rs:RegionList><rdf:Bag>
+    <rdf:li rdf:parseType="Resource">
+     <mwg-rs:Name>Bob</mwg-rs:Name>
+     <mwg-rs:Type>Face</mwg-rs:Type>
+     <mwg-rs:Area rdf:parseType="Resource"><stArea:x>0.25</stArea:x><stArea:y>0.5</stArea:y><stArea:w>0.1</stArea:w><stArea:h>0.2</stArea:h></mwg-rs:Area>
+    </rdf:li>
+   </rdf:Bag></mwg-rs:RegionList>
+  </mwg-rs:Regions>
+ </rdf:Description></rdf:RDF></x:xmpmeta>`
+)
+
+func TestReadXMPFaceRegions(t *testing.T) {
+	tests := []struct {
+		name     string
+		xmp      string
+		expected []FaceRegion
+	}{
+		{name: "attributes", xmp: regionsAttributes, expected: []FaceRegion{{Name: "Alice", X: 0.45, Y: 0.3, Width: 0.1, Height: 0.2}}},
+		{name: "elements", xmp: regionsElements, expected: []FaceRegion{{Name: "Bob", X: 0.2, Y: 0.4, Width: 0.1, Height: 0.2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr, err := ReadXMPFaceRegions(strings.NewReader(tt.xmp))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if fr.ImageWidth != 4000 || fr.ImageHeight != 3000 {
+				t.Errorf("unexpected dimensions: %dx%d", fr.ImageWidth, fr.ImageHeight)
+			}
+			if len(fr.Faces) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, fr.Faces)
+			}
+			for i, f := range fr.Faces {
+				e := tt.expected[i]
+				if f.Name != e.Name || math.Abs(f.X-e.X) > 1e-9 || math.Abs(f.Y-e.Y) > 1e-9 || f.Width != e.Width || f.Height != e.Height {
+					t.Errorf("expected %v, got %v", e, f)
+				}
+			}
+		})
+	}
+}
+
+func TestReadJPEGXMP(t *testing.T) {
+	b := &bytes.Buffer{}
+	b.Write([]byte{0xff, 0xd8})
+	packet := "http://ns.adobe.com/xap/1.0/\x00" + regionsElements
+	b.Write([]byte{0xff, 0xe1})
+	_ = binary.Write(b, binary.BigEndian, uint16(len(packet)+2))
+	b.WriteString(packet)
+	b.Write([]byte{0xff, 0xda, 0, 2})
+
+	x, err := ReadJPEGXMP(bytes.NewReader(append(jpegSample()[:len(jpegSample())-2], b.Bytes()[2:]...)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(x) != regionsElements {
+		t.Errorf("unexpected XMP packet: %q", x)
+	}
+
+	x, err = ReadJPEGXMP(bytes.NewReader(jpegSample()))
+	if err != nil || x != nil {
+		t.Errorf("no XMP packet was expected: %q, %v", x, err)
+	}
+}
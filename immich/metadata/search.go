@@ -18,7 +18,7 @@ func newSliceReader(r io.Reader) *sliceReader {
 
 func (r *sliceReader) ReadSlice(l int) ([]byte, error) {
 	b := make([]byte, l)
-	_, err := r.Read(b)
+	_, err := io.ReadFull(r, b)
 	return b, err
 }
 
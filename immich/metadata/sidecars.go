@@ -0,0 +1,289 @@
+package metadata
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Besides the XMP files sent to the server, other sidecar files can give the metadata of the assets:
+	  - IMG_1234.jpg.txt or IMG_1234.txt: the caption of the photo
+	  - IMG_1234.json: the metadata exported by exiftool -json or other tools
+	  - IMG_1234.thm: the thumbnail made by some cameras, ignored
+
+	New formats are added with RegisterSidecar.
+	The .picasa.ini file of Picasa describes all the files of its folder, it is read by ReadPicasaIni.
+*/
+
+// SidecarReader reads the metadata of a sidecar format
+type SidecarReader interface {
+	ReadSidecar(r io.Reader) (Metadata, error)
+}
+
+// SidecarReaderFunc is a function used as a SidecarReader
+type SidecarReaderFunc func(r io.Reader) (Metadata, error)
+
+func (f SidecarReaderFunc) ReadSidecar(r io.Reader) (Metadata, error) {
+	return f(r)
+}
+
+var (
+	sidecarLock    sync.RWMutex
+	sidecarReaders = map[string]SidecarReader{
+		".txt":  SidecarReaderFunc(readCaptionSidecar),
+		".json": SidecarReaderFunc(readJSONSidecar),
+		".thm":  nil,
+	}
+)
+
+// RegisterSidecar registers the reader of the sidecar files with the given extension.
+// A nil reader registers files to be ignored, like thumbnails.
+func RegisterSidecar(ext string, sr SidecarReader) {
+	sidecarLock.Lock()
+	defer sidecarLock.Unlock()
+	sidecarReaders[strings.ToLower(ext)] = sr
+}
+
+// GetSidecarReader gives the reader of the sidecar files with the given extension.
+// The reader is nil when the files are ignored. ok is false when the extension isn't a sidecar format.
+func GetSidecarReader(ext string) (sr SidecarReader, ok bool) {
+	sidecarLock.RLock()
+	defer sidecarLock.RUnlock()
+	sr, ok = sidecarReaders[strings.ToLower(ext)]
+	return sr, ok
+}
+
+// Merge sets the fields of m with the fields given by o
+func (m *Metadata) Merge(o Metadata) {
+	if o.Description != "" {
+		m.Description = o.Description
+	}
+	if !o.DateTaken.IsZero() {
+		m.DateTaken, m.LocalTime = o.DateTaken, o.LocalTime
+	}
+	if o.Latitude != 0 || o.Longitude != 0 {
+		m.Latitude, m.Longitude, m.Altitude = o.Latitude, o.Longitude, o.Altitude
+	}
+	if o.Orientation != 0 {
+		m.Orientation = o.Orientation
+	}
+	if o.Model != "" {
+		m.Model = o.Model
+	}
+	if o.ContentID != "" {
+		m.ContentID = o.ContentID
+	}
+	for _, t := range o.Tags {
+		m.Tags = addTag(m.Tags, t)
+	}
+}
+
+// readCaptionSidecar reads a text file giving the caption of the asset
+func readCaptionSidecar(r io.Reader) (Metadata, error) {
+	b, err := io.ReadAll(io.LimitReader(r, 64*1024))
+	if err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{Description: strings.TrimSpace(strings.TrimPrefix(string(b), "\ufeff"))}, nil
+}
+
+// readJSONSidecar reads the metadata of a JSON file, like those produced by exiftool -json.
+// The file contains an object, or an array whose first item is the object.
+func readJSONSidecar(r io.Reader) (Metadata, error) {
+	var v any
+	err := json.NewDecoder(r).Decode(&v)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if l, ok := v.([]any); ok && len(l) > 0 {
+		v = l[0]
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return Metadata{}, errors.New("no metadata object in the JSON file")
+	}
+	fields := map[string]any{}
+	for k, v := range obj {
+		fields[strings.ToLower(k)] = v
+	}
+	first := func(keys ...string) any {
+		for _, k := range keys {
+			if v, ok := fields[k]; ok && v != nil && v != "" {
+				return v
+			}
+		}
+		return nil
+	}
+
+	var md Metadata
+	if s, ok := first("description", "imagedescription", "caption-abstract", "caption").(string); ok {
+		md.Description = strings.TrimSpace(s)
+	}
+	if s, ok := first("datetimeoriginal", "datetaken", "createdate").(string); ok {
+		md.DateTaken, md.LocalTime = parseSidecarDate(s)
+	}
+	md.Latitude, err = jsonCoordinate(first("gpslatitude", "latitude"), first("gpslatituderef"))
+	if err != nil {
+		return Metadata{}, fmt.Errorf("latitude: %w", err)
+	}
+	md.Longitude, err = jsonCoordinate(first("gpslongitude", "longitude"), first("gpslongituderef"))
+	if err != nil {
+		return Metadata{}, fmt.Errorf("longitude: %w", err)
+	}
+	md.Altitude, err = jsonAltitude(first("gpsaltitude", "altitude"))
+	if err != nil {
+		return Metadata{}, fmt.Errorf("altitude: %w", err)
+	}
+	switch t := first("keywords", "subject", "tags").(type) {
+	case string:
+		md.Tags = addTag(md.Tags, t)
+	case []any:
+		for _, k := range t {
+			if s, ok := k.(string); ok {
+				md.Tags = addTag(md.Tags, s)
+			}
+		}
+	}
+	if s, ok := first("model").(string); ok {
+		md.Model = strings.TrimSpace(s)
+	}
+	return md, nil
+}
+
+// addTag adds the tag when missing
+func addTag(tags []string, t string) []string {
+	t = strings.TrimSpace(t)
+	if t == "" || slices.Contains(tags, t) {
+		return tags
+	}
+	return append(tags, t)
+}
+
+// parseSidecarDate parses the EXIF and RFC 3339 date formats, the date is local when the time zone isn't given
+func parseSidecarDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006:01:02 15:04:05Z07:00", "2006:01:02 15:04:05.999999999Z07:00", "2006-01-02T15:04Z07:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, false
+		}
+	}
+	for _, layout := range []string{"2006:01:02 15:04:05", "2006:01:02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dmsRE matches the coordinates written by exiftool without the option -n, like 12 deg 34' 56.78" N
+var dmsRE = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*(?:deg|°)\s*(?:(\d+(?:\.\d+)?)\s*'\s*)?(?:(\d+(?:\.\d+)?)\s*"\s*)?([NSEW])?$`)
+
+// jsonCoordinate gives the value of a GPS coordinate: a number, a string holding a number,
+// or degrees, minutes and seconds. The reference, like South or W, gives the hemisphere when the value doesn't.
+func jsonCoordinate(v any, ref any) (float64, error) {
+	var f float64
+	hemisphere, _ := ref.(string)
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		f = n
+	case string:
+		n = strings.TrimSpace(n)
+		var err error
+		f, err = strconv.ParseFloat(n, 64)
+		if err != nil {
+			m := dmsRE.FindStringSubmatch(n)
+			if m == nil {
+				return 0, fmt.Errorf("can't read the coordinate %q", n)
+			}
+			for i, div := range []float64{1, 60, 3600} {
+				if m[i+1] != "" {
+					d, _ := strconv.ParseFloat(m[i+1], 64)
+					f += d / div
+				}
+			}
+			if m[4] != "" {
+				hemisphere = m[4]
+			}
+		}
+	default:
+		return 0, fmt.Errorf("can't read the coordinate %v", v)
+	}
+	if h := strings.ToUpper(strings.TrimSpace(hemisphere)); strings.HasPrefix(h, "S") || strings.HasPrefix(h, "W") {
+		f = -math.Abs(f)
+	}
+	return f, nil
+}
+
+// altitudeRE matches the altitudes written by exiftool without the option -n, like 35.2 m Above Sea Level
+var altitudeRE = regexp.MustCompile(`(?i)^(-?\d+(?:\.\d+)?)\s*m?\s*(above sea level|below sea level)?$`)
+
+// jsonAltitude gives the value of an altitude in meters: a number, or a string holding it
+func jsonAltitude(v any) (float64, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return n, nil
+	case string:
+		m := altitudeRE.FindStringSubmatch(strings.TrimSpace(n))
+		if m == nil {
+			return 0, fmt.Errorf("can't read the altitude %q", n)
+		}
+		f, _ := strconv.ParseFloat(m[1], 64)
+		if strings.EqualFold(m[2], "below sea level") {
+			f = -f
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("can't read the altitude %v", v)
+}
+
+// IsPicasaIni tells if the file name is the one of the file written by Picasa in each folder
+func IsPicasaIni(name string) bool {
+	name = strings.ToLower(name)
+	return name == ".picasa.ini" || name == "picasa.ini"
+}
+
+// ReadPicasaIni reads the captions and the keywords of the files of a .picasa.ini file, by file name.
+// The stars given by Picasa aren't kept.
+func ReadPicasaIni(r io.Reader) (map[string]Metadata, error) {
+	files := map[string]Metadata{}
+	var file string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(s.Text(), "\ufeff"))
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			file = line[1 : len(line)-1]
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || file == "" {
+			continue
+		}
+		md := files[file]
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "caption":
+			md.Description = strings.TrimSpace(v)
+		case "keywords":
+			for _, t := range strings.Split(v, ",") {
+				md.Tags = addTag(md.Tags, t)
+			}
+		default:
+			continue
+		}
+		files[file] = md
+	}
+	return files, s.Err()
+}
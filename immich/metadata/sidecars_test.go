@@ -0,0 +1,159 @@
+package metadata
+
+import (
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadSidecars(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		content string
+		want    Metadata
+		wantErr bool
+	}{
+		{
+			name:    "caption",
+			ext:     ".TXT",
+			content: "\ufeff  Sunset on the beach\n",
+			want:    Metadata{Description: "Sunset on the beach"},
+		},
+		{
+			name: "exiftool",
+			ext:  ".json",
+			content: `[{
+				"SourceFile": "IMG_1234.jpg",
+				"DateTimeOriginal": "2023:10:06 08:30:00+02:00",
+				"GPSLatitude": 48.8577,
+				"GPSLongitude": -2.295,
+				"Keywords": ["beach", "summer"],
+				"Description": "Sunset"
+			}]`,
+			want: Metadata{
+				Description: "Sunset",
+				DateTaken:   time.Date(2023, 10, 6, 6, 30, 0, 0, time.UTC),
+				Latitude:    48.8577,
+				Longitude:   -2.295,
+				Tags:        []string{"beach", "summer"},
+			},
+		},
+		{
+			name:    "object with local date",
+			ext:     ".json",
+			content: `{"dateTaken": "2023-10-06T08:30:00", "latitude": "48.8577", "longitude": "-2.295", "tags": "beach"}`,
+			want: Metadata{
+				DateTaken: time.Date(2023, 10, 6, 8, 30, 0, 0, local),
+				Latitude:  48.8577,
+				Longitude: -2.295,
+				Tags:      []string{"beach"},
+			},
+		},
+		{
+			name: "exiftool without -n",
+			ext:  ".json",
+			content: `[{
+				"GPSLatitude": "48 deg 51' 27.72\" N",
+				"GPSLongitude": "2 deg 17' 42.00\"",
+				"GPSLongitudeRef": "West",
+				"GPSAltitude": "35.2 m Below Sea Level"
+			}]`,
+			want: Metadata{Latitude: 48.8577, Longitude: -2.295, Altitude: -35.2},
+		},
+		{
+			name:    "exiftool -n",
+			ext:     ".json",
+			content: `[{"GPSLatitude": -33.8568, "GPSLatitudeRef": "S", "GPSLongitude": 151.2153, "GPSAltitude": 12}]`,
+			want:    Metadata{Latitude: -33.8568, Longitude: 151.2153, Altitude: 12},
+		},
+		{
+			name:    "unreadable coordinate",
+			ext:     ".json",
+			content: `{"GPSLatitude": "somewhere"}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			ext:     ".json",
+			content: `"text"`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr, ok := GetSidecarReader(tt.ext)
+			if !ok || sr == nil {
+				t.Fatalf("no reader for %s", tt.ext)
+			}
+			got, err := sr.ReadSidecar(strings.NewReader(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadSidecar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Description != tt.want.Description || !got.DateTaken.Equal(tt.want.DateTaken) ||
+				math.Abs(got.Latitude-tt.want.Latitude) > 1e-6 || math.Abs(got.Longitude-tt.want.Longitude) > 1e-6 ||
+				math.Abs(got.Altitude-tt.want.Altitude) > 1e-6 ||
+				!slices.Equal(got.Tags, tt.want.Tags) {
+				t.Errorf("ReadSidecar() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterSidecar(t *testing.T) {
+	if sr, ok := GetSidecarReader(".THM"); !ok || sr != nil {
+		t.Errorf("the thumbnails should be ignored")
+	}
+	if _, ok := GetSidecarReader(".ini"); ok {
+		t.Errorf(".ini isn't a sidecar format")
+	}
+	RegisterSidecar(".ini", SidecarReaderFunc(func(r io.Reader) (Metadata, error) {
+		return Metadata{Model: "test"}, nil
+	}))
+	defer func() {
+		sidecarLock.Lock()
+		delete(sidecarReaders, ".ini")
+		sidecarLock.Unlock()
+	}()
+	sr, ok := GetSidecarReader(".INI")
+	if !ok || sr == nil {
+		t.Fatalf("the .ini reader isn't registered")
+	}
+	md, _ := sr.ReadSidecar(strings.NewReader(""))
+	if md.Model != "test" {
+		t.Errorf("unexpected metadata %+v", md)
+	}
+}
+
+func TestMetadataMerge(t *testing.T) {
+	md := Metadata{Description: "file", DateTaken: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"a"}}
+	md.Merge(Metadata{Description: "sidecar", Latitude: 1, Longitude: 2, Tags: []string{"a", "b"}})
+	if md.Description != "sidecar" || md.DateTaken.IsZero() || md.Latitude != 1 || md.Longitude != 2 || !slices.Equal(md.Tags, []string{"a", "b"}) {
+		t.Errorf("unexpected merge %+v", md)
+	}
+}
+
+func TestReadPicasaIni(t *testing.T) {
+	ini := "\ufeff[Picasa]\nname=Trip\n[IMG_0001.jpg]\nstar=yes\ncaption=Sunset\nkeywords=beach, summer\n\n[IMG_0002.jpg]\nkeywords=beach\n[IMG_0003.jpg]\nstar=yes\n"
+	got, err := ReadPicasaIni(strings.NewReader(ini))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Metadata{
+		"IMG_0001.jpg": {Description: "Sunset", Tags: []string{"beach", "summer"}},
+		"IMG_0002.jpg": {Tags: []string{"beach"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPicasaIni() = %+v, want %+v", got, want)
+	}
+	if !IsPicasaIni(".picasa.ini") || !IsPicasaIni("Picasa.ini") || IsPicasaIni("desktop.ini") {
+		t.Errorf("unexpected IsPicasaIni")
+	}
+}
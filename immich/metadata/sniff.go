@@ -0,0 +1,83 @@
+package metadata
+
+import (
+	"bytes"
+)
+
+// SniffSize is the number of bytes needed by SniffExtension
+const SniffSize = 64
+
+// SniffExtension gives the usual extension of the file format recognized by the first bytes of the file,
+// or "" when the format isn't recognized.
+func SniffExtension(b []byte) string {
+	has := func(offset int, sig string) bool {
+		return len(b) >= offset+len(sig) && string(b[offset:offset+len(sig)]) == sig
+	}
+	switch {
+	case has(0, "\xff\xd8\xff"):
+		return ".jpg"
+	case has(0, "\x89PNG\r\n\x1a\n"):
+		return ".png"
+	case has(0, "GIF87a"), has(0, "GIF89a"):
+		return ".gif"
+	case has(0, "II*\x00") && has(8, "CR"):
+		return ".cr2"
+	case has(0, "II*\x00"), has(0, "MM\x00*"):
+		return ".tif"
+	case has(0, "IIRO"), has(0, "IIRS"):
+		return ".orf"
+	case has(0, "IIU\x00"):
+		return ".rw2"
+	case has(0, "FUJIFILMCCD-RAW"):
+		return ".raf"
+	case has(0, "8BPS"):
+		return ".psd"
+	case has(0, "\xff\x0a"), has(0, "\x00\x00\x00\x0cJXL \r\n\x87\n"):
+		return ".jxl"
+	case has(0, "RIFF") && has(8, "WEBP"):
+		return ".webp"
+	case has(0, "RIFF") && has(8, "AVI "):
+		return ".avi"
+	case has(0, "\x1a\x45\xdf\xa3"):
+		if bytes.Contains(b, []byte("webm")) {
+			return ".webm"
+		}
+		return ".mkv"
+	case has(0, "\x30\x26\xb2\x75\x8e\x66\xcf\x11"):
+		return ".wmv"
+	case has(0, "FLV\x01"):
+		return ".flv"
+	case has(0, "\x00\x00\x01\xba"):
+		return ".mpg"
+	case has(4, "ftyp") && len(b) >= 12:
+		return ftypExtension(b)
+	}
+	return ""
+}
+
+// ftypExtension gives the extension of an ISO base media file after its brands
+func ftypExtension(b []byte) string {
+	brand := string(b[8:12])
+	// the compatible brands follow the major brand and the minor version
+	var compatible []byte
+	if size := int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3]); size > 16 {
+		compatible = b[16:min(size, len(b))]
+	}
+	switch brand {
+	case "avif", "avis":
+		return ".avif"
+	case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+		if bytes.Contains(compatible, []byte("avif")) {
+			return ".avif"
+		}
+		return ".heic"
+	case "crx ":
+		return ".cr3"
+	case "qt  ":
+		return ".mov"
+	}
+	if brand[:3] == "3gp" || brand[:3] == "3g2" {
+		return ".3gp"
+	}
+	return ".mp4"
+}
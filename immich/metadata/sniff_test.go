@@ -0,0 +1,37 @@
+package metadata
+
+import "testing"
+
+func TestSniffExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		b    string
+		want string
+	}{
+		{name: "jpeg", b: "\xff\xd8\xff\xe1\x00\x10Exif", want: ".jpg"},
+		{name: "png", b: "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR", want: ".png"},
+		{name: "gif", b: "GIF89a\x01\x00", want: ".gif"},
+		{name: "tiff", b: "II*\x00\x08\x00\x00\x00", want: ".tif"},
+		{name: "cr2", b: "II*\x00\x10\x00\x00\x00CR\x02\x00", want: ".cr2"},
+		{name: "heic", b: "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00mif1heic", want: ".heic"},
+		{name: "avif", b: "\x00\x00\x00\x1cftypmif1\x00\x00\x00\x00mif1avifmiaf", want: ".avif"},
+		{name: "cr3", b: "\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01crx isom", want: ".cr3"},
+		{name: "mov", b: "\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00qt  ", want: ".mov"},
+		{name: "mp4", b: "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2", want: ".mp4"},
+		{name: "3gp", b: "\x00\x00\x00\x14ftyp3gp5\x00\x00\x00\x003gp5", want: ".3gp"},
+		{name: "webp", b: "RIFF\x24\x00\x00\x00WEBPVP8 ", want: ".webp"},
+		{name: "avi", b: "RIFF\x24\x00\x00\x00AVI LIST", want: ".avi"},
+		{name: "webm", b: "\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm", want: ".webm"},
+		{name: "mkv", b: "\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x88matroska", want: ".mkv"},
+		{name: "text", b: "hello world", want: ""},
+		{name: "short", b: "\xff\xd8", want: ""},
+		{name: "empty", b: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffExtension([]byte(tt.b)); got != tt.want {
+				t.Errorf("SniffExtension() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
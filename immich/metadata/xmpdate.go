@@ -0,0 +1,81 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	nsEXIF      = "http://ns.adobe.com/exif/1.0/"
+	nsPhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+)
+
+// xmpDateFields are the fields giving the date of take, by order of preference
+var xmpDateFields = []xml.Name{
+	{Space: nsEXIF, Local: "DateTimeOriginal"},
+	{Space: nsPhotoshop, Local: "DateCreated"},
+	{Space: nsXMP, Local: "CreateDate"},
+}
+
+// ReadXMPDateTaken gives the date of take of a XMP packet, read from the fields exif:DateTimeOriginal,
+// photoshop:DateCreated or xmp:CreateDate. The date is local when the time zone isn't given.
+// The date is zero when the packet hasn't any.
+func ReadXMPDateTaken(r io.Reader) (t time.Time, isLocal bool, err error) {
+	d := xml.NewDecoder(r)
+	found := make([]string, len(xmpDateFields))
+	field := -1
+	text := strings.Builder{}
+	indexOf := func(n xml.Name) int {
+		for i, f := range xmpDateFields {
+			if f == n {
+				return i
+			}
+		}
+		return -1
+	}
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			// <rdf:Description exif:DateTimeOriginal="...">
+			for _, a := range tok.Attr {
+				if i := indexOf(a.Name); i >= 0 && found[i] == "" {
+					found[i] = a.Value
+				}
+			}
+			if i := indexOf(tok.Name); i >= 0 {
+				field = i
+				text.Reset()
+			}
+		case xml.CharData:
+			if field >= 0 {
+				text.Write(tok)
+			}
+		case xml.EndElement:
+			if field >= 0 && tok.Name == xmpDateFields[field] {
+				if found[field] == "" {
+					found[field] = text.String()
+				}
+				field = -1
+			}
+		}
+	}
+	for _, s := range found {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if t, isLocal = parseSidecarDate(s); !t.IsZero() {
+			return t, isLocal, nil
+		}
+	}
+	return time.Time{}, false, nil
+}
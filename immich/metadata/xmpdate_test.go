@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadXMPDateTaken(t *testing.T) {
+	const head = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">`
+	const tail = `</rdf:RDF></x:xmpmeta>`
+	tests := []struct {
+		xmp     string
+		want    time.Time
+		local   bool
+		wantErr bool
+	}{
+		{
+			xmp:  head + `<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" exif:DateTimeOriginal="2023-06-01T10:20:30+02:00"/>` + tail,
+			want: time.Date(2023, 6, 1, 8, 20, 30, 0, time.UTC),
+		},
+		{
+			xmp:   head + `<rdf:Description xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/"><photoshop:DateCreated>2021-12-24T18:00</photoshop:DateCreated></rdf:Description>` + tail,
+			want:  time.Date(2021, 12, 24, 18, 0, 0, 0, local),
+			local: true,
+		},
+		{
+			// exif:DateTimeOriginal is preferred to xmp:CreateDate
+			xmp:   head + `<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:exif="http://ns.adobe.com/exif/1.0/" xmp:CreateDate="2020-01-01T00:00:00"><exif:DateTimeOriginal>2019-07-14T12:00:00</exif:DateTimeOriginal></rdf:Description>` + tail,
+			want:  time.Date(2019, 7, 14, 12, 0, 0, 0, local),
+			local: true,
+		},
+		{
+			xmp: head + `<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="3"/>` + tail,
+		},
+		{
+			xmp:     head + `<rdf:Description>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, isLocal, err := ReadXMPDateTaken(strings.NewReader(tt.xmp))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if !got.Equal(tt.want) || isLocal != tt.local {
+			t.Errorf("expected %s,%v, got %s,%v", tt.want, tt.local, got, isLocal)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// SetAccessToken authenticates the calls with the session token obtained by the OAuth login instead of the API key
+func (ic *ImmichClient) SetAccessToken(token string) {
+	ic.accessToken = token
+}
+
+// LoginResponse is the session opened by the OAuth login
+type LoginResponse struct {
+	AccessToken          string `json:"accessToken"`
+	UserID               string `json:"userId"`
+	UserEmail            string `json:"userEmail"`
+	Name                 string `json:"name"`
+	IsAdmin              bool   `json:"isAdmin"`
+	ShouldChangePassword bool   `json:"shouldChangePassword"`
+}
+
+// OAuthAuthorize gives the address of the identity provider's login page.
+// The provider redirects the browser to redirectURI once the user is logged in.
+func (ic *ImmichClient) OAuthAuthorize(ctx context.Context, redirectURI string) (string, error) {
+	var r struct {
+		URL string `json:"url"`
+	}
+	err := ic.newServerCall(ctx, EndPointOAuthAuthorize).do(
+		postRequest("/oauth/authorize", "application/json", setAcceptJSON(), setJSONBody(struct {
+			RedirectURI string `json:"redirectUri"`
+		}{RedirectURI: redirectURI})),
+		responseJSON(&r))
+	return r.URL, err
+}
+
+// OAuthCallback opens a session with the address receiving the provider's redirection, containing the authorization code
+func (ic *ImmichClient) OAuthCallback(ctx context.Context, callbackURL string) (LoginResponse, error) {
+	var r LoginResponse
+	err := ic.newServerCall(ctx, EndPointOAuthCallback).do(
+		postRequest("/oauth/callback", "application/json", setAcceptJSON(), setJSONBody(struct {
+			URL string `json:"url"`
+		}{URL: callbackURL})),
+		responseJSON(&r))
+	return r, err
+}
+
+// IsUnauthorized tells if the server has rejected the key or the session token
+func IsUnauthorized(err error) bool {
+	var ce callError
+	return errors.As(err, &ce) && ce.status == http.StatusUnauthorized
+}
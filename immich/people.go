@@ -0,0 +1,83 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+)
+
+type Person struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	IsHidden bool   `json:"isHidden"`
+}
+
+// GetAllPeople gives all the persons known by the server, including the hidden ones
+func (ic *ImmichClient) GetAllPeople(ctx context.Context) ([]Person, error) {
+	var people []Person
+	for page := 1; ; page++ {
+		var r struct {
+			People      []Person `json:"people"`
+			HasNextPage bool     `json:"hasNextPage"`
+		}
+		err := ic.newServerCall(ctx, EndPointGetAllPeople).do(
+			getRequest(fmt.Sprintf("/people?withHidden=true&page=%d&size=500", page), setAcceptJSON()),
+			responseJSON(&r))
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, r.People...)
+		if !r.HasNextPage || len(r.People) == 0 {
+			return people, nil
+		}
+	}
+}
+
+func (ic *ImmichClient) CreatePerson(ctx context.Context, name string) (Person, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{Name: name}
+	var r Person
+	err := ic.newServerCall(ctx, EndPointCreatePerson).do(
+		postRequest("/people", "application/json", setAcceptJSON(), setJSONBody(body)),
+		responseJSON(&r))
+	return r, err
+}
+
+// UpdatePerson renames the person
+func (ic *ImmichClient) UpdatePerson(ctx context.Context, id string, name string) (Person, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{Name: name}
+	var r Person
+	err := ic.newServerCall(ctx, EndPointUpdatePerson).do(
+		putRequest("/people/"+id, setAcceptJSON(), setJSONBody(body)),
+		responseJSON(&r))
+	return r, err
+}
+
+// MergePeople merges the persons ids into the person id: their faces are given to the person, and they are deleted
+func (ic *ImmichClient) MergePeople(ctx context.Context, id string, ids []string) ([]UpdateAlbumResult, error) {
+	var r []UpdateAlbumResult
+	err := ic.newServerCall(ctx, EndPointMergePeople).do(
+		postRequest("/people/"+id+"/merge", "application/json", setAcceptJSON(), setJSONBody(UpdateAlbum{IDS: ids})),
+		responseJSON(&r))
+	return r, err
+}
+
+// Face is the region of a person in an asset.
+// The region is given in the coordinates of an image of ImageWidth x ImageHeight.
+type Face struct {
+	AssetID     string `json:"assetId"`
+	PersonID    string `json:"personId"`
+	ImageWidth  int    `json:"imageWidth"`
+	ImageHeight int    `json:"imageHeight"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+func (ic *ImmichClient) CreateFace(ctx context.Context, face Face) error {
+	return ic.newServerCall(ctx, EndPointCreateFace).do(
+		postRequest("/faces", "application/json", setJSONBody(face)))
+}
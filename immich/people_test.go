@@ -0,0 +1,51 @@
+package immich
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMergePeople(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		calls = append(calls, req.Method+" "+req.URL.Path+" "+strings.TrimSpace(string(b)))
+		switch req.URL.Path {
+		case "/api/people/p1":
+			_, _ = resp.Write([]byte(`{"id":"p1","name":"John Doe"}`))
+		default:
+			_, _ = resp.Write([]byte(`[{"id":"p2","success":true}]`))
+		}
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ic.UpdatePerson(context.Background(), "p1", "John Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "John Doe" {
+		t.Errorf("unexpected person: %v", p)
+	}
+	r, err := ic.MergePeople(context.Background(), "p1", []string{"p2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || !r[0].Success {
+		t.Errorf("unexpected result: %v", r)
+	}
+	expected := []string{
+		`PUT /api/people/p1 {"name":"John Doe"}`,
+		`POST /api/people/p1/merge {"ids":["p2"]}`,
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(calls, "\n"))
+	}
+}
@@ -0,0 +1,43 @@
+package immich
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// SharedLinkTypeAlbum is the type of the links sharing an album
+const SharedLinkTypeAlbum = "ALBUM"
+
+// SharedLinkCreate gives the parameters of a new shared link
+type SharedLinkCreate struct {
+	Type          string     `json:"type"`
+	AlbumID       string     `json:"albumId,omitempty"`
+	Password      string     `json:"password,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	AllowDownload bool       `json:"allowDownload"`
+	AllowUpload   bool       `json:"allowUpload"`
+	ShowMetadata  bool       `json:"showMetadata"`
+}
+
+// SharedLink is a link giving access to an album without an account on the server
+type SharedLink struct {
+	ID        string     `json:"id"`
+	Key       string     `json:"key"`
+	Type      string     `json:"type"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// URL gives the address of the shared link on the server
+func (l SharedLink) URL(server string) string {
+	return strings.TrimSuffix(server, "/") + "/share/" + l.Key
+}
+
+// CreateSharedLink creates a shared link
+func (ic *ImmichClient) CreateSharedLink(ctx context.Context, link SharedLinkCreate) (SharedLink, error) {
+	var r SharedLink
+	err := ic.newServerCall(ctx, EndPointCreateSharedLink).do(
+		postRequest("/shared-links", "application/json", setAcceptJSON(), setJSONBody(link)),
+		responseJSON(&r))
+	return r, err
+}
@@ -0,0 +1,36 @@
+package immich
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateSharedLink(t *testing.T) {
+	var call string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		call = req.Method + " " + req.URL.Path + " " + strings.TrimSpace(string(b))
+		_, _ = resp.Write([]byte(`{"id":"l1","key":"abcd","type":"ALBUM"}`))
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ic.CreateSharedLink(context.Background(), SharedLinkCreate{Type: SharedLinkTypeAlbum, AlbumID: "a1", AllowDownload: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `POST /api/shared-links {"type":"ALBUM","albumId":"a1","allowDownload":true,"allowUpload":false,"showMetadata":false}`
+	if call != expected {
+		t.Errorf("expected the call:\n%s\ngot:\n%s", expected, call)
+	}
+	if u := l.URL("https://photos.example.com/"); u != "https://photos.example.com/share/abcd" {
+		t.Errorf("unexpected URL %q", u)
+	}
+}
@@ -0,0 +1,47 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+)
+
+// Tag is a tag of the server. The name of a nested tag is its last part, the value is the full path, ex: Places/Paris
+type Tag struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (ic *ImmichClient) GetAllTags(ctx context.Context) ([]Tag, error) {
+	var r []Tag
+	err := ic.newServerCall(ctx, EndPointGetAllTags).do(getRequest("/tags", setAcceptJSON()), responseJSON(&r))
+	return r, err
+}
+
+// UpsertTags gives the tags of the names, created when missing
+func (ic *ImmichClient) UpsertTags(ctx context.Context, names []string) ([]Tag, error) {
+	body := struct {
+		Tags []string `json:"tags"`
+	}{Tags: names}
+	var r []Tag
+	err := ic.newServerCall(ctx, EndPointUpsertTags).do(putRequest("/tags", setAcceptJSON(), setJSONBody(body)), responseJSON(&r))
+	return r, err
+}
+
+// TagAssets adds the tag to the assets
+func (ic *ImmichClient) TagAssets(ctx context.Context, tagID string, ids []string) ([]UpdateAlbumResult, error) {
+	var r []UpdateAlbumResult
+	err := ic.newServerCall(ctx, EndPointTagAssets).do(
+		putRequest(fmt.Sprintf("/tags/%s/assets", tagID), setAcceptJSON(), setJSONBody(UpdateAlbum{IDS: ids})),
+		responseJSON(&r))
+	return r, err
+}
+
+// UntagAssets removes the tag from the assets
+func (ic *ImmichClient) UntagAssets(ctx context.Context, tagID string, ids []string) ([]UpdateAlbumResult, error) {
+	var r []UpdateAlbumResult
+	err := ic.newServerCall(ctx, EndPointUntagAssets).do(
+		deleteRequest(fmt.Sprintf("/tags/%s/assets", tagID), setAcceptJSON(), setJSONBody(UpdateAlbum{IDS: ids})),
+		responseJSON(&r))
+	return r, err
+}
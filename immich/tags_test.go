@@ -0,0 +1,56 @@
+package immich
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTagAssets(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		calls = append(calls, req.Method+" "+req.URL.Path+" "+strings.TrimSpace(string(b)))
+		switch req.URL.Path {
+		case "/api/tags":
+			_, _ = resp.Write([]byte(`[{"id":"t1","name":"scanned","value":"scanned"}]`))
+		default:
+			_, _ = resp.Write([]byte(`[{"id":"1","success":true}]`))
+		}
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := ic.UpsertTags(context.Background(), []string{"scanned"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].ID != "t1" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+	r, err := ic.TagAssets(context.Background(), "t1", []string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || !r[0].Success {
+		t.Errorf("unexpected result: %v", r)
+	}
+	_, err = ic.UntagAssets(context.Background(), "t1", []string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`PUT /api/tags {"tags":["scanned"]}`,
+		`PUT /api/tags/t1/assets {"ids":["1"]}`,
+		`DELETE /api/tags/t1/assets {"ids":["1"]}`,
+	}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(calls, "\n"))
+	}
+}
@@ -0,0 +1,122 @@
+package immich
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	maxThrottleRetries  = 10              // number of times a call is sent again when the server asks to slow down
+	maxThrottleWait     = 5 * time.Minute // longest pause accepted, the server's Retry-After is capped
+	defaultThrottleWait = 5 * time.Second // pause when the server doesn't give a Retry-After
+)
+
+// throttle pauses the calls when the server is overloaded
+type throttle struct {
+	lock    sync.Mutex
+	all     bool      // pause all the calls, not only the throttled one
+	until   time.Time // end of the pause of all the calls
+	handler func(endPoint string, wait time.Duration)
+}
+
+// OptionThrottleAll pauses all the calls to the server when one of them is throttled,
+// instead of pausing only the throttled call
+func OptionThrottleAll(all bool) clientOption {
+	return func(ic *ImmichClient) error {
+		ic.throttle.all = all
+		return nil
+	}
+}
+
+// SetThrottleHandler sets the function called when the server asks to slow down, ex: to show the pause in the progress
+func (ic *ImmichClient) SetThrottleHandler(fn func(endPoint string, wait time.Duration)) {
+	ic.throttle.lock.Lock()
+	defer ic.throttle.lock.Unlock()
+	ic.throttle.handler = fn
+}
+
+// throttled records the pause asked by the server
+func (t *throttle) throttled(endPoint string, wait time.Duration) {
+	t.lock.Lock()
+	if t.all {
+		if until := time.Now().Add(wait); until.After(t.until) {
+			t.until = until
+		}
+	}
+	fn := t.handler
+	t.lock.Unlock()
+	if fn != nil {
+		fn(endPoint, wait)
+	}
+}
+
+// wait holds the call during the pause of all the calls
+func (t *throttle) wait(ctx context.Context) error {
+	t.lock.Lock()
+	d := time.Until(t.until)
+	t.lock.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	return sleep(ctx, d)
+}
+
+// sleep waits for the duration, or the cancellation of the context
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryAfter tells if the server asks to slow down, with a 429, or a 503 giving a Retry-After, and gives the pause
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	h := resp.Header.Get("Retry-After")
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusServiceUnavailable && h != "":
+	default:
+		return 0, false
+	}
+	d := defaultThrottleWait
+	if s, err := strconv.Atoi(h); err == nil {
+		d = time.Duration(s) * time.Second
+	} else if t, err := http.ParseTime(h); err == nil {
+		d = t.Sub(now)
+	}
+	return min(max(d, 0), maxThrottleWait), true
+}
+
+// rewind prepares the request to be sent again, it fails when the body can't be read again
+func rewind(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, true
+}
+
+// throttledError tells if the call failed because the server asked to slow down, and gives the pause
+func throttledError(err error) (time.Duration, bool) {
+	var ce callError
+	if !errors.As(err, &ce) {
+		return 0, false
+	}
+	return ce.retryAfter, ce.throttled
+}
@@ -0,0 +1,111 @@
+package immich
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name      string
+		status    int
+		header    string
+		wait      time.Duration
+		throttled bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "429 without header", status: http.StatusTooManyRequests, wait: defaultThrottleWait, throttled: true},
+		{name: "429 in seconds", status: http.StatusTooManyRequests, header: "12", wait: 12 * time.Second, throttled: true},
+		{name: "503 with a date", status: http.StatusServiceUnavailable, header: now.Add(30 * time.Second).Format(http.TimeFormat), wait: 30 * time.Second, throttled: true},
+		{name: "503 without header", status: http.StatusServiceUnavailable},
+		{name: "date in the past", status: http.StatusTooManyRequests, header: now.Add(-time.Minute).Format(http.TimeFormat), wait: 0, throttled: true},
+		{name: "too long", status: http.StatusTooManyRequests, header: "3600", wait: maxThrottleWait, throttled: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+			if tc.header != "" {
+				resp.Header.Set("Retry-After", tc.header)
+			}
+			wait, throttled := retryAfter(resp, now)
+			if wait != tc.wait || throttled != tc.throttled {
+				t.Errorf("expected %s %v, got %s %v", tc.wait, tc.throttled, wait, throttled)
+			}
+		})
+	}
+}
+
+// throttlingServer answers 429 to the first calls
+func throttlingServer(throttles int32, body string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	return httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		if calls.Add(1) <= throttles {
+			resp.Header().Set("Retry-After", "0")
+			resp.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		resp.WriteHeader(http.StatusCreated)
+		_, _ = resp.Write([]byte(body))
+	})), &calls
+}
+
+func TestCallThrottled(t *testing.T) {
+	server, calls := throttlingServer(2, `{"Name": "test"}`)
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234", OptionThrottleAll(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified atomic.Int32
+	ic.SetThrottleHandler(func(endPoint string, wait time.Duration) { notified.Add(1) })
+
+	r := map[string]string{}
+	err = ic.newServerCall(context.Background(), "test").do(postRequest("/albums", "application/json", setAcceptJSON(), setJSONBody(struct{ Name string }{Name: "test"})), responseJSON(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 || notified.Load() != 2 || r["Name"] != "test" {
+		t.Errorf("unexpected calls %d, notifications %d, response %v", calls.Load(), notified.Load(), r)
+	}
+}
+
+func TestAssetUploadThrottled(t *testing.T) {
+	server, calls := throttlingServer(2, `{"id":"123","status":"created"}`)
+	defer server.Close()
+
+	// a single attempt: the throttling must not consume it
+	ic, err := NewImmichClient(server.URL, "1234", OptionUploadRetries(1, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic.supportedMediaTypes = DefaultSupportedMedia
+
+	fsys := fstest.MapFS{
+		"photo.jpg": &fstest.MapFile{Data: []byte("photo")},
+	}
+	la := &browser.LocalAssetFile{
+		FSys:     fsys,
+		FileName: "photo.jpg",
+		Title:    "photo.jpg",
+		FileSize: 5,
+	}
+	ar, err := ic.AssetUpload(context.Background(), la)
+	la.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 || ar.ID != "123" {
+		t.Errorf("unexpected calls %d, response %#v", calls.Load(), ar)
+	}
+}
@@ -0,0 +1,28 @@
+package immich
+
+import (
+	"context"
+	"strconv"
+)
+
+// TimeBucket gives the number of assets of a month
+type TimeBucket struct {
+	TimeBucket string `json:"timeBucket"` // first day of the month, ex: 2024-06-01T00:00:00.000Z or 2024-06-01
+	Count      int    `json:"count"`
+}
+
+// Year gives the year of the bucket, 0 when it can't be read
+func (b TimeBucket) Year() int {
+	if len(b.TimeBucket) < 4 {
+		return 0
+	}
+	y, _ := strconv.Atoi(b.TimeBucket[:4])
+	return y
+}
+
+// GetTimeBuckets gives the number of assets of the user by month, the archived and trashed assets excepted
+func (ic *ImmichClient) GetTimeBuckets(ctx context.Context) ([]TimeBucket, error) {
+	var r []TimeBucket
+	err := ic.newServerCall(ctx, EndPointGetTimeBuckets).do(getRequest("/timeline/buckets?size=MONTH", setAcceptJSON()), responseJSON(&r))
+	return r, err
+}
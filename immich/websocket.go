@@ -0,0 +1,262 @@
+package immich
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// EventUploadSuccess is sent by the server when the thumbnails of a new asset are generated
+const EventUploadSuccess = "on_upload_success"
+
+// ServerEvent is an event sent by the server through its websocket
+type ServerEvent struct {
+	Name string
+	Data json.RawMessage // first argument of the event, ex: the asset
+}
+
+// ListenEvents connects to the websocket of the server and calls fn with each event received,
+// until the context is cancelled or the server closes the connection.
+//
+// The server speaks socket.io (Engine.IO v4) over the websocket.
+func (ic *ImmichClient) ListenEvents(ctx context.Context, fn func(ServerEvent)) error {
+	ws, err := ic.dialWebsocket(ctx, "/socket.io/?EIO=4&transport=websocket")
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	for {
+		msg, err := ws.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if msg == "" {
+			continue
+		}
+		switch msg[0] {
+		case '0': // open, join the default namespace
+			err = ws.WriteMessage("40")
+		case '1': // close
+			return io.EOF
+		case '2': // ping
+			err = ws.WriteMessage("3" + msg[1:])
+		case '4':
+			err = handleSocketIOPacket(msg[1:], fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handleSocketIOPacket decodes the socket.io packet and gives the events to fn
+func handleSocketIOPacket(p string, fn func(ServerEvent)) error {
+	if p == "" {
+		return nil
+	}
+	switch p[0] {
+	case '4': // connection refused
+		return fmt.Errorf("the server refused the websocket connection: %s", p[1:])
+	case '2': // event: 2["name",data...]
+		i := strings.Index(p, "[")
+		if i < 0 {
+			return nil
+		}
+		var args []json.RawMessage
+		err := json.Unmarshal([]byte(p[i:]), &args)
+		if err != nil || len(args) == 0 {
+			return nil
+		}
+		var e ServerEvent
+		err = json.Unmarshal(args[0], &e.Name)
+		if err != nil {
+			return nil
+		}
+		if len(args) > 1 {
+			e.Data = args[1]
+		}
+		fn(e)
+	}
+	return nil
+}
+
+// websocketGUID is the magic value of the handshake, given by the RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsConn is the client side of a websocket, with text messages only
+type wsConn struct {
+	rw io.ReadWriteCloser
+	r  *bufio.Reader
+}
+
+// dialWebsocket opens the websocket with the transport of the client, so the TLS options and the proxy apply
+func (ic *ImmichClient) dialWebsocket(ctx context.Context, path string) (*wsConn, error) {
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	secKey := base64.StdEncoding.EncodeToString(key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ic.endPoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", secKey)
+	if ic.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+ic.accessToken)
+	} else {
+		req.Header.Set("x-api-key", ic.key)
+	}
+
+	// the client's timeout would close the connection
+	client := http.Client{Transport: ic.roundTripper}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return nil, fmt.Errorf("the server doesn't accept the websocket connection: %s", resp.Status)
+	}
+	rw, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, errors.New("the websocket connection can't be written")
+	}
+	h := sha1.Sum([]byte(secKey + websocketGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(h[:]) {
+		rw.Close()
+		return nil, errors.New("invalid websocket handshake")
+	}
+	return &wsConn{rw: rw, r: bufio.NewReader(rw)}, nil
+}
+
+// websocket opcodes
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// ReadMessage gives the next text message, the control frames are handled meanwhile
+func (c *wsConn) ReadMessage() (string, error) {
+	var msg []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return "", err
+		}
+		switch opcode {
+		case wsClose:
+			_ = c.writeFrame(wsClose, nil)
+			return "", io.EOF
+		case wsPing:
+			err = c.writeFrame(wsPong, payload)
+			if err != nil {
+				return "", err
+			}
+			continue
+		case wsPong:
+			continue
+		}
+		msg = append(msg, payload...)
+		if fin {
+			return string(msg), nil
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var h [2]byte
+	_, err = io.ReadFull(c.r, h[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	fin = h[0]&0x80 != 0
+	opcode = h[0] & 0x0F
+	masked := h[1]&0x80 != 0
+	size := uint64(h[1] & 0x7F)
+	switch size {
+	case 126:
+		var b [2]byte
+		_, err = io.ReadFull(c.r, b[:])
+		size = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		_, err = io.ReadFull(c.r, b[:])
+		size = binary.BigEndian.Uint64(b[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if size > 64<<20 {
+		return false, 0, nil, fmt.Errorf("websocket frame too large: %d bytes", size)
+	}
+	var mask [4]byte
+	if masked {
+		_, err = io.ReadFull(c.r, mask[:])
+		if err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, size)
+	_, err = io.ReadFull(c.r, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage sends a text message
+func (c *wsConn) WriteMessage(msg string) error {
+	return c.writeFrame(wsText, []byte(msg))
+}
+
+// writeFrame sends a frame, masked as required for the client
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	b := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		b = append(b, 0x80|byte(n))
+	case n < 1<<16:
+		b = append(b, 0x80|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, 0x80|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	var mask [4]byte
+	_, _ = rand.Read(mask[:])
+	b = append(b, mask[:]...)
+	for i, p := range payload {
+		b = append(b, p^mask[i%4])
+	}
+	_, err := c.rw.Write(b)
+	return err
+}
+
+func (c *wsConn) Close() error {
+	return c.rw.Close()
+}
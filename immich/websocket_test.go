@@ -0,0 +1,100 @@
+package immich
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// socketIOServer speaks the socket.io protocol of the immich server: it sends a ping, then the events
+func socketIOServer(t *testing.T, events []string, pongs chan<- string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/socket.io/" || r.Header.Get("x-api-key") != "1234" || r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+		_ = rw.Flush()
+
+		// the server's frames aren't masked
+		send := func(msg string) {
+			_, _ = rw.Write(append([]byte{0x80 | wsText, byte(len(msg))}, msg...))
+			_ = rw.Flush()
+		}
+		ws := &wsConn{rw: conn, r: bufio.NewReader(rw)}
+
+		send(`0{"sid":"s1","pingInterval":25000,"pingTimeout":20000}`)
+		msg, err := ws.ReadMessage()
+		if err != nil || msg != "40" {
+			t.Errorf("expected the connection to the namespace, got %q, %v", msg, err)
+			return
+		}
+		send(`40{"sid":"n1"}`)
+		send("2")
+		msg, err = ws.ReadMessage()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		pongs <- msg
+		for _, e := range events {
+			send(e)
+		}
+		_, _ = rw.Write([]byte{0x80 | wsClose, 0})
+		_ = rw.Flush()
+	}))
+}
+
+func TestListenEvents(t *testing.T) {
+	pongs := make(chan string, 1)
+	server := socketIOServer(t, []string{
+		`42["on_upload_success",{"id":"a1"}]`,
+		`42["on_server_version",{"major":1}]`,
+		`42["on_upload_success",{"id":"a2"}]`,
+	}, pongs)
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	err = ic.ListenEvents(context.Background(), func(e ServerEvent) {
+		if e.Name == EventUploadSuccess {
+			ids = append(ids, string(e.Data))
+		}
+	})
+	if err == nil || err.Error() != "EOF" {
+		t.Errorf("expected the end of the connection, got %v", err)
+	}
+	if p := <-pongs; p != "3" {
+		t.Errorf("expected the pong, got %q", p)
+	}
+	if len(ids) != 2 || ids[0] != `{"id":"a1"}` || ids[1] != `{"id":"a2"}` {
+		t.Errorf("unexpected events %v", ids)
+	}
+}
+
+func TestListenEventsRefused(t *testing.T) {
+	server := socketIOServer(t, nil, make(chan string, 1))
+	defer server.Close()
+	ic, err := NewImmichClient(server.URL, "wrong key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ic.ListenEvents(context.Background(), func(e ServerEvent) {})
+	if err == nil {
+		t.Errorf("an error is expected")
+	}
+}
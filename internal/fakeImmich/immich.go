@@ -3,6 +3,8 @@ package fakeimmich
 import (
 	"context"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/simulot/immich-go/browser"
 	"github.com/simulot/immich-go/immich"
@@ -14,14 +16,66 @@ func (c *MockedCLient) GetAllAssetsWithFilter(context.Context, func(*immich.Asse
 	return nil
 }
 
+func (c *MockedCLient) SearchAssets(context.Context, immich.SearchQuery, func(*immich.Asset) error) error {
+	return nil
+}
+
 func (c *MockedCLient) AssetUpload(context.Context, *browser.LocalAssetFile) (immich.AssetResponse, error) {
 	return immich.AssetResponse{}, nil
 }
 
+func (c *MockedCLient) CheckBulkUpload(context.Context, []immich.AssetBulkUploadCheckItem) ([]immich.AssetBulkUploadCheckResult, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) UpdateAlbum(context.Context, string, immich.AlbumUpdate) (immich.AlbumSimplified, error) {
+	return immich.AlbumSimplified{}, nil
+}
+
+func (c *MockedCLient) CreateSharedLink(ctx context.Context, link immich.SharedLinkCreate) (immich.SharedLink, error) {
+	return immich.SharedLink{}, nil
+}
+
+func (c *MockedCLient) GetAllLibraries(ctx context.Context) ([]immich.Library, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) CreateLibrary(ctx context.Context, library immich.LibraryCreate) (immich.Library, error) {
+	return immich.Library{}, nil
+}
+
+func (c *MockedCLient) ScanLibrary(ctx context.Context, id string) error {
+	return nil
+}
+
+func (c *MockedCLient) GetAllUsers(ctx context.Context) ([]immich.User, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) GetOnThisDayMemories(ctx context.Context, day time.Time) ([]immich.Memory, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) GetTimeBuckets(ctx context.Context) ([]immich.TimeBucket, error) {
+	return nil, nil
+}
+
 func (c *MockedCLient) DeleteAssets(context.Context, []string, bool) error {
 	return nil
 }
 
+func (c *MockedCLient) RestoreAssets(ctx context.Context, ids []string) error {
+	return nil
+}
+
+func (c *MockedCLient) DownloadAsset(context.Context, string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (c *MockedCLient) GetAssetPreview(context.Context, string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
 func (c *MockedCLient) GetAllAlbums(context.Context) ([]immich.AlbumSimplified, error) {
 	return nil, nil
 }
@@ -38,10 +92,70 @@ func (c *MockedCLient) UpdateAssets(ctx context.Context, ids []string, isArchive
 	return nil
 }
 
+func (c *MockedCLient) BatchUpdateAssets(ctx context.Context, ids []string, update immich.AssetsUpdate) error {
+	return nil
+}
+
 func (c *MockedCLient) StackAssets(ctx context.Context, cover string, ids []string) error {
 	return nil
 }
 
+func (c *MockedCLient) UnstackAssets(ctx context.Context, ids []string) error {
+	return nil
+}
+
+func (c *MockedCLient) GetServerVersion(ctx context.Context) (immich.ServerVersion, error) {
+	return immich.ServerVersion{}, nil
+}
+
+func (c *MockedCLient) GetServerStorage(ctx context.Context) (immich.ServerStorage, error) {
+	return immich.ServerStorage{}, nil
+}
+
+func (c *MockedCLient) UpdatePerson(ctx context.Context, id string, name string) (immich.Person, error) {
+	return immich.Person{ID: id, Name: name}, nil
+}
+
+func (c *MockedCLient) MergePeople(ctx context.Context, id string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) UpdateAssetMetadata(ctx context.Context, id string, update immich.AssetMetadataUpdate) error {
+	return nil
+}
+
+func (c *MockedCLient) SetStackCover(ctx context.Context, oldCover string, newCover string) error {
+	return nil
+}
+
+func (c *MockedCLient) GetAllTags(ctx context.Context) ([]immich.Tag, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) UpsertTags(ctx context.Context, names []string) ([]immich.Tag, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) TagAssets(ctx context.Context, tagID string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) UntagAssets(ctx context.Context, tagID string, ids []string) ([]immich.UpdateAlbumResult, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) GetAllPeople(ctx context.Context) ([]immich.Person, error) {
+	return nil, nil
+}
+
+func (c *MockedCLient) CreatePerson(ctx context.Context, name string) (immich.Person, error) {
+	return immich.Person{ID: name, Name: name}, nil
+}
+
+func (c *MockedCLient) CreateFace(ctx context.Context, face immich.Face) error {
+	return nil
+}
+
 func (c *MockedCLient) UpdateAsset(ctx context.Context, id string, a *browser.LocalAssetFile) (*immich.Asset, error) {
 	return nil, nil
 }
@@ -48,6 +48,9 @@ func (f FakeFile) Stat() (fs.FileInfo, error) {
 
 func (f *FakeFile) Read(b []byte) (int, error) {
 	if f.pos < f.fi.size {
+		if int64(len(b)) > f.fi.size-f.pos {
+			b = b[:f.fi.size-f.pos]
+		}
 		n, err := f.r.Read(b)
 		f.pos += int64(n)
 		return n, err
@@ -63,6 +66,28 @@ func (f *FakeFile) Close() error {
 type FakeFS struct {
 	name  string
 	files map[string]map[string]FakeDirEntry
+	meta  map[string]fakeMeta // title and capture date of the generated files
+
+	jpegContent bool // the JPEG files start with a valid header with the date of capture
+}
+
+// Option configures a fake file system
+type Option func(fsys *FakeFS)
+
+// WithJPEGContent gives to the JPEG files a content starting with a valid JPEG header
+// and an EXIF block with the date of capture, so the files can go through the metadata extraction.
+//
+// The date is taken from the file name, or from the modification date given by the listing.
+func WithJPEGContent() Option {
+	return func(fsys *FakeFS) {
+		fsys.jpegContent = true
+	}
+}
+
+// fakeMeta is the content of a generated JSON file
+type fakeMeta struct {
+	title string
+	date  time.Time
 }
 
 func (fsys FakeFS) Name() string {
@@ -115,6 +140,10 @@ func (fsys FakeFS) Open(name string) (fs.File, error) {
 		case "print-subscriptions.json", "shared_album_comments.json", "user-generated-memory-titles.json":
 			r, fakeInfo.size = fakeJSON()
 		default:
+			if m, ok := fsys.meta[name]; ok {
+				r, fakeInfo.size = fakePhotoData(m.title, m.date)
+				break
+			}
 			d := info.ModTime()
 			if d2 := metadata.TakeTimeFromName(name); !d2.IsZero() {
 				d = d2
@@ -122,6 +151,17 @@ func (fsys FakeFS) Open(name string) (fs.File, error) {
 			title := strings.TrimSuffix(path.Base(name), path.Ext(base))
 			r, fakeInfo.size = fakePhotoData(title, d)
 		}
+	} else if ext := strings.ToLower(ext); fsys.jpegContent && (ext == ".jpg" || ext == ".jpeg") {
+		d := info.ModTime()
+		if m, ok := fsys.meta[name]; ok {
+			d = m.date
+		} else if d2 := metadata.TakeTimeFromName(name); !d2.IsZero() {
+			d = d2
+		}
+		r, fakeInfo.size, err = fakeJPEG(d, fakeInfo.size)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		r = rand.Reader
 	}
@@ -0,0 +1,240 @@
+package fakefs
+
+import (
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"path"
+	"strings"
+	"time"
+)
+
+/*
+	fabricate a takeout with the oddities of the real ones, without user's data.
+
+	- each photo is in the folder of its year, and in the folders of its albums
+	- the names longer than the takeout's limit are truncated: 47 chars for the medias, 46 chars for the JSONs
+	- the names already used in a folder are numbered: IMG_1234(1).JPG, and its JSON IMG_1234.JPG(1).json
+	- some JSONs are missing
+	- the files are spread over several zip parts, a JSON can be in another part than its media
+*/
+
+// Names limits of the takeouts, in characters
+const (
+	takeoutMediaNameMax = 47 // length of the media name, without the extension
+	takeoutJSONNameMax  = 46 // length of the JSON name, without .json
+)
+
+// TakeoutOptions gives the shape of a generated takeout
+type TakeoutOptions struct {
+	Seed             int64   // the same seed gives the same takeout
+	Photos           int     // number of photos and videos
+	Albums           int     // number of albums
+	AlbumSize        int     // maximum number of photos in an album
+	FirstYear        int     // the photos are taken between the first and the last year
+	LastYear         int     //
+	VideoRatio       float64 // proportion of videos
+	LongNameRatio    float64 // proportion of names truncated by the takeout
+	DuplicateRatio   float64 // proportion of names already used in the year, numbered by the takeout
+	MissingJSONRatio float64 // proportion of photos without JSON
+	MediaSize        int64   // average size of the photos in bytes, the videos are 10 times bigger
+	PartSize         int64   // maximum size of a zip part in bytes, 0 for a single part
+	JPEGContent      bool    // the JPEG files have a valid header with the capture date, see WithJPEGContent
+}
+
+// DefaultTakeoutOptions gives a small takeout with all the oddities
+func DefaultTakeoutOptions() TakeoutOptions {
+	return TakeoutOptions{
+		Seed:             1,
+		Photos:           1000,
+		Albums:           10,
+		AlbumSize:        50,
+		FirstYear:        2015,
+		LastYear:         2023,
+		VideoRatio:       0.1,
+		LongNameRatio:    0.05,
+		DuplicateRatio:   0.05,
+		MissingJSONRatio: 0.02,
+		MediaSize:        3_000_000,
+		PartSize:         1_000_000_000,
+	}
+}
+
+// TakeoutAsset describes a generated photo, the expected result of the takeout's reading
+type TakeoutAsset struct {
+	Title   string    // original name, given by the JSON
+	Date    time.Time // capture date
+	Video   bool
+	Files   []string // media files in the year folder, then in the albums folders
+	JSONs   []string // JSON of each file, empty when missing
+	Albums  []string // albums of the photo
+	Size    int64
+	HasJSON bool
+}
+
+// takeoutFile is a file of the generated takeout, in the order of the listing
+type takeoutFile struct {
+	name string
+	size int64
+	meta *fakeMeta
+}
+
+// GenerateTakeout fabricates a takeout, and gives the generated assets
+func GenerateTakeout(opt TakeoutOptions) ([]fs.FS, []TakeoutAsset) {
+	r := rand.New(rand.NewSource(opt.Seed)) //nolint:gosec
+	if opt.LastYear < opt.FirstYear {
+		opt.LastYear = opt.FirstYear
+	}
+	const root = "Takeout/Google Photos/"
+	modTime := time.Date(opt.LastYear+1, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	assets := make([]TakeoutAsset, opt.Photos)
+	byYear := map[int][]int{}
+	for i := range assets {
+		a := &assets[i]
+		year := opt.FirstYear + r.Intn(opt.LastYear-opt.FirstYear+1)
+		a.Date = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(r.Int63n(int64(365 * 24 * time.Hour))))
+		a.Video = r.Float64() < opt.VideoRatio
+		a.HasJSON = r.Float64() >= opt.MissingJSONRatio
+		a.Size = max(1, opt.MediaSize/2+r.Int63n(max(1, opt.MediaSize)))
+		if a.Video {
+			a.Size *= 10
+		}
+		same := byYear[year]
+		switch {
+		case len(same) > 0 && r.Float64() < opt.DuplicateRatio:
+			a.Title = assets[same[r.Intn(len(same))]].Title
+		case r.Float64() < opt.LongNameRatio:
+			a.Title = longName(r, a.Date, a.Video)
+		default:
+			a.Title = mediaName(r, a.Date, a.Video)
+		}
+		byYear[year] = append(byYear[year], i)
+	}
+
+	// the year folders, then the albums
+	folders := []string{}
+	content := map[string][]int{}
+	for y := opt.FirstYear; y <= opt.LastYear; y++ {
+		if len(byYear[y]) > 0 {
+			f := fmt.Sprintf("%sPhotos from %d", root, y)
+			folders = append(folders, f)
+			content[f] = byYear[y]
+		}
+	}
+	for i := 0; i < opt.Albums && opt.Photos > 0; i++ {
+		f := fmt.Sprintf("%s%s %d", root, albumNames[i%len(albumNames)], opt.FirstYear+i%(opt.LastYear-opt.FirstYear+1))
+		if i >= len(albumNames) {
+			f += fmt.Sprintf(" (%d)", i/len(albumNames))
+		}
+		folders = append(folders, f)
+		size := 1 + r.Intn(max(1, min(opt.AlbumSize, opt.Photos)))
+		for _, p := range r.Perm(opt.Photos)[:size] {
+			content[f] = append(content[f], p)
+			assets[p].Albums = append(assets[p].Albums, path.Base(f))
+		}
+	}
+
+	files := []takeoutFile{}
+	for _, folder := range folders {
+		if !strings.Contains(path.Base(folder), "Photos from ") {
+			_, size := fakeAlbumData(path.Base(folder))
+			files = append(files, takeoutFile{name: folder + "/metadata.json", size: size})
+		}
+		used := map[string]int{}
+		entries := []takeoutFile{}
+		for _, p := range content[folder] {
+			a := &assets[p]
+			media, json := takeoutNames(a.Title, used)
+			a.Files = append(a.Files, folder+"/"+media)
+			entries = append(entries, takeoutFile{name: folder + "/" + media, size: a.Size, meta: &fakeMeta{title: a.Title, date: a.Date}})
+			if a.HasJSON {
+				_, size := fakePhotoData(a.Title, a.Date)
+				a.JSONs = append(a.JSONs, folder+"/"+json)
+				entries = append(entries, takeoutFile{name: folder + "/" + json, size: size, meta: &fakeMeta{title: a.Title, date: a.Date}})
+			} else {
+				a.JSONs = append(a.JSONs, "")
+			}
+		}
+		// the takeout doesn't sort the files
+		r.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+		files = append(files, entries...)
+	}
+
+	// spread the files over the zip parts
+	fsyss := []fs.FS{}
+	var fsys *FakeFS
+	var partSize int64
+	for _, f := range files {
+		if fsys == nil || (opt.PartSize > 0 && partSize > 0 && partSize+f.size > opt.PartSize) {
+			fsys = &FakeFS{
+				name:  fmt.Sprintf("takeout-%d0115T100000Z-%03d.zip", opt.LastYear+1, len(fsyss)+1),
+				files: map[string]map[string]FakeDirEntry{},
+				meta:  map[string]fakeMeta{},
+
+				jpegContent: opt.JPEGContent,
+			}
+			fsyss = append(fsyss, fsys)
+			partSize = 0
+		}
+		partSize += f.size
+		fsys.addFile(f.name, f.size, modTime)
+		if f.meta != nil {
+			fsys.meta[normalizeName(f.name)] = *f.meta
+		}
+	}
+	return fsyss, assets
+}
+
+// takeoutNames gives the names of the media and of its JSON in a folder, numbered when the name is already used
+func takeoutNames(title string, used map[string]int) (string, string) {
+	ext := path.Ext(title)
+	media := truncateName(strings.TrimSuffix(title, ext), takeoutMediaNameMax)
+	json := truncateName(title, takeoutJSONNameMax)
+	n := used[strings.ToLower(media+ext)]
+	used[strings.ToLower(media+ext)] = n + 1
+	if n == 0 {
+		return media + ext, json + ".json"
+	}
+	return fmt.Sprintf("%s(%d)%s", media, n, ext), fmt.Sprintf("%s(%d).json", json, n)
+}
+
+// truncateName keeps the first characters of the name
+func truncateName(name string, l int) string {
+	r := []rune(name)
+	if len(r) > l {
+		return string(r[:l])
+	}
+	return name
+}
+
+// mediaName gives a name like the ones of the phones and cameras
+func mediaName(r *rand.Rand, d time.Time, video bool) string {
+	switch {
+	case video:
+		return d.Format("VID_20060102_150405") + ".mp4"
+	case r.Intn(2) == 0:
+		return fmt.Sprintf("IMG_%04d.JPG", r.Intn(10000))
+	default:
+		return d.Format("PXL_20060102_150405") + fmt.Sprintf("%03d.jpg", r.Intn(1000))
+	}
+}
+
+// longName gives a name longer than the limits of the takeout
+func longName(r *rand.Rand, d time.Time, video bool) string {
+	ext := ".jpg"
+	if video {
+		ext = ".mp4"
+	}
+	switch r.Intn(2) {
+	case 0:
+		return d.Format("PXL_20060102_150405") + fmt.Sprintf("%03d.LONG_EXPOSURE-%02d.ORIGINAL%s", r.Intn(1000), r.Intn(100), ext)
+	default:
+		return fmt.Sprintf("Backyard_ceremony_wedding_photography_%s_magnoliastudios-%d%s", d.Format("20060102"), r.Intn(1000), ext)
+	}
+}
+
+var albumNames = []string{
+	"Holidays", "Birthday", "Wedding", "Trip to Lisbon", "Christmas",
+	"Garden", "Hiking", "Family", "Road trip", "Best of",
+}
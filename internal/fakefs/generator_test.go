@@ -0,0 +1,114 @@
+package fakefs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich/metadata"
+)
+
+func TestTakeoutNames(t *testing.T) {
+	used := map[string]int{}
+	tests := []struct {
+		title string
+		media string
+		json  string
+	}{
+		{title: "IMG_3479.JPG", media: "IMG_3479.JPG", json: "IMG_3479.JPG.json"},
+		{title: "IMG_3479.JPG", media: "IMG_3479(1).JPG", json: "IMG_3479.JPG(1).json"},
+		{title: "PXL_20230809_203449253.LONG_EXPOSURE-02.ORIGINAL.jpg", media: "PXL_20230809_203449253.LONG_EXPOSURE-02.ORIGINA.jpg", json: "PXL_20230809_203449253.LONG_EXPOSURE-02.ORIGIN.json"},
+		{title: "Backyard_ceremony_wedding_photography_20200101_magnoliastudios-371.jpg", media: "Backyard_ceremony_wedding_photography_20200101_.jpg", json: "Backyard_ceremony_wedding_photography_20200101.json"},
+		{title: "Backyard_ceremony_wedding_photography_20200101_magnoliastudios-181.jpg", media: "Backyard_ceremony_wedding_photography_20200101_(1).jpg", json: "Backyard_ceremony_wedding_photography_20200101(1).json"},
+	}
+	for _, tt := range tests {
+		media, json := takeoutNames(tt.title, used)
+		if media != tt.media || json != tt.json {
+			t.Errorf("%s: expected %s %s, got %s %s", tt.title, tt.media, tt.json, media, json)
+		}
+	}
+}
+
+func TestGenerateTakeout(t *testing.T) {
+	opt := DefaultTakeoutOptions()
+	opt.PartSize = 100_000_000
+	fsyss, assets := GenerateTakeout(opt)
+	if len(assets) != opt.Photos {
+		t.Errorf("expected %d assets, got %d", opt.Photos, len(assets))
+	}
+	if len(fsyss) < 2 {
+		t.Errorf("expected several parts, got %d", len(fsyss))
+	}
+
+	// the same seed gives the same takeout
+	_, again := GenerateTakeout(opt)
+	if !reflect.DeepEqual(assets, again) {
+		t.Error("the takeout isn't reproducible")
+	}
+
+	// each file is in one of the parts, the JSON gives the original title
+	for _, a := range assets {
+		for i, f := range a.Files {
+			if !inParts(fsyss, f) {
+				t.Errorf("%s not found", f)
+			}
+			if a.JSONs[i] == "" {
+				continue
+			}
+			for _, fsys := range fsyss {
+				b, err := fs.ReadFile(fsys, a.JSONs[i])
+				if err != nil {
+					continue
+				}
+				if !strings.Contains(string(b), `"title": "`+a.Title+`"`) {
+					t.Errorf("%s: title %s not found", a.JSONs[i], a.Title)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateTakeoutJPEGContent(t *testing.T) {
+	opt := DefaultTakeoutOptions()
+	opt.Photos = 50
+	opt.MediaSize = 10_000
+	opt.JPEGContent = true
+	fsyss, assets := GenerateTakeout(opt)
+
+	// the JPEG files give the capture date of the asset
+	for _, a := range assets {
+		if a.Video {
+			continue
+		}
+		for _, fsys := range fsyss {
+			b, err := fs.ReadFile(fsys, a.Files[0])
+			if err != nil {
+				continue
+			}
+			md, err := metadata.GetFromReader(bytes.NewReader(b), path.Ext(a.Files[0]))
+			if err != nil {
+				t.Errorf("%s: %s", a.Files[0], err)
+				continue
+			}
+			if !md.DateTaken.Equal(a.Date.Truncate(time.Second)) {
+				t.Errorf("%s: got date %s, want %s", a.Files[0], md.DateTaken, a.Date)
+			}
+		}
+	}
+}
+
+func inParts(fsyss []fs.FS, name string) bool {
+	for _, fsys := range fsyss {
+		if f, err := fsys.Open(name); err == nil {
+			_, _ = io.CopyN(io.Discard, f, 1)
+			f.Close()
+			return true
+		}
+	}
+	return false
+}
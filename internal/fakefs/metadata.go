@@ -1,10 +1,14 @@
 package fakefs
 
 import (
+	"bytes"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"strings"
 	"time"
+
+	"github.com/simulot/immich-go/immich/metadata"
 )
 
 const albumTemplate = `{
@@ -75,3 +79,24 @@ func fakeJSON() (io.Reader, int64) {
 	t := fakeJSONTemplate
 	return strings.NewReader(t), int64(len(t))
 }
+
+var (
+	jpegSOI = []byte{0xff, 0xd8} // start of image
+	jpegEOI = []byte{0xff, 0xd9} // end of image
+)
+
+// fakeJPEG gives a JPEG file with an EXIF block holding the capture date, padded with random bytes up to the given size
+func fakeJPEG(captureDate time.Time, size int64) (io.Reader, int64, error) {
+	var b bytes.Buffer
+	err := metadata.SetJPEGDateTaken(bytes.NewReader(append(append([]byte{}, jpegSOI...), jpegEOI...)), &b, captureDate)
+	if err != nil {
+		return nil, 0, err
+	}
+	head := bytes.TrimSuffix(b.Bytes(), jpegEOI)
+	pad := size - int64(len(head)) - int64(len(jpegEOI))
+	if pad < 0 {
+		pad = 0
+	}
+	r := io.MultiReader(bytes.NewReader(head), io.LimitReader(rand.Reader, pad), bytes.NewReader(jpegEOI))
+	return r, int64(len(head)) + pad + int64(len(jpegEOI)), nil
+}
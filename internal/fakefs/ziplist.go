@@ -1,13 +1,15 @@
 package fakefs
 
 /*
-	for f in *.zip; do echo "$f: "; unzip -l $f; done >list.lst
+	for f in *.zip; do echo "Archive: $f"; unzip -l $f; done >list.lst
+	for f in *.tgz; do echo "Archive: $f"; tar -tvzf $f; done >list.lst
 */
 import (
 	"bufio"
 	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -21,12 +23,32 @@ import (
 
 var reZipList = regexp.MustCompile(`(-rw-r--r-- 0/0\s+)?(\d+)\s+(.{16})\s+(.*)$`)
 
+// GNU tar: `-rw-r--r-- user/group  2104348 2023-07-20 00:00:12 Takeout/Google Photos/2020 - Costa Rica/IMG_3235.MP4`
+var reGNUTarList = regexp.MustCompile(`^([-dlhbcps][-rwxsStT]{9})\S*\s+\S+/\S+\s+(\d+)\s+(\d{4}-\d{2}-\d{2} \d{2}:\d{2}(?::\d{2})?)\s+(.*)$`)
+
+// BSD tar: `-rw-r--r--  0 user  group  2104348 Jul 20  2023 Takeout/Google Photos/2020 - Costa Rica/IMG_3235.MP4`
+var reBSDTarList = regexp.MustCompile(`^([-dlhbcps][-rwxsStT]{9})\S*\s+\d+\s+\S+\s+\S+\s+(\d+)\s+(\w{3}\s+\d{1,2}\s+(?:\d{2}:\d{2}|\d{4}))\s+(.*)$`)
+
+// readFileLine reads a line of `unzip -l` or `tar -tv` listings.
+// The dateFormat applies to the `unzip -l` listings, the tar listings have their own format.
+// The directories and the links are ignored.
 func readFileLine(l string, dateFormat string) (string, int64, time.Time) {
 	if len(l) < 30 {
 		return "", 0, time.Time{}
 	}
+	if m := reGNUTarList.FindStringSubmatch(l); m != nil {
+		layout := "2006-01-02 15:04"
+		if len(m[3]) > len(layout) {
+			layout += ":05"
+		}
+		modTime, _ := time.ParseInLocation(layout, m[3], time.Local)
+		return tarEntry(m[1], m[2], m[4], modTime)
+	}
+	if m := reBSDTarList.FindStringSubmatch(l); m != nil {
+		return tarEntry(m[1], m[2], m[4], bsdTarTime(m[3], time.Now()))
+	}
 	m := reZipList.FindStringSubmatch(l)
-	if len(m) < 5 {
+	if len(m) < 5 || strings.HasSuffix(m[4], "/") {
 		return "", 0, time.Time{}
 	}
 	size, _ := strconv.ParseInt(m[2], 10, 64)
@@ -34,27 +56,70 @@ func readFileLine(l string, dateFormat string) (string, int64, time.Time) {
 	return m[4], size, modTime
 }
 
-func ScanStringList(dateFormat string, s string) ([]fs.FS, error) {
+// tarEntry keeps the regular files of a tar listing
+func tarEntry(mode, size, name string, modTime time.Time) (string, int64, time.Time) {
+	if mode[0] != '-' || strings.HasSuffix(name, "/") {
+		return "", 0, time.Time{}
+	}
+	s, _ := strconv.ParseInt(size, 10, 64)
+	return name, s, modTime
+}
+
+// bsdTarTime parses the dates of BSD tar listings, like ls does: the year is replaced by the time for the recent files
+func bsdTarTime(s string, now time.Time) time.Time {
+	s = strings.Join(strings.Fields(s), " ")
+	if t, err := time.ParseInLocation("Jan 2 2006", s, time.Local); err == nil {
+		return t
+	}
+	t, err := time.ParseInLocation("Jan 2 15:04", s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+func ScanStringList(dateFormat string, s string, opts ...Option) ([]fs.FS, error) {
 	r := strings.NewReader(s)
 
-	return ScanFileListReader(r, dateFormat)
+	return ScanFileListReader(r, dateFormat, opts...)
 }
 
-func ScanFileList(name string, dateFormat string) ([]fs.FS, error) {
+// ScanFileList reads a file containing the listings of archives.
+// The files listed before any "Archive:" line belong to an archive named after the listing file.
+func ScanFileList(name string, dateFormat string, opts ...Option) ([]fs.FS, error) {
 	f, err := os.Open(name)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	return ScanFileListReader(f, dateFormat)
+	return scanFileList(f, strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)), dateFormat, opts...)
+}
+
+func ScanFileListReader(f io.Reader, dateFormat string, opts ...Option) ([]fs.FS, error) {
+	return scanFileList(f, "archive", dateFormat, opts...)
 }
 
-func ScanFileListReader(f io.Reader, dateFormat string) ([]fs.FS, error) {
+func scanFileList(f io.Reader, defaultName string, dateFormat string, opts ...Option) ([]fs.FS, error) {
 	fsyss := map[string]*FakeFS{}
 	var fsys *FakeFS
 	currentZip := ""
 	ok := false
 
+	newFS := func(name string) *FakeFS {
+		fsys := &FakeFS{
+			name:  name,
+			files: map[string]map[string]FakeDirEntry{},
+		}
+		for _, opt := range opts {
+			opt(fsys)
+		}
+		return fsys
+	}
+
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		l := scanner.Text()
@@ -62,11 +127,7 @@ func ScanFileListReader(f io.Reader, dateFormat string) ([]fs.FS, error) {
 			currentZip = strings.TrimSpace(strings.TrimPrefix(l, "Part:"))
 			fsys, ok = fsyss[currentZip]
 			if !ok {
-				fsys = &FakeFS{
-					name:  currentZip,
-					files: map[string]map[string]FakeDirEntry{},
-				}
-
+				fsys = newFS(currentZip)
 				fsyss[currentZip] = fsys
 			}
 			continue
@@ -75,16 +136,17 @@ func ScanFileListReader(f io.Reader, dateFormat string) ([]fs.FS, error) {
 			currentZip = strings.TrimSpace(strings.TrimPrefix(l, "Archive:"))
 			fsys, ok = fsyss[currentZip]
 			if !ok {
-				fsys = &FakeFS{
-					name:  currentZip,
-					files: map[string]map[string]FakeDirEntry{},
-				}
-
+				fsys = newFS(currentZip)
 				fsyss[currentZip] = fsys
 			}
 			continue
 		}
 		if name, size, modTime := readFileLine(l, dateFormat); name != "" {
+			if fsys == nil {
+				currentZip = defaultName
+				fsys = newFS(currentZip)
+				fsyss[currentZip] = fsys
+			}
 			fsys.addFile(name, size, modTime)
 		}
 	}
@@ -1,10 +1,14 @@
 package fakefs
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
+	"path"
 	"testing"
 	"time"
+
+	"github.com/simulot/immich-go/immich/metadata"
 )
 
 func Test_readFileLine(t *testing.T) {
@@ -59,6 +63,47 @@ func Test_readFileLine(t *testing.T) {
 			wantModTime: time.Date(2023, 12, 9, 17, 23, 0, 0, time.Local),
 			wantSize:    717454980,
 		},
+		{
+			name: "gnu tar",
+			args: args{
+				l:          "-rw-r--r-- simulot/users 2104348 2023-07-20 00:00:12 Takeout/Google Photos/2020 - Costa Rica/IMG_3235.MP4",
+				dateFormat: "01-02-2006 15:04",
+			},
+			wantName:    "Takeout/Google Photos/2020 - Costa Rica/IMG_3235.MP4",
+			wantModTime: time.Date(2023, 7, 20, 0, 0, 12, 0, time.Local),
+			wantSize:    2104348,
+		},
+		{
+			name: "gnu tar directory",
+			args: args{
+				l:          "drwxr-xr-x simulot/users       0 2023-07-20 00:00 Takeout/Google Photos/2020 - Costa Rica/",
+				dateFormat: "2006-01-02 15:04",
+			},
+		},
+		{
+			name: "gnu tar link",
+			args: args{
+				l:          "lrwxrwxrwx simulot/users       0 2023-07-20 00:00 Takeout/Google Photos/link.jpg -> IMG_3235.jpg",
+				dateFormat: "2006-01-02 15:04",
+			},
+		},
+		{
+			name: "bsd tar",
+			args: args{
+				l:          "-rw-r--r--  0 simulot staff   197486 Jul 19  2023 Takeout/Google Photos/2011 - Omaha Zoo/IMG_20110702_153447.jpg",
+				dateFormat: "2006-01-02 15:04",
+			},
+			wantName:    "Takeout/Google Photos/2011 - Omaha Zoo/IMG_20110702_153447.jpg",
+			wantModTime: time.Date(2023, 7, 19, 0, 0, 0, 0, time.Local),
+			wantSize:    197486,
+		},
+		{
+			name: "zip directory",
+			args: args{
+				l:          "        0  07-19-2023 23:53   Takeout/Google Photos/2011 - Omaha Zoo/",
+				dateFormat: "01-02-2006 15:04",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -77,6 +122,103 @@ func Test_readFileLine(t *testing.T) {
 	}
 }
 
+func Test_bsdTarTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"Jul 19  2023", time.Date(2023, 7, 19, 0, 0, 0, 0, time.Local)},
+		{"Feb  1 08:30", time.Date(2024, 2, 1, 8, 30, 0, 0, time.Local)},
+		{"Dec 24 18:00", time.Date(2023, 12, 24, 18, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := bsdTarTime(tt.s, now); !got.Equal(tt.want) {
+				t.Errorf("bsdTarTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanTarList(t *testing.T) {
+	list := `drwxr-xr-x simulot/users       0 2023-07-20 00:00 Takeout/
+-rw-r--r-- simulot/users  145804 2023-07-20 00:00 Takeout/Google Photos/Photos from 2020/IMG_20200101_120000.jpg
+Archive: takeout-002.tgz
+-rw-r--r-- simulot/users  145804 2023-07-20 00:00 Takeout/Google Photos/Photos from 2021/IMG_20210101_120000.jpg
+`
+	fsyss, err := ScanStringList("2006-01-02 15:04", list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"archive":         "Takeout/Google Photos/Photos from 2020/IMG_20200101_120000.jpg",
+		"takeout-002.tgz": "Takeout/Google Photos/Photos from 2021/IMG_20210101_120000.jpg",
+	}
+	if len(fsyss) != len(want) {
+		t.Fatalf("got %d archives, want %d", len(fsyss), len(want))
+	}
+	for _, fsys := range fsyss {
+		name := fsys.(NameFS).Name()
+		if _, err := fs.Stat(fsys, want[name]); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestJPEGContent(t *testing.T) {
+	list := `Archive: takeout-001.zip
+   145804  2024-05-25 22:15   Takeout/Google Photos/Photos from 2020/IMG_20200315_101112.jpg
+   145804  2024-05-25 22:15   Takeout/Google Photos/Photos from 2020/holidays.JPG
+      120  2024-05-25 22:15   Takeout/Google Photos/Photos from 2020/tiny.jpg
+   145804  2024-05-25 22:15   Takeout/Google Photos/Photos from 2020/VID_20200315_101112.mp4
+`
+	fsyss, err := ScanStringList("2006-01-02 15:04", list, WithJPEGContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fsyss[0]
+
+	tests := []struct {
+		name string
+		want time.Time
+	}{
+		{"Takeout/Google Photos/Photos from 2020/IMG_20200315_101112.jpg", time.Date(2020, 3, 15, 10, 11, 12, 0, time.Local)},
+		{"Takeout/Google Photos/Photos from 2020/holidays.JPG", time.Date(2024, 5, 25, 22, 15, 0, 0, time.Local)},
+		{"Takeout/Google Photos/Photos from 2020/tiny.jpg", time.Date(2024, 5, 25, 22, 15, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(path.Base(tt.name), func(t *testing.T) {
+			b, err := fs.ReadFile(fsys, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(b, []byte{0xff, 0xd8}) || !bytes.HasSuffix(b, []byte{0xff, 0xd9}) {
+				t.Errorf("not a JPEG file")
+			}
+			if len(b) < 145804 && path.Base(tt.name) != "tiny.jpg" {
+				t.Errorf("size: got %d, want 145804", len(b))
+			}
+			md, err := metadata.GetFromReader(bytes.NewReader(b), path.Ext(tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !md.DateTaken.Equal(tt.want) {
+				t.Errorf("DateTaken: got %s, want %s", md.DateTaken, tt.want)
+			}
+		})
+	}
+
+	// the other files keep their random content
+	b, err := fs.ReadFile(fsys, "Takeout/Google Photos/Photos from 2020/VID_20200315_101112.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 145804 {
+		t.Errorf("size: got %d, want 145804", len(b))
+	}
+}
+
 func BenchmarkReadFileLine(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _, _ = readFileLine("   145804  2024-05-25 22:15   Takeout/Google Photos/🇵🇹 Lisbonne ❤️ en famille 👨‍👩‍👦‍👦/😀😃😄😁😆😅😂🤣🥲☺️😊😇🙂🙃😉😌😍🥰😘😗😙😚😋😛.jpg", "2006-01-02 15:04")
@@ -0,0 +1,175 @@
+package fakeserver
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+type album struct {
+	ID          string     `json:"id"`
+	AlbumName   string     `json:"albumName"`
+	Description string     `json:"description"`
+	OwnerID     string     `json:"ownerId"`
+	CreatedAt   serverTime `json:"createdAt"`
+	AssetCount  int        `json:"assetCount"`
+	Order       string     `json:"order,omitempty"`
+	Thumbnail   string     `json:"albumThumbnailAssetId,omitempty"`
+	Assets      []*asset   `json:"assets"`
+	assets      []string   // IDs of the assets, in the order of their addition
+}
+
+// view gives the album with its assets, or without them
+func (s *Server) albumView(al *album, withAssets bool) album {
+	v := *al
+	v.AssetCount = len(al.assets)
+	v.Assets = []*asset{}
+	if withAssets {
+		for _, id := range al.assets {
+			if a := s.asset(id); a != nil && !a.IsTrashed {
+				v.Assets = append(v.Assets, a)
+			}
+		}
+	}
+	return v
+}
+
+// getAlbums gives all the albums, or the ones of the asset given by the parameter assetId
+func (s *Server) getAlbums(w http.ResponseWriter, r *http.Request) {
+	assetID := r.URL.Query().Get("assetId")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	albums := []album{}
+	for _, al := range s.albums {
+		if assetID != "" && !slices.Contains(al.assets, assetID) {
+			continue
+		}
+		albums = append(albums, s.albumView(al, false))
+	}
+	sort.Slice(albums, func(i, j int) bool { return albums[i].ID < albums[j].ID })
+	writeJSON(w, http.StatusOK, albums)
+}
+
+func (s *Server) createAlbum(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AlbumName   string   `json:"albumName"`
+		Description string   `json:"description"`
+		AssetIDs    []string `json:"assetIds"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.AlbumName == "" {
+		writeError(w, http.StatusBadRequest, "albumName should not be empty")
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al := &album{
+		ID:          s.newID(),
+		AlbumName:   req.AlbumName,
+		Description: req.Description,
+		OwnerID:     s.user.ID,
+		CreatedAt:   serverTime(time.Now()),
+	}
+	for _, id := range req.AssetIDs {
+		if s.asset(id) != nil && !slices.Contains(al.assets, id) {
+			al.assets = append(al.assets, id)
+		}
+	}
+	s.albums[al.ID] = al
+	writeJSON(w, http.StatusCreated, s.albumView(al, false))
+}
+
+func (s *Server) getAlbum(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al := s.albums[r.PathValue("id")]
+	if al == nil {
+		writeError(w, http.StatusNotFound, "Album not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.albumView(al, r.URL.Query().Get("withoutAssets") != "true"))
+}
+
+func (s *Server) updateAlbum(w http.ResponseWriter, r *http.Request) {
+	var u immich.AlbumUpdate
+	if !readJSON(w, r, &u) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al := s.albums[r.PathValue("id")]
+	if al == nil {
+		writeError(w, http.StatusNotFound, "Album not found")
+		return
+	}
+	if u.AlbumName != "" {
+		al.AlbumName = u.AlbumName
+	}
+	if u.Description != "" {
+		al.Description = u.Description
+	}
+	if u.AlbumThumbnailAssetID != "" {
+		al.Thumbnail = u.AlbumThumbnailAssetID
+	}
+	if u.Order != "" {
+		al.Order = u.Order
+	}
+	writeJSON(w, http.StatusOK, s.albumView(al, false))
+}
+
+func (s *Server) deleteAlbum(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id := r.PathValue("id")
+	if s.albums[id] == nil {
+		writeError(w, http.StatusNotFound, "Album not found")
+		return
+	}
+	delete(s.albums, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) addAlbumAssets(w http.ResponseWriter, r *http.Request) {
+	s.changeAlbumAssets(w, r, func(al *album, id string) string {
+		if s.asset(id) == nil {
+			return "not_found"
+		}
+		if slices.Contains(al.assets, id) {
+			return immich.ErrorDuplicate
+		}
+		al.assets = append(al.assets, id)
+		return ""
+	})
+}
+
+func (s *Server) removeAlbumAssets(w http.ResponseWriter, r *http.Request) {
+	s.changeAlbumAssets(w, r, func(al *album, id string) string {
+		i := slices.Index(al.assets, id)
+		if i < 0 {
+			return "not_found"
+		}
+		al.assets = slices.Delete(al.assets, i, i+1)
+		return ""
+	})
+}
+
+// changeAlbumAssets applies the change to each asset of the request
+func (s *Server) changeAlbumAssets(w http.ResponseWriter, r *http.Request, fn func(al *album, id string) string) {
+	var req immich.UpdateAlbum
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al := s.albums[r.PathValue("id")]
+	if al == nil {
+		writeError(w, http.StatusNotFound, "Album not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, idsResults(req.IDS, func(id string) string { return fn(al, id) }))
+}
@@ -0,0 +1,363 @@
+package fakeserver
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// asset is an asset of the server, as given by the API
+type asset struct {
+	ID               string      `json:"id"`
+	DeviceAssetID    string      `json:"deviceAssetId"`
+	OwnerID          string      `json:"ownerId"`
+	DeviceID         string      `json:"deviceId"`
+	Type             string      `json:"type"`
+	OriginalPath     string      `json:"originalPath"`
+	OriginalFileName string      `json:"originalFileName"`
+	FileCreatedAt    serverTime  `json:"fileCreatedAt"`
+	FileModifiedAt   serverTime  `json:"fileModifiedAt"`
+	CreatedAt        serverTime  `json:"createdAt"`
+	UpdatedAt        serverTime  `json:"updatedAt"`
+	IsFavorite       bool        `json:"isFavorite"`
+	IsArchived       bool        `json:"isArchived"`
+	IsTrashed        bool        `json:"isTrashed"`
+	Duration         string      `json:"duration"`
+	ExifInfo         exifInfo    `json:"exifInfo"`
+	LivePhotoVideoID string      `json:"livePhotoVideoId,omitempty"`
+	Checksum         string      `json:"checksum"`
+	Tags             []*tag      `json:"tags"`
+	Stack            *assetStack `json:"stack,omitempty"`
+	Sidecar          string      `json:"-"` // name of the sidecar sent with the asset
+	size             int64       // size of the content
+	deleted          bool        // deleted permanently
+}
+
+type exifInfo struct {
+	FileSizeInByte   int64      `json:"fileSizeInByte"`
+	DateTimeOriginal serverTime `json:"dateTimeOriginal"`
+	Latitude         float64    `json:"latitude,omitempty"`
+	Longitude        float64    `json:"longitude,omitempty"`
+	Description      string     `json:"description"`
+}
+
+type assetStack struct {
+	ID             string `json:"id"`
+	PrimaryAssetID string `json:"primaryAssetId"`
+	AssetCount     int    `json:"assetCount"`
+}
+
+// upload receives an asset, the content is read on the fly to compute its checksum
+func (s *Server) upload(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fields := map[string]string{}
+	var name, sidecar string
+	var size int64
+	h := sha1.New() //nolint:gosec
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		switch part.FormName() {
+		case "assetData":
+			name = part.FileName()
+			size, err = io.Copy(h, part)
+		case "sidecarData":
+			sidecar = part.FileName()
+			_, err = io.Copy(io.Discard, part)
+		default:
+			var b []byte
+			b, err = io.ReadAll(part)
+			fields[part.FormName()] = string(b)
+		}
+		part.Close()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "assetData is missing")
+		return
+	}
+	typ := s.media.TypeFromExt(strings.ToLower(path.Ext(name)))
+	if typ != immich.TypeImage && typ != immich.TypeVideo {
+		writeError(w, http.StatusBadRequest, "Unsupported file type")
+		return
+	}
+	checksum := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.uploads++
+	if id, ok := s.byChecksum[checksum]; ok {
+		writeJSON(w, http.StatusOK, immich.AssetResponse{ID: id, Status: immich.UploadDuplicate})
+		return
+	}
+	now := time.Now()
+	created, _ := time.Parse(time.RFC3339, fields["fileCreatedAt"])
+	modified, _ := time.Parse(time.RFC3339, fields["fileModifiedAt"])
+	a := &asset{
+		ID:               s.newID(),
+		DeviceAssetID:    fields["deviceAssetId"],
+		OwnerID:          s.user.ID,
+		DeviceID:         fields["deviceId"],
+		Type:             strings.ToUpper(typ),
+		OriginalPath:     "upload/library/" + s.user.ID + "/" + name,
+		OriginalFileName: name,
+		FileCreatedAt:    serverTime(created),
+		FileModifiedAt:   serverTime(modified),
+		CreatedAt:        serverTime(now),
+		UpdatedAt:        serverTime(now),
+		IsFavorite:       fields["isFavorite"] == "true",
+		IsArchived:       fields["isArchived"] == "true",
+		Duration:         fields["duration"],
+		ExifInfo:         exifInfo{FileSizeInByte: size, DateTimeOriginal: serverTime(created)},
+		LivePhotoVideoID: fields["livePhotoVideoId"],
+		Checksum:         checksum,
+		Sidecar:          sidecar,
+		size:             size,
+	}
+	s.assets[a.ID] = a
+	s.byChecksum[checksum] = a.ID
+	writeJSON(w, http.StatusCreated, immich.AssetResponse{ID: a.ID, Status: immich.UploadCreated})
+}
+
+// bulkCheck tells which checksums are already known
+func (s *Server) bulkCheck(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Assets []immich.AssetBulkUploadCheckItem `json:"assets"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	resp := struct {
+		Results []immich.AssetBulkUploadCheckResult `json:"results"`
+	}{Results: []immich.AssetBulkUploadCheckResult{}}
+	for _, item := range req.Assets {
+		result := immich.AssetBulkUploadCheckResult{ID: item.ID, Action: immich.BulkCheckAccept}
+		if id, ok := s.byChecksum[normalizeChecksum(item.Checksum)]; ok {
+			result.Action, result.Reason, result.AssetID = immich.BulkCheckReject, immich.BulkCheckReasonDuplicate, id
+			result.IsTrashed = s.assets[id].IsTrashed
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// normalizeChecksum gives the base64 form of a checksum given in hex or base64
+func normalizeChecksum(c string) string {
+	if len(c) == 2*sha1.Size {
+		if b, err := hex.DecodeString(c); err == nil {
+			return base64.StdEncoding.EncodeToString(b)
+		}
+	}
+	return c
+}
+
+// asset gives the asset of the ID, nil when it doesn't exist
+func (s *Server) asset(id string) *asset {
+	a := s.assets[id]
+	if a == nil || a.deleted {
+		return nil
+	}
+	return a
+}
+
+func (s *Server) getAsset(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a := s.asset(r.PathValue("id"))
+	if a == nil {
+		writeError(w, http.StatusNotFound, "Asset not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+// assetUpdate is the change of an asset, the nil fields are left unchanged
+type assetUpdate struct {
+	IDs              []string   `json:"ids"`
+	IsFavorite       *bool      `json:"isFavorite"`
+	IsArchived       *bool      `json:"isArchived"`
+	Latitude         *float64   `json:"latitude"`
+	Longitude        *float64   `json:"longitude"`
+	Description      *string    `json:"description"`
+	DateTimeOriginal *time.Time `json:"dateTimeOriginal"`
+}
+
+func (u assetUpdate) apply(a *asset) {
+	if u.IsFavorite != nil {
+		a.IsFavorite = *u.IsFavorite
+	}
+	if u.IsArchived != nil {
+		a.IsArchived = *u.IsArchived
+	}
+	if u.Latitude != nil && u.Longitude != nil && (*u.Latitude != 0 || *u.Longitude != 0) {
+		a.ExifInfo.Latitude, a.ExifInfo.Longitude = *u.Latitude, *u.Longitude
+	}
+	if u.Description != nil {
+		a.ExifInfo.Description = *u.Description
+	}
+	if u.DateTimeOriginal != nil {
+		a.ExifInfo.DateTimeOriginal = serverTime(*u.DateTimeOriginal)
+	}
+	a.UpdatedAt = serverTime(time.Now())
+}
+
+func (s *Server) updateAsset(w http.ResponseWriter, r *http.Request) {
+	var u assetUpdate
+	if !readJSON(w, r, &u) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a := s.asset(r.PathValue("id"))
+	if a == nil {
+		writeError(w, http.StatusNotFound, "Asset not found")
+		return
+	}
+	u.apply(a)
+	writeJSON(w, http.StatusOK, a)
+}
+
+func (s *Server) updateAssets(w http.ResponseWriter, r *http.Request) {
+	var u assetUpdate
+	if !readJSON(w, r, &u) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, id := range u.IDs {
+		if a := s.asset(id); a != nil {
+			u.apply(a)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteAssets moves the assets to the trash, or deletes them permanently when forced
+func (s *Server) deleteAssets(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs   []string `json:"ids"`
+		Force bool     `json:"force"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, id := range req.IDs {
+		a := s.asset(id)
+		if a == nil {
+			continue
+		}
+		a.IsTrashed = true
+		if req.Force {
+			a.deleted = true
+			delete(s.byChecksum, a.Checksum)
+			for _, al := range s.albums {
+				al.assets = slices.DeleteFunc(al.assets, func(x string) bool { return x == id })
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) restoreAssets(w http.ResponseWriter, r *http.Request) {
+	var req immich.UpdateAlbum
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	count := 0
+	for _, id := range req.IDS {
+		if a := s.asset(id); a != nil && a.IsTrashed {
+			a.IsTrashed = false
+			count++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
+
+// searchMetadata gives the assets by pages, in the order of their creation
+func (s *Server) searchMetadata(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Page         int        `json:"page"`
+		Size         int        `json:"size"`
+		WithDeleted  bool       `json:"withDeleted"`
+		IsFavorite   bool       `json:"isFavorite"`
+		IsArchived   bool       `json:"isArchived"`
+		WithArchived bool       `json:"withArchived"`
+		Type         string     `json:"type"`
+		TakenAfter   *time.Time `json:"takenAfter"`
+		TakenBefore  *time.Time `json:"takenBefore"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	req.Page = max(req.Page, 1)
+	if req.Size <= 0 {
+		req.Size = 250
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	found := []*asset{}
+	for _, a := range s.assets {
+		taken := time.Time(a.ExifInfo.DateTimeOriginal)
+		switch {
+		case a.deleted,
+			a.IsTrashed && !req.WithDeleted,
+			req.IsFavorite && !a.IsFavorite,
+			req.IsArchived && !a.IsArchived,
+			req.Type != "" && req.Type != a.Type,
+			req.TakenAfter != nil && taken.Before(*req.TakenAfter),
+			req.TakenBefore != nil && taken.After(*req.TakenBefore):
+			continue
+		}
+		found = append(found, a)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+
+	var resp struct {
+		Assets struct {
+			Total    int      `json:"total"`
+			Count    int      `json:"count"`
+			Items    []*asset `json:"items"`
+			NextPage *string  `json:"nextPage"`
+		} `json:"assets"`
+	}
+	start := min((req.Page-1)*req.Size, len(found))
+	end := min(start+req.Size, len(found))
+	resp.Assets.Items = found[start:end]
+	resp.Assets.Total, resp.Assets.Count = len(found), end-start
+	if end < len(found) {
+		next := strconv.Itoa(req.Page + 1)
+		resp.Assets.NextPage = &next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,237 @@
+// Package fakeserver implements in memory the part of the Immich API used by immich-go:
+// the uploads, the bulk check, the search of the assets, the albums, the tags and the stacks.
+// The assets' content isn't kept, only their checksum and their size.
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// Server is an in memory Immich server
+type Server struct {
+	lock    sync.Mutex
+	key     string
+	user    user
+	version immich.ServerVersion
+	media   immich.SupportedMedia
+	mux     *http.ServeMux
+	seq     int
+
+	assets     map[string]*asset
+	byChecksum map[string]string // checksum -> asset ID
+	albums     map[string]*album
+	tags       map[string]*tag
+	stacks     map[string]*stack
+	uploads    int // number of upload calls
+}
+
+// New creates an empty server accepting the API key. An empty key accepts all the calls.
+func New(key string) *Server {
+	s := &Server{
+		key:        key,
+		user:       user{ID: "00000000-0000-0000-0000-000000000001", Email: "demo@immich.app", Name: "Demo", IsAdmin: true},
+		version:    immich.ServerVersion{Major: 1, Minor: 118, Patch: 0},
+		media:      immich.DefaultSupportedMedia,
+		mux:        http.NewServeMux(),
+		assets:     map[string]*asset{},
+		byChecksum: map[string]string{},
+		albums:     map[string]*album{},
+		tags:       map[string]*tag{},
+		stacks:     map[string]*stack{},
+	}
+	s.routes()
+	return s
+}
+
+// SetVersion sets the version announced by the server
+func (s *Server) SetVersion(v immich.ServerVersion) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.version = v
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /api/server/ping", s.ping)
+	s.mux.HandleFunc("GET /api/server/version", s.serverVersion)
+	s.mux.HandleFunc("GET /api/server/features", s.serverFeatures)
+	s.mux.HandleFunc("GET /api/server/media-types", s.mediaTypes)
+	s.mux.HandleFunc("GET /api/server/statistics", s.serverStatistics)
+	s.mux.HandleFunc("GET /api/users/me", s.me)
+	s.mux.HandleFunc("GET /api/jobs", s.jobs)
+
+	s.mux.HandleFunc("POST /api/assets", s.upload)
+	s.mux.HandleFunc("POST /api/assets/bulk-upload-check", s.bulkCheck)
+	s.mux.HandleFunc("GET /api/assets/statistics", s.assetStatistics)
+	s.mux.HandleFunc("GET /api/assets/{id}", s.getAsset)
+	s.mux.HandleFunc("PUT /api/assets/{id}", s.updateAsset)
+	s.mux.HandleFunc("PUT /api/assets", s.updateAssets)
+	s.mux.HandleFunc("DELETE /api/assets", s.deleteAssets)
+	s.mux.HandleFunc("POST /api/trash/restore/assets", s.restoreAssets)
+	s.mux.HandleFunc("POST /api/search/metadata", s.searchMetadata)
+
+	s.mux.HandleFunc("GET /api/albums", s.getAlbums)
+	s.mux.HandleFunc("POST /api/albums", s.createAlbum)
+	s.mux.HandleFunc("GET /api/albums/{id}", s.getAlbum)
+	s.mux.HandleFunc("PATCH /api/albums/{id}", s.updateAlbum)
+	s.mux.HandleFunc("DELETE /api/albums/{id}", s.deleteAlbum)
+	s.mux.HandleFunc("PUT /api/albums/{id}/assets", s.addAlbumAssets)
+	s.mux.HandleFunc("DELETE /api/albums/{id}/assets", s.removeAlbumAssets)
+
+	s.mux.HandleFunc("GET /api/tags", s.getTags)
+	s.mux.HandleFunc("PUT /api/tags", s.upsertTags)
+	s.mux.HandleFunc("PUT /api/tags/{id}/assets", s.tagAssets)
+	s.mux.HandleFunc("DELETE /api/tags/{id}/assets", s.untagAssets)
+
+	s.mux.HandleFunc("POST /api/stacks", s.createStack)
+}
+
+// ServeHTTP checks the key and serves the API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.key != "" && r.Header.Get("x-api-key") != s.key && r.URL.Path != "/api/server/ping" {
+		writeError(w, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// newID gives a new identifier, in the UUID format of the server
+func (s *Server) newID() string {
+	s.seq++
+	return fmt.Sprintf("00000000-0000-0000-0001-%012d", s.seq)
+}
+
+// serverTime is a time in the format of the server
+type serverTime time.Time
+
+func (t serverTime) MarshalJSON() ([]byte, error) {
+	if time.Time(t).IsZero() {
+		return []byte(`null`), nil
+	}
+	return json.Marshal(time.Time(t).UTC().Format("2006-01-02T15:04:05.000Z"))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, immich.ServerMessage{
+		Error:      http.StatusText(status),
+		StatusCode: fmt.Sprint(status),
+		Message:    []string{msg},
+	})
+}
+
+// readJSON decodes the body of the request, and answers a bad request when it fails
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+type user struct {
+	ID      string `json:"id"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	IsAdmin bool   `json:"isAdmin"`
+}
+
+func (s *Server) ping(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"res": "pong"})
+}
+
+func (s *Server) serverVersion(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	writeJSON(w, http.StatusOK, s.version)
+}
+
+func (s *Server) serverFeatures(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, immich.ServerFeatures{Trash: true, Search: true, Sidecar: true})
+}
+
+func (s *Server) mediaTypes(w http.ResponseWriter, r *http.Request) {
+	types := map[string][]string{"image": {}, "video": {}, "sidecar": {".xmp"}}
+	for ext, t := range s.media {
+		switch t {
+		case immich.TypeImage, immich.TypeVideo:
+			types[t] = append(types[t], ext)
+		}
+	}
+	for _, l := range types {
+		sort.Strings(l)
+	}
+	writeJSON(w, http.StatusOK, types)
+}
+
+func (s *Server) me(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.user)
+}
+
+func (s *Server) jobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) serverStatistics(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := immich.ServerStatistics{}
+	for _, a := range s.assets {
+		if a.IsTrashed {
+			continue
+		}
+		if a.Type == "VIDEO" {
+			st.Videos++
+		} else {
+			st.Photos++
+		}
+		st.Usage += a.size
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) assetStatistics(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := immich.UserStatistics{}
+	for _, a := range s.assets {
+		if a.IsTrashed {
+			continue
+		}
+		if a.Type == "VIDEO" {
+			st.Videos++
+		} else {
+			st.Images++
+		}
+		st.Total++
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// idsResults gives the result of each ID, the failing ones have the error
+func idsResults(ids []string, fn func(id string) string) []immich.UpdateAlbumResult {
+	r := make([]immich.UpdateAlbumResult, 0, len(ids))
+	for _, id := range ids {
+		e := fn(id)
+		r = append(r, immich.UpdateAlbumResult{ID: id, Success: e == "", Error: e})
+	}
+	return r
+}
+
+// lowerTrim normalizes the names compared without case
+func lowerTrim(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
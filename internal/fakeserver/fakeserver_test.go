@@ -0,0 +1,178 @@
+package fakeserver
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/browser"
+	"github.com/simulot/immich-go/immich"
+)
+
+func newClient(t *testing.T) (*Server, *immich.ImmichClient) {
+	s := New("KEY")
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	ic, err := immich.NewImmichClient(server.URL, "KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ic.NegotiateServer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ic.ValidateConnection(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return s, ic
+}
+
+func upload(t *testing.T, ic *immich.ImmichClient, name string, content string) immich.AssetResponse {
+	fsys := fstest.MapFS{name: &fstest.MapFile{Data: []byte(content)}}
+	la := &browser.LocalAssetFile{FSys: fsys, FileName: name, Title: name, FileSize: len(content)}
+	la.Metadata.DateTaken = time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	r, err := ic.AssetUpload(context.Background(), la)
+	la.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestUpload(t *testing.T) {
+	s, ic := newClient(t)
+	ctx := context.Background()
+
+	r1 := upload(t, ic, "photo.jpg", "photo")
+	r2 := upload(t, ic, "copy.jpg", "photo")
+	r3 := upload(t, ic, "video.mp4", "video")
+	if r1.Status != immich.UploadCreated || r2.Status != immich.UploadDuplicate || r2.ID != r1.ID || r3.Status != immich.UploadCreated {
+		t.Errorf("unexpected responses %v %v %v", r1, r2, r3)
+	}
+	if s.Uploads() != 3 || len(s.Assets()) != 2 {
+		t.Errorf("unexpected %d uploads, %d assets", s.Uploads(), len(s.Assets()))
+	}
+
+	// the bulk check recognizes the checksums in hex or base64
+	sum := sha1.Sum([]byte("photo")) //nolint:gosec
+	results, err := ic.CheckBulkUpload(ctx, []immich.AssetBulkUploadCheckItem{
+		{ID: "1", Checksum: base64.StdEncoding.EncodeToString(sum[:])},
+		{ID: "2", Checksum: "0123456789abcdef0123456789abcdef01234567"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Action != immich.BulkCheckReject || results[0].AssetID != r1.ID || results[1].Action != immich.BulkCheckAccept {
+		t.Errorf("unexpected bulk check %+v", results)
+	}
+
+	assets, err := ic.GetAllAssets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 || assets[0].OriginalFileName != "photo.jpg" || assets[0].ExifInfo.FileSizeInByte != 5 || assets[1].Type != "VIDEO" {
+		t.Errorf("unexpected assets %+v", assets)
+	}
+	if d := assets[0].ExifInfo.DateTimeOriginal; !d.Equal(time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %s", d)
+	}
+
+	err = ic.DeleteAssets(ctx, []string{r3.ID}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := s.Assets(); !a[1].Trashed {
+		t.Errorf("the asset should be in the trash: %+v", a[1])
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	s := New("")
+	for i := 0; i < 2500; i++ {
+		a := &asset{ID: s.newID(), OriginalFileName: "photo.jpg"}
+		s.assets[a.ID] = a
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	ic, err := immich.NewImmichClient(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets, err := ic.GetAllAssets(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2500 {
+		t.Errorf("expected 2500 assets, got %d", len(assets))
+	}
+}
+
+func TestAlbumsAndTags(t *testing.T) {
+	s, ic := newClient(t)
+	ctx := context.Background()
+	p := upload(t, ic, "photo.jpg", "photo")
+	v := upload(t, ic, "video.mp4", "video")
+
+	al, err := ic.CreateAlbum(ctx, "Holidays", "", []string{p.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := ic.AddAssetToAlbum(ctx, al.ID, []string{p.ID, v.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Error != immich.ErrorDuplicate || !results[1].Success {
+		t.Errorf("unexpected results %+v", results)
+	}
+	albums, err := ic.GetAssetAlbums(ctx, v.ID)
+	if err != nil || len(albums) != 1 || albums[0].AlbumName != "Holidays" || albums[0].AssetCount != 2 {
+		t.Errorf("unexpected albums %+v, %v", albums, err)
+	}
+
+	tags, err := ic.UpsertTags(ctx, []string{"place/Lisbon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ic.TagAssets(ctx, tags[0].ID, []string{p.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := ic.GetAllTags(ctx)
+	if err != nil || len(all) != 2 {
+		t.Errorf("expected the tag and its parent, got %+v, %v", all, err)
+	}
+
+	err = ic.StackAssets(ctx, p.ID, []string{v.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Assets()
+	if !reflect.DeepEqual(got[0].Albums, []string{"Holidays"}) || !reflect.DeepEqual(got[0].Tags, []string{"place/Lisbon"}) {
+		t.Errorf("unexpected asset %+v", got[0])
+	}
+	if got[0].StackID == "" || got[0].StackID != got[1].StackID {
+		t.Errorf("the assets should be stacked: %+v", got)
+	}
+	if want := map[string][]string{"Holidays": {"photo.jpg", "video.mp4"}}; !reflect.DeepEqual(s.Albums(), want) {
+		t.Errorf("unexpected albums %v", s.Albums())
+	}
+}
+
+func TestKey(t *testing.T) {
+	server := httptest.NewServer(New("KEY"))
+	defer server.Close()
+	ic, err := immich.NewImmichClient(server.URL, "WRONG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ic.ValidateConnection(context.Background())
+	if !immich.IsUnauthorized(err) {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+}
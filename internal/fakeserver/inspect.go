@@ -0,0 +1,86 @@
+package fakeserver
+
+import (
+	"sort"
+	"time"
+)
+
+// AssetInfo describes an asset of the server, for the checks of the tests
+type AssetInfo struct {
+	ID       string
+	FileName string
+	Size     int64
+	Date     time.Time
+	Favorite bool
+	Archived bool
+	Trashed  bool
+	Albums   []string // names of the albums
+	Tags     []string // values of the tags
+	StackID  string
+	Sidecar  string // name of the sidecar sent with the asset
+}
+
+// Assets gives the assets of the server, in the order of their upload. The assets deleted permanently aren't given.
+func (s *Server) Assets() []AssetInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	albums := map[string][]string{}
+	for _, al := range s.albums {
+		for _, id := range al.assets {
+			albums[id] = append(albums[id], al.AlbumName)
+		}
+	}
+	infos := []AssetInfo{}
+	for _, a := range s.assets {
+		if a.deleted {
+			continue
+		}
+		info := AssetInfo{
+			ID:       a.ID,
+			FileName: a.OriginalFileName,
+			Size:     a.size,
+			Date:     time.Time(a.ExifInfo.DateTimeOriginal),
+			Favorite: a.IsFavorite,
+			Archived: a.IsArchived,
+			Trashed:  a.IsTrashed,
+			Albums:   albums[a.ID],
+			Sidecar:  a.Sidecar,
+		}
+		sort.Strings(info.Albums)
+		for _, t := range a.Tags {
+			info.Tags = append(info.Tags, t.Value)
+		}
+		sort.Strings(info.Tags)
+		if a.Stack != nil {
+			info.StackID = a.Stack.ID
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Albums gives the names of the albums, with the file names of their assets
+func (s *Server) Albums() map[string][]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	albums := map[string][]string{}
+	for _, al := range s.albums {
+		names := []string{}
+		for _, id := range al.assets {
+			if a := s.asset(id); a != nil {
+				names = append(names, a.OriginalFileName)
+			}
+		}
+		sort.Strings(names)
+		albums[al.AlbumName] = names
+	}
+	return albums
+}
+
+// Uploads gives the number of upload calls, including the duplicates
+func (s *Server) Uploads() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.uploads
+}
@@ -0,0 +1,44 @@
+package fakeserver
+
+import (
+	"net/http"
+)
+
+type stack struct {
+	ID             string   `json:"id"`
+	PrimaryAssetID string   `json:"primaryAssetId"`
+	Assets         []*asset `json:"assets"`
+}
+
+// createStack stacks the assets, the first one is the cover. The assets are removed from their previous stacks.
+func (s *Server) createStack(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AssetIDs []string `json:"assetIds"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if len(req.AssetIDs) < 2 {
+		writeError(w, http.StatusBadRequest, "a stack needs at least 2 assets")
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := &stack{ID: s.newID(), PrimaryAssetID: req.AssetIDs[0]}
+	for _, id := range req.AssetIDs {
+		a := s.asset(id)
+		if a == nil {
+			writeError(w, http.StatusBadRequest, "Asset not found")
+			return
+		}
+		st.Assets = append(st.Assets, a)
+	}
+	for _, a := range st.Assets {
+		if a.Stack != nil {
+			delete(s.stacks, a.Stack.ID)
+		}
+		a.Stack = &assetStack{ID: st.ID, PrimaryAssetID: st.PrimaryAssetID, AssetCount: len(st.Assets)}
+	}
+	s.stacks[st.ID] = st
+	writeJSON(w, http.StatusCreated, st)
+}
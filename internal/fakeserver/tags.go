@@ -0,0 +1,104 @@
+package fakeserver
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+type tag struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	ParentID string `json:"parentId,omitempty"`
+}
+
+// getTags gives all the tags, ordered by value
+func (s *Server) getTags(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tags := []*tag{}
+	for _, t := range s.tags {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Value < tags[j].Value })
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// upsertTags gives the tags of the values, the missing ones and their parents are created
+func (s *Server) upsertTags(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tags := []*tag{}
+	for _, value := range req.Tags {
+		tags = append(tags, s.upsertTag(value))
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// upsertTag gives the tag of the value, like parent/child
+func (s *Server) upsertTag(value string) *tag {
+	for _, t := range s.tags {
+		if lowerTrim(t.Value) == lowerTrim(value) {
+			return t
+		}
+	}
+	t := &tag{ID: s.newID(), Name: path.Base(value), Value: value}
+	if dir := path.Dir(value); dir != "." && dir != "/" {
+		t.ParentID = s.upsertTag(dir).ID
+	}
+	s.tags[t.ID] = t
+	return t
+}
+
+func (s *Server) tagAssets(w http.ResponseWriter, r *http.Request) {
+	s.changeTagAssets(w, r, func(a *asset, t *tag) string {
+		if slices.Contains(a.Tags, t) {
+			return immich.ErrorDuplicate
+		}
+		a.Tags = append(a.Tags, t)
+		return ""
+	})
+}
+
+func (s *Server) untagAssets(w http.ResponseWriter, r *http.Request) {
+	s.changeTagAssets(w, r, func(a *asset, t *tag) string {
+		i := slices.Index(a.Tags, t)
+		if i < 0 {
+			return "not_found"
+		}
+		a.Tags = slices.Delete(a.Tags, i, i+1)
+		return ""
+	})
+}
+
+// changeTagAssets applies the change to each asset of the request
+func (s *Server) changeTagAssets(w http.ResponseWriter, r *http.Request, fn func(a *asset, t *tag) string) {
+	var req immich.UpdateAlbum
+	if !readJSON(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.tags[r.PathValue("id")]
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, idsResults(req.IDS, func(id string) string {
+		a := s.asset(id)
+		if a == nil {
+			return "not_found"
+		}
+		return fn(a, t)
+	}))
+}
@@ -11,11 +11,29 @@ import (
 	"runtime/debug"
 
 	"github.com/simulot/immich-go/cmd"
+	"github.com/simulot/immich-go/cmd/album"
+	"github.com/simulot/immich-go/cmd/backup"
+	"github.com/simulot/immich-go/cmd/bench"
+	"github.com/simulot/immich-go/cmd/completion"
+	"github.com/simulot/immich-go/cmd/download"
 	"github.com/simulot/immich-go/cmd/duplicate"
+	"github.com/simulot/immich-go/cmd/library"
+	"github.com/simulot/immich-go/cmd/login"
+	"github.com/simulot/immich-go/cmd/memories"
 	"github.com/simulot/immich-go/cmd/metadata"
+	"github.com/simulot/immich-go/cmd/orphans"
+	"github.com/simulot/immich-go/cmd/people"
+	"github.com/simulot/immich-go/cmd/restore"
+	"github.com/simulot/immich-go/cmd/setup"
 	"github.com/simulot/immich-go/cmd/stack"
+	"github.com/simulot/immich-go/cmd/stats"
+	"github.com/simulot/immich-go/cmd/sync"
+	"github.com/simulot/immich-go/cmd/tag"
+	"github.com/simulot/immich-go/cmd/testserver"
 	"github.com/simulot/immich-go/cmd/tool"
 	"github.com/simulot/immich-go/cmd/upload"
+	"github.com/simulot/immich-go/cmd/verify"
+	"github.com/simulot/immich-go/helpers/configuration"
 	"github.com/simulot/immich-go/ui"
 	"github.com/telemachus/humane"
 )
@@ -78,39 +96,126 @@ func main() {
 	}
 	if err != nil {
 		if e := context.Cause(ctx); e != nil {
-			err = e
+			err = &cmd.ExitError{Code: cmd.ExitInterrupted, Err: e}
 		}
-		fmt.Println(err.Error())
-		os.Exit(1)
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Println(err.Error())
+		}
+		os.Exit(cmd.ExitCode(err))
 	}
 }
 
+// useProfile applies the profile of the configuration file to the command. The configuration file is optional,
+// unless a profile is requested. When several profiles are given, the first one gives the default flags,
+// and the assets are uploaded to the server of each profile.
+func useProfile(app *cmd.SharedFlags, fs *flag.FlagSet, profilesFile string, names []string, command string, args []string) ([]string, error) {
+	profiles, err := configuration.ReadProfiles(profilesFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && len(names) == 0 {
+			return args, nil
+		}
+		return nil, err
+	}
+	first := ""
+	if len(names) > 0 {
+		first = names[0]
+	}
+	p, err := profiles.Get(first)
+	if err != nil {
+		return nil, err
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	args, err = app.UseProfile(p, given, command, args)
+	if err != nil || len(names) < 2 {
+		return args, err
+	}
+
+	if command != "upload" {
+		return nil, fmt.Errorf("several profiles can only be given to the upload command")
+	}
+	app.Targets = []cmd.Target{{Name: first, Server: app.Server, API: app.API, Key: app.Key}}
+	for _, name := range names[1:] {
+		p, err := profiles.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		app.Targets = append(app.Targets, cmd.Target{Name: name, Server: p.Server, API: p.API, Key: p.Key})
+	}
+	return args, nil
+}
+
+// commands are the commands by name
+var commands = map[string]completion.Command{
+	"upload":       upload.UploadCommand,
+	"flush":        upload.FlushCommand,
+	"download":     download.DownloadCommand,
+	"duplicate":    duplicate.DuplicateCommand,
+	"metadata":     metadata.MetadataCommand,
+	"fix-metadata": metadata.FixMetadataCommand,
+	"sync":         sync.SyncCommand,
+	"backup":       backup.BackupCommand,
+	"verify":       verify.VerifyCommand,
+	"album":        album.AlbumCommand,
+	"people":       people.PeopleCommand,
+	"orphans":      orphans.OrphansCommand,
+	"stack":        stack.NewStackCommand,
+	"unstack":      stack.UnstackCommand,
+	"tag":          tag.TagCommand,
+	"restore":      restore.RestoreCommand,
+	"library":      library.LibraryCommand,
+	"memories":     memories.MemoriesCommand,
+	"stats":        stats.StatsCommand,
+	"test-server":  testserver.TestServerCommand,
+	"bench":        bench.BenchCommand,
+	"init":         setup.InitCommand,
+	"login":        login.LoginCommand,
+	"tool":         tool.CommandTool,
+}
+
 func Run(ctx context.Context) error {
 	app := cmd.SharedFlags{
 		Log:    slog.New(humane.NewHandler(os.Stdout, &humane.Options{Level: slog.LevelInfo})),
 		Banner: ui.NewBanner(version, commit, date),
 	}
-	fs := flag.NewFlagSet("main", flag.ExitOnError)
+	fs := flag.NewFlagSet("main", flag.ContinueOnError)
 	fs.BoolFunc("version", "Get immich-go version", func(s string) error {
 		printVersion()
 		os.Exit(0)
 		return nil
 	})
 
+	var profilesFile string
+	var profiles []string
+	fs.StringVar(&profilesFile, "config", configuration.DefaultProfilesFile(), "YAML configuration file with the profiles")
+	fs.Func("profile", "Name of the profile of the configuration file to use, default the profile named by the default key (repeatable: the upload command sends the assets to the server of each profile)", func(s string) error {
+		profiles = append(profiles, s)
+		return nil
+	})
+
 	app.InitSharedFlags()
 	app.SetFlags(fs)
 
 	err := fs.Parse(os.Args[1:])
 	if err != nil {
-		app.Log.Error(err.Error())
+		if !errors.Is(err, flag.ErrHelp) {
+			app.Log.Error(err.Error())
+		}
 		return err
 	}
 
-	printVersion()
-	fmt.Println(app.Banner.String())
+	// the output of the completion commands is read by the shell
+	if c := fs.Arg(0); c == "completion" || c == "__complete" {
+		app.Quiet = true
+	}
+
+	if !app.Quiet {
+		printVersion()
+		fmt.Println(app.Banner.String())
+	}
 
 	if len(fs.Args()) == 0 {
-		err = errors.New("missing command upload|duplicate|stack|tool")
+		err = errors.New("missing command init|login|completion|upload|download|sync|backup|verify|album|flush|duplicate|fix-metadata|orphans|people|stack|unstack|tag|tool")
 	}
 
 	if err != nil {
@@ -119,25 +224,36 @@ func Run(ctx context.Context) error {
 	}
 
 	cmd := fs.Args()[0]
+	args, err := useProfile(&app, fs, profilesFile, profiles, cmd, fs.Args()[1:])
+	if cmd == "completion" || cmd == "__complete" {
+		// the profile gives the server, but the words to complete are kept as typed
+		args, err = fs.Args()[1:], nil
+	}
+	if err != nil {
+		app.Log.Error(err.Error())
+		return err
+	}
+
 	switch cmd {
-	case "upload":
-		err = upload.UploadCommand(ctx, &app, fs.Args()[1:])
-	case "duplicate":
-		err = duplicate.DuplicateCommand(ctx, &app, fs.Args()[1:])
-	case "metadata":
-		err = metadata.MetadataCommand(ctx, &app, fs.Args()[1:])
-	case "stack":
-		err = stack.NewStackCommand(ctx, &app, fs.Args()[1:])
-	case "tool":
-		err = tool.CommandTool(ctx, &app, fs.Args()[1:])
+	case "completion":
+		err = completion.CompletionCommand(ctx, &app, args)
+	case "__complete":
+		err = completion.NewCompleter(commands, fs, &app).CompleteCommand(ctx, &app, args)
 	default:
-		err = fmt.Errorf("unknown command: %q", cmd)
+		command, ok := commands[cmd]
+		if !ok {
+			err = fmt.Errorf("unknown command: %q", cmd)
+			break
+		}
+		err = command(ctx, &app, args)
 	}
 
-	if err != nil {
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
 		app.Log.Error(err.Error())
 	}
-	fmt.Println("Check the log file: ", app.LogFile)
+	if !app.LogStderr && !app.LogSyslog && (!app.Quiet || err != nil) {
+		fmt.Println("Check the log file: ", app.LogFile)
+	}
 	if app.APITraceWriter != nil {
 		fmt.Println("Check the trace file: ", app.APITraceWriterName)
 	}
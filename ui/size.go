@@ -6,7 +6,7 @@ import (
 )
 
 func FormatBytes(s int) string {
-	suffixes := []string{"B", "KB", "MB", "GB"}
+	suffixes := []string{"B", "KB", "MB", "GB", "TB"}
 	bytes := float64(s)
 	base := 1024.0
 	if bytes < base {